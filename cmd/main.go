@@ -1,24 +1,52 @@
 package main
 
 import (
+	"context"
+	"os/signal"
+	"strconv"
+	"syscall"
+	"time"
+
+	"github.com/google/uuid"
 	"github.com/jmontesinos91/collector/config"
+	"github.com/jmontesinos91/collector/domains/breaker"
+	"github.com/jmontesinos91/collector/domains/egress"
+	"github.com/jmontesinos91/collector/domains/tenancy"
+	"github.com/jmontesinos91/collector/domains/validation"
 	"github.com/jmontesinos91/collector/internal/adapters/api"
 	"github.com/jmontesinos91/collector/internal/adapters/db"
+	"github.com/jmontesinos91/collector/internal/adapters/jobs"
+	"github.com/jmontesinos91/collector/internal/adapters/rpc"
 	"github.com/jmontesinos91/collector/internal/adapters/stream"
 	"github.com/jmontesinos91/collector/internal/repositories/alarmold" //nolint:goimports
+	oaudit "github.com/jmontesinos91/collector/internal/repositories/audit"
+	ocommand "github.com/jmontesinos91/collector/internal/repositories/command"
+	oexportjob "github.com/jmontesinos91/collector/internal/repositories/exportjob"
 	"github.com/jmontesinos91/collector/internal/repositories/facilitylocationsold"
 	"github.com/jmontesinos91/collector/internal/repositories/locationsold"
+	opartition "github.com/jmontesinos91/collector/internal/repositories/partition"
 	"github.com/jmontesinos91/collector/internal/repositories/router"
 	"github.com/jmontesinos91/collector/internal/repositories/routerold" //nolint:goimports
 	trepository "github.com/jmontesinos91/collector/internal/repositories/traffic"
 	"github.com/jmontesinos91/collector/internal/repositories/unitsold" //nolint:goimports
+	owebhook "github.com/jmontesinos91/collector/internal/repositories/webhook"
+	"github.com/jmontesinos91/collector/internal/services/ack"
+	"github.com/jmontesinos91/collector/internal/services/audit"
 	"github.com/jmontesinos91/collector/internal/services/collector"
+	"github.com/jmontesinos91/collector/internal/services/command"
+	"github.com/jmontesinos91/collector/internal/services/devicecache"
+	"github.com/jmontesinos91/collector/internal/services/exportjob"
+	"github.com/jmontesinos91/collector/internal/services/livefeed"
+	"github.com/jmontesinos91/collector/internal/services/partition"
+	"github.com/jmontesinos91/collector/internal/services/threshold"
 	"github.com/jmontesinos91/collector/internal/services/traffic"
+	"github.com/jmontesinos91/collector/internal/services/webhook"
+	"github.com/jmontesinos91/oevents/broker"
 	"github.com/jmontesinos91/ologs/logger"
 	"github.com/jmontesinos91/osecurity/services/omnibackend"
 	"github.com/jmontesinos91/osecurity/sts"
-
-	"github.com/go-playground/validator/v10"
+	"github.com/sirupsen/logrus"
+	"go.elastic.co/apm/module/apmhttp/v2"
 )
 
 func main() {
@@ -36,8 +64,8 @@ func main() {
 	// Http Router
 	httpServer := api.NewHTTPServer(contextLogger, configs.Server, configs.Service, stsClient)
 
-	// Validator
-	validate := validator.New()
+	// Validator, fields are reported by their json name
+	validate := validation.New()
 
 	// DB Connection
 	conn := db.NewDatabaseConnection(contextLogger, configs.Database)
@@ -47,19 +75,139 @@ func main() {
 
 	// Kafka
 	kafka, closer := stream.NewKafkaConnection(contextLogger, configs.Kafka)
-	defer closer()
-
-	// Alarm Client
-	rClient := router.NewRouterService(contextLogger, configs.OmniView)
 
 	// - Initialize repository -
 	trafficRepo := trepository.NewDatabaseRepository(contextLogger, conn)
+	exportJobRepo := oexportjob.NewDatabaseRepository(contextLogger, conn)
+	auditRepo := oaudit.NewDatabaseRepository(contextLogger, conn)
+	partitionRepo := opartition.NewDatabaseRepository(contextLogger, conn)
+	webhookRepo := owebhook.NewDatabaseRepository(contextLogger, conn)
+	commandRepo := ocommand.NewDatabaseRepository(contextLogger, conn)
 	oldLocations := locationsold.NewDatabaseRepository(contextLogger, oldConn)
-	oldRouter := routerold.NewDatabaseRepository(contextLogger, oldConn)
 	oldFacilityLocations := facilitylocationsold.NewDatabaseRepository(contextLogger, oldConn)
-	oldUnits := unitsold.NewDatabaseRepository(contextLogger, oldConn)
 	oldAlarm := alarmold.NewDatabaseRepository(contextLogger, oldConn)
 
+	var oldRouter routerold.IRepository = routerold.NewDatabaseRepository(contextLogger, oldConn)
+	var oldUnits unitsold.IRepository = unitsold.NewDatabaseRepository(contextLogger, oldConn)
+
+	// Tenants reachable by each caller
+	tenancyPolicy := tenancy.Policy{SuperAdminRoles: configs.Tenancy.SuperAdminRoles}
+
+	// Audit log of the operator actions
+	auditSvc := audit.NewDefaultService(contextLogger, auditRepo)
+
+	// Live traffic feed
+	feedSvc := livefeed.NewDefaultService(contextLogger, livefeed.Opts{
+		BufferSize:        configs.Traffic.Stream.BufferSize,
+		HeartbeatInterval: time.Duration(configs.Traffic.Stream.HeartbeatIntervalInSeconds) * time.Second,
+		WriteTimeout:      time.Duration(configs.Traffic.Stream.WriteTimeoutInSeconds) * time.Second,
+		Tenancy:           tenancyPolicy,
+	})
+
+	// Tenant webhooks
+	egressPolicy := egress.Policy{
+		AllowHTTP:    configs.Webhooks.AllowHTTP,
+		AllowPrivate: configs.Webhooks.AllowPrivateNetworks,
+	}
+	webhookSvc := webhook.NewDefaultService(contextLogger, webhookRepo, auditSvc, webhook.Opts{
+		HTTPClient:   apmhttp.WrapClient(egressPolicy.Client()),
+		Egress:       egressPolicy,
+		Timeout:      time.Duration(configs.Webhooks.TimeoutInSeconds) * time.Second,
+		MaxAttempts:  configs.Webhooks.MaxAttempts,
+		BackoffBase:  time.Duration(configs.Webhooks.BackoffBaseInSeconds) * time.Second,
+		BackoffMax:   time.Duration(configs.Webhooks.BackoffMaxInMinutes) * time.Minute,
+		DisableAfter: configs.Webhooks.DisableAfterFailures,
+		BatchSize:    configs.Webhooks.BatchSize,
+		Concurrency:  configs.Webhooks.Concurrency,
+	})
+
+	// Read-through cache for legacy lookups
+	var cacheSvc *devicecache.DefaultService
+	closeInvalidations := func(context.Context) {}
+	if configs.Cache.Enabled {
+		ttl := time.Duration(configs.Cache.TTLInSeconds) * time.Second
+		negativeTTL := time.Duration(configs.Cache.NegativeTTLInSeconds) * time.Second
+		cachedRouter := routerold.NewCachedRepository(oldRouter, configs.Cache.Size, ttl, negativeTTL)
+		cachedUnits := unitsold.NewCachedRepository(oldUnits, configs.Cache.Size, ttl, negativeTTL)
+		oldRouter, oldUnits = cachedRouter, cachedUnits
+
+		// Every replica reads every invalidation through a consumer group of its own
+		replica := uuid.NewString()
+		invalidationsConf := configs.Kafka
+		invalidationsConf.Consumer = config.KafkaConsumerConfigurations{
+			Enabled:    true,
+			Group:      configs.Cache.Invalidations.GroupPrefix + "-" + replica,
+			Topics:     []string{configs.Cache.Invalidations.Topic},
+			MaxRecords: configs.Cache.Invalidations.MaxRecords,
+		}
+		var invalidations broker.MessagingBrokerProvider
+		invalidations, closeInvalidations = stream.NewKafkaConnection(contextLogger, invalidationsConf)
+		cacheSvc = devicecache.NewDefaultService(contextLogger, cachedRouter, cachedUnits, auditSvc,
+			kafka, invalidations, devicecache.Opts{
+				Topic:      configs.Cache.Invalidations.Topic,
+				Replica:    replica,
+				MaxRecords: configs.Cache.Invalidations.MaxRecords,
+				StartedAt:  time.Now(),
+			})
+	}
+
+	// Downlink commands of the devices
+	commandSvc := command.NewDefaultService(contextLogger, commandRepo, oldRouter, auditSvc, command.Opts{
+		MaxPerResponse: configs.Commands.MaxPerResponse,
+		AckTimeout:     time.Duration(configs.Commands.AckTimeoutInSeconds) * time.Second,
+		MaxAttempts:    configs.Commands.MaxAttempts,
+		Tenancy:        tenancyPolicy,
+	})
+
+	// Answers to the device frames by device model or firmware
+	toAckProfile := func(c config.AckProfileConfigurations) ack.Profile {
+		return ack.Profile{
+			Format:        c.Format,
+			SuccessStatus: c.SuccessStatus,
+			FailureStatus: c.FailureStatus,
+			Success:       c.Success,
+			Failure:       c.Failure,
+		}
+	}
+	ackOpts := ack.Opts{
+		Default:  toAckProfile(configs.Acks.Default),
+		Profiles: make(map[string]ack.Profile, len(configs.Acks.Profiles)),
+		Devices:  configs.Acks.Devices,
+		Prefixes: configs.Acks.Prefixes,
+	}
+	for name, c := range configs.Acks.Profiles {
+		ackOpts.Profiles[name] = toAckProfile(c)
+	}
+	ackSvc := ack.NewDefaultService(contextLogger, ackOpts)
+
+	// Alarm Client
+	alarmBreaker := breaker.New(configs.Validation.Breaker.FailureThreshold,
+		time.Duration(configs.Validation.Breaker.OpenTimeoutInSeconds)*time.Second,
+		configs.Validation.Breaker.HalfOpenProbes)
+	remoteClient := router.NewBreakerClient(contextLogger, router.NewRouterService(contextLogger, configs.OmniView),
+		alarmBreaker, time.Duration(configs.Validation.CallTimeoutInSeconds)*time.Second)
+	localRules := router.LocalRules{
+		RequireActiveRouter: configs.Validation.Local.RequireActiveRouter,
+		AllowedTenants:      configs.Validation.Local.AllowedTenants,
+		DeniedTenants:       configs.Validation.Local.DeniedTenants,
+	}
+
+	mode, err := router.ParseMode(configs.Validation.Mode)
+	if err != nil {
+		contextLogger.Error(logrus.FatalLevel, "main", "Invalid alarm validation configuration", err)
+	}
+
+	var rClient router.IClient
+	switch mode {
+	case router.ModeLocal:
+		rClient = router.NewLocalClient(contextLogger, oldRouter, oldUnits, localRules)
+	case router.ModeLocalFirst:
+		localRules.DeferUnknownDevices = true
+		rClient = router.NewChainClient(router.NewLocalClient(contextLogger, oldRouter, oldUnits, localRules), remoteClient)
+	default:
+		rClient = remoteClient
+	}
+
 	repositoryOpts := collector.RepositoryOpts{
 		TrafficRepo:  trafficRepo,
 		OldAlarm:     oldAlarm,
@@ -71,15 +219,175 @@ func main() {
 	}
 
 	// - Initialize service -
-	collectorSvc := collector.NewDefaultService(contextLogger, repositoryOpts, rClient, kafka)
-	trafficSvc := traffic.NewDefaultService(contextLogger, trafficRepo)
+	fallback, err := collector.ParseFallbackPolicy(configs.Validation.Fallback)
+	if err != nil {
+		contextLogger.Error(logrus.FatalLevel, "main", "Invalid alarm validation configuration", err)
+	}
+	validationOpts := collector.ValidationOpts{
+		Fallback:      fallback,
+		QueueSize:     configs.Validation.Queue.Size,
+		RetryInterval: time.Duration(configs.Validation.Queue.RetryIntervalInSeconds) * time.Second,
+		MaxAge:        time.Duration(configs.Validation.Queue.MaxAgeInSeconds) * time.Second,
+	}
+
+	// Frames thresholds of the chattering devices
+	thresholdsConf := configs.Traffic.Thresholds
+	toThreshold := func(c config.TrafficThresholdConfigurations) threshold.Threshold {
+		return threshold.Threshold{
+			Window:      time.Duration(c.WindowInMinutes) * time.Minute,
+			Frames:      c.Frames,
+			PanicFrames: c.PanicFrames,
+		}
+	}
+	thresholdOpts := threshold.Opts{
+		Default:    toThreshold(thresholdsConf.Default),
+		Tenants:    make(map[int]threshold.Threshold, len(thresholdsConf.Tenants)),
+		Devices:    make(map[string]threshold.Threshold, len(thresholdsConf.Devices)),
+		Quarantine: time.Duration(thresholdsConf.QuarantineInMinutes) * time.Minute,
+	}
+	for tenant, c := range thresholdsConf.Tenants {
+		tenantID, err := strconv.Atoi(tenant)
+		if err != nil {
+			contextLogger.Log(logrus.WarnLevel, "main", "Threshold ignored, invalid tenant id: "+tenant)
+			continue
+		}
+		thresholdOpts.Tenants[tenantID] = toThreshold(c)
+	}
+	for imei, c := range thresholdsConf.Devices {
+		thresholdOpts.Devices[imei] = toThreshold(c)
+	}
+	thresholdSvc := threshold.NewDefaultService(contextLogger, trafficRepo, feedSvc, webhookSvc, thresholdOpts)
+
+	collectorSvc := collector.NewDefaultService(contextLogger, repositoryOpts, rClient, kafka, feedSvc, webhookSvc, thresholdSvc,
+		commandSvc, validationOpts)
+	// A misspelled column would otherwise fail every export that does not select its own columns
+	if err := traffic.ValidateExportColumns(configs.Traffic.Export.Columns); err != nil {
+		contextLogger.Error(logrus.FatalLevel, "main", "Invalid traffic export columns configuration", err)
+	}
+	trafficSvc := traffic.NewDefaultService(contextLogger, trafficRepo, auditSvc, feedSvc, traffic.Opts{
+		ExportColumns:    configs.Traffic.Export.Columns,
+		BulkLimit:        configs.Traffic.Bulk.MaxAffected,
+		DeletedRetention: time.Duration(configs.Traffic.Deleted.RetentionInDays) * 24 * time.Hour,
+		BucketsRetention: time.Duration(configs.Traffic.Buckets.RetentionInDays) * 24 * time.Hour,
+		Tenancy:          tenancyPolicy,
+	})
+	exportJobSvc := exportjob.NewDefaultService(contextLogger, exportJobRepo, trafficRepo, auditSvc, exportjob.Opts{
+		Directory:     configs.Traffic.Export.Jobs.Directory,
+		TTL:           time.Duration(configs.Traffic.Export.Jobs.TTLInHours) * time.Hour,
+		Lease:         time.Duration(configs.Traffic.Export.Jobs.LeaseInSeconds) * time.Second,
+		MaxAttempts:   configs.Traffic.Export.Jobs.MaxAttempts,
+		ExportColumns: configs.Traffic.Export.Columns,
+		Tenancy:       tenancyPolicy,
+	})
+
+	// Monthly partitions of the append-only tables and retention of the tenants rows
+	partitionPolicies := make([]partition.Policy, 0, len(configs.Partitions.Tables))
+	for _, table := range configs.Partitions.Tables {
+		tenantRetention := make(map[int]int, len(table.Tenants))
+		for tenant, months := range table.Tenants {
+			tenantID, err := strconv.Atoi(tenant)
+			if err != nil {
+				contextLogger.Log(logrus.WarnLevel, "main", "Tenant retention ignored, invalid tenant id: "+tenant)
+				continue
+			}
+			tenantRetention[tenantID] = months
+		}
+		partitionPolicies = append(partitionPolicies, partition.Policy{
+			Table:                 table.Name,
+			RetentionMonths:       table.RetentionInMonths,
+			Action:                table.Action,
+			TenantRetentionMonths: tenantRetention,
+		})
+	}
+	partitionSvc := partition.NewDefaultService(contextLogger, partitionRepo, partition.Opts{
+		Policies:      partitionPolicies,
+		PremakeMonths: configs.Partitions.PremakeMonths,
+	})
+
+	// - Background jobs -
+	scheduler := jobs.NewScheduler(contextLogger)
+	scheduler.GoWithDrain("pending-validations", collectorSvc.RunPendingValidations)
+	if cacheSvc != nil {
+		scheduler.Go("cache-invalidations", cacheSvc.RunInvalidations)
+	}
+	scheduler.Every("traffic-tenant-backfill", time.Duration(configs.Traffic.Tenants.BackfillIntervalInMinutes)*time.Minute,
+		collectorSvc.BackfillTenants)
+	scheduler.Every("export-jobs", time.Duration(configs.Traffic.Export.Jobs.PollIntervalInSeconds)*time.Second,
+		exportJobSvc.ProcessPending)
+	scheduler.Every("export-cleanup", time.Duration(configs.Traffic.Export.Jobs.CleanupIntervalInMinutes)*time.Minute,
+		exportJobSvc.CleanupExpired)
+	scheduler.Every("traffic-purge", time.Duration(configs.Traffic.Deleted.PurgeIntervalInMinutes)*time.Minute,
+		trafficSvc.PurgeDeleted)
+	scheduler.Every("traffic-buckets-purge", time.Duration(configs.Traffic.Buckets.PurgeIntervalInMinutes)*time.Minute,
+		trafficSvc.PurgeBuckets)
+	scheduler.Every("traffic-thresholds", time.Duration(thresholdsConf.EvaluationIntervalInSeconds)*time.Second,
+		thresholdSvc.Evaluate)
+	scheduler.Every("partition-maintenance", time.Duration(configs.Partitions.MaintenanceIntervalInMinutes)*time.Minute,
+		partitionSvc.RunMaintenance)
+	scheduler.Every("webhook-deliveries", time.Duration(configs.Webhooks.PollIntervalInSeconds)*time.Second,
+		webhookSvc.ProcessDue)
+	scheduler.Every("command-expiry", time.Duration(configs.Commands.ExpireIntervalInMinutes)*time.Minute,
+		commandSvc.ExpireUnacknowledged)
 
 	api.NewHealthController(httpServer)
-	api.NewCollectorController(httpServer, validate, collectorSvc, stsClient)
+	api.NewOpenAPIController(httpServer)
+	api.NewCollectorController(httpServer, validate, collectorSvc, ackSvc, stsClient)
 	api.NewTrafficController(httpServer, validate, trafficSvc, stsClient)
+	api.NewExportJobController(httpServer, exportJobSvc, stsClient)
+	api.NewAuditController(httpServer, auditSvc, stsClient)
+	api.NewLiveFeedController(httpServer, feedSvc, stsClient)
+	api.NewWebhookController(httpServer, webhookSvc, stsClient)
+	api.NewCommandController(httpServer, validate, commandSvc, stsClient)
+	if cacheSvc != nil {
+		api.NewCacheController(httpServer, cacheSvc, stsClient)
+	}
+
+	// gRPC server for the internal services
+	var grpcServer *rpc.GRPCServer
+	if configs.GRPC.Enabled {
+		grpcServer = rpc.NewGRPCServer(contextLogger, configs.GRPC, stsClient)
+		rpc.NewTrafficController(grpcServer, trafficSvc, collectorSvc)
+		go grpcServer.Start()
+	}
 
 	// -- End dependency injection section --
 
 	// Let the party started!
-	httpServer.Start()
+	go httpServer.Start()
+
+	// Graceful shutdown, a second signal kills the process
+	signals, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	<-signals.Done()
+	stop()
+	contextLogger.Log(logrus.InfoLevel, "main", "Shutdown requested, draining")
+
+	// Readiness fails first so the load balancer stops routing new requests
+	httpServer.Drain()
+	time.Sleep(time.Duration(configs.Server.ReadinessDelayInSeconds) * time.Second)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(),
+		time.Duration(configs.Server.ShutdownTimeoutInSeconds)*time.Second)
+	defer cancel()
+
+	// The live feed streams never end on their own
+	feedSvc.Close()
+	if err := httpServer.Shutdown(shutdownCtx); err != nil {
+		contextLogger.Error(logrus.WarnLevel, "main", "Failed to drain http server", err)
+	}
+	if grpcServer != nil {
+		grpcServer.Stop(shutdownCtx)
+	}
+	// Workers last, the pending alarms enqueued while draining are still validated
+	if err := scheduler.Stop(shutdownCtx); err != nil {
+		contextLogger.Error(logrus.WarnLevel, "main", "Failed to stop background jobs", err)
+	}
+	closer(shutdownCtx)
+	closeInvalidations(shutdownCtx)
+	if err := conn.Close(); err != nil {
+		contextLogger.Error(logrus.WarnLevel, "main", "Failed to close database", err)
+	}
+	if err := oldConn.Close(); err != nil {
+		contextLogger.Error(logrus.WarnLevel, "main", "Failed to close old database", err)
+	}
+	contextLogger.Log(logrus.InfoLevel, "main", "Shutdown completed")
 }
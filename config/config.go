@@ -17,6 +17,16 @@ type ServerConfigurations struct {
 	Port          int    `koanf:"port"`
 	BaseDirectory string `koanf:"base-directory"`
 	Host          string `koanf:"host"`
+	// ReadinessDelayInSeconds is how long the readiness probe fails before the server stops accepting connections
+	ReadinessDelayInSeconds int64 `koanf:"readiness-delay-in-seconds"`
+	// ShutdownTimeoutInSeconds is the deadline to drain requests, workers and connections on shutdown
+	ShutdownTimeoutInSeconds int64 `koanf:"shutdown-timeout-in-seconds"`
+}
+
+// GRPCConfigurations grpc server configurations
+type GRPCConfigurations struct {
+	Enabled bool `koanf:"enabled"`
+	Port    int  `koanf:"port"`
 }
 
 // KeysConfigurations asymmetric keys
@@ -64,15 +74,205 @@ type KafkaConsumerConfigurations struct {
 	MaxRecords int      `koanf:"max-records"`
 }
 
+// CacheConfigurations read-through cache configurations for legacy lookups
+type CacheConfigurations struct {
+	Enabled              bool                             `koanf:"enabled"`
+	Size                 int                              `koanf:"size"`
+	TTLInSeconds         int64                            `koanf:"ttl-in-seconds"`
+	NegativeTTLInSeconds int64                            `koanf:"negative-ttl-in-seconds"`
+	Invalidations        CacheInvalidationsConfigurations `koanf:"invalidations"`
+}
+
+// CacheInvalidationsConfigurations topic where the cache invalidations are broadcast to every replica
+type CacheInvalidationsConfigurations struct {
+	Topic       string `koanf:"topic"`
+	GroupPrefix string `koanf:"group-prefix"`
+	MaxRecords  int    `koanf:"max-records"`
+}
+
+// AlarmValidationConfigurations alarm validation client resilience configurations
+type AlarmValidationConfigurations struct {
+	Mode                 string                        `koanf:"mode"`
+	Local                LocalValidationConfigurations `koanf:"local"`
+	Fallback             string                        `koanf:"fallback"`
+	CallTimeoutInSeconds int64                         `koanf:"call-timeout-in-seconds"`
+	Breaker              BreakerConfigurations         `koanf:"breaker"`
+	Queue                AlarmQueueConfigurations      `koanf:"queue"`
+}
+
+// LocalValidationConfigurations rules for the local alarm validation
+type LocalValidationConfigurations struct {
+	RequireActiveRouter bool  `koanf:"require-active-router"`
+	AllowedTenants      []int `koanf:"allowed-tenants"`
+	DeniedTenants       []int `koanf:"denied-tenants"`
+}
+
+// BreakerConfigurations circuit breaker configurations
+type BreakerConfigurations struct {
+	FailureThreshold     int   `koanf:"failure-threshold"`
+	OpenTimeoutInSeconds int64 `koanf:"open-timeout-in-seconds"`
+	HalfOpenProbes       int   `koanf:"half-open-probes"`
+}
+
+// AlarmQueueConfigurations pending alarms queue configurations
+type AlarmQueueConfigurations struct {
+	Size                   int   `koanf:"size"`
+	RetryIntervalInSeconds int64 `koanf:"retry-interval-in-seconds"`
+	MaxAgeInSeconds        int64 `koanf:"max-age-in-seconds"`
+}
+
+// TrafficConfigurations traffic API configurations
+type TrafficConfigurations struct {
+	Export     TrafficExportConfigurations     `koanf:"export"`
+	Bulk       TrafficBulkConfigurations       `koanf:"bulk"`
+	Deleted    TrafficDeletedConfigurations    `koanf:"deleted"`
+	Stream     TrafficStreamConfigurations     `koanf:"stream"`
+	Buckets    TrafficBucketsConfigurations    `koanf:"buckets"`
+	Thresholds TrafficThresholdsConfigurations `koanf:"thresholds"`
+	Tenants    TrafficTenantsConfigurations    `koanf:"tenants"`
+}
+
+// TrafficTenantsConfigurations backfill of the tenant of the traffic rows written without one
+type TrafficTenantsConfigurations struct {
+	BackfillIntervalInMinutes int64 `koanf:"backfill-interval-in-minutes"`
+}
+
+// TrafficThresholdsConfigurations frames thresholds of the devices, the thresholds of a device override
+// the ones of its tenant, keyed by tenant id, which override the default ones
+type TrafficThresholdsConfigurations struct {
+	EvaluationIntervalInSeconds int64                                     `koanf:"evaluation-interval-in-seconds"`
+	QuarantineInMinutes         int64                                     `koanf:"quarantine-in-minutes"`
+	Default                     TrafficThresholdConfigurations            `koanf:"default"`
+	Tenants                     map[string]TrafficThresholdConfigurations `koanf:"tenants"`
+	Devices                     map[string]TrafficThresholdConfigurations `koanf:"devices"`
+}
+
+// TrafficThresholdConfigurations frames a device may send within the window, zero disables a limit
+type TrafficThresholdConfigurations struct {
+	WindowInMinutes int64 `koanf:"window-in-minutes"`
+	Frames          int   `koanf:"frames"`
+	PanicFrames     int   `koanf:"panic-frames"`
+}
+
+// TrafficBucketsConfigurations rolling window frames buckets configurations
+type TrafficBucketsConfigurations struct {
+	PurgeIntervalInMinutes int64 `koanf:"purge-interval-in-minutes"`
+	RetentionInDays        int64 `koanf:"retention-in-days"`
+}
+
+// TrafficStreamConfigurations live traffic feed configurations
+type TrafficStreamConfigurations struct {
+	BufferSize                 int   `koanf:"buffer-size"`
+	HeartbeatIntervalInSeconds int64 `koanf:"heartbeat-interval-in-seconds"`
+	WriteTimeoutInSeconds      int64 `koanf:"write-timeout-in-seconds"`
+}
+
+// TrafficDeletedConfigurations soft deleted traffic purge configurations
+type TrafficDeletedConfigurations struct {
+	RetentionInDays        int64 `koanf:"retention-in-days"`
+	PurgeIntervalInMinutes int64 `koanf:"purge-interval-in-minutes"`
+}
+
+// TrafficBulkConfigurations traffic bulk operations configurations
+type TrafficBulkConfigurations struct {
+	MaxAffected int `koanf:"max-affected"`
+}
+
+// TrafficExportConfigurations traffic export configurations
+type TrafficExportConfigurations struct {
+	Columns []string                 `koanf:"columns"`
+	Jobs    ExportJobsConfigurations `koanf:"jobs"`
+}
+
+// ExportJobsConfigurations asynchronous export jobs configurations
+type ExportJobsConfigurations struct {
+	Directory                string `koanf:"directory"`
+	TTLInHours               int64  `koanf:"ttl-in-hours"`
+	PollIntervalInSeconds    int64  `koanf:"poll-interval-in-seconds"`
+	CleanupIntervalInMinutes int64  `koanf:"cleanup-interval-in-minutes"`
+	LeaseInSeconds           int64  `koanf:"lease-in-seconds"`
+	MaxAttempts              int    `koanf:"max-attempts"`
+}
+
+// PartitionsConfigurations monthly partition maintenance and retention configurations
+type PartitionsConfigurations struct {
+	PremakeMonths                int                            `koanf:"premake-months"`
+	MaintenanceIntervalInMinutes int64                          `koanf:"maintenance-interval-in-minutes"`
+	Tables                       []PartitionTableConfigurations `koanf:"tables"`
+}
+
+// PartitionTableConfigurations retention policy of a table, the retention in months and action apply to the
+// partitions of the partitioned tables and the tenants retention in months is keyed by tenant id
+type PartitionTableConfigurations struct {
+	Name              string         `koanf:"name"`
+	RetentionInMonths int            `koanf:"retention-in-months"`
+	Action            string         `koanf:"action"`
+	Tenants           map[string]int `koanf:"tenants"`
+}
+
+// WebhooksConfigurations tenant webhook deliveries configurations
+type WebhooksConfigurations struct {
+	PollIntervalInSeconds int64 `koanf:"poll-interval-in-seconds"`
+	TimeoutInSeconds      int64 `koanf:"timeout-in-seconds"`
+	MaxAttempts           int   `koanf:"max-attempts"`
+	BackoffBaseInSeconds  int64 `koanf:"backoff-base-in-seconds"`
+	BackoffMaxInMinutes   int64 `koanf:"backoff-max-in-minutes"`
+	DisableAfterFailures  int   `koanf:"disable-after-failures"`
+	BatchSize             int   `koanf:"batch-size"`
+	Concurrency           int   `koanf:"concurrency"`
+	AllowHTTP             bool  `koanf:"allow-http"`
+	AllowPrivateNetworks  bool  `koanf:"allow-private-networks"`
+}
+
+// CommandsConfigurations device downlink commands configurations
+type CommandsConfigurations struct {
+	MaxPerResponse          int   `koanf:"max-per-response"`
+	AckTimeoutInSeconds     int64 `koanf:"ack-timeout-in-seconds"`
+	MaxAttempts             int   `koanf:"max-attempts"`
+	ExpireIntervalInMinutes int64 `koanf:"expire-interval-in-minutes"`
+}
+
+// AcksConfigurations answers to the device frames, the profile of a device overrides the one of
+// the longest prefix of its IMEI which overrides the default one
+type AcksConfigurations struct {
+	Default  AckProfileConfigurations            `koanf:"default"`
+	Profiles map[string]AckProfileConfigurations `koanf:"profiles"`
+	Devices  map[string]string                   `koanf:"devices"`
+	Prefixes map[string]string                   `koanf:"prefixes"`
+}
+
+// AckProfileConfigurations answer a device model or firmware expects, the format is json or text
+type AckProfileConfigurations struct {
+	Format        string `koanf:"format"`
+	SuccessStatus int    `koanf:"success-status"`
+	FailureStatus int    `koanf:"failure-status"`
+	Success       string `koanf:"success"`
+	Failure       string `koanf:"failure"`
+}
+
+// TenancyConfigurations tenant scoping of the traffic data
+type TenancyConfigurations struct {
+	SuperAdminRoles []string `koanf:"super-admin-roles"`
+}
+
 // Configurations Application wide configurations
 type Configurations struct {
 	Server      ServerConfigurations               `koanf:"server"`
+	GRPC        GRPCConfigurations                 `koanf:"grpc"`
 	Keys        KeysConfigurations                 `koanf:"keys"`
 	Service     Service                            `koanf:"service"`
 	Database    DatabaseConfigurations             `koanf:"database"`
 	OldDatabase DatabaseConfigurations             `koanf:"olddatabase"`
 	OmniView    omnibackend.OmniViewConfigurations `koanf:"provider"`
 	Kafka       KafkaConfigurations                `koanf:"kafka"`
+	Cache       CacheConfigurations                `koanf:"cache"`
+	Validation  AlarmValidationConfigurations      `koanf:"alarm-validation"`
+	Traffic     TrafficConfigurations              `koanf:"traffic"`
+	Partitions  PartitionsConfigurations           `koanf:"partitions"`
+	Webhooks    WebhooksConfigurations             `koanf:"webhooks"`
+	Commands    CommandsConfigurations             `koanf:"commands"`
+	Acks        AcksConfigurations                 `koanf:"acks"`
+	Tenancy     TenancyConfigurations              `koanf:"tenancy"`
 }
 
 // LoadConfig Loads configurations depending upon the environment
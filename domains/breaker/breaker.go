@@ -0,0 +1,144 @@
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrOpen is returned by Allow when the breaker is rejecting calls
+var ErrOpen = errors.New("circuit breaker is open")
+
+// State of a circuit breaker
+type State string
+
+// Circuit breaker states
+const (
+	// Closed calls flow normally and failures are counted
+	Closed State = "closed"
+	// Open calls are rejected until the open timeout elapses
+	Open State = "open"
+	// HalfOpen a limited number of probe calls decide whether to close or reopen
+	HalfOpen State = "half-open"
+)
+
+// Breaker is a concurrency safe circuit breaker, it opens after a number of consecutive
+// failures and, once the open timeout elapses, lets a limited number of probes through
+type Breaker struct {
+	mu               sync.Mutex
+	failureThreshold int
+	openTimeout      time.Duration
+	halfOpenProbes   int
+	state            State
+	failures         int
+	probes           int
+	successes        int
+	openedAt         time.Time
+	now              func() time.Time
+}
+
+// New creates a closed Breaker, invalid arguments are replaced with the package defaults
+func New(failureThreshold int, openTimeout time.Duration, halfOpenProbes int) *Breaker {
+	if failureThreshold < 1 {
+		failureThreshold = DefaultFailureThreshold
+	}
+	if openTimeout <= 0 {
+		openTimeout = DefaultOpenTimeout
+	}
+	if halfOpenProbes < 1 {
+		halfOpenProbes = DefaultHalfOpenProbes
+	}
+
+	return &Breaker{
+		failureThreshold: failureThreshold,
+		openTimeout:      openTimeout,
+		halfOpenProbes:   halfOpenProbes,
+		state:            Closed,
+		now:              time.Now,
+	}
+}
+
+// Allow reports whether a call may proceed, callers must report its outcome with Success or Failure
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case Open:
+		if b.now().Sub(b.openedAt) < b.openTimeout {
+			return ErrOpen
+		}
+		b.state = HalfOpen
+		b.probes = 0
+		b.successes = 0
+		fallthrough
+	case HalfOpen:
+		if b.probes >= b.halfOpenProbes {
+			return ErrOpen
+		}
+		b.probes++
+	}
+
+	return nil
+}
+
+// Success records a successful call
+func (b *Breaker) Success() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case HalfOpen:
+		b.successes++
+		if b.successes >= b.halfOpenProbes {
+			b.state = Closed
+			b.failures = 0
+		}
+	case Closed:
+		b.failures = 0
+	}
+}
+
+// Failure records a failed call
+func (b *Breaker) Failure() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case HalfOpen:
+		b.trip()
+	case Closed:
+		b.failures++
+		if b.failures >= b.failureThreshold {
+			b.trip()
+		}
+	}
+}
+
+// Release gives back a call allowed by Allow without recording an outcome, for calls abandoned by the caller
+func (b *Breaker) Release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == HalfOpen && b.probes > 0 {
+		b.probes--
+	}
+}
+
+// State returns the current state of the breaker
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == Open && b.now().Sub(b.openedAt) >= b.openTimeout {
+		return HalfOpen
+	}
+
+	return b.state
+}
+
+func (b *Breaker) trip() {
+	b.state = Open
+	b.openedAt = b.now()
+	b.failures = 0
+}
@@ -0,0 +1,108 @@
+package breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreaker(t *testing.T) {
+	tests := []struct {
+		name    string
+		run     func(b *Breaker, clock *time.Time)
+		asserts func(*testing.T, *Breaker) bool
+	}{
+		{
+			name: "Stays closed below the threshold",
+			run: func(b *Breaker, _ *time.Time) {
+				b.Failure()
+				b.Failure()
+				b.Success()
+				b.Failure()
+			},
+			asserts: func(t *testing.T, b *Breaker) bool {
+				return assert.Equal(t, Closed, b.State()) && assert.NoError(t, b.Allow())
+			},
+		},
+		{
+			name: "Opens after consecutive failures",
+			run: func(b *Breaker, _ *time.Time) {
+				b.Failure()
+				b.Failure()
+				b.Failure()
+			},
+			asserts: func(t *testing.T, b *Breaker) bool {
+				return assert.Equal(t, Open, b.State()) && assert.ErrorIs(t, b.Allow(), ErrOpen)
+			},
+		},
+		{
+			name: "Half-open allows a single probe",
+			run: func(b *Breaker, clock *time.Time) {
+				b.Failure()
+				b.Failure()
+				b.Failure()
+				*clock = clock.Add(time.Minute)
+			},
+			asserts: func(t *testing.T, b *Breaker) bool {
+				return assert.Equal(t, HalfOpen, b.State()) &&
+					assert.NoError(t, b.Allow()) &&
+					assert.ErrorIs(t, b.Allow(), ErrOpen)
+			},
+		},
+		{
+			name: "Released probe lets another probe through",
+			run: func(b *Breaker, clock *time.Time) {
+				b.Failure()
+				b.Failure()
+				b.Failure()
+				*clock = clock.Add(time.Minute)
+				_ = b.Allow()
+				b.Release()
+			},
+			asserts: func(t *testing.T, b *Breaker) bool {
+				return assert.Equal(t, HalfOpen, b.State()) && assert.NoError(t, b.Allow())
+			},
+		},
+		{
+			name: "Successful probe closes the breaker",
+			run: func(b *Breaker, clock *time.Time) {
+				b.Failure()
+				b.Failure()
+				b.Failure()
+				*clock = clock.Add(time.Minute)
+				_ = b.Allow()
+				b.Success()
+			},
+			asserts: func(t *testing.T, b *Breaker) bool {
+				return assert.Equal(t, Closed, b.State()) && assert.NoError(t, b.Allow())
+			},
+		},
+		{
+			name: "Failed probe reopens the breaker",
+			run: func(b *Breaker, clock *time.Time) {
+				b.Failure()
+				b.Failure()
+				b.Failure()
+				*clock = clock.Add(time.Minute)
+				_ = b.Allow()
+				b.Failure()
+			},
+			asserts: func(t *testing.T, b *Breaker) bool {
+				return assert.Equal(t, Open, b.State()) && assert.ErrorIs(t, b.Allow(), ErrOpen)
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+			b := New(3, 30*time.Second, 1)
+			b.now = func() time.Time { return clock }
+
+			tt.run(b, &clock)
+			if !tt.asserts(t, b) {
+				t.Errorf("Assert error on test = '%v'", tt.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,13 @@
+package breaker
+
+import "time"
+
+// Circuit breaker defaults values
+const (
+	// DefaultFailureThreshold Consecutive failures needed to open the breaker
+	DefaultFailureThreshold = 5
+	// DefaultOpenTimeout Time the breaker stays open before probing
+	DefaultOpenTimeout = 30 * time.Second
+	// DefaultHalfOpenProbes Probe calls allowed, and needed to succeed, while half-open
+	DefaultHalfOpenProbes = 1
+)
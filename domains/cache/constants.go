@@ -0,0 +1,4 @@
+package cache
+
+// DefaultCapacity Default number of entries kept by a cache when the given capacity is not valid
+const DefaultCapacity = 1000
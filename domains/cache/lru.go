@@ -0,0 +1,169 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// LRU is a size bounded, concurrency safe least-recently-used cache whose
+// entries expire after a TTL. Lookups that resolved to "not found" can be
+// stored too (negative caching) with their own, usually shorter, TTL.
+type LRU[K comparable, V any] struct {
+	mu          sync.Mutex
+	capacity    int
+	ttl         time.Duration
+	negativeTTL time.Duration
+	items       map[K]*list.Element
+	order       *list.List
+	now         func() time.Time
+}
+
+type entry[K comparable, V any] struct {
+	key       K
+	value     V
+	missing   bool
+	expiresAt time.Time
+}
+
+// NewLRU creates a new LRU cache, a capacity lower than one defaults to DefaultCapacity
+func NewLRU[K comparable, V any](capacity int, ttl, negativeTTL time.Duration) *LRU[K, V] {
+	if capacity < 1 {
+		capacity = DefaultCapacity
+	}
+
+	return &LRU[K, V]{
+		capacity:    capacity,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		items:       make(map[K]*list.Element, capacity),
+		order:       list.New(),
+		now:         time.Now,
+	}
+}
+
+// Get looks up a key, hit reports whether the key was cached and missing
+// reports whether the cached entry is a negative one
+func (c *LRU[K, V]) Get(key K) (value V, missing bool, hit bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return value, false, false
+	}
+
+	e := elem.Value.(*entry[K, V])
+	if c.now().After(e.expiresAt) {
+		c.removeElement(elem)
+		return value, false, false
+	}
+
+	c.order.MoveToFront(elem)
+
+	return e.value, e.missing, true
+}
+
+// Set stores a value for the given key
+func (c *LRU[K, V]) Set(key K, value V) {
+	c.set(key, value, false, c.ttl)
+}
+
+// SetMissing stores a negative entry for the given key, it is a no-op when negative caching is disabled
+func (c *LRU[K, V]) SetMissing(key K) {
+	if c.negativeTTL <= 0 {
+		return
+	}
+
+	var zero V
+	c.set(key, zero, true, c.negativeTTL)
+}
+
+// Delete removes a key from the cache and reports whether it was present
+func (c *LRU[K, V]) Delete(key K) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.items[key]
+	if !ok {
+		return false
+	}
+	c.removeElement(elem)
+
+	return true
+}
+
+// DeleteFunc removes every entry for which match returns true and returns how many were removed,
+// negative entries are matched with the zero value
+func (c *LRU[K, V]) DeleteFunc(match func(key K, value V) bool) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := 0
+	for elem := c.order.Front(); elem != nil; {
+		next := elem.Next()
+		e := elem.Value.(*entry[K, V])
+		if match(e.key, e.value) {
+			c.removeElement(elem)
+			removed++
+		}
+		elem = next
+	}
+
+	return removed
+}
+
+// Purge removes every entry from the cache and returns how many were removed
+func (c *LRU[K, V]) Purge() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	removed := len(c.items)
+	c.items = make(map[K]*list.Element, c.capacity)
+	c.order.Init()
+
+	return removed
+}
+
+// Len returns the number of entries currently cached, expired entries included
+func (c *LRU[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return len(c.items)
+}
+
+func (c *LRU[K, V]) set(key K, value V, missing bool, ttl time.Duration) {
+	if ttl <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := c.now().Add(ttl)
+	if elem, ok := c.items[key]; ok {
+		e := elem.Value.(*entry[K, V])
+		e.value = value
+		e.missing = missing
+		e.expiresAt = expiresAt
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&entry[K, V]{
+		key:       key,
+		value:     value,
+		missing:   missing,
+		expiresAt: expiresAt,
+	})
+
+	for len(c.items) > c.capacity {
+		c.removeElement(c.order.Back())
+	}
+}
+
+func (c *LRU[K, V]) removeElement(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.items, elem.Value.(*entry[K, V]).key)
+}
@@ -0,0 +1,109 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLRU(t *testing.T) {
+	tests := []struct {
+		name    string
+		run     func(c *LRU[string, int], clock *time.Time)
+		asserts func(*testing.T, *LRU[string, int]) bool
+	}{
+		{
+			name: "Hit after set",
+			run: func(c *LRU[string, int], _ *time.Time) {
+				c.Set("a", 1)
+			},
+			asserts: func(t *testing.T, c *LRU[string, int]) bool {
+				value, missing, hit := c.Get("a")
+				return assert.True(t, hit) && assert.False(t, missing) && assert.Equal(t, 1, value)
+			},
+		},
+		{
+			name: "Least recently used entry is evicted",
+			run: func(c *LRU[string, int], _ *time.Time) {
+				c.Set("a", 1)
+				c.Set("b", 2)
+				c.Get("a")
+				c.Set("c", 3)
+			},
+			asserts: func(t *testing.T, c *LRU[string, int]) bool {
+				_, _, hitA := c.Get("a")
+				_, _, hitB := c.Get("b")
+				return assert.True(t, hitA) && assert.False(t, hitB) && assert.Equal(t, 2, c.Len())
+			},
+		},
+		{
+			name: "Entry expires after TTL",
+			run: func(c *LRU[string, int], clock *time.Time) {
+				c.Set("a", 1)
+				*clock = clock.Add(time.Minute + time.Second)
+			},
+			asserts: func(t *testing.T, c *LRU[string, int]) bool {
+				_, _, hit := c.Get("a")
+				return assert.False(t, hit) && assert.Equal(t, 0, c.Len())
+			},
+		},
+		{
+			name: "Negative entry uses its own TTL",
+			run: func(c *LRU[string, int], clock *time.Time) {
+				c.SetMissing("a")
+				*clock = clock.Add(5 * time.Second)
+			},
+			asserts: func(t *testing.T, c *LRU[string, int]) bool {
+				_, missing, hit := c.Get("a")
+				return assert.True(t, hit) && assert.True(t, missing)
+			},
+		},
+		{
+			name: "Negative entry expires",
+			run: func(c *LRU[string, int], clock *time.Time) {
+				c.SetMissing("a")
+				*clock = clock.Add(11 * time.Second)
+			},
+			asserts: func(t *testing.T, c *LRU[string, int]) bool {
+				_, _, hit := c.Get("a")
+				return assert.False(t, hit)
+			},
+		},
+		{
+			name: "Delete and purge",
+			run: func(c *LRU[string, int], _ *time.Time) {
+				c.Set("a", 1)
+				c.Set("b", 2)
+				c.Delete("a")
+			},
+			asserts: func(t *testing.T, c *LRU[string, int]) bool {
+				_, _, hit := c.Get("a")
+				return assert.False(t, hit) && assert.Equal(t, 1, c.Purge()) && assert.Equal(t, 0, c.Len())
+			},
+		},
+		{
+			name: "Delete by predicate removes matching values and negative entries",
+			run: func(c *LRU[string, int], _ *time.Time) {
+				c.Set("a", 1)
+				c.SetMissing("b")
+			},
+			asserts: func(t *testing.T, c *LRU[string, int]) bool {
+				removed := c.DeleteFunc(func(key string, value int) bool { return value == 1 || key == "b" })
+				return assert.Equal(t, 2, removed) && assert.Equal(t, 0, c.Len())
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clock := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+			c := NewLRU[string, int](2, time.Minute, 10*time.Second)
+			c.now = func() time.Time { return clock }
+
+			tt.run(c, &clock)
+			if !tt.asserts(t, c) {
+				t.Errorf("Assert error on test = '%v'", tt.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,26 @@
+package egress
+
+import (
+	"net/netip"
+	"time"
+)
+
+// blockedPrefixes ranges outgoing requests never reach besides the loopback, private, link-local,
+// multicast and unspecified ones: the shared address space hosting some cloud metadata services,
+// the IETF protocol assignments, the benchmarking and reserved ranges and NAT64, which maps to IPv4
+var blockedPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("64:ff9b:1::/48"),
+}
+
+const (
+	// dialTimeout time a connection to the destination may take
+	dialTimeout = 10 * time.Second
+	// keepAlive interval of the keep-alive probes of the connections
+	keepAlive = 30 * time.Second
+)
@@ -0,0 +1,134 @@
+package egress
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"syscall"
+)
+
+// ErrForbiddenDestination is returned when a request would reach an address of the internal network
+var ErrForbiddenDestination = errors.New("destination address is not allowed")
+
+// Resolver looks up the addresses of a host, net.Resolver satisfies it
+type Resolver interface {
+	LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error)
+}
+
+// Policy decides the destinations the requests on behalf of the tenants may reach. The zero value
+// only allows https urls of public addresses
+type Policy struct {
+	// AllowHTTP accepts plain http urls
+	AllowHTTP bool
+	// AllowPrivate lets the requests reach the loopback, private and link-local addresses, meant
+	// for local development only
+	AllowPrivate bool
+	// Resolver defaults to net.DefaultResolver
+	Resolver Resolver
+}
+
+// Allows reports whether the policy lets requests reach the address
+func (p Policy) Allows(addr netip.Addr) bool {
+	if p.AllowPrivate {
+		return true
+	}
+
+	addr = addr.Unmap()
+	if !addr.IsValid() || addr.IsLoopback() || addr.IsPrivate() || addr.IsUnspecified() ||
+		addr.IsLinkLocalUnicast() || addr.IsLinkLocalMulticast() || addr.IsInterfaceLocalMulticast() || addr.IsMulticast() {
+		return false
+	}
+	for _, prefix := range blockedPrefixes {
+		if prefix.Contains(addr) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// CheckURL validates the scheme of the url and that every address its host resolves to is allowed.
+// The addresses are checked again when the client dials, the host may resolve differently by then
+func (p Policy) CheckURL(ctx context.Context, raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" || parsed.Hostname() == "" {
+		return errors.New("an absolute url is required")
+	}
+	switch {
+	case parsed.Scheme == "https":
+	case parsed.Scheme == "http" && p.AllowHTTP:
+	case p.AllowHTTP:
+		return errors.New("an http or https url is required")
+	default:
+		return errors.New("an https url is required")
+	}
+	if p.AllowPrivate {
+		return nil
+	}
+
+	host := parsed.Hostname()
+	if addr, err := netip.ParseAddr(host); err == nil {
+		if !p.Allows(addr) {
+			return ErrForbiddenDestination
+		}
+		return nil
+	}
+
+	addrs, err := p.resolver().LookupNetIP(ctx, "ip", host)
+	if err != nil || len(addrs) == 0 {
+		return fmt.Errorf("host %s cannot be resolved", host)
+	}
+	for _, addr := range addrs {
+		if !p.Allows(addr) {
+			return ErrForbiddenDestination
+		}
+	}
+
+	return nil
+}
+
+// Client returns an http client that refuses to connect to the addresses the policy does not allow,
+// whatever the host resolves to at dial time, and never follows redirects. Proxies are not used as
+// they would hide the destination address
+func (p Policy) Client() *http.Client {
+	dialer := &net.Dialer{
+		Timeout:   dialTimeout,
+		KeepAlive: keepAlive,
+		Control:   p.control,
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+
+	return &http.Client{
+		Transport: transport,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+}
+
+// control checks the address a connection is about to be opened to
+func (p Policy) control(_, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return ErrForbiddenDestination
+	}
+	if !p.Allows(addrPort.Addr()) {
+		return ErrForbiddenDestination
+	}
+
+	return nil
+}
+
+func (p Policy) resolver() Resolver {
+	if p.Resolver == nil {
+		return net.DefaultResolver
+	}
+	return p.Resolver
+}
@@ -0,0 +1,115 @@
+package egress
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type stubResolver map[string][]netip.Addr
+
+func (r stubResolver) LookupNetIP(_ context.Context, _, host string) ([]netip.Addr, error) {
+	addrs, ok := r[host]
+	if !ok {
+		return nil, errors.New("no such host")
+	}
+	return addrs, nil
+}
+
+func TestAllows(t *testing.T) {
+	tests := []struct {
+		addr    string
+		allowed bool
+	}{
+		{addr: "93.184.216.34", allowed: true},
+		{addr: "2606:2800:220:1:248:1893:25c8:1946", allowed: true},
+		{addr: "127.0.0.1"},
+		{addr: "::1"},
+		{addr: "10.12.0.4"},
+		{addr: "172.20.1.1"},
+		{addr: "192.168.1.10"},
+		{addr: "169.254.169.254"},
+		{addr: "100.100.100.200"},
+		{addr: "0.0.0.0"},
+		{addr: "::"},
+		{addr: "fd00:ec2::254"},
+		{addr: "fe80::1"},
+		{addr: "::ffff:127.0.0.1"},
+		{addr: "::ffff:169.254.169.254"},
+		{addr: "64:ff9b::a9fe:a9fe"},
+		{addr: "224.0.0.1"},
+		{addr: "255.255.255.255"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.addr, func(t *testing.T) {
+			assert.Equal(t, tt.allowed, Policy{}.Allows(netip.MustParseAddr(tt.addr)))
+		})
+	}
+
+	assert.True(t, Policy{AllowPrivate: true}.Allows(netip.MustParseAddr("127.0.0.1")), "Expected private addresses allowed")
+}
+
+func TestCheckURL(t *testing.T) {
+	resolver := stubResolver{
+		"hooks.example.com":        {netip.MustParseAddr("93.184.216.34")},
+		"metadata.google.internal": {netip.MustParseAddr("169.254.169.254")},
+		"split.example.com":        {netip.MustParseAddr("93.184.216.34"), netip.MustParseAddr("10.0.0.1")},
+	}
+
+	tests := []struct {
+		name     string
+		policy   Policy
+		url      string
+		errorMsg string
+	}{
+		{name: "Public host", url: "https://hooks.example.com/hooks"},
+		{name: "Public address", url: "https://93.184.216.34:8443/hooks"},
+		{name: "Plain http when allowed", policy: Policy{AllowHTTP: true}, url: "http://hooks.example.com/hooks"},
+		{name: "Private address when allowed", policy: Policy{AllowPrivate: true}, url: "https://127.0.0.1/hooks"},
+		{name: "Plain http", url: "http://hooks.example.com/hooks", errorMsg: "an https url is required"},
+		{name: "Unsupported scheme", policy: Policy{AllowHTTP: true}, url: "ftp://hooks.example.com", errorMsg: "an http or https url is required"},
+		{name: "Relative url", url: "/hooks", errorMsg: "an absolute url is required"},
+		{name: "Loopback address", url: "https://127.0.0.1/hooks", errorMsg: ErrForbiddenDestination.Error()},
+		{name: "Mapped loopback address", url: "https://[::ffff:127.0.0.1]/hooks", errorMsg: ErrForbiddenDestination.Error()},
+		{name: "Metadata host", url: "https://metadata.google.internal/computeMetadata/v1", errorMsg: ErrForbiddenDestination.Error()},
+		{name: "Any private address of the host", url: "https://split.example.com", errorMsg: ErrForbiddenDestination.Error()},
+		{name: "Unknown host", url: "https://unknown.example.com", errorMsg: "cannot be resolved"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.policy.Resolver = resolver
+
+			err := tt.policy.CheckURL(context.Background(), tt.url)
+			if tt.errorMsg == "" {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorContains(t, err, tt.errorMsg)
+			}
+		})
+	}
+}
+
+func TestClient(t *testing.T) {
+	target := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer target.Close()
+	redirect := httptest.NewServer(http.RedirectHandler(target.URL, http.StatusFound))
+	defer redirect.Close()
+
+	_, err := Policy{}.Client().Get(target.URL)
+	assert.ErrorIs(t, err, ErrForbiddenDestination, "Expected the loopback server refused at dial time")
+
+	res, err := Policy{AllowPrivate: true}.Client().Get(redirect.URL)
+	if assert.NoError(t, err) {
+		defer res.Body.Close() //nolint:errcheck
+		assert.Equal(t, http.StatusFound, res.StatusCode, "Expected the redirect not followed")
+	}
+}
@@ -13,3 +13,11 @@ const (
 	// DefaultSizeValue Default value given to `size` parameter if the given one is not valid
 	DefaultSizeValue = 10
 )
+
+// Pagination modes
+const (
+	// ModeOffset page and size based pagination, the default one
+	ModeOffset = "offset"
+	// ModeCursor keyset pagination driven by opaque next and prev tokens
+	ModeCursor = "cursor"
+)
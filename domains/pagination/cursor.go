@@ -0,0 +1,125 @@
+package pagination
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+
+	"github.com/uptrace/bun"
+)
+
+// ErrInvalidCursor the cursor token is malformed or does not match the requested ordering
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor position of a row in a keyset ordered listing. The token binds the sort column and
+// direction so it cannot be replayed against a different ordering.
+type Cursor struct {
+	SortBy   string `json:"s"`
+	SortDesc bool   `json:"d,omitempty"`
+	Value    string `json:"v"`
+	ID       string `json:"i"`
+	Backward bool   `json:"b,omitempty"`
+}
+
+// Encode returns the opaque token of the cursor
+func (c Cursor) Encode() string {
+	raw, _ := json.Marshal(c) //nolint:errchkjson
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// DecodeCursor parses an opaque token, it must have been issued for the given ordering
+func DecodeCursor(token, sortBy string, sortDesc bool) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(raw, &c); err != nil || c.ID == "" {
+		return nil, ErrInvalidCursor
+	}
+	if c.SortBy != sortBy || c.SortDesc != sortDesc {
+		return nil, ErrInvalidCursor
+	}
+
+	return &c, nil
+}
+
+// Keyset orders and bounds a select query by a sort column plus a unique id column as tie-breaker.
+// Both columns must be trusted identifiers, they are never taken from user input.
+type Keyset struct {
+	Column   string
+	IDColumn string
+	SortDesc bool
+	Size     int
+	Cursor   *Cursor
+}
+
+// Apply adds the keyset condition, ordering and limit to the query. One extra row is
+// requested to know whether there is another page.
+func (k Keyset) Apply(q *bun.SelectQuery) *bun.SelectQuery {
+	desc := k.SortDesc
+	if k.Cursor != nil && k.Cursor.Backward {
+		desc = !desc
+	}
+
+	if k.Cursor != nil {
+		op := ">"
+		if desc {
+			op = "<"
+		}
+		q = q.Where("(?, ?) "+op+" (?, ?)", bun.Ident(k.Column), bun.Ident(k.IDColumn), k.Cursor.Value, k.Cursor.ID)
+	}
+
+	direction := " ASC"
+	if desc {
+		direction = " DESC"
+	}
+
+	return q.
+		OrderExpr("?"+direction, bun.Ident(k.Column)).
+		OrderExpr("?"+direction, bun.Ident(k.IDColumn)).
+		Limit(k.Size + 1)
+}
+
+// Page trims the extra row fetched by Keyset.Apply, restores the requested order when paging
+// backward and builds the tokens of the surrounding pages. key returns the sort value and id of a row.
+func Page[T any](k Keyset, sortBy string, rows []T, key func(T) (string, string)) ([]T, string, string) {
+	hasMore := len(rows) > k.Size
+	if hasMore {
+		rows = rows[:k.Size]
+	}
+
+	backward := k.Cursor != nil && k.Cursor.Backward
+	if backward {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+
+	if len(rows) == 0 {
+		return rows, "", ""
+	}
+
+	cursor := func(row T, backward bool) string {
+		value, id := key(row)
+		return Cursor{SortBy: sortBy, SortDesc: k.SortDesc, Value: value, ID: id, Backward: backward}.Encode()
+	}
+
+	var next, prev string
+	if backward {
+		next = cursor(rows[len(rows)-1], false)
+		if hasMore {
+			prev = cursor(rows[0], true)
+		}
+	} else {
+		if hasMore {
+			next = cursor(rows[len(rows)-1], false)
+		}
+		if k.Cursor != nil {
+			prev = cursor(rows[0], true)
+		}
+	}
+
+	return rows, next, prev
+}
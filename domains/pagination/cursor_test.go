@@ -0,0 +1,125 @@
+package pagination
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+)
+
+type row struct {
+	ID    string
+	Value string
+}
+
+func rowKey(r row) (string, string) {
+	return r.Value, r.ID
+}
+
+func TestDecodeCursor(t *testing.T) {
+	token := Cursor{SortBy: "updated_at", SortDesc: true, Value: "2026-10-19T10:00:00Z", ID: "1"}.Encode()
+
+	tests := []struct {
+		name     string
+		token    string
+		sortBy   string
+		sortDesc bool
+		wantErr  bool
+	}{
+		{name: "Same ordering", token: token, sortBy: "updated_at", sortDesc: true},
+		{name: "Different column", token: token, sortBy: "created_at", sortDesc: true, wantErr: true},
+		{name: "Different direction", token: token, sortBy: "updated_at", wantErr: true},
+		{name: "Malformed token", token: "not a token", sortBy: "updated_at", sortDesc: true, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := DecodeCursor(tt.token, tt.sortBy, tt.sortDesc)
+			if tt.wantErr {
+				assert.ErrorIs(t, err, ErrInvalidCursor)
+				assert.Nil(t, c)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "1", c.ID)
+		})
+	}
+}
+
+func TestKeyset_Apply(t *testing.T) {
+	db := bun.NewDB(&sql.DB{}, pgdialect.New())
+
+	tests := []struct {
+		name   string
+		keyset Keyset
+		want   string
+	}{
+		{
+			name:   "First page",
+			keyset: Keyset{Column: "updated_at", IDColumn: "id", Size: 10},
+			want:   `SELECT * FROM "traffic" ORDER BY "updated_at" ASC, "id" ASC LIMIT 11`,
+		},
+		{
+			name:   "Next page descending",
+			keyset: Keyset{Column: "updated_at", IDColumn: "id", SortDesc: true, Size: 10, Cursor: &Cursor{Value: "v", ID: "1"}},
+			want:   `SELECT * FROM "traffic" WHERE (("updated_at", "id") < ('v', '1')) ORDER BY "updated_at" DESC, "id" DESC LIMIT 11`,
+		},
+		{
+			name:   "Previous page descending",
+			keyset: Keyset{Column: "updated_at", IDColumn: "id", SortDesc: true, Size: 10, Cursor: &Cursor{Value: "v", ID: "1", Backward: true}},
+			want:   `SELECT * FROM "traffic" WHERE (("updated_at", "id") > ('v', '1')) ORDER BY "updated_at" ASC, "id" ASC LIMIT 11`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q := tt.keyset.Apply(db.NewSelect().Table("traffic"))
+			assert.Equal(t, tt.want, q.String())
+		})
+	}
+}
+
+func TestPage(t *testing.T) {
+	rows := []row{{ID: "1", Value: "a"}, {ID: "2", Value: "b"}, {ID: "3", Value: "c"}}
+
+	t.Run("First page with more rows", func(t *testing.T) {
+		k := Keyset{Size: 2}
+		items, next, prev := Page(k, "value", append([]row(nil), rows...), rowKey)
+		assert.Equal(t, rows[:2], items)
+		assert.Empty(t, prev)
+
+		c, err := DecodeCursor(next, "value", false)
+		assert.NoError(t, err)
+		assert.Equal(t, "2", c.ID)
+		assert.False(t, c.Backward)
+	})
+
+	t.Run("Last page", func(t *testing.T) {
+		k := Keyset{Size: 2, Cursor: &Cursor{Value: "b", ID: "2"}}
+		items, next, prev := Page(k, "value", []row{rows[2]}, rowKey)
+		assert.Equal(t, []row{rows[2]}, items)
+		assert.Empty(t, next)
+
+		c, err := DecodeCursor(prev, "value", false)
+		assert.NoError(t, err)
+		assert.Equal(t, "3", c.ID)
+		assert.True(t, c.Backward)
+	})
+
+	t.Run("Backward page is restored to the requested order", func(t *testing.T) {
+		k := Keyset{Size: 1, Cursor: &Cursor{Value: "c", ID: "3", Backward: true}}
+		items, next, prev := Page(k, "value", []row{rows[1], rows[0]}, rowKey)
+		assert.Equal(t, []row{rows[1]}, items)
+		assert.NotEmpty(t, next)
+		assert.NotEmpty(t, prev)
+	})
+
+	t.Run("Empty page", func(t *testing.T) {
+		items, next, prev := Page(Keyset{Size: 2}, "value", []row{}, rowKey)
+		assert.Empty(t, items)
+		assert.Empty(t, next)
+		assert.Empty(t, prev)
+	})
+}
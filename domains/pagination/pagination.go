@@ -8,6 +8,10 @@ type Filter struct {
 	Offset   int    `json:"offset"`
 	SortBy   string `json:"sortBy"`
 	SortDesc bool   `json:"sortDesc"`
+	Sorts    []Sort `json:"sorts,omitempty"`
+	Mode     string `json:"mode,omitempty"`
+	Cursor   string `json:"cursor,omitempty"`
+	Count    *bool  `json:"count,omitempty"`
 }
 
 // SanitizePageFilter Handles the sanitization for the values in query parameters
@@ -16,6 +20,10 @@ func (f *Filter) SanitizePageFilter() error {
 	if f.Offset < MinimumFromValue {
 		f.Offset = MinimumFromValue
 	}
+	// Set default page
+	if f.Page < 1 {
+		f.Page = 1
+	}
 	// Set default size
 	if f.Size < MinimumSizeValue {
 		f.Size = DefaultSizeValue
@@ -26,6 +34,20 @@ func (f *Filter) SanitizePageFilter() error {
 	return nil
 }
 
+// IsCursor reports whether the filter asks for keyset pagination
+func (f *Filter) IsCursor() bool {
+	return f.Mode == ModeCursor
+}
+
+// ShouldCount reports whether the total must be counted, counting is on by default
+// for offset pagination and off for cursor pagination
+func (f *Filter) ShouldCount() bool {
+	if f.Count != nil {
+		return *f.Count
+	}
+	return !f.IsCursor()
+}
+
 // PaginatedRes generic model to paginate all API responses
 type PaginatedRes struct {
 	Data        interface{} `json:"data"`
@@ -33,3 +55,12 @@ type PaginatedRes struct {
 	Pages       int         `json:"pages"`
 	Total       int         `json:"total"`
 }
+
+// CursorPaginatedRes generic model to paginate API responses by cursor, total is only present when counted
+type CursorPaginatedRes struct {
+	Data  interface{} `json:"data"`
+	Next  string      `json:"next,omitempty"`
+	Prev  string      `json:"prev,omitempty"`
+	Size  int         `json:"size"`
+	Total *int        `json:"total,omitempty"`
+}
@@ -49,6 +49,14 @@ func TestPageFilter_SanitizePageFilter(t *testing.T) {
 				return assert.Equal(t, MaximumSizeValue, pf.Size)
 			},
 		},
+		{
+			name:    "Check if Page starts at the first page",
+			fields:  fields{},
+			wantErr: false,
+			asserts: func(t *testing.T, err error, pf *Filter) bool {
+				return assert.Equal(t, 1, pf.Page)
+			},
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -68,3 +76,10 @@ func TestPageFilter_SanitizePageFilter(t *testing.T) {
 		})
 	}
 }
+
+func TestFilter_ShouldCount(t *testing.T) {
+	count := true
+	assert.True(t, (&Filter{}).ShouldCount(), "Offset mode counts by default")
+	assert.False(t, (&Filter{Mode: ModeCursor}).ShouldCount(), "Cursor mode does not count by default")
+	assert.True(t, (&Filter{Mode: ModeCursor, Count: &count}).ShouldCount(), "Count can be requested")
+}
@@ -0,0 +1,299 @@
+package pagination
+
+import (
+	"errors"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmontesinos91/collector/domains/validation"
+	"github.com/jmontesinos91/terrors"
+	"github.com/uptrace/bun"
+)
+
+// FieldType value type of a filterable field
+type FieldType string
+
+// Field types
+const (
+	TypeString FieldType = "string"
+	TypeInt    FieldType = "int"
+	TypeBool   FieldType = "bool"
+	TypeTime   FieldType = "time"
+)
+
+// Operator filter comparison, used in query params as field[operator]=value
+type Operator string
+
+// Filter operators
+const (
+	OpEq     Operator = "eq"
+	OpNe     Operator = "ne"
+	OpGt     Operator = "gt"
+	OpGte    Operator = "gte"
+	OpLt     Operator = "lt"
+	OpLte    Operator = "lte"
+	OpLike   Operator = "like"
+	OpPrefix Operator = "prefix"
+	OpIn     Operator = "in"
+	OpCIDR   Operator = "cidr"
+)
+
+// MaximumInValues maximum number of values of an in list
+const MaximumInValues = 100
+
+// operatorSQL comparison template of each operator, the column and value are bound as arguments
+var operatorSQL = map[Operator]string{
+	OpEq:     "? = ?",
+	OpNe:     "? <> ?",
+	OpGt:     "? > ?",
+	OpGte:    "? >= ?",
+	OpLt:     "? < ?",
+	OpLte:    "? <= ?",
+	OpLike:   "? LIKE ?",
+	OpPrefix: "? LIKE ?",
+	OpIn:     "? IN (?)",
+	OpCIDR:   "?::inet <<= ?::cidr",
+}
+
+// Field declares how a resource field can be sorted and filtered. Column is a trusted identifier,
+// it is always quoted and never taken from the request. Templates replace the default SQL of an
+// operator, the column and the value are bound to their two placeholders.
+type Field struct {
+	Name      string
+	Column    string
+	Type      FieldType
+	Sortable  bool
+	Operators []Operator
+	Templates map[Operator]string
+}
+
+// Spec whitelist of the sortable and filterable fields of a resource
+type Spec struct {
+	fields map[string]Field
+}
+
+// Sort a validated sort key
+type Sort struct {
+	Field  string `json:"field"`
+	Desc   bool   `json:"desc,omitempty"`
+	column string
+}
+
+// Column returns the trusted column of the sort key
+func (s Sort) Column() string {
+	return s.column
+}
+
+// Condition a validated and typed filter, in lists hold a slice of values
+type Condition struct {
+	Field    string      `json:"field"`
+	Op       Operator    `json:"op"`
+	Value    interface{} `json:"value"`
+	column   string
+	template string
+}
+
+// NewSpec builds a spec given the resource fields
+func NewSpec(fields ...Field) *Spec {
+	s := &Spec{fields: make(map[string]Field, len(fields))}
+	for _, f := range fields {
+		s.fields[f.Name] = f
+	}
+	return s
+}
+
+// Field returns the declaration of a field by name
+func (s *Spec) Field(name string) (Field, bool) {
+	f, ok := s.fields[name]
+	return f, ok
+}
+
+// ParseSort parses a comma separated list of field[:asc|desc] items, items without
+// direction use sortDesc. An empty value returns no sort keys.
+func (s *Spec) ParseSort(sortBy string, sortDesc bool) ([]Sort, error) {
+	if strings.TrimSpace(sortBy) == "" {
+		return nil, nil
+	}
+
+	var sorts []Sort
+	seen := map[string]bool{}
+	for _, item := range strings.Split(sortBy, ",") {
+		name, direction, hasDirection := strings.Cut(strings.TrimSpace(item), ":")
+
+		f, ok := s.fields[name]
+		if !ok || !f.Sortable {
+			return nil, terrors.New(terrors.ErrBadRequest, "Invalid sortBy parameter, unknown field "+name, map[string]string{
+				"sortBy": "unknown field " + name,
+			})
+		}
+		if seen[name] {
+			return nil, terrors.New(terrors.ErrBadRequest, "Invalid sortBy parameter, repeated field "+name, map[string]string{
+				"sortBy": "repeated field " + name,
+			})
+		}
+		seen[name] = true
+
+		desc := sortDesc
+		if hasDirection {
+			switch strings.ToLower(direction) {
+			case "asc":
+				desc = false
+			case "desc":
+				desc = true
+			default:
+				return nil, terrors.New(terrors.ErrBadRequest, "Invalid sortBy parameter, unknown direction "+direction, map[string]string{
+					"sortBy": "unknown direction " + direction,
+				})
+			}
+		}
+
+		sorts = append(sorts, Sort{Field: name, Desc: desc, column: f.Column})
+	}
+
+	return sorts, nil
+}
+
+// ParseFilters parses the field[operator]=value query params. Params without brackets are
+// left to the caller, so only the bracketed ones are validated against the spec.
+// Conditions are sorted by param name so equal queries always build the same SQL.
+// Every invalid param is listed in the returned bad request error.
+func (s *Spec) ParseFilters(query url.Values) ([]Condition, error) {
+	keys := make([]string, 0, len(query))
+	for key := range query {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var conditions []Condition
+	errs := validation.Errors{}
+	for _, key := range keys {
+		values := query[key]
+		open := strings.IndexByte(key, '[')
+		if open < 0 || !strings.HasSuffix(key, "]") {
+			continue
+		}
+		name, op := key[:open], Operator(key[open+1:len(key)-1])
+
+		f, ok := s.fields[name]
+		if !ok || len(f.Operators) == 0 {
+			errs.Add(key, "unknown field "+name)
+			continue
+		}
+		if !f.allows(op) {
+			errs.Add(key, "unsupported operator "+string(op)+" for field "+name)
+			continue
+		}
+
+		for _, raw := range values {
+			value, err := f.parseOperand(op, raw)
+			if err != nil {
+				errs.Add(key, "invalid value "+raw)
+				continue
+			}
+			conditions = append(conditions, Condition{Field: name, Op: op, Value: value, column: f.Column, template: f.template(op)})
+		}
+	}
+
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	return conditions, nil
+}
+
+func (f Field) allows(op Operator) bool {
+	for _, allowed := range f.Operators {
+		if allowed == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (f Field) template(op Operator) string {
+	if template, ok := f.Templates[op]; ok {
+		return template
+	}
+	return operatorSQL[op]
+}
+
+func (f Field) parseOperand(op Operator, raw string) (interface{}, error) {
+	switch op {
+	case OpIn:
+		items := strings.Split(raw, ",")
+		if len(items) > MaximumInValues {
+			return nil, errors.New("too many values")
+		}
+		values := make([]interface{}, 0, len(items))
+		for _, item := range items {
+			value, err := f.parse(strings.TrimSpace(item))
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, nil
+	case OpCIDR:
+		_, network, err := net.ParseCIDR(raw)
+		if err != nil {
+			return nil, err
+		}
+		return network.String(), nil
+	default:
+		return f.parse(raw)
+	}
+}
+
+func (f Field) parse(raw string) (interface{}, error) {
+	switch f.Type {
+	case TypeInt:
+		return strconv.Atoi(raw)
+	case TypeBool:
+		return strconv.ParseBool(raw)
+	case TypeTime:
+		t, err := time.Parse(time.RFC3339, raw)
+		return t.UTC(), err
+	default:
+		return raw, nil
+	}
+}
+
+// ApplySort adds the sort keys to the query ordering
+func ApplySort(q *bun.SelectQuery, sorts []Sort) *bun.SelectQuery {
+	for _, s := range sorts {
+		if s.Desc {
+			q = q.OrderExpr("? DESC", bun.Ident(s.column))
+		} else {
+			q = q.OrderExpr("? ASC", bun.Ident(s.column))
+		}
+	}
+	return q
+}
+
+// ApplyConditions adds the conditions to the query, all of them must match
+func ApplyConditions(q *bun.SelectQuery, conditions []Condition) *bun.SelectQuery {
+	for _, c := range conditions {
+		value := c.Value
+		switch c.Op {
+		case OpLike:
+			value = "%" + EscapeLike(value.(string)) + "%"
+		case OpPrefix:
+			value = EscapeLike(value.(string)) + "%"
+		case OpIn:
+			value = bun.In(value)
+		}
+		q = q.Where(c.template, bun.Ident(c.column), value)
+	}
+	return q
+}
+
+// EscapeLike escapes the LIKE wildcards of a literal value
+func EscapeLike(value string) string {
+	return likeEscaper.Replace(value)
+}
+
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
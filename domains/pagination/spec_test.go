@@ -0,0 +1,159 @@
+package pagination
+
+import (
+	"database/sql"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/jmontesinos91/terrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+)
+
+var testSpec = NewSpec(
+	Field{Name: "name", Column: "name", Type: TypeString, Sortable: true, Operators: []Operator{OpEq, OpLike, OpPrefix, OpIn}},
+	Field{Name: "ip", Column: "ip", Type: TypeString, Operators: []Operator{OpCIDR},
+		Templates: map[Operator]string{OpCIDR: "safe_inet(?) <<= ?::cidr"}},
+	Field{Name: "alarm", Column: "isAlarm", Type: TypeBool, Sortable: true, Operators: []Operator{OpEq}},
+	Field{Name: "counter", Column: "counter", Type: TypeInt, Operators: []Operator{OpGte, OpLt}},
+	Field{Name: "created_at", Column: "created_at", Type: TypeTime, Sortable: true, Operators: []Operator{OpGte}},
+)
+
+func TestSpec_ParseSort(t *testing.T) {
+	tests := []struct {
+		name     string
+		sortBy   string
+		sortDesc bool
+		want     []Sort
+		errorMsg string
+	}{
+		{name: "Empty", sortBy: ""},
+		{
+			name:     "Single field uses sortDesc",
+			sortBy:   "name",
+			sortDesc: true,
+			want:     []Sort{{Field: "name", Desc: true, column: "name"}},
+		},
+		{
+			name:   "Multiple fields with directions",
+			sortBy: "created_at:desc, alarm:ASC",
+			want:   []Sort{{Field: "created_at", Desc: true, column: "created_at"}, {Field: "alarm", column: "isAlarm"}},
+		},
+		{name: "Unknown field", sortBy: "password", errorMsg: "unknown field password"},
+		{name: "Not sortable field", sortBy: "counter", errorMsg: "unknown field counter"},
+		{name: "Unknown direction", sortBy: "name:up", errorMsg: "unknown direction up"},
+		{name: "Repeated field", sortBy: "name,name:desc", errorMsg: "repeated field name"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sorts, err := testSpec.ParseSort(tt.sortBy, tt.sortDesc)
+			if tt.errorMsg != "" {
+				assert.True(t, terrors.Is(err, terrors.ErrBadRequest))
+				assert.Contains(t, err.Error(), tt.errorMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, sorts)
+		})
+	}
+}
+
+func TestSpec_ParseFilters(t *testing.T) {
+	created := time.Date(2026, 10, 19, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name     string
+		query    url.Values
+		want     []Condition
+		errorMsg string
+	}{
+		{
+			name:  "Params without operator are ignored",
+			query: url.Values{"name": {"x"}, "page": {"2"}},
+		},
+		{
+			name:  "Typed values",
+			query: url.Values{"counter[gte]": {"3"}, "alarm[eq]": {"true"}, "created_at[gte]": {"2026-10-19T00:00:00Z"}},
+			want: []Condition{
+				{Field: "alarm", Op: OpEq, Value: true, column: "isAlarm", template: "? = ?"},
+				{Field: "counter", Op: OpGte, Value: 3, column: "counter", template: "? >= ?"},
+				{Field: "created_at", Op: OpGte, Value: created, column: "created_at", template: "? >= ?"},
+			},
+		},
+		{
+			name:  "In list and CIDR",
+			query: url.Values{"name[in]": {"a, b"}, "ip[cidr]": {"10.0.0.7/8"}},
+			want: []Condition{
+				{Field: "name", Op: OpIn, Value: []interface{}{"a", "b"}, column: "name", template: "? IN (?)"},
+				{Field: "ip", Op: OpCIDR, Value: "10.0.0.0/8", column: "ip", template: "safe_inet(?) <<= ?::cidr"},
+			},
+		},
+		{name: "Invalid CIDR", query: url.Values{"ip[cidr]": {"10.0.0.300/8"}}, errorMsg: "Invalid ip[cidr] parameter"},
+		{name: "Unknown field", query: url.Values{"password[eq]": {"x"}}, errorMsg: "unknown field password"},
+		{name: "Unsupported operator", query: url.Values{"name[gt]": {"x"}}, errorMsg: "unsupported operator gt for field name"},
+		{name: "Invalid value", query: url.Values{"counter[lt]": {"many"}}, errorMsg: "Invalid counter[lt] parameter"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			conditions, err := testSpec.ParseFilters(tt.query)
+			if tt.errorMsg != "" {
+				assert.True(t, terrors.Is(err, terrors.ErrBadRequest))
+				assert.Contains(t, err.Error(), tt.errorMsg)
+				return
+			}
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, tt.want, conditions)
+		})
+	}
+}
+
+func TestSpec_ParseFiltersListsEveryField(t *testing.T) {
+	_, err := testSpec.ParseFilters(url.Values{
+		"password[eq]": {"x"},
+		"name[gt]":     {"x"},
+		"counter[lt]":  {"many"},
+		"counter[gte]": {"3"},
+	})
+
+	var terr *terrors.Error
+	if assert.ErrorAs(t, err, &terr) {
+		assert.True(t, terrors.Is(err, terrors.ErrBadRequest))
+		assert.Equal(t, "Invalid request parameters", terr.Message)
+		assert.Equal(t, map[string]string{
+			"password[eq]": "unknown field password",
+			"name[gt]":     "unsupported operator gt for field name",
+			"counter[lt]":  "invalid value many",
+		}, terr.Params)
+	}
+}
+
+func TestApplySortAndConditions(t *testing.T) {
+	db := bun.NewDB(&sql.DB{}, pgdialect.New())
+
+	sorts, err := testSpec.ParseSort("alarm:desc,name", false)
+	assert.NoError(t, err)
+	conditions, err := testSpec.ParseFilters(url.Values{"name[like]": {"50%_off"}})
+	assert.NoError(t, err)
+
+	q := db.NewSelect().Table("traffic")
+	q = ApplySort(ApplyConditions(q, conditions), sorts)
+
+	assert.Equal(t, `SELECT * FROM "traffic" WHERE ("name" LIKE '%50\%\_off%') ORDER BY "isAlarm" DESC, "name" ASC`, q.String())
+
+	for _, tc := range []struct {
+		query url.Values
+		want  string
+	}{
+		{query: url.Values{"name[prefix]": {"8615"}}, want: `SELECT * FROM "traffic" WHERE ("name" LIKE '8615%')`},
+		{query: url.Values{"name[in]": {"a,b"}}, want: `SELECT * FROM "traffic" WHERE ("name" IN ('a', 'b'))`},
+		{query: url.Values{"ip[cidr]": {"10.0.0.0/8"}}, want: `SELECT * FROM "traffic" WHERE (safe_inet("ip") <<= '10.0.0.0/8'::cidr)`},
+	} {
+		conditions, err := testSpec.ParseFilters(tc.query)
+		assert.NoError(t, err)
+		assert.Equal(t, tc.want, ApplyConditions(db.NewSelect().Table("traffic"), conditions).String())
+	}
+}
@@ -0,0 +1,4 @@
+package pubsub
+
+// DefaultBufferSize Events buffered per subscriber when the given buffer size is not valid
+const DefaultBufferSize = 64
@@ -0,0 +1,114 @@
+package pubsub
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Hub is an in-process, concurrency safe publish/subscribe hub. Every subscriber gets a bounded
+// buffer, events that do not fit are dropped for that subscriber and counted, so a slow consumer
+// never blocks the publishers nor the other subscribers
+type Hub[T any] struct {
+	mu     sync.RWMutex
+	subs   map[*Subscription[T]]struct{}
+	closed bool
+}
+
+// Subscription receives the published events accepted by its match function
+type Subscription[T any] struct {
+	hub     *Hub[T]
+	events  chan T
+	match   func(T) bool
+	dropped atomic.Int64
+	once    sync.Once
+}
+
+// NewHub creates an empty Hub
+func NewHub[T any]() *Hub[T] {
+	return &Hub[T]{
+		subs: make(map[*Subscription[T]]struct{}),
+	}
+}
+
+// Subscribe registers a subscriber, a nil match accepts every event and a buffer size lower
+// than one defaults to DefaultBufferSize. Subscribing to a closed hub returns a closed subscription
+func (h *Hub[T]) Subscribe(bufferSize int, match func(T) bool) *Subscription[T] {
+	if bufferSize < 1 {
+		bufferSize = DefaultBufferSize
+	}
+
+	sub := &Subscription[T]{
+		hub:    h,
+		events: make(chan T, bufferSize),
+		match:  match,
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		sub.once.Do(func() { close(sub.events) })
+		return sub
+	}
+	h.subs[sub] = struct{}{}
+
+	return sub
+}
+
+// Publish delivers the event to every matching subscriber without blocking
+func (h *Hub[T]) Publish(event T) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for sub := range h.subs {
+		if sub.match != nil && !sub.match(event) {
+			continue
+		}
+
+		select {
+		case sub.events <- event:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
+
+// Len returns the number of active subscriptions
+func (h *Hub[T]) Len() int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	return len(h.subs)
+}
+
+// Close closes every subscription, later subscriptions are closed right away
+func (h *Hub[T]) Close() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.closed = true
+	for sub := range h.subs {
+		delete(h.subs, sub)
+		sub.once.Do(func() { close(sub.events) })
+	}
+}
+
+// Events returns the channel the events are delivered to, it is closed when the subscription
+// or the hub are closed
+func (s *Subscription[T]) Events() <-chan T {
+	return s.events
+}
+
+// Dropped returns the number of events dropped since the previous call because the buffer was full
+func (s *Subscription[T]) Dropped() int64 {
+	return s.dropped.Swap(0)
+}
+
+// Close removes the subscription from its hub, it is safe to call more than once
+func (s *Subscription[T]) Close() {
+	s.hub.mu.Lock()
+	defer s.hub.mu.Unlock()
+
+	delete(s.hub.subs, s)
+	s.once.Do(func() { close(s.events) })
+}
@@ -0,0 +1,132 @@
+package pubsub
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHub(t *testing.T) {
+	tests := []struct {
+		name    string
+		run     func(h *Hub[int]) []*Subscription[int]
+		asserts func(*testing.T, *Hub[int], []*Subscription[int]) bool
+	}{
+		{
+			name: "Every subscriber receives the event",
+			run: func(h *Hub[int]) []*Subscription[int] {
+				subs := []*Subscription[int]{h.Subscribe(1, nil), h.Subscribe(1, nil)}
+				h.Publish(1)
+				return subs
+			},
+			asserts: func(t *testing.T, h *Hub[int], subs []*Subscription[int]) bool {
+				return assert.Equal(t, 1, <-subs[0].Events()) && assert.Equal(t, 1, <-subs[1].Events())
+			},
+		},
+		{
+			name: "Events not matching are skipped",
+			run: func(h *Hub[int]) []*Subscription[int] {
+				sub := h.Subscribe(2, func(v int) bool { return v%2 == 0 })
+				h.Publish(1)
+				h.Publish(2)
+				return []*Subscription[int]{sub}
+			},
+			asserts: func(t *testing.T, h *Hub[int], subs []*Subscription[int]) bool {
+				return assert.Equal(t, 2, <-subs[0].Events()) && assert.Len(t, subs[0].Events(), 0)
+			},
+		},
+		{
+			name: "Slow subscriber drops the events that do not fit",
+			run: func(h *Hub[int]) []*Subscription[int] {
+				slow := h.Subscribe(1, nil)
+				fast := h.Subscribe(3, nil)
+				h.Publish(1)
+				h.Publish(2)
+				h.Publish(3)
+				return []*Subscription[int]{slow, fast}
+			},
+			asserts: func(t *testing.T, h *Hub[int], subs []*Subscription[int]) bool {
+				return assert.Equal(t, int64(2), subs[0].Dropped()) &&
+					assert.Equal(t, int64(0), subs[0].Dropped()) &&
+					assert.Equal(t, 1, <-subs[0].Events()) &&
+					assert.Len(t, subs[1].Events(), 3) &&
+					assert.Equal(t, int64(0), subs[1].Dropped())
+			},
+		},
+		{
+			name: "Closed subscription stops receiving",
+			run: func(h *Hub[int]) []*Subscription[int] {
+				sub := h.Subscribe(1, nil)
+				sub.Close()
+				sub.Close()
+				h.Publish(1)
+				return []*Subscription[int]{sub}
+			},
+			asserts: func(t *testing.T, h *Hub[int], subs []*Subscription[int]) bool {
+				_, open := <-subs[0].Events()
+				return assert.False(t, open) && assert.Equal(t, 0, h.Len())
+			},
+		},
+		{
+			name: "Closing the hub closes every subscription",
+			run: func(h *Hub[int]) []*Subscription[int] {
+				sub := h.Subscribe(1, nil)
+				h.Close()
+				return []*Subscription[int]{sub, h.Subscribe(1, nil)}
+			},
+			asserts: func(t *testing.T, h *Hub[int], subs []*Subscription[int]) bool {
+				_, open := <-subs[0].Events()
+				_, openLate := <-subs[1].Events()
+				subs[0].Close()
+				return assert.False(t, open) && assert.False(t, openLate) && assert.Equal(t, 0, h.Len())
+			},
+		},
+		{
+			name: "Invalid buffer size defaults",
+			run: func(h *Hub[int]) []*Subscription[int] {
+				return []*Subscription[int]{h.Subscribe(0, nil)}
+			},
+			asserts: func(t *testing.T, h *Hub[int], subs []*Subscription[int]) bool {
+				return assert.Equal(t, DefaultBufferSize, cap(subs[0].Events()))
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := NewHub[int]()
+			subs := tt.run(h)
+
+			if !tt.asserts(t, h, subs) {
+				t.Errorf("Assert error on test = '%v'", tt.name)
+			}
+		})
+	}
+}
+
+func TestHubConcurrentUse(t *testing.T) {
+	h := NewHub[int]()
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(2)
+		go func() {
+			defer wg.Done()
+			sub := h.Subscribe(4, nil)
+			defer sub.Close()
+			for j := 0; j < 100; j++ {
+				sub.Dropped()
+			}
+		}()
+		go func(v int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				h.Publish(v)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	assert.Equal(t, 0, h.Len())
+}
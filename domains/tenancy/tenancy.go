@@ -0,0 +1,60 @@
+package tenancy
+
+import "slices"
+
+// Scope tenants whose data a caller can reach, the zero value reaches nothing
+type Scope struct {
+	// All reaches every tenant, including the rows of devices without a tenant
+	All     bool
+	Tenants []int
+}
+
+// Unrestricted returns a Scope that reaches every tenant
+func Unrestricted() Scope {
+	return Scope{All: true}
+}
+
+// Of returns a Scope restricted to the given tenants
+func Of(tenants ...int) Scope {
+	return Scope{Tenants: normalize(tenants)}
+}
+
+// Allows reports whether the data of the given tenant is reachable, zero is the tenant
+// of unregistered devices and only an unrestricted scope reaches it
+func (s Scope) Allows(tenantID int) bool {
+	if s.All {
+		return true
+	}
+	return tenantID != 0 && slices.Contains(s.Tenants, tenantID)
+}
+
+// Empty reports whether the scope reaches no tenant at all
+func (s Scope) Empty() bool {
+	return !s.All && len(s.Tenants) == 0
+}
+
+// Policy resolves the scope of a caller from its claims
+type Policy struct {
+	// SuperAdminRoles roles that reach every tenant regardless of their claimed tenants
+	SuperAdminRoles []string
+}
+
+// Scope returns the scope of a caller with the given role and tenants
+func (p Policy) Scope(role string, tenants []int) Scope {
+	if role != "" && slices.Contains(p.SuperAdminRoles, role) {
+		return Unrestricted()
+	}
+	return Of(tenants...)
+}
+
+// normalize sorts the tenants and drops duplicates and the zero tenant
+func normalize(tenants []int) []int {
+	out := make([]int, 0, len(tenants))
+	for _, t := range tenants {
+		if t != 0 {
+			out = append(out, t)
+		}
+	}
+	slices.Sort(out)
+	return slices.Compact(out)
+}
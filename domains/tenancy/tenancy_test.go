@@ -0,0 +1,72 @@
+package tenancy
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPolicyScope(t *testing.T) {
+	policy := Policy{SuperAdminRoles: []string{"SuperAdmin"}}
+
+	tests := []struct {
+		name     string
+		role     string
+		tenants  []int
+		expected Scope
+	}{
+		{
+			name:     "Super admin reaches every tenant",
+			role:     "SuperAdmin",
+			tenants:  []int{1},
+			expected: Scope{All: true},
+		},
+		{
+			name:     "Regular role is restricted to its tenants",
+			role:     "Admin",
+			tenants:  []int{3, 1, 3, 0},
+			expected: Scope{Tenants: []int{1, 3}},
+		},
+		{
+			name:     "Empty role is never a super admin",
+			role:     "",
+			tenants:  nil,
+			expected: Scope{Tenants: []int{}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, policy.Scope(tc.role, tc.tenants))
+		})
+	}
+}
+
+func TestScopeAllows(t *testing.T) {
+	tests := []struct {
+		name     string
+		scope    Scope
+		tenantID int
+		expected bool
+	}{
+		{name: "Unrestricted reaches any tenant", scope: Unrestricted(), tenantID: 9, expected: true},
+		{name: "Unrestricted reaches unregistered devices", scope: Unrestricted(), tenantID: 0, expected: true},
+		{name: "Restricted reaches its tenants", scope: Of(1, 2), tenantID: 2, expected: true},
+		{name: "Restricted does not reach other tenants", scope: Of(1, 2), tenantID: 3, expected: false},
+		{name: "Restricted does not reach unregistered devices", scope: Of(1, 2), tenantID: 0, expected: false},
+		{name: "Zero value reaches nothing", scope: Scope{}, tenantID: 1, expected: false},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, tc.scope.Allows(tc.tenantID))
+		})
+	}
+}
+
+func TestScopeEmpty(t *testing.T) {
+	assert.True(t, Scope{}.Empty())
+	assert.True(t, Of(0).Empty())
+	assert.False(t, Of(1).Empty())
+	assert.False(t, Unrestricted().Empty())
+}
@@ -0,0 +1,133 @@
+package validation
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/jmontesinos91/terrors"
+)
+
+// Errors offending fields of a request keyed by field name, each one with the reason it was rejected.
+// Every field is reported so callers can fix a request in a single round trip.
+type Errors map[string]string
+
+// Add records a rejected field, the first reason given for a field is kept
+func (e Errors) Add(field, reason string) {
+	if _, ok := e[field]; !ok {
+		e[field] = reason
+	}
+}
+
+// Merge records the fields of a bad request error, it returns the error back when it carries no fields
+// or is of any other kind so the caller can propagate it
+func (e Errors) Merge(err error) error {
+	var terr *terrors.Error
+	if !errors.As(err, &terr) || !terr.PrefixMatches(terrors.ErrBadRequest) || len(terr.Params) == 0 {
+		return err
+	}
+
+	for field, reason := range terr.Params {
+		e.Add(field, reason)
+	}
+	return nil
+}
+
+// Fields returns the names of the offending fields sorted
+func (e Errors) Fields() []string {
+	fields := make([]string, 0, len(e))
+	for field := range e {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// Err returns the bad request error listing the offending fields, nil when there are none.
+// A single field is described in the message, e.g. "Invalid alarm parameter, must be a boolean".
+func (e Errors) Err() error {
+	if len(e) == 0 {
+		return nil
+	}
+
+	message := "Invalid request parameters"
+	if len(e) == 1 {
+		field := e.Fields()[0]
+		message = "Invalid " + field + " parameter, " + e[field]
+	}
+
+	params := make(map[string]string, len(e))
+	for field, reason := range e {
+		params[field] = reason
+	}
+	return terrors.New(terrors.ErrBadRequest, message, params)
+}
+
+// Field returns the bad request error of a single offending field
+func Field(field, reason string) error {
+	return Errors{field: reason}.Err()
+}
+
+// New returns a validator reporting fields by their json name
+func New() *validator.Validate {
+	validate := validator.New()
+	validate.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		if name == "" {
+			return field.Name
+		}
+		return name
+	})
+	return validate
+}
+
+// Struct validates the struct tags of a request, the failed rules are reported as offending fields
+func Struct(validate *validator.Validate, request any) error {
+	err := validate.Struct(request)
+	if err == nil {
+		return nil
+	}
+
+	var failures validator.ValidationErrors
+	if !errors.As(err, &failures) {
+		return terrors.New(terrors.ErrBadRequest, "Invalid request body", map[string]string{})
+	}
+
+	errs := Errors{}
+	for _, failure := range failures {
+		errs.Add(fieldName(failure.Namespace()), reason(failure))
+	}
+	return errs.Err()
+}
+
+// fieldName drops the struct name from a validator namespace, e.g. BulkBody.ids[0] is ids[0]
+func fieldName(namespace string) string {
+	if _, field, ok := strings.Cut(namespace, "."); ok {
+		return field
+	}
+	return namespace
+}
+
+func reason(failure validator.FieldError) string {
+	switch failure.Tag() {
+	case "required":
+		return "is required"
+	case "oneof":
+		return "must be one of: " + failure.Param()
+	case "uuid", "uuid4":
+		return "must be a UUID"
+	case "url", "http_url":
+		return "must be a URL"
+	case "min":
+		return "must be at least " + failure.Param()
+	case "max":
+		return "must be at most " + failure.Param()
+	default:
+		return "failed the " + failure.Tag() + " rule"
+	}
+}
@@ -0,0 +1,118 @@
+package validation
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jmontesinos91/terrors"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrors(t *testing.T) {
+	tests := []struct {
+		name     string
+		errs     Errors
+		message  string
+		expected map[string]string
+	}{
+		{
+			name: "No offending fields",
+			errs: Errors{},
+		},
+		{
+			name:     "Single field is described in the message",
+			errs:     Errors{"alarm": "must be a boolean"},
+			message:  "Invalid alarm parameter, must be a boolean",
+			expected: map[string]string{"alarm": "must be a boolean"},
+		},
+		{
+			name:     "Every field is listed",
+			errs:     Errors{"alarm": "must be a boolean", "page": "must be an integer"},
+			message:  "Invalid request parameters",
+			expected: map[string]string{"alarm": "must be a boolean", "page": "must be an integer"},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.errs.Err()
+			if tc.message == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var terr *terrors.Error
+			assert.True(t, errors.As(err, &terr))
+			assert.True(t, terr.PrefixMatches(terrors.ErrBadRequest))
+			assert.Equal(t, tc.message, terr.Message)
+			assert.Equal(t, tc.expected, terr.Params)
+		})
+	}
+}
+
+func TestErrorsAdd(t *testing.T) {
+	errs := Errors{}
+	errs.Add("page", "must be an integer")
+	errs.Add("page", "must be positive")
+	errs.Add("alarm", "must be a boolean")
+
+	assert.Equal(t, "must be an integer", errs["page"])
+	assert.Equal(t, []string{"alarm", "page"}, errs.Fields())
+}
+
+func TestErrorsMerge(t *testing.T) {
+	errs := Errors{"alarm": "must be a boolean"}
+
+	assert.NoError(t, errs.Merge(nil))
+	assert.NoError(t, errs.Merge(Field("sortBy", "unknown field password")))
+	assert.Equal(t, Errors{"alarm": "must be a boolean", "sortBy": "unknown field password"}, errs)
+
+	internal := terrors.InternalService("internal_error", "Failed", map[string]string{})
+	assert.Equal(t, internal, errs.Merge(internal))
+
+	withoutFields := terrors.New(terrors.ErrBadRequest, "Invalid request body", map[string]string{})
+	assert.Equal(t, withoutFields, errs.Merge(withoutFields))
+}
+
+func TestStruct(t *testing.T) {
+	type request struct {
+		Operation string   `json:"operation" validate:"oneof=delete reset-counter"`
+		IDs       []string `json:"ids" validate:"dive,uuid"`
+		Name      string   `json:"name,omitempty" validate:"required"`
+	}
+
+	tests := []struct {
+		name     string
+		request  request
+		expected map[string]string
+	}{
+		{
+			name:    "Valid request",
+			request: request{Operation: "delete", IDs: []string{"5b1f0c7e-3f0a-4d59-9b8e-2b8f8d0c6a11"}, Name: "x"},
+		},
+		{
+			name:    "Fields are reported by their json name",
+			request: request{Operation: "truncate", IDs: []string{"1"}},
+			expected: map[string]string{
+				"operation": "must be one of: delete reset-counter",
+				"ids[0]":    "must be a UUID",
+				"name":      "is required",
+			},
+		},
+	}
+
+	validate := New()
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Struct(validate, tc.request)
+			if tc.expected == nil {
+				assert.NoError(t, err)
+				return
+			}
+
+			var terr *terrors.Error
+			assert.True(t, errors.As(err, &terr))
+			assert.Equal(t, tc.expected, terr.Params)
+		})
+	}
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmontesinos91/collector/internal/services/audit"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/jmontesinos91/terrors"
+	"github.com/sirupsen/logrus"
+)
+
+// AuditController controller struct
+type AuditController struct {
+	log      *logger.ContextLogger
+	auditSvc audit.IService
+}
+
+// NewAuditController Constructor
+func NewAuditController(server *HTTPServer, as audit.IService, sts sts.ISTSClient) *AuditController {
+	ac := &AuditController{
+		log:      server.Logger,
+		auditSvc: as,
+	}
+
+	// Endpoint secure
+	server.Router.Group(func(r chi.Router) {
+		r.Use(JwtVerifyMiddleware(server.Logger, sts))
+		r.Get("/v1/audit", ac.handleRetrieve)
+	})
+
+	return ac
+}
+
+func (ac *AuditController) handleRetrieve(w http.ResponseWriter, r *http.Request) {
+	ac.log.Log(logrus.InfoLevel, "handleRetrieve", "Incoming request to handleRetrieve")
+
+	filters, err := audit.ParseFilterRequest(r)
+	if err != nil {
+		ac.log.Error(logrus.ErrorLevel, "handleRetrieve", "Invalid request parameters", err)
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	data, err := ac.auditSvc.HandleRetrieve(r.Context(), filters)
+	if err != nil {
+		ac.log.Error(logrus.ErrorLevel, "handleRetrieve", "Failed to retrieve audit entries", err)
+		RenderError(r.Context(), w, terrors.InternalService("internal_error", "Failed to retrieve audit entries", map[string]string{}))
+		return
+	}
+
+	RenderJSON(r.Context(), w, http.StatusOK, data)
+}
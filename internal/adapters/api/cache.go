@@ -0,0 +1,53 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmontesinos91/collector/internal/services/devicecache"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/sirupsen/logrus"
+)
+
+// CacheController controller struct
+type CacheController struct {
+	log      *logger.ContextLogger
+	cacheSvc devicecache.IService
+}
+
+// NewCacheController Constructor
+func NewCacheController(server *HTTPServer, cs devicecache.IService, sts sts.ISTSClient) *CacheController {
+	cc := &CacheController{
+		log:      server.Logger,
+		cacheSvc: cs,
+	}
+
+	// Endpoint secure
+	server.Router.Group(func(r chi.Router) {
+		r.Use(JwtVerifyMiddleware(server.Logger, sts))
+		r.Delete("/v1/admin/cache", cc.handleInvalidate)
+	})
+
+	return cc
+}
+
+func (cc *CacheController) handleInvalidate(w http.ResponseWriter, r *http.Request) {
+	cc.log.Log(logrus.InfoLevel, "handleInvalidate", "Incoming request to handleInvalidate")
+
+	request, err := devicecache.ParseInvalidateRequest(r)
+	if err != nil {
+		cc.log.Error(logrus.ErrorLevel, "handleInvalidate", "Invalid request parameters", err)
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	data, err := cc.cacheSvc.HandleInvalidate(r.Context(), request)
+	if err != nil {
+		cc.log.Error(logrus.ErrorLevel, "handleInvalidate", "Failed to invalidate cache", err)
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	RenderJSON(r.Context(), w, http.StatusOK, data)
+}
@@ -2,10 +2,12 @@ package api
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"time"
 
 	"github.com/go-playground/validator/v10"
+	"github.com/jmontesinos91/collector/internal/services/ack"
 	"github.com/jmontesinos91/collector/internal/services/collector"
 	scollector "github.com/jmontesinos91/collector/internal/services/collector"
 	"github.com/jmontesinos91/ologs/logger"
@@ -18,15 +20,18 @@ type CollectorController struct {
 	log         *logger.ContextLogger
 	validate    *validator.Validate
 	collectorSv collector.IService
+	acks        ack.IService
 	stsClient   sts.ISTSClient
 }
 
-// NewCollectorController Constructor
-func NewCollectorController(server *HTTPServer, validator *validator.Validate, ss collector.IService, sts sts.ISTSClient) *CollectorController {
+// NewCollectorController Constructor, the frames are answered with the ack encoder of the profile
+// of each device
+func NewCollectorController(server *HTTPServer, validator *validator.Validate, ss collector.IService, as ack.IService, sts sts.ISTSClient) *CollectorController {
 	sc := &CollectorController{
 		log:         server.Logger,
 		validate:    validator,
 		collectorSv: ss,
+		acks:        as,
 		stsClient:   sts,
 	}
 
@@ -44,20 +49,7 @@ func (sc *CollectorController) handleReceive(w http.ResponseWriter, r *http.Requ
 	ctx, cancel := context.WithTimeout(r.Context(), 90*time.Second)
 	defer cancel()
 
-	payload := &scollector.Payload{}
-	err := payload.ParsePayload(r)
-	if err != nil {
-		RenderError(r.Context(), w, err)
-		return
-	}
-
-	err = sc.collectorSv.Collector(ctx, payload)
-	if err != nil {
-		RenderError(r.Context(), w, err)
-		return
-	}
-
-	RenderJSON(r.Context(), w, http.StatusOK, nil)
+	sc.collect(ctx, w, r)
 }
 
 func (sc *CollectorController) handleCollector(w http.ResponseWriter, r *http.Request) {
@@ -67,17 +59,55 @@ func (sc *CollectorController) handleCollector(w http.ResponseWriter, r *http.Re
 	ctx, cancel := context.WithTimeout(r.Context(), 90*time.Second)
 	defer cancel()
 
+	sc.collect(ctx, w, r)
+}
+
+// collect processes the frame and answers it once, with the success or failure ack of the profile
+// of the device. Frames too malformed to carry a device get the default profile
+func (sc *CollectorController) collect(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 	payload := &scollector.Payload{}
 	err := payload.ParsePayload(r)
+
+	frameAck := ack.Ack{
+		Device: payload.Device(),
+		Seq:    r.URL.Query().Get("seq"),
+	}
+	encoder := sc.acks.Resolve(frameAck.Device)
+
+	if err != nil {
+		sc.renderFailure(w, r, encoder, frameAck, err)
+		return
+	}
+
+	reply, err := sc.collectorSv.Collector(ctx, payload)
 	if err != nil {
-		RenderError(r.Context(), w, err)
+		sc.renderFailure(w, r, encoder, frameAck, err)
 		return
 	}
 
-	err = sc.collectorSv.Collector(ctx, payload)
+	frameAck.Commands = reply.Commands
+	res, err := encoder.Success(frameAck)
 	if err != nil {
-		RenderError(r.Context(), w, err)
+		sc.log.Error(logrus.ErrorLevel, "collect", "Failed to encode the frame ack", err)
+		sc.renderFailure(w, r, encoder, frameAck, err)
+		return
+	}
+
+	RenderBody(r.Context(), w, res.Status, res.ContentType, res.Body)
+}
+
+// renderFailure writes the failure ack of the profile given the standard error response
+func (sc *CollectorController) renderFailure(w http.ResponseWriter, r *http.Request, encoder ack.Encoder, frameAck ack.Ack, err error) {
+	body, errM := json.Marshal(ToErrorResponse(err))
+	if errM != nil {
+		http.Error(w, errM.Error(), http.StatusInternalServerError)
+		return
 	}
 
-	RenderJSON(r.Context(), w, http.StatusOK, nil)
+	res := encoder.Failure(frameAck, ack.Response{
+		Status:      ErrorStatus(err),
+		ContentType: "application/json",
+		Body:        body,
+	})
+	RenderBody(r.Context(), w, res.Status, res.ContentType, res.Body)
 }
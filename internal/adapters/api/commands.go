@@ -0,0 +1,91 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jmontesinos91/collector/internal/services/command"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/sirupsen/logrus"
+)
+
+// CommandController controller struct
+type CommandController struct {
+	log        *logger.ContextLogger
+	validate   *validator.Validate
+	commandSvc command.IService
+}
+
+// NewCommandController Constructor
+func NewCommandController(server *HTTPServer, validator *validator.Validate, cs command.IService, sts sts.ISTSClient) *CommandController {
+	cc := &CommandController{
+		log:        server.Logger,
+		validate:   validator,
+		commandSvc: cs,
+	}
+
+	// Endpoint secure
+	server.Router.Group(func(r chi.Router) {
+		r.Use(JwtVerifyMiddleware(server.Logger, sts))
+		r.Post("/v1/devices/{imei}/commands", cc.handleCreate)
+		r.Get("/v1/devices/{imei}/commands", cc.handleRetrieve)
+		r.Get("/v1/devices/{imei}/commands/{id}", cc.handleFind)
+	})
+
+	return cc
+}
+
+func (cc *CommandController) handleCreate(w http.ResponseWriter, r *http.Request) {
+	cc.log.Log(logrus.InfoLevel, "handleCreate", "Incoming request to handleCreate")
+
+	request, err := command.ParseCreateRequest(r, cc.validate)
+	if err != nil {
+		cc.log.Error(logrus.ErrorLevel, "handleCreate", "Invalid request parameters", err)
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	data, err := cc.commandSvc.HandleCreate(r.Context(), chi.URLParam(r, "imei"), request)
+	if err != nil {
+		cc.log.Error(logrus.ErrorLevel, "handleCreate", "Failed to queue device command", err)
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	RenderJSON(r.Context(), w, http.StatusCreated, data)
+}
+
+func (cc *CommandController) handleRetrieve(w http.ResponseWriter, r *http.Request) {
+	cc.log.Log(logrus.InfoLevel, "handleRetrieve", "Incoming request to handleRetrieve")
+
+	filter, err := command.ParseFilterRequest(r)
+	if err != nil {
+		cc.log.Error(logrus.ErrorLevel, "handleRetrieve", "Invalid request parameters", err)
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	data, err := cc.commandSvc.HandleRetrieve(r.Context(), chi.URLParam(r, "imei"), filter)
+	if err != nil {
+		cc.log.Error(logrus.ErrorLevel, "handleRetrieve", "Failed to retrieve device commands", err)
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	RenderJSON(r.Context(), w, http.StatusOK, data)
+}
+
+func (cc *CommandController) handleFind(w http.ResponseWriter, r *http.Request) {
+	cc.log.Log(logrus.InfoLevel, "handleFind", "Incoming request to handleFind")
+
+	data, err := cc.commandSvc.HandleFind(r.Context(), chi.URLParam(r, "imei"), chi.URLParam(r, "id"))
+	if err != nil {
+		cc.log.Error(logrus.ErrorLevel, "handleFind", "Failed to find device command", err)
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	RenderJSON(r.Context(), w, http.StatusOK, data)
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmontesinos91/collector/internal/services/exportjob"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/sirupsen/logrus"
+)
+
+// ExportJobController controller struct
+type ExportJobController struct {
+	log          *logger.ContextLogger
+	exportJobSvc exportjob.IService
+}
+
+// NewExportJobController Constructor
+func NewExportJobController(server *HTTPServer, es exportjob.IService, sts sts.ISTSClient) *ExportJobController {
+	ec := &ExportJobController{
+		log:          server.Logger,
+		exportJobSvc: es,
+	}
+
+	// Endpoint secure
+	server.Router.Group(func(r chi.Router) {
+		r.Use(JwtVerifyMiddleware(server.Logger, sts))
+		r.Post("/v1/traffic/exports", ec.handleCreate)
+		r.Get("/v1/traffic/exports", ec.handleFind)
+		r.Get("/v1/traffic/exports/{id}", ec.handleRetrieve)
+		r.Get("/v1/traffic/exports/{id}/download", ec.handleDownload)
+	})
+
+	return ec
+}
+
+func (ec *ExportJobController) handleCreate(w http.ResponseWriter, r *http.Request) {
+	ec.log.Log(logrus.InfoLevel, "handleCreate", "Incoming request to handleCreate")
+
+	request, err := exportjob.ParseCreateRequest(r)
+	if err != nil {
+		ec.log.Error(logrus.ErrorLevel, "handleCreate", "Invalid request parameters", err)
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	data, err := ec.exportJobSvc.HandleCreate(r.Context(), request)
+	if err != nil {
+		ec.log.Error(logrus.ErrorLevel, "handleCreate", "Failed to create export job", err)
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	RenderJSON(r.Context(), w, http.StatusAccepted, data)
+}
+
+func (ec *ExportJobController) handleFind(w http.ResponseWriter, r *http.Request) {
+	ec.log.Log(logrus.InfoLevel, "handleFind", "Incoming request to handleFind")
+
+	data, err := ec.exportJobSvc.HandleFind(r.Context())
+	if err != nil {
+		ec.log.Error(logrus.ErrorLevel, "handleFind", "Failed to retrieve export jobs", err)
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	RenderJSON(r.Context(), w, http.StatusOK, data)
+}
+
+func (ec *ExportJobController) handleRetrieve(w http.ResponseWriter, r *http.Request) {
+	ec.log.Log(logrus.InfoLevel, "handleRetrieve", "Incoming request to handleRetrieve")
+
+	data, err := ec.exportJobSvc.HandleRetrieve(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		ec.log.Error(logrus.ErrorLevel, "handleRetrieve", "Failed to retrieve export job", err)
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	RenderJSON(r.Context(), w, http.StatusOK, data)
+}
+
+func (ec *ExportJobController) handleDownload(w http.ResponseWriter, r *http.Request) {
+	ec.log.Log(logrus.InfoLevel, "handleDownload", "Incoming request to handleDownload")
+
+	download, err := ec.exportJobSvc.HandleDownload(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		ec.log.Error(logrus.ErrorLevel, "handleDownload", "Failed to retrieve export file", err)
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	err = RenderAttachment(r.Context(), w, download.Name, download.ContentType, download.Write)
+	if err != nil {
+		ec.log.Error(logrus.ErrorLevel, "handleDownload", "Failed to write export file", err)
+	}
+}
@@ -10,13 +10,15 @@ import (
 
 // HealthController Handles all health related routes
 type HealthController struct {
-	log *logger.ContextLogger
+	log    *logger.ContextLogger
+	server *HTTPServer
 }
 
 // NewHealthController Creates a new instance
 func NewHealthController(server *HTTPServer) *HealthController {
 	hc := &HealthController{
-		log: server.Logger,
+		log:    server.Logger,
+		server: server,
 	}
 
 	// Loads routes
@@ -31,6 +33,12 @@ func (hc *HealthController) handleLivenessCheck(w http.ResponseWriter, r *http.R
 	RenderJSON(r.Context(), w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
+// handleReadinessCheck fails while the server is drained on shutdown, so no new requests are routed to it
 func (hc *HealthController) handleReadinessCheck(w http.ResponseWriter, r *http.Request) {
+	if hc.server.Draining() {
+		RenderJSON(r.Context(), w, http.StatusServiceUnavailable, map[string]string{"status": "draining"})
+		return
+	}
+
 	RenderJSON(r.Context(), w, http.StatusOK, map[string]string{"status": "ok"})
 }
@@ -0,0 +1,119 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jmontesinos91/collector/internal/services/livefeed"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/sirupsen/logrus"
+	"golang.org/x/net/websocket"
+)
+
+// Live feed routes, they stay open while the client listens so they are exempt from the request timeout
+const (
+	liveFeedStreamRoute    = "/v1/traffic/stream"
+	liveFeedWebSocketRoute = "/v1/traffic/stream/ws"
+)
+
+// LiveFeedController controller struct
+type LiveFeedController struct {
+	log     *logger.ContextLogger
+	feedSvc livefeed.IService
+}
+
+// NewLiveFeedController Constructor
+func NewLiveFeedController(server *HTTPServer, fs livefeed.IService, sts sts.ISTSClient) *LiveFeedController {
+	lc := &LiveFeedController{
+		log:     server.Logger,
+		feedSvc: fs,
+	}
+
+	// Endpoint secure, browsers can not set headers on EventSource and WebSocket connections
+	// so the token is also accepted as a query parameter
+	server.Router.Group(func(r chi.Router) {
+		r.Use(AccessTokenFromQuery)
+		r.Use(JwtVerifyMiddleware(server.Logger, sts))
+		r.Get(liveFeedStreamRoute, lc.handleStream)
+		r.Get(liveFeedWebSocketRoute, lc.handleWebSocket)
+	})
+
+	return lc
+}
+
+func (lc *LiveFeedController) handleStream(w http.ResponseWriter, r *http.Request) {
+	lc.log.Log(logrus.InfoLevel, "handleStream", "Incoming request to handleStream")
+
+	filter, err := livefeed.ParseFilterRequest(r)
+	if err != nil {
+		lc.log.Error(logrus.ErrorLevel, "handleStream", "Invalid request parameters", err)
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	// Headers
+	w.Header().Set(middleware.RequestIDHeader, middleware.GetReqID(r.Context()))
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	rc := http.NewResponseController(w)
+	if err := rc.Flush(); err != nil {
+		lc.log.Error(logrus.ErrorLevel, "handleStream", "Streaming is not supported by the connection", err)
+		return
+	}
+
+	_ = lc.feedSvc.Stream(r.Context(), filter, func(event livefeed.Event, deadline time.Time) error {
+		data, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+
+		_ = rc.SetWriteDeadline(deadline)
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event.Type, data); err != nil {
+			return err
+		}
+		return rc.Flush()
+	})
+}
+
+func (lc *LiveFeedController) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	lc.log.Log(logrus.InfoLevel, "handleWebSocket", "Incoming request to handleWebSocket")
+
+	filter, err := livefeed.ParseFilterRequest(r)
+	if err != nil {
+		lc.log.Error(logrus.ErrorLevel, "handleWebSocket", "Invalid request parameters", err)
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	server := websocket.Server{
+		Handler: func(ws *websocket.Conn) {
+			defer ws.Close() //nolint:errcheck
+
+			// The connection is hijacked, reading is the only way to notice the client went away.
+			// Clients only listen, anything they send is discarded
+			ctx, cancel := context.WithCancel(r.Context())
+			defer cancel()
+			go func() {
+				defer cancel()
+				_, _ = io.Copy(io.Discard, ws)
+			}()
+
+			_ = lc.feedSvc.Stream(ctx, filter, func(event livefeed.Event, deadline time.Time) error {
+				_ = ws.SetWriteDeadline(deadline)
+				return websocket.JSON.Send(ws, event)
+			})
+		},
+	}
+	server.ServeHTTP(w, r)
+}
@@ -1,6 +1,7 @@
 package api
 
 import (
+	"context"
 	"net/http"
 
 	"github.com/jmontesinos91/collector/internal/repositories/middleware"
@@ -13,6 +14,10 @@ import (
 	"github.com/sirupsen/logrus"
 )
 
+type contextKey string
+
+const permissionsKey contextKey = "permissions"
+
 // JwtVerifyMiddleware A custom middleware to validate and parse a JWT, it will propagate the claims through the context
 func JwtVerifyMiddleware(logger *logger.ContextLogger, stsClient sts.ISTSClient) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
@@ -35,6 +40,7 @@ func JwtVerifyMiddleware(logger *logger.ContextLogger, stsClient sts.ISTSClient)
 
 			// All good, propagate token using context
 			r = r.WithContext(stsClient.StoreClaimsV2InContext(r.Context(), claims))
+			r = r.WithContext(context.WithValue(r.Context(), permissionsKey, *permissions))
 
 			// Continue the chain
 			next.ServeHTTP(w, r)
@@ -42,6 +48,27 @@ func JwtVerifyMiddleware(logger *logger.ContextLogger, stsClient sts.ISTSClient)
 	}
 }
 
+// AccessTokenFromQuery copies the access_token query parameter into the Authorization header when the
+// header is missing, it is meant for the routes browsers reach without custom headers
+func AccessTokenFromQuery(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			if token := r.URL.Query().Get("access_token"); token != "" {
+				r.Header.Set("Authorization", "Bearer "+token)
+			}
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// PermissionsFromContext returns the permissions of the caller propagated by JwtVerifyMiddleware,
+// handlers use them for checks that depend on the request content
+func PermissionsFromContext(ctx context.Context) []sts.Permission {
+	permissions, _ := ctx.Value(permissionsKey).([]sts.Permission)
+	return permissions
+}
+
 func validateAccess(r *http.Request, permissions *[]sts.Permission) error {
 	route := chi.RouteContext(r.Context()).RoutePattern()
 	method := r.Method
@@ -0,0 +1,40 @@
+package api
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/jmontesinos91/ologs/logger"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// openAPIDocument OpenAPI 3 description of every route of the http server
+//
+//go:embed openapi.json
+var openAPIDocument []byte
+
+// OpenAPIController Serves the OpenAPI document
+type OpenAPIController struct {
+	log *logger.ContextLogger
+}
+
+// NewOpenAPIController Creates a new instance
+func NewOpenAPIController(server *HTTPServer) *OpenAPIController {
+	oc := &OpenAPIController{
+		log: server.Logger,
+	}
+
+	// Loads routes
+	server.Router.Get("/openapi.json", oc.handleDocument)
+
+	return oc
+}
+
+func (oc *OpenAPIController) handleDocument(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set(middleware.RequestIDHeader, middleware.GetReqID(r.Context()))
+	w.Header().Set("Content-Type", "application/json")
+
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(openAPIDocument)
+}
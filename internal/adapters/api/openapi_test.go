@@ -0,0 +1,71 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/jmontesinos91/collector/config"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestOpenAPIDocument keeps openapi.json in line with the routes registered by the controllers
+func TestOpenAPIDocument(t *testing.T) {
+	log := logger.NewContextLogger("OpenAPIDocument", "debug", logger.TextFormat)
+	server := NewHTTPServer(log, config.ServerConfigurations{}, config.Service{}, nil)
+	validate := validator.New()
+
+	// Every controller main registers, the services are never called while the routes are registered
+	NewHealthController(server)
+	NewOpenAPIController(server)
+	NewCollectorController(server, validate, nil, nil, nil)
+	NewTrafficController(server, validate, nil, nil)
+	NewExportJobController(server, nil, nil)
+	NewAuditController(server, nil, nil)
+	NewLiveFeedController(server, nil, nil)
+	NewWebhookController(server, nil, nil)
+	NewCommandController(server, validate, nil, nil)
+	NewCacheController(server, nil, nil)
+
+	routes := map[string]bool{}
+	err := chi.Walk(server.Router, func(method string, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+		routes[strings.ToLower(method)+" "+route] = true
+		return nil
+	})
+	if !assert.NoError(t, err) || !assert.NotEmpty(t, routes) {
+		return
+	}
+
+	document := struct {
+		Paths map[string]map[string]json.RawMessage `json:"paths"`
+	}{}
+	if !assert.NoError(t, json.Unmarshal(openAPIDocument, &document)) {
+		return
+	}
+	documented := map[string]bool{}
+	for path, operations := range document.Paths {
+		for method := range operations {
+			documented[method+" "+path] = true
+		}
+	}
+
+	assert.Empty(t, difference(routes, documented), "routes missing in openapi.json")
+	assert.Empty(t, difference(documented, routes), "operations of openapi.json without a route")
+}
+
+// difference sorted keys of a missing in b
+func difference(a, b map[string]bool) []string {
+	var missing []string
+	for key := range a {
+		if !b[key] {
+			missing = append(missing, key)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
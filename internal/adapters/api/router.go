@@ -1,8 +1,11 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"net/http"
 	"strconv"
+	"sync/atomic"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -20,6 +23,8 @@ type HTTPServer struct {
 	sc        config.ServerConfigurations
 	Router    *chi.Mux
 	stsClient sts.ISTSClient
+	server    *http.Server
+	draining  atomic.Bool
 }
 
 // NewHTTPServer Initializes a new http server
@@ -38,24 +43,67 @@ func NewHTTPServer(logger *logger.ContextLogger, serverConf config.ServerConfigu
 
 	// Set a timeout value on the request models (ctx), that will signal
 	// through ctx.Done() that the request has timed out and further
-	// processing should be stopped.
-	router.Use(middleware.Timeout(60 * time.Second))
+	// processing should be stopped. The live feed streams are exempt.
+	router.Use(timeoutExcept(60*time.Second, liveFeedStreamRoute, liveFeedWebSocketRoute))
+
+	// No write timeout, the live feed streams are long-lived responses
+	server := &http.Server{
+		Addr:    ":" + strconv.Itoa(serverConf.Port),
+		Handler: router,
+	}
 
 	return &HTTPServer{
 		Logger:    logger,
 		sc:        serverConf,
 		Router:    router,
 		stsClient: sts,
+		server:    server,
+	}
+}
+
+// timeoutExcept applies the request timeout to every path but the given long-lived ones
+func timeoutExcept(timeout time.Duration, paths ...string) func(http.Handler) http.Handler {
+	exempt := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		exempt[path] = true
+	}
+
+	return func(next http.Handler) http.Handler {
+		withTimeout := middleware.Timeout(timeout)(next)
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if exempt[r.URL.Path] {
+				next.ServeHTTP(w, r)
+				return
+			}
+			withTimeout.ServeHTTP(w, r)
+		})
 	}
 }
 
-// Start Fires the http server
+// Start Fires the http server, it returns once the server is shut down
 func (r *HTTPServer) Start() {
-	listeningAddr := ":" + strconv.Itoa(r.sc.Port)
-	r.Logger.Log(logrus.InfoLevel, "Start", "Server listening on port "+listeningAddr+"")
+	r.Logger.Log(logrus.InfoLevel, "Start", "Server listening on port "+r.server.Addr+"")
 
-	err := http.ListenAndServe(listeningAddr, r.Router)
-	if err != nil {
+	err := r.server.ListenAndServe()
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
 		r.Logger.Error(logrus.FatalLevel, "Start", "Failed to start http server. ", err)
 	}
 }
+
+// Drain makes the readiness probe fail so the load balancer stops routing new requests, the
+// server keeps serving until it is shut down
+func (r *HTTPServer) Drain() {
+	r.draining.Store(true)
+}
+
+// Draining reports whether the server is being drained
+func (r *HTTPServer) Draining() bool {
+	return r.draining.Load()
+}
+
+// Shutdown stops accepting connections and waits for the in-flight requests until the given
+// context is done
+func (r *HTTPServer) Shutdown(ctx context.Context) error {
+	r.Drain()
+	return r.server.Shutdown(ctx)
+}
@@ -4,13 +4,29 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"io"
+	"mime"
 	"net/http"
 
+	"github.com/jmontesinos91/collector/domains/validation"
 	"github.com/jmontesinos91/terrors"
 
 	"github.com/go-chi/chi/v5/middleware"
 )
 
+// ErrorResponse body of the error responses, fields are only present on bad requests
+type ErrorResponse struct {
+	Code    string       `json:"code"`
+	Message string       `json:"message"`
+	Fields  []FieldError `json:"fields,omitempty"`
+}
+
+// FieldError offending field of a bad request
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
 // RenderJSON Render A helper function to render a JSON response
 func RenderJSON(ctx context.Context, w http.ResponseWriter, httpStatusCode int, payload interface{}) {
 	// Headers
@@ -38,36 +54,89 @@ func RenderFile(ctx context.Context, w http.ResponseWriter, httpStatusCode int,
 	_, _ = w.Write(payload)
 }
 
+// RenderBody Render A helper function to render an already encoded response
+func RenderBody(ctx context.Context, w http.ResponseWriter, httpStatusCode int, contentType string, payload []byte) {
+	// Headers
+	w.Header().Set(middleware.RequestIDHeader, middleware.GetReqID(ctx))
+	w.Header().Set("Content-Type", contentType)
+
+	w.WriteHeader(httpStatusCode)
+	_, _ = w.Write(payload)
+}
+
+// RenderAttachment A helper function to stream a downloadable file response, the status
+// is sent before the content so write errors can only be reported to the caller
+func RenderAttachment(ctx context.Context, w http.ResponseWriter, filename, contentType string, write func(io.Writer) error) error {
+	// Headers
+	w.Header().Set(middleware.RequestIDHeader, middleware.GetReqID(ctx))
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+
+	w.WriteHeader(http.StatusOK)
+	return write(w)
+}
+
 // RenderError Renders a error with some sane defaults.
 // This function receive any type of error, but is recommended use a terror
 // for cases when you what to send a specific status code, because other kind
 // of errors are handled as internal_errors
 func RenderError(ctx context.Context, w http.ResponseWriter, err error) {
-	var httpStatusCode int
-	var code string
-	var message string
+	RenderJSON(ctx, w, ErrorStatus(err), ToErrorResponse(err))
+}
+
+// ErrorStatus returns the http status of an error, other kind of errors than terrors are internal errors
+func ErrorStatus(err error) int {
+	var terr *terrors.Error
+	if !errors.As(err, &terr) {
+		return http.StatusInternalServerError
+	}
+
+	if terr.PrefixMatches(terrors.ErrPreconditionFailed) || terr.PrefixMatches(terrors.ErrBadRequest) {
+		return http.StatusBadRequest
+	} else if terr.PrefixMatches(terrors.ErrUnauthorized) {
+		return http.StatusUnauthorized
+	} else if terr.PrefixMatches(terrors.ErrForbidden) {
+		return http.StatusForbidden
+	} else if terr.PrefixMatches(terrors.ErrNotFound) {
+		return http.StatusNotFound
+	} else if terr.PrefixMatches(terrors.ErrConflict) {
+		return http.StatusConflict
+	} else if terr.PrefixMatches(terrors.ErrRateLimited) {
+		return http.StatusTooManyRequests
+	}
+	return http.StatusInternalServerError
+}
 
-	// Check if error can be parsed as terror
+// ToErrorResponse returns the body of an error response
+func ToErrorResponse(err error) ErrorResponse {
 	var terr *terrors.Error
-	if errors.As(err, &terr) {
-		if terr.PrefixMatches(terrors.ErrPreconditionFailed) || terr.PrefixMatches(terrors.ErrBadRequest) {
-			httpStatusCode = http.StatusBadRequest
-		} else if terr.PrefixMatches(terrors.ErrUnauthorized) {
-			httpStatusCode = http.StatusUnauthorized
-		} else if terr.PrefixMatches(terrors.ErrNotFound) {
-			httpStatusCode = http.StatusNotFound
-		} else {
-			httpStatusCode = http.StatusInternalServerError
-		}
-		// Assing Code and Error message
-		code = terr.Code
-		message = terr.Message
+	if !errors.As(err, &terr) {
+		return ErrorResponse{}
 	}
 
-	payload := map[string]string{
-		"code":    code,
-		"message": message,
+	payload := ErrorResponse{
+		Code:    terr.Code,
+		Message: terr.Message,
 	}
 
-	RenderJSON(ctx, w, httpStatusCode, payload)
+	// Bad requests list every offending field with the reason it was rejected
+	if ErrorStatus(err) == http.StatusBadRequest {
+		payload.Fields = toFieldErrors(terr.Params)
+	}
+
+	return payload
+}
+
+// toFieldErrors lists the params of a bad request sorted by field
+func toFieldErrors(params map[string]string) []FieldError {
+	if len(params) == 0 {
+		return nil
+	}
+
+	errs := validation.Errors(params)
+	fields := make([]FieldError, 0, len(errs))
+	for _, field := range errs.Fields() {
+		fields = append(fields, FieldError{Field: field, Message: errs[field]})
+	}
+	return fields
 }
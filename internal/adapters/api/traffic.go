@@ -5,6 +5,7 @@ import (
 
 	"github.com/go-chi/chi/v5"
 	"github.com/go-playground/validator/v10"
+	"github.com/jmontesinos91/collector/internal/repositories/middleware"
 	tservice "github.com/jmontesinos91/collector/internal/services/traffic"
 	"github.com/jmontesinos91/ologs/logger"
 	"github.com/jmontesinos91/osecurity/sts"
@@ -32,7 +33,11 @@ func NewTrafficController(server *HTTPServer, validator *validator.Validate, ts
 	server.Router.Group(func(r chi.Router) {
 		r.Use(JwtVerifyMiddleware(server.Logger, sts))
 		r.Get("/v1/traffic", sc.handleRetrieve)
+		r.Get("/v1/traffic/export", sc.handleExport)
+		r.Get("/v1/traffic/stats", sc.handleStats)
+		r.Post("/v1/traffic/bulk", sc.handleBulk)
 		r.Post("/v1/traffic/{id}", sc.handleDelete)
+		r.Post("/v1/traffic/{id}/restore", sc.handleRestore)
 		r.Post("/v1/traffic/counter/reset/{id}", sc.handleCounterReset)
 	})
 
@@ -49,6 +54,22 @@ func (tc *TrafficController) handleRetrieve(w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	if filters.Filter.IsCursor() {
+		data, err := tc.trafficSvc.HandleRetrieveCursor(r.Context(), filters)
+		if err != nil {
+			tc.log.Error(logrus.ErrorLevel, "handleRetrieve", "Failed to retrieve traffics", err)
+			if terrors.Is(err, terrors.ErrBadRequest) {
+				RenderError(r.Context(), w, err)
+				return
+			}
+			RenderError(r.Context(), w, terrors.InternalService("internal_error", "Failed to retrieve traffics", map[string]string{}))
+			return
+		}
+
+		RenderJSON(r.Context(), w, http.StatusOK, data)
+		return
+	}
+
 	data, err := tc.trafficSvc.HandleRetrieve(r.Context(), filters)
 	if err != nil {
 		tc.log.Error(logrus.ErrorLevel, "handleRetrieve", "Failed to retrieve traffics", err)
@@ -59,6 +80,83 @@ func (tc *TrafficController) handleRetrieve(w http.ResponseWriter, r *http.Reque
 	RenderJSON(r.Context(), w, http.StatusOK, data)
 }
 
+func (tc *TrafficController) handleExport(w http.ResponseWriter, r *http.Request) {
+	tc.log.Log(logrus.InfoLevel, "handleExport", "Incoming request to handleExport")
+
+	filters, err := tservice.ParseFilterRequest(r)
+	if err != nil {
+		tc.log.Error(logrus.ErrorLevel, "handleExport", "Invalid request parameters", err)
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	file, err := tc.trafficSvc.HandleExport(r.Context(), filters)
+	if err != nil {
+		tc.log.Error(logrus.ErrorLevel, "handleExport", "Failed to export traffics", err)
+		if terrors.Is(err, terrors.ErrBadRequest) {
+			RenderError(r.Context(), w, err)
+			return
+		}
+		RenderError(r.Context(), w, terrors.InternalService("internal_error", "Failed to export traffics", map[string]string{}))
+		return
+	}
+
+	err = RenderAttachment(r.Context(), w, file.Name, file.ContentType, file.Write)
+	if err != nil {
+		tc.log.Error(logrus.ErrorLevel, "handleExport", "Failed to write traffics export", err)
+	}
+}
+
+func (tc *TrafficController) handleStats(w http.ResponseWriter, r *http.Request) {
+	tc.log.Log(logrus.InfoLevel, "handleStats", "Incoming request to handleStats")
+
+	request, err := tservice.ParseStatsRequest(r)
+	if err != nil {
+		tc.log.Error(logrus.ErrorLevel, "handleStats", "Invalid request parameters", err)
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	data, err := tc.trafficSvc.HandleStats(r.Context(), request)
+	if err != nil {
+		tc.log.Error(logrus.ErrorLevel, "handleStats", "Failed to aggregate traffics", err)
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	RenderJSON(r.Context(), w, http.StatusOK, data)
+}
+
+func (tc *TrafficController) handleBulk(w http.ResponseWriter, r *http.Request) {
+	tc.log.Log(logrus.InfoLevel, "handleBulk", "Incoming request to handleBulk")
+
+	request, err := tservice.ParseBulkRequest(r, tc.validate)
+	if err != nil {
+		tc.log.Error(logrus.ErrorLevel, "handleBulk", "Invalid request parameters", err)
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	// The route admits any bulk action, each operation requires its own
+	if !middleware.AllowsAction(PermissionsFromContext(r.Context()), middleware.BulkAction(request.Operation)) {
+		RenderError(r.Context(), w, terrors.New(terrors.ErrForbidden, "Operation not allowed", map[string]string{}))
+		return
+	}
+
+	data, err := tc.trafficSvc.HandleBulk(r.Context(), request)
+	if err != nil {
+		tc.log.Error(logrus.ErrorLevel, "handleBulk", "Failed to apply bulk operation", err)
+		if terrors.Is(err, terrors.ErrBadRequest) {
+			RenderError(r.Context(), w, err)
+			return
+		}
+		RenderError(r.Context(), w, terrors.InternalService("internal_error", "Failed to apply bulk operation", map[string]string{}))
+		return
+	}
+
+	RenderJSON(r.Context(), w, http.StatusOK, data)
+}
+
 func (tc *TrafficController) handleDelete(w http.ResponseWriter, r *http.Request) {
 	tc.log.Log(logrus.InfoLevel, "handleDelete", "Incoming request to handleDelete")
 
@@ -67,6 +165,10 @@ func (tc *TrafficController) handleDelete(w http.ResponseWriter, r *http.Request
 	err := tc.trafficSvc.HandleDelete(r.Context(), trafficID)
 	if err != nil {
 		tc.log.Error(logrus.ErrorLevel, "handleDelete", "Failed to delete traffic resource", err)
+		if terrors.Is(err, terrors.ErrBadRequest, terrors.ErrNotFound) {
+			RenderError(r.Context(), w, err)
+			return
+		}
 		RenderError(r.Context(), w, terrors.InternalService("internal_error", "Failed to delete traffic resource", map[string]string{}))
 		return
 	}
@@ -74,6 +176,25 @@ func (tc *TrafficController) handleDelete(w http.ResponseWriter, r *http.Request
 	RenderJSON(r.Context(), w, http.StatusAccepted, nil)
 }
 
+func (tc *TrafficController) handleRestore(w http.ResponseWriter, r *http.Request) {
+	tc.log.Log(logrus.InfoLevel, "handleRestore", "Incoming request to handleRestore")
+
+	trafficID := chi.URLParam(r, "id")
+
+	err := tc.trafficSvc.HandleRestore(r.Context(), trafficID)
+	if err != nil {
+		tc.log.Error(logrus.ErrorLevel, "handleRestore", "Failed to restore traffic resource", err)
+		if terrors.Is(err, terrors.ErrBadRequest, terrors.ErrNotFound, terrors.ErrConflict) {
+			RenderError(r.Context(), w, err)
+			return
+		}
+		RenderError(r.Context(), w, terrors.InternalService("internal_error", "Failed to restore traffic resource", map[string]string{}))
+		return
+	}
+
+	RenderJSON(r.Context(), w, http.StatusAccepted, nil)
+}
+
 func (tc *TrafficController) handleCounterReset(w http.ResponseWriter, r *http.Request) {
 	tc.log.Log(logrus.InfoLevel, "handleCounterReset", "Incoming request to handleRetrieve")
 
@@ -82,6 +203,10 @@ func (tc *TrafficController) handleCounterReset(w http.ResponseWriter, r *http.R
 	err := tc.trafficSvc.HandleResetCounter(r.Context(), trafficID)
 	if err != nil {
 		tc.log.Error(logrus.ErrorLevel, "handleCounterReset", "Failed to reset traffic counter", err)
+		if terrors.Is(err, terrors.ErrBadRequest, terrors.ErrNotFound) {
+			RenderError(r.Context(), w, err)
+			return
+		}
 		RenderError(r.Context(), w, terrors.InternalService("internal_error", "Failed to reset traffic counter", map[string]string{}))
 		return
 	}
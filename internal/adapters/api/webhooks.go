@@ -0,0 +1,137 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/jmontesinos91/collector/internal/services/webhook"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/sirupsen/logrus"
+)
+
+// WebhookController controller struct
+type WebhookController struct {
+	log        *logger.ContextLogger
+	webhookSvc webhook.IService
+}
+
+// NewWebhookController Constructor
+func NewWebhookController(server *HTTPServer, ws webhook.IService, sts sts.ISTSClient) *WebhookController {
+	wc := &WebhookController{
+		log:        server.Logger,
+		webhookSvc: ws,
+	}
+
+	// Endpoint secure
+	server.Router.Group(func(r chi.Router) {
+		r.Use(JwtVerifyMiddleware(server.Logger, sts))
+		r.Post("/v1/webhooks", wc.handleCreate)
+		r.Get("/v1/webhooks", wc.handleFind)
+		r.Get("/v1/webhooks/{id}", wc.handleRetrieve)
+		r.Put("/v1/webhooks/{id}", wc.handleUpdate)
+		r.Delete("/v1/webhooks/{id}", wc.handleDelete)
+		r.Get("/v1/webhooks/{id}/deliveries", wc.handleDeliveries)
+	})
+
+	return wc
+}
+
+func (wc *WebhookController) handleCreate(w http.ResponseWriter, r *http.Request) {
+	wc.log.Log(logrus.InfoLevel, "handleCreate", "Incoming request to handleCreate")
+
+	request, err := webhook.ParseCreateRequest(r)
+	if err != nil {
+		wc.log.Error(logrus.ErrorLevel, "handleCreate", "Invalid request parameters", err)
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	data, err := wc.webhookSvc.HandleCreate(r.Context(), request)
+	if err != nil {
+		wc.log.Error(logrus.ErrorLevel, "handleCreate", "Failed to create webhook subscription", err)
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	RenderJSON(r.Context(), w, http.StatusCreated, data)
+}
+
+func (wc *WebhookController) handleFind(w http.ResponseWriter, r *http.Request) {
+	wc.log.Log(logrus.InfoLevel, "handleFind", "Incoming request to handleFind")
+
+	data, err := wc.webhookSvc.HandleFind(r.Context())
+	if err != nil {
+		wc.log.Error(logrus.ErrorLevel, "handleFind", "Failed to retrieve webhook subscriptions", err)
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	RenderJSON(r.Context(), w, http.StatusOK, data)
+}
+
+func (wc *WebhookController) handleRetrieve(w http.ResponseWriter, r *http.Request) {
+	wc.log.Log(logrus.InfoLevel, "handleRetrieve", "Incoming request to handleRetrieve")
+
+	data, err := wc.webhookSvc.HandleRetrieve(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		wc.log.Error(logrus.ErrorLevel, "handleRetrieve", "Failed to retrieve webhook subscription", err)
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	RenderJSON(r.Context(), w, http.StatusOK, data)
+}
+
+func (wc *WebhookController) handleUpdate(w http.ResponseWriter, r *http.Request) {
+	wc.log.Log(logrus.InfoLevel, "handleUpdate", "Incoming request to handleUpdate")
+
+	request, err := webhook.ParseUpdateRequest(r)
+	if err != nil {
+		wc.log.Error(logrus.ErrorLevel, "handleUpdate", "Invalid request parameters", err)
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	data, err := wc.webhookSvc.HandleUpdate(r.Context(), chi.URLParam(r, "id"), request)
+	if err != nil {
+		wc.log.Error(logrus.ErrorLevel, "handleUpdate", "Failed to update webhook subscription", err)
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	RenderJSON(r.Context(), w, http.StatusOK, data)
+}
+
+func (wc *WebhookController) handleDelete(w http.ResponseWriter, r *http.Request) {
+	wc.log.Log(logrus.InfoLevel, "handleDelete", "Incoming request to handleDelete")
+
+	err := wc.webhookSvc.HandleDelete(r.Context(), chi.URLParam(r, "id"))
+	if err != nil {
+		wc.log.Error(logrus.ErrorLevel, "handleDelete", "Failed to delete webhook subscription", err)
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	RenderJSON(r.Context(), w, http.StatusAccepted, nil)
+}
+
+func (wc *WebhookController) handleDeliveries(w http.ResponseWriter, r *http.Request) {
+	wc.log.Log(logrus.InfoLevel, "handleDeliveries", "Incoming request to handleDeliveries")
+
+	filter, err := webhook.ParseDeliveryFilterRequest(r)
+	if err != nil {
+		wc.log.Error(logrus.ErrorLevel, "handleDeliveries", "Invalid request parameters", err)
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	data, err := wc.webhookSvc.HandleDeliveries(r.Context(), chi.URLParam(r, "id"), filter)
+	if err != nil {
+		wc.log.Error(logrus.ErrorLevel, "handleDeliveries", "Failed to retrieve webhook deliveries", err)
+		RenderError(r.Context(), w, err)
+		return
+	}
+
+	RenderJSON(r.Context(), w, http.StatusOK, data)
+}
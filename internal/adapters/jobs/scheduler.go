@@ -0,0 +1,98 @@
+package jobs
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/sirupsen/logrus"
+)
+
+// Scheduler runs background jobs until it is stopped
+type Scheduler struct {
+	log    *logger.ContextLogger
+	ctx    context.Context
+	cancel context.CancelFunc
+	// runCtx context of the periodic runs, it is only canceled when they do not finish in time
+	runCtx context.Context
+	abort  context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// NewScheduler creates a new Scheduler
+func NewScheduler(l *logger.ContextLogger) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	runCtx, abort := context.WithCancel(context.Background())
+
+	return &Scheduler{
+		log:    l,
+		ctx:    ctx,
+		cancel: cancel,
+		runCtx: runCtx,
+		abort:  abort,
+	}
+}
+
+// Go runs a long-lived job, the job must return once its context is done
+func (s *Scheduler) Go(name string, job func(ctx context.Context)) {
+	s.wg.Add(1)
+	go func() {
+		defer s.wg.Done()
+		s.log.Log(logrus.InfoLevel, "Scheduler", "Background job started: "+name)
+		job(s.ctx)
+		s.log.Log(logrus.InfoLevel, "Scheduler", "Background job stopped: "+name)
+	}()
+}
+
+// GoWithDrain runs a long-lived job that has work left to flush once it is stopped, the job must
+// return once its context is done and flush only while the drain context is not, the drain context
+// is canceled when the stop deadline is reached
+func (s *Scheduler) GoWithDrain(name string, job func(ctx, drainCtx context.Context)) {
+	s.Go(name, func(ctx context.Context) {
+		job(ctx, s.runCtx)
+	})
+}
+
+// Every runs a job periodically, a run is never overlapped with the next one. Once the scheduler is
+// stopped no run is started and the running one is given until the stop deadline to finish
+func (s *Scheduler) Every(name string, interval time.Duration, job func(ctx context.Context)) {
+	if interval <= 0 {
+		s.log.Log(logrus.WarnLevel, "Scheduler", "Background job disabled, invalid interval: "+name)
+		return
+	}
+
+	s.Go(name, func(ctx context.Context) {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				job(s.runCtx)
+			}
+		}
+	})
+}
+
+// Stop signals every job to finish and waits for them until the given context is done, then the
+// periodic runs still in progress are canceled
+func (s *Scheduler) Stop(ctx context.Context) error {
+	s.cancel()
+	defer s.abort()
+
+	done := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
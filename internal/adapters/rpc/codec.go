@@ -0,0 +1,33 @@
+package rpc
+
+import (
+	"encoding/json"
+
+	"google.golang.org/grpc/encoding"
+)
+
+// CodecName content subtype of the messages, the API is served without generated protobuf code so
+// callers select it with grpc.CallContentSubtype(CodecName) or force it with grpc.ForceCodec(Codec{})
+const CodecName = "json"
+
+// Codec marshals the gRPC messages as JSON, the field names match the REST API
+type Codec struct{}
+
+func init() {
+	encoding.RegisterCodec(Codec{})
+}
+
+// Marshal encodes the message
+func (Codec) Marshal(v any) ([]byte, error) {
+	return json.Marshal(v)
+}
+
+// Unmarshal decodes the message
+func (Codec) Unmarshal(data []byte, v any) error {
+	return json.Unmarshal(data, v)
+}
+
+// Name returns the content subtype served by the codec
+func (Codec) Name() string {
+	return CodecName
+}
@@ -0,0 +1,52 @@
+package rpc
+
+import (
+	"errors"
+
+	"github.com/jmontesinos91/terrors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// toStatus converts an error into a gRPC status with the same sane defaults as the http
+// RenderError, errors other than terrors are reported as internal errors
+func toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+
+	var terr *terrors.Error
+	if !errors.As(err, &terr) {
+		return status.Error(codes.Internal, "")
+	}
+
+	code := codes.Internal
+	switch {
+	case terr.PrefixMatches(terrors.ErrPreconditionFailed) || terr.PrefixMatches(terrors.ErrBadRequest):
+		code = codes.InvalidArgument
+	case terr.PrefixMatches(terrors.ErrUnauthorized):
+		code = codes.Unauthenticated
+	case terr.PrefixMatches(terrors.ErrForbidden):
+		code = codes.PermissionDenied
+	case terr.PrefixMatches(terrors.ErrNotFound):
+		code = codes.NotFound
+	case terr.PrefixMatches(terrors.ErrConflict):
+		code = codes.AlreadyExists
+	case terr.PrefixMatches(terrors.ErrRateLimited):
+		code = codes.ResourceExhausted
+	}
+
+	return status.Error(code, terr.Message)
+}
+
+// failure returns the status of a failed call, client errors are reported as they are and any other
+// error is hidden behind the given message like the http handlers do
+func failure(err error, message string) error {
+	if terrors.Is(err, terrors.ErrBadRequest, terrors.ErrNotFound, terrors.ErrConflict) {
+		return toStatus(err)
+	}
+	return toStatus(terrors.InternalService("internal_error", message, map[string]string{}))
+}
@@ -0,0 +1,189 @@
+package rpc
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	omiddleware "github.com/jmontesinos91/collector/internal/repositories/middleware"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/osecurity/services/omnibackend/enum"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/jmontesinos91/terrors"
+
+	"github.com/google/uuid"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// unaryTimeout same deadline the http router applies, streams are exempt as the live feed is
+const unaryTimeout = 60 * time.Second
+
+// requestIDHeader metadata key carrying the request id, it is generated when the caller does not send one
+const requestIDHeader = "x-request-id"
+
+// route http route and method a grpc method is authorized as, methods with an empty path only require
+// a valid token
+type route struct {
+	path   string
+	method string
+}
+
+// methodRoutes grpc methods and the http routes whose permissions they share, methods missing here are denied
+var methodRoutes = map[string]route{
+	trafficMethod("ListTraffic"):   {path: "/v1/traffic", method: http.MethodGet},
+	trafficMethod("ExportTraffic"): {path: "/v1/traffic/export", method: http.MethodGet},
+	trafficMethod("DeleteTraffic"): {path: "/v1/traffic/{id}", method: http.MethodPost},
+	trafficMethod("ResetCounter"):  {path: "/v1/traffic/counter/reset/{id}", method: http.MethodPost},
+	trafficMethod("SubmitFrame"):   {},
+}
+
+// serverStream overrides the context of a server stream
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+// Context returns the overridden context
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+func requestIDUnaryInterceptor(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	return handler(withRequestID(ctx), req)
+}
+
+func requestIDStreamInterceptor(srv any, ss grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	return handler(srv, &serverStream{ServerStream: ss, ctx: withRequestID(ss.Context())})
+}
+
+// withRequestID propagates the request id the same way the chi RequestID middleware does and returns it
+// to the caller as a header
+func withRequestID(ctx context.Context) context.Context {
+	requestID := ""
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get(requestIDHeader); len(values) > 0 {
+			requestID = values[0]
+		}
+	}
+	if requestID == "" {
+		requestID = uuid.NewString()
+	}
+
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDHeader, requestID))
+
+	return context.WithValue(ctx, middleware.RequestIDKey, requestID)
+}
+
+func recoveryUnaryInterceptor(log *logger.ContextLogger) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				log.WithContext(logrus.ErrorLevel, "recoveryUnaryInterceptor", "Recovered from panic in "+info.FullMethod, logger.Context{
+					"panic": r,
+				}, nil)
+				err = status.Error(codes.Internal, "")
+			}
+		}()
+
+		return handler(ctx, req)
+	}
+}
+
+func recoveryStreamInterceptor(log *logger.ContextLogger) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				log.WithContext(logrus.ErrorLevel, "recoveryStreamInterceptor", "Recovered from panic in "+info.FullMethod, logger.Context{
+					"panic": r,
+				}, nil)
+				err = status.Error(codes.Internal, "")
+			}
+		}()
+
+		return handler(srv, ss)
+	}
+}
+
+func timeoutUnaryInterceptor(timeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+
+		return handler(ctx, req)
+	}
+}
+
+func authUnaryInterceptor(log *logger.ContextLogger, stsClient sts.ISTSClient) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx, err := authenticate(ctx, log, stsClient, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+
+		return handler(ctx, req)
+	}
+}
+
+func authStreamInterceptor(log *logger.ContextLogger, stsClient sts.ISTSClient) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), log, stsClient, info.FullMethod)
+		if err != nil {
+			return err
+		}
+
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// authenticate validates the token of the authorization metadata and the permissions of the method the
+// same way JwtVerifyMiddleware does, the claims are propagated through the returned context
+func authenticate(ctx context.Context, log *logger.ContextLogger, stsClient sts.ISTSClient, fullMethod string) (context.Context, error) {
+	unauthorized := toStatus(terrors.Unauthorized(terrors.ErrUnauthorized, "Invalid credentials", map[string]string{}))
+
+	route, ok := methodRoutes[fullMethod]
+	if !ok {
+		return nil, unauthorized
+	}
+
+	r, err := http.NewRequestWithContext(ctx, route.method, route.path, nil)
+	if err != nil {
+		return nil, unauthorized
+	}
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if values := md.Get("authorization"); len(values) > 0 {
+			r.Header.Set("Authorization", values[0])
+		}
+	}
+
+	claims, permissions, err := stsClient.ValidateTokenFromRequest(r, enum.TRAFFIC)
+	if err != nil {
+		log.Error(logrus.ErrorLevel, "authenticate", "JWT parsing failure: %v", err)
+		return nil, unauthorized
+	}
+
+	if route.path != "" && !hasPermission(permissions, route) {
+		return nil, unauthorized
+	}
+
+	return stsClient.StoreClaimsV2InContext(ctx, claims), nil
+}
+
+func hasPermission(permissions *[]sts.Permission, route route) bool {
+	if permissions == nil {
+		return false
+	}
+
+	// Range all permissions
+	for _, permission := range *permissions {
+		if omiddleware.ValidatePermission(permission, route.path, route.method) {
+			return true //if we found one permission we let the access
+		}
+	}
+
+	return false
+}
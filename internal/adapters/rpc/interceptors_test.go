@@ -0,0 +1,172 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/osecurity/services/omnibackend/enum"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// stsClientStub accepts the "Bearer unit-test-token" authorization with the given permissions
+type stsClientStub struct {
+	sts.ISTSClient
+	permissions *[]sts.Permission
+	calls       int
+}
+
+func (s *stsClientStub) ValidateTokenFromRequest(r *http.Request, _ enum.Subject) (*sts.Claims, *[]sts.Permission, error) {
+	s.calls++
+	if r.Header.Get("Authorization") != "Bearer unit-test-token" {
+		return nil, nil, errors.New("unit-test-error")
+	}
+	return &sts.Claims{UserID: 1, Role: "unit-test-role"}, s.permissions, nil
+}
+
+func (s *stsClientStub) StoreClaimsV2InContext(ctx context.Context, claims *sts.Claims) context.Context {
+	return context.WithValue(ctx, &sts.Claim, *claims)
+}
+
+func TestAuthInterceptors(t *testing.T) {
+	log := logger.NewContextLogger("AuthInterceptors", "debug", logger.TextFormat)
+	withToken := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer unit-test-token"))
+
+	type assertsParams struct {
+		sts     *stsClientStub
+		claims  *sts.Claims
+		handled bool
+	}
+
+	tests := []struct {
+		name        string
+		ctx         context.Context
+		method      string
+		stream      bool
+		permissions *[]sts.Permission
+		asserts     func(*testing.T, error, assertsParams) bool
+	}{
+		{
+			name:        "Missing token is unauthenticated",
+			ctx:         context.Background(),
+			method:      trafficMethod("ListTraffic"),
+			permissions: &[]sts.Permission{{Active: 1, Action: "read", Subject: "traffic"}},
+			asserts: func(t *testing.T, err error, ap assertsParams) bool {
+				return assert.Equal(t, codes.Unauthenticated, status.Code(err)) && assert.False(t, ap.handled)
+			},
+		},
+		{
+			name:        "Unknown method is denied before validating the token",
+			ctx:         withToken,
+			method:      "/" + TrafficServiceName + "/Unknown",
+			permissions: &[]sts.Permission{{Active: 1, Action: "read", Subject: "traffic"}},
+			asserts: func(t *testing.T, err error, ap assertsParams) bool {
+				return assert.Equal(t, codes.Unauthenticated, status.Code(err)) && assert.False(t, ap.handled) &&
+					assert.Equal(t, 0, ap.sts.calls)
+			},
+		},
+		{
+			name:        "Permission of another route is denied",
+			ctx:         withToken,
+			method:      trafficMethod("ResetCounter"),
+			permissions: &[]sts.Permission{{Active: 1, Action: "read", Subject: "traffic"}},
+			asserts: func(t *testing.T, err error, ap assertsParams) bool {
+				return assert.Equal(t, codes.Unauthenticated, status.Code(err)) && assert.False(t, ap.handled)
+			},
+		},
+		{
+			name:   "Token without permissions is denied",
+			ctx:    withToken,
+			method: trafficMethod("ListTraffic"),
+			asserts: func(t *testing.T, err error, ap assertsParams) bool {
+				return assert.Equal(t, codes.Unauthenticated, status.Code(err)) && assert.False(t, ap.handled)
+			},
+		},
+		{
+			name:        "Permission of the route is allowed with the claims",
+			ctx:         withToken,
+			method:      trafficMethod("ListTraffic"),
+			permissions: &[]sts.Permission{{Active: 1, Action: "read", Subject: "traffic"}},
+			asserts: func(t *testing.T, err error, ap assertsParams) bool {
+				return assert.NoError(t, err) && assert.True(t, ap.handled) &&
+					assert.NotNil(t, ap.claims) && assert.Equal(t, 1, ap.claims.UserID)
+			},
+		},
+		{
+			name:   "SubmitFrame only requires a token",
+			ctx:    withToken,
+			method: trafficMethod("SubmitFrame"),
+			asserts: func(t *testing.T, err error, ap assertsParams) bool {
+				return assert.NoError(t, err) && assert.True(t, ap.handled) && assert.NotNil(t, ap.claims)
+			},
+		},
+		{
+			name:   "SubmitFrame without a token is unauthenticated",
+			ctx:    context.Background(),
+			method: trafficMethod("SubmitFrame"),
+			asserts: func(t *testing.T, err error, ap assertsParams) bool {
+				return assert.Equal(t, codes.Unauthenticated, status.Code(err)) && assert.False(t, ap.handled)
+			},
+		},
+		{
+			name:        "Stream is allowed with the permission of its route",
+			ctx:         withToken,
+			method:      trafficMethod("ExportTraffic"),
+			stream:      true,
+			permissions: &[]sts.Permission{{Active: 1, Action: "export", Subject: "traffic"}},
+			asserts: func(t *testing.T, err error, ap assertsParams) bool {
+				return assert.NoError(t, err) && assert.True(t, ap.handled) && assert.NotNil(t, ap.claims)
+			},
+		},
+		{
+			name:        "Stream is denied with the permission of another route",
+			ctx:         withToken,
+			method:      trafficMethod("ExportTraffic"),
+			stream:      true,
+			permissions: &[]sts.Permission{{Active: 1, Action: "read", Subject: "traffic"}},
+			asserts: func(t *testing.T, err error, ap assertsParams) bool {
+				return assert.Equal(t, codes.Unauthenticated, status.Code(err)) && assert.False(t, ap.handled)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			ap := assertsParams{sts: &stsClientStub{permissions: tc.permissions}}
+			handled := func(ctx context.Context) {
+				ap.handled = true
+				if claims, ok := ctx.Value(&sts.Claim).(sts.Claims); ok {
+					ap.claims = &claims
+				}
+			}
+
+			var err error
+			if tc.stream {
+				interceptor := authStreamInterceptor(log, ap.sts)
+				err = interceptor(nil, &serverStream{ctx: tc.ctx}, &grpc.StreamServerInfo{FullMethod: tc.method},
+					func(_ any, ss grpc.ServerStream) error {
+						handled(ss.Context())
+						return nil
+					})
+			} else {
+				interceptor := authUnaryInterceptor(log, ap.sts)
+				_, err = interceptor(tc.ctx, nil, &grpc.UnaryServerInfo{FullMethod: tc.method},
+					func(ctx context.Context, _ any) (any, error) {
+						handled(ctx)
+						return &Empty{}, nil
+					})
+			}
+
+			if !tc.asserts(t, err, ap) {
+				t.Errorf("Assert error on test = '%v'", tc.name)
+			}
+		})
+	}
+}
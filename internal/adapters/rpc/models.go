@@ -0,0 +1,72 @@
+package rpc
+
+import (
+	"net/url"
+
+	"github.com/jmontesinos91/collector/internal/services/command"
+)
+
+// ListTrafficRequest filters of the listing, keys and values follow the query params of GET /v1/traffic
+type ListTrafficRequest struct {
+	Filters map[string]string `json:"filters"`
+}
+
+// ListTrafficResponse page of traffics, page numbers are set on offset listings and tokens on cursor listings
+type ListTrafficResponse struct {
+	Data        interface{} `json:"data"`
+	CurrentPage int         `json:"currentPage,omitempty"`
+	Pages       int         `json:"pages,omitempty"`
+	Next        string      `json:"next,omitempty"`
+	Prev        string      `json:"prev,omitempty"`
+	Size        int         `json:"size,omitempty"`
+	Total       *int        `json:"total,omitempty"`
+}
+
+// ExportTrafficRequest filters of the export, keys and values follow the query params of GET /v1/traffic
+type ExportTrafficRequest struct {
+	Filters map[string]string `json:"filters"`
+}
+
+// TrafficRequest identifies a traffic
+type TrafficRequest struct {
+	ID string `json:"id"`
+}
+
+// SubmitFrameRequest raw device frame, the ip is used when the frame does not carry one. Acks are the
+// ids of the commands the device reports as executed
+type SubmitFrameRequest struct {
+	Frame string   `json:"frame"`
+	IP    string   `json:"ip,omitempty"`
+	Acks  []string `json:"acks,omitempty"`
+}
+
+// SubmitFrameResponse request id the frame was processed with and the commands queued for the device
+type SubmitFrameResponse struct {
+	RequestID string             `json:"requestId"`
+	Commands  []command.Downlink `json:"commands,omitempty"`
+}
+
+// Empty response of the methods without content
+type Empty struct{}
+
+// values converts the filters into the query values parsed by the traffic service
+func values(filters map[string]string) url.Values {
+	query := make(url.Values, len(filters))
+	for key, value := range filters {
+		query.Set(key, value)
+	}
+	return query
+}
+
+// downlinks converts the commands of a reply into the form they are delivered to the device
+func downlinks(commands []command.Command) []command.Downlink {
+	if len(commands) == 0 {
+		return nil
+	}
+
+	result := make([]command.Downlink, 0, len(commands))
+	for _, c := range commands {
+		result = append(result, command.ToDownlink(c))
+	}
+	return result
+}
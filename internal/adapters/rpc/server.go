@@ -0,0 +1,81 @@
+package rpc
+
+import (
+	"context"
+	"errors"
+	"net"
+	"strconv"
+
+	"github.com/jmontesinos91/collector/config"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+)
+
+// GRPCServer grpc server
+type GRPCServer struct {
+	Logger    *logger.ContextLogger
+	sc        config.GRPCConfigurations
+	Server    *grpc.Server
+	stsClient sts.ISTSClient
+}
+
+// NewGRPCServer Initializes a new grpc server, every call goes through the request id, recovery and
+// authentication interceptors
+func NewGRPCServer(logger *logger.ContextLogger, serverConf config.GRPCConfigurations, sts sts.ISTSClient) *GRPCServer {
+	server := grpc.NewServer(
+		grpc.ChainUnaryInterceptor(
+			requestIDUnaryInterceptor,
+			recoveryUnaryInterceptor(logger),
+			timeoutUnaryInterceptor(unaryTimeout),
+			authUnaryInterceptor(logger, sts),
+		),
+		grpc.ChainStreamInterceptor(
+			requestIDStreamInterceptor,
+			recoveryStreamInterceptor(logger),
+			authStreamInterceptor(logger, sts),
+		),
+	)
+
+	return &GRPCServer{
+		Logger:    logger,
+		sc:        serverConf,
+		Server:    server,
+		stsClient: sts,
+	}
+}
+
+// Start Fires the grpc server
+func (s *GRPCServer) Start() {
+	listeningAddr := ":" + strconv.Itoa(s.sc.Port)
+	s.Logger.Log(logrus.InfoLevel, "Start", "gRPC server listening on port "+listeningAddr+"")
+
+	listener, err := net.Listen("tcp", listeningAddr)
+	if err != nil {
+		s.Logger.Error(logrus.FatalLevel, "Start", "Failed to listen for grpc server. ", err)
+		return
+	}
+
+	err = s.Server.Serve(listener)
+	if err != nil && !errors.Is(err, grpc.ErrServerStopped) {
+		s.Logger.Error(logrus.FatalLevel, "Start", "Failed to start grpc server. ", err)
+	}
+}
+
+// Stop stops accepting connections and waits for the in-flight calls until the given context is
+// done, then the remaining ones are canceled
+func (s *GRPCServer) Stop(ctx context.Context) {
+	done := make(chan struct{})
+	go func() {
+		s.Server.GracefulStop()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-ctx.Done():
+		s.Logger.Log(logrus.WarnLevel, "Stop", "gRPC calls still in progress at the shutdown deadline were canceled")
+		s.Server.Stop()
+	}
+}
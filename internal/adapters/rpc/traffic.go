@@ -0,0 +1,195 @@
+package rpc
+
+import (
+	"context"
+
+	"github.com/go-chi/chi/v5/middleware"
+	scollector "github.com/jmontesinos91/collector/internal/services/collector"
+	"github.com/jmontesinos91/collector/internal/services/command"
+	tservice "github.com/jmontesinos91/collector/internal/services/traffic"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/sirupsen/logrus"
+	"google.golang.org/grpc"
+)
+
+// TrafficServiceName fully qualified name of the traffic service
+const TrafficServiceName = "collector.traffic.v1.TrafficService"
+
+// TrafficServiceServer traffic queries and frame ingestion over grpc
+type TrafficServiceServer interface {
+	ListTraffic(ctx context.Context, request *ListTrafficRequest) (*ListTrafficResponse, error)
+	ExportTraffic(request *ExportTrafficRequest, stream grpc.ServerStream) error
+	DeleteTraffic(ctx context.Context, request *TrafficRequest) (*Empty, error)
+	ResetCounter(ctx context.Context, request *TrafficRequest) (*Empty, error)
+	SubmitFrame(ctx context.Context, request *SubmitFrameRequest) (*SubmitFrameResponse, error)
+}
+
+// TrafficServiceDesc description of the traffic service, it is written by hand since the messages are
+// encoded by the JSON Codec instead of generated protobuf code. Clients are generated from traffic.proto,
+// which must be updated along with it
+var TrafficServiceDesc = grpc.ServiceDesc{
+	ServiceName: TrafficServiceName,
+	HandlerType: (*TrafficServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{MethodName: "ListTraffic", Handler: unaryHandler("ListTraffic", TrafficServiceServer.ListTraffic)},
+		{MethodName: "DeleteTraffic", Handler: unaryHandler("DeleteTraffic", TrafficServiceServer.DeleteTraffic)},
+		{MethodName: "ResetCounter", Handler: unaryHandler("ResetCounter", TrafficServiceServer.ResetCounter)},
+		{MethodName: "SubmitFrame", Handler: unaryHandler("SubmitFrame", TrafficServiceServer.SubmitFrame)},
+	},
+	Streams: []grpc.StreamDesc{
+		{StreamName: "ExportTraffic", Handler: exportTrafficHandler, ServerStreams: true},
+	},
+}
+
+// trafficMethod full name of a traffic service method as seen by the interceptors
+func trafficMethod(name string) string {
+	return "/" + TrafficServiceName + "/" + name
+}
+
+// unaryHandler decodes the request of a unary method and runs it through the interceptors
+func unaryHandler[Req any, Res any](name string, call func(TrafficServiceServer, context.Context, *Req) (*Res, error)) grpc.MethodHandler {
+	return func(srv any, ctx context.Context, dec func(any) error, interceptor grpc.UnaryServerInterceptor) (any, error) {
+		request := new(Req)
+		if err := dec(request); err != nil {
+			return nil, err
+		}
+
+		handler := func(ctx context.Context, req any) (any, error) {
+			return call(srv.(TrafficServiceServer), ctx, req.(*Req))
+		}
+		if interceptor == nil {
+			return handler(ctx, request)
+		}
+
+		return interceptor(ctx, request, &grpc.UnaryServerInfo{Server: srv, FullMethod: trafficMethod(name)}, handler)
+	}
+}
+
+func exportTrafficHandler(srv any, stream grpc.ServerStream) error {
+	request := &ExportTrafficRequest{}
+	if err := stream.RecvMsg(request); err != nil {
+		return err
+	}
+
+	return srv.(TrafficServiceServer).ExportTraffic(request, stream)
+}
+
+// TrafficController grpc controller of the traffic service
+type TrafficController struct {
+	log          *logger.ContextLogger
+	trafficSvc   tservice.IService
+	collectorSvc scollector.IService
+}
+
+// NewTrafficController Constructor, registers the traffic service in the server
+func NewTrafficController(server *GRPCServer, ts tservice.IService, cs scollector.IService) *TrafficController {
+	tc := &TrafficController{
+		log:          server.Logger,
+		trafficSvc:   ts,
+		collectorSvc: cs,
+	}
+
+	server.Server.RegisterService(&TrafficServiceDesc, tc)
+
+	return tc
+}
+
+// ListTraffic lists the traffics with the filters of GET /v1/traffic
+func (tc *TrafficController) ListTraffic(ctx context.Context, request *ListTrafficRequest) (*ListTrafficResponse, error) {
+	tc.log.Log(logrus.InfoLevel, "ListTraffic", "Incoming request to ListTraffic")
+
+	filters, err := tservice.ParseFilterValues(values(request.Filters))
+	if err != nil {
+		tc.log.Error(logrus.ErrorLevel, "ListTraffic", "Invalid request parameters", err)
+		return nil, toStatus(err)
+	}
+
+	if filters.Filter.IsCursor() {
+		data, err := tc.trafficSvc.HandleRetrieveCursor(ctx, filters)
+		if err != nil {
+			tc.log.Error(logrus.ErrorLevel, "ListTraffic", "Failed to retrieve traffics", err)
+			return nil, failure(err, "Failed to retrieve traffics")
+		}
+
+		return &ListTrafficResponse{Data: data.Data, Next: data.Next, Prev: data.Prev, Size: data.Size, Total: data.Total}, nil
+	}
+
+	data, err := tc.trafficSvc.HandleRetrieve(ctx, filters)
+	if err != nil {
+		tc.log.Error(logrus.ErrorLevel, "ListTraffic", "Failed to retrieve traffics", err)
+		return nil, failure(err, "Failed to retrieve traffics")
+	}
+
+	total := data.Total
+	return &ListTrafficResponse{Data: data.Data, CurrentPage: data.CurrentPage, Pages: data.Pages, Total: &total}, nil
+}
+
+// ExportTraffic streams every traffic matching the filters of GET /v1/traffic/export, one message per traffic
+func (tc *TrafficController) ExportTraffic(request *ExportTrafficRequest, stream grpc.ServerStream) error {
+	tc.log.Log(logrus.InfoLevel, "ExportTraffic", "Incoming request to ExportTraffic")
+
+	filters, err := tservice.ParseFilterValues(values(request.Filters))
+	if err != nil {
+		tc.log.Error(logrus.ErrorLevel, "ExportTraffic", "Invalid request parameters", err)
+		return toStatus(err)
+	}
+
+	err = tc.trafficSvc.HandleStream(stream.Context(), filters, func(traffic tservice.Traffic) error {
+		return stream.SendMsg(&traffic)
+	})
+	if err != nil {
+		tc.log.Error(logrus.ErrorLevel, "ExportTraffic", "Failed to export traffics", err)
+		return failure(err, "Failed to export traffics")
+	}
+
+	return nil
+}
+
+// DeleteTraffic soft deletes a traffic
+func (tc *TrafficController) DeleteTraffic(ctx context.Context, request *TrafficRequest) (*Empty, error) {
+	tc.log.Log(logrus.InfoLevel, "DeleteTraffic", "Incoming request to DeleteTraffic")
+
+	err := tc.trafficSvc.HandleDelete(ctx, request.ID)
+	if err != nil {
+		tc.log.Error(logrus.ErrorLevel, "DeleteTraffic", "Failed to delete traffic resource", err)
+		return nil, failure(err, "Failed to delete traffic resource")
+	}
+
+	return &Empty{}, nil
+}
+
+// ResetCounter resets the counter of a traffic
+func (tc *TrafficController) ResetCounter(ctx context.Context, request *TrafficRequest) (*Empty, error) {
+	tc.log.Log(logrus.InfoLevel, "ResetCounter", "Incoming request to ResetCounter")
+
+	err := tc.trafficSvc.HandleResetCounter(ctx, request.ID)
+	if err != nil {
+		tc.log.Error(logrus.ErrorLevel, "ResetCounter", "Failed to reset traffic counter", err)
+		return nil, failure(err, "Failed to reset traffic counter")
+	}
+
+	return &Empty{}, nil
+}
+
+// SubmitFrame processes a raw device frame the same way the collector http endpoint does
+func (tc *TrafficController) SubmitFrame(ctx context.Context, request *SubmitFrameRequest) (*SubmitFrameResponse, error) {
+	tc.log.Log(logrus.InfoLevel, "SubmitFrame", "Incoming request to SubmitFrame")
+
+	payload := &scollector.Payload{}
+	err := payload.ParseFrame(request.Frame, request.IP)
+	if err != nil {
+		tc.log.Error(logrus.ErrorLevel, "SubmitFrame", "Invalid request parameters", err)
+		return nil, toStatus(err)
+	}
+
+	payload.Acks = command.ParseAcks(request.Acks...)
+
+	reply, err := tc.collectorSvc.Collector(ctx, payload)
+	if err != nil {
+		tc.log.Error(logrus.ErrorLevel, "SubmitFrame", "Failed to process frame", err)
+		return nil, toStatus(err)
+	}
+
+	requestID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	return &SubmitFrameResponse{RequestID: requestID, Commands: downlinks(reply.Commands)}, nil
+}
@@ -0,0 +1,45 @@
+package rpc
+
+import (
+	"os"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestTrafficServiceContract keeps the published traffic.proto in line with the hand written service description
+func TestTrafficServiceContract(t *testing.T) {
+	contract, err := os.ReadFile("traffic.proto")
+	if !assert.NoError(t, err) {
+		return
+	}
+
+	pkg := regexp.MustCompile(`(?m)^package ([\w.]+);`).FindSubmatch(contract)
+	service := regexp.MustCompile(`(?m)^service (\w+) \{`).FindSubmatch(contract)
+	if assert.NotNil(t, pkg) && assert.NotNil(t, service) {
+		assert.Equal(t, TrafficServiceName, string(pkg[1])+"."+string(service[1]))
+	}
+
+	unary, streams := map[string]bool{}, map[string]bool{}
+	for _, rpc := range regexp.MustCompile(`rpc (\w+)\(\w+\) returns \((stream )?\w+\);`).FindAllSubmatch(contract, -1) {
+		if len(rpc[2]) > 0 {
+			streams[string(rpc[1])] = true
+		} else {
+			unary[string(rpc[1])] = true
+		}
+	}
+
+	described := map[string]bool{}
+	for _, method := range TrafficServiceDesc.Methods {
+		described[method.MethodName] = true
+		assert.True(t, unary[method.MethodName], "unary method %s is missing in traffic.proto", method.MethodName)
+		assert.Contains(t, methodRoutes, trafficMethod(method.MethodName))
+	}
+	for _, stream := range TrafficServiceDesc.Streams {
+		described[stream.StreamName] = true
+		assert.True(t, streams[stream.StreamName], "stream %s is missing in traffic.proto", stream.StreamName)
+		assert.Contains(t, methodRoutes, trafficMethod(stream.StreamName))
+	}
+	assert.Len(t, described, len(unary)+len(streams), "traffic.proto declares methods that are not served")
+}
@@ -1,20 +1,25 @@
 package stream
 
 import (
+	"context"
+
 	"github.com/jmontesinos91/collector/config"
 	"github.com/jmontesinos91/oevents/broker"
 	"github.com/jmontesinos91/ologs/logger"
 	"github.com/sirupsen/logrus"
 )
 
-// NewKafkaConnection generate a new MessagingBrokerProvider
-func NewKafkaConnection(log *logger.ContextLogger, c config.KafkaConfigurations) (broker.MessagingBrokerProvider, func()) {
+// NewKafkaConnection generate a new MessagingBrokerProvider, the closer flushes the buffered records
+// until the given context is done before closing the connection
+func NewKafkaConnection(log *logger.ContextLogger, c config.KafkaConfigurations) (broker.MessagingBrokerProvider, func(ctx context.Context)) {
 	streamConfig := broker.OBrokerConfig{
-		Servers:         c.Servers,
-		User:            c.User,
-		Password:        c.Password,
-		ClientName:      c.ClientName,
-		ConsumerEnabled: c.Consumer.Enabled,
+		Servers:           c.Servers,
+		User:              c.User,
+		Password:          c.Password,
+		ClientName:        c.ClientName,
+		ConsumerEnabled:   c.Consumer.Enabled,
+		ConsumerGroupName: c.Consumer.Group,
+		ConsumeFromTopics: c.Consumer.Topics,
 	}
 
 	var stream broker.MessagingBrokerProvider
@@ -33,7 +38,12 @@ func NewKafkaConnection(log *logger.ContextLogger, c config.KafkaConfigurations)
 	}
 	log.Log(logrus.InfoLevel, "NewKafkaConnection", "Kafka Client started!")
 
-	return stream, func() {
+	return stream, func(ctx context.Context) {
+		if client, ok := stream.(*broker.Client); ok {
+			if err := client.Conn.Flush(ctx); err != nil {
+				log.Error(logrus.ErrorLevel, "NewKafkaConnection", "Failed to flush the kafka records:", err)
+			}
+		}
 		stream.Close()
 	}
 }
@@ -0,0 +1,101 @@
+package audit
+
+import (
+	"context"
+	"math"
+
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/terrors"
+	"github.com/sirupsen/logrus"
+	"github.com/uptrace/bun"
+)
+
+// DatabaseRepository struct
+type DatabaseRepository struct {
+	log *logger.ContextLogger
+	db  *bun.DB
+}
+
+// NewDatabaseRepository creates an instance of DatabaseRepository
+func NewDatabaseRepository(l *logger.ContextLogger, conn *bun.DB) *DatabaseRepository {
+	return &DatabaseRepository{
+		log: l,
+		db:  conn,
+	}
+}
+
+// Create Handles the creation of a new audit entry on database
+func (r *DatabaseRepository) Create(ctx context.Context, model *Model) error {
+	_, err := r.db.NewInsert().
+		Model(model).
+		Exec(ctx)
+	if err != nil {
+		r.log.Error(logrus.ErrorLevel, "Create", "Error creating audit entry", err)
+		return terrors.InternalService("create_audit", "Failed to create audit entry in the database", map[string]string{})
+	}
+	return nil
+}
+
+// Retrieve Retrieves audit entries by filters, newest first unless ascending order is requested
+func (r *DatabaseRepository) Retrieve(ctx context.Context, filter *Metadata) ([]Model, int, int, error) {
+	var models []Model
+
+	query := setFilters(r.db.NewSelect().Model((*Model)(nil)), filter)
+
+	pages, total := 0, 0
+	if filter.Filter.ShouldCount() {
+		var err error
+		total, err = query.Count(ctx)
+		if err != nil {
+			r.log.Error(logrus.ErrorLevel, "Retrieve", "Error counting audit entries", err)
+			return nil, 0, 0, terrors.InternalService("count_error", "Error counting records in the database", map[string]string{})
+		}
+		pages = int(math.Ceil(float64(total) / float64(filter.Filter.Size)))
+	}
+
+	order := "DESC"
+	if !filter.Filter.SortDesc {
+		order = "ASC"
+	}
+	query = query.
+		OrderExpr("created_at " + order).
+		OrderExpr("id " + order).
+		Limit(filter.Filter.Size).
+		Offset((filter.Filter.Page - 1) * filter.Filter.Size)
+
+	if err := query.Scan(ctx, &models); err != nil {
+		r.log.Error(logrus.ErrorLevel, "Retrieve", "Error scanning audit entries", err)
+		return nil, 0, 0, terrors.InternalService("retrieve_audit", "Error retrieving audit entries from the database", map[string]string{})
+	}
+
+	return models, pages, total, nil
+}
+
+func setFilters(q *bun.SelectQuery, filter *Metadata) *bun.SelectQuery {
+	if filter.ActorID != nil {
+		q = q.Where("actor_id = ?", *filter.ActorID)
+	}
+	if filter.Role != "" {
+		q = q.Where("role = ?", filter.Role)
+	}
+	if filter.Action != "" {
+		q = q.Where("action = ?", filter.Action)
+	}
+	if filter.Resource != "" {
+		q = q.Where("resource = ?", filter.Resource)
+	}
+	if filter.TargetID != "" {
+		q = q.Where("target_id = ?", filter.TargetID)
+	}
+	if filter.RequestID != "" {
+		q = q.Where("request_id = ?", filter.RequestID)
+	}
+	if filter.From != nil {
+		q = q.Where("created_at >= ?", filter.From)
+	}
+	if filter.To != nil {
+		q = q.Where("created_at <= ?", filter.To)
+	}
+
+	return q
+}
@@ -0,0 +1,38 @@
+package audit
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/jmontesinos91/collector/domains/pagination"
+	"github.com/uptrace/bun"
+)
+
+// Model Database model for audit entries, the table is append-only
+type Model struct {
+	bun.BaseModel `bun:"table:audit_log"`
+
+	ID        string          `bun:"id,pk"`
+	ActorID   int             `bun:"actor_id"`
+	Role      string          `bun:"role"`
+	Action    string          `bun:"action"`
+	Resource  string          `bun:"resource"`
+	TargetID  string          `bun:"target_id"`
+	Before    json.RawMessage `bun:"before,type:jsonb,nullzero"`
+	After     json.RawMessage `bun:"after,type:jsonb,nullzero"`
+	RequestID string          `bun:"request_id"`
+	CreatedAt time.Time       `bun:"created_at"`
+}
+
+// Metadata struct filter for repository layer
+type Metadata struct {
+	ActorID   *int
+	Role      string
+	Action    string
+	Resource  string
+	TargetID  string
+	RequestID string
+	From      *time.Time
+	To        *time.Time
+	Filter    pagination.Filter
+}
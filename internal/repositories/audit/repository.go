@@ -0,0 +1,11 @@
+package audit
+
+import (
+	"context"
+)
+
+// IRepository interface
+type IRepository interface {
+	Create(ctx context.Context, model *Model) error
+	Retrieve(ctx context.Context, filter *Metadata) ([]Model, int, int, error)
+}
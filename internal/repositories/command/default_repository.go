@@ -0,0 +1,187 @@
+package command
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"slices"
+	"time"
+
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/terrors"
+	"github.com/sirupsen/logrus"
+	"github.com/uptrace/bun"
+)
+
+// DatabaseRepository struct
+type DatabaseRepository struct {
+	log *logger.ContextLogger
+	db  *bun.DB
+}
+
+// NewDatabaseRepository creates an instance of DatabaseRepository
+func NewDatabaseRepository(l *logger.ContextLogger, conn *bun.DB) *DatabaseRepository {
+	return &DatabaseRepository{
+		log: l,
+		db:  conn,
+	}
+}
+
+// Create Handles the creation of a new device command on database
+func (r *DatabaseRepository) Create(ctx context.Context, model *Model) error {
+	_, err := r.db.NewInsert().
+		Model(model).
+		Exec(ctx)
+	if err != nil {
+		r.log.Error(logrus.ErrorLevel, "Create", "Error creating device command", err)
+		return terrors.InternalService("create_command", "Failed to create device command in the database", map[string]string{})
+	}
+	return nil
+}
+
+// FindByID Handles to find a command of the given device
+func (r *DatabaseRepository) FindByID(ctx context.Context, imei, commandID string) (*Model, error) {
+	model := &Model{}
+	err := r.db.NewSelect().
+		Model(model).
+		Where("id = ?", commandID).
+		Where("imei = ?", imei).
+		Limit(1).
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, terrors.New(terrors.ErrNotFound, "Device command not found", map[string]string{})
+		}
+		r.log.Error(logrus.ErrorLevel, "FindByID", "Error finding device command", err)
+		return nil, terrors.InternalService("find_command", "Failed to retrieve device command from the database", map[string]string{})
+	}
+
+	return model, nil
+}
+
+// Retrieve Retrieves a page of the commands of a device, newest first
+func (r *DatabaseRepository) Retrieve(ctx context.Context, filter *Metadata) ([]Model, int, int, error) {
+	var models []Model
+
+	query := r.db.NewSelect().
+		Model((*Model)(nil)).
+		Where("imei = ?", filter.IMEI)
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+
+	pages, total := 0, 0
+	if filter.Filter.ShouldCount() {
+		var err error
+		total, err = query.Count(ctx)
+		if err != nil {
+			r.log.Error(logrus.ErrorLevel, "Retrieve", "Error counting device commands", err)
+			return nil, 0, 0, terrors.InternalService("count_error", "Error counting records in the database", map[string]string{})
+		}
+		pages = int(math.Ceil(float64(total) / float64(filter.Filter.Size)))
+	}
+
+	err := query.
+		OrderExpr("created_at DESC").
+		OrderExpr("id DESC").
+		Limit(filter.Filter.Size).
+		Offset((filter.Filter.Page-1)*filter.Filter.Size).
+		Scan(ctx, &models)
+	if err != nil {
+		r.log.Error(logrus.ErrorLevel, "Retrieve", "Error scanning device commands", err)
+		return nil, 0, 0, terrors.InternalService("retrieve_commands", "Error retrieving device commands from the database", map[string]string{})
+	}
+
+	return models, pages, total, nil
+}
+
+// ClaimPending Handles marking as sent the oldest pending commands of a device, the queued ones and the
+// ones sent longer than the ack timeout ago with attempts left. The claimed commands are returned in the
+// order they were queued. Concurrent frames of the device never claim the same command.
+func (r *DatabaseRepository) ClaimPending(ctx context.Context, imei string, now time.Time, ackTimeout time.Duration,
+	maxAttempts, limit int) ([]Model, error) {
+	var models []Model
+
+	pending := r.db.NewSelect().
+		Model((*Model)(nil)).
+		Column("id").
+		Where("imei = ?", imei).
+		WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Where("status = ?", StatusQueued).
+				WhereGroup(" OR ", func(q *bun.SelectQuery) *bun.SelectQuery {
+					return q.Where("status = ?", StatusSent).
+						Where("sent_at <= ?", now.Add(-ackTimeout)).
+						Where("attempts < ?", maxAttempts)
+				})
+		}).
+		Order("created_at ASC").
+		Limit(limit).
+		For("UPDATE SKIP LOCKED")
+
+	err := r.db.NewUpdate().
+		Model((*Model)(nil)).
+		Set("status = ?", StatusSent).
+		Set("attempts = attempts + 1").
+		Set("sent_at = ?", now).
+		Set("updated_at = ?", now).
+		Where("id IN (?)", pending).
+		Returning("*").
+		Scan(ctx, &models)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		r.log.Error(logrus.ErrorLevel, "ClaimPending", "Error claiming device commands", err)
+		return nil, terrors.InternalService("claim_commands", "Failed to claim device commands in the database", map[string]string{})
+	}
+
+	slices.SortFunc(models, func(a, b Model) int {
+		return a.CreatedAt.Compare(b.CreatedAt)
+	})
+
+	return models, nil
+}
+
+// Acknowledge Handles marking as acknowledged the sent commands of a device, including the ones expired
+// before the device acknowledged them. The commands of other devices and the ones never sent are left untouched
+func (r *DatabaseRepository) Acknowledge(ctx context.Context, imei string, commandIDs []string, now time.Time) (int, error) {
+	if len(commandIDs) == 0 {
+		return 0, nil
+	}
+
+	res, err := r.db.NewUpdate().
+		Model((*Model)(nil)).
+		Set("status = ?", StatusAcknowledged).
+		Set("acknowledged_at = ?", now).
+		Set("updated_at = ?", now).
+		Where("id IN (?)", bun.In(commandIDs)).
+		Where("imei = ?", imei).
+		Where("status IN (?)", bun.In([]string{StatusSent, StatusExpired})).
+		Exec(ctx)
+	if err != nil {
+		r.log.Error(logrus.ErrorLevel, "Acknowledge", "Error acknowledging device commands", err)
+		return 0, terrors.InternalService("acknowledge_commands", "Failed to acknowledge device commands in the database", map[string]string{})
+	}
+
+	rows, err := res.RowsAffected()
+	return int(rows), err
+}
+
+// Expire Handles marking as expired the commands sent every attempt and not acknowledged within the
+// ack timeout of the last one
+func (r *DatabaseRepository) Expire(ctx context.Context, now time.Time, ackTimeout time.Duration, maxAttempts int) (int, error) {
+	res, err := r.db.NewUpdate().
+		Model((*Model)(nil)).
+		Set("status = ?", StatusExpired).
+		Set("expired_at = ?", now).
+		Set("updated_at = ?", now).
+		Where("status = ?", StatusSent).
+		Where("sent_at <= ?", now.Add(-ackTimeout)).
+		Where("attempts >= ?", maxAttempts).
+		Exec(ctx)
+	if err != nil {
+		r.log.Error(logrus.ErrorLevel, "Expire", "Error expiring device commands", err)
+		return 0, terrors.InternalService("expire_commands", "Failed to expire device commands in the database", map[string]string{})
+	}
+
+	rows, err := res.RowsAffected()
+	return int(rows), err
+}
@@ -0,0 +1,43 @@
+package command
+
+import (
+	"time"
+
+	"github.com/jmontesinos91/collector/domains/pagination"
+	"github.com/uptrace/bun"
+)
+
+// Command statuses
+const (
+	StatusQueued       = "queued"
+	StatusSent         = "sent"
+	StatusAcknowledged = "acknowledged"
+	// StatusExpired the command was sent every attempt without being acknowledged
+	StatusExpired = "expired"
+)
+
+// Model Database model for the downlink commands queued per device
+type Model struct {
+	bun.BaseModel `bun:"table:device_commands"`
+
+	ID             string         `bun:"id,pk"`
+	IMEI           string         `bun:"imei"`
+	TenantID       int            `bun:"tenant_id"`
+	Type           string         `bun:"type"`
+	Params         map[string]int `bun:"params,type:jsonb"`
+	Status         string         `bun:"status"`
+	Attempts       int            `bun:"attempts"`
+	CreatedBy      int            `bun:"created_by"`
+	CreatedAt      time.Time      `bun:"created_at"`
+	UpdatedAt      time.Time      `bun:"updated_at"`
+	SentAt         *time.Time     `bun:"sent_at"`
+	AcknowledgedAt *time.Time     `bun:"acknowledged_at"`
+	ExpiredAt      *time.Time     `bun:"expired_at"`
+}
+
+// Metadata struct filter for the commands of a device
+type Metadata struct {
+	IMEI   string
+	Status string
+	Filter pagination.Filter
+}
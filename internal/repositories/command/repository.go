@@ -0,0 +1,16 @@
+package command
+
+import (
+	"context"
+	"time"
+)
+
+// IRepository interface
+type IRepository interface {
+	Create(ctx context.Context, model *Model) error
+	FindByID(ctx context.Context, imei, commandID string) (*Model, error)
+	Retrieve(ctx context.Context, filter *Metadata) ([]Model, int, int, error)
+	ClaimPending(ctx context.Context, imei string, now time.Time, ackTimeout time.Duration, maxAttempts, limit int) ([]Model, error)
+	Acknowledge(ctx context.Context, imei string, commandIDs []string, now time.Time) (int, error)
+	Expire(ctx context.Context, now time.Time, ackTimeout time.Duration, maxAttempts int) (int, error)
+}
@@ -0,0 +1,232 @@
+package exportjob
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/terrors"
+	"github.com/uptrace/bun"
+)
+
+// DatabaseRepository struct
+type DatabaseRepository struct {
+	log *logger.ContextLogger
+	db  *bun.DB
+}
+
+// NewDatabaseRepository creates an instance of DatabaseRepository
+func NewDatabaseRepository(l *logger.ContextLogger, conn *bun.DB) *DatabaseRepository {
+	return &DatabaseRepository{
+		log: l,
+		db:  conn,
+	}
+}
+
+// Create Handles the creation of a new export job record on database
+func (r *DatabaseRepository) Create(ctx context.Context, model *Model) error {
+	_, err := r.db.NewInsert().
+		Model(model).
+		Exec(ctx)
+	if err != nil {
+		return terrors.InternalService("create_export_job", "Failed to create export job in the database", map[string]string{})
+	}
+	return nil
+}
+
+// FindByID Handles to find an export job owned by the given user
+func (r *DatabaseRepository) FindByID(ctx context.Context, jobID string, userID int) (*Model, error) {
+	model := &Model{}
+	err := r.db.NewSelect().
+		Model(model).
+		Where("id = ?", jobID).
+		Where("user_id = ?", userID).
+		Limit(1).
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, terrors.New(terrors.ErrNotFound, "Export job not found", map[string]string{})
+		}
+		return nil, terrors.InternalService("find_export_job", "Failed to retrieve export job from the database", map[string]string{})
+	}
+
+	return model, nil
+}
+
+// FindByUser Handles to find the export jobs of the given user, newest first
+func (r *DatabaseRepository) FindByUser(ctx context.Context, userID int) ([]Model, error) {
+	var models []Model
+	err := r.db.NewSelect().
+		Model(&models).
+		Where("user_id = ?", userID).
+		Order("created_at DESC").
+		Scan(ctx)
+	if err != nil {
+		return nil, terrors.InternalService("find_export_jobs", "Failed to retrieve export jobs from the database", map[string]string{})
+	}
+
+	return models, nil
+}
+
+// ClaimNext Marks the oldest pending job, or running job whose lease expired with attempts left, as running
+// under a new lease and returns it, nil when there is nothing to do. Rows locked by other workers are
+// skipped so several instances can share the queue.
+func (r *DatabaseRepository) ClaimNext(ctx context.Context, now time.Time, lease time.Duration, maxAttempts int) (*Model, error) {
+	model := &Model{}
+
+	pending := r.db.NewSelect().
+		Model((*Model)(nil)).
+		Column("id").
+		WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
+			return q.Where("status = ?", StatusPending).
+				WhereOr("status = ? AND COALESCE(lease_expires_at, updated_at) <= ? AND attempts < ?",
+					StatusRunning, now, maxAttempts)
+		}).
+		Order("created_at ASC").
+		Limit(1).
+		For("UPDATE SKIP LOCKED")
+
+	err := r.db.NewUpdate().
+		Model(model).
+		Set("status = ?", StatusRunning).
+		Set("attempts = attempts + 1").
+		Set("lease_expires_at = ?", now.Add(lease)).
+		Set("rows_written = 0").
+		Set("updated_at = ?", now).
+		Where("id = (?)", pending).
+		Returning("*").
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return model, nil
+}
+
+// RenewLease Handles extending the lease of a running job, ErrLeaseLost is returned when the job was
+// claimed again by another attempt
+func (r *DatabaseRepository) RenewLease(ctx context.Context, jobID string, attempt int, leaseExpiresAt time.Time) error {
+	return fenced(r.db.NewUpdate().
+		Model((*Model)(nil)).
+		Set("lease_expires_at = ?", leaseExpiresAt).
+		Where("id = ?", jobID).
+		Where("status = ?", StatusRunning).
+		Where("attempts = ?", attempt).
+		Exec(ctx))
+}
+
+// FailAbandoned Handles update as failed the running jobs whose lease expired without attempts left,
+// the failed jobs are returned
+func (r *DatabaseRepository) FailAbandoned(ctx context.Context, now time.Time, maxAttempts int, reason string) ([]string, error) {
+	var failed []string
+	_, err := r.db.NewUpdate().
+		Model((*Model)(nil)).
+		Set("status = ?", StatusFailed).
+		Set("error = ?", reason).
+		Set("lease_expires_at = NULL").
+		Set("completed_at = ?", now).
+		Set("updated_at = ?", now).
+		Where("status = ?", StatusRunning).
+		Where("COALESCE(lease_expires_at, updated_at) <= ?", now).
+		Where("attempts >= ?", maxAttempts).
+		Returning("id").
+		Exec(ctx, &failed)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	return failed, nil
+}
+
+// UpdateProgress Handles update the rows written by a running job, ErrLeaseLost is returned when the
+// job was claimed again by another attempt
+func (r *DatabaseRepository) UpdateProgress(ctx context.Context, jobID string, attempt int, rowsWritten, totalRows int) error {
+	return fenced(r.db.NewUpdate().
+		Model((*Model)(nil)).
+		Set("rows_written = ?", rowsWritten).
+		Set("total_rows = ?", totalRows).
+		Set("updated_at = ?", time.Now().UTC()).
+		Where("id = ?", jobID).
+		Where("status = ?", StatusRunning).
+		Where("attempts = ?", attempt).
+		Exec(ctx))
+}
+
+// Complete Handles update a job as completed, ErrLeaseLost is returned when the job was claimed again
+// by another attempt
+func (r *DatabaseRepository) Complete(ctx context.Context, jobID string, attempt int, filePath string, fileSize int64,
+	rowsWritten int, expiresAt time.Time) error {
+	now := time.Now().UTC()
+	return fenced(r.db.NewUpdate().
+		Model((*Model)(nil)).
+		Set("status = ?", StatusCompleted).
+		Set("file_path = ?", filePath).
+		Set("file_size = ?", fileSize).
+		Set("lease_expires_at = NULL").
+		Set("rows_written = ?", rowsWritten).
+		Set("completed_at = ?", now).
+		Set("expires_at = ?", expiresAt).
+		Set("updated_at = ?", now).
+		Where("id = ?", jobID).
+		Where("status = ?", StatusRunning).
+		Where("attempts = ?", attempt).
+		Exec(ctx))
+}
+
+// Fail Handles update a job as failed, ErrLeaseLost is returned when the job was claimed again by
+// another attempt
+func (r *DatabaseRepository) Fail(ctx context.Context, jobID string, attempt int, reason string) error {
+	now := time.Now().UTC()
+	return fenced(r.db.NewUpdate().
+		Model((*Model)(nil)).
+		Set("status = ?", StatusFailed).
+		Set("error = ?", reason).
+		Set("lease_expires_at = NULL").
+		Set("completed_at = ?", now).
+		Set("updated_at = ?", now).
+		Where("id = ?", jobID).
+		Where("status = ?", StatusRunning).
+		Where("attempts = ?", attempt).
+		Exec(ctx))
+}
+
+// FindExpired Handles to find the completed jobs whose file already expired
+func (r *DatabaseRepository) FindExpired(ctx context.Context, now time.Time) ([]Model, error) {
+	var models []Model
+	err := r.db.NewSelect().
+		Model(&models).
+		Where("status = ?", StatusCompleted).
+		Where("expires_at < ?", now).
+		Scan(ctx)
+
+	return models, err
+}
+
+// MarkExpired Handles update a job as expired once its file is gone
+func (r *DatabaseRepository) MarkExpired(ctx context.Context, jobID string) error {
+	_, err := r.db.NewUpdate().
+		Model((*Model)(nil)).
+		Set("status = ?", StatusExpired).
+		Set("updated_at = ?", time.Now().UTC()).
+		Where("id = ?", jobID).
+		Exec(ctx)
+	return err
+}
+
+// fenced reports ErrLeaseLost when a write guarded by the attempt of a job did not match it
+func fenced(result sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+
+	if affected, err := result.RowsAffected(); err == nil && affected == 0 {
+		return ErrLeaseLost
+	}
+
+	return nil
+}
@@ -0,0 +1,46 @@
+package exportjob
+
+import (
+	"errors"
+	"time"
+
+	"github.com/uptrace/bun"
+)
+
+// Job statuses
+const (
+	StatusPending   = "pending"
+	StatusRunning   = "running"
+	StatusCompleted = "completed"
+	StatusFailed    = "failed"
+	StatusExpired   = "expired"
+)
+
+// ErrLeaseLost is returned by the writes of a worker whose job was claimed again by another attempt
+var ErrLeaseLost = errors.New("export job is no longer held by this attempt")
+
+// Model Database model for export jobs
+type Model struct {
+	bun.BaseModel `bun:"table:export_jobs"`
+
+	ID          string     `bun:"id,pk"`
+	UserID      int        `bun:"user_id"`
+	Status      string     `bun:"status"`
+	Format      string     `bun:"format"`
+	Query       string     `bun:"query"`
+	AllTenants  bool       `bun:"all_tenants"`
+	Tenants     []int      `bun:"tenants,array"`
+	RowsWritten int        `bun:"rows_written"`
+	TotalRows   int        `bun:"total_rows"`
+	FilePath    string     `bun:"file_path"`
+	FileSize    int64      `bun:"file_size"`
+	Error       string     `bun:"error"`
+	CreatedAt   time.Time  `bun:"created_at"`
+	UpdatedAt   time.Time  `bun:"updated_at"`
+	CompletedAt *time.Time `bun:"completed_at"`
+	ExpiresAt   *time.Time `bun:"expires_at"`
+	// A running job whose lease expired was left behind by its worker, the attempts count its claims and
+	// guard the writes of the worker holding the job
+	Attempts       int        `bun:"attempts"`
+	LeaseExpiresAt *time.Time `bun:"lease_expires_at"`
+}
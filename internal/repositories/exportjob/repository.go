@@ -0,0 +1,22 @@
+package exportjob
+
+import (
+	"context"
+	"time"
+)
+
+// IRepository interface
+type IRepository interface {
+	Create(ctx context.Context, model *Model) error
+	FindByID(ctx context.Context, jobID string, userID int) (*Model, error)
+	FindByUser(ctx context.Context, userID int) ([]Model, error)
+	ClaimNext(ctx context.Context, now time.Time, lease time.Duration, maxAttempts int) (*Model, error)
+	RenewLease(ctx context.Context, jobID string, attempt int, leaseExpiresAt time.Time) error
+	FailAbandoned(ctx context.Context, now time.Time, maxAttempts int, reason string) ([]string, error)
+	UpdateProgress(ctx context.Context, jobID string, attempt int, rowsWritten, totalRows int) error
+	Complete(ctx context.Context, jobID string, attempt int, filePath string, fileSize int64, rowsWritten int,
+		expiresAt time.Time) error
+	Fail(ctx context.Context, jobID string, attempt int, reason string) error
+	FindExpired(ctx context.Context, now time.Time) ([]Model, error)
+	MarkExpired(ctx context.Context, jobID string) error
+}
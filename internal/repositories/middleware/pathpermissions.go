@@ -12,29 +12,97 @@ type Paths string
 const (
 	read         Paths = "/v1/traffic"
 	export       Paths = "/v1/traffic/export"
+	exports      Paths = "/v1/traffic/exports/{id}/download"
+	stats        Paths = "/v1/traffic/stats"
+	stream       Paths = "/v1/traffic/stream/ws"
 	resetcounter Paths = "/v1/traffic/counter"
+	bulk         Paths = "/v1/traffic/bulk"
+	restore      Paths = "/v1/traffic/{id}/restore"
+	cache        Paths = "/v1/admin/cache"
+	audit        Paths = "/v1/audit"
+	webhooks     Paths = "/v1/webhooks/{id}/deliveries"
+	commands     Paths = "/v1/devices/{imei}/commands/{id}"
 )
 
 func ValidatePermission(permission sts.Permission, path string, method string) bool {
-	action := strings.ReplaceAll(permission.Action, "_", "")
-	action = strings.ToLower(action)
+	action := normalizeAction(permission.Action)
 
 	switch action {
 	case "read":
 		if strings.Contains(string(read), path) && method == http.MethodGet {
 			return true
 		}
+		if strings.Contains(string(stats), path) && method == http.MethodGet {
+			return true
+		}
+		// Contains both the server-sent events and the websocket routes
+		if strings.Contains(string(stream), path) && method == http.MethodGet {
+			return true
+		}
 	case "export":
 		if strings.Contains(string(export), path) && method == http.MethodGet {
 			return true
 		}
+		if strings.Contains(string(exports), path) && (method == http.MethodGet || method == http.MethodPost) {
+			return true
+		}
 	case "resetcounter":
 		if strings.Contains(string(resetcounter), path) && method == http.MethodPost {
 			return true
 		}
+	case "restore":
+		// Exact match, the restore path contains the delete route
+		if string(restore) == path && method == http.MethodPost {
+			return true
+		}
+	case "bulkdelete", "bulkresetcounter", "bulkmarknotified":
+		if strings.Contains(string(bulk), path) && method == http.MethodPost {
+			return true
+		}
+	case "audit":
+		if strings.Contains(string(audit), path) && method == http.MethodGet {
+			return true
+		}
+	case "webhooks":
+		// Contains every route of the webhook subscriptions
+		if strings.Contains(string(webhooks), path) {
+			switch method {
+			case http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete:
+				return true
+			}
+		}
+	case "commands":
+		// Contains every route of the device commands
+		if strings.Contains(string(commands), path) && (method == http.MethodGet || method == http.MethodPost) {
+			return true
+		}
+	case "invalidatecache":
+		if strings.Contains(string(cache), path) && method == http.MethodDelete {
+			return true
+		}
 	default:
 		return false
 	}
 
 	return false
 }
+
+// BulkAction returns the permission action required to run a bulk traffic operation
+func BulkAction(operation string) string {
+	return normalizeAction("bulk" + strings.ReplaceAll(operation, "-", ""))
+}
+
+// AllowsAction reports whether any of the permissions grants the action
+func AllowsAction(permissions []sts.Permission, action string) bool {
+	for _, permission := range permissions {
+		if normalizeAction(permission.Action) == normalizeAction(action) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func normalizeAction(action string) string {
+	return strings.ToLower(strings.ReplaceAll(action, "_", ""))
+}
@@ -0,0 +1,148 @@
+package partition
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/terrors"
+	"github.com/sirupsen/logrus"
+	"github.com/uptrace/bun"
+)
+
+// DatabaseRepository struct
+type DatabaseRepository struct {
+	log *logger.ContextLogger
+	db  *bun.DB
+}
+
+// NewDatabaseRepository creates an instance of DatabaseRepository
+func NewDatabaseRepository(l *logger.ContextLogger, conn *bun.DB) *DatabaseRepository {
+	return &DatabaseRepository{
+		log: l,
+		db:  conn,
+	}
+}
+
+// List Retrieves the partitions of the table with their bound expression, total size and estimated rows
+func (r *DatabaseRepository) List(ctx context.Context, table string) ([]Model, error) {
+	var models []Model
+
+	err := r.db.NewRaw(`
+		SELECT c.relname                            AS name,
+		       pg_get_expr(c.relpartbound, c.oid)   AS bound,
+		       pg_total_relation_size(c.oid)        AS size_bytes,
+		       GREATEST(c.reltuples, 0)::int8       AS rows
+		FROM pg_inherits i
+		         JOIN pg_class c ON c.oid = i.inhrelid
+		WHERE i.inhparent = to_regclass(?)
+		ORDER BY c.relname`, table).
+		Scan(ctx, &models)
+	if err != nil && err != sql.ErrNoRows {
+		r.log.Error(logrus.ErrorLevel, "List", "Error listing partitions", err)
+		return nil, terrors.InternalService("list_partitions", "Failed to list partitions from the database", map[string]string{})
+	}
+
+	return models, nil
+}
+
+// EnsureMonth Creates the partition holding the month of the given time when missing, returns the name of
+// the created partition or an empty string when it already existed
+func (r *DatabaseRepository) EnsureMonth(ctx context.Context, table string, month time.Time) (string, error) {
+	var created sql.NullString
+
+	err := r.db.NewRaw("SELECT ensure_monthly_partition(?, ?)", table, month.UTC()).
+		Scan(ctx, &created)
+	if err != nil {
+		r.log.Error(logrus.ErrorLevel, "EnsureMonth", "Error creating partition", err)
+		return "", terrors.InternalService("create_partition", "Failed to create partition in the database", map[string]string{})
+	}
+
+	return created.String, nil
+}
+
+// Detach Detaches the partition from the table so it stops being part of its queries
+func (r *DatabaseRepository) Detach(ctx context.Context, table string, partition string) error {
+	_, err := r.db.NewRaw("ALTER TABLE ? DETACH PARTITION ?", bun.Ident(table), bun.Ident(partition)).
+		Exec(ctx)
+	if err != nil {
+		r.log.Error(logrus.ErrorLevel, "Detach", "Error detaching partition", err)
+		return terrors.InternalService("detach_partition", "Failed to detach partition in the database", map[string]string{})
+	}
+
+	return nil
+}
+
+// Drop Drops a detached partition with all of its rows
+func (r *DatabaseRepository) Drop(ctx context.Context, partition string) error {
+	_, err := r.db.NewRaw("DROP TABLE IF EXISTS ?", bun.Ident(partition)).
+		Exec(ctx)
+	if err != nil {
+		r.log.Error(logrus.ErrorLevel, "Drop", "Error dropping partition", err)
+		return terrors.InternalService("drop_partition", "Failed to drop partition in the database", map[string]string{})
+	}
+
+	return nil
+}
+
+// Archive Moves a detached partition to the archive schema, keeping its rows out of the live tables
+func (r *DatabaseRepository) Archive(ctx context.Context, partition string, schema string) error {
+	_, err := r.db.NewRaw("ALTER TABLE ? SET SCHEMA ?", bun.Ident(partition), bun.Ident(schema)).
+		Exec(ctx)
+	if err != nil {
+		r.log.Error(logrus.ErrorLevel, "Archive", "Error archiving partition", err)
+		return terrors.InternalService("archive_partition", "Failed to archive partition in the database", map[string]string{})
+	}
+
+	return nil
+}
+
+// CountTenantRows Counts the rows of the tenant whose column is before the given time
+func (r *DatabaseRepository) CountTenantRows(ctx context.Context, table, column string, tenantID int, before time.Time) (int, error) {
+	count, err := r.db.NewSelect().
+		Table(table).
+		Where("tenant_id = ?", tenantID).
+		Where("? < ?", bun.Ident(column), before).
+		Count(ctx)
+	if err != nil {
+		r.log.Error(logrus.ErrorLevel, "CountTenantRows", "Error counting tenant rows", err)
+		return 0, terrors.InternalService("count_tenant_rows", "Failed to count tenant rows in the database", map[string]string{})
+	}
+
+	return count, nil
+}
+
+// PurgeTenantRows Deletes the rows of the tenant whose column is before the given time, rows are removed
+// in batches so the table is never locked for long
+func (r *DatabaseRepository) PurgeTenantRows(ctx context.Context, table, column string, tenantID int, before time.Time,
+	batchSize int) (int, error) {
+	purged := 0
+	for {
+		batch := r.db.NewSelect().
+			Table(table).
+			Column("id").
+			Where("tenant_id = ?", tenantID).
+			Where("? < ?", bun.Ident(column), before).
+			Limit(batchSize)
+
+		res, err := r.db.NewDelete().
+			TableExpr("?", bun.Ident(table)).
+			Where("id IN (?)", batch).
+			Exec(ctx)
+		if err != nil {
+			r.log.Error(logrus.ErrorLevel, "PurgeTenantRows", "Error purging tenant rows", err)
+			return purged, terrors.InternalService("purge_tenant_rows", "Failed to purge tenant rows from the database", map[string]string{})
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return purged, err
+		}
+		purged += int(rows)
+
+		if int(rows) < batchSize {
+			return purged, nil
+		}
+	}
+}
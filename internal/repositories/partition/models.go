@@ -0,0 +1,9 @@
+package partition
+
+// Model is a partition attached to a partitioned table as reported by the catalog
+type Model struct {
+	Name      string `bun:"name"`
+	Bound     string `bun:"bound"`
+	SizeBytes int64  `bun:"size_bytes"`
+	Rows      int64  `bun:"rows"`
+}
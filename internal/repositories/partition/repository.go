@@ -0,0 +1,17 @@
+package partition
+
+import (
+	"context"
+	"time"
+)
+
+// IRepository interface
+type IRepository interface {
+	List(ctx context.Context, table string) ([]Model, error)
+	EnsureMonth(ctx context.Context, table string, month time.Time) (string, error)
+	Detach(ctx context.Context, table string, partition string) error
+	Drop(ctx context.Context, partition string) error
+	Archive(ctx context.Context, partition string, schema string) error
+	CountTenantRows(ctx context.Context, table, column string, tenantID int, before time.Time) (int, error)
+	PurgeTenantRows(ctx context.Context, table, column string, tenantID int, before time.Time, batchSize int) (int, error)
+}
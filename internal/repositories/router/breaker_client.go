@@ -0,0 +1,82 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jmontesinos91/collector/domains/breaker"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrCircuitOpen is returned when the alarm validation client is not accepting calls
+var ErrCircuitOpen = errors.New("alarm_client: circuit breaker is open")
+
+// ErrTimeout is returned when the alarm validation does not answer within the call timeout
+var ErrTimeout = errors.New("alarm_client: alarm validation timed out")
+
+// ErrUnavailable is returned when the alarm validation could not be reached or answered with a server error
+var ErrUnavailable = errors.New("alarm_client: alarm validation unavailable")
+
+// IsUnavailable reports whether the alarm validation could not reach a decision, either the breaker is
+// open, the call timed out or the service failed, as opposed to a request that could not be built
+func IsUnavailable(err error) bool {
+	return errors.Is(err, ErrCircuitOpen) || errors.Is(err, ErrTimeout) || errors.Is(err, ErrUnavailable)
+}
+
+// BreakerClient circuit breaker decorator for IClient, it bounds every call with a timeout
+// and stops calling the wrapped client after repeated failures
+type BreakerClient struct {
+	log     *logger.ContextLogger
+	next    IClient
+	breaker *breaker.Breaker
+	timeout time.Duration
+}
+
+// NewBreakerClient creates a new BreakerClient, a zero timeout leaves calls unbounded
+func NewBreakerClient(l *logger.ContextLogger, next IClient, b *breaker.Breaker, timeout time.Duration) *BreakerClient {
+	return &BreakerClient{
+		log:     l,
+		next:    next,
+		breaker: b,
+		timeout: timeout,
+	}
+}
+
+// ValidateIMEI validates the alarm through the wrapped client unless the breaker is open, calls
+// abandoned by the caller are not counted as failures
+func (c *BreakerClient) ValidateIMEI(ctx context.Context, request Request) (*Response, error) {
+	if err := c.breaker.Allow(); err != nil {
+		return nil, ErrCircuitOpen
+	}
+
+	callCtx := ctx
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		callCtx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	response, err := c.next.ValidateIMEI(callCtx, request)
+	if err != nil {
+		if ctx.Err() != nil {
+			c.breaker.Release()
+			return nil, err
+		}
+
+		c.breaker.Failure()
+		if c.breaker.State() == breaker.Open {
+			c.log.Error(logrus.WarnLevel, "ValidateIMEI", "alarm_client: circuit breaker opened", err)
+		}
+		if errors.Is(callCtx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("%w: %v", ErrTimeout, err)
+		}
+		return nil, err
+	}
+
+	c.breaker.Success()
+
+	return response, nil
+}
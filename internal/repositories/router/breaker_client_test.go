@@ -0,0 +1,149 @@
+package router_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jmontesinos91/collector/domains/breaker"
+	"github.com/jmontesinos91/collector/internal/repositories/router"
+	"github.com/jmontesinos91/collector/internal/repositories/router/routermock"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestBreakerClient(t *testing.T) {
+	log := logger.NewContextLogger("BreakerClient", "debug", logger.TextFormat)
+	failure := errors.New("unit-test-error")
+
+	tests := []struct {
+		name       string
+		timeout    int
+		clientFunc func() *routermock.IClient
+		run        func(*router.BreakerClient) (*router.Response, error)
+		asserts    func(*testing.T, *routermock.IClient, *router.Response, error) bool
+	}{
+		{
+			name: "Success passes the response through",
+			clientFunc: func() *routermock.IClient {
+				clientMock := &routermock.IClient{}
+				clientMock.On("ValidateIMEI", mock.Anything, mock.Anything).Return(&router.Response{Success: true}, nil)
+				return clientMock
+			},
+			run: func(c *router.BreakerClient) (*router.Response, error) {
+				return c.ValidateIMEI(context.Background(), router.Request{IMEI: "861585041440544"})
+			},
+			asserts: func(t *testing.T, _ *routermock.IClient, response *router.Response, err error) bool {
+				return assert.NoError(t, err) && assert.True(t, response.Success)
+			},
+		},
+		{
+			name: "Failed call is not reported as unavailable",
+			clientFunc: func() *routermock.IClient {
+				clientMock := &routermock.IClient{}
+				clientMock.On("ValidateIMEI", mock.Anything, mock.Anything).Return(nil, failure)
+				return clientMock
+			},
+			run: func(c *router.BreakerClient) (*router.Response, error) {
+				return c.ValidateIMEI(context.Background(), router.Request{IMEI: "861585041440544"})
+			},
+			asserts: func(t *testing.T, _ *routermock.IClient, _ *router.Response, err error) bool {
+				return assert.ErrorIs(t, err, failure) && assert.False(t, router.IsUnavailable(err))
+			},
+		},
+		{
+			name: "Server error is reported as unavailable",
+			clientFunc: func() *routermock.IClient {
+				clientMock := &routermock.IClient{}
+				clientMock.On("ValidateIMEI", mock.Anything, mock.Anything).
+					Return(nil, fmt.Errorf("%w: service response with 503 status code", router.ErrUnavailable))
+				return clientMock
+			},
+			run: func(c *router.BreakerClient) (*router.Response, error) {
+				return c.ValidateIMEI(context.Background(), router.Request{IMEI: "861585041440544"})
+			},
+			asserts: func(t *testing.T, _ *routermock.IClient, _ *router.Response, err error) bool {
+				return assert.True(t, router.IsUnavailable(err))
+			},
+		},
+		{
+			name: "Calls cancelled by the caller do not open the breaker",
+			clientFunc: func() *routermock.IClient {
+				clientMock := &routermock.IClient{}
+				clientMock.On("ValidateIMEI", mock.Anything, mock.Anything).
+					Return(nil, func(ctx context.Context, _ router.Request) error {
+						return ctx.Err()
+					})
+				return clientMock
+			},
+			run: func(c *router.BreakerClient) (*router.Response, error) {
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				for i := 0; i < 2; i++ {
+					_, _ = c.ValidateIMEI(ctx, router.Request{IMEI: "861585041440544"})
+				}
+				return c.ValidateIMEI(ctx, router.Request{IMEI: "861585041440544"})
+			},
+			asserts: func(t *testing.T, clientMock *routermock.IClient, _ *router.Response, err error) bool {
+				return assert.ErrorIs(t, err, context.Canceled) && assert.False(t, router.IsUnavailable(err)) &&
+					clientMock.AssertNumberOfCalls(t, "ValidateIMEI", 3)
+			},
+		},
+		{
+			name:    "Timed out call is reported as unavailable",
+			timeout: 1,
+			clientFunc: func() *routermock.IClient {
+				clientMock := &routermock.IClient{}
+				clientMock.On("ValidateIMEI", mock.Anything, mock.Anything).
+					Return(func(ctx context.Context, _ router.Request) *router.Response {
+						<-ctx.Done()
+						return nil
+					}, func(ctx context.Context, _ router.Request) error {
+						return ctx.Err()
+					})
+				return clientMock
+			},
+			run: func(c *router.BreakerClient) (*router.Response, error) {
+				return c.ValidateIMEI(context.Background(), router.Request{IMEI: "861585041440544"})
+			},
+			asserts: func(t *testing.T, _ *routermock.IClient, _ *router.Response, err error) bool {
+				return assert.ErrorIs(t, err, router.ErrTimeout) && assert.True(t, router.IsUnavailable(err))
+			},
+		},
+		{
+			name: "Open breaker skips the wrapped client",
+			clientFunc: func() *routermock.IClient {
+				clientMock := &routermock.IClient{}
+				clientMock.On("ValidateIMEI", mock.Anything, mock.Anything).Return(nil, failure).Times(2)
+				return clientMock
+			},
+			run: func(c *router.BreakerClient) (*router.Response, error) {
+				for i := 0; i < 2; i++ {
+					_, _ = c.ValidateIMEI(context.Background(), router.Request{IMEI: "861585041440544"})
+				}
+				return c.ValidateIMEI(context.Background(), router.Request{IMEI: "861585041440544"})
+			},
+			asserts: func(t *testing.T, clientMock *routermock.IClient, _ *router.Response, err error) bool {
+				return assert.ErrorIs(t, err, router.ErrCircuitOpen) && assert.True(t, router.IsUnavailable(err)) &&
+					clientMock.AssertNumberOfCalls(t, "ValidateIMEI", 2)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			clientMock := tc.clientFunc()
+			client := router.NewBreakerClient(log, clientMock, breaker.New(2, time.Minute, 1),
+				time.Duration(tc.timeout)*time.Millisecond)
+
+			response, err := tc.run(client)
+
+			if !tc.asserts(t, clientMock, response, err) {
+				t.Errorf("Assert error on test = '%v'", tc.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,30 @@
+package router
+
+import (
+	"context"
+)
+
+// ChainClient validates alarms with a primary client and falls back to a secondary one
+// when the primary could not reach a decision
+type ChainClient struct {
+	primary   IClient
+	secondary IClient
+}
+
+// NewChainClient creates a new ChainClient
+func NewChainClient(primary, secondary IClient) *ChainClient {
+	return &ChainClient{
+		primary:   primary,
+		secondary: secondary,
+	}
+}
+
+// ValidateIMEI validates the alarm with the primary client, errors are retried with the secondary one
+func (c *ChainClient) ValidateIMEI(ctx context.Context, request Request) (*Response, error) {
+	response, err := c.primary.ValidateIMEI(ctx, request)
+	if err == nil {
+		return response, nil
+	}
+
+	return c.secondary.ValidateIMEI(ctx, request)
+}
@@ -0,0 +1,103 @@
+package router_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jmontesinos91/collector/internal/repositories/router"
+	"github.com/jmontesinos91/collector/internal/repositories/router/routermock"
+	"github.com/jmontesinos91/collector/internal/repositories/routerold"
+	"github.com/jmontesinos91/collector/internal/repositories/routerold/routeroldmocks"
+	"github.com/jmontesinos91/collector/internal/repositories/unitsold"
+	"github.com/jmontesinos91/collector/internal/repositories/unitsold/unitsoldmocks"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/terrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestChainClient(t *testing.T) {
+	log := logger.NewContextLogger("ChainClient", "debug", logger.TextFormat)
+
+	tests := []struct {
+		name       string
+		routerFunc func() *routeroldmocks.IRepository
+		asserts    func(*testing.T, *routermock.IClient, *router.Response, error) bool
+	}{
+		{
+			name: "Registered device is decided locally",
+			routerFunc: func() *routeroldmocks.IRepository {
+				routerRepo := &routeroldmocks.IRepository{}
+				routerRepo.On("FindByIMEI", mock.Anything, mock.Anything).
+					Return(&routerold.RouterModel{ID: 1, TenantID: 4, Active: 1}, nil)
+				return routerRepo
+			},
+			asserts: func(t *testing.T, remote *routermock.IClient, response *router.Response, err error) bool {
+				return assert.NoError(t, err) && assert.Equal(t, "Alarm validated locally", response.Message) &&
+					remote.AssertNotCalled(t, "ValidateIMEI", mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name: "Registered device rejected locally is not sent to the remote client",
+			routerFunc: func() *routeroldmocks.IRepository {
+				routerRepo := &routeroldmocks.IRepository{}
+				routerRepo.On("FindByIMEI", mock.Anything, mock.Anything).
+					Return(&routerold.RouterModel{ID: 1, TenantID: 4, Active: 0}, nil)
+				return routerRepo
+			},
+			asserts: func(t *testing.T, remote *routermock.IClient, response *router.Response, err error) bool {
+				return assert.NoError(t, err) && assert.False(t, response.Success) &&
+					remote.AssertNotCalled(t, "ValidateIMEI", mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name: "Unknown device falls through to the remote client",
+			routerFunc: func() *routeroldmocks.IRepository {
+				routerRepo := &routeroldmocks.IRepository{}
+				routerRepo.On("FindByIMEI", mock.Anything, mock.Anything).
+					Return(nil, terrors.New(terrors.ErrNotFound, "Router information not found", map[string]string{}))
+				return routerRepo
+			},
+			asserts: func(t *testing.T, remote *routermock.IClient, response *router.Response, err error) bool {
+				return assert.NoError(t, err) && assert.Equal(t, "unit-test-remote", response.Message) &&
+					remote.AssertNumberOfCalls(t, "ValidateIMEI", 1)
+			},
+		},
+		{
+			name: "Registry failure falls through to the remote client",
+			routerFunc: func() *routeroldmocks.IRepository {
+				routerRepo := &routeroldmocks.IRepository{}
+				routerRepo.On("FindByIMEI", mock.Anything, mock.Anything).Return(nil, errors.New("unit-test-error"))
+				return routerRepo
+			},
+			asserts: func(t *testing.T, remote *routermock.IClient, response *router.Response, err error) bool {
+				return assert.NoError(t, err) && assert.Equal(t, "unit-test-remote", response.Message) &&
+					remote.AssertNumberOfCalls(t, "ValidateIMEI", 1)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			unitsRepo := &unitsoldmocks.IRepository{}
+			unitsRepo.On("FindByRouterID", mock.Anything, mock.Anything).
+				Return(&unitsold.UnitsModel{ID: 10, RouterID: 1, IsActive: true}, nil)
+			remote := &routermock.IClient{}
+			remote.On("ValidateIMEI", mock.Anything, mock.Anything).
+				Return(&router.Response{Success: true, Message: "unit-test-remote"}, nil)
+
+			// Local-first mode
+			local := router.NewLocalClient(log, tc.routerFunc(), unitsRepo, router.LocalRules{
+				RequireActiveRouter: true,
+				DeferUnknownDevices: true,
+			})
+			client := router.NewChainClient(local, remote)
+			response, err := client.ValidateIMEI(context.Background(), router.Request{IMEI: "861585041440544", AlarmType: "1"})
+
+			if !tc.asserts(t, remote, response, err) {
+				t.Errorf("Assert error on test = '%v'", tc.name)
+			}
+		})
+	}
+}
@@ -74,7 +74,7 @@ func (c *DefaultWebClient) ValidateIMEI(ctx context.Context, request Request) (*
 	response, err := ctxhttp.Do(ctx, c.httpClient, req)
 
 	if err != nil {
-		return nil, errors.New("alarm_client: error doing request: " + err.Error())
+		return nil, fmt.Errorf("%w: error doing request: %v", ErrUnavailable, err)
 	}
 	// Defer closing of the response body
 	defer func() {
@@ -87,15 +87,16 @@ func (c *DefaultWebClient) ValidateIMEI(ctx context.Context, request Request) (*
 	// Read response
 	body, err := io.ReadAll(response.Body)
 	if err != nil {
-		return nil, fmt.Errorf("alarm_client: error reading response: %v", err)
+		return nil, fmt.Errorf("%w: error reading response: %v", ErrUnavailable, err)
 	}
 	// If status is not a 2xx response return an error
 	if response.StatusCode < 200 || response.StatusCode >= 500 {
-		return nil, errors.New("alarm_client: service response with " + string(rune(response.StatusCode)) + " status code, response body: " + string(body))
+		return nil, fmt.Errorf("%w: service response with %d status code, response body: %s", ErrUnavailable,
+			response.StatusCode, string(body))
 	}
 
 	if err := json.Unmarshal(body, &res); err != nil {
-		return nil, errors.New("alarm_client: error parsing response body: " + err.Error())
+		return nil, fmt.Errorf("%w: error parsing response body: %v", ErrUnavailable, err)
 	}
 
 	return &res, nil
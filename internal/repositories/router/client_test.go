@@ -0,0 +1,70 @@
+package router_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/jmontesinos91/collector/internal/repositories/router"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/osecurity/services/omnibackend"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDefaultWebClient(t *testing.T) {
+	log := logger.NewContextLogger("DefaultWebClient", "debug", logger.TextFormat)
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		closed  bool
+		asserts func(*testing.T, *router.Response, error) bool
+	}{
+		{
+			name: "Validated alarm",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				_, _ = w.Write([]byte(`{"success":true,"message":"ok"}`))
+			},
+			asserts: func(t *testing.T, response *router.Response, err error) bool {
+				return assert.NoError(t, err) && assert.True(t, response.Success)
+			},
+		},
+		{
+			name: "Server error is reported as unavailable",
+			handler: func(w http.ResponseWriter, _ *http.Request) {
+				w.WriteHeader(http.StatusBadGateway)
+			},
+			asserts: func(t *testing.T, _ *router.Response, err error) bool {
+				return assert.ErrorIs(t, err, router.ErrUnavailable)
+			},
+		},
+		{
+			name:   "Refused connection is reported as unavailable",
+			closed: true,
+			asserts: func(t *testing.T, _ *router.Response, err error) bool {
+				return assert.ErrorIs(t, err, router.ErrUnavailable)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			server := httptest.NewServer(tc.handler)
+			defer server.Close()
+			if tc.closed {
+				server.Close()
+			}
+
+			client := router.NewRouterService(log, omnibackend.OmniViewConfigurations{
+				Server:           server.URL,
+				TimeoutInSeconds: 5,
+			})
+			response, err := client.ValidateIMEI(context.Background(), router.Request{IMEI: "861585041440544"})
+
+			if !tc.asserts(t, response, err) {
+				t.Errorf("Assert error on test = '%v'", tc.name)
+			}
+		})
+	}
+}
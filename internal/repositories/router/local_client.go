@@ -0,0 +1,131 @@
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+
+	"github.com/jmontesinos91/collector/internal/repositories/routerold"
+	"github.com/jmontesinos91/collector/internal/repositories/unitsold"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/terrors"
+	"github.com/sirupsen/logrus"
+)
+
+// ErrUnknownDevice is returned by LocalClient, when configured to defer, for devices missing in the registry
+var ErrUnknownDevice = errors.New("alarm_client: device not found in the local registry")
+
+// VirtualButtonAlarmType alarm type sent by the virtual panic button
+const VirtualButtonAlarmType = "3"
+
+// LocalRules rules applied by LocalClient to decide whether an alarm is valid
+type LocalRules struct {
+	RequireActiveRouter bool
+	AllowedTenants      []int
+	DeniedTenants       []int
+	// DeferUnknownDevices returns ErrUnknownDevice instead of rejecting the alarm, so a
+	// chained client can decide
+	DeferUnknownDevices bool
+}
+
+// LocalClient validates alarms against the legacy device registry without calling Omniview
+type LocalClient struct {
+	log     *logger.ContextLogger
+	routers routerold.IRepository
+	units   unitsold.IRepository
+	rules   LocalRules
+}
+
+// NewLocalClient creates a new LocalClient
+func NewLocalClient(l *logger.ContextLogger, routers routerold.IRepository, units unitsold.IRepository, rules LocalRules) *LocalClient {
+	return &LocalClient{
+		log:     l,
+		routers: routers,
+		units:   units,
+		rules:   rules,
+	}
+}
+
+// ValidateIMEI validates the alarm using the router and unit registered for the IMEI or unit ID
+func (c *LocalClient) ValidateIMEI(ctx context.Context, request Request) (*Response, error) {
+	routerModel, unit, err := c.findDevice(ctx, request)
+	if err != nil {
+		if !terrors.Is(err, terrors.ErrNotFound) {
+			c.log.Error(logrus.ErrorLevel, "ValidateIMEI", "alarm_client: error searching device", err)
+			return nil, fmt.Errorf("%w: error searching device -> %w", ErrUnavailable, err)
+		}
+		if c.rules.DeferUnknownDevices {
+			return nil, ErrUnknownDevice
+		}
+		return reject("Device is not registered"), nil
+	}
+
+	if c.rules.RequireActiveRouter && routerModel.Active != 1 {
+		return reject("Router is not active"), nil
+	}
+
+	if !unit.IsActive {
+		return reject("Unit is not active"), nil
+	}
+
+	if request.AlarmType == VirtualButtonAlarmType && !unit.IsVirtualButtonAlarm {
+		return reject("Virtual button alarms are not allowed for the unit"), nil
+	}
+
+	if len(c.rules.AllowedTenants) > 0 && !slices.Contains(c.rules.AllowedTenants, routerModel.TenantID) {
+		return reject("Tenant is not allowed to raise alarms"), nil
+	}
+
+	if slices.Contains(c.rules.DeniedTenants, routerModel.TenantID) {
+		return reject("Tenant is not allowed to raise alarms"), nil
+	}
+
+	return &Response{
+		Success: true,
+		Message: "Alarm validated locally",
+		Data: map[string]int{
+			"routerId": routerModel.ID,
+			"unitId":   unit.ID,
+			"tenantId": routerModel.TenantID,
+		},
+	}, nil
+}
+
+func (c *LocalClient) findDevice(ctx context.Context, request Request) (*routerold.RouterModel, *unitsold.UnitsModel, error) {
+	if request.IMEI != "" {
+		routerModel, err := c.routers.FindByIMEI(ctx, request.IMEI)
+		if err != nil {
+			return nil, nil, err
+		}
+		unit, err := c.units.FindByRouterID(ctx, routerModel.ID)
+		if err != nil {
+			return nil, nil, err
+		}
+		return routerModel, unit, nil
+	}
+
+	unitID, err := strconv.Atoi(request.UnitID)
+	if err != nil {
+		return nil, nil, terrors.New(terrors.ErrNotFound, "Unit information not found", map[string]string{})
+	}
+
+	unit, err := c.units.FindByID(ctx, unitID)
+	if err != nil {
+		return nil, nil, err
+	}
+	routerModel, err := c.routers.FindByID(ctx, unit.RouterID)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return routerModel, unit, nil
+}
+
+func reject(message string) *Response {
+	return &Response{
+		Success: false,
+		Message: message,
+	}
+}
@@ -0,0 +1,211 @@
+package router_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/jmontesinos91/collector/internal/repositories/router"
+	"github.com/jmontesinos91/collector/internal/repositories/routerold"
+	"github.com/jmontesinos91/collector/internal/repositories/routerold/routeroldmocks"
+	"github.com/jmontesinos91/collector/internal/repositories/unitsold"
+	"github.com/jmontesinos91/collector/internal/repositories/unitsold/unitsoldmocks"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/terrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestLocalClient(t *testing.T) {
+	log := logger.NewContextLogger("LocalClient", "debug", logger.TextFormat)
+	notFound := terrors.New(terrors.ErrNotFound, "Router information not found", map[string]string{})
+
+	type fields struct {
+		routerModel *routerold.RouterModel
+		routerErr   error
+		unit        *unitsold.UnitsModel
+	}
+
+	tests := []struct {
+		name    string
+		rules   router.LocalRules
+		request router.Request
+		fields  fields
+		asserts func(*testing.T, *router.Response, error) bool
+	}{
+		{
+			name:    "Active device is validated",
+			rules:   router.LocalRules{RequireActiveRouter: true},
+			request: router.Request{IMEI: "861585041440544", AlarmType: "1"},
+			fields: fields{
+				routerModel: &routerold.RouterModel{ID: 1, TenantID: 4, Active: 1},
+				unit:        &unitsold.UnitsModel{ID: 10, RouterID: 1, IsActive: true},
+			},
+			asserts: func(t *testing.T, response *router.Response, err error) bool {
+				return assert.NoError(t, err) && assert.True(t, response.Success) &&
+					assert.Equal(t, map[string]int{"routerId": 1, "unitId": 10, "tenantId": 4}, response.Data)
+			},
+		},
+		{
+			name:    "Inactive router is rejected",
+			rules:   router.LocalRules{RequireActiveRouter: true},
+			request: router.Request{IMEI: "861585041440544", AlarmType: "1"},
+			fields: fields{
+				routerModel: &routerold.RouterModel{ID: 1, TenantID: 4, Active: 0},
+				unit:        &unitsold.UnitsModel{ID: 10, RouterID: 1, IsActive: true},
+			},
+			asserts: func(t *testing.T, response *router.Response, err error) bool {
+				return assert.NoError(t, err) && assert.False(t, response.Success) &&
+					assert.Equal(t, "Router is not active", response.Message)
+			},
+		},
+		{
+			name:    "Inactive router is accepted when not required",
+			request: router.Request{IMEI: "861585041440544", AlarmType: "1"},
+			fields: fields{
+				routerModel: &routerold.RouterModel{ID: 1, TenantID: 4, Active: 0},
+				unit:        &unitsold.UnitsModel{ID: 10, RouterID: 1, IsActive: true},
+			},
+			asserts: func(t *testing.T, response *router.Response, err error) bool {
+				return assert.NoError(t, err) && assert.True(t, response.Success)
+			},
+		},
+		{
+			name:    "Inactive unit is rejected",
+			request: router.Request{IMEI: "861585041440544", AlarmType: "1"},
+			fields: fields{
+				routerModel: &routerold.RouterModel{ID: 1, TenantID: 4, Active: 1},
+				unit:        &unitsold.UnitsModel{ID: 10, RouterID: 1, IsActive: false},
+			},
+			asserts: func(t *testing.T, response *router.Response, err error) bool {
+				return assert.NoError(t, err) && assert.False(t, response.Success) &&
+					assert.Equal(t, "Unit is not active", response.Message)
+			},
+		},
+		{
+			name:    "Virtual button is rejected when the unit does not allow it",
+			request: router.Request{IMEI: "861585041440544", AlarmType: router.VirtualButtonAlarmType},
+			fields: fields{
+				routerModel: &routerold.RouterModel{ID: 1, TenantID: 4, Active: 1},
+				unit:        &unitsold.UnitsModel{ID: 10, RouterID: 1, IsActive: true},
+			},
+			asserts: func(t *testing.T, response *router.Response, err error) bool {
+				return assert.NoError(t, err) && assert.False(t, response.Success) &&
+					assert.Equal(t, "Virtual button alarms are not allowed for the unit", response.Message)
+			},
+		},
+		{
+			name:    "Virtual button is validated when the unit allows it",
+			request: router.Request{IMEI: "861585041440544", AlarmType: router.VirtualButtonAlarmType},
+			fields: fields{
+				routerModel: &routerold.RouterModel{ID: 1, TenantID: 4, Active: 1},
+				unit:        &unitsold.UnitsModel{ID: 10, RouterID: 1, IsActive: true, IsVirtualButtonAlarm: true},
+			},
+			asserts: func(t *testing.T, response *router.Response, err error) bool {
+				return assert.NoError(t, err) && assert.True(t, response.Success)
+			},
+		},
+		{
+			name:    "Allowed tenant is validated",
+			rules:   router.LocalRules{AllowedTenants: []int{4, 5}},
+			request: router.Request{IMEI: "861585041440544", AlarmType: "1"},
+			fields: fields{
+				routerModel: &routerold.RouterModel{ID: 1, TenantID: 4, Active: 1},
+				unit:        &unitsold.UnitsModel{ID: 10, RouterID: 1, IsActive: true},
+			},
+			asserts: func(t *testing.T, response *router.Response, err error) bool {
+				return assert.NoError(t, err) && assert.True(t, response.Success)
+			},
+		},
+		{
+			name:    "Tenant out of the allowed ones is rejected",
+			rules:   router.LocalRules{AllowedTenants: []int{5}},
+			request: router.Request{IMEI: "861585041440544", AlarmType: "1"},
+			fields: fields{
+				routerModel: &routerold.RouterModel{ID: 1, TenantID: 4, Active: 1},
+				unit:        &unitsold.UnitsModel{ID: 10, RouterID: 1, IsActive: true},
+			},
+			asserts: func(t *testing.T, response *router.Response, err error) bool {
+				return assert.NoError(t, err) && assert.False(t, response.Success) &&
+					assert.Equal(t, "Tenant is not allowed to raise alarms", response.Message)
+			},
+		},
+		{
+			name:    "Denied tenant is rejected",
+			rules:   router.LocalRules{DeniedTenants: []int{4}},
+			request: router.Request{IMEI: "861585041440544", AlarmType: "1"},
+			fields: fields{
+				routerModel: &routerold.RouterModel{ID: 1, TenantID: 4, Active: 1},
+				unit:        &unitsold.UnitsModel{ID: 10, RouterID: 1, IsActive: true},
+			},
+			asserts: func(t *testing.T, response *router.Response, err error) bool {
+				return assert.NoError(t, err) && assert.False(t, response.Success) &&
+					assert.Equal(t, "Tenant is not allowed to raise alarms", response.Message)
+			},
+		},
+		{
+			name:    "Unit ID looks the router up by the unit",
+			request: router.Request{UnitID: "10", AlarmType: "1"},
+			fields: fields{
+				routerModel: &routerold.RouterModel{ID: 1, TenantID: 4, Active: 1},
+				unit:        &unitsold.UnitsModel{ID: 10, RouterID: 1, IsActive: true},
+			},
+			asserts: func(t *testing.T, response *router.Response, err error) bool {
+				return assert.NoError(t, err) && assert.True(t, response.Success) &&
+					assert.Equal(t, 10, response.Data.(map[string]int)["unitId"])
+			},
+		},
+		{
+			name:    "Invalid unit ID is not registered",
+			request: router.Request{UnitID: "unit", AlarmType: "1"},
+			asserts: func(t *testing.T, response *router.Response, err error) bool {
+				return assert.NoError(t, err) && assert.False(t, response.Success) &&
+					assert.Equal(t, "Device is not registered", response.Message)
+			},
+		},
+		{
+			name:    "Unknown device is rejected",
+			request: router.Request{IMEI: "861585041440544", AlarmType: "1"},
+			fields:  fields{routerErr: notFound},
+			asserts: func(t *testing.T, response *router.Response, err error) bool {
+				return assert.NoError(t, err) && assert.False(t, response.Success) &&
+					assert.Equal(t, "Device is not registered", response.Message)
+			},
+		},
+		{
+			name:    "Unknown device is deferred",
+			rules:   router.LocalRules{DeferUnknownDevices: true},
+			request: router.Request{IMEI: "861585041440544", AlarmType: "1"},
+			fields:  fields{routerErr: notFound},
+			asserts: func(t *testing.T, response *router.Response, err error) bool {
+				return assert.ErrorIs(t, err, router.ErrUnknownDevice) && assert.Nil(t, response)
+			},
+		},
+		{
+			name:    "Registry failure is returned",
+			request: router.Request{IMEI: "861585041440544", AlarmType: "1"},
+			fields:  fields{routerErr: errors.New("unit-test-error")},
+			asserts: func(t *testing.T, response *router.Response, err error) bool {
+				return assert.Error(t, err) && assert.NotErrorIs(t, err, router.ErrUnknownDevice) && assert.Nil(t, response)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			routerRepo := &routeroldmocks.IRepository{}
+			routerRepo.On("FindByIMEI", mock.Anything, mock.Anything).Return(tc.fields.routerModel, tc.fields.routerErr)
+			routerRepo.On("FindByID", mock.Anything, mock.Anything).Return(tc.fields.routerModel, tc.fields.routerErr)
+			unitsRepo := &unitsoldmocks.IRepository{}
+			unitsRepo.On("FindByRouterID", mock.Anything, mock.Anything).Return(tc.fields.unit, nil)
+			unitsRepo.On("FindByID", mock.Anything, 10).Return(tc.fields.unit, nil)
+
+			client := router.NewLocalClient(log, routerRepo, unitsRepo, tc.rules)
+			response, err := client.ValidateIMEI(context.Background(), tc.request)
+
+			if !tc.asserts(t, response, err) {
+				t.Errorf("Assert error on test = '%v'", tc.name)
+			}
+		})
+	}
+}
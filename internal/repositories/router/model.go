@@ -1,5 +1,7 @@
 package router
 
+import "fmt"
+
 // Request Holds the response for a created payout
 type Request struct {
 	IMEI      string `json:"imei"`
@@ -13,3 +15,28 @@ type Response struct {
 	Message string      `json:"message"`
 	Data    interface{} `json:"data"`
 }
+
+// Mode selects which client validates the alarms
+type Mode string
+
+// Alarm validation modes
+const (
+	// ModeRemote validates alarms on Omniview
+	ModeRemote Mode = "remote"
+	// ModeLocal validates alarms against the legacy device registry
+	ModeLocal Mode = "local"
+	// ModeLocalFirst validates alarms locally and asks Omniview when the local registry cannot decide
+	ModeLocalFirst Mode = "local-first"
+)
+
+// ParseMode returns the validation mode of the given name, remote when it is empty
+func ParseMode(value string) (Mode, error) {
+	switch mode := Mode(value); mode {
+	case "":
+		return ModeRemote, nil
+	case ModeRemote, ModeLocal, ModeLocalFirst:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown alarm validation mode %q, it must be %s, %s or %s", value, ModeRemote, ModeLocal, ModeLocalFirst)
+	}
+}
@@ -0,0 +1,35 @@
+package router_test
+
+import (
+	"testing"
+
+	"github.com/jmontesinos91/collector/internal/repositories/router"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMode(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected router.Mode
+		wantErr  bool
+	}{
+		{name: "Empty defaults to remote", value: "", expected: router.ModeRemote},
+		{name: "Remote", value: "remote", expected: router.ModeRemote},
+		{name: "Local", value: "local", expected: router.ModeLocal},
+		{name: "Local first", value: "local-first", expected: router.ModeLocalFirst},
+		{name: "Unknown value is rejected", value: "local_first", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			mode, err := router.ParseMode(tc.value)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, mode)
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package routerold
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmontesinos91/collector/domains/cache"
+	"github.com/jmontesinos91/terrors"
+)
+
+// CachedRepository read-through cache decorator for IRepository, lookups by IMEI and by ID are
+// served from bounded LRUs and unknown keys are negatively cached
+type CachedRepository struct {
+	next   IRepository
+	byIMEI *cache.LRU[string, RouterModel]
+	byID   *cache.LRU[int, RouterModel]
+}
+
+// NewCachedRepository creates an instance of CachedRepository wrapping the given repository
+func NewCachedRepository(next IRepository, size int, ttl, negativeTTL time.Duration) *CachedRepository {
+	return &CachedRepository{
+		next:   next,
+		byIMEI: cache.NewLRU[string, RouterModel](size, ttl, negativeTTL),
+		byID:   cache.NewLRU[int, RouterModel](size, ttl, negativeTTL),
+	}
+}
+
+// FindByIMEI Handles the find by imei of router record, going to the wrapped repository on a cache miss
+func (r *CachedRepository) FindByIMEI(ctx context.Context, imei string) (*RouterModel, error) {
+	if model, missing, hit := r.byIMEI.Get(imei); hit {
+		if missing {
+			return &RouterModel{}, terrors.New(terrors.ErrNotFound, "Router information not found", map[string]string{})
+		}
+		return &model, nil
+	}
+
+	model, err := r.next.FindByIMEI(ctx, imei)
+	if err != nil {
+		if terrors.Is(err, terrors.ErrNotFound) {
+			r.byIMEI.SetMissing(imei)
+		}
+		return model, err
+	}
+
+	r.byIMEI.Set(imei, *model)
+
+	return model, nil
+}
+
+// FindByID Handles the find by id of router record, going to the wrapped repository on a cache miss
+func (r *CachedRepository) FindByID(ctx context.Context, routerID int) (*RouterModel, error) {
+	if model, missing, hit := r.byID.Get(routerID); hit {
+		if missing {
+			return &RouterModel{}, terrors.New(terrors.ErrNotFound, "Router information not found", map[string]string{})
+		}
+		return &model, nil
+	}
+
+	model, err := r.next.FindByID(ctx, routerID)
+	if err != nil {
+		if terrors.Is(err, terrors.ErrNotFound) {
+			r.byID.SetMissing(routerID)
+		}
+		return model, err
+	}
+
+	r.byID.Set(routerID, *model)
+
+	return model, nil
+}
+
+// UpdateLatAndLong Updates the router position and evicts the cached router, the entries are evicted
+// even when the update fails since the write may have been applied
+func (r *CachedRepository) UpdateLatAndLong(ctx context.Context, routerID int, lat, long string) error {
+	err := r.next.UpdateLatAndLong(ctx, routerID, lat, long)
+	r.evict(routerID)
+
+	return err
+}
+
+// InvalidateIMEI Removes the cached entries of the router with the given IMEI, by IMEI and by ID, and returns
+// how many were removed along with the router ID, zero when the router was not cached
+func (r *CachedRepository) InvalidateIMEI(imei string) (int, int) {
+	routerID := 0
+	if model, missing, hit := r.byIMEI.Get(imei); hit && !missing {
+		routerID = model.ID
+	}
+
+	invalidated := 0
+	if r.byIMEI.Delete(imei) {
+		invalidated++
+	}
+	invalidated += r.byID.DeleteFunc(func(id int, model RouterModel) bool {
+		if model.IMEI != imei {
+			return routerID != 0 && id == routerID
+		}
+		routerID = id
+		return true
+	})
+
+	return invalidated, routerID
+}
+
+// InvalidateID Removes the cached entries of the given router, by ID and by IMEI
+func (r *CachedRepository) InvalidateID(routerID int) int {
+	return r.evict(routerID)
+}
+
+// Purge Removes every cached entry
+func (r *CachedRepository) Purge() int {
+	return r.byIMEI.Purge() + r.byID.Purge()
+}
+
+func (r *CachedRepository) evict(routerID int) int {
+	invalidated := 0
+	if r.byID.Delete(routerID) {
+		invalidated++
+	}
+
+	return invalidated + r.byIMEI.DeleteFunc(func(_ string, model RouterModel) bool {
+		return model.ID == routerID
+	})
+}
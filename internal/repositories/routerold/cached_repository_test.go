@@ -0,0 +1,78 @@
+package routerold_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jmontesinos91/collector/internal/repositories/routerold"
+	"github.com/jmontesinos91/collector/internal/repositories/routerold/routeroldmocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCachedRepository(t *testing.T) {
+	ctx := context.Background()
+
+	tests := []struct {
+		name    string
+		run     func(*routerold.CachedRepository)
+		asserts func(*testing.T, *routeroldmocks.IRepository, *routerold.CachedRepository) bool
+	}{
+		{
+			name: "Updated position is read back from the database",
+			run: func(c *routerold.CachedRepository) {
+				_ = c.UpdateLatAndLong(ctx, 1, "19.43", "-99.13")
+			},
+			asserts: func(t *testing.T, repoMock *routeroldmocks.IRepository, c *routerold.CachedRepository) bool {
+				_, _ = c.FindByIMEI(ctx, "861585041440544")
+				_, _ = c.FindByID(ctx, 1)
+				return repoMock.AssertNumberOfCalls(t, "FindByIMEI", 2) &&
+					repoMock.AssertNumberOfCalls(t, "FindByID", 2)
+			},
+		},
+		{
+			name: "Invalidate by IMEI evicts the router by ID too",
+			run: func(c *routerold.CachedRepository) {
+				invalidated, routerID := c.InvalidateIMEI("861585041440544")
+				assert.Equal(t, 2, invalidated)
+				assert.Equal(t, 1, routerID)
+			},
+			asserts: func(t *testing.T, repoMock *routeroldmocks.IRepository, c *routerold.CachedRepository) bool {
+				_, _ = c.FindByID(ctx, 1)
+				return repoMock.AssertNumberOfCalls(t, "FindByID", 2)
+			},
+		},
+		{
+			name: "Invalidate by ID evicts the router by IMEI too",
+			run: func(c *routerold.CachedRepository) {
+				assert.Equal(t, 2, c.InvalidateID(1))
+			},
+			asserts: func(t *testing.T, repoMock *routeroldmocks.IRepository, c *routerold.CachedRepository) bool {
+				_, _ = c.FindByIMEI(ctx, "861585041440544")
+				return repoMock.AssertNumberOfCalls(t, "FindByIMEI", 2)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			repoMock := &routeroldmocks.IRepository{}
+			repoMock.On("FindByIMEI", mock.Anything, "861585041440544").
+				Return(&routerold.RouterModel{ID: 1, IMEI: "861585041440544"}, nil)
+			repoMock.On("FindByID", mock.Anything, 1).
+				Return(&routerold.RouterModel{ID: 1, IMEI: "861585041440544"}, nil)
+			repoMock.On("UpdateLatAndLong", mock.Anything, 1, mock.Anything, mock.Anything).Return(nil)
+
+			cached := routerold.NewCachedRepository(repoMock, 10, time.Minute, time.Minute)
+			_, _ = cached.FindByIMEI(ctx, "861585041440544")
+			_, _ = cached.FindByID(ctx, 1)
+
+			tc.run(cached)
+
+			if !tc.asserts(t, repoMock, cached) {
+				t.Errorf("Assert error on test = '%v'", tc.name)
+			}
+		})
+	}
+}
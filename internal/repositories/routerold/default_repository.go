@@ -44,6 +44,24 @@ func (r *DatabaseRepository) FindByIMEI(ctx context.Context, imei string) (*Rout
 	return model, nil
 }
 
+// FindByID Handles the find by id of router record on old database
+func (r *DatabaseRepository) FindByID(ctx context.Context, routerID int) (*RouterModel, error) {
+	model := &RouterModel{}
+	query := r.db.NewSelect().
+		Model(model).
+		Where("id = ?", routerID).
+		Limit(1)
+
+	if err := query.Scan(ctx); err != nil {
+		if err.Error() == sql.ErrNoRows.Error() {
+			return model, terrors.New(terrors.ErrNotFound, "Router information not found", map[string]string{})
+		}
+		return model, fmt.Errorf("router_old_repository: Error while searching for routersvc -> %w", err)
+	}
+
+	return model, nil
+}
+
 func (r *DatabaseRepository) UpdateLatAndLong(ctx context.Context, routerID int, lat, long string) error {
 	_, errUpdate := r.db.NewUpdate().
 		Table("routers").
@@ -7,5 +7,13 @@ import (
 // IRepository interface
 type IRepository interface {
 	FindByIMEI(ctx context.Context, imei string) (*RouterModel, error)
+	FindByID(ctx context.Context, routerID int) (*RouterModel, error)
 	UpdateLatAndLong(ctx context.Context, routerID int, lat, long string) error
 }
+
+// ICache invalidation interface for cached router lookups
+type ICache interface {
+	InvalidateIMEI(imei string) (int, int)
+	InvalidateID(routerID int) int
+	Purge() int
+}
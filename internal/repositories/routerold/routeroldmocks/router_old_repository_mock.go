@@ -28,6 +28,32 @@ func (_m *IRepository) ActiveAndDeactivateRouter(ctx context.Context, routerID i
 	return r0
 }
 
+// FindByID provides a mock function with given fields: ctx, routerID
+func (_m *IRepository) FindByID(ctx context.Context, routerID int) (*routerold.RouterModel, error) {
+	ret := _m.Called(ctx, routerID)
+
+	var r0 *routerold.RouterModel
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, int) (*routerold.RouterModel, error)); ok {
+		return rf(ctx, routerID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, int) *routerold.RouterModel); ok {
+		r0 = rf(ctx, routerID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*routerold.RouterModel)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, int) error); ok {
+		r1 = rf(ctx, routerID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // FindByIMEI provides a mock function with given fields: ctx, imei
 func (_m *IRepository) FindByIMEI(ctx context.Context, imei string) (*routerold.RouterModel, error) {
 	ret := _m.Called(ctx, imei)
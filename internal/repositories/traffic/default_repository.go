@@ -5,14 +5,42 @@ import (
 	"database/sql"
 	"errors"
 	"math"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
+	"github.com/jmontesinos91/collector/domains/pagination"
 	"github.com/jmontesinos91/ologs/logger"
 	"github.com/jmontesinos91/terrors"
 	"github.com/sirupsen/logrus"
 	"github.com/uptrace/bun"
 )
 
+// streamBatchSize rows fetched from the cursor per round trip
+const streamBatchSize = 1000
+
+// defaultCursorColumn sort column of keyset listings when none is requested
+const defaultCursorColumn = "updated_at"
+
+// cursorColumns columns allowed as keyset sort keys and how their value is kept in a cursor
+var cursorColumns = map[string]func(m Model) string{
+	"created_at": func(m Model) string { return m.CreatedAt.UTC().Format(time.RFC3339Nano) },
+	"updated_at": func(m Model) string { return m.UpdatedAt.UTC().Format(time.RFC3339Nano) },
+	"counter":    func(m Model) string { return strconv.Itoa(m.Counter) },
+	"imei":       func(m Model) string { return m.IMEI },
+	"ip":         func(m Model) string { return m.Ip },
+}
+
+// statsGroups group by expressions allowed in stats queries
+var statsGroups = map[string]string{
+	"":           "''",
+	GroupByIMEI:  "?TableAlias.imei",
+	GroupByIP:    "?TableAlias.ip",
+	GroupByAlarm: "?TableAlias.\"isAlarm\"::text",
+}
+
 // DatabaseRepository struct
 type DatabaseRepository struct {
 	log *logger.ContextLogger
@@ -59,6 +87,25 @@ func (r *DatabaseRepository) FindByIMEI(ctx context.Context, imei string, isAlar
 	}
 }
 
+// FindByID Handles to find a traffic by id, soft deleted traffics included
+func (r *DatabaseRepository) FindByID(ctx context.Context, trafficID string) (*Model, error) {
+	model := &Model{}
+	err := r.db.NewSelect().
+		Model(model).
+		WhereAllWithDeleted().
+		Where("id = ?", trafficID).
+		Limit(1).
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, terrors.New(terrors.ErrNotFound, "Traffic not found", map[string]string{})
+		}
+		return nil, terrors.InternalService("find_traffic", "Failed to retrieve traffic from the database", map[string]string{})
+	}
+
+	return model, nil
+}
+
 // FindByLastUsed Handles to find traffic model by imei
 func (r *DatabaseRepository) FindByLastUsed(ctx context.Context) ([]Model, error) {
 	var tModel []Model
@@ -74,12 +121,13 @@ func (r *DatabaseRepository) FindByLastUsed(ctx context.Context) ([]Model, error
 	return tModel, err
 }
 
-// UpdateIsNotified Handles update as notified
+// UpdateIsNotified Handles update as notified, soft deleted traffics are left untouched
 func (r *DatabaseRepository) UpdateIsNotified(ctx context.Context, trafficID string) error {
 	_, errUpdate := r.db.NewUpdate().
 		Table("traffic").
 		Set("isnotified = ?", true).
 		Where("id = ?", trafficID).
+		Where("deleted_at IS NULL").
 		Exec(ctx)
 	if errUpdate != nil {
 		return errUpdate
@@ -87,16 +135,22 @@ func (r *DatabaseRepository) UpdateIsNotified(ctx context.Context, trafficID str
 	return nil
 }
 
-// UpdateByIMEI Handles update the register by IMEI
-func (r *DatabaseRepository) UpdateByIMEI(ctx context.Context, imei, request string, isAlarm bool) error {
-	_, errUpdate := r.db.NewUpdate().
+// UpdateByIMEI Handles update the register by IMEI, the tenant is kept when the device tenant is unknown
+func (r *DatabaseRepository) UpdateByIMEI(ctx context.Context, imei, request string, isAlarm bool, tenantID int) error {
+	query := r.db.NewUpdate().
 		Table("traffic").
 		Set("request = ?", request).
 		Set("updated_at = ?", time.Now().UTC()).
 		Set("counter=counter+1").
-		Set("isnotified = ?", false).
+		Set("isnotified = ?", false)
+	if tenantID != 0 {
+		query = query.Set("tenant_id = ?", tenantID)
+	}
+
+	_, errUpdate := query.
 		Where("imei = ?", imei).
 		Where("\"isAlarm\" = ?", isAlarm).
+		Where("deleted_at IS NULL").
 		Exec(ctx)
 	if errUpdate != nil {
 		return errUpdate
@@ -112,25 +166,28 @@ func (r *DatabaseRepository) Retrieve(ctx context.Context, filter *Metadata) ([]
 
 	query = setFilters(query, filter)
 
-	pages, total, err := paginationMeta(ctx, query, filter)
-	if err != nil {
-		r.log.Error(logrus.ErrorLevel, "Retrieve", "Error counting records", err)
-		return nil, 0, 0, terrors.InternalService("count_error", "Error counting records in the database", map[string]string{})
+	pages, total := 0, 0
+	if filter.Filter.ShouldCount() {
+		var err error
+		pages, total, err = paginationMeta(ctx, query, filter)
+		if err != nil {
+			r.log.Error(logrus.ErrorLevel, "Retrieve", "Error counting records", err)
+			return nil, 0, 0, terrors.InternalService("count_error", "Error counting records in the database", map[string]string{})
+		}
 	}
 
 	if filter.Filter.Size > 0 {
-		query = query.Limit(filter.Filter.Size).Offset((filter.Filter.Page - 1) * filter.Filter.Size) // perPage = size //offset = offset
+		offset := (filter.Filter.Page - 1) * filter.Filter.Size
+		if filter.Filter.Offset > 0 {
+			offset = filter.Filter.Offset
+		}
+		query = query.Limit(filter.Filter.Size).Offset(offset)
 	}
 
-	if filter.Filter.SortBy != "" {
-		order := filter.Filter.SortBy
-		if filter.Filter.SortDesc {
-			order += " " + "DESC"
-		} else {
-			order += " " + "ASC"
-		}
-		query = query.Order(order)
+	if filter.Qparam != "" && len(filter.Filter.Sorts) == 0 {
+		query = orderByRelevance(query, filter.Qparam)
 	}
+	query = pagination.ApplySort(withWindows(query), filter.Filter.Sorts)
 
 	if err := query.Scan(ctx, &traffics); err != nil {
 		r.log.Error(logrus.ErrorLevel, "Retrieve", "Error scanning traffics", err)
@@ -140,9 +197,64 @@ func (r *DatabaseRepository) Retrieve(ctx context.Context, filter *Metadata) ([]
 	return traffics, pages, total, nil
 }
 
-// DeleteByID Handles update the register by IMEI
+// RetrieveCursor Retrieves traffic data by filters paginated by keyset, the sort column
+// must be one of the cursor columns and the id breaks ties
+func (r *DatabaseRepository) RetrieveCursor(ctx context.Context, filter *Metadata) (CursorPage, error) {
+	sortBy, column, sortDesc := defaultCursorColumn, defaultCursorColumn, filter.Filter.SortDesc
+	switch len(filter.Filter.Sorts) {
+	case 0:
+	case 1:
+		sortBy, column, sortDesc = filter.Filter.Sorts[0].Field, filter.Filter.Sorts[0].Column(), filter.Filter.Sorts[0].Desc
+	default:
+		return CursorPage{}, terrors.New(terrors.ErrBadRequest, "Invalid sortBy parameter, cursor pagination supports a single field", map[string]string{})
+	}
+	key, ok := cursorColumns[column]
+	if !ok {
+		return CursorPage{}, terrors.New(terrors.ErrBadRequest, "Invalid sortBy parameter, unsupported field for cursor pagination", map[string]string{})
+	}
+
+	keyset := pagination.Keyset{
+		Column:   column,
+		IDColumn: "id",
+		SortDesc: sortDesc,
+		Size:     filter.Filter.Size,
+	}
+	if filter.Filter.Cursor != "" {
+		cursor, err := pagination.DecodeCursor(filter.Filter.Cursor, sortBy, sortDesc)
+		if err != nil {
+			return CursorPage{}, terrors.New(terrors.ErrBadRequest, "Invalid cursor parameter", map[string]string{})
+		}
+		keyset.Cursor = cursor
+	}
+
+	page := CursorPage{}
+	if filter.Filter.ShouldCount() {
+		total, err := setFilters(r.db.NewSelect().Model((*Model)(nil)), filter).Count(ctx)
+		if err != nil {
+			r.log.Error(logrus.ErrorLevel, "RetrieveCursor", "Error counting records", err)
+			return CursorPage{}, terrors.InternalService("count_error", "Error counting records in the database", map[string]string{})
+		}
+		page.Total = &total
+	}
+
+	var traffics []Model
+	query := keyset.Apply(withWindows(setFilters(r.db.NewSelect().Model(&Model{}), filter)))
+	if err := query.Scan(ctx, &traffics); err != nil {
+		r.log.Error(logrus.ErrorLevel, "RetrieveCursor", "Error scanning traffics", err)
+		return CursorPage{}, terrors.InternalService("count_error", "Error retrieving traffics from the database", map[string]string{})
+	}
+
+	page.Models, page.Next, page.Prev = pagination.Page(keyset, sortBy, traffics, func(m Model) (string, string) {
+		return key(m), m.ID
+	})
+
+	return page, nil
+}
+
+// DeleteByID Handles the soft delete of a traffic, the row is kept until it is purged
 func (r *DatabaseRepository) DeleteByID(ctx context.Context, trafficID string) error {
 	_, errUpdate := r.db.NewDelete().
+		Model((*Model)(nil)).
 		Where("id = ?", trafficID).
 		Exec(ctx)
 	if errUpdate != nil {
@@ -151,6 +263,86 @@ func (r *DatabaseRepository) DeleteByID(ctx context.Context, trafficID string) e
 	return nil
 }
 
+// Restore Handles undo the soft delete of a traffic, it fails when the device already
+// reported again and a new traffic took the place of the deleted one
+func (r *DatabaseRepository) Restore(ctx context.Context, trafficID string) error {
+	return r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		var model Model
+		err := tx.NewSelect().
+			Model(&model).
+			WhereDeleted().
+			Where("id = ?", trafficID).
+			For("UPDATE").
+			Scan(ctx)
+		if errors.Is(err, sql.ErrNoRows) {
+			return terrors.New(terrors.ErrNotFound, "Deleted traffic not found", map[string]string{})
+		}
+		if err != nil {
+			r.log.Error(logrus.ErrorLevel, "Restore", "Error finding deleted traffic", err)
+			return terrors.InternalService("restore_traffic", "Failed restore traffic from the database", map[string]string{})
+		}
+
+		exists, err := tx.NewSelect().
+			Model((*Model)(nil)).
+			Where("imei = ?", model.IMEI).
+			Where("\"isAlarm\" = ?", model.IsAlarm).
+			Exists(ctx)
+		if err != nil {
+			r.log.Error(logrus.ErrorLevel, "Restore", "Error finding active traffic", err)
+			return terrors.InternalService("restore_traffic", "Failed restore traffic from the database", map[string]string{})
+		}
+		if exists {
+			return terrors.New(terrors.ErrConflict, "The device already has an active traffic", map[string]string{})
+		}
+
+		_, err = tx.NewUpdate().
+			Model((*Model)(nil)).
+			WhereDeleted().
+			Set("deleted_at = NULL").
+			Where("id = ?", trafficID).
+			Exec(ctx)
+		if err != nil {
+			r.log.Error(logrus.ErrorLevel, "Restore", "Error restoring traffic", err)
+			return terrors.InternalService("restore_traffic", "Failed restore traffic from the database", map[string]string{})
+		}
+		return nil
+	})
+}
+
+// PurgeDeleted Handles the hard delete of the traffics soft deleted before the given time,
+// rows are removed in batches so the table is never locked for long
+func (r *DatabaseRepository) PurgeDeleted(ctx context.Context, before time.Time, batchSize int) (int, error) {
+	purged := 0
+	for {
+		batch := r.db.NewSelect().
+			Model((*Model)(nil)).
+			Column("id").
+			WhereDeleted().
+			Where("deleted_at < ?", before).
+			Limit(batchSize)
+
+		res, err := r.db.NewDelete().
+			Model((*Model)(nil)).
+			Where("id IN (?)", batch).
+			ForceDelete().
+			Exec(ctx)
+		if err != nil {
+			r.log.Error(logrus.ErrorLevel, "PurgeDeleted", "Error purging deleted traffics", err)
+			return purged, terrors.InternalService("purge_traffic", "Failed purge deleted traffics from the database", map[string]string{})
+		}
+
+		rows, err := res.RowsAffected()
+		if err != nil {
+			return purged, err
+		}
+		purged += int(rows)
+
+		if int(rows) < batchSize {
+			return purged, nil
+		}
+	}
+}
+
 // ResetCounter Handles update the register by IMEI
 func (r *DatabaseRepository) ResetCounter(ctx context.Context, trafficID string) error {
 	_, errUpdate := r.db.NewUpdate().
@@ -158,6 +350,7 @@ func (r *DatabaseRepository) ResetCounter(ctx context.Context, trafficID string)
 		Set("counter = 0").
 		Set("updated_at = ?", time.Now().UTC()).
 		Where("id = ?", trafficID).
+		Where("deleted_at IS NULL").
 		Exec(ctx)
 	if errUpdate != nil {
 		return terrors.InternalService("reset_counter", "Failed reset counter traffic from the database", map[string]string{})
@@ -165,6 +358,178 @@ func (r *DatabaseRepository) ResetCounter(ctx context.Context, trafficID string)
 	return nil
 }
 
+// RecordFrame Handles counting a frame in the bucket of the given time, the bucket is created on its first frame
+func (r *DatabaseRepository) RecordFrame(ctx context.Context, imei string, isAlarm bool, at time.Time) error {
+	_, err := r.db.NewInsert().
+		Model(&BucketModel{
+			IMEI:    imei,
+			IsAlarm: isAlarm,
+			Bucket:  at.UTC().Truncate(BucketWidth),
+			Frames:  1,
+		}).
+		On("CONFLICT (imei, is_alarm, bucket) DO UPDATE").
+		Set("frames = traffic_buckets.frames + EXCLUDED.frames").
+		Exec(ctx)
+	if err != nil {
+		return terrors.InternalService("record_frame", "Failed record frame in the database", map[string]string{})
+	}
+	return nil
+}
+
+// PurgeBuckets Handles the delete of the frames buckets started before the given time
+func (r *DatabaseRepository) PurgeBuckets(ctx context.Context, before time.Time) (int, error) {
+	res, err := r.db.NewDelete().
+		Model((*BucketModel)(nil)).
+		Where("bucket < ?", before).
+		Exec(ctx)
+	if err != nil {
+		r.log.Error(logrus.ErrorLevel, "PurgeBuckets", "Error purging frames buckets", err)
+		return 0, terrors.InternalService("purge_buckets", "Failed purge frames buckets from the database", map[string]string{})
+	}
+
+	rows, err := res.RowsAffected()
+	return int(rows), err
+}
+
+// RetrieveUntenanted Handles retrieving, in order, the devices after the given one that have traffic
+// rows without a tenant, the deleted rows included
+func (r *DatabaseRepository) RetrieveUntenanted(ctx context.Context, after string, limit int) ([]string, error) {
+	var imeis []string
+	err := r.db.NewSelect().
+		Table("traffic").
+		ColumnExpr("DISTINCT imei").
+		Where("tenant_id IS NULL").
+		Where("imei > ?", after).
+		OrderExpr("imei ASC").
+		Limit(limit).
+		Scan(ctx, &imeis)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		r.log.Error(logrus.ErrorLevel, "RetrieveUntenanted", "Error retrieving devices without tenant", err)
+		return nil, terrors.InternalService("retrieve_untenanted", "Failed retrieve devices without tenant from the database", map[string]string{})
+	}
+
+	return imeis, nil
+}
+
+// AssignTenant Handles setting the tenant of the traffic rows of the device that have none
+func (r *DatabaseRepository) AssignTenant(ctx context.Context, imei string, tenantID int) (int, error) {
+	res, err := r.db.NewUpdate().
+		Table("traffic").
+		Set("tenant_id = ?", tenantID).
+		Where("imei = ?", imei).
+		Where("tenant_id IS NULL").
+		Exec(ctx)
+	if err != nil {
+		r.log.Error(logrus.ErrorLevel, "AssignTenant", "Error assigning traffic tenant", err)
+		return 0, terrors.InternalService("assign_tenant", "Failed assign traffic tenant in the database", map[string]string{})
+	}
+
+	rows, err := res.RowsAffected()
+	return int(rows), err
+}
+
+// WindowTotals Sums the frames buckets of every device over the window, only the devices reaching the
+// minimum frames or panic frames are returned and a minimum lower than one is ignored
+func (r *DatabaseRepository) WindowTotals(ctx context.Context, window time.Duration, minFrames, minPanics int) ([]WindowTotal, error) {
+	if minFrames < 1 && minPanics < 1 {
+		return nil, nil
+	}
+
+	query := r.db.NewSelect().
+		Model((*BucketModel)(nil)).
+		Column("imei").
+		ColumnExpr("SUM(frames) AS frames").
+		ColumnExpr("COALESCE(SUM(frames) FILTER (WHERE is_alarm), 0) AS panics").
+		ColumnExpr(`(SELECT MAX(t.tenant_id) FROM traffic AS t
+			WHERE t.imei = traffic_buckets.imei AND t.deleted_at IS NULL) AS tenant_id`).
+		Where("bucket > now() - ?::interval", interval(window)).
+		Group("imei")
+
+	switch {
+	case minFrames > 0 && minPanics > 0:
+		query = query.Having("SUM(frames) >= ? OR SUM(frames) FILTER (WHERE is_alarm) >= ?", minFrames, minPanics)
+	case minFrames > 0:
+		query = query.Having("SUM(frames) >= ?", minFrames)
+	default:
+		query = query.Having("SUM(frames) FILTER (WHERE is_alarm) >= ?", minPanics)
+	}
+
+	var totals []WindowTotal
+	if err := query.Scan(ctx, &totals); err != nil {
+		r.log.Error(logrus.ErrorLevel, "WindowTotals", "Error summing frames buckets", err)
+		return nil, terrors.InternalService("window_totals", "Failed sum frames buckets from the database", map[string]string{})
+	}
+
+	return totals, nil
+}
+
+// RetrieveExcessive Retrieves the flagged devices with the latest end of the quarantine of their rows
+func (r *DatabaseRepository) RetrieveExcessive(ctx context.Context) ([]ExcessiveDevice, error) {
+	var devices []ExcessiveDevice
+	err := r.db.NewSelect().
+		Model((*Model)(nil)).
+		Column("imei").
+		ColumnExpr("MAX(quarantined_until) AS quarantined_until").
+		Where("excessive_since IS NOT NULL").
+		Group("imei").
+		Scan(ctx, &devices)
+	if err != nil {
+		r.log.Error(logrus.ErrorLevel, "RetrieveExcessive", "Error scanning excessive devices", err)
+		return nil, terrors.InternalService("retrieve_excessive", "Failed retrieve excessive devices from the database", map[string]string{})
+	}
+
+	return devices, nil
+}
+
+// FlagExcessive Handles flagging the traffic rows of the devices, the rows already flagged keep their
+// flag and quarantine. The devices with a newly flagged row are returned.
+func (r *DatabaseRepository) FlagExcessive(ctx context.Context, imeis []string, at time.Time, quarantinedUntil *time.Time) ([]string, error) {
+	if len(imeis) == 0 {
+		return nil, nil
+	}
+
+	var flagged []string
+	_, err := r.db.NewUpdate().
+		Table("traffic").
+		Set("excessive_since = ?", at).
+		Set("quarantined_until = ?", quarantinedUntil).
+		Where("imei IN (?)", bun.In(imeis)).
+		Where("excessive_since IS NULL").
+		Where("deleted_at IS NULL").
+		Returning("imei").
+		Exec(ctx, &flagged)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		r.log.Error(logrus.ErrorLevel, "FlagExcessive", "Error flagging excessive devices", err)
+		return nil, terrors.InternalService("flag_excessive", "Failed flag excessive devices in the database", map[string]string{})
+	}
+
+	return slices.Compact(slices.Sorted(slices.Values(flagged))), nil
+}
+
+// ClearExcessive Handles clearing the flag and quarantine of the traffic rows of the devices,
+// the devices with a cleared row are returned
+func (r *DatabaseRepository) ClearExcessive(ctx context.Context, imeis []string) ([]string, error) {
+	if len(imeis) == 0 {
+		return nil, nil
+	}
+
+	var cleared []string
+	_, err := r.db.NewUpdate().
+		Table("traffic").
+		Set("excessive_since = NULL").
+		Set("quarantined_until = NULL").
+		Where("imei IN (?)", bun.In(imeis)).
+		Where("excessive_since IS NOT NULL").
+		Returning("imei").
+		Exec(ctx, &cleared)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		r.log.Error(logrus.ErrorLevel, "ClearExcessive", "Error clearing excessive devices", err)
+		return nil, terrors.InternalService("clear_excessive", "Failed clear excessive devices in the database", map[string]string{})
+	}
+
+	return slices.Compact(slices.Sorted(slices.Values(cleared))), nil
+}
+
 func (r *DatabaseRepository) RetrieveData(ctx context.Context, filter *Metadata) ([]Model, error) {
 	var traffics []Model
 
@@ -180,31 +545,214 @@ func (r *DatabaseRepository) RetrieveData(ctx context.Context, filter *Metadata)
 	return traffics, nil
 }
 
+// Bulk Applies the operation to every traffic row matching the filters and returns the
+// affected rows. The rows are locked first so the operation never exceeds the limit.
+func (r *DatabaseRepository) Bulk(ctx context.Context, operation string, filter *Metadata, limit int) (int, error) {
+	affected := 0
+	err := r.db.RunInTx(ctx, nil, func(ctx context.Context, tx bun.Tx) error {
+		var ids []string
+		err := setFilters(tx.NewSelect().Model((*Model)(nil)).Column("id"), filter).
+			Limit(limit+1).
+			For("UPDATE").
+			Scan(ctx, &ids)
+		if err != nil {
+			r.log.Error(logrus.ErrorLevel, "Bulk", "Error selecting traffics", err)
+			return terrors.InternalService("bulk_error", "Error selecting traffics in the database", map[string]string{})
+		}
+		if len(ids) > limit {
+			return terrors.New(terrors.ErrBadRequest, "Too many affected rows, narrow the filters", map[string]string{
+				"filter": "matches more than " + strconv.Itoa(limit) + " rows",
+			})
+		}
+		if len(ids) == 0 {
+			return nil
+		}
+
+		var res sql.Result
+		switch operation {
+		case BulkDelete:
+			res, err = tx.NewDelete().
+				Model((*Model)(nil)).
+				Where("id IN (?)", bun.In(ids)).
+				Exec(ctx)
+		case BulkResetCounter:
+			res, err = tx.NewUpdate().
+				Table("traffic").
+				Set("counter = 0").
+				Set("updated_at = ?", time.Now().UTC()).
+				Where("id IN (?)", bun.In(ids)).
+				Exec(ctx)
+		case BulkMarkNotified:
+			res, err = tx.NewUpdate().
+				Table("traffic").
+				Set("isnotified = ?", true).
+				Where("id IN (?)", bun.In(ids)).
+				Exec(ctx)
+		default:
+			return terrors.New(terrors.ErrBadRequest, "Invalid operation", map[string]string{})
+		}
+		if err != nil {
+			r.log.Error(logrus.ErrorLevel, "Bulk", "Error applying bulk operation", err)
+			return terrors.InternalService("bulk_error", "Error applying bulk operation in the database", map[string]string{})
+		}
+
+		rows, err := res.RowsAffected()
+		affected = int(rows)
+		return err
+	})
+
+	return affected, err
+}
+
+// CountData Counts the traffic rows matching the filters
+func (r *DatabaseRepository) CountData(ctx context.Context, filter *Metadata) (int, error) {
+	query := r.db.NewSelect().Model((*Model)(nil))
+
+	query = setFilters(query, filter)
+
+	total, err := query.Count(ctx)
+	if err != nil {
+		r.log.Error(logrus.ErrorLevel, "CountData", "Error counting traffics", err)
+		return 0, terrors.InternalService("count_error", "Error counting records in the database", map[string]string{})
+	}
+
+	return total, nil
+}
+
+// StreamData Walks every traffic row matching the filters through a server-side cursor,
+// so arbitrarily large result sets never have to be held in memory.
+func (r *DatabaseRepository) StreamData(ctx context.Context, filter *Metadata, fn func(Model) error) error {
+	query := r.db.NewSelect().Model((*Model)(nil))
+
+	query = setFilters(query, filter).Order("created_at ASC", "id ASC")
+
+	return r.db.RunInTx(ctx, &sql.TxOptions{ReadOnly: true}, func(ctx context.Context, tx bun.Tx) error {
+		if _, err := tx.ExecContext(ctx, "DECLARE traffic_export NO SCROLL CURSOR FOR "+query.String()); err != nil {
+			r.log.Error(logrus.ErrorLevel, "StreamData", "Error declaring cursor", err)
+			return terrors.InternalService("stream_error", "Error retrieving traffics from the database", map[string]string{})
+		}
+
+		for {
+			var batch []Model
+			if err := tx.NewRaw("FETCH ? FROM traffic_export", streamBatchSize).Scan(ctx, &batch); err != nil && !errors.Is(err, sql.ErrNoRows) {
+				r.log.Error(logrus.ErrorLevel, "StreamData", "Error fetching from cursor", err)
+				return terrors.InternalService("stream_error", "Error retrieving traffics from the database", map[string]string{})
+			}
+
+			for _, m := range batch {
+				if err := fn(m); err != nil {
+					return err
+				}
+			}
+
+			if len(batch) < streamBatchSize {
+				break
+			}
+		}
+
+		_, err := tx.ExecContext(ctx, "CLOSE traffic_export")
+		return err
+	})
+}
+
+// Stats Aggregates the frames buckets of the traffic rows matching the filters by the time bucket the
+// frames were received in and group key, ordered by bucket then key. Only the frames still within
+// the buckets retention are aggregated
+func (r *DatabaseRepository) Stats(ctx context.Context, query *StatsQuery) ([]StatsRow, error) {
+	group, ok := statsGroups[query.GroupBy]
+	if !ok {
+		return nil, terrors.New(terrors.ErrBadRequest, "Invalid groupBy parameter", map[string]string{})
+	}
+
+	// The bucket columns are renamed so the traffic filters stay unambiguous
+	frames := r.db.NewSelect().
+		Model((*BucketModel)(nil)).
+		ColumnExpr("imei AS frames_imei, is_alarm AS frames_alarm, bucket AS frames_bucket, frames")
+	if query.From != nil {
+		frames = frames.Where("bucket >= ?", query.From.UTC().Truncate(BucketWidth))
+	}
+	if query.To != nil {
+		frames = frames.Where("bucket <= ?", query.To)
+	}
+
+	var rows []StatsRow
+	q := r.db.NewSelect().
+		Model((*Model)(nil)).
+		Join("JOIN (?) AS b", frames).
+		JoinOn("b.frames_imei = ?TableAlias.imei").
+		JoinOn("b.frames_alarm = ?TableAlias.\"isAlarm\"").
+		ColumnExpr("date_trunc(?, b.frames_bucket) AS bucket", query.Bucket).
+		ColumnExpr(group + " AS key").
+		ColumnExpr("COUNT(DISTINCT ?TableAlias.id) AS records").
+		ColumnExpr("COALESCE(SUM(b.frames), 0) AS frames").
+		ColumnExpr("COALESCE(SUM(b.frames) FILTER (WHERE b.frames_alarm), 0) AS alarms").
+		ColumnExpr("COUNT(DISTINCT ?TableAlias.imei) AS devices")
+
+	q = setFilters(q, query.Filter).
+		GroupExpr("1, 2").
+		OrderExpr("1 ASC, 2 ASC")
+
+	if query.Limit > 0 {
+		q = q.Limit(query.Limit)
+	}
+
+	if err := q.Scan(ctx, &rows); err != nil {
+		r.log.Error(logrus.ErrorLevel, "Stats", "Error aggregating traffics", err)
+		return nil, terrors.InternalService("stats_error", "Error aggregating traffics in the database", map[string]string{})
+	}
+
+	return rows, nil
+}
+
 func setFilters(q *bun.SelectQuery, filter *Metadata) *bun.SelectQuery {
+	if filter.IncludeDeleted {
+		q = q.WhereAllWithDeleted()
+	}
 
 	q = q.WhereGroup(" AND ", func(q *bun.SelectQuery) *bun.SelectQuery {
 
+		if !filter.Scope.All {
+			if filter.Scope.Empty() {
+				q = q.Where("FALSE")
+			} else {
+				q = q.Where("tenant_id IN (?)", bun.In(filter.Scope.Tenants))
+			}
+		}
+
 		if filter.Qparam != "" {
+			pattern := "%" + pagination.EscapeLike(filter.Qparam) + "%"
 			q = q.WhereGroup(" OR ", func(q *bun.SelectQuery) *bun.SelectQuery {
-				q = q.Where("id::text LIKE ?", "%"+filter.Qparam+"%").
-					WhereOr("request LIKE ?", "%"+filter.Qparam+"%").
-					WhereOr("imei LIKE ?", "%"+filter.Qparam+"%").
-					WhereOr("ip LIKE ?", "%"+filter.Qparam+"%")
+				q = q.Where("request ILIKE ?", pattern).
+					WhereOr("imei ILIKE ?", pattern).
+					WhereOr("ip ILIKE ?", pattern)
+				// A pasted traffic id still finds its row, through the primary key
+				if id, err := uuid.Parse(filter.Qparam); err == nil {
+					q = q.WhereOr("id = ?", id.String())
+				}
 				return q
 			})
 		}
 
+		if len(filter.IDs) > 0 {
+			q = q.Where("id IN (?)", bun.In(filter.IDs))
+		}
+		// The plain filters are anchored prefixes, the same as the prefix operator, and a complete id is an
+		// exact match, so they are served by the pattern indexes and the primary key
 		if filter.ID != "" {
-			q = q.Where("id::text LIKE ?", "%"+filter.ID+"%")
+			if id, err := uuid.Parse(filter.ID); err == nil {
+				q = q.Where("id = ?", id.String())
+			} else {
+				q = q.Where("id::text LIKE ?", pagination.EscapeLike(strings.ToLower(filter.ID))+"%")
+			}
 		}
 		if filter.Request != "" {
-			q = q.Where("request LIKE ?", "%"+filter.Request+"%")
+			q = q.Where("request LIKE ?", pagination.EscapeLike(filter.Request)+"%")
 		}
 		if filter.IMEI != "" {
-			q = q.Where("imei LIKE ?", "%"+filter.IMEI+"%")
+			q = q.Where("imei LIKE ?", pagination.EscapeLike(filter.IMEI)+"%")
 		}
 		if filter.Ip != "" {
-			q = q.Where("ip LIKE ?", "%"+filter.Ip+"%")
+			q = q.Where("ip LIKE ?", pagination.EscapeLike(filter.Ip)+"%")
 		}
 		if filter.IsAlarm != nil {
 			q = q.Where("\"isAlarm\" = ?", filter.IsAlarm)
@@ -216,6 +764,20 @@ func setFilters(q *bun.SelectQuery, filter *Metadata) *bun.SelectQuery {
 				q = q.Where("counter > ?", filter.Counter)
 			}
 		}
+		if filter.CreatedFrom != nil {
+			q = q.Where("created_at >= ?", filter.CreatedFrom)
+		}
+		if filter.CreatedTo != nil {
+			q = q.Where("created_at <= ?", filter.CreatedTo)
+		}
+		if filter.UpdatedFrom != nil {
+			q = q.Where("updated_at >= ?", filter.UpdatedFrom)
+		}
+		if filter.UpdatedTo != nil {
+			q = q.Where("updated_at <= ?", filter.UpdatedTo)
+		}
+
+		q = pagination.ApplyConditions(q, filter.Conditions)
 
 		return q
 	})
@@ -223,6 +785,34 @@ func setFilters(q *bun.SelectQuery, filter *Metadata) *bun.SelectQuery {
 	return q
 }
 
+// withWindows adds the rolling window counters of each row, they are summed from the frames buckets
+// of the row so the listing filters never have to touch the buckets
+func withWindows(q *bun.SelectQuery) *bun.SelectQuery {
+	return q.
+		ColumnExpr("?TableAlias.*").
+		ColumnExpr("windows.frames_5m, windows.frames_1h, windows.frames_24h").
+		Join(`LEFT JOIN LATERAL (
+			SELECT COALESCE(SUM(b.frames) FILTER (WHERE b.bucket > now() - ?::interval), 0) AS frames_5m,
+				COALESCE(SUM(b.frames) FILTER (WHERE b.bucket > now() - ?::interval), 0) AS frames_1h,
+				COALESCE(SUM(b.frames), 0) AS frames_24h
+			FROM traffic_buckets AS b
+			WHERE b.imei = ?TableAlias.imei AND b.is_alarm = ?TableAlias."isAlarm" AND b.bucket > now() - ?::interval
+		) AS windows ON TRUE`, interval(Window5m), interval(Window1h), interval(Window24h))
+}
+
+// interval formats a duration as a postgres interval
+func interval(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', -1, 64) + " seconds"
+}
+
+// orderByRelevance ranks the rows by how closely the search term matches a word of the
+// searched columns, the substring filter itself is served by the trigram indexes
+func orderByRelevance(q *bun.SelectQuery, term string) *bun.SelectQuery {
+	return q.
+		OrderExpr("GREATEST(word_similarity(?, imei), word_similarity(?, ip), word_similarity(?, request)) DESC", term, term, term).
+		OrderExpr("id ASC")
+}
+
 func paginationMeta(ctx context.Context, q *bun.SelectQuery, filter *Metadata) (int, int, error) {
 	totalRecords := 0
 
@@ -0,0 +1,175 @@
+package traffic
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/jmontesinos91/collector/domains/pagination"
+	"github.com/jmontesinos91/collector/domains/tenancy"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/uptrace/bun"
+	"github.com/uptrace/bun/dialect/pgdialect"
+)
+
+// offlineConnector a database that can not be reached, the queries are only recorded by the hook
+type offlineConnector struct{}
+
+func (offlineConnector) Connect(context.Context) (driver.Conn, error) {
+	return nil, errors.New("offline")
+}
+
+func (c offlineConnector) Driver() driver.Driver {
+	return c
+}
+
+func (offlineConnector) Open(string) (driver.Conn, error) {
+	return nil, errors.New("offline")
+}
+
+// queryRecorder bun hook recording the formatted queries
+type queryRecorder struct {
+	queries []string
+}
+
+func (r *queryRecorder) BeforeQuery(ctx context.Context, event *bun.QueryEvent) context.Context {
+	r.queries = append(r.queries, event.Query)
+	return ctx
+}
+
+func (r *queryRecorder) AfterQuery(context.Context, *bun.QueryEvent) {}
+
+// last the last recorded query
+func (r *queryRecorder) last() string {
+	if len(r.queries) == 0 {
+		return ""
+	}
+	return r.queries[len(r.queries)-1]
+}
+
+func TestRetrieveSearch(t *testing.T) {
+	log := logger.NewContextLogger("RetrieveSearch", "debug", logger.TextFormat)
+	// Without counting the listing is the only query
+	noCount := false
+
+	sortBy := func(field string) []pagination.Sort {
+		sorts, err := Spec.ParseSort(field, false)
+		if err != nil {
+			t.Fatalf("ParseSort() error = %v", err)
+		}
+		return sorts
+	}
+
+	tests := []struct {
+		name    string
+		filter  *Metadata
+		cursor  bool
+		asserts func(*testing.T, string) bool
+	}{
+		{
+			name:   "Search is ranked by relevance",
+			filter: &Metadata{Qparam: "8615", Filter: pagination.Filter{Page: 1, Size: 10, Count: &noCount}},
+			asserts: func(t *testing.T, query string) bool {
+				return assert.Contains(t, query, "ORDER BY GREATEST(word_similarity('8615', imei), "+
+					"word_similarity('8615', ip), word_similarity('8615', request)) DESC, id ASC") &&
+					assert.Contains(t, query, "((request ILIKE '%8615%') OR (imei ILIKE '%8615%') OR (ip ILIKE '%8615%'))")
+			},
+		},
+		{
+			name:   "Requested sort replaces the relevance",
+			filter: &Metadata{Qparam: "8615", Filter: pagination.Filter{Page: 1, Size: 10, Count: &noCount, Sorts: sortBy("imei")}},
+			asserts: func(t *testing.T, query string) bool {
+				return assert.NotContains(t, query, "word_similarity") &&
+					assert.Contains(t, query, `ORDER BY "imei" ASC`)
+			},
+		},
+		{
+			name:   "Cursor listing keeps the keyset order",
+			filter: &Metadata{Qparam: "8615", Filter: pagination.Filter{Size: 10, Mode: pagination.ModeCursor}},
+			cursor: true,
+			asserts: func(t *testing.T, query string) bool {
+				return assert.NotContains(t, query, "word_similarity") &&
+					assert.Contains(t, query, `ORDER BY "updated_at" ASC, "id" ASC`)
+			},
+		},
+		{
+			name:   "Listing without search is not ranked",
+			filter: &Metadata{Filter: pagination.Filter{Page: 1, Size: 10, Count: &noCount}},
+			asserts: func(t *testing.T, query string) bool {
+				return assert.NotContains(t, query, "word_similarity") && assert.NotContains(t, query, "ILIKE")
+			},
+		},
+		{
+			name:   "Traffic id is matched exactly",
+			filter: &Metadata{Qparam: "0D8C4E0A-1B5B-4F4E-9C1F-2A3B4C5D6E7F", Filter: pagination.Filter{Page: 1, Size: 10, Count: &noCount}},
+			asserts: func(t *testing.T, query string) bool {
+				return assert.Contains(t, query, "OR (id = '0d8c4e0a-1b5b-4f4e-9c1f-2a3b4c5d6e7f')")
+			},
+		},
+		{
+			name:   "Partial traffic id is not matched by id",
+			filter: &Metadata{Qparam: "0d8c4e0a", Filter: pagination.Filter{Page: 1, Size: 10, Count: &noCount}},
+			asserts: func(t *testing.T, query string) bool {
+				return assert.NotContains(t, query, "id = ")
+			},
+		},
+		{
+			name:   "Partial plain id is an anchored prefix",
+			filter: &Metadata{ID: "0D8C4E0A", Filter: pagination.Filter{Page: 1, Size: 10, Count: &noCount}},
+			asserts: func(t *testing.T, query string) bool {
+				return assert.Contains(t, query, "(id::text LIKE '0d8c4e0a%')")
+			},
+		},
+		{
+			name: "Plain filters are exact or anchored prefixes",
+			filter: &Metadata{ID: "0d8c4e0a-1b5b-4f4e-9c1f-2a3b4c5d6e7f", IMEI: "8615_", Ip: "10.0.", Request: "P,",
+				Filter: pagination.Filter{Page: 1, Size: 10, Count: &noCount}},
+			asserts: func(t *testing.T, query string) bool {
+				return assert.Contains(t, query, "(id = '0d8c4e0a-1b5b-4f4e-9c1f-2a3b4c5d6e7f')") &&
+					assert.Contains(t, query, `(request LIKE 'P,%')`) &&
+					assert.Contains(t, query, `(imei LIKE '8615\_%')`) &&
+					assert.Contains(t, query, `(ip LIKE '10.0.%')`)
+			},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			recorder := &queryRecorder{}
+			db := bun.NewDB(sql.OpenDB(offlineConnector{}), pgdialect.New())
+			db.AddQueryHook(recorder)
+			repo := NewDatabaseRepository(log, db)
+
+			tc.filter.Scope = tenancy.Scope{All: true}
+			var err error
+			if tc.cursor {
+				_, err = repo.RetrieveCursor(context.Background(), tc.filter)
+			} else {
+				_, _, _, err = repo.Retrieve(context.Background(), tc.filter)
+			}
+
+			// The database is offline, only the query is checked
+			if !assert.Error(t, err) || !tc.asserts(t, recorder.last()) {
+				t.Errorf("Assert error on test = '%v'", tc.name)
+			}
+		})
+	}
+}
+
+func TestUpdateIsNotified(t *testing.T) {
+	log := logger.NewContextLogger("UpdateIsNotified", "debug", logger.TextFormat)
+	recorder := &queryRecorder{}
+	db := bun.NewDB(sql.OpenDB(offlineConnector{}), pgdialect.New())
+	db.AddQueryHook(recorder)
+	repo := NewDatabaseRepository(log, db)
+
+	err := repo.UpdateIsNotified(context.Background(), "0d8c4e0a-1b5b-4f4e-9c1f-2a3b4c5d6e7f")
+
+	// The database is offline, only the query is checked
+	if !assert.Error(t, err) || !assert.Contains(t, recorder.last(), "(deleted_at IS NULL)") {
+		t.Errorf("Assert error on test = '%v'", "Soft deleted traffics are not notified")
+	}
+}
@@ -2,6 +2,7 @@ package traffic
 
 import (
 	"github.com/jmontesinos91/collector/domains/pagination"
+	"github.com/jmontesinos91/collector/domains/tenancy"
 	"time"
 
 	"github.com/uptrace/bun"
@@ -11,25 +12,158 @@ import (
 type Model struct {
 	bun.BaseModel `bun:"table:traffic"`
 
-	ID         string    `bun:"id,pk"`
-	Request    string    `bun:"request,pk"`
-	IMEI       string    `bun:"imei"`
-	Ip         string    `bun:"ip"`
-	IsAlarm    bool      `bun:"isAlarm"`
-	IsNotified bool      `bun:"isnotified"`
-	Counter    int       `bun:"counter"`
-	CreatedAt  time.Time `bun:"created_at"`
-	UpdatedAt  time.Time `bun:"updated_at"`
+	ID         string     `bun:"id,pk"`
+	Request    string     `bun:"request,pk"`
+	IMEI       string     `bun:"imei"`
+	Ip         string     `bun:"ip"`
+	IsAlarm    bool       `bun:"isAlarm"`
+	IsNotified bool       `bun:"isnotified"`
+	Counter    int        `bun:"counter"`
+	TenantID   int        `bun:"tenant_id,nullzero"`
+	CreatedAt  time.Time  `bun:"created_at"`
+	UpdatedAt  time.Time  `bun:"updated_at"`
+	DeletedAt  *time.Time `bun:"deleted_at,soft_delete,nullzero"`
+	// The device crossed its frames threshold, its frames are rejected until the quarantine ends
+	ExcessiveSince   *time.Time `bun:"excessive_since,nullzero"`
+	QuarantinedUntil *time.Time `bun:"quarantined_until,nullzero"`
+
+	// Frames received over the rolling windows, only listings compute them
+	Frames5m  int `bun:"frames_5m,scanonly"`
+	Frames1h  int `bun:"frames_1h,scanonly"`
+	Frames24h int `bun:"frames_24h,scanonly"`
+}
+
+// BucketModel Database model for the frames a traffic row received within a minute
+type BucketModel struct {
+	bun.BaseModel `bun:"table:traffic_buckets,alias:traffic_buckets"`
+
+	IMEI    string    `bun:"imei,pk"`
+	IsAlarm bool      `bun:"is_alarm,pk"`
+	Bucket  time.Time `bun:"bucket,pk"`
+	Frames  int       `bun:"frames"`
+}
+
+// BucketWidth time span of a frames bucket
+const BucketWidth = time.Minute
+
+// Rolling windows of the frame counters, buckets are counted while their start lies within the window
+const (
+	Window5m  = 5 * time.Minute
+	Window1h  = time.Hour
+	Window24h = 24 * time.Hour
+)
+
+// WindowTotal frames and panic frames a device sent over a rolling window
+type WindowTotal struct {
+	IMEI     string `bun:"imei"`
+	TenantID int    `bun:"tenant_id"`
+	Frames   int    `bun:"frames"`
+	Panics   int    `bun:"panics"`
+}
+
+// ExcessiveDevice device flagged for crossing its frames threshold
+type ExcessiveDevice struct {
+	IMEI             string     `bun:"imei"`
+	QuarantinedUntil *time.Time `bun:"quarantined_until"`
 }
 
 // Metadata struct filter for repository layer
 type Metadata struct {
-	Qparam  string
-	ID      string
-	Request string
-	IMEI    string
-	Ip      string
-	IsAlarm *bool
-	Counter *int
-	Filter  pagination.Filter
+	Qparam      string
+	ID          string
+	IDs         []string
+	Request     string
+	IMEI        string
+	Ip          string
+	IsAlarm     *bool
+	Counter     *int
+	CreatedFrom *time.Time
+	CreatedTo   *time.Time
+	UpdatedFrom *time.Time
+	UpdatedTo   *time.Time
+	// IncludeDeleted also lists the soft deleted rows
+	IncludeDeleted bool
+	// Conditions typed filters parsed from field[operator]=value params, see Spec
+	Conditions []pagination.Condition
+	// Scope tenants the rows must belong to, the zero value matches no row
+	Scope  tenancy.Scope
+	Filter pagination.Filter
+}
+
+// Spec sortable and filterable traffic fields, text fields offer anchored operators
+// backed by pattern indexes and the ip can be matched against a network
+var Spec = pagination.NewSpec(
+	pagination.Field{Name: "id", Column: "id", Type: pagination.TypeString, Sortable: true,
+		Operators: []pagination.Operator{pagination.OpEq, pagination.OpIn}},
+	pagination.Field{Name: "request", Column: "request", Type: pagination.TypeString,
+		Operators: []pagination.Operator{pagination.OpEq, pagination.OpLike, pagination.OpPrefix}},
+	pagination.Field{Name: "imei", Column: "imei", Type: pagination.TypeString, Sortable: true,
+		Operators: []pagination.Operator{pagination.OpEq, pagination.OpNe, pagination.OpLike, pagination.OpPrefix, pagination.OpIn}},
+	pagination.Field{Name: "ip", Column: "ip", Type: pagination.TypeString, Sortable: true,
+		Operators: []pagination.Operator{pagination.OpEq, pagination.OpNe, pagination.OpLike, pagination.OpPrefix, pagination.OpCIDR},
+		Templates: map[pagination.Operator]string{pagination.OpCIDR: "traffic_ip_inet(?) <<= ?::cidr"}},
+	pagination.Field{Name: "alarm", Column: "isAlarm", Type: pagination.TypeBool, Sortable: true,
+		Operators: []pagination.Operator{pagination.OpEq}},
+	pagination.Field{Name: "counter", Column: "counter", Type: pagination.TypeInt, Sortable: true,
+		Operators: []pagination.Operator{pagination.OpEq, pagination.OpNe, pagination.OpGt, pagination.OpGte, pagination.OpLt, pagination.OpLte}},
+	pagination.Field{Name: "created_at", Column: "created_at", Type: pagination.TypeTime, Sortable: true,
+		Operators: []pagination.Operator{pagination.OpGt, pagination.OpGte, pagination.OpLt, pagination.OpLte}},
+	pagination.Field{Name: "updated_at", Column: "updated_at", Type: pagination.TypeTime, Sortable: true,
+		Operators: []pagination.Operator{pagination.OpGt, pagination.OpGte, pagination.OpLt, pagination.OpLte}},
+	pagination.Field{Name: "excessive_since", Column: "excessive_since", Type: pagination.TypeTime, Sortable: true,
+		Operators: []pagination.Operator{pagination.OpGt, pagination.OpGte, pagination.OpLt, pagination.OpLte}},
+	// The rolling window counters are computed by the listing query, they can only be sorted on
+	pagination.Field{Name: "frames_5m", Column: "frames_5m", Type: pagination.TypeInt, Sortable: true},
+	pagination.Field{Name: "frames_1h", Column: "frames_1h", Type: pagination.TypeInt, Sortable: true},
+	pagination.Field{Name: "frames_24h", Column: "frames_24h", Type: pagination.TypeInt, Sortable: true},
+)
+
+// Bulk operations
+const (
+	BulkDelete       = "delete"
+	BulkResetCounter = "reset-counter"
+	BulkMarkNotified = "mark-notified"
+)
+
+// CursorPage page of a keyset paginated listing, total is only set when counted
+type CursorPage struct {
+	Models []Model
+	Next   string
+	Prev   string
+	Total  *int
+}
+
+// Stats group by fields
+const (
+	GroupByIMEI  = "imei"
+	GroupByIP    = "ip"
+	GroupByAlarm = "alarm"
+)
+
+// Stats bucket sizes
+const (
+	BucketMinute = "minute"
+	BucketHour   = "hour"
+	BucketDay    = "day"
+)
+
+// StatsQuery aggregation of the frames buckets of the traffic rows matching the filter, from and to
+// bound the time the frames were received
+type StatsQuery struct {
+	GroupBy string
+	Bucket  string
+	From    *time.Time
+	To      *time.Time
+	Limit   int
+	Filter  *Metadata
+}
+
+// StatsRow aggregated values of a bucket and group key
+type StatsRow struct {
+	Bucket  time.Time `bun:"bucket"`
+	Key     string    `bun:"key"`
+	Records int       `bun:"records"`
+	Frames  int       `bun:"frames"`
+	Alarms  int       `bun:"alarms"`
+	Devices int       `bun:"devices"`
 }
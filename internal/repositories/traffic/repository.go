@@ -2,17 +2,34 @@ package traffic
 
 import (
 	"context"
+	"time"
 )
 
 // IRepository interface
 type IRepository interface {
 	Create(ctx context.Context, model *Model) error
 	FindByIMEI(ctx context.Context, imei string, isAlarm bool) (bool, error)
+	FindByID(ctx context.Context, trafficID string) (*Model, error)
 	FindByLastUsed(ctx context.Context) ([]Model, error)
 	UpdateIsNotified(ctx context.Context, trafficID string) error
-	UpdateByIMEI(ctx context.Context, imei, request string, isAlarm bool) error
+	UpdateByIMEI(ctx context.Context, imei, request string, isAlarm bool, tenantID int) error
 	Retrieve(ctx context.Context, filter *Metadata) ([]Model, int, int, error)
+	RetrieveCursor(ctx context.Context, filter *Metadata) (CursorPage, error)
 	DeleteByID(ctx context.Context, trafficID string) error
+	Restore(ctx context.Context, trafficID string) error
+	PurgeDeleted(ctx context.Context, before time.Time, batchSize int) (int, error)
 	RetrieveData(ctx context.Context, filter *Metadata) ([]Model, error)
+	RecordFrame(ctx context.Context, imei string, isAlarm bool, at time.Time) error
+	PurgeBuckets(ctx context.Context, before time.Time) (int, error)
+	WindowTotals(ctx context.Context, window time.Duration, minFrames, minPanics int) ([]WindowTotal, error)
+	RetrieveExcessive(ctx context.Context) ([]ExcessiveDevice, error)
+	FlagExcessive(ctx context.Context, imeis []string, at time.Time, quarantinedUntil *time.Time) ([]string, error)
+	ClearExcessive(ctx context.Context, imeis []string) ([]string, error)
 	ResetCounter(ctx context.Context, trafficID string) error
+	CountData(ctx context.Context, filter *Metadata) (int, error)
+	StreamData(ctx context.Context, filter *Metadata, fn func(Model) error) error
+	Stats(ctx context.Context, query *StatsQuery) ([]StatsRow, error)
+	Bulk(ctx context.Context, operation string, filter *Metadata, limit int) (int, error)
+	RetrieveUntenanted(ctx context.Context, after string, limit int) ([]string, error)
+	AssignTenant(ctx context.Context, imei string, tenantID int) (int, error)
 }
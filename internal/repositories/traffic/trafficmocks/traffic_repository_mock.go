@@ -7,6 +7,8 @@ import (
 
 	"github.com/jmontesinos91/collector/internal/repositories/traffic"
 	mock "github.com/stretchr/testify/mock"
+
+	time "time"
 )
 
 // IRepository is an autogenerated mock type for the IRepository type
@@ -66,6 +68,32 @@ func (_m *IRepository) FindByIMEI(ctx context.Context, imei string, isAlarm bool
 	return r0, r1
 }
 
+// FindByID provides a mock function with given fields: ctx, trafficID
+func (_m *IRepository) FindByID(ctx context.Context, trafficID string) (*traffic.Model, error) {
+	ret := _m.Called(ctx, trafficID)
+
+	var r0 *traffic.Model
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) (*traffic.Model, error)); ok {
+		return rf(ctx, trafficID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string) *traffic.Model); ok {
+		r0 = rf(ctx, trafficID)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*traffic.Model)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string) error); ok {
+		r1 = rf(ctx, trafficID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // FindByLastUsed provides a mock function with given fields: ctx
 func (_m *IRepository) FindByLastUsed(ctx context.Context) ([]traffic.Model, error) {
 	ret := _m.Called(ctx)
@@ -132,6 +160,30 @@ func (_m *IRepository) Retrieve(ctx context.Context, filter *traffic.Metadata) (
 	return r0, r1, r2, r3
 }
 
+// RetrieveCursor provides a mock function with given fields: ctx, filter
+func (_m *IRepository) RetrieveCursor(ctx context.Context, filter *traffic.Metadata) (traffic.CursorPage, error) {
+	ret := _m.Called(ctx, filter)
+
+	var r0 traffic.CursorPage
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *traffic.Metadata) (traffic.CursorPage, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *traffic.Metadata) traffic.CursorPage); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		r0 = ret.Get(0).(traffic.CursorPage)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *traffic.Metadata) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // RetrieveData provides a mock function with given fields: ctx, filter
 func (_m *IRepository) RetrieveData(ctx context.Context, filter *traffic.Metadata) ([]traffic.Model, error) {
 	ret := _m.Called(ctx, filter)
@@ -158,13 +210,13 @@ func (_m *IRepository) RetrieveData(ctx context.Context, filter *traffic.Metadat
 	return r0, r1
 }
 
-// UpdateByIMEI provides a mock function with given fields: ctx, imei, request, isAlarm
-func (_m *IRepository) UpdateByIMEI(ctx context.Context, imei string, request string, isAlarm bool) error {
-	ret := _m.Called(ctx, imei, request, isAlarm)
+// UpdateByIMEI provides a mock function with given fields: ctx, imei, request, isAlarm, tenantID
+func (_m *IRepository) UpdateByIMEI(ctx context.Context, imei string, request string, isAlarm bool, tenantID int) error {
+	ret := _m.Called(ctx, imei, request, isAlarm, tenantID)
 
 	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, string, string, bool) error); ok {
-		r0 = rf(ctx, imei, request, isAlarm)
+	if rf, ok := ret.Get(0).(func(context.Context, string, string, bool, int) error); ok {
+		r0 = rf(ctx, imei, request, isAlarm, tenantID)
 	} else {
 		r0 = ret.Error(0)
 	}
@@ -186,6 +238,186 @@ func (_m *IRepository) UpdateIsNotified(ctx context.Context, trafficID string) e
 	return r0
 }
 
+// Restore provides a mock function with given fields: ctx, trafficID
+func (_m *IRepository) Restore(ctx context.Context, trafficID string) error {
+	ret := _m.Called(ctx, trafficID)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string) error); ok {
+		r0 = rf(ctx, trafficID)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// PurgeDeleted provides a mock function with given fields: ctx, before, batchSize
+func (_m *IRepository) PurgeDeleted(ctx context.Context, before time.Time, batchSize int) (int, error) {
+	ret := _m.Called(ctx, before, batchSize)
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, int) (int, error)); ok {
+		return rf(ctx, before, batchSize)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time, int) int); ok {
+		r0 = rf(ctx, before, batchSize)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time, int) error); ok {
+		r1 = rf(ctx, before, batchSize)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RecordFrame provides a mock function with given fields: ctx, imei, isAlarm, at
+func (_m *IRepository) RecordFrame(ctx context.Context, imei string, isAlarm bool, at time.Time) error {
+	ret := _m.Called(ctx, imei, isAlarm, at)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, bool, time.Time) error); ok {
+		r0 = rf(ctx, imei, isAlarm, at)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// PurgeBuckets provides a mock function with given fields: ctx, before
+func (_m *IRepository) PurgeBuckets(ctx context.Context, before time.Time) (int, error) {
+	ret := _m.Called(ctx, before)
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) (int, error)); ok {
+		return rf(ctx, before)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Time) int); ok {
+		r0 = rf(ctx, before)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Time) error); ok {
+		r1 = rf(ctx, before)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// WindowTotals provides a mock function with given fields: ctx, window, minFrames, minPanics
+func (_m *IRepository) WindowTotals(ctx context.Context, window time.Duration, minFrames int, minPanics int) ([]traffic.WindowTotal, error) {
+	ret := _m.Called(ctx, window, minFrames, minPanics)
+
+	var r0 []traffic.WindowTotal
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, time.Duration, int, int) ([]traffic.WindowTotal, error)); ok {
+		return rf(ctx, window, minFrames, minPanics)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, time.Duration, int, int) []traffic.WindowTotal); ok {
+		r0 = rf(ctx, window, minFrames, minPanics)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]traffic.WindowTotal)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, time.Duration, int, int) error); ok {
+		r1 = rf(ctx, window, minFrames, minPanics)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RetrieveExcessive provides a mock function with given fields: ctx
+func (_m *IRepository) RetrieveExcessive(ctx context.Context) ([]traffic.ExcessiveDevice, error) {
+	ret := _m.Called(ctx)
+
+	var r0 []traffic.ExcessiveDevice
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context) ([]traffic.ExcessiveDevice, error)); ok {
+		return rf(ctx)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context) []traffic.ExcessiveDevice); ok {
+		r0 = rf(ctx)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]traffic.ExcessiveDevice)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context) error); ok {
+		r1 = rf(ctx)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// FlagExcessive provides a mock function with given fields: ctx, imeis, at, quarantinedUntil
+func (_m *IRepository) FlagExcessive(ctx context.Context, imeis []string, at time.Time, quarantinedUntil *time.Time) ([]string, error) {
+	ret := _m.Called(ctx, imeis, at, quarantinedUntil)
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string, time.Time, *time.Time) ([]string, error)); ok {
+		return rf(ctx, imeis, at, quarantinedUntil)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string, time.Time, *time.Time) []string); ok {
+		r0 = rf(ctx, imeis, at, quarantinedUntil)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string, time.Time, *time.Time) error); ok {
+		r1 = rf(ctx, imeis, at, quarantinedUntil)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// ClearExcessive provides a mock function with given fields: ctx, imeis
+func (_m *IRepository) ClearExcessive(ctx context.Context, imeis []string) ([]string, error) {
+	ret := _m.Called(ctx, imeis)
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, []string) ([]string, error)); ok {
+		return rf(ctx, imeis)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, []string) []string); ok {
+		r0 = rf(ctx, imeis)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, []string) error); ok {
+		r1 = rf(ctx, imeis)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // ResetCounter provides a mock function with given fields: ctx, trafficID
 func (_m *IRepository) ResetCounter(ctx context.Context, trafficID string) error {
 	ret := _m.Called(ctx, trafficID)
@@ -200,6 +432,144 @@ func (_m *IRepository) ResetCounter(ctx context.Context, trafficID string) error
 	return r0
 }
 
+// Bulk provides a mock function with given fields: ctx, operation, filter, limit
+func (_m *IRepository) Bulk(ctx context.Context, operation string, filter *traffic.Metadata, limit int) (int, error) {
+	ret := _m.Called(ctx, operation, filter, limit)
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, *traffic.Metadata, int) (int, error)); ok {
+		return rf(ctx, operation, filter, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, *traffic.Metadata, int) int); ok {
+		r0 = rf(ctx, operation, filter, limit)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, *traffic.Metadata, int) error); ok {
+		r1 = rf(ctx, operation, filter, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// CountData provides a mock function with given fields: ctx, filter
+func (_m *IRepository) CountData(ctx context.Context, filter *traffic.Metadata) (int, error) {
+	ret := _m.Called(ctx, filter)
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *traffic.Metadata) (int, error)); ok {
+		return rf(ctx, filter)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *traffic.Metadata) int); ok {
+		r0 = rf(ctx, filter)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *traffic.Metadata) error); ok {
+		r1 = rf(ctx, filter)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// StreamData provides a mock function with given fields: ctx, filter, fn
+func (_m *IRepository) StreamData(ctx context.Context, filter *traffic.Metadata, fn func(traffic.Model) error) error {
+	ret := _m.Called(ctx, filter, fn)
+
+	var r0 error
+	if rf, ok := ret.Get(0).(func(context.Context, *traffic.Metadata, func(traffic.Model) error) error); ok {
+		r0 = rf(ctx, filter, fn)
+	} else {
+		r0 = ret.Error(0)
+	}
+
+	return r0
+}
+
+// Stats provides a mock function with given fields: ctx, query
+func (_m *IRepository) Stats(ctx context.Context, query *traffic.StatsQuery) ([]traffic.StatsRow, error) {
+	ret := _m.Called(ctx, query)
+
+	var r0 []traffic.StatsRow
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, *traffic.StatsQuery) ([]traffic.StatsRow, error)); ok {
+		return rf(ctx, query)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, *traffic.StatsQuery) []traffic.StatsRow); ok {
+		r0 = rf(ctx, query)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]traffic.StatsRow)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, *traffic.StatsQuery) error); ok {
+		r1 = rf(ctx, query)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// RetrieveUntenanted provides a mock function with given fields: ctx, after, limit
+func (_m *IRepository) RetrieveUntenanted(ctx context.Context, after string, limit int) ([]string, error) {
+	ret := _m.Called(ctx, after, limit)
+
+	var r0 []string
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) ([]string, error)); ok {
+		return rf(ctx, after, limit)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) []string); ok {
+		r0 = rf(ctx, after, limit)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = rf(ctx, after, limit)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
+// AssignTenant provides a mock function with given fields: ctx, imei, tenantID
+func (_m *IRepository) AssignTenant(ctx context.Context, imei string, tenantID int) (int, error) {
+	ret := _m.Called(ctx, imei, tenantID)
+
+	var r0 int
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) (int, error)); ok {
+		return rf(ctx, imei, tenantID)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, string, int) int); ok {
+		r0 = rf(ctx, imei, tenantID)
+	} else {
+		r0 = ret.Get(0).(int)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, string, int) error); ok {
+		r1 = rf(ctx, imei, tenantID)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 type mockConstructorTestingTNewIRepository interface {
 	mock.TestingT
 	Cleanup(func())
@@ -0,0 +1,87 @@
+package unitsold
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmontesinos91/collector/domains/cache"
+	"github.com/jmontesinos91/terrors"
+)
+
+// CachedRepository read-through cache decorator for IRepository, lookups by router and by
+// unit ID are served from bounded LRUs and unknown keys are negatively cached
+type CachedRepository struct {
+	next     IRepository
+	byRouter *cache.LRU[int, UnitsModel]
+	byID     *cache.LRU[int, UnitsModel]
+}
+
+// NewCachedRepository creates an instance of CachedRepository wrapping the given repository
+func NewCachedRepository(next IRepository, size int, ttl, negativeTTL time.Duration) *CachedRepository {
+	return &CachedRepository{
+		next:     next,
+		byRouter: cache.NewLRU[int, UnitsModel](size, ttl, negativeTTL),
+		byID:     cache.NewLRU[int, UnitsModel](size, ttl, negativeTTL),
+	}
+}
+
+// FindByRouterID Handles the find by router of unit record, going to the wrapped repository on a cache miss
+func (r *CachedRepository) FindByRouterID(ctx context.Context, routerID int) (*UnitsModel, error) {
+	return r.find(ctx, r.byRouter, routerID, r.next.FindByRouterID)
+}
+
+// FindByID Handles the find by unitID record, going to the wrapped repository on a cache miss
+func (r *CachedRepository) FindByID(ctx context.Context, unitID int) (*UnitsModel, error) {
+	return r.find(ctx, r.byID, unitID, r.next.FindByID)
+}
+
+// InvalidateRouter Removes the cached unit of the given router, by router and by unit ID
+func (r *CachedRepository) InvalidateRouter(routerID int) int {
+	invalidated := 0
+	if r.byRouter.Delete(routerID) {
+		invalidated++
+	}
+
+	return invalidated + r.byID.DeleteFunc(func(_ int, model UnitsModel) bool {
+		return model.RouterID == routerID
+	})
+}
+
+// InvalidateUnit Removes the cached entries of the given unit, by unit ID and by router
+func (r *CachedRepository) InvalidateUnit(unitID int) int {
+	invalidated := 0
+	if r.byID.Delete(unitID) {
+		invalidated++
+	}
+
+	return invalidated + r.byRouter.DeleteFunc(func(_ int, model UnitsModel) bool {
+		return model.ID == unitID
+	})
+}
+
+// Purge Removes every cached entry
+func (r *CachedRepository) Purge() int {
+	return r.byRouter.Purge() + r.byID.Purge()
+}
+
+func (r *CachedRepository) find(ctx context.Context, lru *cache.LRU[int, UnitsModel], key int,
+	lookup func(ctx context.Context, key int) (*UnitsModel, error)) (*UnitsModel, error) {
+	if model, missing, hit := lru.Get(key); hit {
+		if missing {
+			return &UnitsModel{}, terrors.New(terrors.ErrNotFound, "Unit information not found", map[string]string{})
+		}
+		return &model, nil
+	}
+
+	model, err := lookup(ctx, key)
+	if err != nil {
+		if terrors.Is(err, terrors.ErrNotFound) {
+			lru.SetMissing(key)
+		}
+		return model, err
+	}
+
+	lru.Set(key, *model)
+
+	return model, nil
+}
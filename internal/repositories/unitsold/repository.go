@@ -9,3 +9,10 @@ type IRepository interface {
 	FindByRouterID(ctx context.Context, routerID int) (*UnitsModel, error)
 	FindByID(ctx context.Context, unitID int) (*UnitsModel, error)
 }
+
+// ICache invalidation interface for cached unit lookups
+type ICache interface {
+	InvalidateRouter(routerID int) int
+	InvalidateUnit(unitID int) int
+	Purge() int
+}
@@ -0,0 +1,285 @@
+package webhook
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/terrors"
+	"github.com/sirupsen/logrus"
+	"github.com/uptrace/bun"
+)
+
+// DatabaseRepository struct
+type DatabaseRepository struct {
+	log *logger.ContextLogger
+	db  *bun.DB
+}
+
+// NewDatabaseRepository creates an instance of DatabaseRepository
+func NewDatabaseRepository(l *logger.ContextLogger, conn *bun.DB) *DatabaseRepository {
+	return &DatabaseRepository{
+		log: l,
+		db:  conn,
+	}
+}
+
+// CreateSubscription Handles the creation of a new webhook subscription on database
+func (r *DatabaseRepository) CreateSubscription(ctx context.Context, model *SubscriptionModel) error {
+	_, err := r.db.NewInsert().
+		Model(model).
+		Exec(ctx)
+	if err != nil {
+		r.log.Error(logrus.ErrorLevel, "CreateSubscription", "Error creating webhook subscription", err)
+		return terrors.InternalService("create_webhook", "Failed to create webhook subscription in the database", map[string]string{})
+	}
+	return nil
+}
+
+// FindSubscription Handles to find a webhook subscription owned by one of the given tenants
+func (r *DatabaseRepository) FindSubscription(ctx context.Context, subscriptionID string, tenants []int) (*SubscriptionModel, error) {
+	model := &SubscriptionModel{}
+	err := r.db.NewSelect().
+		Model(model).
+		Where("id = ?", subscriptionID).
+		Where("tenant_id IN (?)", bun.In(tenants)).
+		Limit(1).
+		Scan(ctx)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, terrors.New(terrors.ErrNotFound, "Webhook subscription not found", map[string]string{})
+		}
+		r.log.Error(logrus.ErrorLevel, "FindSubscription", "Error finding webhook subscription", err)
+		return nil, terrors.InternalService("find_webhook", "Failed to retrieve webhook subscription from the database", map[string]string{})
+	}
+
+	return model, nil
+}
+
+// FindSubscriptions Handles to find the webhook subscriptions of the given tenants, newest first
+func (r *DatabaseRepository) FindSubscriptions(ctx context.Context, tenants []int) ([]SubscriptionModel, error) {
+	var models []SubscriptionModel
+	err := r.db.NewSelect().
+		Model(&models).
+		Where("tenant_id IN (?)", bun.In(tenants)).
+		Order("created_at DESC").
+		Scan(ctx)
+	if err != nil {
+		r.log.Error(logrus.ErrorLevel, "FindSubscriptions", "Error finding webhook subscriptions", err)
+		return nil, terrors.InternalService("find_webhooks", "Failed to retrieve webhook subscriptions from the database", map[string]string{})
+	}
+
+	return models, nil
+}
+
+// FindSubscriptionsByIDs Handles to find the webhook subscriptions with the given ids
+func (r *DatabaseRepository) FindSubscriptionsByIDs(ctx context.Context, subscriptionIDs []string) ([]SubscriptionModel, error) {
+	var models []SubscriptionModel
+	err := r.db.NewSelect().
+		Model(&models).
+		Where("id IN (?)", bun.In(subscriptionIDs)).
+		Scan(ctx)
+
+	return models, err
+}
+
+// FindActiveByEvent Handles to find the active subscriptions of a tenant to the given event type
+func (r *DatabaseRepository) FindActiveByEvent(ctx context.Context, tenantID int, eventType string) ([]SubscriptionModel, error) {
+	var models []SubscriptionModel
+	err := r.db.NewSelect().
+		Model(&models).
+		Where("tenant_id = ?", tenantID).
+		Where("active").
+		Where("event_types @> ARRAY[?]::text[]", eventType).
+		Scan(ctx)
+
+	return models, err
+}
+
+// UpdateSubscription Handles update the editable fields and the state of a webhook subscription
+func (r *DatabaseRepository) UpdateSubscription(ctx context.Context, model *SubscriptionModel) error {
+	_, err := r.db.NewUpdate().
+		Model(model).
+		Column("url", "event_types", "description", "active", "consecutive_failures", "disabled_at", "disabled_reason", "updated_at").
+		WherePK().
+		Exec(ctx)
+	if err != nil {
+		r.log.Error(logrus.ErrorLevel, "UpdateSubscription", "Error updating webhook subscription", err)
+		return terrors.InternalService("update_webhook", "Failed to update webhook subscription in the database", map[string]string{})
+	}
+	return nil
+}
+
+// DeleteSubscription Handles to delete a webhook subscription along with its delivery log
+func (r *DatabaseRepository) DeleteSubscription(ctx context.Context, subscriptionID string) error {
+	_, err := r.db.NewDelete().
+		Model((*SubscriptionModel)(nil)).
+		Where("id = ?", subscriptionID).
+		Exec(ctx)
+	if err != nil {
+		r.log.Error(logrus.ErrorLevel, "DeleteSubscription", "Error deleting webhook subscription", err)
+		return terrors.InternalService("delete_webhook", "Failed to delete webhook subscription in the database", map[string]string{})
+	}
+	return nil
+}
+
+// RecordSuccess Handles reset the consecutive failures of a subscription
+func (r *DatabaseRepository) RecordSuccess(ctx context.Context, subscriptionID string) error {
+	_, err := r.db.NewUpdate().
+		Model((*SubscriptionModel)(nil)).
+		Set("consecutive_failures = 0").
+		Where("id = ?", subscriptionID).
+		Where("consecutive_failures > 0").
+		Exec(ctx)
+	return err
+}
+
+// RecordFailure Handles increase the consecutive failures of an active subscription and disables it
+// once they reach disableAfter, it reports whether this failure disabled the subscription
+func (r *DatabaseRepository) RecordFailure(ctx context.Context, subscriptionID string, disableAfter int, reason string) (bool, error) {
+	now := time.Now().UTC()
+
+	var active bool
+	err := r.db.NewUpdate().
+		Model((*SubscriptionModel)(nil)).
+		Set("consecutive_failures = consecutive_failures + 1").
+		Set("active = consecutive_failures + 1 < ?", disableAfter).
+		Set("disabled_at = CASE WHEN consecutive_failures + 1 < ? THEN disabled_at ELSE ? END", disableAfter, now).
+		Set("disabled_reason = CASE WHEN consecutive_failures + 1 < ? THEN disabled_reason ELSE ? END", disableAfter, reason).
+		Set("updated_at = ?", now).
+		Where("id = ?", subscriptionID).
+		Where("active").
+		Returning("active").
+		Scan(ctx, &active)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, err
+	}
+
+	return !active, nil
+}
+
+// CreateDeliveries Handles the creation of the pending deliveries of an event
+func (r *DatabaseRepository) CreateDeliveries(ctx context.Context, models []DeliveryModel) error {
+	_, err := r.db.NewInsert().
+		Model(&models).
+		Exec(ctx)
+	return err
+}
+
+// ClaimDue Returns up to limit pending deliveries whose next attempt is due and pushes their next attempt
+// by the lease, so a delivery is retried after the lease if the worker stops before recording the outcome.
+// Rows locked by other workers are skipped so several instances can share the queue.
+func (r *DatabaseRepository) ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]DeliveryModel, error) {
+	var models []DeliveryModel
+
+	due := r.db.NewSelect().
+		Model((*DeliveryModel)(nil)).
+		Column("id").
+		Where("status = ?", StatusPending).
+		Where("next_attempt_at <= ?", now).
+		Order("next_attempt_at ASC").
+		Limit(limit).
+		For("UPDATE SKIP LOCKED")
+
+	err := r.db.NewUpdate().
+		Model((*DeliveryModel)(nil)).
+		Set("next_attempt_at = ?", now.Add(lease)).
+		Set("updated_at = ?", now).
+		Where("id IN (?)", due).
+		Returning("*").
+		Scan(ctx, &models)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	return models, nil
+}
+
+// MarkDelivered Handles update a delivery as delivered
+func (r *DatabaseRepository) MarkDelivered(ctx context.Context, deliveryID string, attempt Attempt) error {
+	now := time.Now().UTC()
+	_, err := r.db.NewUpdate().
+		Model((*DeliveryModel)(nil)).
+		Set("status = ?", StatusDelivered).
+		Set("attempts = ?", attempt.Attempts).
+		Set("last_status_code = ?", attempt.StatusCode).
+		Set("last_error = ''").
+		Set("delivered_at = ?", now).
+		Set("updated_at = ?", now).
+		Where("id = ?", deliveryID).
+		Exec(ctx)
+	return err
+}
+
+// MarkRetry Handles update a delivery that failed and will be attempted again
+func (r *DatabaseRepository) MarkRetry(ctx context.Context, deliveryID string, attempt Attempt) error {
+	_, err := r.db.NewUpdate().
+		Model((*DeliveryModel)(nil)).
+		Set("attempts = ?", attempt.Attempts).
+		Set("last_status_code = ?", attempt.StatusCode).
+		Set("last_error = ?", attempt.Error).
+		Set("next_attempt_at = ?", attempt.NextAttemptAt).
+		Set("updated_at = ?", time.Now().UTC()).
+		Where("id = ?", deliveryID).
+		Exec(ctx)
+	return err
+}
+
+// MarkFailed Handles update a delivery as failed, it will not be attempted again
+func (r *DatabaseRepository) MarkFailed(ctx context.Context, deliveryID string, attempt Attempt) error {
+	_, err := r.db.NewUpdate().
+		Model((*DeliveryModel)(nil)).
+		Set("status = ?", StatusFailed).
+		Set("attempts = ?", attempt.Attempts).
+		Set("last_status_code = ?", attempt.StatusCode).
+		Set("last_error = ?", attempt.Error).
+		Set("updated_at = ?", time.Now().UTC()).
+		Where("id = ?", deliveryID).
+		Exec(ctx)
+	return err
+}
+
+// RetrieveDeliveries Retrieves the delivery log by filters, newest first
+func (r *DatabaseRepository) RetrieveDeliveries(ctx context.Context, filter *DeliveryMetadata) ([]DeliveryModel, int, int, error) {
+	var models []DeliveryModel
+
+	query := r.db.NewSelect().
+		Model((*DeliveryModel)(nil)).
+		Where("subscription_id = ?", filter.SubscriptionID)
+	if filter.Status != "" {
+		query = query.Where("status = ?", filter.Status)
+	}
+	if filter.EventType != "" {
+		query = query.Where("event_type = ?", filter.EventType)
+	}
+
+	pages, total := 0, 0
+	if filter.Filter.ShouldCount() {
+		var err error
+		total, err = query.Count(ctx)
+		if err != nil {
+			r.log.Error(logrus.ErrorLevel, "RetrieveDeliveries", "Error counting webhook deliveries", err)
+			return nil, 0, 0, terrors.InternalService("count_error", "Error counting records in the database", map[string]string{})
+		}
+		pages = int(math.Ceil(float64(total) / float64(filter.Filter.Size)))
+	}
+
+	err := query.
+		OrderExpr("created_at DESC").
+		OrderExpr("id DESC").
+		Limit(filter.Filter.Size).
+		Offset((filter.Filter.Page-1)*filter.Filter.Size).
+		Scan(ctx, &models)
+	if err != nil {
+		r.log.Error(logrus.ErrorLevel, "RetrieveDeliveries", "Error scanning webhook deliveries", err)
+		return nil, 0, 0, terrors.InternalService("retrieve_webhook_deliveries", "Error retrieving webhook deliveries from the database", map[string]string{})
+	}
+
+	return models, pages, total, nil
+}
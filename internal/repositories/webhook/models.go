@@ -0,0 +1,70 @@
+package webhook
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/jmontesinos91/collector/domains/pagination"
+	"github.com/uptrace/bun"
+)
+
+// Delivery statuses
+const (
+	StatusPending   = "pending"
+	StatusDelivered = "delivered"
+	StatusFailed    = "failed"
+)
+
+// SubscriptionModel Database model for the webhook endpoints registered by the tenants
+type SubscriptionModel struct {
+	bun.BaseModel `bun:"table:webhook_subscriptions"`
+
+	ID                  string     `bun:"id,pk"`
+	TenantID            int        `bun:"tenant_id"`
+	URL                 string     `bun:"url"`
+	Secret              string     `bun:"secret"`
+	EventTypes          []string   `bun:"event_types,array"`
+	Description         string     `bun:"description"`
+	Active              bool       `bun:"active"`
+	ConsecutiveFailures int        `bun:"consecutive_failures"`
+	DisabledAt          *time.Time `bun:"disabled_at"`
+	DisabledReason      string     `bun:"disabled_reason"`
+	CreatedBy           int        `bun:"created_by"`
+	CreatedAt           time.Time  `bun:"created_at"`
+	UpdatedAt           time.Time  `bun:"updated_at"`
+}
+
+// DeliveryModel Database model for the delivery log, one row per event and subscription
+type DeliveryModel struct {
+	bun.BaseModel `bun:"table:webhook_deliveries"`
+
+	ID             string          `bun:"id,pk"`
+	SubscriptionID string          `bun:"subscription_id"`
+	EventID        string          `bun:"event_id"`
+	EventType      string          `bun:"event_type"`
+	Payload        json.RawMessage `bun:"payload,type:jsonb"`
+	Status         string          `bun:"status"`
+	Attempts       int             `bun:"attempts"`
+	NextAttemptAt  time.Time       `bun:"next_attempt_at"`
+	LastStatusCode int             `bun:"last_status_code"`
+	LastError      string          `bun:"last_error"`
+	CreatedAt      time.Time       `bun:"created_at"`
+	UpdatedAt      time.Time       `bun:"updated_at"`
+	DeliveredAt    *time.Time      `bun:"delivered_at"`
+}
+
+// Attempt outcome of a delivery attempt
+type Attempt struct {
+	Attempts      int
+	StatusCode    int
+	Error         string
+	NextAttemptAt time.Time
+}
+
+// DeliveryMetadata struct filter for the delivery log
+type DeliveryMetadata struct {
+	SubscriptionID string
+	Status         string
+	EventType      string
+	Filter         pagination.Filter
+}
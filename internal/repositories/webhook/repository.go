@@ -0,0 +1,25 @@
+package webhook
+
+import (
+	"context"
+	"time"
+)
+
+// IRepository interface
+type IRepository interface {
+	CreateSubscription(ctx context.Context, model *SubscriptionModel) error
+	FindSubscription(ctx context.Context, subscriptionID string, tenants []int) (*SubscriptionModel, error)
+	FindSubscriptions(ctx context.Context, tenants []int) ([]SubscriptionModel, error)
+	FindSubscriptionsByIDs(ctx context.Context, subscriptionIDs []string) ([]SubscriptionModel, error)
+	FindActiveByEvent(ctx context.Context, tenantID int, eventType string) ([]SubscriptionModel, error)
+	UpdateSubscription(ctx context.Context, model *SubscriptionModel) error
+	DeleteSubscription(ctx context.Context, subscriptionID string) error
+	RecordSuccess(ctx context.Context, subscriptionID string) error
+	RecordFailure(ctx context.Context, subscriptionID string, disableAfter int, reason string) (bool, error)
+	CreateDeliveries(ctx context.Context, models []DeliveryModel) error
+	ClaimDue(ctx context.Context, now time.Time, lease time.Duration, limit int) ([]DeliveryModel, error)
+	MarkDelivered(ctx context.Context, deliveryID string, attempt Attempt) error
+	MarkRetry(ctx context.Context, deliveryID string, attempt Attempt) error
+	MarkFailed(ctx context.Context, deliveryID string, attempt Attempt) error
+	RetrieveDeliveries(ctx context.Context, filter *DeliveryMetadata) ([]DeliveryModel, int, int, error)
+}
@@ -0,0 +1,16 @@
+package ack
+
+// Placeholders of the success and failure templates of a text profile
+const (
+	// PlaceholderSeq sequence number the device sent in the seq query param
+	PlaceholderSeq = "{seq}"
+	// PlaceholderDevice IMEI or unit id of the frame
+	PlaceholderDevice = "{device}"
+)
+
+const (
+	// DefaultSuccessStatus http status of the successful acks
+	DefaultSuccessStatus = 200
+	// ContentTypeText content type of the text acks
+	ContentTypeText = "text/plain; charset=utf-8"
+)
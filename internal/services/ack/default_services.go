@@ -0,0 +1,76 @@
+package ack
+
+import (
+	"cmp"
+	"slices"
+	"strings"
+
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultService struct
+type DefaultService struct {
+	log      *logger.ContextLogger
+	fallback Encoder
+	devices  map[string]Encoder
+	prefixes []prefix
+}
+
+// NewDefaultService creates a new instance of DefaultService, the devices and prefixes of unknown
+// profiles are ignored
+func NewDefaultService(l *logger.ContextLogger, opts Opts) *DefaultService {
+	encoders := make(map[string]Encoder, len(opts.Profiles))
+	for name, profile := range opts.Profiles {
+		encoders[name] = NewEncoder(profile)
+	}
+
+	s := &DefaultService{
+		log:      l,
+		fallback: NewEncoder(opts.Default),
+		devices:  make(map[string]Encoder, len(opts.Devices)),
+		prefixes: make([]prefix, 0, len(opts.Prefixes)),
+	}
+
+	for device, name := range opts.Devices {
+		encoder, ok := encoders[name]
+		if !ok {
+			l.Log(logrus.WarnLevel, "NewDefaultService", "Ack profile ignored, unknown profile "+name+" of device "+device)
+			continue
+		}
+		s.devices[device] = encoder
+	}
+
+	for p, name := range opts.Prefixes {
+		encoder, ok := encoders[name]
+		if !ok {
+			l.Log(logrus.WarnLevel, "NewDefaultService", "Ack profile ignored, unknown profile "+name+" of prefix "+p)
+			continue
+		}
+		s.prefixes = append(s.prefixes, prefix{prefix: p, encoder: encoder})
+	}
+
+	// The longest prefix is the most specific one
+	slices.SortFunc(s.prefixes, func(a, b prefix) int {
+		return cmp.Or(cmp.Compare(len(b.prefix), len(a.prefix)), strings.Compare(a.prefix, b.prefix))
+	})
+
+	return s
+}
+
+// Resolve returns the encoder of the profile of a device
+func (s *DefaultService) Resolve(device string) Encoder {
+	if encoder, ok := s.devices[device]; ok {
+		return encoder
+	}
+
+	if device != "" {
+		for _, p := range s.prefixes {
+			if strings.HasPrefix(device, p.prefix) {
+				return p.encoder
+			}
+		}
+	}
+
+	return s.fallback
+}
@@ -0,0 +1,143 @@
+package ack_test
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/jmontesinos91/collector/internal/services/ack"
+	"github.com/jmontesinos91/collector/internal/services/command"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	plain = ack.Profile{Format: command.FormatText, FailureStatus: http.StatusOK, Success: "ACK,{seq}", Failure: "NAK,{seq}"}
+	digit = ack.Profile{Format: command.FormatText, Success: "1", Failure: "0"}
+
+	standardFailure = ack.Response{
+		Status:      http.StatusBadRequest,
+		ContentType: "application/json",
+		Body:        []byte(`{"code":"bad_request","message":"Invalid Request String"}`),
+	}
+)
+
+func TestResolve(t *testing.T) {
+	log := logger.NewContextLogger("Resolve", "debug", logger.TextFormat)
+	service := ack.NewDefaultService(log, ack.Opts{
+		Default:  ack.Profile{Format: command.FormatJSON},
+		Profiles: map[string]ack.Profile{"plain": plain, "digit": digit},
+		Devices:  map[string]string{"860000000000001": "digit", "1234": "plain", "860000000000002": "unknown"},
+		Prefixes: map[string]string{"8600": "digit", "86000000": "plain", "35": "unknown"},
+	})
+
+	tests := []struct {
+		name     string
+		device   string
+		expected ack.Encoder
+	}{
+		{name: "Device profile overrides its prefix", device: "860000000000001", expected: ack.NewEncoder(digit)},
+		{name: "Unit id profile", device: "1234", expected: ack.NewEncoder(plain)},
+		{name: "Longest prefix wins", device: "860000000000003", expected: ack.NewEncoder(plain)},
+		{name: "Unknown device profile falls back to its prefix", device: "860000000000002", expected: ack.NewEncoder(plain)},
+		{name: "Shorter prefix", device: "860012345678901", expected: ack.NewEncoder(digit)},
+		{name: "Unknown prefix profile is ignored", device: "350000000000001", expected: ack.NewEncoder(ack.Profile{Format: command.FormatJSON})},
+		{name: "Empty device gets the default", device: "", expected: ack.NewEncoder(ack.Profile{Format: command.FormatJSON})},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, service.Resolve(tt.device))
+		})
+	}
+}
+
+func TestEncoders(t *testing.T) {
+	commands := []command.Command{
+		{ID: "a", Type: command.TypeReboot},
+		{ID: "b", Type: command.TypeSetReportingInterval, Params: command.Params{Seconds: 60}},
+	}
+	frame := ack.Ack{Device: "860000000000001", Seq: "42"}
+	withCommands := ack.Ack{Device: "860000000000001", Seq: "42", Commands: commands}
+
+	tests := []struct {
+		name     string
+		profile  ack.Profile
+		ack      ack.Ack
+		failure  bool
+		expected ack.Response
+	}{
+		{
+			name:     "JSON success without commands",
+			profile:  ack.Profile{},
+			ack:      frame,
+			expected: ack.Response{Status: http.StatusOK, ContentType: "application/json", Body: []byte("null")},
+		},
+		{
+			name:    "JSON success with commands",
+			profile: ack.Profile{Format: command.FormatJSON, SuccessStatus: http.StatusAccepted},
+			ack:     withCommands,
+			expected: ack.Response{Status: http.StatusAccepted, ContentType: "application/json",
+				Body: []byte(`{"commands":[{"id":"a","type":"reboot","params":{}},{"id":"b","type":"set-reporting-interval","params":{"seconds":60}}]}`)},
+		},
+		{
+			name:     "JSON failure keeps the standard error",
+			profile:  ack.Profile{},
+			ack:      frame,
+			failure:  true,
+			expected: standardFailure,
+		},
+		{
+			name:     "JSON failure with the status of the profile",
+			profile:  ack.Profile{FailureStatus: http.StatusOK},
+			ack:      frame,
+			failure:  true,
+			expected: ack.Response{Status: http.StatusOK, ContentType: standardFailure.ContentType, Body: standardFailure.Body},
+		},
+		{
+			name:     "Text success",
+			profile:  plain,
+			ack:      frame,
+			expected: ack.Response{Status: http.StatusOK, ContentType: ack.ContentTypeText, Body: []byte("ACK,42")},
+		},
+		{
+			name:     "Text success with commands",
+			profile:  plain,
+			ack:      withCommands,
+			expected: ack.Response{Status: http.StatusOK, ContentType: ack.ContentTypeText, Body: []byte("ACK,42\nCMD,a,RBT\nCMD,b,INT,60")},
+		},
+		{
+			name:     "Text success without template",
+			profile:  ack.Profile{Format: command.FormatText},
+			ack:      frame,
+			expected: ack.Response{Status: http.StatusOK, ContentType: ack.ContentTypeText, Body: []byte("")},
+		},
+		{
+			name:     "Text failure with the status of the profile",
+			profile:  plain,
+			ack:      frame,
+			failure:  true,
+			expected: ack.Response{Status: http.StatusOK, ContentType: ack.ContentTypeText, Body: []byte("NAK,42")},
+		},
+		{
+			name:     "Text failure with the status of the error",
+			profile:  digit,
+			ack:      frame,
+			failure:  true,
+			expected: ack.Response{Status: http.StatusBadRequest, ContentType: ack.ContentTypeText, Body: []byte("0")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoder := ack.NewEncoder(tt.profile)
+			if tt.failure {
+				assert.Equal(t, tt.expected, encoder.Failure(tt.ack, standardFailure))
+				return
+			}
+
+			res, err := encoder.Success(tt.ack)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, res)
+		})
+	}
+}
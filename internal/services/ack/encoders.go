@@ -0,0 +1,80 @@
+package ack
+
+import (
+	"strings"
+
+	"github.com/jmontesinos91/collector/internal/services/command"
+)
+
+// NewEncoder returns the encoder of a profile, the profiles with an unknown format answer as json
+func NewEncoder(profile Profile) Encoder {
+	profile = Normalize(profile)
+	if profile.Format == command.FormatText {
+		return TextEncoder{profile: profile, commands: command.NewEncoder(command.FormatText)}
+	}
+	return JSONEncoder{profile: profile, commands: command.NewEncoder(command.FormatJSON)}
+}
+
+// JSONEncoder answers with null or the queued commands on success and the standard error response
+// on failure
+type JSONEncoder struct {
+	profile  Profile
+	commands command.Encoder
+}
+
+// Success writes the queued commands, null when there are none
+func (e JSONEncoder) Success(ack Ack) (Response, error) {
+	if len(ack.Commands) == 0 {
+		return Response{Status: e.profile.SuccessStatus, ContentType: "application/json", Body: []byte("null")}, nil
+	}
+
+	body, err := e.commands.Encode(ack.Commands)
+	if err != nil {
+		return Response{}, err
+	}
+
+	return Response{Status: e.profile.SuccessStatus, ContentType: e.commands.ContentType(), Body: body}, nil
+}
+
+// Failure keeps the standard error response, with the failure status of the profile when it has one
+func (e JSONEncoder) Failure(_ Ack, failure Response) Response {
+	if e.profile.FailureStatus != 0 {
+		failure.Status = e.profile.FailureStatus
+	}
+	return failure
+}
+
+// TextEncoder answers with the success template followed by a line per queued command, or with
+// the failure template
+type TextEncoder struct {
+	profile  Profile
+	commands command.Encoder
+}
+
+// Success writes the success template and the queued commands
+func (e TextEncoder) Success(ack Ack) (Response, error) {
+	var lines []string
+	if e.profile.Success != "" {
+		lines = append(lines, Render(e.profile.Success, ack))
+	}
+
+	if len(ack.Commands) > 0 {
+		body, err := e.commands.Encode(ack.Commands)
+		if err != nil {
+			return Response{}, err
+		}
+		lines = append(lines, string(body))
+	}
+
+	return Response{Status: e.profile.SuccessStatus, ContentType: ContentTypeText, Body: []byte(strings.Join(lines, "\n"))}, nil
+}
+
+// Failure writes the failure template with the failure status of the profile, or the status of the error
+func (e TextEncoder) Failure(ack Ack, failure Response) Response {
+	status := failure.Status
+	if e.profile.FailureStatus != 0 {
+		status = e.profile.FailureStatus
+	}
+
+	return Response{Status: status, ContentType: ContentTypeText, Body: []byte(Render(e.profile.Failure, ack))}
+}
@@ -0,0 +1,26 @@
+package ack
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Normalize drops the statuses that are not valid http statuses, the successful acks default to 200
+func Normalize(profile Profile) Profile {
+	if !validStatus(profile.SuccessStatus) {
+		profile.SuccessStatus = DefaultSuccessStatus
+	}
+	if !validStatus(profile.FailureStatus) {
+		profile.FailureStatus = 0
+	}
+	return profile
+}
+
+// Render replaces the placeholders of a template with the values of the ack
+func Render(template string, ack Ack) string {
+	return strings.NewReplacer(PlaceholderSeq, ack.Seq, PlaceholderDevice, ack.Device).Replace(template)
+}
+
+func validStatus(status int) bool {
+	return status >= http.StatusContinue && status <= 599
+}
@@ -0,0 +1,46 @@
+package ack
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		profile  Profile
+		expected Profile
+	}{
+		{
+			name:     "Success status defaults to 200",
+			profile:  Profile{Format: "text"},
+			expected: Profile{Format: "text", SuccessStatus: 200},
+		},
+		{
+			name:     "Valid statuses are kept",
+			profile:  Profile{SuccessStatus: 202, FailureStatus: 200},
+			expected: Profile{SuccessStatus: 202, FailureStatus: 200},
+		},
+		{
+			name:     "Invalid statuses are dropped",
+			profile:  Profile{SuccessStatus: 2000, FailureStatus: -1},
+			expected: Profile{SuccessStatus: 200},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Normalize(tt.profile))
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	ack := Ack{Device: "860000000000001", Seq: "42"}
+
+	assert.Equal(t, "ACK,42", Render("ACK,{seq}", ack))
+	assert.Equal(t, "860000000000001;42;860000000000001", Render("{device};{seq};{device}", ack))
+	assert.Equal(t, "1", Render("1", ack))
+	assert.Equal(t, "ACK,", Render("ACK,{seq}", Ack{}))
+}
@@ -0,0 +1,51 @@
+package ack
+
+import (
+	"github.com/jmontesinos91/collector/internal/services/command"
+)
+
+// Profile answer a device model or firmware expects to its frames. The json format keeps the
+// standard answers, the text one writes the success or failure template followed by the queued
+// commands, e.g. ACK,{seq} and NAK,{seq} or a status digit
+type Profile struct {
+	// Format json or text, it is also the wire format of the queued commands
+	Format string
+	// SuccessStatus http status of the successful acks, 200 when it is zero
+	SuccessStatus int
+	// FailureStatus http status of the failed acks, the status of the error when it is zero
+	FailureStatus int
+	Success       string
+	Failure       string
+}
+
+// Opts ack profiles options, the profile of a device overrides the one of the longest prefix of
+// its IMEI which overrides the default one. The first 8 digits of an IMEI are the type allocation
+// code of the device model
+type Opts struct {
+	Default  Profile
+	Profiles map[string]Profile
+	// Devices profile names keyed by IMEI or unit id
+	Devices map[string]string
+	// Prefixes profile names keyed by IMEI prefix
+	Prefixes map[string]string
+}
+
+// Ack answer to a device frame
+type Ack struct {
+	Device   string
+	Seq      string
+	Commands []command.Command
+}
+
+// Response status and body written to the device
+type Response struct {
+	Status      int
+	ContentType string
+	Body        []byte
+}
+
+// prefix profile of the devices whose IMEI starts with the prefix
+type prefix struct {
+	prefix  string
+	encoder Encoder
+}
@@ -0,0 +1,13 @@
+package ack
+
+// IService Resolves the ack encoder of the devices
+type IService interface {
+	Resolve(device string) Encoder
+}
+
+// Encoder writes the answers to the frames of a device profile. Failure receives the standard error
+// response, which the encoder keeps or replaces with the one its devices expect
+type Encoder interface {
+	Success(ack Ack) (Response, error)
+	Failure(ack Ack, failure Response) Response
+}
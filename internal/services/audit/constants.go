@@ -0,0 +1,21 @@
+package audit
+
+// Audited resources
+const (
+	ResourceTraffic   = "traffic"
+	ResourceExportJob = "export-job"
+	ResourceCache     = "device-cache"
+	ResourceWebhook   = "webhook"
+	ResourceCommand   = "device-command"
+)
+
+// Audited actions, bulk actions are suffixed with the operation, e.g. bulk-reset-counter
+const (
+	ActionCreate       = "create"
+	ActionUpdate       = "update"
+	ActionDelete       = "delete"
+	ActionRestore      = "restore"
+	ActionResetCounter = "reset-counter"
+	ActionBulk         = "bulk"
+	ActionInvalidate   = "invalidate"
+)
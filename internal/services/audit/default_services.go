@@ -0,0 +1,101 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/jmontesinos91/collector/domains/pagination"
+	oaudit "github.com/jmontesinos91/collector/internal/repositories/audit"
+	"github.com/jmontesinos91/ologs/logger"
+	tracekey "github.com/jmontesinos91/ologs/logger/v2"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultService struct
+type DefaultService struct {
+	log       *logger.ContextLogger
+	auditRepo oaudit.IRepository
+}
+
+// NewDefaultService creates a new instance of DefaultService
+func NewDefaultService(l *logger.ContextLogger, ar oaudit.IRepository) *DefaultService {
+	return &DefaultService{
+		log:       l,
+		auditRepo: ar,
+	}
+}
+
+// Record stores the event in the audit log. The action already happened when it is recorded,
+// so failures are logged instead of returned and the entry is stored even if the caller went away
+func (s *DefaultService) Record(ctx context.Context, event Event) {
+	requestID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	claims, _ := ctx.Value(&sts.Claim).(sts.Claims)
+	logCtx := logger.Context{
+		tracekey.TrackingID: requestID,
+		tracekey.UserID:     claims.UserID,
+		tracekey.Role:       claims.Role,
+		"Action":            event.Action,
+		"Resource":          event.Resource,
+		"TargetID":          event.TargetID,
+	}
+
+	model := &oaudit.Model{
+		ID:        uuid.NewString(),
+		ActorID:   claims.UserID,
+		Role:      claims.Role,
+		Action:    event.Action,
+		Resource:  event.Resource,
+		TargetID:  event.TargetID,
+		Before:    s.marshal(event.Before, logCtx),
+		After:     s.marshal(event.After, logCtx),
+		RequestID: requestID,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	if err := s.auditRepo.Create(context.WithoutCancel(ctx), model); err != nil {
+		s.log.WithContext(logrus.ErrorLevel, "Record", "Failed to record audit entry", logCtx, err)
+	}
+}
+
+// HandleRetrieve retrieves a page of audit entries
+func (s *DefaultService) HandleRetrieve(ctx context.Context, filter *FilterRequest) (pagination.PaginatedRes, error) {
+	requestID := ctx.Value(middleware.RequestIDKey).(string)
+	claims := ctx.Value(&sts.Claim).(sts.Claims)
+
+	_ = filter.Filter.SanitizePageFilter()
+	models, pages, total, err := s.auditRepo.Retrieve(ctx, ToMetadata(filter))
+	if err != nil {
+		s.log.WithContext(logrus.ErrorLevel,
+			"HandleRetrieve",
+			"Failed to retrieve audit entries",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+				tracekey.UserID:     claims.UserID,
+				tracekey.Role:       claims.Role,
+			},
+			err)
+		return pagination.PaginatedRes{}, err
+	}
+
+	return ToPaginatedResponse(ToEntrySlice(models), filter.Filter.Page, pages, total), nil
+}
+
+func (s *DefaultService) marshal(value interface{}, logCtx logger.Context) json.RawMessage {
+	if value == nil {
+		return nil
+	}
+
+	data, err := json.Marshal(value)
+	if err != nil {
+		s.log.WithContext(logrus.ErrorLevel, "Record", "Failed to encode audit state", logCtx, err)
+		return nil
+	}
+	if string(data) == "null" {
+		return nil
+	}
+	return data
+}
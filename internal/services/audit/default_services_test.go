@@ -0,0 +1,199 @@
+package audit_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jmontesinos91/collector/domains/pagination"
+	oaudit "github.com/jmontesinos91/collector/internal/repositories/audit"
+	"github.com/jmontesinos91/collector/internal/repositories/audit/auditmocks"
+	"github.com/jmontesinos91/collector/internal/services/audit"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/jmontesinos91/terrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func testContext() context.Context {
+	ctx := context.Background()
+	ctx = context.WithValue(ctx, middleware.RequestIDKey, "unit-test-request-id")
+	ctx = context.WithValue(ctx, &sts.Claim, sts.Claims{
+		UserID: 7,
+		Role:   "unit-test-role",
+	})
+	return ctx
+}
+
+func TestRecord(t *testing.T) {
+	log := logger.NewContextLogger("Record", "debug", logger.TextFormat)
+
+	cases := []struct { //nolint:wsl
+		name     string
+		ctx      context.Context
+		event    audit.Event
+		repoFunc func() *auditmocks.IRepository
+		asserts  func(*testing.T, *auditmocks.IRepository) bool
+	}{
+		{
+			name: "Happy path the actor and request come from the context",
+			ctx:  testContext(),
+			event: audit.Event{
+				Action:   audit.ActionResetCounter,
+				Resource: audit.ResourceTraffic,
+				TargetID: "unit-test-traffic-id",
+				Before:   map[string]int{"counter": 12},
+				After:    map[string]int{"counter": 0},
+			},
+			repoFunc: func() *auditmocks.IRepository {
+				repositoryMock := &auditmocks.IRepository{}
+				repositoryMock.On("Create", mock.Anything, mock.MatchedBy(func(m *oaudit.Model) bool {
+					return m.ID != "" &&
+						m.ActorID == 7 &&
+						m.Role == "unit-test-role" &&
+						m.RequestID == "unit-test-request-id" &&
+						m.Action == audit.ActionResetCounter &&
+						m.TargetID == "unit-test-traffic-id" &&
+						string(m.Before) == `{"counter":12}` &&
+						string(m.After) == `{"counter":0}` &&
+						!m.CreatedAt.IsZero()
+				})).Return(nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, repo *auditmocks.IRepository) bool {
+				return repo.AssertExpectations(t)
+			},
+		},
+		{
+			name: "Missing states are stored as null",
+			ctx:  testContext(),
+			event: audit.Event{
+				Action:   audit.ActionDelete,
+				Resource: audit.ResourceTraffic,
+				TargetID: "unit-test-traffic-id",
+				Before:   (*struct{})(nil),
+			},
+			repoFunc: func() *auditmocks.IRepository {
+				repositoryMock := &auditmocks.IRepository{}
+				repositoryMock.On("Create", mock.Anything, mock.MatchedBy(func(m *oaudit.Model) bool {
+					return m.Before == nil && m.After == nil
+				})).Return(nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, repo *auditmocks.IRepository) bool {
+				return repo.AssertExpectations(t)
+			},
+		},
+		{
+			name: "Entry is stored even when the request was cancelled",
+			ctx: func() context.Context {
+				ctx, cancel := context.WithCancel(testContext())
+				cancel()
+				return ctx
+			}(),
+			event: audit.Event{Action: audit.ActionCreate, Resource: audit.ResourceExportJob},
+			repoFunc: func() *auditmocks.IRepository {
+				repositoryMock := &auditmocks.IRepository{}
+				repositoryMock.On("Create", mock.MatchedBy(func(ctx context.Context) bool {
+					return ctx.Err() == nil
+				}), mock.Anything).Return(nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, repo *auditmocks.IRepository) bool {
+				return repo.AssertExpectations(t)
+			},
+		},
+		{
+			name:  "Error on create is not propagated",
+			ctx:   testContext(),
+			event: audit.Event{Action: audit.ActionInvalidate, Resource: audit.ResourceCache},
+			repoFunc: func() *auditmocks.IRepository {
+				repositoryMock := &auditmocks.IRepository{}
+				repositoryMock.On("Create", mock.Anything, mock.Anything).
+					Return(terrors.InternalService("create_audit", "Failed to create audit entry in the database", map[string]string{}))
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, repo *auditmocks.IRepository) bool {
+				return repo.AssertExpectations(t)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := tc.repoFunc()
+			service := audit.NewDefaultService(log, repo)
+
+			service.Record(tc.ctx, tc.event)
+
+			if !tc.asserts(t, repo) {
+				t.Errorf("Assert error on test = '%v'", tc.name)
+			}
+		})
+	}
+}
+
+func TestHandleRetrieve(t *testing.T) {
+	log := logger.NewContextLogger("HandleRetrieve", "debug", logger.TextFormat)
+
+	cases := []struct { //nolint:wsl
+		name     string
+		filter   *audit.FilterRequest
+		repoFunc func() *auditmocks.IRepository
+		err      bool
+		asserts  func(*testing.T, pagination.PaginatedRes, *auditmocks.IRepository) bool
+	}{
+		{
+			name:   "Happy path the page is sanitized",
+			filter: &audit.FilterRequest{Action: audit.ActionDelete},
+			repoFunc: func() *auditmocks.IRepository {
+				repositoryMock := &auditmocks.IRepository{}
+				repositoryMock.On("Retrieve", mock.Anything, mock.MatchedBy(func(m *oaudit.Metadata) bool {
+					return m.Action == audit.ActionDelete &&
+						m.Filter.Page == 1 &&
+						m.Filter.Size == pagination.DefaultSizeValue
+				})).Return([]oaudit.Model{{ID: "1", Action: audit.ActionDelete}}, 1, 1, nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, res pagination.PaginatedRes, repo *auditmocks.IRepository) bool {
+				entries, ok := res.Data.([]audit.Entry)
+				return assert.True(t, ok) &&
+					assert.Len(t, entries, 1) &&
+					assert.Equal(t, 1, res.CurrentPage) &&
+					assert.Equal(t, 1, res.Total) &&
+					repo.AssertExpectations(t)
+			},
+		},
+		{
+			name:   "Error on retrieve",
+			filter: &audit.FilterRequest{},
+			repoFunc: func() *auditmocks.IRepository {
+				repositoryMock := &auditmocks.IRepository{}
+				repositoryMock.On("Retrieve", mock.Anything, mock.Anything).
+					Return(nil, 0, 0, terrors.InternalService("retrieve_audit", "Error retrieving audit entries from the database", map[string]string{}))
+				return repositoryMock
+			},
+			err: true,
+			asserts: func(t *testing.T, res pagination.PaginatedRes, repo *auditmocks.IRepository) bool {
+				return assert.Nil(t, res.Data) && repo.AssertExpectations(t)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := tc.repoFunc()
+			service := audit.NewDefaultService(log, repo)
+
+			res, err := service.HandleRetrieve(testContext(), tc.filter)
+			if (err != nil) != tc.err {
+				t.Errorf("DefaultService.HandleRetrieve() error = %v, wantErr %v", err, tc.err)
+			}
+
+			if !tc.asserts(t, res, repo) {
+				t.Errorf("Assert error on test = '%v'", tc.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package audit
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/jmontesinos91/collector/domains/pagination"
+	oaudit "github.com/jmontesinos91/collector/internal/repositories/audit"
+	"github.com/jmontesinos91/terrors"
+)
+
+// ParseFilterRequest builds a single filter object given http params, entries are listed
+// newest first unless sortDesc=false
+func ParseFilterRequest(r *http.Request) (*FilterRequest, error) {
+	query := r.URL.Query()
+	fr := FilterRequest{
+		Role:      query.Get("role"),
+		Action:    query.Get("action"),
+		Resource:  query.Get("resource"),
+		TargetID:  query.Get("targetId"),
+		RequestID: query.Get("requestId"),
+		Filter:    pagination.Filter{SortDesc: true},
+	}
+
+	if actorStr := query.Get("actorId"); actorStr != "" {
+		actorID, err := strconv.Atoi(actorStr)
+		if err != nil {
+			return nil, terrors.New(terrors.ErrBadRequest, "Invalid actorId parameter", map[string]string{})
+		}
+		fr.ActorID = &actorID
+	}
+
+	from, err := parseTimeParam(query, "from")
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseTimeParam(query, "to")
+	if err != nil {
+		return nil, err
+	}
+	fr.From, fr.To = from, to
+	if fr.From != nil && fr.To != nil && fr.From.After(*fr.To) {
+		return nil, terrors.New(terrors.ErrBadRequest, "Invalid from parameter, it must not be after to", map[string]string{})
+	}
+
+	if sortDesc := query.Get("sortDesc"); sortDesc != "" {
+		desc, err := strconv.ParseBool(sortDesc)
+		if err != nil {
+			return nil, terrors.New(terrors.ErrBadRequest, "Invalid sortDesc parameter", map[string]string{})
+		}
+		fr.Filter.SortDesc = desc
+	}
+
+	if sizeStr := query.Get("size"); sizeStr != "" {
+		size, err := strconv.Atoi(sizeStr)
+		if err != nil {
+			return nil, terrors.New(terrors.ErrBadRequest, "Invalid size parameter", map[string]string{})
+		}
+		fr.Filter.Size = size
+	}
+
+	if pageStr := query.Get("page"); pageStr != "" {
+		page, err := strconv.Atoi(pageStr)
+		if err != nil {
+			return nil, terrors.New(terrors.ErrBadRequest, "Invalid page parameter", map[string]string{})
+		}
+		fr.Filter.Page = page
+	}
+
+	if countStr := query.Get("count"); countStr != "" {
+		count, err := strconv.ParseBool(countStr)
+		if err != nil {
+			return nil, terrors.New(terrors.ErrBadRequest, "Invalid count parameter", map[string]string{})
+		}
+		fr.Filter.Count = &count
+	}
+
+	return &fr, nil
+}
+
+func parseTimeParam(query url.Values, param string) (*time.Time, error) {
+	value := query.Get(param)
+	if value == "" {
+		return nil, nil
+	}
+
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, terrors.New(terrors.ErrBadRequest, "Invalid "+param+" parameter", map[string]string{})
+	}
+	return &parsed, nil
+}
+
+// ToMetadata maps the properties of the service filter into repo filter
+func ToMetadata(filterRequest *FilterRequest) *oaudit.Metadata {
+	return &oaudit.Metadata{
+		ActorID:   filterRequest.ActorID,
+		Role:      filterRequest.Role,
+		Action:    filterRequest.Action,
+		Resource:  filterRequest.Resource,
+		TargetID:  filterRequest.TargetID,
+		RequestID: filterRequest.RequestID,
+		From:      filterRequest.From,
+		To:        filterRequest.To,
+		Filter:    filterRequest.Filter,
+	}
+}
+
+// ToEntrySlice converts an audit model slice into a serializable slice
+func ToEntrySlice(models []oaudit.Model) []Entry {
+	entries := make([]Entry, 0, len(models))
+	for _, model := range models {
+		entries = append(entries, ToEntry(model))
+	}
+
+	return entries
+}
+
+// ToEntry converts a model to an Entry struct to be serialized
+func ToEntry(model oaudit.Model) Entry {
+	return Entry{
+		ID:        model.ID,
+		ActorID:   model.ActorID,
+		Role:      model.Role,
+		Action:    model.Action,
+		Resource:  model.Resource,
+		TargetID:  model.TargetID,
+		Before:    model.Before,
+		After:     model.After,
+		RequestID: model.RequestID,
+		CreatedAt: model.CreatedAt,
+	}
+}
+
+// ToPaginatedResponse creates a paginated response
+func ToPaginatedResponse(data interface{}, currentPage, pages, total int) pagination.PaginatedRes {
+	return pagination.PaginatedRes{
+		Data:        data,
+		CurrentPage: currentPage,
+		Pages:       pages,
+		Total:       total,
+	}
+}
@@ -0,0 +1,137 @@
+package audit
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/jmontesinos91/collector/domains/pagination"
+	oaudit "github.com/jmontesinos91/collector/internal/repositories/audit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFilterRequest(t *testing.T) {
+	actorID := 7
+	from := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 10, 2, 0, 0, 0, 0, time.UTC)
+	count := false
+	tests := []struct {
+		name        string
+		queryParams map[string]string
+		expected    *FilterRequest
+		errorMsg    string
+	}{
+		{
+			name:        "Defaults newest first",
+			queryParams: map[string]string{},
+			expected:    &FilterRequest{Filter: pagination.Filter{SortDesc: true}},
+		},
+		{
+			name: "Happy path valid parameters",
+			queryParams: map[string]string{
+				"actorId":   "7",
+				"role":      "admin",
+				"action":    "delete",
+				"resource":  "traffic",
+				"targetId":  "unit-test-traffic-id",
+				"requestId": "unit-test-request-id",
+				"from":      "2026-10-01T00:00:00Z",
+				"to":        "2026-10-02T00:00:00Z",
+				"sortDesc":  "false",
+				"page":      "2",
+				"size":      "20",
+				"count":     "false",
+			},
+			expected: &FilterRequest{
+				ActorID:   &actorID,
+				Role:      "admin",
+				Action:    "delete",
+				Resource:  "traffic",
+				TargetID:  "unit-test-traffic-id",
+				RequestID: "unit-test-request-id",
+				From:      &from,
+				To:        &to,
+				Filter:    pagination.Filter{Page: 2, Size: 20, Count: &count},
+			},
+		},
+		{
+			name:        "Invalid actorId parameter",
+			queryParams: map[string]string{"actorId": "me"},
+			errorMsg:    "Invalid actorId parameter",
+		},
+		{
+			name:        "Invalid from parameter",
+			queryParams: map[string]string{"from": "yesterday"},
+			errorMsg:    "Invalid from parameter",
+		},
+		{
+			name:        "From after to",
+			queryParams: map[string]string{"from": "2026-10-02T00:00:00Z", "to": "2026-10-01T00:00:00Z"},
+			errorMsg:    "it must not be after to",
+		},
+		{
+			name:        "Invalid sortDesc parameter",
+			queryParams: map[string]string{"sortDesc": "newest"},
+			errorMsg:    "Invalid sortDesc parameter",
+		},
+		{
+			name:        "Invalid page parameter",
+			queryParams: map[string]string{"page": "first"},
+			errorMsg:    "Invalid page parameter",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := url.Values{}
+			for key, value := range tt.queryParams {
+				query.Set(key, value)
+			}
+			req := &http.Request{
+				URL: &url.URL{RawQuery: query.Encode()},
+			}
+
+			fr, err := ParseFilterRequest(req)
+			if tt.errorMsg != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+				assert.Nil(t, fr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, fr)
+			}
+		})
+	}
+}
+
+func TestToEntry(t *testing.T) {
+	model := oaudit.Model{
+		ID:        "1",
+		ActorID:   7,
+		Role:      "admin",
+		Action:    ActionResetCounter,
+		Resource:  ResourceTraffic,
+		TargetID:  "unit-test-traffic-id",
+		Before:    json.RawMessage(`{"counter":12}`),
+		After:     json.RawMessage(`{"counter":0}`),
+		RequestID: "unit-test-request-id",
+		CreatedAt: time.Now(),
+	}
+
+	result := ToEntry(model)
+	assert.Equal(t, Entry{
+		ID:        model.ID,
+		ActorID:   model.ActorID,
+		Role:      model.Role,
+		Action:    model.Action,
+		Resource:  model.Resource,
+		TargetID:  model.TargetID,
+		Before:    model.Before,
+		After:     model.After,
+		RequestID: model.RequestID,
+		CreatedAt: model.CreatedAt,
+	}, result)
+	assert.Empty(t, ToEntrySlice(nil))
+}
@@ -0,0 +1,45 @@
+package audit
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/jmontesinos91/collector/domains/pagination"
+)
+
+// Event a mutating action to be recorded, the actor and request are taken from the context.
+// Before and after hold the state of the target and are stored as JSON
+type Event struct {
+	Action   string
+	Resource string
+	TargetID string
+	Before   interface{}
+	After    interface{}
+}
+
+// FilterRequest holds the http request params
+type FilterRequest struct {
+	ActorID   *int              `json:"actorId,omitempty"`
+	Role      string            `json:"role,omitempty"`
+	Action    string            `json:"action,omitempty"`
+	Resource  string            `json:"resource,omitempty"`
+	TargetID  string            `json:"targetId,omitempty"`
+	RequestID string            `json:"requestId,omitempty"`
+	From      *time.Time        `json:"from,omitempty"`
+	To        *time.Time        `json:"to,omitempty"`
+	Filter    pagination.Filter `json:"filter,omitempty"`
+}
+
+// Entry audit log item
+type Entry struct {
+	ID        string          `json:"id"`
+	ActorID   int             `json:"actorId"`
+	Role      string          `json:"role"`
+	Action    string          `json:"action"`
+	Resource  string          `json:"resource"`
+	TargetID  string          `json:"targetId,omitempty"`
+	Before    json.RawMessage `json:"before,omitempty"`
+	After     json.RawMessage `json:"after,omitempty"`
+	RequestID string          `json:"requestId,omitempty"`
+	CreatedAt time.Time       `json:"createdAt"`
+}
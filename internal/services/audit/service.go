@@ -0,0 +1,19 @@
+package audit
+
+import (
+	"context"
+
+	"github.com/jmontesinos91/collector/domains/pagination"
+)
+
+// IRecorder Records the mutating actions of the operators, services depend on it to
+// audit their own actions
+type IRecorder interface {
+	Record(ctx context.Context, event Event)
+}
+
+// IService Manage the audit log
+type IService interface {
+	IRecorder
+	HandleRetrieve(ctx context.Context, filter *FilterRequest) (pagination.PaginatedRes, error)
+}
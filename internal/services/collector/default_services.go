@@ -2,6 +2,7 @@ package collector
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	straffic "github.com/jmontesinos91/collector/internal/services/traffic"
 	"github.com/jmontesinos91/oevents"
@@ -18,6 +19,10 @@ import (
 	"github.com/jmontesinos91/collector/internal/repositories/routerold"
 	"github.com/jmontesinos91/collector/internal/repositories/traffic"
 	"github.com/jmontesinos91/collector/internal/repositories/unitsold"
+	"github.com/jmontesinos91/collector/internal/services/command"
+	"github.com/jmontesinos91/collector/internal/services/livefeed"
+	"github.com/jmontesinos91/collector/internal/services/threshold"
+	"github.com/jmontesinos91/collector/internal/services/webhook"
 	"github.com/jmontesinos91/oevents/broker"
 	"github.com/jmontesinos91/ologs/logger"
 	tracekey "github.com/jmontesinos91/ologs/logger/v2"
@@ -46,10 +51,22 @@ type DefaultService struct {
 	facilityLocations facilitylocationsold.IRepository
 	alarmClient       router.IClient
 	streamClient      broker.MessagingBrokerProvider
+	feed              livefeed.IPublisher
+	webhooks          webhook.IDispatcher
+	quarantine        threshold.IQuarantine
+	commands          command.IQueue
+	validation        ValidationOpts
+	pending           chan pendingAlarm
 }
 
 // NewDefaultService creates a new instance of DefaultService Payout
-func NewDefaultService(l *logger.ContextLogger, r RepositoryOpts, a router.IClient, bc broker.MessagingBrokerProvider) *DefaultService {
+func NewDefaultService(l *logger.ContextLogger, r RepositoryOpts, a router.IClient, bc broker.MessagingBrokerProvider,
+	fp livefeed.IPublisher, wd webhook.IDispatcher, q threshold.IQuarantine, cq command.IQueue, v ValidationOpts) *DefaultService {
+	var pending chan pendingAlarm
+	if v.Fallback == Queue {
+		pending = make(chan pendingAlarm, max(v.QueueSize, 1))
+	}
+
 	return &DefaultService{
 		log:               l,
 		trafficRepo:       r.TrafficRepo,
@@ -60,11 +77,17 @@ func NewDefaultService(l *logger.ContextLogger, r RepositoryOpts, a router.IClie
 		facilityLocations: r.FacilityLocations,
 		alarmClient:       a,
 		streamClient:      bc,
+		feed:              fp,
+		webhooks:          wd,
+		quarantine:        q,
+		commands:          cq,
+		validation:        v,
+		pending:           pending,
 	}
 }
 
-// Collector routers of service of get byID
-func (s *DefaultService) Collector(ctx context.Context, payload *Payload) error {
+// Collector routers of service of get byID, the reply carries the commands queued for the device
+func (s *DefaultService) Collector(ctx context.Context, payload *Payload) (Reply, error) {
 	requestID := ctx.Value(middleware.RequestIDKey).(string)
 	var alarmType = "0"
 	var isAlarm = false
@@ -76,8 +99,20 @@ func (s *DefaultService) Collector(ctx context.Context, payload *Payload) error
 		IMEI = payload.UnitID
 	}
 
-	if payload.Scare == "P" && (payload.ConfirmPanic == "1" || payload.ConfirmPanic == "2") {
+	// Panic frames are never rejected, a device pressing panic repeatedly is the one that trips the thresholds
+	isPanic := payload.Scare == "P" && (payload.ConfirmPanic == "1" || payload.ConfirmPanic == "2")
+	if !isPanic && s.quarantine.IsQuarantined(IMEI) {
+		s.log.WithContext(logrus.WarnLevel,
+			"Collector",
+			"Frame rejected, the device is quarantined for excessive traffic",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+				"IMEI":              IMEI,
+			}, nil)
+		return Reply{}, terrors.New(terrors.ErrRateLimited, "Device quarantined for excessive traffic", map[string]string{})
+	}
 
+	if isPanic {
 		if payload.ConfirmPanic == "2" {
 			alarmType = "3"
 		}
@@ -88,91 +123,189 @@ func (s *DefaultService) Collector(ctx context.Context, payload *Payload) error
 			UnitID:    payload.UnitID,
 		}
 
+		waiting := "0"
+		if payload.Attending == "0" {
+			waiting = "1"
+		}
+
+		alarm := straffic.Alarm{
+			IMEI:      IMEI,
+			Latitude:  payload.Latitude,
+			Longitude: payload.Longitude,
+			AlarmType: alarmType,
+			Attending: payload.Attending,
+			Waiting:   waiting,
+		}
+
+		tenantID := s.resolveTenant(ctx, IMEI, isUnitID)
+
 		//Call to API //wait for the endpoint with IMEI
+		queued := false
 		response, err := s.alarmClient.ValidateIMEI(ctx, request)
-		if err != nil {
+		switch {
+		case err != nil && router.IsUnavailable(err):
 			s.log.WithContext(logrus.ErrorLevel,
 				"Collector",
-				"Error when validate IME I",
+				"Error when validate IMEI, applying fallback policy",
 				logger.Context{
 					tracekey.TrackingID: requestID,
 					"IMEI":              payload.IMEI,
+					"Fallback":          s.validation.Fallback,
 				},
-				nil)
-		}
-
-		if response.Success {
-			isAlarm = true
-			waiting := "0"
-			if payload.Attending == "0" {
-				waiting = "1"
-			}
+				err)
 
-			alarm := straffic.Alarm{
-				IMEI:      IMEI,
-				Latitude:  payload.Latitude,
-				Longitude: payload.Longitude,
-				AlarmType: alarmType,
-				Attending: payload.Attending,
-				Waiting:   waiting,
-			}
-
-			eventID, err := s.publishAlarmEvent(ctx, alarm, requestID)
-			if err != nil {
-				s.log.WithContext(
-					logrus.ErrorLevel,
-					"Collector",
-					"The alarm event could not be published:",
-					logger.Context{}, err)
-			}
-
-			s.log.WithContext(
-				logrus.InfoLevel,
+			isAlarm, queued = s.applyFallback(ctx, pendingAlarm{
+				payload:    *payload,
+				request:    request,
+				alarm:      alarm,
+				isUnitID:   isUnitID,
+				tenantID:   tenantID,
+				requestID:  requestID,
+				enqueuedAt: time.Now().UTC(),
+			})
+		case err != nil:
+			s.log.WithContext(logrus.ErrorLevel,
 				"Collector",
-				"Alarm requested event published",
+				"Error when validate IMEI",
 				logger.Context{
-					"EventID": eventID,
-				}, err)
+					tracekey.TrackingID: requestID,
+					"IMEI":              payload.IMEI,
+				},
+				err)
+		case response.Success:
+			isAlarm = true
+			s.notifyAlarm(ctx, alarm, tenantID, isUnitID, requestID, true)
 		}
 
-		errM := s.createOrUpdateTraffic(ctx, payload, isAlarm, isUnitID, requestID)
-		if errM != nil {
-			return terrors.New(terrors.ErrBadRequest, terrors.MsgBadRequest, map[string]string{})
+		// A queued frame is stored once its validation settles, so it is counted once
+		if !queued {
+			errM := s.createOrUpdateTraffic(ctx, payload, isAlarm, isUnitID, tenantID, requestID)
+			if errM != nil {
+				return Reply{}, terrors.New(terrors.ErrBadRequest, terrors.MsgBadRequest, map[string]string{})
+			}
 		}
 	} else {
 		//Validate UnitID or IMEI
-		IsVehicle, routerID, unitID := s.validateRouter(ctx, payload)
+		IsVehicle, routerModel, unitID := s.validateRouter(ctx, payload)
+		tenantID := 0
+		if routerModel != nil {
+			tenantID = routerModel.TenantID
+		} else if isUnitID {
+			tenantID = s.resolveTenant(ctx, payload.UnitID, true)
+		}
+
 		if IsVehicle {
-			existAlarm, alarmID, _ := s.oldAlarm.FindByRouterID(ctx, routerID)
-			err := s.updateRouterPosition(ctx, routerID, unitID, alarmID, payload.Latitude, payload.Longitude, existAlarm)
+			existAlarm, alarmID, _ := s.oldAlarm.FindByRouterID(ctx, routerModel.ID)
+			err := s.updateRouterPosition(ctx, routerModel.ID, unitID, alarmID, payload.Latitude, payload.Longitude, existAlarm)
 			if err != nil {
-				return terrors.New(terrors.ErrBadRequest, terrors.MsgBadRequest, map[string]string{})
+				return Reply{}, terrors.New(terrors.ErrBadRequest, terrors.MsgBadRequest, map[string]string{})
 			}
+
+			s.webhooks.Dispatch(ctx, webhook.Event{
+				Type:     webhook.EventDevicePosition,
+				TenantID: routerModel.TenantID,
+				Data:     ToPositionEventData(payload, routerModel.ID, unitID, requestID),
+			})
 		}
 
-		err := s.createOrUpdateTraffic(ctx, payload, isAlarm, isUnitID, requestID)
+		err := s.createOrUpdateTraffic(ctx, payload, isAlarm, isUnitID, tenantID, requestID)
 		if err != nil {
-			return terrors.New(terrors.ErrBadRequest, terrors.MsgBadRequest, map[string]string{})
+			return Reply{}, terrors.New(terrors.ErrBadRequest, terrors.MsgBadRequest, map[string]string{})
 		}
 	}
 
-	return nil
+	if payload.IMEI == "" {
+		return Reply{}, nil
+	}
+	return Reply{Commands: s.commands.Deliver(ctx, payload.IMEI, payload.Acks)}, nil
 }
 
-func (s *DefaultService) validateRouter(ctx context.Context, payload *Payload) (bool, int, int) {
+// validateRouter reports whether the router of the frame is installed on a vehicle, the router is
+// returned whenever it is registered so its tenant is known even without a unit
+func (s *DefaultService) validateRouter(ctx context.Context, payload *Payload) (bool, *routerold.RouterModel, int) {
 	routerModel, err := s.oldRouter.FindByIMEI(ctx, payload.IMEI)
 	if err != nil {
-		return false, 0, 0
+		return false, nil, 0
 	}
 
 	unit, err := s.oldUnits.FindByRouterID(ctx, routerModel.ID)
 	if err != nil {
-		return false, 0, 0
+		return false, routerModel, 0
+	}
+	return unit.IsVehicle, routerModel, unit.ID
+}
+
+// resolveTenant returns the tenant of the device identified by an IMEI or a unit ID, zero when the
+// device is not registered
+func (s *DefaultService) resolveTenant(ctx context.Context, device string, isUnitID bool) int {
+	if !isUnitID {
+		routerModel, err := s.oldRouter.FindByIMEI(ctx, device)
+		if err != nil {
+			return 0
+		}
+		return routerModel.TenantID
+	}
+
+	unitID, err := strconv.Atoi(device)
+	if err != nil {
+		return 0
+	}
+	unit, err := s.oldUnits.FindByID(ctx, unitID)
+	if err != nil {
+		return 0
+	}
+	routerModel, err := s.oldRouter.FindByID(ctx, unit.RouterID)
+	if err != nil {
+		return 0
+	}
+	return routerModel.TenantID
+}
+
+// BackfillTenants assigns the tenant of their device to the traffic rows written without one, the
+// devices that are no longer registered keep no tenant
+func (s *DefaultService) BackfillTenants(ctx context.Context) {
+	after := ""
+	assigned, unresolved := 0, 0
+	for ctx.Err() == nil {
+		imeis, err := s.trafficRepo.RetrieveUntenanted(ctx, after, tenantBackfillBatch)
+		if err != nil {
+			s.log.Error(logrus.ErrorLevel, "BackfillTenants", "Error retrieving devices without tenant", err)
+			return
+		}
+
+		for _, imei := range imeis {
+			// The rows keep no trace of whether the device was a unit ID, the router is looked up first
+			tenantID := s.resolveTenant(ctx, imei, false)
+			if tenantID == 0 {
+				tenantID = s.resolveTenant(ctx, imei, true)
+			}
+			if tenantID == 0 {
+				unresolved++
+				continue
+			}
+
+			rows, errA := s.trafficRepo.AssignTenant(ctx, imei, tenantID)
+			if errA != nil {
+				s.log.Error(logrus.ErrorLevel, "BackfillTenants", "Error assigning traffic tenant", errA)
+				return
+			}
+			assigned += rows
+		}
+
+		if len(imeis) < tenantBackfillBatch {
+			break
+		}
+		after = imeis[len(imeis)-1]
+	}
+
+	if assigned > 0 || unresolved > 0 {
+		s.log.Log(logrus.InfoLevel, "BackfillTenants",
+			"Traffic rows assigned a tenant: "+strconv.Itoa(assigned)+", devices unresolved: "+strconv.Itoa(unresolved))
 	}
-	return unit.IsVehicle, routerModel.ID, unit.ID
 }
 
-func (s *DefaultService) createOrUpdateTraffic(ctx context.Context, payload *Payload, isAlarm, isUnitID bool, requestID string) error {
+func (s *DefaultService) createOrUpdateTraffic(ctx context.Context, payload *Payload, isAlarm, isUnitID bool, tenantID int,
+	requestID string) error {
 	IMEI := payload.IMEI
 	if isUnitID {
 		IMEI = payload.UnitID
@@ -191,6 +324,7 @@ func (s *DefaultService) createOrUpdateTraffic(ctx context.Context, payload *Pay
 			Ip:         payload.IP,
 			IsAlarm:    isAlarm,
 			IsNotified: false,
+			TenantID:   tenantID,
 			CreatedAt:  time.Now().UTC(),
 			UpdatedAt:  time.Now().UTC(),
 		}
@@ -199,7 +333,7 @@ func (s *DefaultService) createOrUpdateTraffic(ctx context.Context, payload *Pay
 			return errM
 		}
 	} else {
-		err := s.trafficRepo.UpdateByIMEI(ctx, IMEI, payload.Request, isAlarm)
+		err := s.trafficRepo.UpdateByIMEI(ctx, IMEI, payload.Request, isAlarm, tenantID)
 		if err != nil {
 			s.log.WithContext(logrus.ErrorLevel,
 				"Collector",
@@ -208,8 +342,36 @@ func (s *DefaultService) createOrUpdateTraffic(ctx context.Context, payload *Pay
 					tracekey.TrackingID: requestID,
 					"IMEI":              payload.IMEI,
 				}, err)
+			return nil
 		}
 	}
+
+	// The rolling window counters are best effort, a lost frame must not fail the collection
+	err = s.trafficRepo.RecordFrame(ctx, IMEI, isAlarm, time.Now().UTC())
+	if err != nil {
+		s.log.WithContext(logrus.ErrorLevel,
+			"Collector",
+			"Error when try to record traffic frame",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+				"IMEI":              payload.IMEI,
+			}, err)
+	}
+
+	eventType := livefeed.EventFrame
+	if isAlarm {
+		eventType = livefeed.EventAlarm
+	}
+	s.feed.Publish(ctx, livefeed.Event{
+		Type:      eventType,
+		IMEI:      IMEI,
+		IP:        payload.IP,
+		Request:   payload.Request,
+		IsAlarm:   isAlarm,
+		TenantID:  tenantID,
+		RequestID: requestID,
+	})
+
 	return nil
 }
 
@@ -255,13 +417,157 @@ func (s *DefaultService) updateRouterPosition(ctx context.Context, routerID, uni
 	return nil
 }
 
-func (s *DefaultService) publishAlarmEvent(ctx context.Context, alarm straffic.Alarm, requestID string) (string, error) {
+// RunPendingValidations validates again the alarms queued while the validation was unavailable,
+// it blocks until the given context is done. The alarms still queued then are validated once more
+// so they are not lost on shutdown, until the drain context is done
+func (s *DefaultService) RunPendingValidations(ctx, drainCtx context.Context) {
+	if s.pending == nil {
+		return
+	}
+
+	ticker := time.NewTicker(max(s.validation.RetryInterval, time.Second))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			if len(s.pending) > 0 {
+				s.retryPendingAlarms(drainCtx)
+			}
+			if left := len(s.pending); left > 0 {
+				s.log.Log(logrus.WarnLevel, "RunPendingValidations",
+					"Pending alarms dropped on shutdown: "+strconv.Itoa(left))
+			}
+			return
+		case <-ticker.C:
+			s.retryPendingAlarms(ctx)
+		}
+	}
+}
+
+func (s *DefaultService) retryPendingAlarms(ctx context.Context) {
+	for i := len(s.pending); i > 0; i-- {
+		if ctx.Err() != nil {
+			return
+		}
+		pa := <-s.pending
+
+		if s.validation.MaxAge > 0 && time.Since(pa.enqueuedAt) > s.validation.MaxAge {
+			s.log.WithContext(logrus.WarnLevel,
+				"retryPendingAlarms",
+				"Pending alarm expired before it could be validated",
+				logger.Context{
+					tracekey.TrackingID: pa.requestID,
+					"IMEI":              pa.alarm.IMEI,
+				}, nil)
+			s.settle(ctx, pa, false)
+			continue
+		}
+
+		response, err := s.alarmClient.ValidateIMEI(ctx, pa.request)
+		if err != nil && router.IsUnavailable(err) {
+			if !s.enqueue(pa) {
+				s.settle(ctx, pa, false)
+			}
+			if errors.Is(err, router.ErrCircuitOpen) {
+				return
+			}
+			continue
+		}
+
+		isAlarm := err == nil && response.Success
+		if isAlarm {
+			s.notifyAlarm(ctx, pa.alarm, pa.tenantID, pa.isUnitID, pa.requestID, true)
+		}
+		s.settle(ctx, pa, isAlarm)
+	}
+}
+
+// settle stores the frame of a queued alarm once its validation settles
+func (s *DefaultService) settle(ctx context.Context, pa pendingAlarm, isAlarm bool) {
+	errT := s.createOrUpdateTraffic(ctx, &pa.payload, isAlarm, pa.isUnitID, pa.tenantID, pa.requestID)
+	if errT != nil {
+		s.log.WithContext(logrus.ErrorLevel,
+			"retryPendingAlarms",
+			"Error when try to create alarm traffic",
+			logger.Context{
+				tracekey.TrackingID: pa.requestID,
+				"IMEI":              pa.alarm.IMEI,
+			}, errT)
+	}
+}
+
+// applyFallback applies the configured policy to an alarm that could not be validated, it reports
+// whether the frame must be stored as an alarm and whether the alarm was queued to be validated later
+func (s *DefaultService) applyFallback(ctx context.Context, pa pendingAlarm) (bool, bool) {
+	switch s.validation.Fallback {
+	case FailOpen:
+		s.notifyAlarm(ctx, pa.alarm, pa.tenantID, pa.isUnitID, pa.requestID, false)
+		return true, false
+	case Queue:
+		return false, s.enqueue(pa)
+	default:
+		return false, false
+	}
+}
+
+// enqueue queues an alarm to be validated later and reports whether the queue had room for it
+func (s *DefaultService) enqueue(pa pendingAlarm) bool {
+	select {
+	case s.pending <- pa:
+		return true
+	default:
+		s.log.WithContext(logrus.ErrorLevel,
+			"enqueue",
+			"Pending alarms queue is full, the frame is stored as regular traffic",
+			logger.Context{
+				tracekey.TrackingID: pa.requestID,
+				"IMEI":              pa.alarm.IMEI,
+			}, nil)
+		return false
+	}
+}
+
+// notifyAlarm publishes the alarm to Omniview and delivers it to the webhooks of the device tenant
+func (s *DefaultService) notifyAlarm(ctx context.Context, alarm straffic.Alarm, tenantID int, isUnitID bool, requestID string,
+	verified bool) {
+	eventID, err := s.publishAlarmEvent(ctx, alarm, requestID, verified)
+	if err != nil {
+		s.log.WithContext(
+			logrus.ErrorLevel,
+			"Collector",
+			"The alarm event could not be published:",
+			logger.Context{}, err)
+	}
+
+	s.log.WithContext(
+		logrus.InfoLevel,
+		"Collector",
+		"Alarm requested event published",
+		logger.Context{
+			"EventID":  eventID,
+			"Verified": verified,
+		}, err)
+
+	s.webhooks.Dispatch(ctx, webhook.Event{
+		ID:       eventID,
+		Type:     webhook.EventAlarmAccepted,
+		TenantID: tenantID,
+		Data:     ToAlarmEventData(alarm, isUnitID, requestID, verified),
+	})
+}
+
+func (s *DefaultService) publishAlarmEvent(ctx context.Context, alarm straffic.Alarm, requestID string, verified bool) (string, error) {
 
 	alarmEvent, err := eventfactory.NewAlarmAcceptedEvent(eventfactory.SourceCollector, ToEventAlarmPayload(alarm, requestID, time.Now().UTC().Format(time.RFC3339)))
 	if err != nil {
 		return "", err
 	}
 
+	if !verified {
+		alarmEvent.Data["unverified"] = true
+	}
+
 	ok := s.streamClient.Publish(ctx, oevents.WebHookOmniViewTopic, *alarmEvent)
 	if !ok {
 		return "", fmt.Errorf("event [%s] could not be published", alarmEvent.EventType)
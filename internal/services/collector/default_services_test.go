@@ -2,6 +2,8 @@ package collector_test
 
 import (
 	"context"
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -18,6 +20,14 @@ import (
 	"github.com/jmontesinos91/collector/internal/repositories/unitsold"
 	"github.com/jmontesinos91/collector/internal/repositories/unitsold/unitsoldmocks"
 	"github.com/jmontesinos91/collector/internal/services/collector"
+	"github.com/jmontesinos91/collector/internal/services/command"
+	"github.com/jmontesinos91/collector/internal/services/command/queuemocks"
+	"github.com/jmontesinos91/collector/internal/services/livefeed"
+	"github.com/jmontesinos91/collector/internal/services/livefeed/publishermocks"
+	"github.com/jmontesinos91/collector/internal/services/threshold/quarantinemocks"
+	"github.com/jmontesinos91/collector/internal/services/webhook"
+	"github.com/jmontesinos91/collector/internal/services/webhook/dispatchermocks"
+	"github.com/jmontesinos91/oevents"
 	"github.com/jmontesinos91/oevents/broker/brokermock"
 	"github.com/jmontesinos91/ologs/logger"
 	"github.com/jmontesinos91/osecurity/sts"
@@ -41,6 +51,10 @@ func TestCollect(t *testing.T) {
 		routerClientFunc func() *routermock.IClient
 		streamClient     *brokermock.MessagingBrokerProvider
 		streamClientFunc func() *brokermock.MessagingBrokerProvider
+		feedPublisher    *publishermocks.IPublisher
+		webhooks         *dispatchermocks.IDispatcher
+		quarantine       *quarantinemocks.IQuarantine
+		commands         *queuemocks.IQueue
 	}
 	type repositoryOpts struct { //nolint:wsl
 		trafficRepo               *trafficmocks.IRepository
@@ -65,11 +79,13 @@ func TestCollect(t *testing.T) {
 		args
 		fields
 		repositoryOpts
+		reply collector.Reply
 	}
 	cases := []struct { //nolint:wsl
 		name           string
 		fields         fields
 		repositoryOpts repositoryOpts
+		validationOpts collector.ValidationOpts
 		args           args
 		err            bool
 		asserts        func(*testing.T, error, assertsParams) bool
@@ -91,12 +107,20 @@ func TestCollect(t *testing.T) {
 				},
 			},
 			repositoryOpts: repositoryOpts{
+				oldRouterRepoFunc: func() *routeroldmocks.IRepository {
+					repositoryMock := &routeroldmocks.IRepository{}
+					repositoryMock.On("FindByIMEI", mock.Anything, "861585041440544").
+						Return(&routerold.RouterModel{ID: 10, TenantID: 4}, nil)
+					return repositoryMock
+				},
 				trafficRepoFunc: func() *trafficmocks.IRepository {
 					repositoryMock := &trafficmocks.IRepository{}
 					repositoryMock.On("FindByIMEI", mock.Anything, mock.Anything, mock.Anything).
 						Return(false, nil)
 					repositoryMock.On("Create", mock.Anything, mock.Anything).
 						Return(nil)
+					repositoryMock.On("RecordFrame", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+						Return(nil)
 					return repositoryMock
 				},
 			},
@@ -125,12 +149,25 @@ func TestCollect(t *testing.T) {
 			err: false,
 			asserts: func(t *testing.T, err error, ap assertsParams) bool {
 				return assert.NoError(t, err) &&
+					ap.webhooks.AssertCalled(t, "Dispatch", mock.Anything, mock.MatchedBy(func(e webhook.Event) bool {
+						data, ok := e.Data.(collector.AlarmEventData)
+						return e.Type == webhook.EventAlarmAccepted &&
+							e.TenantID == 4 &&
+							ok && data.Verified && data.IMEI == "861585041440544"
+					})) &&
 					ap.routerClient.AssertExpectations(t) &&
 					ap.routerClient.AssertCalled(t, "ValidateIMEI", mock.Anything, mock.Anything) &&
 					ap.trafficRepo.AssertExpectations(t) &&
 					ap.trafficRepo.AssertCalled(t, "Create", mock.Anything, mock.Anything) &&
 					ap.streamClient.AssertExpectations(t) &&
-					ap.streamClient.AssertCalled(t, "Publish", mock.Anything, mock.Anything, mock.Anything)
+					ap.streamClient.AssertCalled(t, "Publish", mock.Anything, mock.Anything, mock.Anything) &&
+					ap.feedPublisher.AssertCalled(t, "Publish", mock.Anything, mock.MatchedBy(func(e livefeed.Event) bool {
+						return e.Type == livefeed.EventAlarm &&
+							e.IMEI == "861585041440544" &&
+							e.IP == "192.168.100.1" &&
+							e.IsAlarm &&
+							e.RequestID == "unit-test-request-id"
+					}))
 			},
 		},
 		{
@@ -156,6 +193,8 @@ func TestCollect(t *testing.T) {
 						Return(false, nil)
 					repositoryMock.On("Create", mock.Anything, mock.Anything).
 						Return(nil)
+					repositoryMock.On("RecordFrame", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+						Return(nil)
 					return repositoryMock
 				},
 				oldRouterRepoFunc: func() *routeroldmocks.IRepository {
@@ -164,6 +203,14 @@ func TestCollect(t *testing.T) {
 						Return(&routerold.RouterModel{}, nil)
 					repositoryMock.On("ActiveAndDeactivateRouter", mock.Anything, mock.Anything, mock.Anything).
 						Return(nil)
+					repositoryMock.On("FindByID", mock.Anything, 10).
+						Return(&routerold.RouterModel{ID: 10, TenantID: 4}, nil)
+					return repositoryMock
+				},
+				oldUnitsRepoFunc: func() *unitsoldmocks.IRepository {
+					repositoryMock := &unitsoldmocks.IRepository{}
+					repositoryMock.On("FindByID", mock.Anything, 53438).
+						Return(&unitsold.UnitsModel{ID: 53438, RouterID: 10}, nil)
 					return repositoryMock
 				},
 			},
@@ -200,7 +247,13 @@ func TestCollect(t *testing.T) {
 					ap.trafficRepo.AssertExpectations(t) &&
 					ap.trafficRepo.AssertCalled(t, "Create", mock.Anything, mock.Anything) &&
 					ap.streamClient.AssertExpectations(t) &&
-					ap.streamClient.AssertCalled(t, "Publish", mock.Anything, mock.Anything, mock.Anything)
+					ap.streamClient.AssertCalled(t, "Publish", mock.Anything, mock.Anything, mock.Anything) &&
+					ap.webhooks.AssertCalled(t, "Dispatch", mock.Anything, mock.MatchedBy(func(e webhook.Event) bool {
+						data, ok := e.Data.(collector.AlarmEventData)
+						return e.Type == webhook.EventAlarmAccepted &&
+							e.TenantID == 4 &&
+							ok && data.UnitID == "53438" && data.IMEI == ""
+					}))
 			},
 		},
 		{
@@ -251,7 +304,9 @@ func TestCollect(t *testing.T) {
 					repositoryMock := &trafficmocks.IRepository{}
 					repositoryMock.On("FindByIMEI", mock.Anything, mock.Anything, mock.Anything).
 						Return(true, nil)
-					repositoryMock.On("UpdateByIMEI", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+					repositoryMock.On("UpdateByIMEI", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+						Return(nil)
+					repositoryMock.On("RecordFrame", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
 						Return(nil)
 					return repositoryMock
 				},
@@ -291,7 +346,8 @@ func TestCollect(t *testing.T) {
 						mock.Anything) &&
 					ap.facilityLocationsRepo.AssertExpectations(t) &&
 					ap.facilityLocationsRepo.AssertCalled(t, "Create", ap.ctx,
-						mock.Anything)
+						mock.Anything) &&
+					ap.commands.AssertNotCalled(t, "Deliver", mock.Anything, mock.Anything, mock.Anything)
 			},
 		},
 		{
@@ -311,6 +367,12 @@ func TestCollect(t *testing.T) {
 				},
 			},
 			repositoryOpts: repositoryOpts{
+				oldRouterRepoFunc: func() *routeroldmocks.IRepository {
+					repositoryMock := &routeroldmocks.IRepository{}
+					repositoryMock.On("FindByIMEI", mock.Anything, mock.Anything).
+						Return(nil, terrors.New(terrors.ErrNotFound, "Router information not found", map[string]string{}))
+					return repositoryMock
+				},
 				trafficRepoFunc: func() *trafficmocks.IRepository {
 					repositoryMock := &trafficmocks.IRepository{}
 					repositoryMock.On("FindByIMEI", mock.Anything, mock.Anything, mock.Anything).
@@ -402,6 +464,8 @@ func TestCollect(t *testing.T) {
 						Return(false, nil)
 					repositoryMock.On("Create", mock.Anything, mock.Anything).
 						Return(nil)
+					repositoryMock.On("RecordFrame", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+						Return(nil)
 					return repositoryMock
 				},
 			},
@@ -448,7 +512,7 @@ func TestCollect(t *testing.T) {
 				oldRouterRepoFunc: func() *routeroldmocks.IRepository {
 					repositoryMock := &routeroldmocks.IRepository{}
 					repositoryMock.On("FindByIMEI", mock.Anything, mock.Anything).
-						Return(&routerold.RouterModel{ID: 1}, nil)
+						Return(&routerold.RouterModel{ID: 1, TenantID: 4}, nil)
 					repositoryMock.On("UpdateLatAndLong",
 						mock.Anything, mock.Anything, mock.Anything, mock.Anything).
 						Return(nil)
@@ -482,7 +546,10 @@ func TestCollect(t *testing.T) {
 					repositoryMock := &trafficmocks.IRepository{}
 					repositoryMock.On("FindByIMEI", mock.Anything, mock.Anything, mock.Anything).
 						Return(false, nil)
-					repositoryMock.On("Create", mock.Anything, mock.Anything).
+					repositoryMock.On("Create", mock.Anything, mock.MatchedBy(func(m *otraffic.Model) bool {
+						return m.TenantID == 4
+					})).Return(nil)
+					repositoryMock.On("RecordFrame", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
 						Return(nil)
 					return repositoryMock
 				},
@@ -527,7 +594,13 @@ func TestCollect(t *testing.T) {
 						mock.Anything) &&
 					ap.facilityLocationsRepo.AssertExpectations(t) &&
 					ap.facilityLocationsRepo.AssertCalled(t, "Create", ap.ctx,
-						mock.Anything)
+						mock.Anything) &&
+					ap.webhooks.AssertCalled(t, "Dispatch", mock.Anything, mock.MatchedBy(func(e webhook.Event) bool {
+						data, ok := e.Data.(collector.PositionEventData)
+						return e.Type == webhook.EventDevicePosition &&
+							e.TenantID == 4 &&
+							ok && data.RouterID == 1 && data.Latitude == "123456789"
+					}))
 			},
 		},
 		{
@@ -586,7 +659,7 @@ func TestCollect(t *testing.T) {
 					repositoryMock := &trafficmocks.IRepository{}
 					repositoryMock.On("FindByIMEI", mock.Anything, mock.Anything, mock.Anything).
 						Return(true, nil)
-					repositoryMock.On("UpdateByIMEI", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+					repositoryMock.On("UpdateByIMEI", mock.Anything, mock.Anything, mock.Anything, mock.Anything, mock.Anything).
 						Return(
 							terrors.New(terrors.ErrBadRequest, "Internal error service", map[string]string{}))
 					return repositoryMock
@@ -616,7 +689,373 @@ func TestCollect(t *testing.T) {
 			},
 			err: false,
 			asserts: func(t *testing.T, err error, ap assertsParams) bool {
-				return assert.NoError(t, err)
+				return assert.NoError(t, err) &&
+					ap.feedPublisher.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name: "Validation unavailable with fail-open publishes unverified alarm",
+			fields: fields{
+				routerClientFunc: func() *routermock.IClient {
+					routerMock := &routermock.IClient{}
+					routerMock.On("ValidateIMEI", mock.Anything, mock.Anything).
+						Return(nil, router.ErrCircuitOpen)
+					return routerMock
+				},
+				streamClientFunc: func() *brokermock.MessagingBrokerProvider {
+					streamClientMock := new(brokermock.MessagingBrokerProvider)
+					streamClientMock.On("Publish", mock.Anything, mock.Anything, mock.MatchedBy(func(e oevents.OmniViewEvent) bool {
+						return e.Data["unverified"] == true
+					})).Return(true)
+					return streamClientMock
+				},
+			},
+			repositoryOpts: repositoryOpts{
+				oldRouterRepoFunc: func() *routeroldmocks.IRepository {
+					repositoryMock := &routeroldmocks.IRepository{}
+					repositoryMock.On("FindByIMEI", mock.Anything, "861585041440544").
+						Return(&routerold.RouterModel{ID: 10, TenantID: 4}, nil)
+					return repositoryMock
+				},
+				trafficRepoFunc: func() *trafficmocks.IRepository {
+					repositoryMock := &trafficmocks.IRepository{}
+					repositoryMock.On("FindByIMEI", mock.Anything, mock.Anything, true).
+						Return(false, nil)
+					repositoryMock.On("Create", mock.Anything, mock.Anything).
+						Return(nil)
+					repositoryMock.On("RecordFrame", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+						Return(nil)
+					return repositoryMock
+				},
+			},
+			validationOpts: collector.ValidationOpts{Fallback: collector.FailOpen},
+			args: args{
+				collect: &collector.Payload{
+					Request:      "P,12,12,861585041440544,12,12,123456789,123456789,00,00,00,1",
+					IP:           "192.168.100.1",
+					IMEI:         "861585041440544",
+					Latitude:     "123456789",
+					Longitude:    "123456789",
+					Attending:    "0",
+					ConfirmPanic: "1",
+					Scare:        "P",
+				},
+			},
+			err: false,
+			asserts: func(t *testing.T, err error, ap assertsParams) bool {
+				return assert.NoError(t, err) &&
+					ap.streamClient.AssertExpectations(t) &&
+					ap.trafficRepo.AssertExpectations(t) &&
+					ap.webhooks.AssertCalled(t, "Dispatch", mock.Anything, mock.MatchedBy(func(e webhook.Event) bool {
+						data, ok := e.Data.(collector.AlarmEventData)
+						return e.Type == webhook.EventAlarmAccepted && e.TenantID == 4 && ok && !data.Verified
+					}))
+			},
+		},
+		{
+			name: "Validation server error with fail-open publishes unverified alarm",
+			fields: fields{
+				routerClientFunc: func() *routermock.IClient {
+					routerMock := &routermock.IClient{}
+					routerMock.On("ValidateIMEI", mock.Anything, mock.Anything).
+						Return(nil, fmt.Errorf("%w: service response with 502 status code", router.ErrUnavailable))
+					return routerMock
+				},
+				streamClientFunc: func() *brokermock.MessagingBrokerProvider {
+					streamClientMock := new(brokermock.MessagingBrokerProvider)
+					streamClientMock.On("Publish", mock.Anything, mock.Anything, mock.MatchedBy(func(e oevents.OmniViewEvent) bool {
+						return e.Data["unverified"] == true
+					})).Return(true)
+					return streamClientMock
+				},
+			},
+			repositoryOpts: repositoryOpts{
+				oldRouterRepoFunc: func() *routeroldmocks.IRepository {
+					repositoryMock := &routeroldmocks.IRepository{}
+					repositoryMock.On("FindByIMEI", mock.Anything, "861585041440544").
+						Return(&routerold.RouterModel{ID: 10, TenantID: 4}, nil)
+					return repositoryMock
+				},
+				trafficRepoFunc: func() *trafficmocks.IRepository {
+					repositoryMock := &trafficmocks.IRepository{}
+					repositoryMock.On("FindByIMEI", mock.Anything, mock.Anything, true).
+						Return(false, nil)
+					repositoryMock.On("Create", mock.Anything, mock.Anything).
+						Return(nil)
+					repositoryMock.On("RecordFrame", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+						Return(nil)
+					return repositoryMock
+				},
+			},
+			validationOpts: collector.ValidationOpts{Fallback: collector.FailOpen},
+			args: args{
+				collect: &collector.Payload{
+					Request:      "P,12,12,861585041440544,12,12,123456789,123456789,00,00,00,1",
+					IP:           "192.168.100.1",
+					IMEI:         "861585041440544",
+					Latitude:     "123456789",
+					Longitude:    "123456789",
+					Attending:    "0",
+					ConfirmPanic: "1",
+					Scare:        "P",
+				},
+			},
+			err: false,
+			asserts: func(t *testing.T, err error, ap assertsParams) bool {
+				return assert.NoError(t, err) &&
+					ap.streamClient.AssertExpectations(t) &&
+					ap.trafficRepo.AssertExpectations(t) &&
+					ap.webhooks.AssertCalled(t, "Dispatch", mock.Anything, mock.MatchedBy(func(e webhook.Event) bool {
+						data, ok := e.Data.(collector.AlarmEventData)
+						return e.Type == webhook.EventAlarmAccepted && e.TenantID == 4 && ok && !data.Verified
+					}))
+			},
+		},
+		{
+			name: "Validation unavailable with fail-closed stores regular traffic",
+			fields: fields{
+				routerClientFunc: func() *routermock.IClient {
+					routerMock := &routermock.IClient{}
+					routerMock.On("ValidateIMEI", mock.Anything, mock.Anything).
+						Return(nil, router.ErrCircuitOpen)
+					return routerMock
+				},
+			},
+			repositoryOpts: repositoryOpts{
+				oldRouterRepoFunc: func() *routeroldmocks.IRepository {
+					repositoryMock := &routeroldmocks.IRepository{}
+					repositoryMock.On("FindByIMEI", mock.Anything, "861585041440544").
+						Return(&routerold.RouterModel{ID: 10, TenantID: 4}, nil)
+					return repositoryMock
+				},
+				trafficRepoFunc: func() *trafficmocks.IRepository {
+					repositoryMock := &trafficmocks.IRepository{}
+					repositoryMock.On("FindByIMEI", mock.Anything, mock.Anything, false).
+						Return(false, nil)
+					repositoryMock.On("Create", mock.Anything, mock.MatchedBy(func(m *otraffic.Model) bool {
+						return m.TenantID == 4 && !m.IsAlarm
+					})).Return(nil)
+					repositoryMock.On("RecordFrame", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+						Return(nil)
+					return repositoryMock
+				},
+			},
+			validationOpts: collector.ValidationOpts{Fallback: collector.FailClosed},
+			args: args{
+				collect: &collector.Payload{
+					Request:      "P,12,12,861585041440544,12,12,123456789,123456789,00,00,00,1",
+					IP:           "192.168.100.1",
+					IMEI:         "861585041440544",
+					Latitude:     "123456789",
+					Longitude:    "123456789",
+					Attending:    "0",
+					ConfirmPanic: "1",
+					Scare:        "P",
+				},
+			},
+			err: false,
+			asserts: func(t *testing.T, err error, ap assertsParams) bool {
+				return assert.NoError(t, err) &&
+					ap.trafficRepo.AssertExpectations(t) &&
+					ap.feedPublisher.AssertCalled(t, "Publish", mock.Anything, mock.MatchedBy(func(e livefeed.Event) bool {
+						return e.Type == livefeed.EventFrame && !e.IsAlarm && e.TenantID == 4
+					}))
+			},
+		},
+		{
+			name: "Validation request error with fail-open stores regular traffic without the fallback",
+			fields: fields{
+				routerClientFunc: func() *routermock.IClient {
+					routerMock := &routermock.IClient{}
+					routerMock.On("ValidateIMEI", mock.Anything, mock.Anything).
+						Return(nil, errors.New("alarm_client: error creating http request"))
+					return routerMock
+				},
+				streamClientFunc: func() *brokermock.MessagingBrokerProvider {
+					return new(brokermock.MessagingBrokerProvider)
+				},
+			},
+			repositoryOpts: repositoryOpts{
+				oldRouterRepoFunc: func() *routeroldmocks.IRepository {
+					repositoryMock := &routeroldmocks.IRepository{}
+					repositoryMock.On("FindByIMEI", mock.Anything, "861585041440544").
+						Return(&routerold.RouterModel{ID: 10, TenantID: 4}, nil)
+					return repositoryMock
+				},
+				trafficRepoFunc: func() *trafficmocks.IRepository {
+					repositoryMock := &trafficmocks.IRepository{}
+					repositoryMock.On("FindByIMEI", mock.Anything, mock.Anything, false).
+						Return(false, nil)
+					repositoryMock.On("Create", mock.Anything, mock.MatchedBy(func(m *otraffic.Model) bool {
+						return !m.IsAlarm
+					})).Return(nil)
+					repositoryMock.On("RecordFrame", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+						Return(nil)
+					return repositoryMock
+				},
+			},
+			validationOpts: collector.ValidationOpts{Fallback: collector.FailOpen},
+			args: args{
+				collect: &collector.Payload{
+					Request:      "P,12,12,861585041440544,12,12,123456789,123456789,00,00,00,1",
+					IP:           "192.168.100.1",
+					IMEI:         "861585041440544",
+					Latitude:     "123456789",
+					Longitude:    "123456789",
+					Attending:    "0",
+					ConfirmPanic: "1",
+					Scare:        "P",
+				},
+			},
+			err: false,
+			asserts: func(t *testing.T, err error, ap assertsParams) bool {
+				return assert.NoError(t, err) &&
+					ap.trafficRepo.AssertExpectations(t) &&
+					ap.webhooks.AssertNotCalled(t, "Dispatch", mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name: "Quarantined device frame is rejected",
+			fields: fields{
+				quarantine: func() *quarantinemocks.IQuarantine {
+					quarantineMock := &quarantinemocks.IQuarantine{}
+					quarantineMock.On("IsQuarantined", "861585041440544").Return(true)
+					return quarantineMock
+				}(),
+			},
+			repositoryOpts: repositoryOpts{
+				trafficRepoFunc: func() *trafficmocks.IRepository {
+					return &trafficmocks.IRepository{}
+				},
+			},
+			args: args{
+				collect: &collector.Payload{
+					Request:      "P,12,12,861585041440544,12,12,123456789,123456789,00,00,00,0",
+					IP:           "192.168.100.1",
+					IMEI:         "861585041440544",
+					ConfirmPanic: "0",
+				},
+			},
+			err: true,
+			asserts: func(t *testing.T, err error, ap assertsParams) bool {
+				return assert.True(t, terrors.Is(err, terrors.ErrRateLimited)) &&
+					ap.quarantine.AssertExpectations(t) &&
+					ap.trafficRepo.AssertNotCalled(t, "FindByIMEI", mock.Anything, mock.Anything, mock.Anything) &&
+					ap.feedPublisher.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything) &&
+					ap.webhooks.AssertNotCalled(t, "Dispatch", mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name: "Quarantined device panic frame is still validated",
+			fields: fields{
+				routerClientFunc: func() *routermock.IClient {
+					routerMock := &routermock.IClient{}
+					routerMock.On("ValidateIMEI", mock.Anything, mock.Anything).
+						Return(&router.Response{Success: true}, nil)
+					return routerMock
+				},
+				streamClientFunc: func() *brokermock.MessagingBrokerProvider {
+					streamClientMock := new(brokermock.MessagingBrokerProvider)
+					streamClientMock.On("Publish", mock.Anything, mock.Anything, mock.Anything).
+						Return(true)
+					return streamClientMock
+				},
+				quarantine: func() *quarantinemocks.IQuarantine {
+					quarantineMock := &quarantinemocks.IQuarantine{}
+					quarantineMock.On("IsQuarantined", "861585041440544").Return(true)
+					return quarantineMock
+				}(),
+			},
+			repositoryOpts: repositoryOpts{
+				oldRouterRepoFunc: func() *routeroldmocks.IRepository {
+					repositoryMock := &routeroldmocks.IRepository{}
+					repositoryMock.On("FindByIMEI", mock.Anything, "861585041440544").
+						Return(&routerold.RouterModel{ID: 10, TenantID: 4}, nil)
+					return repositoryMock
+				},
+				trafficRepoFunc: func() *trafficmocks.IRepository {
+					repositoryMock := &trafficmocks.IRepository{}
+					repositoryMock.On("FindByIMEI", mock.Anything, mock.Anything, true).
+						Return(false, nil)
+					repositoryMock.On("Create", mock.Anything, mock.Anything).
+						Return(nil)
+					repositoryMock.On("RecordFrame", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+						Return(nil)
+					return repositoryMock
+				},
+			},
+			args: args{
+				collect: &collector.Payload{
+					Request:      "P,12,12,861585041440544,12,12,123456789,123456789,00,00,00,1",
+					IP:           "192.168.100.1",
+					IMEI:         "861585041440544",
+					Latitude:     "123456789",
+					Longitude:    "123456789",
+					Attending:    "0",
+					ConfirmPanic: "1",
+					Scare:        "P",
+				},
+			},
+			err: false,
+			asserts: func(t *testing.T, err error, ap assertsParams) bool {
+				return assert.NoError(t, err) &&
+					ap.routerClient.AssertCalled(t, "ValidateIMEI", mock.Anything, mock.Anything) &&
+					ap.streamClient.AssertCalled(t, "Publish", mock.Anything, mock.Anything, mock.Anything) &&
+					ap.trafficRepo.AssertExpectations(t) &&
+					ap.quarantine.AssertNotCalled(t, "IsQuarantined", mock.Anything)
+			},
+		},
+		{
+			name: "Queued commands are delivered in the reply",
+			fields: fields{
+				commands: func() *queuemocks.IQueue {
+					queueMock := &queuemocks.IQueue{}
+					queueMock.On("Deliver", mock.Anything, "861585041440544", []string{"5b0e1c52-8a2d-4c4e-9a6f-3d1b7e2f4a90"}).
+						Return([]command.Command{{ID: "unit-test-command", Type: command.TypeReboot}})
+					return queueMock
+				}(),
+			},
+			repositoryOpts: repositoryOpts{
+				oldRouterRepoFunc: func() *routeroldmocks.IRepository {
+					repositoryMock := &routeroldmocks.IRepository{}
+					repositoryMock.On("FindByIMEI", mock.Anything, "861585041440544").
+						Return(&routerold.RouterModel{ID: 1, TenantID: 4}, nil)
+					return repositoryMock
+				},
+				oldUnitsRepoFunc: func() *unitsoldmocks.IRepository {
+					repositoryMock := &unitsoldmocks.IRepository{}
+					repositoryMock.On("FindByRouterID", mock.Anything, mock.Anything).
+						Return(nil, errors.New("unit-test-error"))
+					return repositoryMock
+				},
+				trafficRepoFunc: func() *trafficmocks.IRepository {
+					repositoryMock := &trafficmocks.IRepository{}
+					repositoryMock.On("FindByIMEI", mock.Anything, mock.Anything, mock.Anything).
+						Return(false, nil)
+					repositoryMock.On("Create", mock.Anything, mock.Anything).
+						Return(nil)
+					repositoryMock.On("RecordFrame", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+						Return(nil)
+					return repositoryMock
+				},
+			},
+			args: args{
+				collect: &collector.Payload{
+					Request:      "P,12,12,861585041440544,12,12,123456789,123456789,00,00,00,0",
+					IP:           "192.168.100.1",
+					IMEI:         "861585041440544",
+					Latitude:     "123456789",
+					Longitude:    "123456789",
+					ConfirmPanic: "0",
+					Acks:         []string{"5b0e1c52-8a2d-4c4e-9a6f-3d1b7e2f4a90"},
+				},
+			},
+			err: false,
+			asserts: func(t *testing.T, err error, ap assertsParams) bool {
+				return assert.NoError(t, err) &&
+					ap.commands.AssertExpectations(t) &&
+					assert.Len(t, ap.reply.Commands, 1) &&
+					assert.Equal(t, "unit-test-command", ap.reply.Commands[0].ID)
 			},
 		},
 	}
@@ -635,6 +1074,22 @@ func TestCollect(t *testing.T) {
 				tc.fields.routerClient = tc.fields.routerClientFunc()
 			}
 
+			tc.fields.feedPublisher = &publishermocks.IPublisher{}
+			tc.fields.feedPublisher.On("Publish", mock.Anything, mock.Anything).Return()
+
+			tc.fields.webhooks = &dispatchermocks.IDispatcher{}
+			tc.fields.webhooks.On("Dispatch", mock.Anything, mock.Anything).Return()
+
+			if tc.fields.quarantine == nil {
+				tc.fields.quarantine = &quarantinemocks.IQuarantine{}
+				tc.fields.quarantine.On("IsQuarantined", mock.Anything).Return(false)
+			}
+
+			if tc.fields.commands == nil {
+				tc.fields.commands = &queuemocks.IQueue{}
+				tc.fields.commands.On("Deliver", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+			}
+
 			if tc.repositoryOpts.trafficRepoFunc != nil {
 				tc.repositoryOpts.trafficRepo = tc.repositoryOpts.trafficRepoFunc()
 			}
@@ -671,9 +1126,14 @@ func TestCollect(t *testing.T) {
 			collectorService := collector.NewDefaultService(log,
 				repoOpts,
 				tc.fields.routerClient,
-				tc.fields.streamClient)
+				tc.fields.streamClient,
+				tc.fields.feedPublisher,
+				tc.fields.webhooks,
+				tc.fields.quarantine,
+				tc.fields.commands,
+				tc.validationOpts)
 
-			err := collectorService.Collector(tc.args.ctx, tc.args.collect)
+			reply, err := collectorService.Collector(tc.args.ctx, tc.args.collect)
 			if (err != nil) != tc.err {
 				t.Errorf("DefaultService.FindByID() error = %v, wantErr %v", err, tc.err)
 			}
@@ -682,6 +1142,7 @@ func TestCollect(t *testing.T) {
 				fields:         tc.fields,
 				repositoryOpts: tc.repositoryOpts,
 				args:           tc.args,
+				reply:          reply,
 			}
 
 			if !tc.asserts(t, err, assertsParams) {
@@ -690,3 +1151,287 @@ func TestCollect(t *testing.T) {
 		})
 	}
 }
+
+func TestRunPendingValidations(t *testing.T) {
+	log := logger.NewContextLogger("RunPendingValidations", "debug", logger.TextFormat)
+	ctxBack := context.WithValue(context.Background(), middleware.RequestIDKey, "unit-test-request-id")
+
+	routerMock := &routermock.IClient{}
+	routerMock.On("ValidateIMEI", mock.Anything, mock.Anything).
+		Return(nil, fmt.Errorf("%w: unit-test-error", router.ErrTimeout)).Once()
+	routerMock.On("ValidateIMEI", mock.Anything, mock.Anything).
+		Return(&router.Response{Success: true}, nil).Once()
+
+	streamClientMock := new(brokermock.MessagingBrokerProvider)
+	streamClientMock.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(true)
+
+	oldRouterMock := &routeroldmocks.IRepository{}
+	oldRouterMock.On("FindByIMEI", mock.Anything, "861585041440544").
+		Return(&routerold.RouterModel{ID: 10, TenantID: 4}, nil)
+
+	trafficMock := &trafficmocks.IRepository{}
+	trafficMock.On("FindByIMEI", mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
+	trafficMock.On("Create", mock.Anything, mock.Anything).Return(nil)
+	trafficMock.On("RecordFrame", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	feedMock := &publishermocks.IPublisher{}
+	feedMock.On("Publish", mock.Anything, mock.Anything).Return()
+	webhooksMock := &dispatchermocks.IDispatcher{}
+	webhooksMock.On("Dispatch", mock.Anything, mock.Anything).Return()
+	quarantineMock := &quarantinemocks.IQuarantine{}
+	quarantineMock.On("IsQuarantined", mock.Anything).Return(false)
+	commandsMock := &queuemocks.IQueue{}
+	commandsMock.On("Deliver", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	collectorService := collector.NewDefaultService(log,
+		collector.RepositoryOpts{TrafficRepo: trafficMock, OldRouter: oldRouterMock},
+		routerMock,
+		streamClientMock,
+		feedMock,
+		webhooksMock,
+		quarantineMock,
+		commandsMock,
+		collector.ValidationOpts{Fallback: collector.Queue, QueueSize: 10, RetryInterval: time.Hour})
+
+	_, err := collectorService.Collector(ctxBack, &collector.Payload{
+		Request:      "P,12,12,861585041440544,12,12,123456789,123456789,00,00,00,1",
+		IP:           "192.168.100.1",
+		IMEI:         "861585041440544",
+		Latitude:     "123456789",
+		Longitude:    "123456789",
+		Attending:    "0",
+		ConfirmPanic: "1",
+		Scare:        "P",
+	})
+	assert.NoError(t, err)
+	streamClientMock.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything, mock.Anything)
+
+	// The queued alarm is validated once more when the job is stopped
+	ctx, cancel := context.WithCancel(ctxBack)
+	cancel()
+	collectorService.RunPendingValidations(ctx, ctxBack)
+
+	routerMock.AssertExpectations(t)
+	streamClientMock.AssertCalled(t, "Publish", mock.Anything, mock.Anything, mock.Anything)
+	// The frame is stored once, as an alarm, when its validation settles
+	trafficMock.AssertNumberOfCalls(t, "Create", 1)
+	trafficMock.AssertNumberOfCalls(t, "RecordFrame", 1)
+	trafficMock.AssertCalled(t, "Create", mock.Anything, mock.MatchedBy(func(m *otraffic.Model) bool {
+		return m.IsAlarm
+	}))
+}
+
+func TestRunPendingValidationsDrainDeadline(t *testing.T) {
+	log := logger.NewContextLogger("RunPendingValidations", "debug", logger.TextFormat)
+	ctxBack := context.WithValue(context.Background(), middleware.RequestIDKey, "unit-test-request-id")
+
+	routerMock := &routermock.IClient{}
+	routerMock.On("ValidateIMEI", mock.Anything, mock.Anything).
+		Return(nil, fmt.Errorf("%w: unit-test-error", router.ErrTimeout)).Once()
+
+	streamClientMock := new(brokermock.MessagingBrokerProvider)
+	oldRouterMock := &routeroldmocks.IRepository{}
+	oldRouterMock.On("FindByIMEI", mock.Anything, "861585041440544").
+		Return(&routerold.RouterModel{ID: 10, TenantID: 4}, nil)
+
+	trafficMock := &trafficmocks.IRepository{}
+	trafficMock.On("FindByIMEI", mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
+	trafficMock.On("Create", mock.Anything, mock.Anything).Return(nil)
+	trafficMock.On("RecordFrame", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	feedMock := &publishermocks.IPublisher{}
+	feedMock.On("Publish", mock.Anything, mock.Anything).Return()
+	webhooksMock := &dispatchermocks.IDispatcher{}
+	webhooksMock.On("Dispatch", mock.Anything, mock.Anything).Return()
+	quarantineMock := &quarantinemocks.IQuarantine{}
+	quarantineMock.On("IsQuarantined", mock.Anything).Return(false)
+	commandsMock := &queuemocks.IQueue{}
+	commandsMock.On("Deliver", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+	collectorService := collector.NewDefaultService(log,
+		collector.RepositoryOpts{TrafficRepo: trafficMock, OldRouter: oldRouterMock},
+		routerMock,
+		streamClientMock,
+		feedMock,
+		webhooksMock,
+		quarantineMock,
+		commandsMock,
+		collector.ValidationOpts{Fallback: collector.Queue, QueueSize: 10, RetryInterval: time.Hour})
+
+	_, err := collectorService.Collector(ctxBack, &collector.Payload{
+		Request:      "P,12,12,861585041440544,12,12,123456789,123456789,00,00,00,1",
+		IP:           "192.168.100.1",
+		IMEI:         "861585041440544",
+		Latitude:     "123456789",
+		Longitude:    "123456789",
+		Attending:    "0",
+		ConfirmPanic: "1",
+		Scare:        "P",
+	})
+	assert.NoError(t, err)
+
+	// The shutdown deadline is already reached, the queued alarm is not validated again
+	ctx, cancel := context.WithCancel(ctxBack)
+	cancel()
+	drainCtx, abort := context.WithCancel(ctxBack)
+	abort()
+	collectorService.RunPendingValidations(ctx, drainCtx)
+
+	routerMock.AssertNumberOfCalls(t, "ValidateIMEI", 1)
+	streamClientMock.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything, mock.Anything)
+}
+
+func TestBackfillTenants(t *testing.T) {
+	log := logger.NewContextLogger("BackfillTenants", "debug", logger.TextFormat)
+
+	trafficMock := &trafficmocks.IRepository{}
+	trafficMock.On("RetrieveUntenanted", mock.Anything, "", mock.Anything).
+		Return([]string{"12", "861585041440544", "999999999999999"}, nil).Once()
+	trafficMock.On("AssignTenant", mock.Anything, "861585041440544", 4).Return(3, nil).Once()
+	trafficMock.On("AssignTenant", mock.Anything, "12", 7).Return(1, nil).Once()
+
+	oldRouterMock := &routeroldmocks.IRepository{}
+	oldRouterMock.On("FindByIMEI", mock.Anything, "861585041440544").
+		Return(&routerold.RouterModel{ID: 10, TenantID: 4}, nil)
+	oldRouterMock.On("FindByIMEI", mock.Anything, mock.Anything).Return(nil, errors.New("not found"))
+	oldRouterMock.On("FindByID", mock.Anything, 20).Return(&routerold.RouterModel{ID: 20, TenantID: 7}, nil)
+
+	unitsMock := &unitsoldmocks.IRepository{}
+	unitsMock.On("FindByID", mock.Anything, 12).Return(&unitsold.UnitsModel{ID: 12, RouterID: 20}, nil)
+	unitsMock.On("FindByID", mock.Anything, mock.Anything).Return(nil, errors.New("not found"))
+
+	collectorService := collector.NewDefaultService(log,
+		collector.RepositoryOpts{TrafficRepo: trafficMock, OldRouter: oldRouterMock, OldUnits: unitsMock},
+		&routermock.IClient{},
+		new(brokermock.MessagingBrokerProvider),
+		&publishermocks.IPublisher{},
+		&dispatchermocks.IDispatcher{},
+		&quarantinemocks.IQuarantine{},
+		&queuemocks.IQueue{},
+		collector.ValidationOpts{})
+
+	collectorService.BackfillTenants(context.Background())
+
+	trafficMock.AssertExpectations(t)
+	trafficMock.AssertNotCalled(t, "AssignTenant", mock.Anything, "999999999999999", mock.Anything)
+}
+
+func TestQueueFallback(t *testing.T) {
+	log := logger.NewContextLogger("QueueFallback", "debug", logger.TextFormat)
+	ctxBack := context.WithValue(context.Background(), middleware.RequestIDKey, "unit-test-request-id")
+	unavailable := fmt.Errorf("%w: unit-test-error", router.ErrTimeout)
+
+	cases := []struct { //nolint:wsl
+		name             string
+		queueSize        int
+		routerClientFunc func() *routermock.IClient
+		asserts          func(*testing.T, *trafficmocks.IRepository) bool
+	}{
+		{
+			name:      "Full queue stores the frame as regular traffic right away",
+			queueSize: 1,
+			routerClientFunc: func() *routermock.IClient {
+				routerMock := &routermock.IClient{}
+				routerMock.On("ValidateIMEI", mock.Anything, mock.Anything).Return(nil, unavailable).Times(2)
+				routerMock.On("ValidateIMEI", mock.Anything, mock.Anything).Return(&router.Response{Success: true}, nil)
+				return routerMock
+			},
+			asserts: func(t *testing.T, trafficMock *trafficmocks.IRepository) bool {
+				return trafficMock.AssertNumberOfCalls(t, "Create", 2) &&
+					trafficMock.AssertNumberOfCalls(t, "RecordFrame", 2) &&
+					trafficMock.AssertCalled(t, "RecordFrame", mock.Anything, mock.Anything, false, mock.Anything) &&
+					trafficMock.AssertCalled(t, "RecordFrame", mock.Anything, mock.Anything, true, mock.Anything)
+			},
+		},
+		{
+			name:      "Rejected retried alarm is stored once as regular traffic",
+			queueSize: 10,
+			routerClientFunc: func() *routermock.IClient {
+				routerMock := &routermock.IClient{}
+				routerMock.On("ValidateIMEI", mock.Anything, mock.Anything).Return(nil, unavailable).Once()
+				routerMock.On("ValidateIMEI", mock.Anything, mock.Anything).Return(&router.Response{Success: false}, nil)
+				return routerMock
+			},
+			asserts: func(t *testing.T, trafficMock *trafficmocks.IRepository) bool {
+				return trafficMock.AssertNumberOfCalls(t, "Create", 1) &&
+					trafficMock.AssertNumberOfCalls(t, "RecordFrame", 1) &&
+					trafficMock.AssertCalled(t, "RecordFrame", mock.Anything, mock.Anything, false, mock.Anything)
+			},
+		},
+		{
+			name:      "Alarm still unavailable is never stored",
+			queueSize: 10,
+			routerClientFunc: func() *routermock.IClient {
+				routerMock := &routermock.IClient{}
+				routerMock.On("ValidateIMEI", mock.Anything, mock.Anything).Return(nil, unavailable)
+				return routerMock
+			},
+			asserts: func(t *testing.T, trafficMock *trafficmocks.IRepository) bool {
+				return trafficMock.AssertNotCalled(t, "Create", mock.Anything, mock.Anything) &&
+					trafficMock.AssertNotCalled(t, "RecordFrame", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			streamClientMock := new(brokermock.MessagingBrokerProvider)
+			streamClientMock.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(true)
+			oldRouterMock := &routeroldmocks.IRepository{}
+			oldRouterMock.On("FindByIMEI", mock.Anything, mock.Anything).
+				Return(&routerold.RouterModel{ID: 10, TenantID: 4}, nil)
+
+			trafficMock := &trafficmocks.IRepository{}
+			trafficMock.On("FindByIMEI", mock.Anything, mock.Anything, mock.Anything).Return(false, nil)
+			trafficMock.On("Create", mock.Anything, mock.Anything).Return(nil)
+			trafficMock.On("RecordFrame", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+			feedMock := &publishermocks.IPublisher{}
+			feedMock.On("Publish", mock.Anything, mock.Anything).Return()
+			webhooksMock := &dispatchermocks.IDispatcher{}
+			webhooksMock.On("Dispatch", mock.Anything, mock.Anything).Return()
+			quarantineMock := &quarantinemocks.IQuarantine{}
+			quarantineMock.On("IsQuarantined", mock.Anything).Return(false)
+			commandsMock := &queuemocks.IQueue{}
+			commandsMock.On("Deliver", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+			collectorService := collector.NewDefaultService(log,
+				collector.RepositoryOpts{TrafficRepo: trafficMock, OldRouter: oldRouterMock},
+				tc.routerClientFunc(),
+				streamClientMock,
+				feedMock,
+				webhooksMock,
+				quarantineMock,
+				commandsMock,
+				collector.ValidationOpts{Fallback: collector.Queue, QueueSize: tc.queueSize, RetryInterval: time.Hour})
+
+			for _, imei := range []string{"861585041440544", "861585041440545"} {
+				_, err := collectorService.Collector(ctxBack, &collector.Payload{
+					Request:      "P,12,12," + imei + ",12,12,123456789,123456789,00,00,00,1",
+					IP:           "192.168.100.1",
+					IMEI:         imei,
+					Latitude:     "123456789",
+					Longitude:    "123456789",
+					Attending:    "0",
+					ConfirmPanic: "1",
+					Scare:        "P",
+				})
+				assert.NoError(t, err)
+				if tc.queueSize > 1 {
+					break
+				}
+			}
+
+			// Validated again once by the shutdown drain
+			ctx, cancel := context.WithCancel(ctxBack)
+			cancel()
+			drainCtx, abort := context.WithCancel(ctxBack)
+			defer abort()
+			collectorService.RunPendingValidations(ctx, drainCtx)
+
+			if !tc.asserts(t, trafficMock) {
+				t.Errorf("Assert error on test = '%v'", tc.name)
+			}
+		})
+	}
+}
@@ -10,18 +10,39 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/google/uuid"
 	"github.com/jmontesinos91/collector/internal/repositories/traffic"
+	"github.com/jmontesinos91/collector/internal/services/command"
 	"github.com/jmontesinos91/terrors"
 )
 
 // ParsePayload Build the model expected for repository
 func (p *Payload) ParsePayload(r *http.Request) error {
 	query := r.URL.Query()
-	collect := strings.ReplaceAll(query.Get("router"), " ", "")
+	collect := query.Get("router")
 
-	if collect == "" {
-		collect = strings.ReplaceAll(chi.URLParam(r, "str"), " ", "")
+	if strings.ReplaceAll(collect, " ", "") == "" {
+		collect = chi.URLParam(r, "str")
 	}
 
+	remoteIP := r.RemoteAddr
+	if ip := strings.TrimSuffix(r.Header.Get("Referer"), "/"); ip != "" {
+		remoteIP = ip
+	}
+
+	err := p.ParseFrame(collect, remoteIP)
+	if err != nil {
+		return terrors.New(terrors.ErrBadRequest, "Invalid Request String", map[string]string{
+			"router": "must be a device frame of at least 12 comma separated values carrying the gprs and the imei or unit id",
+		})
+	}
+
+	p.Acks = command.ParseAcks(query["ack"]...)
+
+	return nil
+}
+
+// ParseFrame Build the model from a raw device frame, the remote ip is used when the frame does not carry one
+func (p *Payload) ParseFrame(frame, remoteIP string) error {
+	collect := strings.ReplaceAll(frame, " ", "")
 	collectString := strings.Split(collect, ",")
 
 	if len(collectString) < 12 {
@@ -32,13 +53,14 @@ func (p *Payload) ParsePayload(r *http.Request) error {
 
 	if collectString[2] != "" {
 		p.IP = collectString[2]
-	} else if ip := strings.TrimSuffix(r.Header.Get("Referer"), "/"); ip != "" {
-		p.IP = ip
 	} else {
-		p.IP = r.RemoteAddr
+		p.IP = remoteIP
 	}
 
 	p.GPRS = collectString[0]
+	if p.GPRS == "" {
+		return terrors.New(terrors.ErrBadRequest, "Invalid Request String", nil)
+	}
 
 	if collectString[3] != "" {
 		p.IMEI = collectString[3]
@@ -78,6 +100,14 @@ func (p *Payload) ParsePayload(r *http.Request) error {
 	return nil
 }
 
+// Device returns the IMEI of the frame, or its unit id when the frame does not carry one
+func (p *Payload) Device() string {
+	if p.IMEI != "" {
+		return p.IMEI
+	}
+	return p.UnitID
+}
+
 func (p *Payload) ParseAlarmPayload(alarmType, waiting string) AlarmPayload {
 	return AlarmPayload{
 		IMEI:      p.IMEI,
@@ -114,3 +144,34 @@ func ToEventAlarmPayload(alarm straffic.Alarm, requestID, eventDate string) even
 		EventDate: eventDate,
 	}
 }
+
+// ToAlarmEventData maps an accepted alarm into its webhook data
+func ToAlarmEventData(alarm straffic.Alarm, isUnitID bool, requestID string, verified bool) AlarmEventData {
+	data := AlarmEventData{
+		IMEI:      alarm.IMEI,
+		Latitude:  alarm.Latitude,
+		Longitude: alarm.Longitude,
+		AlarmType: alarm.AlarmType,
+		Attending: alarm.Attending,
+		Waiting:   alarm.Waiting,
+		Verified:  verified,
+		RequestID: requestID,
+	}
+	if isUnitID {
+		data.IMEI, data.UnitID = "", alarm.IMEI
+	}
+
+	return data
+}
+
+// ToPositionEventData maps a vehicle frame into its webhook data
+func ToPositionEventData(payload *Payload, routerID, unitID int, requestID string) PositionEventData {
+	return PositionEventData{
+		IMEI:      payload.IMEI,
+		RouterID:  routerID,
+		UnitID:    unitID,
+		Latitude:  payload.Latitude,
+		Longitude: payload.Longitude,
+		RequestID: requestID,
+	}
+}
@@ -41,6 +41,26 @@ func TestParsePayload(t *testing.T) {
 			},
 			expectError: false,
 		},
+		{
+			name: "Acknowledged commands",
+			queryParams: map[string]string{
+				"router": "P,12,192.168.100.1,861585041440544,12,12,123456789,123456789,00,00,00,1",
+				"ack":    "5b0e1c52-8a2d-4c4e-9a6f-3d1b7e2f4a90,not-a-command",
+			},
+			expected: &Payload{
+				Request:      "P,12,192.168.100.1,861585041440544,12,12,123456789,123456789,00,00,00,1",
+				IP:           "192.168.100.1",
+				IMEI:         "861585041440544",
+				Latitude:     "123456789",
+				Longitude:    "123456789",
+				Attending:    "0",
+				ConfirmPanic: "1",
+				Scare:        "P",
+				GPRS:         "P",
+				Acks:         []string{"5b0e1c52-8a2d-4c4e-9a6f-3d1b7e2f4a90"},
+			},
+			expectError: false,
+		},
 		{
 			name: "Biggest length parameter",
 			queryParams: map[string]string{
@@ -210,6 +230,57 @@ func TestParsePayload(t *testing.T) {
 	}
 }
 
+func TestParseFrame(t *testing.T) {
+	tests := []struct {
+		name        string
+		frame       string
+		remoteIP    string
+		expected    *Payload
+		expectError bool
+	}{
+		{
+			name:     "Remote ip when the frame does not carry one",
+			frame:    "P, 12, ,861585041440544,12,12,123456789,123456789,00,00,00,1",
+			remoteIP: "10.0.0.7",
+			expected: &Payload{
+				Request:      "P,12,,861585041440544,12,12,123456789,123456789,00,00,00,1",
+				IP:           "10.0.0.7",
+				IMEI:         "861585041440544",
+				Latitude:     "123456789",
+				Longitude:    "123456789",
+				Attending:    "0",
+				ConfirmPanic: "1",
+				Scare:        "P",
+				GPRS:         "P",
+			},
+		},
+		{
+			name:        "Missing gprs field",
+			frame:       ",12,192.168.100.1,861585041440544,12,12,123456789,123456789,00,00,00,1",
+			expectError: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			payload := &Payload{}
+			err := payload.ParseFrame(tt.frame, tt.remoteIP)
+			if tt.expectError {
+				assert.EqualError(t, err, "bad_request: Invalid Request String")
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, payload)
+			}
+		})
+	}
+}
+
+func TestDevice(t *testing.T) {
+	assert.Equal(t, "861585041440544", (&Payload{IMEI: "861585041440544", UnitID: "12"}).Device())
+	assert.Equal(t, "12", (&Payload{UnitID: "12"}).Device())
+	assert.Equal(t, "", (&Payload{}).Device())
+}
+
 func TestParseAlarmPayload(t *testing.T) {
 	type args struct { //nolint:wsl
 		payload   *Payload
@@ -389,3 +460,30 @@ func TestToEventAlarmPayload(t *testing.T) {
 		})
 	}
 }
+
+func TestParseFallbackPolicy(t *testing.T) {
+	tests := []struct {
+		name     string
+		value    string
+		expected FallbackPolicy
+		wantErr  bool
+	}{
+		{name: "Empty defaults to fail-closed", value: "", expected: FailClosed},
+		{name: "Fail-open", value: "fail-open", expected: FailOpen},
+		{name: "Fail-closed", value: "fail-closed", expected: FailClosed},
+		{name: "Queue", value: "queue", expected: Queue},
+		{name: "Unknown value is rejected", value: "fail_open", wantErr: true},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			policy, err := ParseFallbackPolicy(tc.value)
+			if tc.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, policy)
+		})
+	}
+}
@@ -1,17 +1,33 @@
 package collector
 
+import (
+	"fmt"
+	"time"
+
+	"github.com/jmontesinos91/collector/internal/repositories/router"
+	"github.com/jmontesinos91/collector/internal/services/command"
+	straffic "github.com/jmontesinos91/collector/internal/services/traffic"
+)
+
 // Payload payload example
 type Payload struct {
-	GPRS         string `json:"gprs"`
-	Scare        string `json:"scare"`
-	IMEI         string `json:"imei"`
-	Latitude     string `json:"latitude"`
-	Longitude    string `json:"longitude"`
-	Attending    string `json:"attending"`
-	ConfirmPanic string `json:"confirmPanic"`
-	IP           string `json:"ip"`
-	Request      string `json:"request"`
-	UnitID       string `json:"unitID"`
+	GPRS         string   `json:"gprs"`
+	Scare        string   `json:"scare"`
+	IMEI         string   `json:"imei"`
+	Latitude     string   `json:"latitude"`
+	Longitude    string   `json:"longitude"`
+	Attending    string   `json:"attending"`
+	ConfirmPanic string   `json:"confirmPanic"`
+	IP           string   `json:"ip"`
+	Request      string   `json:"request"`
+	UnitID       string   `json:"unitID"`
+	Acks         []string `json:"acks,omitempty"`
+}
+
+// Reply answer to a device frame
+type Reply struct {
+	// Commands queued for the device, delivered once
+	Commands []command.Command
 }
 
 type AlarmPayload struct {
@@ -22,3 +38,73 @@ type AlarmPayload struct {
 	Waiting   string
 	Attending string
 }
+
+// AlarmEventData webhook data of an accepted alarm, unverified alarms were accepted by the
+// fail-open policy while the validation was unavailable
+type AlarmEventData struct {
+	IMEI      string `json:"imei,omitempty"`
+	UnitID    string `json:"unitId,omitempty"`
+	Latitude  string `json:"latitude"`
+	Longitude string `json:"longitude"`
+	AlarmType string `json:"alarmType"`
+	Attending string `json:"attending"`
+	Waiting   string `json:"waiting"`
+	Verified  bool   `json:"verified"`
+	RequestID string `json:"requestId"`
+}
+
+// PositionEventData webhook data of a vehicle position update
+type PositionEventData struct {
+	IMEI      string `json:"imei"`
+	RouterID  int    `json:"routerId"`
+	UnitID    int    `json:"unitId"`
+	Latitude  string `json:"latitude"`
+	Longitude string `json:"longitude"`
+	RequestID string `json:"requestId"`
+}
+
+// tenantBackfillBatch devices resolved per page by the tenant backfill
+const tenantBackfillBatch = 500
+
+// FallbackPolicy decides what happens to a panic frame when the alarm validation is unavailable
+type FallbackPolicy string
+
+// Alarm validation fallback policies
+const (
+	// FailOpen publishes the alarm flagged as unverified
+	FailOpen FallbackPolicy = "fail-open"
+	// FailClosed drops the alarm, the frame is stored as regular traffic
+	FailClosed FallbackPolicy = "fail-closed"
+	// Queue keeps the alarm in memory and validates it again later
+	Queue FallbackPolicy = "queue"
+)
+
+// ParseFallbackPolicy returns the fallback policy of the given name, fail-closed when it is empty
+func ParseFallbackPolicy(value string) (FallbackPolicy, error) {
+	switch policy := FallbackPolicy(value); policy {
+	case "":
+		return FailClosed, nil
+	case FailOpen, FailClosed, Queue:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown alarm validation fallback %q, it must be %s, %s or %s", value, FailOpen, FailClosed, Queue)
+	}
+}
+
+// ValidationOpts alarm validation fallback options
+type ValidationOpts struct {
+	Fallback      FallbackPolicy
+	QueueSize     int
+	RetryInterval time.Duration
+	MaxAge        time.Duration
+}
+
+type pendingAlarm struct {
+	payload    Payload
+	request    router.Request
+	alarm      straffic.Alarm
+	isUnitID   bool
+	tenantID   int
+	requestID  string
+	enqueuedAt time.Time
+}
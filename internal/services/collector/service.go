@@ -6,5 +6,5 @@ import (
 
 // IService Manage routers interfaces
 type IService interface {
-	Collector(ctx context.Context, payload *Payload) error
+	Collector(ctx context.Context, payload *Payload) (Reply, error)
 }
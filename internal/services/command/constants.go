@@ -0,0 +1,46 @@
+package command
+
+import "time"
+
+// Command types a device can be sent
+const (
+	TypeReboot               = "reboot"
+	TypeSetReportingInterval = "set-reporting-interval"
+	TypeActivateOutput       = "activate-output"
+	TypeAcknowledgeAlarm     = "acknowledge-alarm"
+)
+
+// Types every command type a device can be sent
+var Types = []string{TypeReboot, TypeSetReportingInterval, TypeActivateOutput, TypeAcknowledgeAlarm}
+
+// Wire formats of the commands delivered in the collector response
+const (
+	// FormatJSON {"commands":[{"id":"...","type":"reboot","params":{}}]}
+	FormatJSON = "json"
+	// FormatText one CMD,<id>,<code>[,<args>] line per command
+	FormatText = "text"
+)
+
+const (
+	// DefaultMaxPerResponse commands delivered in a single collector response
+	DefaultMaxPerResponse = 5
+	// DefaultMaxAttempts times a command is delivered before it is expired
+	DefaultMaxAttempts = 3
+	// MinReportingInterval shortest reporting interval in seconds a device can be set to
+	MinReportingInterval = 10
+	// MaxReportingInterval longest reporting interval in seconds a device can be set to
+	MaxReportingInterval = 86400
+	// MaxOutput digital outputs of a device
+	MaxOutput = 8
+)
+
+// DefaultAckTimeout time a sent command waits for the device acknowledgement before it is delivered again
+const DefaultAckTimeout = 5 * time.Minute
+
+// textCodes codes of the command types in the text wire format
+var textCodes = map[string]string{
+	TypeReboot:               "RBT",
+	TypeSetReportingInterval: "INT",
+	TypeActivateOutput:       "OUT",
+	TypeAcknowledgeAlarm:     "ALM",
+}
@@ -0,0 +1,206 @@
+package command
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/jmontesinos91/collector/domains/pagination"
+	ocommand "github.com/jmontesinos91/collector/internal/repositories/command"
+	"github.com/jmontesinos91/collector/internal/repositories/routerold"
+	"github.com/jmontesinos91/collector/internal/services/audit"
+	"github.com/jmontesinos91/ologs/logger"
+	tracekey "github.com/jmontesinos91/ologs/logger/v2"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/jmontesinos91/terrors"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultService struct
+type DefaultService struct {
+	log         *logger.ContextLogger
+	commandRepo ocommand.IRepository
+	oldRouter   routerold.IRepository
+	auditor     audit.IRecorder
+	opts        Opts
+}
+
+// NewDefaultService creates a new instance of DefaultService
+func NewDefaultService(l *logger.ContextLogger, cr ocommand.IRepository, ro routerold.IRepository, ar audit.IRecorder, opts Opts) *DefaultService {
+	if opts.MaxPerResponse <= 0 {
+		opts.MaxPerResponse = DefaultMaxPerResponse
+	}
+	if opts.AckTimeout <= 0 {
+		opts.AckTimeout = DefaultAckTimeout
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = DefaultMaxAttempts
+	}
+
+	return &DefaultService{
+		log:         l,
+		commandRepo: cr,
+		oldRouter:   ro,
+		auditor:     ar,
+		opts:        opts,
+	}
+}
+
+// HandleCreate Queues a command for a device of the tenants of the requesting user, it is delivered
+// in the response to the next frame of the device
+func (s *DefaultService) HandleCreate(ctx context.Context, imei string, request *CreateRequest) (Command, error) {
+	claims := ctx.Value(&sts.Claim).(sts.Claims)
+
+	tenantID, err := s.authorize(ctx, "HandleCreate", imei)
+	if err != nil {
+		return Command{}, err
+	}
+
+	now := time.Now().UTC()
+	model := &ocommand.Model{
+		ID:        uuid.NewString(),
+		IMEI:      imei,
+		TenantID:  tenantID,
+		Type:      request.Type,
+		Params:    ToModelParams(request.Type, request.Params),
+		Status:    ocommand.StatusQueued,
+		CreatedBy: claims.UserID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	if err := s.commandRepo.Create(ctx, model); err != nil {
+		s.logError(ctx, "HandleCreate", "Failed to queue device command", imei, err)
+		return Command{}, err
+	}
+
+	command := ToCommand(*model)
+	s.auditor.Record(ctx, audit.Event{
+		Action:   audit.ActionCreate,
+		Resource: audit.ResourceCommand,
+		TargetID: command.ID,
+		After:    command,
+	})
+
+	return command, nil
+}
+
+// HandleRetrieve Retrieves the commands of a device of the tenants of the requesting user, newest first
+func (s *DefaultService) HandleRetrieve(ctx context.Context, imei string, filter *FilterRequest) (pagination.PaginatedRes, error) {
+	if _, err := s.authorize(ctx, "HandleRetrieve", imei); err != nil {
+		return pagination.PaginatedRes{}, err
+	}
+
+	_ = filter.Filter.SanitizePageFilter()
+
+	models, pages, total, err := s.commandRepo.Retrieve(ctx, ToMetadata(imei, filter))
+	if err != nil {
+		s.logError(ctx, "HandleRetrieve", "Failed to retrieve device commands", imei, err)
+		return pagination.PaginatedRes{}, err
+	}
+
+	return ToPaginatedResponse(ToCommandSlice(models), filter.Filter.Page, pages, total), nil
+}
+
+// HandleFind Retrieves a command of a device of the tenants of the requesting user
+func (s *DefaultService) HandleFind(ctx context.Context, imei, commandID string) (Command, error) {
+	if _, err := s.authorize(ctx, "HandleFind", imei); err != nil {
+		return Command{}, err
+	}
+
+	model, err := s.commandRepo.FindByID(ctx, imei, commandID)
+	if err != nil {
+		s.logError(ctx, "HandleFind", "Failed to find device command", imei, err)
+		return Command{}, err
+	}
+
+	return ToCommand(*model), nil
+}
+
+// Deliver acknowledges the commands the device reports as executed and claims its oldest pending
+// commands as sent. The frame was already stored when they are delivered, so failures are logged
+// and the device gets its commands on a later frame. A sent command the device does not acknowledge
+// within the ack timeout is delivered again with the same id, until its attempts run out
+func (s *DefaultService) Deliver(ctx context.Context, imei string, acks []string) []Command {
+	requestID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	logCtx := logger.Context{
+		tracekey.TrackingID: requestID,
+		"IMEI":              imei,
+	}
+
+	now := time.Now().UTC()
+	if len(acks) > 0 {
+		if _, err := s.commandRepo.Acknowledge(ctx, imei, acks, now); err != nil {
+			s.log.WithContext(logrus.ErrorLevel, "Deliver", "Failed to acknowledge device commands", logCtx, err)
+		}
+	}
+
+	models, err := s.commandRepo.ClaimPending(ctx, imei, now, s.opts.AckTimeout, s.opts.MaxAttempts, s.opts.MaxPerResponse)
+	if err != nil {
+		s.log.WithContext(logrus.ErrorLevel, "Deliver", "Failed to claim pending device commands", logCtx, err)
+		return nil
+	}
+	if len(models) == 0 {
+		return nil
+	}
+
+	return ToCommandSlice(models)
+}
+
+// ExpireUnacknowledged expires the commands sent every attempt without being acknowledged, it is meant
+// to be run periodically by the scheduler
+func (s *DefaultService) ExpireUnacknowledged(ctx context.Context) {
+	expired, err := s.commandRepo.Expire(ctx, time.Now().UTC(), s.opts.AckTimeout, s.opts.MaxAttempts)
+	if err != nil {
+		s.log.Error(logrus.ErrorLevel, "ExpireUnacknowledged", "Failed to expire device commands", err)
+		return
+	}
+
+	if expired > 0 {
+		s.log.WithContext(logrus.InfoLevel,
+			"ExpireUnacknowledged",
+			"Unacknowledged device commands expired",
+			logger.Context{
+				"Expired": expired,
+			},
+			nil)
+	}
+}
+
+// authorize returns the tenant of a device the caller can reach, the devices of other tenants
+// are reported as not found so their existence is not disclosed
+func (s *DefaultService) authorize(ctx context.Context, method, imei string) (int, error) {
+	claims := ctx.Value(&sts.Claim).(sts.Claims)
+
+	routerModel, err := s.oldRouter.FindByIMEI(ctx, imei)
+	if err != nil {
+		if !terrors.Is(err, terrors.ErrNotFound) {
+			s.logError(ctx, method, "Failed to find device", imei, err)
+			return 0, err
+		}
+		return 0, terrors.New(terrors.ErrNotFound, "Device not found", map[string]string{})
+	}
+
+	if !s.opts.Tenancy.Scope(claims.Role, claims.Tenants).Allows(routerModel.TenantID) {
+		return 0, terrors.New(terrors.ErrNotFound, "Device not found", map[string]string{})
+	}
+
+	return routerModel.TenantID, nil
+}
+
+func (s *DefaultService) logError(ctx context.Context, method, message, imei string, err error) {
+	requestID := ctx.Value(middleware.RequestIDKey).(string)
+	claims := ctx.Value(&sts.Claim).(sts.Claims)
+
+	s.log.WithContext(logrus.ErrorLevel,
+		method,
+		message,
+		logger.Context{
+			tracekey.TrackingID: requestID,
+			tracekey.UserID:     claims.UserID,
+			tracekey.Role:       claims.Role,
+			"IMEI":              imei,
+		},
+		err)
+}
@@ -0,0 +1,403 @@
+package command_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jmontesinos91/collector/domains/pagination"
+	"github.com/jmontesinos91/collector/domains/tenancy"
+	ocommand "github.com/jmontesinos91/collector/internal/repositories/command"
+	"github.com/jmontesinos91/collector/internal/repositories/command/commandmocks"
+	"github.com/jmontesinos91/collector/internal/repositories/routerold"
+	"github.com/jmontesinos91/collector/internal/repositories/routerold/routeroldmocks"
+	"github.com/jmontesinos91/collector/internal/services/audit"
+	"github.com/jmontesinos91/collector/internal/services/audit/recordermocks"
+	"github.com/jmontesinos91/collector/internal/services/command"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/jmontesinos91/terrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const (
+	imei      = "860000000000001"
+	commandID = "5b0e1c52-8a2d-4c4e-9a6f-3d1b7e2f4a90"
+)
+
+var opts = command.Opts{Tenancy: tenancy.Policy{SuperAdminRoles: []string{"unit-test-super-admin"}}}
+
+func auditRecorder() *recordermocks.IRecorder {
+	recorder := &recordermocks.IRecorder{}
+	recorder.On("Record", mock.Anything, mock.Anything).Return()
+	return recorder
+}
+
+func routerRepository(tenantID int) *routeroldmocks.IRepository {
+	routerMock := &routeroldmocks.IRepository{}
+	routerMock.On("FindByIMEI", mock.Anything, imei).Return(&routerold.RouterModel{ID: 1, IMEI: imei, TenantID: tenantID}, nil)
+	return routerMock
+}
+
+func testContext(role string, tenants ...int) context.Context {
+	ctxBack := context.Background()
+	ctxBack = context.WithValue(ctxBack, middleware.RequestIDKey, "unit-test-request-id")
+	return context.WithValue(ctxBack, &sts.Claim, sts.Claims{
+		UserID:  7,
+		Role:    role,
+		Tenants: tenants,
+	})
+}
+
+func TestHandleCreate(t *testing.T) {
+	log := logger.NewContextLogger("HandleCreate", "debug", logger.TextFormat)
+
+	cases := []struct { //nolint:wsl
+		name        string
+		ctx         context.Context
+		request     *command.CreateRequest
+		routerFunc  func() *routeroldmocks.IRepository
+		commandFunc func() *commandmocks.IRepository
+		asserts     func(*testing.T, command.Command, error, *commandmocks.IRepository, *recordermocks.IRecorder) bool
+	}{
+		{
+			name: "Happy path the command is queued for the tenant of the device",
+			ctx:  testContext("unit-test-role", 4),
+			request: &command.CreateRequest{
+				Type:   command.TypeSetReportingInterval,
+				Params: command.Params{Seconds: 60, Output: 2},
+			},
+			routerFunc: func() *routeroldmocks.IRepository {
+				return routerRepository(4)
+			},
+			commandFunc: func() *commandmocks.IRepository {
+				repositoryMock := &commandmocks.IRepository{}
+				repositoryMock.On("Create", mock.Anything, mock.MatchedBy(func(m *ocommand.Model) bool {
+					return m.IMEI == imei &&
+						m.TenantID == 4 &&
+						m.Status == ocommand.StatusQueued &&
+						m.CreatedBy == 7 &&
+						assert.ObjectsAreEqual(map[string]int{"seconds": 60}, m.Params)
+				})).Return(nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, res command.Command, err error, repo *commandmocks.IRepository, recorder *recordermocks.IRecorder) bool {
+				return assert.NoError(t, err) &&
+					assert.NotEmpty(t, res.ID) &&
+					assert.Equal(t, command.Params{Seconds: 60}, res.Params) &&
+					recorder.AssertCalled(t, "Record", mock.Anything, mock.MatchedBy(func(e audit.Event) bool {
+						return e.Resource == audit.ResourceCommand && e.Action == audit.ActionCreate && e.TargetID == res.ID
+					})) &&
+					repo.AssertExpectations(t)
+			},
+		},
+		{
+			name:    "Super admin reaches the devices of every tenant",
+			ctx:     testContext("unit-test-super-admin"),
+			request: &command.CreateRequest{Type: command.TypeReboot},
+			routerFunc: func() *routeroldmocks.IRepository {
+				return routerRepository(9)
+			},
+			commandFunc: func() *commandmocks.IRepository {
+				repositoryMock := &commandmocks.IRepository{}
+				repositoryMock.On("Create", mock.Anything, mock.Anything).Return(nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, res command.Command, err error, repo *commandmocks.IRepository, _ *recordermocks.IRecorder) bool {
+				return assert.NoError(t, err) &&
+					assert.Equal(t, 9, res.TenantID) &&
+					repo.AssertExpectations(t)
+			},
+		},
+		{
+			name:    "Device of another tenant is not found",
+			ctx:     testContext("unit-test-role", 4),
+			request: &command.CreateRequest{Type: command.TypeReboot},
+			routerFunc: func() *routeroldmocks.IRepository {
+				return routerRepository(9)
+			},
+			commandFunc: func() *commandmocks.IRepository {
+				return &commandmocks.IRepository{}
+			},
+			asserts: func(t *testing.T, _ command.Command, err error, repo *commandmocks.IRepository, recorder *recordermocks.IRecorder) bool {
+				return assert.True(t, terrors.Is(err, terrors.ErrNotFound)) &&
+					repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything) &&
+					recorder.AssertNotCalled(t, "Record", mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name:    "Unregistered device is not found",
+			ctx:     testContext("unit-test-role", 4),
+			request: &command.CreateRequest{Type: command.TypeReboot},
+			routerFunc: func() *routeroldmocks.IRepository {
+				routerMock := &routeroldmocks.IRepository{}
+				routerMock.On("FindByIMEI", mock.Anything, imei).
+					Return(&routerold.RouterModel{}, terrors.New(terrors.ErrNotFound, "Router information not found", map[string]string{}))
+				return routerMock
+			},
+			commandFunc: func() *commandmocks.IRepository {
+				return &commandmocks.IRepository{}
+			},
+			asserts: func(t *testing.T, _ command.Command, err error, repo *commandmocks.IRepository, _ *recordermocks.IRecorder) bool {
+				return assert.True(t, terrors.Is(err, terrors.ErrNotFound)) &&
+					assert.EqualError(t, err, "not_found: Device not found") &&
+					repo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name:    "Repository error is returned",
+			ctx:     testContext("unit-test-role", 4),
+			request: &command.CreateRequest{Type: command.TypeReboot},
+			routerFunc: func() *routeroldmocks.IRepository {
+				return routerRepository(4)
+			},
+			commandFunc: func() *commandmocks.IRepository {
+				repositoryMock := &commandmocks.IRepository{}
+				repositoryMock.On("Create", mock.Anything, mock.Anything).Return(errors.New("unit-test-error"))
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, _ command.Command, err error, _ *commandmocks.IRepository, recorder *recordermocks.IRecorder) bool {
+				return assert.Error(t, err) &&
+					recorder.AssertNotCalled(t, "Record", mock.Anything, mock.Anything)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := tc.commandFunc()
+			recorder := auditRecorder()
+			service := command.NewDefaultService(log, repo, tc.routerFunc(), recorder, opts)
+
+			res, err := service.HandleCreate(tc.ctx, imei, tc.request)
+			if !tc.asserts(t, res, err, repo, recorder) {
+				t.Errorf("Assert error on test = '%v'", tc.name)
+			}
+		})
+	}
+}
+
+func TestHandleRetrieve(t *testing.T) {
+	log := logger.NewContextLogger("HandleRetrieve", "debug", logger.TextFormat)
+
+	cases := []struct { //nolint:wsl
+		name        string
+		ctx         context.Context
+		filter      *command.FilterRequest
+		routerFunc  func() *routeroldmocks.IRepository
+		commandFunc func() *commandmocks.IRepository
+		asserts     func(*testing.T, pagination.PaginatedRes, error, *commandmocks.IRepository) bool
+	}{
+		{
+			name:   "Happy path the commands of the device are paginated",
+			ctx:    testContext("unit-test-role", 4),
+			filter: &command.FilterRequest{Status: ocommand.StatusSent},
+			routerFunc: func() *routeroldmocks.IRepository {
+				return routerRepository(4)
+			},
+			commandFunc: func() *commandmocks.IRepository {
+				repositoryMock := &commandmocks.IRepository{}
+				repositoryMock.On("Retrieve", mock.Anything, mock.MatchedBy(func(m *ocommand.Metadata) bool {
+					return m.IMEI == imei && m.Status == ocommand.StatusSent && m.Filter.Page == 1 && m.Filter.Size > 0
+				})).Return([]ocommand.Model{{ID: commandID, IMEI: imei, Status: ocommand.StatusSent}}, 1, 1, nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, res pagination.PaginatedRes, err error, repo *commandmocks.IRepository) bool {
+				data, ok := res.Data.([]command.Command)
+				return assert.NoError(t, err) &&
+					assert.True(t, ok) &&
+					assert.Len(t, data, 1) &&
+					assert.Equal(t, 1, res.Total) &&
+					repo.AssertExpectations(t)
+			},
+		},
+		{
+			name:   "Device of another tenant is not found",
+			ctx:    testContext("unit-test-role", 4),
+			filter: &command.FilterRequest{},
+			routerFunc: func() *routeroldmocks.IRepository {
+				return routerRepository(9)
+			},
+			commandFunc: func() *commandmocks.IRepository {
+				return &commandmocks.IRepository{}
+			},
+			asserts: func(t *testing.T, _ pagination.PaginatedRes, err error, repo *commandmocks.IRepository) bool {
+				return assert.True(t, terrors.Is(err, terrors.ErrNotFound)) &&
+					repo.AssertNotCalled(t, "Retrieve", mock.Anything, mock.Anything)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := tc.commandFunc()
+			service := command.NewDefaultService(log, repo, tc.routerFunc(), auditRecorder(), opts)
+
+			res, err := service.HandleRetrieve(tc.ctx, imei, tc.filter)
+			if !tc.asserts(t, res, err, repo) {
+				t.Errorf("Assert error on test = '%v'", tc.name)
+			}
+		})
+	}
+}
+
+func TestHandleFind(t *testing.T) {
+	log := logger.NewContextLogger("HandleFind", "debug", logger.TextFormat)
+
+	cases := []struct { //nolint:wsl
+		name        string
+		ctx         context.Context
+		routerFunc  func() *routeroldmocks.IRepository
+		commandFunc func() *commandmocks.IRepository
+		asserts     func(*testing.T, command.Command, error) bool
+	}{
+		{
+			name: "Happy path",
+			ctx:  testContext("unit-test-role", 4),
+			routerFunc: func() *routeroldmocks.IRepository {
+				return routerRepository(4)
+			},
+			commandFunc: func() *commandmocks.IRepository {
+				repositoryMock := &commandmocks.IRepository{}
+				repositoryMock.On("FindByID", mock.Anything, imei, commandID).
+					Return(&ocommand.Model{ID: commandID, IMEI: imei, Type: command.TypeReboot, Params: map[string]int{}}, nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, res command.Command, err error) bool {
+				return assert.NoError(t, err) &&
+					assert.Equal(t, commandID, res.ID)
+			},
+		},
+		{
+			name: "Command not found",
+			ctx:  testContext("unit-test-role", 4),
+			routerFunc: func() *routeroldmocks.IRepository {
+				return routerRepository(4)
+			},
+			commandFunc: func() *commandmocks.IRepository {
+				repositoryMock := &commandmocks.IRepository{}
+				repositoryMock.On("FindByID", mock.Anything, imei, commandID).
+					Return(nil, terrors.New(terrors.ErrNotFound, "Device command not found", map[string]string{}))
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, _ command.Command, err error) bool {
+				return assert.True(t, terrors.Is(err, terrors.ErrNotFound))
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := command.NewDefaultService(log, tc.commandFunc(), tc.routerFunc(), auditRecorder(), opts)
+
+			res, err := service.HandleFind(tc.ctx, imei, commandID)
+			if !tc.asserts(t, res, err) {
+				t.Errorf("Assert error on test = '%v'", tc.name)
+			}
+		})
+	}
+}
+
+func TestDeliver(t *testing.T) {
+	log := logger.NewContextLogger("Deliver", "debug", logger.TextFormat)
+	sentAt := time.Now().UTC()
+
+	cases := []struct { //nolint:wsl
+		name        string
+		acks        []string
+		opts        command.Opts
+		commandFunc func() *commandmocks.IRepository
+		asserts     func(*testing.T, []command.Command, *commandmocks.IRepository) bool
+	}{
+		{
+			name: "Happy path acknowledgements are stored before the pending commands are claimed",
+			acks: []string{commandID},
+			commandFunc: func() *commandmocks.IRepository {
+				repositoryMock := &commandmocks.IRepository{}
+				repositoryMock.On("Acknowledge", mock.Anything, imei, []string{commandID}, mock.Anything).Return(1, nil).Once()
+				repositoryMock.On("ClaimPending", mock.Anything, imei, mock.Anything, command.DefaultAckTimeout, command.DefaultMaxAttempts, command.DefaultMaxPerResponse).
+					Return([]ocommand.Model{{ID: "unit-test-command", Type: command.TypeReboot, Status: ocommand.StatusSent, SentAt: &sentAt}}, nil).Once()
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, res []command.Command, repo *commandmocks.IRepository) bool {
+				return assert.Len(t, res, 1) &&
+					assert.Equal(t, "unit-test-command", res[0].ID) &&
+					repo.AssertExpectations(t)
+			},
+		},
+		{
+			name: "No acknowledgements and nothing queued",
+			commandFunc: func() *commandmocks.IRepository {
+				repositoryMock := &commandmocks.IRepository{}
+				repositoryMock.On("ClaimPending", mock.Anything, imei, mock.Anything, command.DefaultAckTimeout, command.DefaultMaxAttempts, command.DefaultMaxPerResponse).Return([]ocommand.Model{}, nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, res []command.Command, repo *commandmocks.IRepository) bool {
+				return assert.Empty(t, res) &&
+					repo.AssertNotCalled(t, "Acknowledge", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name: "Configured ack timeout and attempts decide the redeliveries",
+			opts: command.Opts{AckTimeout: time.Minute, MaxAttempts: 5},
+			commandFunc: func() *commandmocks.IRepository {
+				repositoryMock := &commandmocks.IRepository{}
+				repositoryMock.On("ClaimPending", mock.Anything, imei, mock.Anything, time.Minute, 5, command.DefaultMaxPerResponse).
+					Return([]ocommand.Model{{ID: "unit-test-command", Type: command.TypeReboot, Status: ocommand.StatusSent, Attempts: 2, SentAt: &sentAt}}, nil).Once()
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, res []command.Command, repo *commandmocks.IRepository) bool {
+				return assert.Len(t, res, 1) &&
+					assert.Equal(t, 2, res[0].Attempts) &&
+					repo.AssertExpectations(t)
+			},
+		},
+		{
+			name: "Failures are not returned to the device",
+			acks: []string{commandID},
+			commandFunc: func() *commandmocks.IRepository {
+				repositoryMock := &commandmocks.IRepository{}
+				repositoryMock.On("Acknowledge", mock.Anything, imei, mock.Anything, mock.Anything).Return(0, errors.New("unit-test-error"))
+				repositoryMock.On("ClaimPending", mock.Anything, imei, mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, errors.New("unit-test-error"))
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, res []command.Command, repo *commandmocks.IRepository) bool {
+				return assert.Nil(t, res) &&
+					repo.AssertExpectations(t)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := tc.commandFunc()
+			service := command.NewDefaultService(log, repo, &routeroldmocks.IRepository{}, auditRecorder(), tc.opts)
+
+			res := service.Deliver(context.Background(), imei, tc.acks)
+			if !tc.asserts(t, res, repo) {
+				t.Errorf("Assert error on test = '%v'", tc.name)
+			}
+		})
+	}
+}
+
+func TestExpireUnacknowledged(t *testing.T) {
+	log := logger.NewContextLogger("ExpireUnacknowledged", "debug", logger.TextFormat)
+
+	repo := &commandmocks.IRepository{}
+	repo.On("Expire", mock.Anything, mock.Anything, command.DefaultAckTimeout, command.DefaultMaxAttempts).Return(2, nil).Once()
+	service := command.NewDefaultService(log, repo, &routeroldmocks.IRepository{}, auditRecorder(), command.Opts{})
+
+	service.ExpireUnacknowledged(context.Background())
+	repo.AssertExpectations(t)
+
+	failing := &commandmocks.IRepository{}
+	failing.On("Expire", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(0, errors.New("unit-test-error")).Once()
+	service = command.NewDefaultService(log, failing, &routeroldmocks.IRepository{}, auditRecorder(), command.Opts{})
+
+	assert.NotPanics(t, func() { service.ExpireUnacknowledged(context.Background()) })
+	failing.AssertExpectations(t)
+}
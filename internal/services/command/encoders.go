@@ -0,0 +1,62 @@
+package command
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+)
+
+// NewEncoder returns the encoder of a wire format, the commands are sent as json unless the
+// text format is requested
+func NewEncoder(format string) Encoder {
+	if format == FormatText {
+		return TextEncoder{}
+	}
+	return JSONEncoder{}
+}
+
+// JSONEncoder writes the commands as a json document
+type JSONEncoder struct{}
+
+// ContentType of the json wire format
+func (JSONEncoder) ContentType() string {
+	return "application/json"
+}
+
+// Encode writes {"commands":[...]} with the downlink of every command
+func (JSONEncoder) Encode(commands []Command) ([]byte, error) {
+	downlinks := make([]Downlink, 0, len(commands))
+	for _, command := range commands {
+		downlinks = append(downlinks, ToDownlink(command))
+	}
+
+	return json.Marshal(struct {
+		Commands []Downlink `json:"commands"`
+	}{Commands: downlinks})
+}
+
+// TextEncoder writes one CMD,<id>,<code>[,<args>] line per command: RBT reboots, INT,<seconds> sets the
+// reporting interval, OUT,<output>,<seconds> activates an output and ALM acknowledges the alarm
+type TextEncoder struct{}
+
+// ContentType of the text wire format
+func (TextEncoder) ContentType() string {
+	return "text/plain; charset=utf-8"
+}
+
+// Encode writes the line of every command
+func (TextEncoder) Encode(commands []Command) ([]byte, error) {
+	lines := make([]string, 0, len(commands))
+	for _, command := range commands {
+		fields := []string{"CMD", command.ID, textCodes[command.Type]}
+		switch command.Type {
+		case TypeSetReportingInterval:
+			fields = append(fields, strconv.Itoa(command.Params.Seconds))
+		case TypeActivateOutput:
+			fields = append(fields, strconv.Itoa(command.Params.Output), strconv.Itoa(command.Params.Seconds))
+		}
+		lines = append(lines, strings.Join(fields, ","))
+	}
+
+	return []byte(strings.Join(lines, "\n")), nil
+}
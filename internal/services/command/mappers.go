@@ -0,0 +1,178 @@
+package command
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/google/uuid"
+	"github.com/jmontesinos91/collector/domains/pagination"
+	"github.com/jmontesinos91/collector/domains/validation"
+	ocommand "github.com/jmontesinos91/collector/internal/repositories/command"
+	"github.com/jmontesinos91/terrors"
+)
+
+// ParseCreateRequest builds a new command request given the http body, the params each type needs are required
+func ParseCreateRequest(r *http.Request, validate *validator.Validate) (*CreateRequest, error) {
+	var request CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return nil, terrors.New(terrors.ErrBadRequest, "Invalid request body", map[string]string{})
+	}
+
+	errs := validation.Errors{}
+	if err := errs.Merge(validation.Struct(validate, request)); err != nil {
+		return nil, err
+	}
+
+	switch request.Type {
+	case TypeSetReportingInterval:
+		if request.Params.Seconds < MinReportingInterval {
+			errs.Add("params.seconds", "must be at least "+strconv.Itoa(MinReportingInterval))
+		}
+	case TypeActivateOutput:
+		if request.Params.Output < 1 {
+			errs.Add("params.output", "is required")
+		}
+	}
+
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	return &request, nil
+}
+
+// ParseFilterRequest builds the commands filter given http params
+func ParseFilterRequest(r *http.Request) (*FilterRequest, error) {
+	query := r.URL.Query()
+	errs := validation.Errors{}
+
+	fr := FilterRequest{
+		Status: query.Get("status"),
+	}
+
+	switch fr.Status {
+	case "", ocommand.StatusQueued, ocommand.StatusSent, ocommand.StatusAcknowledged, ocommand.StatusExpired:
+	default:
+		errs.Add("status", "must be one of: queued sent acknowledged expired")
+	}
+
+	if sizeStr := query.Get("size"); sizeStr != "" {
+		size, err := strconv.Atoi(sizeStr)
+		if err != nil {
+			errs.Add("size", "must be an integer")
+		}
+		fr.Filter.Size = size
+	}
+
+	if pageStr := query.Get("page"); pageStr != "" {
+		page, err := strconv.Atoi(pageStr)
+		if err != nil {
+			errs.Add("page", "must be an integer")
+		}
+		fr.Filter.Page = page
+	}
+
+	if countStr := query.Get("count"); countStr != "" {
+		count, err := strconv.ParseBool(countStr)
+		if err != nil {
+			errs.Add("count", "must be a boolean")
+		}
+		fr.Filter.Count = &count
+	}
+
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	return &fr, nil
+}
+
+// ParseAcks returns the command ids of the acknowledgements, each value may be a comma separated
+// list. The values that are not command ids are dropped
+func ParseAcks(values ...string) []string {
+	var acks []string
+	for _, value := range values {
+		for _, ack := range strings.Split(value, ",") {
+			ack = strings.TrimSpace(ack)
+			if uuid.Validate(ack) == nil {
+				acks = append(acks, ack)
+			}
+		}
+	}
+
+	return acks
+}
+
+// ToMetadata maps the properties of the service filter into repo filter
+func ToMetadata(imei string, filterRequest *FilterRequest) *ocommand.Metadata {
+	return &ocommand.Metadata{
+		IMEI:   imei,
+		Status: filterRequest.Status,
+		Filter: filterRequest.Filter,
+	}
+}
+
+// ToModelParams keeps the params read by the command type
+func ToModelParams(commandType string, params Params) map[string]int {
+	switch commandType {
+	case TypeSetReportingInterval:
+		return map[string]int{"seconds": params.Seconds}
+	case TypeActivateOutput:
+		return map[string]int{"output": params.Output, "seconds": params.Seconds}
+	default:
+		return map[string]int{}
+	}
+}
+
+// ToCommandSlice converts a command model slice into a serializable slice
+func ToCommandSlice(models []ocommand.Model) []Command {
+	commands := make([]Command, 0, len(models))
+	for _, model := range models {
+		commands = append(commands, ToCommand(model))
+	}
+
+	return commands
+}
+
+// ToCommand converts a model to a Command struct to be serialized
+func ToCommand(model ocommand.Model) Command {
+	return Command{
+		ID:       model.ID,
+		IMEI:     model.IMEI,
+		TenantID: model.TenantID,
+		Type:     model.Type,
+		Params: Params{
+			Seconds: model.Params["seconds"],
+			Output:  model.Params["output"],
+		},
+		Status:         model.Status,
+		Attempts:       model.Attempts,
+		CreatedBy:      model.CreatedBy,
+		CreatedAt:      model.CreatedAt,
+		SentAt:         model.SentAt,
+		AcknowledgedAt: model.AcknowledgedAt,
+		ExpiredAt:      model.ExpiredAt,
+	}
+}
+
+// ToDownlink converts a command into the form it is delivered to the device
+func ToDownlink(command Command) Downlink {
+	return Downlink{
+		ID:     command.ID,
+		Type:   command.Type,
+		Params: command.Params,
+	}
+}
+
+// ToPaginatedResponse creates a paginated response
+func ToPaginatedResponse(data interface{}, currentPage, pages, total int) pagination.PaginatedRes {
+	return pagination.PaginatedRes{
+		Data:        data,
+		CurrentPage: currentPage,
+		Pages:       pages,
+		Total:       total,
+	}
+}
@@ -0,0 +1,204 @@
+package command
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/jmontesinos91/collector/domains/pagination"
+	"github.com/jmontesinos91/collector/domains/validation"
+	ocommand "github.com/jmontesinos91/collector/internal/repositories/command"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCreateRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected *CreateRequest
+		errorMsg string
+	}{
+		{
+			name:     "Happy path reboot",
+			body:     `{"type":"reboot"}`,
+			expected: &CreateRequest{Type: TypeReboot},
+		},
+		{
+			name:     "Happy path reporting interval",
+			body:     `{"type":"set-reporting-interval","params":{"seconds":60}}`,
+			expected: &CreateRequest{Type: TypeSetReportingInterval, Params: Params{Seconds: 60}},
+		},
+		{
+			name:     "Invalid body",
+			body:     `{"type":`,
+			errorMsg: "Invalid request body",
+		},
+		{
+			name:     "Missing type",
+			body:     `{}`,
+			errorMsg: "Invalid type parameter, is required",
+		},
+		{
+			name:     "Unknown type",
+			body:     `{"type":"shutdown"}`,
+			errorMsg: "Invalid type parameter, must be one of",
+		},
+		{
+			name:     "Reporting interval too short",
+			body:     `{"type":"set-reporting-interval","params":{"seconds":5}}`,
+			errorMsg: "Invalid params.seconds parameter, must be at least 10",
+		},
+		{
+			name:     "Reporting interval too long",
+			body:     `{"type":"set-reporting-interval","params":{"seconds":90000}}`,
+			errorMsg: "Invalid params.seconds parameter, must be at most 86400",
+		},
+		{
+			name:     "Missing output",
+			body:     `{"type":"activate-output","params":{"seconds":30}}`,
+			errorMsg: "Invalid params.output parameter, is required",
+		},
+		{
+			name:     "Output out of range",
+			body:     `{"type":"activate-output","params":{"output":9}}`,
+			errorMsg: "Invalid params.output parameter, must be at most 8",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, "/v1/devices/860000000000001/commands", strings.NewReader(tt.body))
+
+			request, err := ParseCreateRequest(req, validation.New())
+			if tt.errorMsg != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+				assert.Nil(t, request)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, request)
+			}
+		})
+	}
+}
+
+func TestParseFilterRequest(t *testing.T) {
+	count := false
+	tests := []struct {
+		name        string
+		queryParams map[string]string
+		expected    *FilterRequest
+		errorMsg    string
+	}{
+		{
+			name: "Happy path valid parameters",
+			queryParams: map[string]string{
+				"status": ocommand.StatusQueued,
+				"page":   "2",
+				"size":   "20",
+				"count":  "false",
+			},
+			expected: &FilterRequest{
+				Status: ocommand.StatusQueued,
+				Filter: pagination.Filter{Page: 2, Size: 20, Count: &count},
+			},
+		},
+		{
+			name:        "Unknown status",
+			queryParams: map[string]string{"status": "failed"},
+			errorMsg:    "Invalid status parameter",
+		},
+		{
+			name:        "Invalid page",
+			queryParams: map[string]string{"page": "first"},
+			errorMsg:    "Invalid page parameter",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := url.Values{}
+			for key, value := range tt.queryParams {
+				query.Set(key, value)
+			}
+			req, _ := http.NewRequest(http.MethodGet, "/v1/devices/860000000000001/commands?"+query.Encode(), nil)
+
+			fr, err := ParseFilterRequest(req)
+			if tt.errorMsg != "" {
+				assert.ErrorContains(t, err, tt.errorMsg)
+				assert.Nil(t, fr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, fr)
+			}
+		})
+	}
+}
+
+func TestParseAcks(t *testing.T) {
+	id := "5b0e1c52-8a2d-4c4e-9a6f-3d1b7e2f4a90"
+
+	assert.Nil(t, ParseAcks())
+	assert.Nil(t, ParseAcks(""))
+	assert.Equal(t, []string{id}, ParseAcks(id))
+	assert.Equal(t, []string{id, id}, ParseAcks(id+", not-a-command,"+id))
+	assert.Equal(t, []string{id, id}, ParseAcks(id, "not-a-command", id))
+}
+
+func TestToModelParams(t *testing.T) {
+	params := Params{Seconds: 30, Output: 2}
+
+	assert.Equal(t, map[string]int{}, ToModelParams(TypeReboot, params))
+	assert.Equal(t, map[string]int{"seconds": 30}, ToModelParams(TypeSetReportingInterval, params))
+	assert.Equal(t, map[string]int{"output": 2, "seconds": 30}, ToModelParams(TypeActivateOutput, params))
+}
+
+func TestEncoders(t *testing.T) {
+	commands := []Command{
+		{ID: "a", IMEI: "860000000000001", Type: TypeReboot},
+		{ID: "b", IMEI: "860000000000001", Type: TypeSetReportingInterval, Params: Params{Seconds: 60}},
+		{ID: "c", IMEI: "860000000000001", Type: TypeActivateOutput, Params: Params{Output: 2, Seconds: 30}},
+		{ID: "d", IMEI: "860000000000001", Type: TypeAcknowledgeAlarm},
+	}
+
+	tests := []struct {
+		name        string
+		format      string
+		contentType string
+		expected    string
+	}{
+		{
+			name:        "JSON",
+			format:      FormatJSON,
+			contentType: "application/json",
+			expected: `{"commands":[{"id":"a","type":"reboot","params":{}},` +
+				`{"id":"b","type":"set-reporting-interval","params":{"seconds":60}},` +
+				`{"id":"c","type":"activate-output","params":{"seconds":30,"output":2}},` +
+				`{"id":"d","type":"acknowledge-alarm","params":{}}]}`,
+		},
+		{
+			name:        "Text",
+			format:      FormatText,
+			contentType: "text/plain; charset=utf-8",
+			expected:    "CMD,a,RBT\nCMD,b,INT,60\nCMD,c,OUT,2,30\nCMD,d,ALM",
+		},
+		{
+			name:        "Unknown formats fall back to json",
+			format:      "xml",
+			contentType: "application/json",
+			expected:    `{"commands":[{"id":"a","type":"reboot","params":{}}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encoder := NewEncoder(tt.format)
+			body, err := encoder.Encode(commands)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.contentType, encoder.ContentType())
+			assert.True(t, strings.HasPrefix(string(body), tt.expected), string(body))
+		})
+	}
+}
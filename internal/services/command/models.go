@@ -0,0 +1,61 @@
+package command
+
+import (
+	"time"
+
+	"github.com/jmontesinos91/collector/domains/pagination"
+	"github.com/jmontesinos91/collector/domains/tenancy"
+)
+
+// CreateRequest holds the body of a new device command
+type CreateRequest struct {
+	Type   string `json:"type" validate:"required,oneof=reboot set-reporting-interval activate-output acknowledge-alarm"`
+	Params Params `json:"params"`
+}
+
+// Params arguments of a command, each type only reads its own: seconds is the reporting interval of
+// set-reporting-interval and how long activate-output keeps the output on, zero keeps it on
+type Params struct {
+	Seconds int `json:"seconds,omitempty" validate:"min=0,max=86400"`
+	Output  int `json:"output,omitempty" validate:"min=0,max=8"`
+}
+
+// FilterRequest holds the http request params of the commands of a device
+type FilterRequest struct {
+	Status string            `json:"status,omitempty"`
+	Filter pagination.Filter `json:"filter,omitempty"`
+}
+
+// Command downlink command of a device
+type Command struct {
+	ID             string     `json:"id"`
+	IMEI           string     `json:"imei"`
+	TenantID       int        `json:"tenantId"`
+	Type           string     `json:"type"`
+	Params         Params     `json:"params"`
+	Status         string     `json:"status"`
+	Attempts       int        `json:"attempts"`
+	CreatedBy      int        `json:"createdBy"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	SentAt         *time.Time `json:"sentAt,omitempty"`
+	AcknowledgedAt *time.Time `json:"acknowledgedAt,omitempty"`
+	ExpiredAt      *time.Time `json:"expiredAt,omitempty"`
+}
+
+// Downlink command as it is delivered to the device
+type Downlink struct {
+	ID     string `json:"id"`
+	Type   string `json:"type"`
+	Params Params `json:"params"`
+}
+
+// Opts device commands options
+type Opts struct {
+	// MaxPerResponse commands delivered in a single collector response
+	MaxPerResponse int
+	// AckTimeout time a sent command waits for the device acknowledgement before it is delivered again
+	AckTimeout time.Duration
+	// MaxAttempts times a command is delivered before it is expired
+	MaxAttempts int
+	Tenancy     tenancy.Policy
+}
@@ -0,0 +1,26 @@
+package command
+
+import (
+	"context"
+
+	"github.com/jmontesinos91/collector/domains/pagination"
+)
+
+// IQueue Delivers the queued commands of a device, the collector depends on it to answer the frames
+type IQueue interface {
+	Deliver(ctx context.Context, imei string, acks []string) []Command
+}
+
+// IService Manage the downlink commands of the devices of the requesting user
+type IService interface {
+	IQueue
+	HandleCreate(ctx context.Context, imei string, request *CreateRequest) (Command, error)
+	HandleRetrieve(ctx context.Context, imei string, filter *FilterRequest) (pagination.PaginatedRes, error)
+	HandleFind(ctx context.Context, imei, commandID string) (Command, error)
+}
+
+// Encoder writes the commands delivered to a device in its wire format
+type Encoder interface {
+	ContentType() string
+	Encode(commands []Command) ([]byte, error)
+}
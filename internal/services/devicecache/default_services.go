@@ -0,0 +1,149 @@
+package devicecache
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jmontesinos91/collector/internal/repositories/routerold"
+	"github.com/jmontesinos91/collector/internal/repositories/unitsold"
+	"github.com/jmontesinos91/collector/internal/services/audit"
+	"github.com/jmontesinos91/oevents/broker"
+	"github.com/jmontesinos91/ologs/logger"
+	tracekey "github.com/jmontesinos91/ologs/logger/v2"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/jmontesinos91/terrors"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultService struct
+type DefaultService struct {
+	log         *logger.ContextLogger
+	routerCache routerold.ICache
+	unitCache   unitsold.ICache
+	auditor     audit.IRecorder
+	publisher   broker.MessagingBrokerProvider
+	subscriber  broker.MessagingBrokerProvider
+	opts        Opts
+}
+
+// NewDefaultService creates a new instance of DefaultService, the invalidations are published to
+// the other replicas and the ones they publish are read from the subscriber
+func NewDefaultService(l *logger.ContextLogger, rc routerold.ICache, uc unitsold.ICache, ar audit.IRecorder,
+	publisher broker.MessagingBrokerProvider, subscriber broker.MessagingBrokerProvider, opts Opts) *DefaultService {
+	return &DefaultService{
+		log:         l,
+		routerCache: rc,
+		unitCache:   uc,
+		auditor:     ar,
+		publisher:   publisher,
+		subscriber:  subscriber,
+		opts:        opts,
+	}
+}
+
+// HandleInvalidate Removes the requested entries from the legacy lookups cache
+func (s *DefaultService) HandleInvalidate(ctx context.Context, request *InvalidateRequest) (InvalidateResponse, error) {
+	requestID := ctx.Value(middleware.RequestIDKey).(string)
+	claims := ctx.Value(&sts.Claim).(sts.Claims)
+
+	if err := request.Validate(); err != nil {
+		return InvalidateResponse{}, err
+	}
+
+	invalidated := s.invalidate(request)
+
+	s.log.WithContext(logrus.InfoLevel,
+		"HandleInvalidate",
+		"Device lookups cache invalidated",
+		logger.Context{
+			tracekey.TrackingID: requestID,
+			tracekey.UserID:     claims.UserID,
+			tracekey.Role:       claims.Role,
+			"Invalidated":       invalidated,
+		},
+		nil)
+
+	response := InvalidateResponse{Invalidated: invalidated}
+	s.auditor.Record(ctx, audit.Event{
+		Action:   audit.ActionInvalidate,
+		Resource: audit.ResourceCache,
+		Before:   request,
+		After:    response,
+	})
+
+	if !s.publisher.Publish(ctx, s.opts.Topic, ToInvalidatedEvent(request, s.opts.Replica)) {
+		return response, terrors.New(terrors.ErrInternalService,
+			"The cache was invalidated on this replica only, retry to reach every replica", map[string]string{})
+	}
+
+	return response, nil
+}
+
+// RunInvalidations applies the invalidations broadcast by the other replicas until the context is done
+func (s *DefaultService) RunInvalidations(ctx context.Context) {
+	messages := make(chan broker.OmniViewMessage, max(s.opts.MaxRecords, 1))
+	s.subscriber.Subscribe(ctx, s.opts.MaxRecords, messages)
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case msg := <-messages:
+			s.applyInvalidation(msg)
+			msg.Ack.Done()
+		}
+	}
+}
+
+func (s *DefaultService) applyInvalidation(msg broker.OmniViewMessage) {
+	if msg.Event.EventType != InvalidatedEvent || msg.Event.Source == s.opts.Replica {
+		return
+	}
+
+	// The cache was empty when the replica started, a new consumer group reads the topic from the start
+	if at, err := time.Parse(time.RFC3339Nano, msg.Event.Timestamp); err == nil && at.Before(s.opts.StartedAt) {
+		return
+	}
+
+	request, err := ToInvalidateRequest(msg.Event.Data)
+	if err != nil {
+		s.log.Error(logrus.WarnLevel, "RunInvalidations", "Invalid cache invalidation event "+msg.Event.ID, err)
+		return
+	}
+
+	invalidated := s.invalidate(request)
+	s.log.WithContext(logrus.InfoLevel,
+		"RunInvalidations",
+		"Device lookups cache invalidated by another replica",
+		logger.Context{
+			"Replica":     msg.Event.Source,
+			"EventID":     msg.Event.ID,
+			"Invalidated": invalidated,
+		},
+		nil)
+}
+
+func (s *DefaultService) invalidate(request *InvalidateRequest) int {
+	if request.All {
+		return s.routerCache.Purge() + s.unitCache.Purge()
+	}
+
+	invalidated := 0
+	if request.IMEI != "" {
+		removed, routerID := s.routerCache.InvalidateIMEI(request.IMEI)
+		invalidated += removed
+		if routerID != 0 && routerID != request.RouterID {
+			invalidated += s.unitCache.InvalidateRouter(routerID)
+		}
+	}
+	if request.RouterID != 0 {
+		invalidated += s.routerCache.InvalidateID(request.RouterID)
+		invalidated += s.unitCache.InvalidateRouter(request.RouterID)
+	}
+	if request.UnitID != 0 {
+		invalidated += s.unitCache.InvalidateUnit(request.UnitID)
+	}
+
+	return invalidated
+}
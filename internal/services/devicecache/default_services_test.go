@@ -0,0 +1,281 @@
+package devicecache_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jmontesinos91/collector/internal/repositories/routerold"
+	"github.com/jmontesinos91/collector/internal/repositories/routerold/routeroldmocks"
+	"github.com/jmontesinos91/collector/internal/repositories/unitsold"
+	"github.com/jmontesinos91/collector/internal/repositories/unitsold/unitsoldmocks"
+	"github.com/jmontesinos91/collector/internal/services/audit/recordermocks"
+	"github.com/jmontesinos91/collector/internal/services/devicecache"
+	"github.com/jmontesinos91/oevents"
+	"github.com/jmontesinos91/oevents/broker"
+	"github.com/jmontesinos91/oevents/broker/brokermock"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/jmontesinos91/terrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestHandleInvalidate(t *testing.T) {
+	ctxBack := context.Background()
+	ctxBack = context.WithValue(ctxBack, middleware.RequestIDKey, "unit-test-request-id")
+	ctxBack = context.WithValue(ctxBack, &sts.Claim, sts.Claims{
+		UserID: 0,
+		Role:   "unit-test-role",
+	})
+	log := logger.NewContextLogger("HandleInvalidate", "debug", logger.TextFormat)
+
+	type assertsParams struct { //nolint:wsl
+		routerRepo *routeroldmocks.IRepository
+		unitsRepo  *unitsoldmocks.IRepository
+		router     *routerold.CachedRepository
+		units      *unitsold.CachedRepository
+		publisher  *brokermock.MessagingBrokerProvider
+		result     devicecache.InvalidateResponse
+	}
+
+	cases := []struct { //nolint:wsl
+		name      string
+		request   *devicecache.InvalidateRequest
+		published bool
+		asserts   func(*testing.T, error, assertsParams) bool
+	}{
+		{
+			name:      "Invalidate by IMEI evicts the router and its unit",
+			request:   &devicecache.InvalidateRequest{IMEI: "861585041440544"},
+			published: true,
+			asserts: func(t *testing.T, err error, ap assertsParams) bool {
+				_, errFind := ap.router.FindByIMEI(ctxBack, "861585041440544")
+				_, _ = ap.router.FindByID(ctxBack, 1)
+				_, _ = ap.units.FindByRouterID(ctxBack, 1)
+				_, _ = ap.units.FindByID(ctxBack, 10)
+				return assert.NoError(t, err) &&
+					assert.NoError(t, errFind) &&
+					assert.Equal(t, 4, ap.result.Invalidated) &&
+					ap.routerRepo.AssertNumberOfCalls(t, "FindByIMEI", 3) &&
+					ap.routerRepo.AssertNumberOfCalls(t, "FindByID", 2) &&
+					ap.unitsRepo.AssertNumberOfCalls(t, "FindByRouterID", 2) &&
+					ap.unitsRepo.AssertNumberOfCalls(t, "FindByID", 2) &&
+					ap.publisher.AssertCalled(t, "Publish", mock.Anything, "unit-test-topic",
+						mock.MatchedBy(func(e oevents.OmniViewEvent) bool {
+							return e.EventType == devicecache.InvalidatedEvent && e.Source == "unit-test-replica" &&
+								e.Data["imei"] == "861585041440544"
+						}))
+			},
+		},
+		{
+			name:      "Unknown IMEI stays negatively cached",
+			request:   &devicecache.InvalidateRequest{RouterID: 1},
+			published: true,
+			asserts: func(t *testing.T, err error, ap assertsParams) bool {
+				_, errFind := ap.router.FindByIMEI(ctxBack, "unknown")
+				_, _ = ap.router.FindByIMEI(ctxBack, "861585041440544")
+				_, _ = ap.units.FindByID(ctxBack, 10)
+				return assert.NoError(t, err) &&
+					assert.True(t, terrors.Is(errFind, terrors.ErrNotFound)) &&
+					assert.Equal(t, 4, ap.result.Invalidated) &&
+					ap.routerRepo.AssertNumberOfCalls(t, "FindByIMEI", 3) &&
+					ap.unitsRepo.AssertNumberOfCalls(t, "FindByID", 2)
+			},
+		},
+		{
+			name:      "All purges every entry",
+			request:   &devicecache.InvalidateRequest{All: true},
+			published: true,
+			asserts: func(t *testing.T, err error, ap assertsParams) bool {
+				_, errFind := ap.units.FindByRouterID(ctxBack, 1)
+				return assert.NoError(t, err) &&
+					assert.NoError(t, errFind) &&
+					assert.Equal(t, 5, ap.result.Invalidated) &&
+					ap.unitsRepo.AssertNumberOfCalls(t, "FindByRouterID", 2)
+			},
+		},
+		{
+			name:    "Empty request is rejected without purging",
+			request: &devicecache.InvalidateRequest{},
+			asserts: func(t *testing.T, err error, ap assertsParams) bool {
+				_, errFind := ap.units.FindByRouterID(ctxBack, 1)
+				return assert.True(t, terrors.Is(err, terrors.ErrBadRequest)) &&
+					assert.NoError(t, errFind) &&
+					ap.unitsRepo.AssertNumberOfCalls(t, "FindByRouterID", 1) &&
+					ap.publisher.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name:    "All combined with an entry is rejected",
+			request: &devicecache.InvalidateRequest{All: true, UnitID: 10},
+			asserts: func(t *testing.T, err error, ap assertsParams) bool {
+				return assert.True(t, terrors.Is(err, terrors.ErrBadRequest)) &&
+					ap.publisher.AssertNotCalled(t, "Publish", mock.Anything, mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name:    "Failed broadcast is reported after the local invalidation",
+			request: &devicecache.InvalidateRequest{IMEI: "861585041440544"},
+			asserts: func(t *testing.T, err error, ap assertsParams) bool {
+				return assert.True(t, terrors.Is(err, terrors.ErrInternalService)) &&
+					assert.Equal(t, 4, ap.result.Invalidated)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			routerRepo := &routeroldmocks.IRepository{}
+			routerRepo.On("FindByIMEI", mock.Anything, "861585041440544").
+				Return(&routerold.RouterModel{ID: 1, IMEI: "861585041440544"}, nil)
+			routerRepo.On("FindByIMEI", mock.Anything, "unknown").
+				Return(&routerold.RouterModel{}, terrors.New(terrors.ErrNotFound, "Router information not found", map[string]string{}))
+			routerRepo.On("FindByID", mock.Anything, 1).
+				Return(&routerold.RouterModel{ID: 1, IMEI: "861585041440544"}, nil)
+			unitsRepo := &unitsoldmocks.IRepository{}
+			unitsRepo.On("FindByRouterID", mock.Anything, 1).
+				Return(&unitsold.UnitsModel{ID: 10, RouterID: 1}, nil)
+			unitsRepo.On("FindByID", mock.Anything, 10).
+				Return(&unitsold.UnitsModel{ID: 10, RouterID: 1}, nil)
+
+			cachedRouter := routerold.NewCachedRepository(routerRepo, 10, time.Minute, time.Minute)
+			cachedUnits := unitsold.NewCachedRepository(unitsRepo, 10, time.Minute, time.Minute)
+
+			// Warm up the cache, repeated lookups must not reach the repositories
+			for i := 0; i < 2; i++ {
+				_, _ = cachedRouter.FindByIMEI(ctxBack, "861585041440544")
+				_, _ = cachedRouter.FindByIMEI(ctxBack, "unknown")
+				_, _ = cachedRouter.FindByID(ctxBack, 1)
+				_, _ = cachedUnits.FindByRouterID(ctxBack, 1)
+				_, _ = cachedUnits.FindByID(ctxBack, 10)
+			}
+
+			recorder := &recordermocks.IRecorder{}
+			recorder.On("Record", mock.Anything, mock.Anything).Return()
+
+			publisher := &brokermock.MessagingBrokerProvider{}
+			publisher.On("Publish", mock.Anything, mock.Anything, mock.Anything).Return(tc.published)
+
+			service := devicecache.NewDefaultService(log, cachedRouter, cachedUnits, recorder, publisher,
+				&brokermock.MessagingBrokerProvider{}, devicecache.Opts{Topic: "unit-test-topic", Replica: "unit-test-replica"})
+			result, err := service.HandleInvalidate(ctxBack, tc.request)
+
+			ap := assertsParams{
+				routerRepo: routerRepo,
+				unitsRepo:  unitsRepo,
+				router:     cachedRouter,
+				units:      cachedUnits,
+				publisher:  publisher,
+				result:     result,
+			}
+			if !tc.asserts(t, err, ap) {
+				t.Errorf("Assert error on test = '%v'", tc.name)
+			}
+		})
+	}
+}
+
+func TestRunInvalidations(t *testing.T) {
+	log := logger.NewContextLogger("RunInvalidations", "debug", logger.TextFormat)
+	startedAt := time.Now()
+
+	cases := []struct {
+		name    string
+		event   oevents.OmniViewEvent
+		asserts func(*testing.T, *routeroldmocks.IRepository) bool
+	}{
+		{
+			name:  "Invalidation of another replica is applied",
+			event: devicecache.ToInvalidatedEvent(&devicecache.InvalidateRequest{IMEI: "861585041440544"}, "other-replica"),
+			asserts: func(t *testing.T, routerRepo *routeroldmocks.IRepository) bool {
+				return routerRepo.AssertNumberOfCalls(t, "FindByIMEI", 2)
+			},
+		},
+		{
+			name:  "Purge of another replica is applied",
+			event: devicecache.ToInvalidatedEvent(&devicecache.InvalidateRequest{All: true}, "other-replica"),
+			asserts: func(t *testing.T, routerRepo *routeroldmocks.IRepository) bool {
+				return routerRepo.AssertNumberOfCalls(t, "FindByIMEI", 2)
+			},
+		},
+		{
+			name:  "Own invalidation is skipped",
+			event: devicecache.ToInvalidatedEvent(&devicecache.InvalidateRequest{IMEI: "861585041440544"}, "unit-test-replica"),
+			asserts: func(t *testing.T, routerRepo *routeroldmocks.IRepository) bool {
+				return routerRepo.AssertNumberOfCalls(t, "FindByIMEI", 1)
+			},
+		},
+		{
+			name: "Invalidation published before the replica started is skipped",
+			event: oevents.OmniViewEvent{
+				ID:        "unit-test-event",
+				Source:    "other-replica",
+				EventType: devicecache.InvalidatedEvent,
+				Timestamp: startedAt.Add(-time.Minute).UTC().Format(time.RFC3339Nano),
+				Data:      map[string]interface{}{"all": true},
+			},
+			asserts: func(t *testing.T, routerRepo *routeroldmocks.IRepository) bool {
+				return routerRepo.AssertNumberOfCalls(t, "FindByIMEI", 1)
+			},
+		},
+		{
+			name: "Invalidation without entries is skipped",
+			event: oevents.OmniViewEvent{
+				ID:        "unit-test-event",
+				Source:    "other-replica",
+				EventType: devicecache.InvalidatedEvent,
+				Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
+				Data:      map[string]interface{}{},
+			},
+			asserts: func(t *testing.T, routerRepo *routeroldmocks.IRepository) bool {
+				return routerRepo.AssertNumberOfCalls(t, "FindByIMEI", 1)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			routerRepo := &routeroldmocks.IRepository{}
+			routerRepo.On("FindByIMEI", mock.Anything, "861585041440544").
+				Return(&routerold.RouterModel{ID: 1, IMEI: "861585041440544"}, nil)
+			cachedRouter := routerold.NewCachedRepository(routerRepo, 10, time.Minute, time.Minute)
+			cachedUnits := unitsold.NewCachedRepository(&unitsoldmocks.IRepository{}, 10, time.Minute, time.Minute)
+			_, _ = cachedRouter.FindByIMEI(context.Background(), "861585041440544")
+
+			// The event is delivered as a single record batch, acknowledged once it is applied
+			var ack sync.WaitGroup
+			ack.Add(1)
+			subscriber := &brokermock.MessagingBrokerProvider{}
+			subscriber.On("Subscribe", mock.Anything, 100, mock.Anything).Run(func(args mock.Arguments) {
+				messages := args.Get(2).(chan<- broker.OmniViewMessage)
+				messages <- broker.OmniViewMessage{Event: tc.event, Ack: &ack}
+			}).Return()
+
+			service := devicecache.NewDefaultService(log, cachedRouter, cachedUnits, &recordermocks.IRecorder{},
+				&brokermock.MessagingBrokerProvider{}, subscriber, devicecache.Opts{
+					Topic:      "unit-test-topic",
+					Replica:    "unit-test-replica",
+					MaxRecords: 100,
+					StartedAt:  startedAt,
+				})
+
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan struct{})
+			go func() {
+				service.RunInvalidations(ctx)
+				close(done)
+			}()
+			ack.Wait()
+			cancel()
+			<-done
+
+			_, _ = cachedRouter.FindByIMEI(context.Background(), "861585041440544")
+			if !tc.asserts(t, routerRepo) {
+				t.Errorf("Assert error on test = '%v'", tc.name)
+			}
+		})
+	}
+}
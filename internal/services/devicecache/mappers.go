@@ -0,0 +1,92 @@
+package devicecache
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/jmontesinos91/oevents"
+	"github.com/jmontesinos91/terrors"
+)
+
+// ParseInvalidateRequest builds an invalidate request given http params
+func ParseInvalidateRequest(r *http.Request) (*InvalidateRequest, error) {
+	ir := InvalidateRequest{}
+	query := r.URL.Query()
+
+	if imei := query.Get("imei"); imei != "" {
+		ir.IMEI = imei
+	}
+
+	if routerIDStr := query.Get("routerId"); routerIDStr != "" {
+		routerID, err := strconv.Atoi(routerIDStr)
+		if err != nil {
+			return nil, terrors.New(terrors.ErrBadRequest, "Invalid routerId parameter", map[string]string{})
+		}
+		ir.RouterID = routerID
+	}
+
+	if unitIDStr := query.Get("unitId"); unitIDStr != "" {
+		unitID, err := strconv.Atoi(unitIDStr)
+		if err != nil {
+			return nil, terrors.New(terrors.ErrBadRequest, "Invalid unitId parameter", map[string]string{})
+		}
+		ir.UnitID = unitID
+	}
+
+	if allStr := query.Get("all"); allStr != "" {
+		all, err := strconv.ParseBool(allStr)
+		if err != nil {
+			return nil, terrors.New(terrors.ErrBadRequest, "Invalid all parameter", map[string]string{})
+		}
+		ir.All = all
+	}
+
+	return &ir, nil
+}
+
+// Validate checks the request targets specific entries or explicitly asks to purge every entry
+func (ir *InvalidateRequest) Validate() error {
+	targeted := ir.IMEI != "" || ir.RouterID != 0 || ir.UnitID != 0
+	if ir.All && targeted {
+		return terrors.New(terrors.ErrBadRequest, "The all parameter can not be combined with imei, routerId or unitId", map[string]string{})
+	}
+	if !ir.All && !targeted {
+		return terrors.New(terrors.ErrBadRequest, "Nothing to invalidate, set all=true to purge every entry", map[string]string{})
+	}
+
+	return nil
+}
+
+// ToInvalidatedEvent maps an invalidate request into the event broadcast to the replicas
+func ToInvalidatedEvent(request *InvalidateRequest, replica string) oevents.OmniViewEvent {
+	data := map[string]interface{}{}
+	if raw, err := json.Marshal(request); err == nil {
+		_ = json.Unmarshal(raw, &data)
+	}
+
+	return oevents.OmniViewEvent{
+		ID:        uuid.NewString(),
+		Source:    replica,
+		EventType: InvalidatedEvent,
+		Timestamp: time.Now().UTC().Format(time.RFC3339Nano),
+		Data:      data,
+	}
+}
+
+// ToInvalidateRequest maps the data of a broadcast invalidation into its request
+func ToInvalidateRequest(data map[string]interface{}) (*InvalidateRequest, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	request := InvalidateRequest{}
+	if err := json.Unmarshal(raw, &request); err != nil {
+		return nil, err
+	}
+
+	return &request, request.Validate()
+}
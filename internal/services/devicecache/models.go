@@ -0,0 +1,32 @@
+package devicecache
+
+import "time"
+
+// InvalidatedEvent type of the event broadcast to every replica when the cache is invalidated
+const InvalidatedEvent = "collector.cache.invalidated"
+
+// InvalidateRequest holds the entries to invalidate, every entry is purged only when All is set
+type InvalidateRequest struct {
+	IMEI     string `json:"imei,omitempty"`
+	RouterID int    `json:"routerId,omitempty"`
+	UnitID   int    `json:"unitId,omitempty"`
+	All      bool   `json:"all,omitempty"`
+}
+
+// InvalidateResponse holds the number of entries invalidated on the replica serving the request,
+// the other replicas invalidate theirs once they receive the broadcast
+type InvalidateResponse struct {
+	Invalidated int `json:"invalidated"`
+}
+
+// Opts device cache options
+type Opts struct {
+	// Topic where the invalidations are broadcast, every replica consumes it with its own group
+	Topic string
+	// Replica identifies this replica, it skips the invalidations it published itself
+	Replica string
+	// MaxRecords polled from the topic at once
+	MaxRecords int
+	// StartedAt invalidations published before the replica started are skipped, its cache was empty
+	StartedAt time.Time
+}
@@ -0,0 +1,9 @@
+package devicecache
+
+import "context"
+
+// IService Manage the legacy device lookups cache
+type IService interface {
+	HandleInvalidate(ctx context.Context, request *InvalidateRequest) (InvalidateResponse, error)
+	RunInvalidations(ctx context.Context)
+}
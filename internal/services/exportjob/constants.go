@@ -0,0 +1,20 @@
+package exportjob
+
+import "time"
+
+const (
+	// DefaultTTL time a completed export file is kept
+	DefaultTTL = 24 * time.Hour
+	// DefaultProgressEvery rows written between progress updates
+	DefaultProgressEvery = 5000
+	// DefaultLease time a running job is held by its worker without renewing it
+	DefaultLease = time.Minute
+	// DefaultMaxAttempts times a job is run before it is failed when its workers keep stopping
+	DefaultMaxAttempts = 3
+
+	failureTimeout = 5 * time.Second
+	// tmpSuffix suffix of an export file while it is being written
+	tmpSuffix = ".tmp"
+
+	abandonedReason = "Export interrupted, its workers stopped before it could complete"
+)
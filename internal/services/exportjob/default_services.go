@@ -0,0 +1,445 @@
+package exportjob
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/jmontesinos91/collector/domains/tenancy"
+	oexportjob "github.com/jmontesinos91/collector/internal/repositories/exportjob"
+	otraffic "github.com/jmontesinos91/collector/internal/repositories/traffic"
+	"github.com/jmontesinos91/collector/internal/services/audit"
+	"github.com/jmontesinos91/collector/internal/services/traffic"
+	"github.com/jmontesinos91/ologs/logger"
+	tracekey "github.com/jmontesinos91/ologs/logger/v2"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/jmontesinos91/terrors"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultService struct
+type DefaultService struct {
+	log         *logger.ContextLogger
+	jobRepo     oexportjob.IRepository
+	trafficRepo otraffic.IRepository
+	auditor     audit.IRecorder
+	opts        Opts
+}
+
+// NewDefaultService creates a new instance of DefaultService
+func NewDefaultService(l *logger.ContextLogger, jr oexportjob.IRepository, tr otraffic.IRepository, ar audit.IRecorder, opts Opts) *DefaultService {
+	if opts.ProgressEvery <= 0 {
+		opts.ProgressEvery = DefaultProgressEvery
+	}
+	if opts.TTL <= 0 {
+		opts.TTL = DefaultTTL
+	}
+	if opts.Lease <= 0 {
+		opts.Lease = DefaultLease
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = DefaultMaxAttempts
+	}
+
+	return &DefaultService{
+		log:         l,
+		jobRepo:     jr,
+		trafficRepo: tr,
+		auditor:     ar,
+		opts:        opts,
+	}
+}
+
+// HandleCreate Queues a new export job for the requesting user
+func (s *DefaultService) HandleCreate(ctx context.Context, request *CreateRequest) (Job, error) {
+	requestID := ctx.Value(middleware.RequestIDKey).(string)
+	claims := ctx.Value(&sts.Claim).(sts.Claims)
+
+	if _, err := traffic.ResolveExportColumns(request.Filter.Columns, s.opts.ExportColumns); err != nil {
+		return Job{}, err
+	}
+
+	// The scope is resolved now, the job runs without the claims of the requesting user
+	scope := s.opts.Tenancy.Scope(claims.Role, claims.Tenants)
+	now := time.Now().UTC()
+	model := &oexportjob.Model{
+		ID:         uuid.NewString(),
+		UserID:     claims.UserID,
+		Status:     oexportjob.StatusPending,
+		Format:     request.Filter.Format,
+		Query:      request.Query.Encode(),
+		AllTenants: scope.All,
+		Tenants:    scope.Tenants,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	err := s.jobRepo.Create(ctx, model)
+	if err != nil {
+		s.log.WithContext(logrus.ErrorLevel,
+			"HandleCreate",
+			"Failed to create export job",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+				tracekey.UserID:     claims.UserID,
+				tracekey.Role:       claims.Role,
+			},
+			err)
+		return Job{}, err
+	}
+
+	job := ToJob(*model)
+	s.auditor.Record(ctx, audit.Event{
+		Action:   audit.ActionCreate,
+		Resource: audit.ResourceExportJob,
+		TargetID: job.ID,
+		After:    job,
+	})
+
+	return job, nil
+}
+
+// HandleFind Retrieves the export jobs of the requesting user
+func (s *DefaultService) HandleFind(ctx context.Context) ([]Job, error) {
+	requestID := ctx.Value(middleware.RequestIDKey).(string)
+	claims := ctx.Value(&sts.Claim).(sts.Claims)
+
+	models, err := s.jobRepo.FindByUser(ctx, claims.UserID)
+	if err != nil {
+		s.log.WithContext(logrus.ErrorLevel,
+			"HandleFind",
+			"Failed to retrieve export jobs",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+				tracekey.UserID:     claims.UserID,
+				tracekey.Role:       claims.Role,
+			},
+			err)
+		return nil, err
+	}
+
+	return ToJobSlice(models), nil
+}
+
+// HandleRetrieve Retrieves the status and progress of an export job of the requesting user
+func (s *DefaultService) HandleRetrieve(ctx context.Context, jobID string) (Job, error) {
+	model, err := s.find(ctx, "HandleRetrieve", jobID)
+	if err != nil {
+		return Job{}, err
+	}
+
+	return ToJob(*model), nil
+}
+
+// HandleDownload Retrieves the export file of a completed job of the requesting user
+func (s *DefaultService) HandleDownload(ctx context.Context, jobID string) (*Download, error) {
+	model, err := s.find(ctx, "HandleDownload", jobID)
+	if err != nil {
+		return nil, err
+	}
+
+	switch model.Status {
+	case oexportjob.StatusCompleted:
+	case oexportjob.StatusExpired:
+		return nil, terrors.New(terrors.ErrNotFound, "Export file expired", map[string]string{})
+	default:
+		return nil, terrors.New(terrors.ErrPreconditionFailed, "Export job is not completed", map[string]string{
+			"status": model.Status,
+		})
+	}
+
+	if _, err := os.Stat(model.FilePath); err != nil {
+		return nil, terrors.New(terrors.ErrNotFound, "Export file not found", map[string]string{})
+	}
+
+	return &Download{
+		Name:        "traffic_" + fileName(model.ID, model.Format),
+		ContentType: contentType(model.Format),
+		path:        model.FilePath,
+	}, nil
+}
+
+func (s *DefaultService) find(ctx context.Context, method, jobID string) (*oexportjob.Model, error) {
+	requestID := ctx.Value(middleware.RequestIDKey).(string)
+	claims := ctx.Value(&sts.Claim).(sts.Claims)
+
+	if _, err := uuid.Parse(jobID); err != nil {
+		return nil, terrors.New(terrors.ErrBadRequest, "Invalid jobID", map[string]string{})
+	}
+
+	model, err := s.jobRepo.FindByID(ctx, jobID, claims.UserID)
+	if err != nil {
+		s.log.WithContext(logrus.ErrorLevel,
+			method,
+			"Failed to retrieve export job",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+				tracekey.UserID:     claims.UserID,
+				tracekey.Role:       claims.Role,
+				"JobID":             jobID,
+			},
+			err)
+		return nil, err
+	}
+
+	return model, nil
+}
+
+// ProcessPending Runs the pending export jobs one after another until the queue is empty, the jobs left
+// running by a stopped worker are run again until their attempts run out
+func (s *DefaultService) ProcessPending(ctx context.Context) {
+	s.failAbandoned(ctx)
+
+	for ctx.Err() == nil {
+		job, err := s.jobRepo.ClaimNext(ctx, time.Now().UTC(), s.opts.Lease, s.opts.MaxAttempts)
+		if err != nil {
+			s.log.Error(logrus.ErrorLevel, "ProcessPending", "Failed to claim export job", err)
+			return
+		}
+		if job == nil {
+			return
+		}
+
+		s.run(ctx, job)
+	}
+}
+
+// failAbandoned fails the jobs left running by a stopped worker once they have no attempts left
+func (s *DefaultService) failAbandoned(ctx context.Context) {
+	failed, err := s.jobRepo.FailAbandoned(ctx, time.Now().UTC(), s.opts.MaxAttempts, abandonedReason)
+	if err != nil {
+		s.log.Error(logrus.ErrorLevel, "ProcessPending", "Failed to fail abandoned export jobs", err)
+		return
+	}
+
+	for _, jobID := range failed {
+		logCtx := logger.Context{"JobID": jobID}
+		s.log.WithContext(logrus.WarnLevel, "ProcessPending", "Export job abandoned by its workers", logCtx, nil)
+		if err := s.removeLeftovers(jobID); err != nil {
+			s.log.WithContext(logrus.ErrorLevel, "ProcessPending", "Failed to remove export file", logCtx, err)
+		}
+	}
+}
+
+func (s *DefaultService) run(ctx context.Context, job *oexportjob.Model) {
+	logCtx := logger.Context{
+		tracekey.UserID: job.UserID,
+		"JobID":         job.ID,
+		"Attempt":       job.Attempts,
+	}
+
+	// The job is stopped when its lease is lost, another worker claimed it again
+	jobCtx, stopJob := context.WithCancel(ctx)
+	defer stopJob()
+	go s.renewLease(jobCtx, stopJob, job)
+
+	path := filePath(s.opts.Directory, job.ID, job.Attempts, job.Format)
+	rows, size, err := s.write(jobCtx, job, path)
+	if err != nil {
+		s.log.WithContext(logrus.ErrorLevel, "ProcessPending", "Export job failed", logCtx, err)
+
+		// The job context may be already canceled, the failure must be recorded anyway
+		failCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), failureTimeout)
+		defer cancel()
+		errFail := s.jobRepo.Fail(failCtx, job.ID, job.Attempts, failureReason(ctx, err))
+		if errFail != nil && !errors.Is(errFail, oexportjob.ErrLeaseLost) {
+			s.log.WithContext(logrus.ErrorLevel, "ProcessPending", "Failed to mark export job as failed", logCtx, errFail)
+		}
+		return
+	}
+
+	err = s.jobRepo.Complete(ctx, job.ID, job.Attempts, path, size, rows, time.Now().UTC().Add(s.opts.TTL))
+	if err != nil {
+		s.log.WithContext(logrus.ErrorLevel, "ProcessPending", "Failed to mark export job as completed", logCtx, err)
+		if errRemove := os.Remove(path); errRemove != nil {
+			s.log.WithContext(logrus.ErrorLevel, "ProcessPending", "Failed to remove export file", logCtx, errRemove)
+		}
+		return
+	}
+
+	logCtx["Rows"] = rows
+	s.log.WithContext(logrus.InfoLevel, "ProcessPending", "Export job completed", logCtx, nil)
+}
+
+// renewLease keeps extending the lease of a running job until the given context is done, the job is
+// stopped when another worker claimed it again
+func (s *DefaultService) renewLease(ctx context.Context, stopJob context.CancelFunc, job *oexportjob.Model) {
+	ticker := time.NewTicker(s.opts.Lease / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			err := s.jobRepo.RenewLease(ctx, job.ID, job.Attempts, time.Now().UTC().Add(s.opts.Lease))
+			if errors.Is(err, oexportjob.ErrLeaseLost) {
+				s.log.WithContext(logrus.WarnLevel, "ProcessPending", "Export job claimed again by another worker",
+					logger.Context{"JobID": job.ID, "Attempt": job.Attempts}, err)
+				stopJob()
+				return
+			}
+			if err != nil && ctx.Err() == nil {
+				s.log.WithContext(logrus.WarnLevel, "ProcessPending", "Failed to renew export job lease",
+					logger.Context{"JobID": job.ID, "Attempt": job.Attempts}, err)
+			}
+		}
+	}
+}
+
+// write streams the job rows into its gzip compressed export file, the file is written aside and only
+// moved into place once complete so a download never sees a partial export
+func (s *DefaultService) write(ctx context.Context, job *oexportjob.Model, path string) (int, int64, error) {
+	query, err := url.ParseQuery(job.Query)
+	if err != nil {
+		return 0, 0, err
+	}
+	filter, err := traffic.ParseFilterValues(query)
+	if err != nil {
+		return 0, 0, err
+	}
+	columns, err := traffic.ResolveExportColumns(filter.Columns, s.opts.ExportColumns)
+	if err != nil {
+		return 0, 0, err
+	}
+	metadata := traffic.ToMetadata(filter)
+	metadata.Scope = tenancy.Scope{All: job.AllTenants, Tenants: job.Tenants}
+
+	total, err := s.trafficRepo.CountData(ctx, metadata)
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := s.jobRepo.UpdateProgress(ctx, job.ID, job.Attempts, 0, total); err != nil {
+		return 0, 0, err
+	}
+
+	if err := os.MkdirAll(s.opts.Directory, 0o750); err != nil {
+		return 0, 0, err
+	}
+
+	// A job claimed again may have left the partial file of its previous attempt
+	if job.Attempts > 1 {
+		if err := s.removeLeftovers(job.ID); err != nil {
+			return 0, 0, err
+		}
+	}
+
+	tmpPath := path + tmpSuffix
+	file, err := os.Create(tmpPath)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer os.Remove(tmpPath) //nolint:errcheck
+	defer file.Close()       //nolint:errcheck
+
+	var w io.Writer = file
+	var gz *gzip.Writer
+	if job.Format != traffic.FormatXLSX {
+		gz = gzip.NewWriter(file)
+		w = gz
+	}
+
+	encoder, err := traffic.NewEncoder(job.Format, columns, w)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	rows := 0
+	err = s.trafficRepo.StreamData(ctx, metadata, func(model otraffic.Model) error {
+		if err := encoder.Encode(traffic.ToTraffic(model)); err != nil {
+			return err
+		}
+
+		rows++
+		if rows%s.opts.ProgressEvery == 0 {
+			return s.jobRepo.UpdateProgress(ctx, job.ID, job.Attempts, rows, total)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, 0, err
+	}
+
+	if err := encoder.Close(); err != nil {
+		return 0, 0, err
+	}
+	if gz != nil {
+		if err := gz.Close(); err != nil {
+			return 0, 0, err
+		}
+	}
+	if err := file.Close(); err != nil {
+		return 0, 0, err
+	}
+
+	info, err := os.Stat(tmpPath)
+	if err != nil {
+		return 0, 0, err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return 0, 0, err
+	}
+
+	return rows, info.Size(), nil
+}
+
+// removeLeftovers removes the partial files the attempts of a job left behind
+func (s *DefaultService) removeLeftovers(jobID string) error {
+	paths, err := filepath.Glob(filepath.Join(s.opts.Directory, jobID+"-*"+tmpSuffix))
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// CleanupExpired Removes the export files whose expiry already passed
+func (s *DefaultService) CleanupExpired(ctx context.Context) {
+	models, err := s.jobRepo.FindExpired(ctx, time.Now().UTC())
+	if err != nil {
+		s.log.Error(logrus.ErrorLevel, "CleanupExpired", "Failed to retrieve expired export jobs", err)
+		return
+	}
+
+	for _, model := range models {
+		logCtx := logger.Context{
+			tracekey.UserID: model.UserID,
+			"JobID":         model.ID,
+		}
+
+		if err := os.Remove(model.FilePath); err != nil && !os.IsNotExist(err) {
+			s.log.WithContext(logrus.ErrorLevel, "CleanupExpired", "Failed to remove export file", logCtx, err)
+			continue
+		}
+
+		if err := s.jobRepo.MarkExpired(ctx, model.ID); err != nil {
+			s.log.WithContext(logrus.ErrorLevel, "CleanupExpired", "Failed to mark export job as expired", logCtx, err)
+		}
+	}
+}
+
+func failureReason(ctx context.Context, err error) string {
+	if ctx.Err() != nil {
+		return "Export interrupted, the service was stopped"
+	}
+
+	var terr *terrors.Error
+	if errors.As(err, &terr) {
+		return terr.Message
+	}
+	return fmt.Sprintf("Export failed: %v", err)
+}
@@ -0,0 +1,434 @@
+package exportjob_test
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	oexportjob "github.com/jmontesinos91/collector/internal/repositories/exportjob"
+	"github.com/jmontesinos91/collector/internal/repositories/exportjob/exportjobmocks"
+	otraffic "github.com/jmontesinos91/collector/internal/repositories/traffic"
+	"github.com/jmontesinos91/collector/internal/repositories/traffic/trafficmocks"
+	"github.com/jmontesinos91/collector/internal/services/audit/recordermocks"
+	"github.com/jmontesinos91/collector/internal/services/exportjob"
+	"github.com/jmontesinos91/collector/internal/services/traffic"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/jmontesinos91/terrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const jobID = "5b0f7c0e-8a8b-4d43-9a55-0a7d0c6e8f11"
+
+func auditRecorder() *recordermocks.IRecorder {
+	recorder := &recordermocks.IRecorder{}
+	recorder.On("Record", mock.Anything, mock.Anything).Return()
+	return recorder
+}
+
+// writeFile creates a file with the given content in the directory and returns its path
+func writeFile(t *testing.T, dir, name, content string) string {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// files lists the names of the files in the directory
+func files(dir string) []string {
+	entries, _ := os.ReadDir(dir)
+	names := make([]string, 0, len(entries))
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	return names
+}
+
+func testContext() context.Context {
+	ctxBack := context.Background()
+	ctxBack = context.WithValue(ctxBack, middleware.RequestIDKey, "unit-test-request-id")
+	return context.WithValue(ctxBack, &sts.Claim, sts.Claims{
+		UserID:  7,
+		Role:    "unit-test-role",
+		Tenants: []int{3},
+	})
+}
+
+func TestHandleCreate(t *testing.T) {
+	ctxBack := testContext()
+	log := logger.NewContextLogger("HandleCreate", "debug", logger.TextFormat)
+
+	type assertsParams struct { //nolint:wsl
+		jobRepo *exportjobmocks.IRepository
+		result  exportjob.Job
+	}
+
+	cases := []struct { //nolint:wsl
+		name        string
+		request     *exportjob.CreateRequest
+		jobRepoFunc func() *exportjobmocks.IRepository
+		asserts     func(*testing.T, error, assertsParams) bool
+	}{
+		{
+			name: "Happy path the job is queued for the requesting user",
+			request: &exportjob.CreateRequest{
+				Filter: &traffic.FilterRequest{IMEI: "861585041440544", Format: traffic.FormatCSV},
+				Query:  url.Values{"imei": {"861585041440544"}},
+			},
+			jobRepoFunc: func() *exportjobmocks.IRepository {
+				repositoryMock := &exportjobmocks.IRepository{}
+				repositoryMock.On("Create", mock.Anything, mock.MatchedBy(func(m *oexportjob.Model) bool {
+					return m.UserID == 7 && m.Status == oexportjob.StatusPending && m.Query == "imei=861585041440544" &&
+						!m.AllTenants && assert.ObjectsAreEqual([]int{3}, m.Tenants)
+				})).Return(nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, err error, ap assertsParams) bool {
+				return assert.NoError(t, err) &&
+					assert.NotEmpty(t, ap.result.ID) &&
+					assert.Equal(t, oexportjob.StatusPending, ap.result.Status) &&
+					ap.jobRepo.AssertExpectations(t)
+			},
+		},
+		{
+			name: "Repository error",
+			request: &exportjob.CreateRequest{
+				Filter: &traffic.FilterRequest{Format: traffic.FormatXLSX},
+				Query:  url.Values{},
+			},
+			jobRepoFunc: func() *exportjobmocks.IRepository {
+				repositoryMock := &exportjobmocks.IRepository{}
+				repositoryMock.On("Create", mock.Anything, mock.Anything).
+					Return(terrors.InternalService("create_export_job", "Failed to create export job in the database", map[string]string{}))
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, err error, ap assertsParams) bool {
+				return assert.Error(t, err) &&
+					ap.jobRepo.AssertExpectations(t)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			jobRepo := tc.jobRepoFunc()
+			service := exportjob.NewDefaultService(log, jobRepo, &trafficmocks.IRepository{}, auditRecorder(), exportjob.Opts{})
+			result, err := service.HandleCreate(ctxBack, tc.request)
+
+			if !tc.asserts(t, err, assertsParams{jobRepo: jobRepo, result: result}) {
+				t.Errorf("Assert error on test = '%v'", tc.name)
+			}
+		})
+	}
+}
+
+func TestHandleDownload(t *testing.T) {
+	ctxBack := testContext()
+	log := logger.NewContextLogger("HandleDownload", "debug", logger.TextFormat)
+
+	cases := []struct { //nolint:wsl
+		name        string
+		jobID       string
+		jobRepoFunc func(dir string) *exportjobmocks.IRepository
+		asserts     func(*testing.T, error, *exportjob.Download) bool
+	}{
+		{
+			name:  "Happy path completed job",
+			jobID: jobID,
+			jobRepoFunc: func(dir string) *exportjobmocks.IRepository {
+				path := writeFile(t, dir, jobID+"-1.csv.gz", "content")
+				repositoryMock := &exportjobmocks.IRepository{}
+				repositoryMock.On("FindByID", mock.Anything, jobID, 7).
+					Return(&oexportjob.Model{ID: jobID, Status: oexportjob.StatusCompleted, Format: traffic.FormatCSV, FilePath: path}, nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, err error, d *exportjob.Download) bool {
+				var sb strings.Builder
+				return assert.NoError(t, err) &&
+					assert.Equal(t, "traffic_"+jobID+".csv.gz", d.Name) &&
+					assert.NoError(t, d.Write(&sb)) &&
+					assert.Equal(t, "content", sb.String())
+			},
+		},
+		{
+			name:  "Missing file",
+			jobID: jobID,
+			jobRepoFunc: func(dir string) *exportjobmocks.IRepository {
+				repositoryMock := &exportjobmocks.IRepository{}
+				repositoryMock.On("FindByID", mock.Anything, jobID, 7).
+					Return(&oexportjob.Model{ID: jobID, Status: oexportjob.StatusCompleted, Format: traffic.FormatCSV,
+						FilePath: filepath.Join(dir, jobID+"-1.csv.gz")}, nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, err error, d *exportjob.Download) bool {
+				return assert.True(t, terrors.Is(err, terrors.ErrNotFound)) &&
+					assert.Nil(t, d)
+			},
+		},
+		{
+			name:  "Job still running",
+			jobID: jobID,
+			jobRepoFunc: func(_ string) *exportjobmocks.IRepository {
+				repositoryMock := &exportjobmocks.IRepository{}
+				repositoryMock.On("FindByID", mock.Anything, jobID, 7).
+					Return(&oexportjob.Model{ID: jobID, Status: oexportjob.StatusRunning}, nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, err error, d *exportjob.Download) bool {
+				return assert.True(t, terrors.Is(err, terrors.ErrPreconditionFailed)) &&
+					assert.Nil(t, d)
+			},
+		},
+		{
+			name:  "Expired job",
+			jobID: jobID,
+			jobRepoFunc: func(_ string) *exportjobmocks.IRepository {
+				repositoryMock := &exportjobmocks.IRepository{}
+				repositoryMock.On("FindByID", mock.Anything, jobID, 7).
+					Return(&oexportjob.Model{ID: jobID, Status: oexportjob.StatusExpired}, nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, err error, d *exportjob.Download) bool {
+				return assert.True(t, terrors.Is(err, terrors.ErrNotFound)) &&
+					assert.Nil(t, d)
+			},
+		},
+		{
+			name:  "Invalid job id",
+			jobID: "not-a-uuid",
+			jobRepoFunc: func(_ string) *exportjobmocks.IRepository {
+				return &exportjobmocks.IRepository{}
+			},
+			asserts: func(t *testing.T, err error, d *exportjob.Download) bool {
+				return assert.True(t, terrors.Is(err, terrors.ErrBadRequest)) &&
+					assert.Nil(t, d)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := exportjob.NewDefaultService(log, tc.jobRepoFunc(t.TempDir()), &trafficmocks.IRepository{}, auditRecorder(), exportjob.Opts{})
+			result, err := service.HandleDownload(ctxBack, tc.jobID)
+
+			if !tc.asserts(t, err, result) {
+				t.Errorf("Assert error on test = '%v'", tc.name)
+			}
+		})
+	}
+}
+
+func TestProcessPending(t *testing.T) {
+	log := logger.NewContextLogger("ProcessPending", "debug", logger.TextFormat)
+	rows := []otraffic.Model{
+		{ID: "1", IMEI: "861585041440544", Counter: 3, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		{ID: "2", IMEI: "861585041440545", Counter: 1, CreatedAt: time.Now(), UpdatedAt: time.Now()},
+	}
+	streamRows := func() *trafficmocks.IRepository {
+		repositoryMock := &trafficmocks.IRepository{}
+		repositoryMock.On("CountData", mock.Anything, mock.Anything).Return(2, nil)
+		repositoryMock.On("StreamData", mock.Anything, mock.Anything, mock.Anything).
+			Return(func(_ context.Context, _ *otraffic.Metadata, fn func(otraffic.Model) error) error {
+				for _, row := range rows {
+					if err := fn(row); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+		return repositoryMock
+	}
+
+	type assertsParams struct { //nolint:wsl
+		jobRepo *exportjobmocks.IRepository
+		dir     string
+	}
+
+	cases := []struct { //nolint:wsl
+		name            string
+		jobRepoFunc     func(dir string) *exportjobmocks.IRepository
+		trafficRepoFunc func() *trafficmocks.IRepository
+		asserts         func(*testing.T, assertsParams) bool
+	}{
+		{
+			name: "Happy path the rows are written into a compressed file",
+			jobRepoFunc: func(dir string) *exportjobmocks.IRepository {
+				path := filepath.Join(dir, jobID+"-1.csv.gz")
+				repositoryMock := &exportjobmocks.IRepository{}
+				repositoryMock.On("FailAbandoned", mock.Anything, mock.Anything, exportjob.DefaultMaxAttempts, mock.Anything).
+					Return(nil, nil)
+				repositoryMock.On("ClaimNext", mock.Anything, mock.Anything, exportjob.DefaultLease, exportjob.DefaultMaxAttempts).
+					Return(&oexportjob.Model{ID: jobID, UserID: 7, Format: traffic.FormatCSV, Query: "columns=id%2Cimei", Tenants: []int{3}, Attempts: 1}, nil).Once()
+				repositoryMock.On("ClaimNext", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil).Once()
+				repositoryMock.On("UpdateProgress", mock.Anything, jobID, 1, mock.Anything, 2).Return(nil)
+				repositoryMock.On("Complete", mock.Anything, jobID, 1, path, mock.MatchedBy(func(size int64) bool {
+					info, err := os.Stat(path)
+					return err == nil && size == info.Size()
+				}), 2, mock.Anything).Return(nil)
+				return repositoryMock
+			},
+			trafficRepoFunc: func() *trafficmocks.IRepository {
+				repositoryMock := &trafficmocks.IRepository{}
+				repositoryMock.On("CountData", mock.Anything, mock.MatchedBy(func(m *otraffic.Metadata) bool {
+					return m.Scope.Allows(3) && !m.Scope.Allows(4)
+				})).Return(2, nil)
+				repositoryMock.On("StreamData", mock.Anything, mock.Anything, mock.Anything).
+					Return(func(_ context.Context, _ *otraffic.Metadata, fn func(otraffic.Model) error) error {
+						for _, row := range rows {
+							if err := fn(row); err != nil {
+								return err
+							}
+						}
+						return nil
+					})
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, ap assertsParams) bool {
+				file, err := os.Open(filepath.Join(ap.dir, jobID+"-1.csv.gz"))
+				if !assert.NoError(t, err) {
+					return false
+				}
+				defer file.Close() //nolint:errcheck
+				reader, err := gzip.NewReader(file)
+				if !assert.NoError(t, err) {
+					return false
+				}
+				content, err := io.ReadAll(reader)
+
+				return assert.NoError(t, err) &&
+					assert.Equal(t, "ID,IMEI\n1,861585041440544\n2,861585041440545\n", string(content)) &&
+					assert.Equal(t, []string{jobID + "-1.csv.gz"}, files(ap.dir)) &&
+					ap.jobRepo.AssertExpectations(t)
+			},
+		},
+		{
+			name: "A job claimed again drops the partial file of its previous attempt",
+			jobRepoFunc: func(dir string) *exportjobmocks.IRepository {
+				writeFile(t, dir, jobID+"-1.csv.gz.tmp", "partial")
+				repositoryMock := &exportjobmocks.IRepository{}
+				repositoryMock.On("FailAbandoned", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+				repositoryMock.On("ClaimNext", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+					Return(&oexportjob.Model{ID: jobID, UserID: 7, Format: traffic.FormatCSV, Query: "columns=id", Attempts: 2}, nil).Once()
+				repositoryMock.On("ClaimNext", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil).Once()
+				repositoryMock.On("UpdateProgress", mock.Anything, jobID, 2, mock.Anything, 2).Return(nil)
+				repositoryMock.On("Complete", mock.Anything, jobID, 2, filepath.Join(dir, jobID+"-2.csv.gz"),
+					mock.Anything, 2, mock.Anything).Return(nil)
+				return repositoryMock
+			},
+			trafficRepoFunc: streamRows,
+			asserts: func(t *testing.T, ap assertsParams) bool {
+				return assert.Equal(t, []string{jobID + "-2.csv.gz"}, files(ap.dir)) &&
+					ap.jobRepo.AssertExpectations(t)
+			},
+		},
+		{
+			name: "A worker that lost its lease drops its file",
+			jobRepoFunc: func(_ string) *exportjobmocks.IRepository {
+				repositoryMock := &exportjobmocks.IRepository{}
+				repositoryMock.On("FailAbandoned", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+				repositoryMock.On("ClaimNext", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+					Return(&oexportjob.Model{ID: jobID, UserID: 7, Format: traffic.FormatCSV, Query: "columns=id", Attempts: 1}, nil).Once()
+				repositoryMock.On("ClaimNext", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil).Once()
+				repositoryMock.On("UpdateProgress", mock.Anything, jobID, 1, mock.Anything, 2).Return(nil)
+				repositoryMock.On("Complete", mock.Anything, jobID, 1, mock.Anything, mock.Anything, 2, mock.Anything).
+					Return(oexportjob.ErrLeaseLost)
+				return repositoryMock
+			},
+			trafficRepoFunc: streamRows,
+			asserts: func(t *testing.T, ap assertsParams) bool {
+				return assert.Empty(t, files(ap.dir)) &&
+					ap.jobRepo.AssertExpectations(t)
+			},
+		},
+		{
+			name: "Jobs abandoned without attempts left are failed and their files dropped",
+			jobRepoFunc: func(dir string) *exportjobmocks.IRepository {
+				writeFile(t, dir, jobID+"-3.csv.gz.tmp", "partial")
+				repositoryMock := &exportjobmocks.IRepository{}
+				repositoryMock.On("FailAbandoned", mock.Anything, mock.Anything, exportjob.DefaultMaxAttempts, mock.Anything).
+					Return([]string{jobID}, nil)
+				repositoryMock.On("ClaimNext", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil).Once()
+				return repositoryMock
+			},
+			trafficRepoFunc: func() *trafficmocks.IRepository {
+				return &trafficmocks.IRepository{}
+			},
+			asserts: func(t *testing.T, ap assertsParams) bool {
+				return assert.Empty(t, files(ap.dir)) &&
+					ap.jobRepo.AssertExpectations(t)
+			},
+		},
+		{
+			name: "Stream error marks the job as failed",
+			jobRepoFunc: func(_ string) *exportjobmocks.IRepository {
+				repositoryMock := &exportjobmocks.IRepository{}
+				repositoryMock.On("FailAbandoned", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil)
+				repositoryMock.On("ClaimNext", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+					Return(&oexportjob.Model{ID: jobID, UserID: 7, Format: traffic.FormatXLSX, Attempts: 1}, nil).Once()
+				repositoryMock.On("ClaimNext", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(nil, nil).Once()
+				repositoryMock.On("UpdateProgress", mock.Anything, jobID, 1, 0, 2).Return(nil)
+				repositoryMock.On("Fail", mock.Anything, jobID, 1, "Error retrieving traffics from the database").Return(nil)
+				return repositoryMock
+			},
+			trafficRepoFunc: func() *trafficmocks.IRepository {
+				repositoryMock := &trafficmocks.IRepository{}
+				repositoryMock.On("CountData", mock.Anything, mock.Anything).Return(2, nil)
+				repositoryMock.On("StreamData", mock.Anything, mock.Anything, mock.Anything).
+					Return(terrors.InternalService("stream_error", "Error retrieving traffics from the database", map[string]string{}))
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, ap assertsParams) bool {
+				return assert.Empty(t, files(ap.dir)) &&
+					ap.jobRepo.AssertNotCalled(t, "Complete", mock.Anything, mock.Anything, mock.Anything, mock.Anything,
+						mock.Anything, mock.Anything, mock.Anything) &&
+					ap.jobRepo.AssertExpectations(t)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			dir := t.TempDir()
+			jobRepo := tc.jobRepoFunc(dir)
+			service := exportjob.NewDefaultService(log, jobRepo, tc.trafficRepoFunc(), auditRecorder(), exportjob.Opts{Directory: dir})
+			service.ProcessPending(context.Background())
+
+			if !tc.asserts(t, assertsParams{jobRepo: jobRepo, dir: dir}) {
+				t.Errorf("Assert error on test = '%v'", tc.name)
+			}
+		})
+	}
+}
+
+func TestCleanupExpired(t *testing.T) {
+	log := logger.NewContextLogger("CleanupExpired", "debug", logger.TextFormat)
+	dir := t.TempDir()
+	path := writeFile(t, dir, jobID+"-1.csv.gz", "content")
+	// A directory with content can not be removed
+	failing := filepath.Join(dir, "failing")
+	if err := os.Mkdir(failing, 0o750); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, failing, "content", "content")
+
+	jobRepo := &exportjobmocks.IRepository{}
+	jobRepo.On("FindExpired", mock.Anything, mock.Anything).
+		Return([]oexportjob.Model{{ID: jobID, FilePath: path}, {ID: "failing", FilePath: failing}}, nil)
+	jobRepo.On("MarkExpired", mock.Anything, jobID).Return(nil)
+
+	service := exportjob.NewDefaultService(log, jobRepo, &trafficmocks.IRepository{}, auditRecorder(), exportjob.Opts{Directory: dir})
+	service.CleanupExpired(context.Background())
+
+	jobRepo.AssertExpectations(t)
+	jobRepo.AssertNotCalled(t, "MarkExpired", mock.Anything, "failing")
+	assert.NoFileExists(t, path)
+}
@@ -0,0 +1,104 @@
+package exportjob
+
+import (
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"time"
+
+	oexportjob "github.com/jmontesinos91/collector/internal/repositories/exportjob"
+	"github.com/jmontesinos91/collector/internal/services/traffic"
+)
+
+// ParseCreateRequest builds an export job request given http params, the filters are validated
+// with the same rules as the traffic listing
+func ParseCreateRequest(r *http.Request) (*CreateRequest, error) {
+	query := r.URL.Query()
+
+	filter, err := traffic.ParseFilterValues(query)
+	if err != nil {
+		return nil, err
+	}
+	if filter.Format == "" {
+		filter.Format = traffic.FormatCSV
+	}
+
+	// Pagination does not apply to exports
+	query.Del("page")
+	query.Del("size")
+
+	// Relative date ranges are pinned to the creation time, the job may run much later
+	for param, value := range map[string]*time.Time{
+		"createdFrom": filter.CreatedFrom,
+		"createdTo":   filter.CreatedTo,
+		"updatedFrom": filter.UpdatedFrom,
+		"updatedTo":   filter.UpdatedTo,
+	} {
+		if value != nil {
+			query.Set(param, value.Format(time.RFC3339Nano))
+		}
+	}
+
+	return &CreateRequest{
+		Filter: filter,
+		Query:  query,
+	}, nil
+}
+
+// ToJobSlice converts an export job model slice into a serializable slice
+func ToJobSlice(models []oexportjob.Model) []Job {
+	jobs := make([]Job, 0, len(models))
+	for _, model := range models {
+		jobs = append(jobs, ToJob(model))
+	}
+
+	return jobs
+}
+
+// ToJob converts a model to a Job struct to be serialized
+func ToJob(model oexportjob.Model) Job {
+	progress := 0.0
+	switch {
+	case model.Status == oexportjob.StatusCompleted || model.Status == oexportjob.StatusExpired:
+		progress = 100
+	case model.TotalRows > 0:
+		progress = float64(model.RowsWritten) * 100 / float64(model.TotalRows)
+	}
+
+	return Job{
+		ID:          model.ID,
+		Status:      model.Status,
+		Format:      model.Format,
+		RowsWritten: model.RowsWritten,
+		TotalRows:   model.TotalRows,
+		Progress:    progress,
+		FileSize:    model.FileSize,
+		Error:       model.Error,
+		CreatedAt:   model.CreatedAt,
+		UpdatedAt:   model.UpdatedAt,
+		CompletedAt: model.CompletedAt,
+		ExpiresAt:   model.ExpiresAt,
+	}
+}
+
+// fileName name of the export file of a job, CSV files are gzip compressed
+// while XLSX files are already a zip archive
+func fileName(jobID, format string) string {
+	if format == traffic.FormatXLSX {
+		return fmt.Sprintf("%s.%s", jobID, traffic.FormatXLSX)
+	}
+	return fmt.Sprintf("%s.%s.gz", jobID, traffic.FormatCSV)
+}
+
+// filePath file of an attempt of a job, each attempt writes its own so a worker that lost the job
+// can not overwrite the file of the attempt that replaced it
+func filePath(directory, jobID string, attempt int, format string) string {
+	return filepath.Join(directory, fileName(fmt.Sprintf("%s-%d", jobID, attempt), format))
+}
+
+func contentType(format string) string {
+	if format == traffic.FormatXLSX {
+		return traffic.ContentType(format)
+	}
+	return "application/gzip"
+}
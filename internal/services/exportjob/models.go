@@ -0,0 +1,66 @@
+package exportjob
+
+import (
+	"io"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/jmontesinos91/collector/domains/tenancy"
+	"github.com/jmontesinos91/collector/internal/services/traffic"
+)
+
+// CreateRequest holds the filters of a new export job, in the traffic listing syntax
+type CreateRequest struct {
+	Filter *traffic.FilterRequest
+	Query  url.Values
+}
+
+// Job export job status
+type Job struct {
+	ID          string     `json:"id"`
+	Status      string     `json:"status"`
+	Format      string     `json:"format"`
+	RowsWritten int        `json:"rowsWritten"`
+	TotalRows   int        `json:"totalRows"`
+	Progress    float64    `json:"progress"`
+	FileSize    int64      `json:"fileSize,omitempty"`
+	Error       string     `json:"error,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+}
+
+// Download a completed export file ready to be sent
+type Download struct {
+	Name        string
+	ContentType string
+	path        string
+}
+
+// Opts export jobs service options
+type Opts struct {
+	// Directory the export files are written to, it must be shared by every instance running the jobs
+	// since any of them may serve the download
+	Directory     string
+	TTL           time.Duration
+	ProgressEvery int
+	// Lease time a running job is held by its worker, it is renewed while the job runs
+	Lease         time.Duration
+	MaxAttempts   int
+	ExportColumns []string
+	Tenancy       tenancy.Policy
+}
+
+// Write copies the export file into the writer
+func (d *Download) Write(w io.Writer) error {
+	file, err := os.Open(d.path)
+	if err != nil {
+		return err
+	}
+	defer file.Close() //nolint:errcheck
+
+	_, err = io.Copy(w, file)
+	return err
+}
@@ -0,0 +1,11 @@
+package exportjob
+
+import "context"
+
+// IService Manage the asynchronous traffic export jobs of the requesting user
+type IService interface {
+	HandleCreate(ctx context.Context, request *CreateRequest) (Job, error)
+	HandleFind(ctx context.Context) ([]Job, error)
+	HandleRetrieve(ctx context.Context, jobID string) (Job, error)
+	HandleDownload(ctx context.Context, jobID string) (*Download, error)
+}
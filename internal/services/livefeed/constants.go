@@ -0,0 +1,27 @@
+package livefeed
+
+import "time"
+
+// Event types pushed to the live feed subscribers
+const (
+	// EventFrame a device frame stored as regular traffic
+	EventFrame = "frame"
+	// EventAlarm a device frame stored as an alarm
+	EventAlarm = "alarm"
+	// EventCounterReset the counter of one or many traffics was reset
+	EventCounterReset = "counter-reset"
+	// EventExcessiveTraffic a device crossed its frames threshold
+	EventExcessiveTraffic = "excessive-traffic"
+	// EventDropped events were dropped because the subscriber could not keep up
+	EventDropped = "dropped"
+	// EventHeartbeat sent while there are no events to keep the connection alive
+	EventHeartbeat = "heartbeat"
+)
+
+// Live feed defaults values
+const (
+	// DefaultHeartbeatInterval Time without events before a heartbeat is sent
+	DefaultHeartbeatInterval = 15 * time.Second
+	// DefaultWriteTimeout Time a subscriber has to accept a write before it is disconnected
+	DefaultWriteTimeout = 10 * time.Second
+)
@@ -0,0 +1,128 @@
+package livefeed
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jmontesinos91/collector/domains/pubsub"
+	"github.com/jmontesinos91/ologs/logger"
+	tracekey "github.com/jmontesinos91/ologs/logger/v2"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultService struct
+type DefaultService struct {
+	log  *logger.ContextLogger
+	hub  *pubsub.Hub[Event]
+	opts Opts
+}
+
+// NewDefaultService creates a new instance of DefaultService
+func NewDefaultService(l *logger.ContextLogger, opts Opts) *DefaultService {
+	if opts.HeartbeatInterval <= 0 {
+		opts.HeartbeatInterval = DefaultHeartbeatInterval
+	}
+	if opts.WriteTimeout <= 0 {
+		opts.WriteTimeout = DefaultWriteTimeout
+	}
+
+	return &DefaultService{
+		log:  l,
+		hub:  pubsub.NewHub[Event](),
+		opts: opts,
+	}
+}
+
+// Publish delivers the event to the subscribers, it never blocks the caller
+func (s *DefaultService) Publish(ctx context.Context, event Event) {
+	if event.RequestID == "" {
+		event.RequestID, _ = ctx.Value(middleware.RequestIDKey).(string)
+	}
+	if event.At.IsZero() {
+		event.At = time.Now().UTC()
+	}
+
+	s.hub.Publish(event)
+}
+
+// Stream subscribes to the events matching the filter and writes them until the context is done,
+// a write fails or the feed is closed. Events dropped because the subscriber could not keep up are
+// reported before the next write, and a heartbeat is written while the feed is idle
+func (s *DefaultService) Stream(ctx context.Context, filter *FilterRequest, write WriteFunc) error {
+	requestID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	claims, _ := ctx.Value(&sts.Claim).(sts.Claims)
+	logCtx := logger.Context{
+		tracekey.TrackingID: requestID,
+		tracekey.UserID:     claims.UserID,
+		tracekey.Role:       claims.Role,
+	}
+
+	if filter == nil {
+		filter = &FilterRequest{}
+	}
+	filter.Scope = s.opts.Tenancy.Scope(claims.Role, claims.Tenants)
+	sub := s.hub.Subscribe(s.opts.BufferSize, func(event Event) bool {
+		return Matches(filter, event)
+	})
+	defer sub.Close()
+
+	logCtx["Subscribers"] = s.hub.Len()
+	s.log.WithContext(logrus.InfoLevel, "Stream", "Live feed subscriber connected", logCtx, nil)
+
+	err := s.pump(ctx, sub, write)
+	if err != nil {
+		s.log.WithContext(logrus.WarnLevel, "Stream", "Live feed subscriber disconnected", logCtx, err)
+	}
+
+	return err
+}
+
+func (s *DefaultService) pump(ctx context.Context, sub *pubsub.Subscription[Event], write WriteFunc) error {
+	heartbeat := time.NewTicker(s.opts.HeartbeatInterval)
+	defer heartbeat.Stop()
+
+	send := func(event Event) error {
+		if dropped := sub.Dropped(); dropped > 0 {
+			err := write(Event{Type: EventDropped, Dropped: dropped, At: time.Now().UTC()}, s.deadline())
+			if err != nil {
+				return err
+			}
+		}
+		return write(event, s.deadline())
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-sub.Events():
+			if !ok {
+				return nil
+			}
+			if err := send(event); err != nil {
+				return err
+			}
+			heartbeat.Reset(s.opts.HeartbeatInterval)
+		case <-heartbeat.C:
+			if err := send(Event{Type: EventHeartbeat, At: time.Now().UTC()}); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *DefaultService) deadline() time.Time {
+	return time.Now().Add(s.opts.WriteTimeout)
+}
+
+// Subscribers returns the number of connected subscribers
+func (s *DefaultService) Subscribers() int {
+	return s.hub.Len()
+}
+
+// Close disconnects every subscriber
+func (s *DefaultService) Close() {
+	s.hub.Close()
+}
@@ -0,0 +1,176 @@
+package livefeed_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jmontesinos91/collector/internal/services/livefeed"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/stretchr/testify/assert"
+)
+
+func testContext() context.Context {
+	ctx := context.Background()
+	ctx = context.WithValue(ctx, middleware.RequestIDKey, "unit-test-request-id")
+	ctx = context.WithValue(ctx, &sts.Claim, sts.Claims{
+		UserID:  7,
+		Role:    "unit-test-role",
+		Tenants: []int{4},
+	})
+	return ctx
+}
+
+// stream runs Stream in the background and waits until the subscriber is connected
+func stream(t *testing.T, service *livefeed.DefaultService, ctx context.Context, filter *livefeed.FilterRequest,
+	write livefeed.WriteFunc) <-chan error {
+	done := make(chan error, 1)
+	before := service.Subscribers()
+	go func() {
+		done <- service.Stream(ctx, filter, write)
+	}()
+
+	assert.Eventually(t, func() bool { return service.Subscribers() > before }, time.Second, time.Millisecond)
+	return done
+}
+
+func collect(events chan<- livefeed.Event) livefeed.WriteFunc {
+	return func(event livefeed.Event, deadline time.Time) error {
+		if deadline.IsZero() {
+			return errors.New("missing deadline")
+		}
+		events <- event
+		return nil
+	}
+}
+
+func TestStream(t *testing.T) {
+	log := logger.NewContextLogger("Stream", "debug", logger.TextFormat)
+
+	cases := []struct { //nolint:wsl
+		name    string
+		opts    livefeed.Opts
+		filter  *livefeed.FilterRequest
+		run     func(*livefeed.DefaultService, context.CancelFunc, chan livefeed.Event) []livefeed.Event
+		err     bool
+		asserts func(*testing.T, []livefeed.Event) bool
+	}{
+		{
+			name:   "Happy path only matching events of the subscriber tenants are written",
+			opts:   livefeed.Opts{HeartbeatInterval: time.Hour},
+			filter: &livefeed.FilterRequest{IMEI: "8615"},
+			run: func(s *livefeed.DefaultService, cancel context.CancelFunc, events chan livefeed.Event) []livefeed.Event {
+				s.Publish(testContext(), livefeed.Event{Type: livefeed.EventFrame, IMEI: "000000000000000", TenantID: 4})
+				s.Publish(testContext(), livefeed.Event{Type: livefeed.EventFrame, IMEI: "861585041449999", TenantID: 5})
+				s.Publish(testContext(), livefeed.Event{Type: livefeed.EventFrame, IMEI: "861585041440544", TenantID: 4})
+				event := <-events
+				cancel()
+				return []livefeed.Event{event}
+			},
+			asserts: func(t *testing.T, written []livefeed.Event) bool {
+				return assert.Len(t, written, 1) &&
+					assert.Equal(t, "861585041440544", written[0].IMEI) &&
+					assert.Equal(t, "unit-test-request-id", written[0].RequestID) &&
+					assert.False(t, written[0].At.IsZero())
+			},
+		},
+		{
+			name: "Heartbeat while the feed is idle",
+			opts: livefeed.Opts{HeartbeatInterval: 5 * time.Millisecond},
+			run: func(s *livefeed.DefaultService, cancel context.CancelFunc, events chan livefeed.Event) []livefeed.Event {
+				event := <-events
+				cancel()
+				return []livefeed.Event{event}
+			},
+			asserts: func(t *testing.T, written []livefeed.Event) bool {
+				return assert.NotEmpty(t, written) && assert.Equal(t, livefeed.EventHeartbeat, written[0].Type)
+			},
+		},
+		{
+			name: "Closing the feed ends the stream",
+			opts: livefeed.Opts{HeartbeatInterval: time.Hour},
+			run: func(s *livefeed.DefaultService, cancel context.CancelFunc, events chan livefeed.Event) []livefeed.Event {
+				s.Close()
+				return nil
+			},
+			asserts: func(t *testing.T, written []livefeed.Event) bool {
+				return assert.Empty(t, written)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			service := livefeed.NewDefaultService(log, tc.opts)
+			ctx, cancel := context.WithCancel(testContext())
+			defer cancel()
+
+			events := make(chan livefeed.Event, 16)
+			done := stream(t, service, ctx, tc.filter, collect(events))
+			written := tc.run(service, cancel, events)
+
+			err := <-done
+			if (err != nil) != tc.err {
+				t.Errorf("DefaultService.Stream() error = %v, wantErr %v", err, tc.err)
+			}
+
+			close(events)
+			for event := range events {
+				written = append(written, event)
+			}
+
+			if !tc.asserts(t, written) {
+				t.Errorf("Assert error on test = '%v'", tc.name)
+			}
+			assert.Equal(t, 0, service.Subscribers())
+		})
+	}
+}
+
+func TestStreamWriteFailure(t *testing.T) {
+	log := logger.NewContextLogger("Stream", "debug", logger.TextFormat)
+	service := livefeed.NewDefaultService(log, livefeed.Opts{HeartbeatInterval: time.Hour})
+
+	done := stream(t, service, testContext(), nil, func(livefeed.Event, time.Time) error {
+		return errors.New("write deadline exceeded")
+	})
+	service.Publish(testContext(), livefeed.Event{Type: livefeed.EventFrame, TenantID: 4})
+
+	assert.EqualError(t, <-done, "write deadline exceeded")
+	assert.Equal(t, 0, service.Subscribers())
+}
+
+func TestStreamSlowSubscriber(t *testing.T) {
+	log := logger.NewContextLogger("Stream", "debug", logger.TextFormat)
+	service := livefeed.NewDefaultService(log, livefeed.Opts{BufferSize: 1, HeartbeatInterval: time.Hour})
+	ctx, cancel := context.WithCancel(testContext())
+	defer cancel()
+
+	release := make(chan struct{})
+	events := make(chan livefeed.Event, 16)
+	done := stream(t, service, ctx, nil, func(event livefeed.Event, deadline time.Time) error {
+		events <- event
+		<-release
+		return nil
+	})
+
+	// The first event blocks the subscriber, one more fits the buffer and the rest are dropped
+	service.Publish(testContext(), livefeed.Event{Type: livefeed.EventFrame, IMEI: "1", TenantID: 4})
+	assert.Equal(t, "1", (<-events).IMEI)
+	for _, imei := range []string{"2", "3", "4"} {
+		service.Publish(testContext(), livefeed.Event{Type: livefeed.EventFrame, IMEI: imei, TenantID: 4})
+	}
+	close(release)
+
+	dropped := <-events
+	next := <-events
+	cancel()
+	assert.NoError(t, <-done)
+
+	assert.Equal(t, livefeed.EventDropped, dropped.Type)
+	assert.Equal(t, int64(2), dropped.Dropped)
+	assert.Equal(t, "2", next.IMEI)
+}
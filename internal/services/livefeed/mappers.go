@@ -0,0 +1,84 @@
+package livefeed
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/jmontesinos91/terrors"
+)
+
+var eventTypes = map[string]bool{
+	EventFrame:            true,
+	EventAlarm:            true,
+	EventCounterReset:     true,
+	EventExcessiveTraffic: true,
+}
+
+// ParseFilterRequest builds a single filter object given http params, types is a comma
+// separated list of event types
+func ParseFilterRequest(r *http.Request) (*FilterRequest, error) {
+	query := r.URL.Query()
+	fr := FilterRequest{
+		IMEI: query.Get("imei"),
+		IP:   query.Get("ip"),
+	}
+
+	if alarm := query.Get("alarm"); alarm != "" {
+		isAlarm, err := strconv.ParseBool(alarm)
+		if err != nil {
+			return nil, terrors.New(terrors.ErrBadRequest, "Invalid alarm parameter", map[string]string{})
+		}
+		fr.IsAlarm = &isAlarm
+	}
+
+	if types := query.Get("types"); types != "" {
+		for _, eventType := range strings.Split(types, ",") {
+			eventType = strings.TrimSpace(eventType)
+			if !eventTypes[eventType] {
+				return nil, terrors.New(terrors.ErrBadRequest, "Invalid types parameter", map[string]string{
+					"types": "unknown event type " + eventType,
+				})
+			}
+			fr.Types = append(fr.Types, eventType)
+		}
+	}
+
+	return &fr, nil
+}
+
+// Matches reports whether the event passes the filter. Events without device, like bulk
+// operations, only reach the subscribers not filtering by device, and events of other
+// tenants never reach the subscriber
+func Matches(filter *FilterRequest, event Event) bool {
+	if filter == nil {
+		return true
+	}
+
+	if !filter.Scope.Allows(event.TenantID) {
+		return false
+	}
+	if len(filter.Types) > 0 && !contains(filter.Types, event.Type) {
+		return false
+	}
+	if filter.IMEI != "" && !strings.Contains(event.IMEI, filter.IMEI) {
+		return false
+	}
+	if filter.IP != "" && !strings.Contains(event.IP, filter.IP) {
+		return false
+	}
+	if filter.IsAlarm != nil && (event.IMEI == "" || event.IsAlarm != *filter.IsAlarm) {
+		return false
+	}
+
+	return true
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
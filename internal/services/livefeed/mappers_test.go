@@ -0,0 +1,109 @@
+package livefeed
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/jmontesinos91/collector/domains/tenancy"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseFilterRequest(t *testing.T) {
+	isAlarm := true
+	tests := []struct {
+		name        string
+		queryParams map[string]string
+		expected    *FilterRequest
+		errorMsg    string
+	}{
+		{
+			name:        "Empty filter",
+			queryParams: map[string]string{},
+			expected:    &FilterRequest{},
+		},
+		{
+			name: "Happy path valid parameters",
+			queryParams: map[string]string{
+				"imei":  "8615",
+				"ip":    "192.168",
+				"alarm": "true",
+				"types": "alarm, counter-reset",
+			},
+			expected: &FilterRequest{
+				IMEI:    "8615",
+				IP:      "192.168",
+				IsAlarm: &isAlarm,
+				Types:   []string{EventAlarm, EventCounterReset},
+			},
+		},
+		{
+			name:        "Invalid alarm parameter",
+			queryParams: map[string]string{"alarm": "maybe"},
+			errorMsg:    "Invalid alarm parameter",
+		},
+		{
+			name:        "Invalid types parameter",
+			queryParams: map[string]string{"types": "frame,heartbeat"},
+			errorMsg:    "Invalid types parameter",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := url.Values{}
+			for key, value := range tt.queryParams {
+				values.Set(key, value)
+			}
+			r := &http.Request{URL: &url.URL{RawQuery: values.Encode()}}
+
+			result, err := ParseFilterRequest(r)
+			if tt.errorMsg != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestMatches(t *testing.T) {
+	isAlarm := true
+	frame := Event{Type: EventFrame, IMEI: "861585041440544", IP: "192.168.100.1"}
+	alarm := Event{Type: EventAlarm, IMEI: "861585041440544", IP: "192.168.100.1", IsAlarm: true}
+	bulk := Event{Type: EventCounterReset, Affected: 20}
+	tenantFrame := Event{Type: EventFrame, IMEI: "861585041440544", TenantID: 4}
+	all := tenancy.Unrestricted()
+
+	tests := []struct {
+		name     string
+		filter   *FilterRequest
+		event    Event
+		expected bool
+	}{
+		{name: "No filter", filter: nil, event: frame, expected: true},
+		{name: "Empty filter receives bulk events", filter: &FilterRequest{Scope: all}, event: bulk, expected: true},
+		{name: "IMEI contained", filter: &FilterRequest{Scope: all, IMEI: "0414"}, event: frame, expected: true},
+		{name: "IMEI not contained", filter: &FilterRequest{Scope: all, IMEI: "9999"}, event: frame, expected: false},
+		{name: "IP contained", filter: &FilterRequest{Scope: all, IP: "192.168."}, event: frame, expected: true},
+		{name: "IP not contained", filter: &FilterRequest{Scope: all, IP: "10.0."}, event: frame, expected: false},
+		{name: "Alarm state", filter: &FilterRequest{Scope: all, IsAlarm: &isAlarm}, event: alarm, expected: true},
+		{name: "Alarm state mismatch", filter: &FilterRequest{Scope: all, IsAlarm: &isAlarm}, event: frame, expected: false},
+		{name: "Device filter skips bulk events", filter: &FilterRequest{Scope: all, IsAlarm: &isAlarm}, event: bulk, expected: false},
+		{name: "Type listed", filter: &FilterRequest{Scope: all, Types: []string{EventAlarm}}, event: alarm, expected: true},
+		{name: "Type not listed", filter: &FilterRequest{Scope: all, Types: []string{EventAlarm}}, event: frame, expected: false},
+		{name: "Tenant in scope", filter: &FilterRequest{Scope: tenancy.Of(4)}, event: tenantFrame, expected: true},
+		{name: "Tenant out of scope", filter: &FilterRequest{Scope: tenancy.Of(5)}, event: tenantFrame, expected: false},
+		{name: "Unregistered device out of a restricted scope", filter: &FilterRequest{Scope: tenancy.Of(4)}, event: frame, expected: false},
+		{name: "Empty scope receives nothing", filter: &FilterRequest{}, event: bulk, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Matches(tt.filter, tt.event))
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package livefeed
+
+import (
+	"time"
+
+	"github.com/jmontesinos91/collector/domains/tenancy"
+)
+
+// Event a change on the traffic pushed to the live feed. Bulk operations publish a single
+// event without device and the number of affected traffics
+type Event struct {
+	Type      string    `json:"type"`
+	TrafficID string    `json:"trafficId,omitempty"`
+	IMEI      string    `json:"imei,omitempty"`
+	IP        string    `json:"ip,omitempty"`
+	Request   string    `json:"request,omitempty"`
+	IsAlarm   bool      `json:"isAlarm"`
+	Counter   *int      `json:"counter,omitempty"`
+	TenantID  int       `json:"tenantId,omitempty"`
+	Affected  int       `json:"affected,omitempty"`
+	Dropped   int64     `json:"dropped,omitempty"`
+	RequestID string    `json:"requestId,omitempty"`
+	At        time.Time `json:"at"`
+}
+
+// FilterRequest holds the http request params, it follows the listing semantics:
+// imei and ip match any value containing them
+type FilterRequest struct {
+	IMEI    string   `json:"imei,omitempty"`
+	IP      string   `json:"ip,omitempty"`
+	IsAlarm *bool    `json:"alarm,omitempty"`
+	Types   []string `json:"types,omitempty"`
+	// Scope tenants of the subscriber, it is resolved from the claims and never from the params
+	Scope tenancy.Scope `json:"-"`
+}
+
+// Opts live feed options
+type Opts struct {
+	BufferSize        int
+	HeartbeatInterval time.Duration
+	WriteTimeout      time.Duration
+	Tenancy           tenancy.Policy
+}
@@ -0,0 +1,22 @@
+package livefeed
+
+import (
+	"context"
+	"time"
+)
+
+// IPublisher Publishes traffic changes to the live feed, services depend on it to
+// announce their own changes
+type IPublisher interface {
+	Publish(ctx context.Context, event Event)
+}
+
+// IService Manage the live traffic feed
+type IService interface {
+	IPublisher
+	Stream(ctx context.Context, filter *FilterRequest, write WriteFunc) error
+}
+
+// WriteFunc writes an event to a subscriber, the write must fail once the deadline is exceeded
+// so a stalled client is disconnected
+type WriteFunc func(event Event, deadline time.Time) error
@@ -0,0 +1,30 @@
+package partition
+
+const (
+	// ActionDrop drops the expired partitions with their rows
+	ActionDrop = "drop"
+	// ActionArchive moves the expired partitions to the archive schema
+	ActionArchive = "archive"
+
+	// ArchiveSchema schema holding the archived partitions, created by the partitioning migration
+	ArchiveSchema = "archive"
+
+	// DefaultPremakeMonths months created ahead of the current one when none is configured
+	DefaultPremakeMonths = 3
+
+	partitionMonthLayout = "200601"
+
+	// tenantPurgeBatchSize rows of a tenant deleted per statement
+	tenantPurgeBatchSize = 5000
+)
+
+// partitionedTables append-only tables partitioned by month by the migrations, only their partitions expire
+var partitionedTables = map[string]bool{
+	"audit_log": true,
+}
+
+// tenantRetentionColumns column the tenant retention is measured on for the tables whose rows carry a tenant.
+// traffic holds the current state of each device, only its soft deleted rows expire
+var tenantRetentionColumns = map[string]string{
+	"traffic": "deleted_at",
+}
@@ -0,0 +1,282 @@
+package partition
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+
+	opartition "github.com/jmontesinos91/collector/internal/repositories/partition"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/terrors"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultService struct
+type DefaultService struct {
+	log           *logger.ContextLogger
+	partitionRepo opartition.IRepository
+	opts          Opts
+}
+
+// NewDefaultService creates a new instance of DefaultService
+func NewDefaultService(l *logger.ContextLogger, pr opartition.IRepository, opts Opts) *DefaultService {
+	return &DefaultService{
+		log:           l,
+		partitionRepo: pr,
+		opts:          opts,
+	}
+}
+
+// Report lists the partitions of every partitioned table under a retention policy with their sizes
+func (s *DefaultService) Report(ctx context.Context) ([]TableReport, error) {
+	reports := make([]TableReport, 0, len(s.opts.Policies))
+	for _, policy := range s.opts.Policies {
+		if !partitionedTables[policy.Table] {
+			continue
+		}
+
+		partitions, err := s.partitions(ctx, policy.Table)
+		if err != nil {
+			return nil, err
+		}
+
+		report := TableReport{
+			Table:           policy.Table,
+			RetentionMonths: policy.RetentionMonths,
+			Action:          policy.Action,
+			Partitions:      partitions,
+		}
+		for _, p := range partitions {
+			report.Rows += p.Rows
+			report.SizeBytes += p.SizeBytes
+		}
+		reports = append(reports, report)
+	}
+
+	return reports, nil
+}
+
+// Maintain creates the partitions of the coming months and detaches the partitions past the retention
+// of each partitioned table, dropping or archiving them, then purges the rows of the tenants past their
+// own retention. A failing table does not stop the maintenance of the others
+func (s *DefaultService) Maintain(ctx context.Context, dryRun bool) ([]MaintenanceResult, error) {
+	current := monthStart(time.Now())
+
+	var errs []error
+	results := make([]MaintenanceResult, 0, len(s.opts.Policies))
+	for _, policy := range s.opts.Policies {
+		result, err := s.maintainTable(ctx, policy, current, dryRun)
+		if err != nil {
+			s.log.WithContext(logrus.ErrorLevel, "Maintain", "Failed to maintain table partitions",
+				logger.Context{"Table": policy.Table}, err)
+			errs = append(errs, err)
+		}
+		results = append(results, result)
+	}
+
+	return results, errors.Join(errs...)
+}
+
+// RunMaintenance runs the partition maintenance, it is meant to be scheduled as a background job
+func (s *DefaultService) RunMaintenance(ctx context.Context) {
+	// Failures are logged by Maintain, the tables maintained are still reported
+	results, _ := s.Maintain(ctx, false)
+	for _, result := range results {
+		if len(result.Created) == 0 && len(result.Expired) == 0 && len(result.Purged) == 0 {
+			continue
+		}
+		s.log.WithContext(logrus.InfoLevel,
+			"RunMaintenance",
+			"Table partitions maintained",
+			logger.Context{
+				"Table":   result.Table,
+				"Created": result.Created,
+				"Expired": result.Expired,
+				"Purged":  result.Purged,
+				"Action":  result.Action,
+			},
+			nil)
+	}
+}
+
+func (s *DefaultService) maintainTable(ctx context.Context, policy Policy, current time.Time, dryRun bool) (MaintenanceResult, error) {
+	result := MaintenanceResult{
+		Table:   policy.Table,
+		Action:  policy.Action,
+		DryRun:  dryRun,
+		Created: []string{},
+		Expired: []string{},
+		Purged:  []TenantPurge{},
+	}
+
+	if err := validatePolicy(policy); err != nil {
+		return result, err
+	}
+
+	if partitionedTables[policy.Table] {
+		var err error
+		if result, err = s.maintainPartitions(ctx, policy, current, dryRun, result); err != nil {
+			return result, err
+		}
+	}
+
+	return s.purgeTenants(ctx, policy, current, dryRun, result)
+}
+
+// maintainPartitions creates the partitions of the coming months and expires the ones past the retention
+func (s *DefaultService) maintainPartitions(ctx context.Context, policy Policy, current time.Time, dryRun bool,
+	result MaintenanceResult) (MaintenanceResult, error) {
+	partitions, err := s.partitions(ctx, policy.Table)
+	if err != nil {
+		return result, err
+	}
+
+	existing := make(map[string]bool, len(partitions))
+	for _, p := range partitions {
+		if p.From != nil {
+			existing[p.From.Format(partitionMonthLayout)] = true
+		}
+	}
+
+	premake := s.opts.PremakeMonths
+	if premake <= 0 {
+		premake = DefaultPremakeMonths
+	}
+	for i := 0; i <= premake; i++ {
+		month := current.AddDate(0, i, 0)
+		if existing[month.Format(partitionMonthLayout)] {
+			continue
+		}
+
+		if dryRun {
+			result.Created = append(result.Created, partitionName(policy.Table, month))
+			continue
+		}
+
+		created, err := s.partitionRepo.EnsureMonth(ctx, policy.Table, month)
+		if err != nil {
+			return result, err
+		}
+		if created != "" {
+			result.Created = append(result.Created, created)
+		}
+	}
+
+	if policy.RetentionMonths > 0 {
+		cutoff := current.AddDate(0, -policy.RetentionMonths, 0)
+		for _, p := range partitions {
+			if p.Default || p.To == nil || p.To.After(cutoff) {
+				continue
+			}
+
+			if !dryRun {
+				if err := s.expire(ctx, policy, p.Name); err != nil {
+					return result, err
+				}
+			}
+			result.Expired = append(result.Expired, p.Name)
+		}
+	}
+
+	return result, nil
+}
+
+// purgeTenants deletes the rows of the tenants past their own retention, the tenants are purged in order
+// of tenant id and only the ones with rows deleted are reported unless it is a dry run
+func (s *DefaultService) purgeTenants(ctx context.Context, policy Policy, current time.Time, dryRun bool,
+	result MaintenanceResult) (MaintenanceResult, error) {
+	column := tenantRetentionColumns[policy.Table]
+	for _, tenantID := range slices.Sorted(maps.Keys(policy.TenantRetentionMonths)) {
+		purge := TenantPurge{
+			TenantID: tenantID,
+			Before:   current.AddDate(0, -policy.TenantRetentionMonths[tenantID], 0),
+		}
+
+		var err error
+		if dryRun {
+			purge.Rows, err = s.partitionRepo.CountTenantRows(ctx, policy.Table, column, tenantID, purge.Before)
+		} else {
+			purge.Rows, err = s.partitionRepo.PurgeTenantRows(ctx, policy.Table, column, tenantID, purge.Before,
+				tenantPurgeBatchSize)
+		}
+		if err != nil {
+			return result, err
+		}
+		if dryRun || purge.Rows > 0 {
+			result.Purged = append(result.Purged, purge)
+		}
+	}
+
+	return result, nil
+}
+
+// validatePolicy checks the retention and action only target partitioned tables and the tenant retentions
+// only target tables whose rows carry a tenant
+func validatePolicy(policy Policy) error {
+	if !partitionedTables[policy.Table] {
+		if _, ok := tenantRetentionColumns[policy.Table]; !ok || policy.RetentionMonths != 0 {
+			return terrors.New(terrors.ErrBadRequest, "Partition retention is not supported by the table, it is not partitioned",
+				map[string]string{
+					"table": policy.Table,
+				})
+		}
+	} else if policy.Action != ActionDrop && policy.Action != ActionArchive {
+		return terrors.New(terrors.ErrBadRequest, "Invalid partition retention action", map[string]string{
+			"table":  policy.Table,
+			"action": policy.Action,
+		})
+	}
+
+	if len(policy.TenantRetentionMonths) == 0 {
+		return nil
+	}
+
+	if _, ok := tenantRetentionColumns[policy.Table]; !ok {
+		return terrors.New(terrors.ErrBadRequest, "Tenant retention is not supported by the table", map[string]string{
+			"table": policy.Table,
+		})
+	}
+
+	for tenantID, months := range policy.TenantRetentionMonths {
+		if months <= 0 {
+			return terrors.New(terrors.ErrBadRequest, "Invalid tenant retention, it must be at least one month",
+				map[string]string{
+					"table":  policy.Table,
+					"tenant": strconv.Itoa(tenantID),
+				})
+		}
+	}
+
+	return nil
+}
+
+// expire detaches the partition from its table and then drops or archives it
+func (s *DefaultService) expire(ctx context.Context, policy Policy, partition string) error {
+	if err := s.partitionRepo.Detach(ctx, policy.Table, partition); err != nil {
+		return err
+	}
+
+	if policy.Action == ActionArchive {
+		return s.partitionRepo.Archive(ctx, partition, ArchiveSchema)
+	}
+	return s.partitionRepo.Drop(ctx, partition)
+}
+
+func (s *DefaultService) partitions(ctx context.Context, table string) ([]Partition, error) {
+	models, err := s.partitionRepo.List(ctx, table)
+	if err != nil {
+		return nil, err
+	}
+
+	partitions, err := ToPartitionSlice(models)
+	if err != nil {
+		s.log.WithContext(logrus.ErrorLevel, "partitions", "Failed to read table partitions",
+			logger.Context{"Table": table}, err)
+		return nil, err
+	}
+
+	return partitions, nil
+}
@@ -0,0 +1,355 @@
+package partition_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	opartition "github.com/jmontesinos91/collector/internal/repositories/partition"
+	"github.com/jmontesinos91/collector/internal/repositories/partition/partitionmocks"
+	"github.com/jmontesinos91/collector/internal/services/partition"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/terrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// currentMonth first instant of the current month in UTC
+func currentMonth() time.Time {
+	now := time.Now().UTC()
+	return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// monthly returns the catalog partition of the table for the month offset from the current one
+func monthly(table string, offset int) opartition.Model {
+	from := currentMonth().AddDate(0, offset, 0)
+	to := from.AddDate(0, 1, 0)
+	return opartition.Model{
+		Name: table + "_p" + from.Format("200601"),
+		Bound: fmt.Sprintf("FOR VALUES FROM ('%s') TO ('%s')",
+			from.Format("2006-01-02 15:04:05-07"), to.Format("2006-01-02 15:04:05-07")),
+		Rows:      10,
+		SizeBytes: 1024,
+	}
+}
+
+func defaultPartition(table string) opartition.Model {
+	return opartition.Model{Name: table + "_default", Bound: "DEFAULT", Rows: 1, SizeBytes: 512}
+}
+
+func TestMaintain(t *testing.T) {
+	log := logger.NewContextLogger("Maintain", "debug", logger.TextFormat)
+
+	expired := monthly("audit_log", -13)
+	kept := monthly("audit_log", -12)
+	partitions := []opartition.Model{
+		expired,
+		kept,
+		monthly("audit_log", 0),
+		monthly("audit_log", 1),
+		defaultPartition("audit_log"),
+	}
+
+	cases := []struct { //nolint:wsl
+		name     string
+		opts     partition.Opts
+		dryRun   bool
+		repoFunc func() *partitionmocks.IRepository
+		err      bool
+		asserts  func(*testing.T, []partition.MaintenanceResult, *partitionmocks.IRepository) bool
+	}{
+		{
+			name: "Happy path creates the missing months and drops the expired partitions",
+			opts: partition.Opts{
+				Policies:      []partition.Policy{{Table: "audit_log", RetentionMonths: 12, Action: partition.ActionDrop}},
+				PremakeMonths: 2,
+			},
+			repoFunc: func() *partitionmocks.IRepository {
+				repositoryMock := &partitionmocks.IRepository{}
+				repositoryMock.On("List", mock.Anything, "audit_log").Return(partitions, nil)
+				repositoryMock.On("EnsureMonth", mock.Anything, "audit_log", currentMonth().AddDate(0, 2, 0)).
+					Return(monthly("audit_log", 2).Name, nil)
+				repositoryMock.On("Detach", mock.Anything, "audit_log", expired.Name).Return(nil)
+				repositoryMock.On("Drop", mock.Anything, expired.Name).Return(nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, results []partition.MaintenanceResult, repo *partitionmocks.IRepository) bool {
+				return assert.Len(t, results, 1) &&
+					assert.Equal(t, []string{monthly("audit_log", 2).Name}, results[0].Created) &&
+					assert.Equal(t, []string{expired.Name}, results[0].Expired) &&
+					repo.AssertNotCalled(t, "Detach", mock.Anything, "audit_log", kept.Name) &&
+					repo.AssertExpectations(t)
+			},
+		},
+		{
+			name: "Expired partitions are archived",
+			opts: partition.Opts{
+				Policies:      []partition.Policy{{Table: "audit_log", RetentionMonths: 1, Action: partition.ActionArchive}},
+				PremakeMonths: 1,
+			},
+			repoFunc: func() *partitionmocks.IRepository {
+				repositoryMock := &partitionmocks.IRepository{}
+				repositoryMock.On("List", mock.Anything, "audit_log").Return([]opartition.Model{
+					monthly("audit_log", -2),
+					monthly("audit_log", -1),
+					monthly("audit_log", 0),
+					monthly("audit_log", 1),
+				}, nil)
+				repositoryMock.On("Detach", mock.Anything, "audit_log", monthly("audit_log", -2).Name).Return(nil)
+				repositoryMock.On("Archive", mock.Anything, monthly("audit_log", -2).Name, partition.ArchiveSchema).Return(nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, results []partition.MaintenanceResult, repo *partitionmocks.IRepository) bool {
+				return assert.Empty(t, results[0].Created) &&
+					assert.Equal(t, []string{monthly("audit_log", -2).Name}, results[0].Expired) &&
+					repo.AssertNotCalled(t, "Drop", mock.Anything, mock.Anything) &&
+					repo.AssertExpectations(t)
+			},
+		},
+		{
+			name:   "Dry run does not change the partitions",
+			dryRun: true,
+			opts: partition.Opts{
+				Policies:      []partition.Policy{{Table: "audit_log", RetentionMonths: 12, Action: partition.ActionDrop}},
+				PremakeMonths: 2,
+			},
+			repoFunc: func() *partitionmocks.IRepository {
+				repositoryMock := &partitionmocks.IRepository{}
+				repositoryMock.On("List", mock.Anything, "audit_log").Return(partitions, nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, results []partition.MaintenanceResult, repo *partitionmocks.IRepository) bool {
+				return assert.True(t, results[0].DryRun) &&
+					assert.Equal(t, []string{monthly("audit_log", 2).Name}, results[0].Created) &&
+					assert.Equal(t, []string{expired.Name}, results[0].Expired) &&
+					repo.AssertNotCalled(t, "EnsureMonth", mock.Anything, mock.Anything, mock.Anything) &&
+					repo.AssertNotCalled(t, "Detach", mock.Anything, mock.Anything, mock.Anything) &&
+					repo.AssertExpectations(t)
+			},
+		},
+		{
+			name: "Zero retention keeps every partition",
+			opts: partition.Opts{
+				Policies:      []partition.Policy{{Table: "audit_log", Action: partition.ActionDrop}},
+				PremakeMonths: 1,
+			},
+			repoFunc: func() *partitionmocks.IRepository {
+				repositoryMock := &partitionmocks.IRepository{}
+				repositoryMock.On("List", mock.Anything, "audit_log").Return(partitions, nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, results []partition.MaintenanceResult, repo *partitionmocks.IRepository) bool {
+				return assert.Empty(t, results[0].Expired) &&
+					repo.AssertNotCalled(t, "Detach", mock.Anything, mock.Anything, mock.Anything) &&
+					repo.AssertExpectations(t)
+			},
+		},
+		{
+			name: "Tenant rows past their retention are purged",
+			opts: partition.Opts{
+				Policies: []partition.Policy{{
+					Table:                 "traffic",
+					TenantRetentionMonths: map[int]int{7: 3, 4: 6},
+				}},
+				PremakeMonths: 1,
+			},
+			repoFunc: func() *partitionmocks.IRepository {
+				repositoryMock := &partitionmocks.IRepository{}
+				repositoryMock.On("PurgeTenantRows", mock.Anything, "traffic", "deleted_at", 4,
+					currentMonth().AddDate(0, -6, 0), mock.Anything).Return(0, nil)
+				repositoryMock.On("PurgeTenantRows", mock.Anything, "traffic", "deleted_at", 7,
+					currentMonth().AddDate(0, -3, 0), mock.Anything).Return(25, nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, results []partition.MaintenanceResult, repo *partitionmocks.IRepository) bool {
+				return assert.Equal(t, []partition.TenantPurge{
+					{TenantID: 7, Before: currentMonth().AddDate(0, -3, 0), Rows: 25},
+				}, results[0].Purged) &&
+					repo.AssertNotCalled(t, "List", mock.Anything, mock.Anything) &&
+					repo.AssertExpectations(t)
+			},
+		},
+		{
+			name:   "Dry run counts the tenant rows past their retention",
+			dryRun: true,
+			opts: partition.Opts{
+				Policies: []partition.Policy{{
+					Table:                 "traffic",
+					TenantRetentionMonths: map[int]int{7: 3},
+				}},
+				PremakeMonths: 1,
+			},
+			repoFunc: func() *partitionmocks.IRepository {
+				repositoryMock := &partitionmocks.IRepository{}
+				repositoryMock.On("CountTenantRows", mock.Anything, "traffic", "deleted_at", 7,
+					currentMonth().AddDate(0, -3, 0)).Return(0, nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, results []partition.MaintenanceResult, repo *partitionmocks.IRepository) bool {
+				return assert.Equal(t, []partition.TenantPurge{
+					{TenantID: 7, Before: currentMonth().AddDate(0, -3, 0)},
+				}, results[0].Purged) &&
+					repo.AssertNotCalled(t, "PurgeTenantRows", mock.Anything, mock.Anything, mock.Anything,
+						mock.Anything, mock.Anything, mock.Anything) &&
+					repo.AssertExpectations(t)
+			},
+		},
+		{
+			name: "Partition retention of a table that is not partitioned",
+			opts: partition.Opts{
+				Policies: []partition.Policy{{Table: "traffic", RetentionMonths: 12, Action: partition.ActionDrop}},
+			},
+			repoFunc: func() *partitionmocks.IRepository {
+				return &partitionmocks.IRepository{}
+			},
+			err: true,
+			asserts: func(t *testing.T, results []partition.MaintenanceResult, repo *partitionmocks.IRepository) bool {
+				return assert.Empty(t, results[0].Expired) &&
+					repo.AssertNotCalled(t, "List", mock.Anything, mock.Anything) &&
+					repo.AssertNotCalled(t, "PurgeTenantRows", mock.Anything, mock.Anything, mock.Anything,
+						mock.Anything, mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name: "Tenant retention shorter than a month",
+			opts: partition.Opts{
+				Policies: []partition.Policy{{
+					Table:                 "traffic",
+					TenantRetentionMonths: map[int]int{7: 0},
+				}},
+			},
+			repoFunc: func() *partitionmocks.IRepository {
+				return &partitionmocks.IRepository{}
+			},
+			err: true,
+			asserts: func(t *testing.T, results []partition.MaintenanceResult, repo *partitionmocks.IRepository) bool {
+				return assert.Empty(t, results[0].Purged) &&
+					repo.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name: "Tenant retention of a table without tenants",
+			opts: partition.Opts{
+				Policies: []partition.Policy{{
+					Table:                 "audit_log",
+					RetentionMonths:       24,
+					Action:                partition.ActionArchive,
+					TenantRetentionMonths: map[int]int{7: 3},
+				}},
+			},
+			repoFunc: func() *partitionmocks.IRepository {
+				return &partitionmocks.IRepository{}
+			},
+			err: true,
+			asserts: func(t *testing.T, results []partition.MaintenanceResult, repo *partitionmocks.IRepository) bool {
+				return repo.AssertNotCalled(t, "List", mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name: "A failing table does not stop the others",
+			opts: partition.Opts{
+				Policies: []partition.Policy{
+					{Table: "audit_log", RetentionMonths: 12, Action: partition.ActionDrop},
+					{Table: "audit_log", Action: "truncate"},
+					{Table: "traffic", TenantRetentionMonths: map[int]int{7: 3}},
+				},
+				PremakeMonths: 1,
+			},
+			repoFunc: func() *partitionmocks.IRepository {
+				repositoryMock := &partitionmocks.IRepository{}
+				repositoryMock.On("List", mock.Anything, "audit_log").
+					Return(nil, terrors.InternalService("list_partitions", "Failed to list partitions from the database", map[string]string{}))
+				repositoryMock.On("PurgeTenantRows", mock.Anything, "traffic", "deleted_at", 7,
+					currentMonth().AddDate(0, -3, 0), mock.Anything).Return(25, nil)
+				return repositoryMock
+			},
+			err: true,
+			asserts: func(t *testing.T, results []partition.MaintenanceResult, repo *partitionmocks.IRepository) bool {
+				return assert.Len(t, results, 3) &&
+					assert.Len(t, results[2].Purged, 1) &&
+					repo.AssertNumberOfCalls(t, "List", 1) &&
+					repo.AssertExpectations(t)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := tc.repoFunc()
+			service := partition.NewDefaultService(log, repo, tc.opts)
+
+			results, err := service.Maintain(context.Background(), tc.dryRun)
+			if (err != nil) != tc.err {
+				t.Errorf("DefaultService.Maintain() error = %v, wantErr %v", err, tc.err)
+			}
+
+			if !tc.asserts(t, results, repo) {
+				t.Errorf("Assert error on test = '%v'", tc.name)
+			}
+		})
+	}
+}
+
+func TestReport(t *testing.T) {
+	log := logger.NewContextLogger("Report", "debug", logger.TextFormat)
+
+	cases := []struct { //nolint:wsl
+		name     string
+		repoFunc func() *partitionmocks.IRepository
+		err      bool
+		asserts  func(*testing.T, []partition.TableReport, *partitionmocks.IRepository) bool
+	}{
+		{
+			name: "Happy path sizes are totalled per partitioned table",
+			repoFunc: func() *partitionmocks.IRepository {
+				repositoryMock := &partitionmocks.IRepository{}
+				repositoryMock.On("List", mock.Anything, "audit_log").
+					Return([]opartition.Model{monthly("audit_log", 0), defaultPartition("audit_log")}, nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, reports []partition.TableReport, repo *partitionmocks.IRepository) bool {
+				return assert.Len(t, reports, 1) &&
+					assert.Equal(t, "audit_log", reports[0].Table) &&
+					assert.Len(t, reports[0].Partitions, 2) &&
+					assert.Equal(t, int64(11), reports[0].Rows) &&
+					assert.Equal(t, int64(1536), reports[0].SizeBytes) &&
+					repo.AssertExpectations(t)
+			},
+		},
+		{
+			name: "Unsupported partition bound",
+			repoFunc: func() *partitionmocks.IRepository {
+				repositoryMock := &partitionmocks.IRepository{}
+				repositoryMock.On("List", mock.Anything, "audit_log").
+					Return([]opartition.Model{{Name: "audit_log_list", Bound: "FOR VALUES IN ('a')"}}, nil)
+				return repositoryMock
+			},
+			err: true,
+			asserts: func(t *testing.T, reports []partition.TableReport, repo *partitionmocks.IRepository) bool {
+				return assert.Nil(t, reports) && repo.AssertExpectations(t)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := tc.repoFunc()
+			service := partition.NewDefaultService(log, repo, partition.Opts{
+				Policies: []partition.Policy{
+					{Table: "audit_log", RetentionMonths: 12, Action: partition.ActionDrop},
+					{Table: "traffic", TenantRetentionMonths: map[int]int{7: 3}},
+				},
+			})
+
+			reports, err := service.Report(context.Background())
+			if (err != nil) != tc.err {
+				t.Errorf("DefaultService.Report() error = %v, wantErr %v", err, tc.err)
+			}
+
+			if !tc.asserts(t, reports, repo) {
+				t.Errorf("Assert error on test = '%v'", tc.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package partition
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	opartition "github.com/jmontesinos91/collector/internal/repositories/partition"
+	"github.com/jmontesinos91/terrors"
+)
+
+var rangeBoundRegexp = regexp.MustCompile(`^FOR VALUES FROM \((.+)\) TO \((.+)\)$`)
+
+var boundLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07",
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+}
+
+// ToPartitionSlice maps the catalog partitions of a table
+func ToPartitionSlice(models []opartition.Model) ([]Partition, error) {
+	partitions := make([]Partition, 0, len(models))
+	for _, model := range models {
+		p, err := ToPartition(model)
+		if err != nil {
+			return nil, err
+		}
+		partitions = append(partitions, p)
+	}
+	return partitions, nil
+}
+
+// ToPartition maps a catalog partition parsing its range bound expression
+func ToPartition(model opartition.Model) (Partition, error) {
+	p := Partition{
+		Name:      model.Name,
+		Rows:      model.Rows,
+		SizeBytes: model.SizeBytes,
+	}
+
+	if model.Bound == "DEFAULT" {
+		p.Default = true
+		return p, nil
+	}
+
+	matches := rangeBoundRegexp.FindStringSubmatch(model.Bound)
+	if matches == nil {
+		return Partition{}, terrors.InternalService("partition_bound",
+			fmt.Sprintf("Unsupported bound %s of partition %s", model.Bound, model.Name), map[string]string{})
+	}
+
+	from, ok := parseBoundValue(matches[1])
+	if !ok {
+		return Partition{}, terrors.InternalService("partition_bound",
+			fmt.Sprintf("Invalid lower bound %s of partition %s", matches[1], model.Name), map[string]string{})
+	}
+	to, ok := parseBoundValue(matches[2])
+	if !ok {
+		return Partition{}, terrors.InternalService("partition_bound",
+			fmt.Sprintf("Invalid upper bound %s of partition %s", matches[2], model.Name), map[string]string{})
+	}
+	p.From, p.To = from, to
+
+	return p, nil
+}
+
+// partitionName name given by ensure_monthly_partition to the partition of a month
+func partitionName(table string, month time.Time) string {
+	return table + "_p" + month.UTC().Format(partitionMonthLayout)
+}
+
+// monthStart returns the first instant of the month of t in UTC
+func monthStart(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+}
+
+// parseBoundValue parses a range bound value, MINVALUE and MAXVALUE are unbounded and return nil
+func parseBoundValue(value string) (*time.Time, bool) {
+	if value == "MINVALUE" || value == "MAXVALUE" {
+		return nil, true
+	}
+
+	value = strings.Trim(value, "'")
+	for _, layout := range boundLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			t = t.UTC()
+			return &t, true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,90 @@
+package partition
+
+import (
+	"testing"
+	"time"
+
+	opartition "github.com/jmontesinos91/collector/internal/repositories/partition"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestToPartition(t *testing.T) {
+	from := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name     string
+		model    opartition.Model
+		expected Partition
+		errorMsg string
+	}{
+		{
+			name: "Happy path monthly range in UTC",
+			model: opartition.Model{
+				Name:      "traffic_p202610",
+				Bound:     "FOR VALUES FROM ('2026-10-01 00:00:00+00') TO ('2026-11-01 00:00:00+00')",
+				Rows:      120,
+				SizeBytes: 8192,
+			},
+			expected: Partition{Name: "traffic_p202610", From: &from, To: &to, Rows: 120, SizeBytes: 8192},
+		},
+		{
+			name: "Range rendered in another session time zone",
+			model: opartition.Model{
+				Name:  "traffic_p202610",
+				Bound: "FOR VALUES FROM ('2026-09-30 18:00:00-06') TO ('2026-10-31 18:00:00-06')",
+			},
+			expected: Partition{Name: "traffic_p202610", From: &from, To: &to},
+		},
+		{
+			name: "Range with minute offsets",
+			model: opartition.Model{
+				Name:  "traffic_p202610",
+				Bound: "FOR VALUES FROM ('2026-10-01 05:30:00+05:30') TO ('2026-11-01 05:30:00+05:30')",
+			},
+			expected: Partition{Name: "traffic_p202610", From: &from, To: &to},
+		},
+		{
+			name: "Unbounded range",
+			model: opartition.Model{
+				Name:  "traffic_legacy",
+				Bound: "FOR VALUES FROM (MINVALUE) TO ('2026-10-01 00:00:00+00')",
+			},
+			expected: Partition{Name: "traffic_legacy", To: &from},
+		},
+		{
+			name:     "Default partition",
+			model:    opartition.Model{Name: "traffic_default", Bound: "DEFAULT", Rows: 3},
+			expected: Partition{Name: "traffic_default", Default: true, Rows: 3},
+		},
+		{
+			name:     "Unsupported bound",
+			model:    opartition.Model{Name: "traffic_list", Bound: "FOR VALUES IN ('a')"},
+			errorMsg: "Unsupported bound FOR VALUES IN ('a') of partition traffic_list",
+		},
+		{
+			name:     "Invalid timestamp",
+			model:    opartition.Model{Name: "traffic_p202610", Bound: "FOR VALUES FROM ('yesterday') TO ('2026-11-01 00:00:00+00')"},
+			errorMsg: "Invalid lower bound 'yesterday' of partition traffic_p202610",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := ToPartition(tt.model)
+			if tt.errorMsg != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+				return
+			}
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, result)
+		})
+	}
+}
+
+func TestPartitionName(t *testing.T) {
+	month := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.Equal(t, "traffic_p202601", partitionName("traffic", month))
+	assert.Equal(t, month, monthStart(time.Date(2026, 1, 31, 23, 59, 0, 0, time.UTC)))
+}
@@ -0,0 +1,58 @@
+package partition
+
+import "time"
+
+// Policy retention policy of a table. The retention and action apply to the partitions of the partitioned
+// tables, a zero retention keeps every partition
+type Policy struct {
+	Table           string
+	RetentionMonths int
+	Action          string
+	// TenantRetentionMonths retention of the rows of a tenant keyed by tenant id, it is only taken by the
+	// tables whose rows carry a tenant
+	TenantRetentionMonths map[int]int
+}
+
+// Opts partition maintenance options
+type Opts struct {
+	Policies      []Policy
+	PremakeMonths int
+}
+
+// Partition a monthly partition of a table, the default partition has no bounds
+type Partition struct {
+	Name      string     `json:"name"`
+	From      *time.Time `json:"from,omitempty"`
+	To        *time.Time `json:"to,omitempty"`
+	Default   bool       `json:"default"`
+	Rows      int64      `json:"rows"`
+	SizeBytes int64      `json:"sizeBytes"`
+}
+
+// TableReport partitions and sizes of a table under a retention policy
+type TableReport struct {
+	Table           string      `json:"table"`
+	RetentionMonths int         `json:"retentionMonths"`
+	Action          string      `json:"action"`
+	Partitions      []Partition `json:"partitions"`
+	Rows            int64       `json:"rows"`
+	SizeBytes       int64       `json:"sizeBytes"`
+}
+
+// MaintenanceResult partitions created ahead and partitions expired by the retention policy of a table.
+// On a dry run nothing is changed and the result lists what would be done
+type MaintenanceResult struct {
+	Table   string        `json:"table"`
+	Action  string        `json:"action"`
+	DryRun  bool          `json:"dryRun"`
+	Created []string      `json:"created"`
+	Expired []string      `json:"expired"`
+	Purged  []TenantPurge `json:"purged"`
+}
+
+// TenantPurge rows of a tenant deleted for being past its retention, on a dry run the rows that would be
+type TenantPurge struct {
+	TenantID int       `json:"tenantId"`
+	Before   time.Time `json:"before"`
+	Rows     int       `json:"rows"`
+}
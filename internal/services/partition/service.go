@@ -0,0 +1,9 @@
+package partition
+
+import "context"
+
+// IService Manage the monthly partitions and the retention of the history tables
+type IService interface {
+	Report(ctx context.Context) ([]TableReport, error)
+	Maintain(ctx context.Context, dryRun bool) ([]MaintenanceResult, error)
+}
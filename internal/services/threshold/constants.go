@@ -0,0 +1,20 @@
+package threshold
+
+import (
+	"time"
+
+	"github.com/jmontesinos91/collector/internal/repositories/traffic"
+)
+
+// DefaultWindow window of a threshold configured without one
+const DefaultWindow = traffic.Window5m
+
+// MaxWindow largest window of a threshold, older frames buckets are purged
+const MaxWindow = traffic.Window24h
+
+// windowNames names of the rolling windows listed on the traffics
+var windowNames = map[time.Duration]string{
+	traffic.Window5m:  "5m",
+	traffic.Window1h:  "1h",
+	traffic.Window24h: "24h",
+}
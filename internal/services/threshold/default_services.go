@@ -0,0 +1,182 @@
+package threshold
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/jmontesinos91/collector/internal/repositories/traffic"
+	"github.com/jmontesinos91/collector/internal/services/livefeed"
+	"github.com/jmontesinos91/collector/internal/services/webhook"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultService struct
+type DefaultService struct {
+	log         *logger.ContextLogger
+	trafficRepo traffic.IRepository
+	feed        livefeed.IPublisher
+	webhooks    webhook.IDispatcher
+	opts        Opts
+	windows     []windowQuery
+	mu          sync.RWMutex
+	quarantined map[string]time.Time
+}
+
+// NewDefaultService creates a new instance of DefaultService
+func NewDefaultService(l *logger.ContextLogger, r traffic.IRepository, fp livefeed.IPublisher, wd webhook.IDispatcher,
+	o Opts) *DefaultService {
+	o.Default = Normalize(o.Default)
+	tenants := make(map[int]Threshold, len(o.Tenants))
+	for tenantID, threshold := range o.Tenants {
+		tenants[tenantID] = Normalize(threshold)
+	}
+	devices := make(map[string]Threshold, len(o.Devices))
+	for imei, threshold := range o.Devices {
+		devices[imei] = Normalize(threshold)
+	}
+	o.Tenants, o.Devices = tenants, devices
+
+	return &DefaultService{
+		log:         l,
+		trafficRepo: r,
+		feed:        fp,
+		webhooks:    wd,
+		opts:        o,
+		windows:     windowQueries(o),
+		quarantined: map[string]time.Time{},
+	}
+}
+
+// Evaluate flags the devices over their threshold and clears the flagged ones back under it. The
+// quarantined devices are reloaded from the flagged rows so every instance rejects the same devices.
+func (s *DefaultService) Evaluate(ctx context.Context) {
+	flagged, err := s.trafficRepo.RetrieveExcessive(ctx)
+	if err != nil {
+		s.log.Error(logrus.ErrorLevel, "Evaluate", "Failed to retrieve the excessive devices", err)
+		return
+	}
+
+	exceeding := make(map[string]excess)
+	for _, wq := range s.windows {
+		totals, err := s.trafficRepo.WindowTotals(ctx, wq.window, wq.minFrames, wq.minPanics)
+		if err != nil {
+			// Without every window a device could be cleared while it is still over its threshold
+			s.log.Error(logrus.ErrorLevel, "Evaluate", "Failed to sum the frames of the devices", err)
+			return
+		}
+
+		for _, total := range totals {
+			threshold := s.thresholdOf(total.IMEI, total.TenantID)
+			if threshold.Window == wq.window && Exceeds(threshold, total) {
+				exceeding[total.IMEI] = excess{total: total, threshold: threshold}
+			}
+		}
+	}
+
+	now := time.Now().UTC()
+	var quarantinedUntil *time.Time
+	if s.opts.Quarantine > 0 {
+		until := now.Add(s.opts.Quarantine)
+		quarantinedUntil = &until
+	}
+
+	quarantined := make(map[string]time.Time)
+	wasFlagged := make(map[string]bool, len(flagged))
+	var normal []string
+	for _, device := range flagged {
+		wasFlagged[device.IMEI] = true
+		if _, ok := exceeding[device.IMEI]; !ok {
+			normal = append(normal, device.IMEI)
+			continue
+		}
+		if device.QuarantinedUntil != nil {
+			quarantined[device.IMEI] = *device.QuarantinedUntil
+		}
+	}
+
+	newlyFlagged, err := s.trafficRepo.FlagExcessive(ctx, slices.Sorted(maps.Keys(exceeding)), now, quarantinedUntil)
+	if err != nil {
+		s.log.Error(logrus.ErrorLevel, "Evaluate", "Failed to flag the excessive devices", err)
+	}
+	for _, imei := range newlyFlagged {
+		if quarantinedUntil != nil && quarantinedUntil.After(quarantined[imei]) {
+			quarantined[imei] = *quarantinedUntil
+		}
+		// A device flagged before only had a new traffic row flagged, it was already announced
+		if !wasFlagged[imei] {
+			s.announce(ctx, exceeding[imei], now, quarantinedUntil)
+		}
+	}
+
+	cleared, err := s.trafficRepo.ClearExcessive(ctx, normal)
+	if err != nil {
+		s.log.Error(logrus.ErrorLevel, "Evaluate", "Failed to clear the excessive devices", err)
+	}
+	if len(cleared) > 0 {
+		s.log.WithContext(logrus.InfoLevel,
+			"Evaluate",
+			"Devices back under their frames threshold",
+			logger.Context{
+				"Cleared": cleared,
+			},
+			nil)
+	}
+
+	s.mu.Lock()
+	s.quarantined = quarantined
+	s.mu.Unlock()
+}
+
+// IsQuarantined reports whether the device was flagged and its quarantine has not ended yet
+func (s *DefaultService) IsQuarantined(imei string) bool {
+	s.mu.RLock()
+	until, ok := s.quarantined[imei]
+	s.mu.RUnlock()
+
+	return ok && time.Now().Before(until)
+}
+
+// thresholdOf returns the threshold of a device, the one of the device overrides the one of its tenant
+func (s *DefaultService) thresholdOf(imei string, tenantID int) Threshold {
+	if threshold, ok := s.opts.Devices[imei]; ok {
+		return threshold
+	}
+	if threshold, ok := s.opts.Tenants[tenantID]; ok {
+		return threshold
+	}
+	return s.opts.Default
+}
+
+// announce publishes a device newly over its threshold to the live feed and the webhooks of its tenant
+func (s *DefaultService) announce(ctx context.Context, e excess, at time.Time, quarantinedUntil *time.Time) {
+	s.log.WithContext(logrus.WarnLevel,
+		"Evaluate",
+		"Device over its frames threshold",
+		logger.Context{
+			"IMEI":             e.total.IMEI,
+			"TenantID":         e.total.TenantID,
+			"Window":           WindowName(e.threshold.Window),
+			"Frames":           e.total.Frames,
+			"PanicFrames":      e.total.Panics,
+			"QuarantinedUntil": quarantinedUntil,
+		},
+		nil)
+
+	s.feed.Publish(ctx, livefeed.Event{
+		Type:     livefeed.EventExcessiveTraffic,
+		IMEI:     e.total.IMEI,
+		TenantID: e.total.TenantID,
+		At:       at,
+	})
+
+	s.webhooks.Dispatch(ctx, webhook.Event{
+		Type:       webhook.EventDeviceExcessiveTraffic,
+		TenantID:   e.total.TenantID,
+		OccurredAt: at,
+		Data:       ToExcessiveEventData(e.total, e.threshold, quarantinedUntil),
+	})
+}
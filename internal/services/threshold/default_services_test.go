@@ -0,0 +1,202 @@
+package threshold_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	otraffic "github.com/jmontesinos91/collector/internal/repositories/traffic"
+	"github.com/jmontesinos91/collector/internal/repositories/traffic/trafficmocks"
+	"github.com/jmontesinos91/collector/internal/services/livefeed"
+	"github.com/jmontesinos91/collector/internal/services/livefeed/publishermocks"
+	"github.com/jmontesinos91/collector/internal/services/threshold"
+	"github.com/jmontesinos91/collector/internal/services/webhook"
+	"github.com/jmontesinos91/collector/internal/services/webhook/dispatchermocks"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/terrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestEvaluate(t *testing.T) {
+	log := logger.NewContextLogger("Evaluate", "debug", logger.TextFormat)
+	later := time.Now().UTC().Add(time.Hour)
+	earlier := time.Now().UTC().Add(-time.Hour)
+
+	type mocks struct { //nolint:wsl
+		repo      *trafficmocks.IRepository
+		feed      *publishermocks.IPublisher
+		webhooks  *dispatchermocks.IDispatcher
+		evaluator *threshold.DefaultService
+	}
+	cases := []struct { //nolint:wsl
+		name     string
+		opts     threshold.Opts
+		repoFunc func() *trafficmocks.IRepository
+		asserts  func(*testing.T, mocks) bool
+	}{
+		{
+			name: "Device over its threshold is flagged, announced and quarantined",
+			opts: threshold.Opts{
+				Default:    threshold.Threshold{Window: otraffic.Window5m, Frames: 300},
+				Quarantine: 10 * time.Minute,
+			},
+			repoFunc: func() *trafficmocks.IRepository {
+				repositoryMock := &trafficmocks.IRepository{}
+				repositoryMock.On("RetrieveExcessive", mock.Anything).Return([]otraffic.ExcessiveDevice{}, nil)
+				repositoryMock.On("WindowTotals", mock.Anything, otraffic.Window5m, 300, 0).
+					Return([]otraffic.WindowTotal{
+						{IMEI: "861585041440544", TenantID: 4, Frames: 320},
+						{IMEI: "861585041440545", TenantID: 4, Frames: 299},
+					}, nil)
+				repositoryMock.On("FlagExcessive", mock.Anything, []string{"861585041440544"}, mock.Anything,
+					mock.MatchedBy(func(until *time.Time) bool {
+						return until != nil && time.Until(*until) > 9*time.Minute
+					})).Return([]string{"861585041440544"}, nil)
+				repositoryMock.On("ClearExcessive", mock.Anything, []string(nil)).Return(nil, nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, m mocks) bool {
+				return m.repo.AssertExpectations(t) &&
+					assert.True(t, m.evaluator.IsQuarantined("861585041440544")) &&
+					assert.False(t, m.evaluator.IsQuarantined("861585041440545")) &&
+					m.feed.AssertCalled(t, "Publish", mock.Anything, mock.MatchedBy(func(e livefeed.Event) bool {
+						return e.Type == livefeed.EventExcessiveTraffic && e.IMEI == "861585041440544" && e.TenantID == 4
+					})) &&
+					m.webhooks.AssertCalled(t, "Dispatch", mock.Anything, mock.MatchedBy(func(e webhook.Event) bool {
+						data, ok := e.Data.(threshold.ExcessiveEventData)
+						return e.Type == webhook.EventDeviceExcessiveTraffic && e.TenantID == 4 &&
+							ok && data.Frames == 320 && data.FramesLimit == 300 && data.Window == "5m" &&
+							data.QuarantinedUntil != nil
+					}))
+			},
+		},
+		{
+			name: "Device and tenant thresholds override the default one",
+			opts: threshold.Opts{
+				Default: threshold.Threshold{Window: otraffic.Window5m, Frames: 300},
+				Tenants: map[int]threshold.Threshold{4: {Window: otraffic.Window5m, Frames: 1000}},
+				Devices: map[string]threshold.Threshold{"861585041440546": {Window: otraffic.Window5m, PanicFrames: 3}},
+			},
+			repoFunc: func() *trafficmocks.IRepository {
+				repositoryMock := &trafficmocks.IRepository{}
+				repositoryMock.On("RetrieveExcessive", mock.Anything).Return([]otraffic.ExcessiveDevice{}, nil)
+				repositoryMock.On("WindowTotals", mock.Anything, otraffic.Window5m, 300, 3).
+					Return([]otraffic.WindowTotal{
+						{IMEI: "861585041440544", TenantID: 4, Frames: 500},
+						{IMEI: "861585041440545", TenantID: 7, Frames: 500},
+						{IMEI: "861585041440546", TenantID: 4, Frames: 10, Panics: 3},
+					}, nil)
+				repositoryMock.On("FlagExcessive", mock.Anything, []string{"861585041440545", "861585041440546"},
+					mock.Anything, (*time.Time)(nil)).Return([]string{"861585041440545", "861585041440546"}, nil)
+				repositoryMock.On("ClearExcessive", mock.Anything, []string(nil)).Return(nil, nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, m mocks) bool {
+				return m.repo.AssertExpectations(t) &&
+					assert.False(t, m.evaluator.IsQuarantined("861585041440545")) &&
+					m.webhooks.AssertNumberOfCalls(t, "Dispatch", 2)
+			},
+		},
+		{
+			name: "Flagged device back under its threshold is cleared",
+			opts: threshold.Opts{
+				Default:    threshold.Threshold{Window: otraffic.Window5m, Frames: 300},
+				Quarantine: 10 * time.Minute,
+			},
+			repoFunc: func() *trafficmocks.IRepository {
+				repositoryMock := &trafficmocks.IRepository{}
+				repositoryMock.On("RetrieveExcessive", mock.Anything).Return([]otraffic.ExcessiveDevice{
+					{IMEI: "861585041440544", QuarantinedUntil: &later},
+					{IMEI: "861585041440545", QuarantinedUntil: &later},
+				}, nil)
+				repositoryMock.On("WindowTotals", mock.Anything, otraffic.Window5m, 300, 0).
+					Return([]otraffic.WindowTotal{{IMEI: "861585041440545", TenantID: 4, Frames: 400}}, nil)
+				repositoryMock.On("FlagExcessive", mock.Anything, []string{"861585041440545"}, mock.Anything, mock.Anything).
+					Return([]string{}, nil)
+				repositoryMock.On("ClearExcessive", mock.Anything, []string{"861585041440544"}).
+					Return([]string{"861585041440544"}, nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, m mocks) bool {
+				return m.repo.AssertExpectations(t) &&
+					assert.False(t, m.evaluator.IsQuarantined("861585041440544")) &&
+					assert.True(t, m.evaluator.IsQuarantined("861585041440545")) &&
+					m.webhooks.AssertNotCalled(t, "Dispatch", mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name: "Ended quarantine of a device still over its threshold",
+			opts: threshold.Opts{
+				Default: threshold.Threshold{Window: otraffic.Window5m, Frames: 300},
+			},
+			repoFunc: func() *trafficmocks.IRepository {
+				repositoryMock := &trafficmocks.IRepository{}
+				repositoryMock.On("RetrieveExcessive", mock.Anything).Return([]otraffic.ExcessiveDevice{
+					{IMEI: "861585041440544", QuarantinedUntil: &earlier},
+				}, nil)
+				repositoryMock.On("WindowTotals", mock.Anything, otraffic.Window5m, 300, 0).
+					Return([]otraffic.WindowTotal{{IMEI: "861585041440544", TenantID: 4, Frames: 400}}, nil)
+				repositoryMock.On("FlagExcessive", mock.Anything, []string{"861585041440544"}, mock.Anything, mock.Anything).
+					Return([]string{}, nil)
+				repositoryMock.On("ClearExcessive", mock.Anything, []string(nil)).Return(nil, nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, m mocks) bool {
+				return m.repo.AssertExpectations(t) &&
+					assert.False(t, m.evaluator.IsQuarantined("861585041440544"))
+			},
+		},
+		{
+			name: "Error summing frames keeps the flags",
+			opts: threshold.Opts{
+				Default: threshold.Threshold{Window: otraffic.Window5m, Frames: 300},
+			},
+			repoFunc: func() *trafficmocks.IRepository {
+				repositoryMock := &trafficmocks.IRepository{}
+				repositoryMock.On("RetrieveExcessive", mock.Anything).Return([]otraffic.ExcessiveDevice{
+					{IMEI: "861585041440544"},
+				}, nil)
+				repositoryMock.On("WindowTotals", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+					Return(nil, terrors.InternalService("window_totals", "Failed sum frames buckets from the database", map[string]string{}))
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, m mocks) bool {
+				return m.repo.AssertExpectations(t) &&
+					m.repo.AssertNotCalled(t, "ClearExcessive", mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name: "Error retrieving the flagged devices",
+			repoFunc: func() *trafficmocks.IRepository {
+				repositoryMock := &trafficmocks.IRepository{}
+				repositoryMock.On("RetrieveExcessive", mock.Anything).
+					Return(nil, terrors.InternalService("retrieve_excessive", "Failed retrieve excessive devices from the database", map[string]string{}))
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, m mocks) bool {
+				return m.repo.AssertExpectations(t) &&
+					m.repo.AssertNotCalled(t, "WindowTotals", mock.Anything, mock.Anything, mock.Anything, mock.Anything)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			m := mocks{
+				repo:     tc.repoFunc(),
+				feed:     &publishermocks.IPublisher{},
+				webhooks: &dispatchermocks.IDispatcher{},
+			}
+			m.feed.On("Publish", mock.Anything, mock.Anything).Return()
+			m.webhooks.On("Dispatch", mock.Anything, mock.Anything).Return()
+			m.evaluator = threshold.NewDefaultService(log, m.repo, m.feed, m.webhooks, tc.opts)
+
+			m.evaluator.Evaluate(context.Background())
+
+			if !tc.asserts(t, m) {
+				t.Errorf("Assert error on test = '%v'", tc.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package threshold
+
+import (
+	"cmp"
+	"slices"
+	"time"
+
+	"github.com/jmontesinos91/collector/internal/repositories/traffic"
+)
+
+// Normalize bounds the window of the threshold to the frames buckets kept
+func Normalize(threshold Threshold) Threshold {
+	if threshold.Window <= 0 {
+		threshold.Window = DefaultWindow
+	}
+	threshold.Window = min(threshold.Window, MaxWindow)
+	return threshold
+}
+
+// Exceeds reports whether the frames of a device reached any of the limits of the threshold
+func Exceeds(threshold Threshold, total traffic.WindowTotal) bool {
+	return (threshold.Frames > 0 && total.Frames >= threshold.Frames) ||
+		(threshold.PanicFrames > 0 && total.Panics >= threshold.PanicFrames)
+}
+
+// WindowName formats a window like the rolling windows listed on the traffics
+func WindowName(window time.Duration) string {
+	if name, ok := windowNames[window]; ok {
+		return name
+	}
+	return window.String()
+}
+
+// ToExcessiveEventData maps a device over its threshold into the webhook data
+func ToExcessiveEventData(total traffic.WindowTotal, threshold Threshold, quarantinedUntil *time.Time) ExcessiveEventData {
+	return ExcessiveEventData{
+		IMEI:             total.IMEI,
+		Window:           WindowName(threshold.Window),
+		Frames:           total.Frames,
+		PanicFrames:      total.Panics,
+		FramesLimit:      max(threshold.Frames, 0),
+		PanicFramesLimit: max(threshold.PanicFrames, 0),
+		QuarantinedUntil: quarantinedUntil,
+	}
+}
+
+// windowQueries groups the enabled thresholds by window keeping the lowest limits of each one
+func windowQueries(opts Opts) []windowQuery {
+	thresholds := []Threshold{opts.Default}
+	for _, threshold := range opts.Tenants {
+		thresholds = append(thresholds, threshold)
+	}
+	for _, threshold := range opts.Devices {
+		thresholds = append(thresholds, threshold)
+	}
+
+	byWindow := make(map[time.Duration]*windowQuery)
+	for _, threshold := range thresholds {
+		if threshold.Frames < 1 && threshold.PanicFrames < 1 {
+			continue
+		}
+
+		wq, ok := byWindow[threshold.Window]
+		if !ok {
+			wq = &windowQuery{window: threshold.Window}
+			byWindow[threshold.Window] = wq
+		}
+		wq.minFrames = lowestLimit(wq.minFrames, threshold.Frames)
+		wq.minPanics = lowestLimit(wq.minPanics, threshold.PanicFrames)
+	}
+
+	queries := make([]windowQuery, 0, len(byWindow))
+	for _, wq := range byWindow {
+		queries = append(queries, *wq)
+	}
+	slices.SortFunc(queries, func(a, b windowQuery) int {
+		return cmp.Compare(a.window, b.window)
+	})
+
+	return queries
+}
+
+// lowestLimit returns the lowest of two limits ignoring the disabled ones
+func lowestLimit(current, limit int) int {
+	if limit < 1 {
+		return current
+	}
+	if current < 1 {
+		return limit
+	}
+	return min(current, limit)
+}
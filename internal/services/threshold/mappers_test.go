@@ -0,0 +1,97 @@
+package threshold
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jmontesinos91/collector/internal/repositories/traffic"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	assert.Equal(t, DefaultWindow, Normalize(Threshold{Frames: 10}).Window)
+	assert.Equal(t, MaxWindow, Normalize(Threshold{Window: 48 * time.Hour}).Window)
+	assert.Equal(t, traffic.Window1h, Normalize(Threshold{Window: traffic.Window1h}).Window)
+}
+
+func TestExceeds(t *testing.T) {
+	tests := []struct {
+		name      string
+		threshold Threshold
+		total     traffic.WindowTotal
+		expected  bool
+	}{
+		{
+			name:      "Frames limit reached",
+			threshold: Threshold{Frames: 100},
+			total:     traffic.WindowTotal{Frames: 100},
+			expected:  true,
+		},
+		{
+			name:      "Panic frames limit reached",
+			threshold: Threshold{Frames: 100, PanicFrames: 5},
+			total:     traffic.WindowTotal{Frames: 10, Panics: 6},
+			expected:  true,
+		},
+		{
+			name:      "Under every limit",
+			threshold: Threshold{Frames: 100, PanicFrames: 5},
+			total:     traffic.WindowTotal{Frames: 99, Panics: 4},
+			expected:  false,
+		},
+		{
+			name:      "Disabled limits never exceed",
+			threshold: Threshold{},
+			total:     traffic.WindowTotal{Frames: 1000, Panics: 1000},
+			expected:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Exceeds(tt.threshold, tt.total))
+		})
+	}
+}
+
+func TestWindowName(t *testing.T) {
+	assert.Equal(t, "5m", WindowName(traffic.Window5m))
+	assert.Equal(t, "24h", WindowName(traffic.Window24h))
+	assert.Equal(t, "10m0s", WindowName(10*time.Minute))
+}
+
+func TestToExcessiveEventData(t *testing.T) {
+	until := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	data := ToExcessiveEventData(
+		traffic.WindowTotal{IMEI: "861585041440544", TenantID: 4, Frames: 320, Panics: 2},
+		Threshold{Window: traffic.Window5m, Frames: 300, PanicFrames: -1},
+		&until)
+
+	assert.Equal(t, ExcessiveEventData{
+		IMEI:             "861585041440544",
+		Window:           "5m",
+		Frames:           320,
+		PanicFrames:      2,
+		FramesLimit:      300,
+		QuarantinedUntil: &until,
+	}, data)
+}
+
+func TestWindowQueries(t *testing.T) {
+	queries := windowQueries(Opts{
+		Default: Threshold{Window: traffic.Window5m, Frames: 300, PanicFrames: 20},
+		Tenants: map[int]Threshold{
+			4: {Window: traffic.Window5m, Frames: 100},
+			5: {Window: traffic.Window1h, PanicFrames: 50},
+			6: {Window: traffic.Window24h},
+		},
+		Devices: map[string]Threshold{
+			"861585041440544": {Window: traffic.Window1h, Frames: 2000, PanicFrames: 80},
+		},
+	})
+
+	assert.Equal(t, []windowQuery{
+		{window: traffic.Window5m, minFrames: 100, minPanics: 20},
+		{window: traffic.Window1h, minFrames: 2000, minPanics: 50},
+	}, queries)
+}
@@ -0,0 +1,50 @@
+package threshold
+
+import (
+	"time"
+
+	"github.com/jmontesinos91/collector/internal/repositories/traffic"
+)
+
+// Threshold frames and panic frames a device may send within the window before it is flagged,
+// a limit lower than one is disabled
+type Threshold struct {
+	Window      time.Duration
+	Frames      int
+	PanicFrames int
+}
+
+// Opts thresholds options, the threshold of a device overrides the one of its tenant which
+// overrides the default one
+type Opts struct {
+	Default Threshold
+	Tenants map[int]Threshold
+	Devices map[string]Threshold
+	// Quarantine time the frames of a newly flagged device are rejected, zero disables it
+	Quarantine time.Duration
+}
+
+// ExcessiveEventData webhook data of a device that crossed its frames threshold
+type ExcessiveEventData struct {
+	IMEI             string     `json:"imei"`
+	Window           string     `json:"window"`
+	Frames           int        `json:"frames"`
+	PanicFrames      int        `json:"panicFrames"`
+	FramesLimit      int        `json:"framesLimit,omitempty"`
+	PanicFramesLimit int        `json:"panicFramesLimit,omitempty"`
+	QuarantinedUntil *time.Time `json:"quarantinedUntil,omitempty"`
+}
+
+// windowQuery devices summed over a window, only the ones reaching the lowest limits of the
+// thresholds on that window can cross theirs
+type windowQuery struct {
+	window    time.Duration
+	minFrames int
+	minPanics int
+}
+
+// excess a device over its threshold
+type excess struct {
+	total     traffic.WindowTotal
+	threshold Threshold
+}
@@ -0,0 +1,17 @@
+package threshold
+
+import (
+	"context"
+)
+
+// IQuarantine Tells whether the frames of a device must be rejected, the collector depends on it
+// to enforce the quarantine of the devices over their threshold
+type IQuarantine interface {
+	IsQuarantined(imei string) bool
+}
+
+// IService Watch the frames of the devices against their thresholds
+type IService interface {
+	IQuarantine
+	Evaluate(ctx context.Context)
+}
@@ -2,9 +2,14 @@ package traffic
 
 import (
 	"context"
+	"time"
+
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/jmontesinos91/collector/domains/pagination"
+	"github.com/jmontesinos91/collector/domains/tenancy"
 	otraffic "github.com/jmontesinos91/collector/internal/repositories/traffic"
+	"github.com/jmontesinos91/collector/internal/services/audit"
+	"github.com/jmontesinos91/collector/internal/services/livefeed"
 	"github.com/jmontesinos91/ologs/logger"
 	tracekey "github.com/jmontesinos91/ologs/logger/v2"
 	"github.com/jmontesinos91/osecurity/sts"
@@ -15,12 +20,19 @@ import (
 type DefaultService struct {
 	log         *logger.ContextLogger
 	trafficRepo otraffic.IRepository
+	auditor     audit.IRecorder
+	feed        livefeed.IPublisher
+	opts        Opts
 }
 
-func NewDefaultService(l *logger.ContextLogger, tr otraffic.IRepository) *DefaultService {
+func NewDefaultService(l *logger.ContextLogger, tr otraffic.IRepository, ar audit.IRecorder, fp livefeed.IPublisher,
+	opts Opts) *DefaultService {
 	return &DefaultService{
 		log:         l,
 		trafficRepo: tr,
+		auditor:     ar,
+		feed:        fp,
+		opts:        opts,
 	}
 }
 
@@ -28,7 +40,8 @@ func (s *DefaultService) HandleRetrieve(ctx context.Context, filter *FilterReque
 	requestID := ctx.Value(middleware.RequestIDKey).(string)
 	claims := ctx.Value(&sts.Claim).(sts.Claims)
 
-	repoFilter := ToMetadata(filter)
+	_ = filter.Filter.SanitizePageFilter()
+	repoFilter := s.metadata(claims, filter)
 	trafficModels, pages, totalRecords, err := s.trafficRepo.Retrieve(ctx, repoFilter)
 	if err != nil {
 		s.log.WithContext(logrus.ErrorLevel,
@@ -46,6 +59,29 @@ func (s *DefaultService) HandleRetrieve(ctx context.Context, filter *FilterReque
 	return ToPaginatedResponse(ToTrafficSlice(trafficModels), filter.Filter.Page, pages, totalRecords), nil
 }
 
+// HandleRetrieveCursor retrieves a page of traffics paginated by keyset
+func (s *DefaultService) HandleRetrieveCursor(ctx context.Context, filter *FilterRequest) (pagination.CursorPaginatedRes, error) {
+	requestID := ctx.Value(middleware.RequestIDKey).(string)
+	claims := ctx.Value(&sts.Claim).(sts.Claims)
+
+	_ = filter.Filter.SanitizePageFilter()
+	page, err := s.trafficRepo.RetrieveCursor(ctx, s.metadata(claims, filter))
+	if err != nil {
+		s.log.WithContext(logrus.ErrorLevel,
+			"HandleRetrieveCursor",
+			"Failed to retrieve traffics",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+				tracekey.UserID:     claims.UserID,
+				tracekey.Role:       claims.Role,
+			},
+			err)
+		return pagination.CursorPaginatedRes{}, err
+	}
+
+	return ToCursorPaginatedResponse(ToTrafficSlice(page.Models), page.Next, page.Prev, filter.Filter.Size, page.Total), nil
+}
+
 func (s *DefaultService) HandleDelete(ctx context.Context, trafficID string) error {
 	requestID := ctx.Value(middleware.RequestIDKey).(string)
 	claims := ctx.Value(&sts.Claim).(sts.Claims)
@@ -53,7 +89,12 @@ func (s *DefaultService) HandleDelete(ctx context.Context, trafficID string) err
 		return terrors.New(terrors.ErrBadRequest, "Invalid trafficID", map[string]string{})
 	}
 
-	err := s.trafficRepo.DeleteByID(ctx, trafficID)
+	before, err := s.authorize(ctx, claims, trafficID)
+	if err != nil {
+		return err
+	}
+
+	err = s.trafficRepo.DeleteByID(ctx, trafficID)
 	if err != nil {
 		s.log.WithContext(logrus.ErrorLevel,
 			"HandleResetCounter",
@@ -67,6 +108,52 @@ func (s *DefaultService) HandleDelete(ctx context.Context, trafficID string) err
 		return err
 	}
 
+	s.auditor.Record(ctx, audit.Event{
+		Action:   audit.ActionDelete,
+		Resource: audit.ResourceTraffic,
+		TargetID: trafficID,
+		Before:   before,
+		After:    s.snapshot(ctx, trafficID),
+	})
+
+	return nil
+}
+
+// HandleRestore undoes the soft delete of a traffic
+func (s *DefaultService) HandleRestore(ctx context.Context, trafficID string) error {
+	requestID := ctx.Value(middleware.RequestIDKey).(string)
+	claims := ctx.Value(&sts.Claim).(sts.Claims)
+	if trafficID == "" {
+		return terrors.New(terrors.ErrBadRequest, "Invalid trafficID", map[string]string{})
+	}
+
+	before, err := s.authorize(ctx, claims, trafficID)
+	if err != nil {
+		return err
+	}
+
+	err = s.trafficRepo.Restore(ctx, trafficID)
+	if err != nil {
+		s.log.WithContext(logrus.ErrorLevel,
+			"HandleRestore",
+			"Failed to restore traffic resource",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+				tracekey.UserID:     claims.UserID,
+				tracekey.Role:       claims.Role,
+			},
+			err)
+		return err
+	}
+
+	s.auditor.Record(ctx, audit.Event{
+		Action:   audit.ActionRestore,
+		Resource: audit.ResourceTraffic,
+		TargetID: trafficID,
+		Before:   before,
+		After:    s.snapshot(ctx, trafficID),
+	})
+
 	return nil
 }
 
@@ -77,7 +164,12 @@ func (s *DefaultService) HandleResetCounter(ctx context.Context, trafficID strin
 		return terrors.New(terrors.ErrBadRequest, "Invalid trafficID", map[string]string{})
 	}
 
-	err := s.trafficRepo.ResetCounter(ctx, trafficID)
+	before, err := s.authorize(ctx, claims, trafficID)
+	if err != nil {
+		return err
+	}
+
+	err = s.trafficRepo.ResetCounter(ctx, trafficID)
 	if err != nil {
 		s.log.WithContext(logrus.ErrorLevel,
 			"HandleResetCounter",
@@ -91,5 +183,274 @@ func (s *DefaultService) HandleResetCounter(ctx context.Context, trafficID strin
 		return err
 	}
 
+	after := s.snapshot(ctx, trafficID)
+	s.auditor.Record(ctx, audit.Event{
+		Action:   audit.ActionResetCounter,
+		Resource: audit.ResourceTraffic,
+		TargetID: trafficID,
+		Before:   before,
+		After:    after,
+	})
+
+	event := livefeed.Event{Type: livefeed.EventCounterReset, TrafficID: trafficID, TenantID: before.TenantID}
+	if after != nil {
+		event.IMEI, event.IP, event.IsAlarm, event.Counter = after.IMEI, after.Ip, after.IsAlarm, &after.Counter
+	}
+	s.feed.Publish(ctx, event)
+
+	return nil
+}
+
+// HandleExport builds the export of every traffic matching the filters in the requested format, the
+// traffics are streamed through HandleStream while the file is written so it never has to fit in memory
+func (s *DefaultService) HandleExport(ctx context.Context, filter *FilterRequest) (*ExportFile, error) {
+	columns, err := ResolveExportColumns(filter.Columns, s.opts.ExportColumns)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewExportFile(filter.Format, columns, func(fn func(Traffic) error) error {
+		return s.HandleStream(ctx, filter, fn)
+	}), nil
+}
+
+// HandleStream walks every traffic matching the filters, the rows are read through a server side
+// cursor so the result never has to fit in memory. An error returned by fn stops the walk
+func (s *DefaultService) HandleStream(ctx context.Context, filter *FilterRequest, fn func(Traffic) error) error {
+	requestID := ctx.Value(middleware.RequestIDKey).(string)
+	claims := ctx.Value(&sts.Claim).(sts.Claims)
+
+	err := s.trafficRepo.StreamData(ctx, s.metadata(claims, filter), func(model otraffic.Model) error {
+		return fn(ToTraffic(model))
+	})
+	if err != nil {
+		s.log.WithContext(logrus.ErrorLevel,
+			"HandleStream",
+			"Failed to stream traffics",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+				tracekey.UserID:     claims.UserID,
+				tracekey.Role:       claims.Role,
+			},
+			err)
+		return err
+	}
+
+	return nil
+}
+
+// HandleStats aggregates the frames of the traffics matching the filters by the time bucket they were
+// received in and group key
+func (s *DefaultService) HandleStats(ctx context.Context, request *StatsRequest) (Stats, error) {
+	requestID := ctx.Value(middleware.RequestIDKey).(string)
+	claims := ctx.Value(&sts.Claim).(sts.Claims)
+
+	// The stats are aggregated from the frames buckets, a range the buckets no longer cover would come back
+	// empty or partial
+	if err := s.validateStatsRange(request, time.Now().UTC()); err != nil {
+		return Stats{}, err
+	}
+
+	rows, err := s.trafficRepo.Stats(ctx, &otraffic.StatsQuery{
+		GroupBy: request.GroupBy,
+		Bucket:  request.Bucket,
+		From:    request.From,
+		To:      request.To,
+		Limit:   MaximumStatsPoints + 1,
+		Filter:  s.metadata(claims, request.Filter),
+	})
+	if err != nil {
+		s.log.WithContext(logrus.ErrorLevel,
+			"HandleStats",
+			"Failed to aggregate traffics",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+				tracekey.UserID:     claims.UserID,
+				tracekey.Role:       claims.Role,
+			},
+			err)
+		return Stats{}, err
+	}
+
+	if len(rows) > MaximumStatsPoints {
+		return Stats{}, terrors.New(terrors.ErrBadRequest, "Too many data points, narrow the filters or use a wider bucket", map[string]string{})
+	}
+
+	return ToStats(request, rows), nil
+}
+
+func (s *DefaultService) validateStatsRange(request *StatsRequest, now time.Time) error {
+	oldest := now.Add(-s.bucketsRetention()).Truncate(otraffic.BucketWidth)
+	message := "must not be before " + oldest.Format(time.RFC3339) + ", stats are only kept for " + s.bucketsRetention().String()
+
+	fields := map[string]string{}
+	if request.From != nil && request.From.Before(oldest) {
+		fields["from"] = message
+	}
+	if request.To != nil && request.To.Before(oldest) {
+		fields["to"] = message
+	}
+	if len(fields) > 0 {
+		return terrors.New(terrors.ErrBadRequest, "The range is older than the stats retention", fields)
+	}
+
 	return nil
 }
+
+// HandleBulk applies the operation to every traffic matching the request, dry runs only count them
+func (s *DefaultService) HandleBulk(ctx context.Context, request *BulkRequest) (BulkResponse, error) {
+	requestID := ctx.Value(middleware.RequestIDKey).(string)
+	claims := ctx.Value(&sts.Claim).(sts.Claims)
+
+	limit := s.opts.BulkLimit
+	if limit <= 0 {
+		limit = DefaultBulkLimit
+	}
+
+	var affected int
+	var err error
+	repoFilter := s.metadata(claims, request.Filter)
+	if request.DryRun {
+		affected, err = s.trafficRepo.CountData(ctx, repoFilter)
+	} else {
+		affected, err = s.trafficRepo.Bulk(ctx, request.Operation, repoFilter, limit)
+	}
+	if err != nil {
+		s.log.WithContext(logrus.ErrorLevel,
+			"HandleBulk",
+			"Failed to apply bulk operation",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+				tracekey.UserID:     claims.UserID,
+				tracekey.Role:       claims.Role,
+				"Operation":         request.Operation,
+			},
+			err)
+		return BulkResponse{}, err
+	}
+
+	response := BulkResponse{
+		Operation: request.Operation,
+		DryRun:    request.DryRun,
+		Affected:  affected,
+		Limit:     limit,
+	}
+	if !request.DryRun {
+		s.auditor.Record(ctx, audit.Event{
+			Action:   audit.ActionBulk + "-" + request.Operation,
+			Resource: audit.ResourceTraffic,
+			Before:   request.Filter,
+			After:    response,
+		})
+	}
+	if !request.DryRun && request.Operation == otraffic.BulkResetCounter && affected > 0 {
+		s.publishBulk(ctx, repoFilter.Scope, livefeed.Event{Type: livefeed.EventCounterReset, Affected: affected})
+	}
+
+	return response, nil
+}
+
+// PurgeDeleted hard deletes the traffics soft deleted longer than the retention period
+func (s *DefaultService) PurgeDeleted(ctx context.Context) {
+	retention := s.opts.DeletedRetention
+	if retention <= 0 {
+		retention = DefaultDeletedRetention
+	}
+
+	purged, err := s.trafficRepo.PurgeDeleted(ctx, time.Now().UTC().Add(-retention), purgeBatchSize)
+	if err != nil {
+		s.log.Error(logrus.ErrorLevel, "PurgeDeleted", "Failed to purge deleted traffics", err)
+		return
+	}
+
+	if purged > 0 {
+		s.log.WithContext(logrus.InfoLevel,
+			"PurgeDeleted",
+			"Deleted traffics purged",
+			logger.Context{
+				"Purged": purged,
+			},
+			nil)
+	}
+}
+
+// PurgeBuckets deletes the frames buckets older than the retention, the ones within the largest
+// rolling window are always kept
+func (s *DefaultService) PurgeBuckets(ctx context.Context) {
+	purged, err := s.trafficRepo.PurgeBuckets(ctx, time.Now().UTC().Add(-s.bucketsRetention()))
+	if err != nil {
+		s.log.Error(logrus.ErrorLevel, "PurgeBuckets", "Failed to purge frames buckets", err)
+		return
+	}
+
+	if purged > 0 {
+		s.log.WithContext(logrus.InfoLevel,
+			"PurgeBuckets",
+			"Frames buckets purged",
+			logger.Context{
+				"Purged": purged,
+			},
+			nil)
+	}
+}
+
+// bucketsRetention time the frames buckets are kept, never shorter than the largest rolling window
+func (s *DefaultService) bucketsRetention() time.Duration {
+	retention := s.opts.BucketsRetention
+	if retention <= 0 {
+		retention = DefaultBucketsRetention
+	}
+
+	return max(retention, otraffic.Window24h+otraffic.BucketWidth)
+}
+
+// metadata maps the filter into the repository filter restricted to the tenants of the caller
+func (s *DefaultService) metadata(claims sts.Claims, filter *FilterRequest) *otraffic.Metadata {
+	repoFilter := ToMetadata(filter)
+	repoFilter.Scope = s.opts.Tenancy.Scope(claims.Role, claims.Tenants)
+	return repoFilter
+}
+
+// authorize returns the current state of a traffic the caller can reach, the traffics of
+// other tenants are reported as not found so their existence is not disclosed
+func (s *DefaultService) authorize(ctx context.Context, claims sts.Claims, trafficID string) (*Traffic, error) {
+	model, err := s.trafficRepo.FindByID(ctx, trafficID)
+	if err != nil {
+		return nil, err
+	}
+
+	if !s.opts.Tenancy.Scope(claims.Role, claims.Tenants).Allows(model.TenantID) {
+		return nil, terrors.New(terrors.ErrNotFound, "Traffic not found", map[string]string{})
+	}
+
+	t := ToTraffic(*model)
+	return &t, nil
+}
+
+// publishBulk publishes the event of a bulk operation once per tenant of a restricted caller,
+// so it reaches the subscribers of those tenants only
+func (s *DefaultService) publishBulk(ctx context.Context, scope tenancy.Scope, event livefeed.Event) {
+	if scope.All {
+		s.feed.Publish(ctx, event)
+		return
+	}
+
+	for _, tenantID := range scope.Tenants {
+		event.TenantID = tenantID
+		s.feed.Publish(ctx, event)
+	}
+}
+
+// snapshot returns the current state of a traffic for the audit log, nil when it can not be read
+func (s *DefaultService) snapshot(ctx context.Context, trafficID string) *Traffic {
+	model, err := s.trafficRepo.FindByID(ctx, trafficID)
+	if err != nil {
+		if !terrors.Is(err, terrors.ErrNotFound) {
+			s.log.Error(logrus.WarnLevel, "snapshot", "Failed to read traffic state for the audit log", err)
+		}
+		return nil
+	}
+
+	t := ToTraffic(*model)
+	return &t
+}
@@ -1,11 +1,18 @@
 package traffic_test
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/jmontesinos91/collector/domains/pagination"
+	"github.com/jmontesinos91/collector/domains/tenancy"
 	otraffic "github.com/jmontesinos91/collector/internal/repositories/traffic"
 	"github.com/jmontesinos91/collector/internal/repositories/traffic/trafficmocks"
+	"github.com/jmontesinos91/collector/internal/services/audit"
+	"github.com/jmontesinos91/collector/internal/services/audit/recordermocks"
+	"github.com/jmontesinos91/collector/internal/services/livefeed"
+	"github.com/jmontesinos91/collector/internal/services/livefeed/publishermocks"
 	"github.com/jmontesinos91/collector/internal/services/traffic"
 	straffic "github.com/jmontesinos91/collector/internal/services/traffic"
 	"github.com/jmontesinos91/ologs/logger"
@@ -13,7 +20,10 @@ import (
 	"github.com/jmontesinos91/terrors"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/xuri/excelize/v2"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestRetrieve(t *testing.T) {
@@ -176,7 +186,7 @@ func TestRetrieve(t *testing.T) {
 				tc.repositoryOpts.trafficRepo = tc.repositoryOpts.trafficRepoFunc()
 			}
 
-			trafficSvc := traffic.NewDefaultService(log, tc.repositoryOpts.trafficRepo)
+			trafficSvc := traffic.NewDefaultService(log, tc.repositoryOpts.trafficRepo, auditRecorder(), feedPublisher(), traffic.Opts{})
 
 			result, err := trafficSvc.HandleRetrieve(tc.args.ctx, tc.args.filter)
 			if (err != nil) != tc.err {
@@ -202,8 +212,9 @@ func TestDeleteTrafficByID(t *testing.T) {
 	ctxBack := context.Background()
 	ctxBack = context.WithValue(ctxBack, middleware.RequestIDKey, "unit-test-request-id")
 	ctxBack = context.WithValue(ctxBack, &sts.Claim, sts.Claims{
-		UserID: 0,
-		Role:   "unit-test-role",
+		UserID:  0,
+		Role:    "unit-test-role",
+		Tenants: []int{1},
 	})
 	log := logger.NewContextLogger("Retrieve", "debug", logger.TextFormat)
 
@@ -235,6 +246,8 @@ func TestDeleteTrafficByID(t *testing.T) {
 			repositoryOpts: repositoryOpts{
 				trafficRepoFunc: func() *trafficmocks.IRepository {
 					repositoryMock := &trafficmocks.IRepository{}
+					repositoryMock.On("FindByID", mock.Anything, mock.Anything).
+						Return(&otraffic.Model{ID: "unit-test-traffic-id", TenantID: 1}, nil)
 					repositoryMock.On("DeleteByID", mock.Anything, mock.Anything).
 						Return(nil)
 					return repositoryMock
@@ -262,11 +275,57 @@ func TestDeleteTrafficByID(t *testing.T) {
 					assert.Equal(t, "Invalid trafficID", terr.Message)
 			},
 		},
+		{
+			name: "Traffic of another tenant",
+			repositoryOpts: repositoryOpts{
+				trafficRepoFunc: func() *trafficmocks.IRepository {
+					repositoryMock := &trafficmocks.IRepository{}
+					repositoryMock.On("FindByID", mock.Anything, mock.Anything).
+						Return(&otraffic.Model{ID: "unit-test-traffic-id", TenantID: 2}, nil)
+					return repositoryMock
+				},
+			},
+			args: args{
+				trafficID: "unit-test-traffic-id",
+			},
+			err: true,
+			asserts: func(t *testing.T, err error, ap assertsParams) bool {
+				return assert.True(t, terrors.Is(err, terrors.ErrNotFound)) &&
+					ap.trafficRepo.AssertNotCalled(t, "DeleteByID", mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name: "Super admin reaches traffics of unregistered devices",
+			repositoryOpts: repositoryOpts{
+				trafficRepoFunc: func() *trafficmocks.IRepository {
+					repositoryMock := &trafficmocks.IRepository{}
+					repositoryMock.On("FindByID", mock.Anything, mock.Anything).
+						Return(&otraffic.Model{ID: "unit-test-traffic-id"}, nil)
+					repositoryMock.On("DeleteByID", mock.Anything, "unit-test-traffic-id").
+						Return(nil)
+					return repositoryMock
+				},
+			},
+			args: args{
+				ctx: context.WithValue(ctxBack, &sts.Claim, sts.Claims{
+					UserID: 0,
+					Role:   "unit-test-super-admin",
+				}),
+				trafficID: "unit-test-traffic-id",
+			},
+			err: false,
+			asserts: func(t *testing.T, err error, ap assertsParams) bool {
+				return assert.NoError(t, err) &&
+					ap.trafficRepo.AssertExpectations(t)
+			},
+		},
 		{
 			name: "Error on delete",
 			repositoryOpts: repositoryOpts{
 				trafficRepoFunc: func() *trafficmocks.IRepository {
 					repositoryMock := &trafficmocks.IRepository{}
+					repositoryMock.On("FindByID", mock.Anything, mock.Anything).
+						Return(&otraffic.Model{ID: "unit-test-traffic-id", TenantID: 1}, nil)
 					repositoryMock.On("DeleteByID", mock.Anything, mock.Anything).
 						Return(terrors.InternalService("delete_traffic", "Failed delete traffic from the database", map[string]string{}))
 
@@ -295,7 +354,9 @@ func TestDeleteTrafficByID(t *testing.T) {
 				tc.repositoryOpts.trafficRepo = tc.repositoryOpts.trafficRepoFunc()
 			}
 
-			trafficSvc := traffic.NewDefaultService(log, tc.repositoryOpts.trafficRepo)
+			trafficSvc := traffic.NewDefaultService(log, tc.repositoryOpts.trafficRepo, auditRecorder(), feedPublisher(), traffic.Opts{
+				Tenancy: tenancy.Policy{SuperAdminRoles: []string{"unit-test-super-admin"}},
+			})
 
 			err := trafficSvc.HandleDelete(tc.args.ctx, tc.args.trafficID)
 			if (err != nil) != tc.err {
@@ -320,8 +381,9 @@ func TestResetCounter(t *testing.T) {
 	ctxBack := context.Background()
 	ctxBack = context.WithValue(ctxBack, middleware.RequestIDKey, "unit-test-request-id")
 	ctxBack = context.WithValue(ctxBack, &sts.Claim, sts.Claims{
-		UserID: 0,
-		Role:   "unit-test-role",
+		UserID:  0,
+		Role:    "unit-test-role",
+		Tenants: []int{1},
 	})
 	log := logger.NewContextLogger("Retrieve", "debug", logger.TextFormat)
 
@@ -338,7 +400,8 @@ func TestResetCounter(t *testing.T) {
 	type assertsParams struct { //nolint:wsl
 		args
 		repositoryOpts
-		result error
+		result    error
+		publisher *publishermocks.IPublisher
 	}
 
 	cases := []struct { //nolint:wsl
@@ -353,6 +416,8 @@ func TestResetCounter(t *testing.T) {
 			repositoryOpts: repositoryOpts{
 				trafficRepoFunc: func() *trafficmocks.IRepository {
 					repositoryMock := &trafficmocks.IRepository{}
+					repositoryMock.On("FindByID", mock.Anything, mock.Anything).
+						Return(&otraffic.Model{ID: "unit-test-traffic-id", IMEI: "861585041440544", TenantID: 1}, nil)
 					repositoryMock.On("ResetCounter", mock.Anything, mock.Anything).
 						Return(nil)
 					return repositoryMock
@@ -365,7 +430,14 @@ func TestResetCounter(t *testing.T) {
 			asserts: func(t *testing.T, err error, ap assertsParams) bool {
 				return assert.Nil(t, ap.result) &&
 					assert.NoError(t, err) &&
-					ap.trafficRepo.AssertExpectations(t)
+					ap.trafficRepo.AssertExpectations(t) &&
+					ap.publisher.AssertCalled(t, "Publish", mock.Anything, mock.MatchedBy(func(e livefeed.Event) bool {
+						return e.Type == livefeed.EventCounterReset &&
+							e.TrafficID == "unit-test-traffic-id" &&
+							e.IMEI == "861585041440544" &&
+							e.TenantID == 1 &&
+							e.Counter != nil && *e.Counter == 0
+					}))
 			},
 		},
 		{
@@ -373,6 +445,8 @@ func TestResetCounter(t *testing.T) {
 			repositoryOpts: repositoryOpts{
 				trafficRepoFunc: func() *trafficmocks.IRepository {
 					repositoryMock := &trafficmocks.IRepository{}
+					repositoryMock.On("FindByID", mock.Anything, mock.Anything).
+						Return(&otraffic.Model{ID: "unit-test-traffic-id", TenantID: 1}, nil)
 					repositoryMock.On("ResetCounter", mock.Anything, mock.Anything).
 						Return([]otraffic.Model{}, 1, 10, nil)
 					return repositoryMock
@@ -393,6 +467,8 @@ func TestResetCounter(t *testing.T) {
 			repositoryOpts: repositoryOpts{
 				trafficRepoFunc: func() *trafficmocks.IRepository {
 					repositoryMock := &trafficmocks.IRepository{}
+					repositoryMock.On("FindByID", mock.Anything, mock.Anything).
+						Return(&otraffic.Model{ID: "unit-test-traffic-id", TenantID: 1}, nil)
 					repositoryMock.On("ResetCounter", mock.Anything, mock.Anything).
 						Return(terrors.InternalService("reset_counter", "Failed reset counter traffic from the database", map[string]string{}))
 
@@ -421,7 +497,8 @@ func TestResetCounter(t *testing.T) {
 				tc.repositoryOpts.trafficRepo = tc.repositoryOpts.trafficRepoFunc()
 			}
 
-			trafficSvc := traffic.NewDefaultService(log, tc.repositoryOpts.trafficRepo)
+			publisher := feedPublisher()
+			trafficSvc := traffic.NewDefaultService(log, tc.repositoryOpts.trafficRepo, auditRecorder(), publisher, traffic.Opts{})
 
 			err := trafficSvc.HandleResetCounter(tc.args.ctx, tc.args.trafficID)
 			if (err != nil) != tc.err {
@@ -431,6 +508,7 @@ func TestResetCounter(t *testing.T) {
 			assertsParams := assertsParams{
 				repositoryOpts: tc.repositoryOpts,
 				args:           tc.args,
+				publisher:      publisher,
 			}
 
 			if !tc.asserts(t, err, assertsParams) {
@@ -440,3 +518,728 @@ func TestResetCounter(t *testing.T) {
 		})
 	}
 }
+
+func TestHandleExport(t *testing.T) {
+	ctxBack := context.Background()
+	ctxBack = context.WithValue(ctxBack, middleware.RequestIDKey, "unit-test-request-id")
+	ctxBack = context.WithValue(ctxBack, &sts.Claim, sts.Claims{
+		UserID: 0,
+		Role:   "unit-test-role",
+	})
+	log := logger.NewContextLogger("Export", "debug", logger.TextFormat)
+
+	cases := []struct { //nolint:wsl
+		name     string
+		filter   *straffic.FilterRequest
+		opts     straffic.Opts
+		repoFunc func() *trafficmocks.IRepository
+		err      bool
+		asserts  func(*testing.T, *straffic.ExportFile, *trafficmocks.IRepository) bool
+	}{
+		{
+			name:   "CSV with requested columns",
+			filter: &straffic.FilterRequest{Format: straffic.FormatCSV, Columns: []string{"imei", "counter"}},
+			repoFunc: func() *trafficmocks.IRepository {
+				repositoryMock := &trafficmocks.IRepository{}
+				repositoryMock.On("StreamData", mock.Anything, mock.Anything, mock.Anything).
+					Return(walkModels(otraffic.Model{IMEI: "861585041440544", Counter: 7}))
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, file *straffic.ExportFile, repo *trafficmocks.IRepository) bool {
+				var buf bytes.Buffer
+				return assert.NoError(t, file.Write(&buf)) &&
+					assert.Equal(t, "IMEI,Counter\n861585041440544,7\n", buf.String()) &&
+					assert.Equal(t, "text/csv", file.ContentType) &&
+					assert.True(t, strings.HasSuffix(file.Name, ".csv")) &&
+					repo.AssertExpectations(t)
+			},
+		},
+		{
+			name:   "XLSX with configured columns",
+			filter: &straffic.FilterRequest{Format: straffic.FormatXLSX},
+			opts:   straffic.Opts{ExportColumns: []string{"id", "alarm"}},
+			repoFunc: func() *trafficmocks.IRepository {
+				repositoryMock := &trafficmocks.IRepository{}
+				repositoryMock.On("StreamData", mock.Anything, mock.Anything, mock.Anything).
+					Return(walkModels(otraffic.Model{ID: "1", IsAlarm: true}, otraffic.Model{ID: "2"}))
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, file *straffic.ExportFile, repo *trafficmocks.IRepository) bool {
+				var buf bytes.Buffer
+				if !assert.NoError(t, file.Write(&buf)) || !assert.True(t, strings.HasSuffix(file.Name, ".xlsx")) {
+					return false
+				}
+				workbook, err := excelize.OpenReader(&buf)
+				if !assert.NoError(t, err) {
+					return false
+				}
+				rows, err := workbook.GetRows("Sheet1")
+				return assert.NoError(t, err) &&
+					assert.Equal(t, [][]string{{"ID", "Alarm"}, {"1", "TRUE"}, {"2", "FALSE"}}, rows) &&
+					repo.AssertExpectations(t)
+			},
+		},
+		{
+			name:   "Unknown column is rejected before streaming",
+			filter: &straffic.FilterRequest{Columns: []string{"imei", "password"}},
+			repoFunc: func() *trafficmocks.IRepository {
+				return &trafficmocks.IRepository{}
+			},
+			err: true,
+			asserts: func(t *testing.T, file *straffic.ExportFile, repo *trafficmocks.IRepository) bool {
+				return assert.Nil(t, file) &&
+					repo.AssertNotCalled(t, "StreamData", mock.Anything, mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name:   "Error on stream is returned by the write",
+			filter: &straffic.FilterRequest{},
+			repoFunc: func() *trafficmocks.IRepository {
+				repositoryMock := &trafficmocks.IRepository{}
+				repositoryMock.On("StreamData", mock.Anything, mock.Anything, mock.Anything).
+					Return(terrors.InternalService("stream_error", "Error streaming traffics from the database", map[string]string{}))
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, file *straffic.ExportFile, repo *trafficmocks.IRepository) bool {
+				var buf bytes.Buffer
+				return assert.Error(t, file.Write(&buf)) && repo.AssertExpectations(t)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := tc.repoFunc()
+			trafficSvc := traffic.NewDefaultService(log, repo, auditRecorder(), feedPublisher(), tc.opts)
+
+			file, err := trafficSvc.HandleExport(ctxBack, tc.filter)
+			if (err != nil) != tc.err {
+				t.Errorf("DefaultService.HandleExport() error = %v, wantErr %v", err, tc.err)
+			}
+
+			if !tc.asserts(t, file, repo) {
+				t.Errorf("Assert error on test = '%v'", tc.name)
+			}
+		})
+	}
+}
+
+func TestHandleStream(t *testing.T) {
+	ctxBack := context.Background()
+	ctxBack = context.WithValue(ctxBack, middleware.RequestIDKey, "unit-test-request-id")
+	ctxBack = context.WithValue(ctxBack, &sts.Claim, sts.Claims{
+		UserID:  0,
+		Role:    "unit-test-role",
+		Tenants: []int{1},
+	})
+	log := logger.NewContextLogger("Stream", "debug", logger.TextFormat)
+
+	rows := []otraffic.Model{
+		{ID: "1", IMEI: "861585041440544", TenantID: 1},
+		{ID: "2", IMEI: "861585041440545", TenantID: 1},
+	}
+	walk := func(_ context.Context, _ *otraffic.Metadata, fn func(otraffic.Model) error) error {
+		for _, row := range rows {
+			if err := fn(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	cases := []struct { //nolint:wsl
+		name     string
+		fn       func(*[]straffic.Traffic) func(straffic.Traffic) error
+		repoFunc func() *trafficmocks.IRepository
+		err      bool
+		asserts  func(*testing.T, []straffic.Traffic, *trafficmocks.IRepository) bool
+	}{
+		{
+			name: "Happy path every row is walked within the caller tenants",
+			fn: func(walked *[]straffic.Traffic) func(straffic.Traffic) error {
+				return func(t straffic.Traffic) error {
+					*walked = append(*walked, t)
+					return nil
+				}
+			},
+			repoFunc: func() *trafficmocks.IRepository {
+				repositoryMock := &trafficmocks.IRepository{}
+				repositoryMock.On("StreamData", mock.Anything, mock.MatchedBy(func(m *otraffic.Metadata) bool {
+					return m.IMEI == "8615" && m.Scope.Allows(1) && !m.Scope.Allows(2)
+				}), mock.Anything).Return(walk)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, walked []straffic.Traffic, repo *trafficmocks.IRepository) bool {
+				return assert.Len(t, walked, 2) &&
+					assert.Equal(t, "861585041440545", walked[1].IMEI) &&
+					repo.AssertExpectations(t)
+			},
+		},
+		{
+			name: "The walk stops on the first consumer error",
+			fn: func(walked *[]straffic.Traffic) func(straffic.Traffic) error {
+				return func(t straffic.Traffic) error {
+					*walked = append(*walked, t)
+					return errors.New("stream closed")
+				}
+			},
+			repoFunc: func() *trafficmocks.IRepository {
+				repositoryMock := &trafficmocks.IRepository{}
+				repositoryMock.On("StreamData", mock.Anything, mock.Anything, mock.Anything).Return(walk)
+				return repositoryMock
+			},
+			err: true,
+			asserts: func(t *testing.T, walked []straffic.Traffic, repo *trafficmocks.IRepository) bool {
+				return assert.Len(t, walked, 1)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := tc.repoFunc()
+			trafficSvc := traffic.NewDefaultService(log, repo, auditRecorder(), feedPublisher(), straffic.Opts{})
+
+			var walked []straffic.Traffic
+			err := trafficSvc.HandleStream(ctxBack, &straffic.FilterRequest{IMEI: "8615"}, tc.fn(&walked))
+			if (err != nil) != tc.err {
+				t.Errorf("DefaultService.HandleStream() error = %v, wantErr %v", err, tc.err)
+			}
+
+			if !tc.asserts(t, walked, repo) {
+				t.Errorf("Assert error on test = '%v'", tc.name)
+			}
+		})
+	}
+}
+
+func TestHandleStats(t *testing.T) {
+	ctxBack := context.Background()
+	ctxBack = context.WithValue(ctxBack, middleware.RequestIDKey, "unit-test-request-id")
+	ctxBack = context.WithValue(ctxBack, &sts.Claim, sts.Claims{
+		UserID: 0,
+		Role:   "unit-test-role",
+	})
+	log := logger.NewContextLogger("Stats", "debug", logger.TextFormat)
+	hour := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	dayAgo := time.Now().Add(-24 * time.Hour)
+	monthAgo := time.Now().AddDate(0, -1, 0)
+
+	cases := []struct { //nolint:wsl
+		name     string
+		request  *straffic.StatsRequest
+		repoFunc func() *trafficmocks.IRepository
+		err      bool
+		asserts  func(*testing.T, straffic.Stats, *trafficmocks.IRepository) bool
+	}{
+		{
+			name: "Happy path grouped by IMEI",
+			request: &straffic.StatsRequest{
+				Filter:  &straffic.FilterRequest{},
+				GroupBy: otraffic.GroupByIMEI,
+				Bucket:  otraffic.BucketHour,
+			},
+			repoFunc: func() *trafficmocks.IRepository {
+				repositoryMock := &trafficmocks.IRepository{}
+				repositoryMock.On("Stats", mock.Anything, mock.MatchedBy(func(q *otraffic.StatsQuery) bool {
+					return q.GroupBy == otraffic.GroupByIMEI && q.Bucket == otraffic.BucketHour && q.Limit == straffic.MaximumStatsPoints+1
+				})).Return([]otraffic.StatsRow{
+					{Bucket: hour, Key: "861585041440544", Records: 1, Frames: 5, Devices: 1},
+				}, nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, stats straffic.Stats, repo *trafficmocks.IRepository) bool {
+				return assert.Len(t, stats.Series, 1) &&
+					assert.Equal(t, 5, stats.Series[0].Total.Frames) &&
+					repo.AssertExpectations(t)
+			},
+		},
+		{
+			name: "Too many data points",
+			request: &straffic.StatsRequest{
+				Filter: &straffic.FilterRequest{},
+				Bucket: otraffic.BucketMinute,
+			},
+			repoFunc: func() *trafficmocks.IRepository {
+				repositoryMock := &trafficmocks.IRepository{}
+				repositoryMock.On("Stats", mock.Anything, mock.Anything).
+					Return(make([]otraffic.StatsRow, straffic.MaximumStatsPoints+1), nil)
+				return repositoryMock
+			},
+			err: true,
+			asserts: func(t *testing.T, stats straffic.Stats, repo *trafficmocks.IRepository) bool {
+				return assert.Nil(t, stats.Series) && repo.AssertExpectations(t)
+			},
+		},
+		{
+			name: "Range within the buckets retention",
+			request: &straffic.StatsRequest{
+				Filter: &straffic.FilterRequest{},
+				Bucket: otraffic.BucketDay,
+				From:   &dayAgo,
+			},
+			repoFunc: func() *trafficmocks.IRepository {
+				repositoryMock := &trafficmocks.IRepository{}
+				repositoryMock.On("Stats", mock.Anything, mock.Anything).Return([]otraffic.StatsRow{}, nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, stats straffic.Stats, repo *trafficmocks.IRepository) bool {
+				return repo.AssertExpectations(t)
+			},
+		},
+		{
+			name: "Range older than the buckets retention is rejected",
+			request: &straffic.StatsRequest{
+				Filter: &straffic.FilterRequest{},
+				Bucket: otraffic.BucketDay,
+				From:   &monthAgo,
+			},
+			repoFunc: func() *trafficmocks.IRepository {
+				return &trafficmocks.IRepository{}
+			},
+			err: true,
+			asserts: func(t *testing.T, stats straffic.Stats, repo *trafficmocks.IRepository) bool {
+				return assert.Nil(t, stats.Series) &&
+					repo.AssertNotCalled(t, "Stats", mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name: "Error on aggregate",
+			request: &straffic.StatsRequest{
+				Filter: &straffic.FilterRequest{},
+				Bucket: otraffic.BucketDay,
+			},
+			repoFunc: func() *trafficmocks.IRepository {
+				repositoryMock := &trafficmocks.IRepository{}
+				repositoryMock.On("Stats", mock.Anything, mock.Anything).
+					Return(nil, terrors.InternalService("stats_error", "Error aggregating traffics in the database", map[string]string{}))
+				return repositoryMock
+			},
+			err: true,
+			asserts: func(t *testing.T, stats straffic.Stats, repo *trafficmocks.IRepository) bool {
+				return repo.AssertExpectations(t)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := tc.repoFunc()
+			trafficSvc := traffic.NewDefaultService(log, repo, auditRecorder(), feedPublisher(), straffic.Opts{})
+
+			stats, err := trafficSvc.HandleStats(ctxBack, tc.request)
+			if (err != nil) != tc.err {
+				t.Errorf("DefaultService.HandleStats() error = %v, wantErr %v", err, tc.err)
+			}
+
+			if !tc.asserts(t, stats, repo) {
+				t.Errorf("Assert error on test = '%v'", tc.name)
+			}
+		})
+	}
+}
+
+func TestRetrieveCursor(t *testing.T) {
+	ctxBack := context.Background()
+	ctxBack = context.WithValue(ctxBack, middleware.RequestIDKey, "unit-test-request-id")
+	ctxBack = context.WithValue(ctxBack, &sts.Claim, sts.Claims{
+		UserID: 0,
+		Role:   "unit-test-role",
+	})
+	log := logger.NewContextLogger("RetrieveCursor", "debug", logger.TextFormat)
+
+	cases := []struct { //nolint:wsl
+		name     string
+		filter   *straffic.FilterRequest
+		repoFunc func() *trafficmocks.IRepository
+		err      bool
+		asserts  func(*testing.T, pagination.CursorPaginatedRes, *trafficmocks.IRepository) bool
+	}{
+		{
+			name:   "Happy path the page size is sanitized",
+			filter: &straffic.FilterRequest{Filter: pagination.Filter{Mode: pagination.ModeCursor}},
+			repoFunc: func() *trafficmocks.IRepository {
+				repositoryMock := &trafficmocks.IRepository{}
+				repositoryMock.On("RetrieveCursor", mock.Anything, mock.MatchedBy(func(m *otraffic.Metadata) bool {
+					return m.Filter.Size == pagination.DefaultSizeValue && m.Filter.IsCursor()
+				})).Return(otraffic.CursorPage{Models: []otraffic.Model{{ID: "1"}}, Next: "next"}, nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, res pagination.CursorPaginatedRes, repo *trafficmocks.IRepository) bool {
+				return assert.Equal(t, "next", res.Next) &&
+					assert.Empty(t, res.Prev) &&
+					assert.Nil(t, res.Total) &&
+					assert.Equal(t, pagination.DefaultSizeValue, res.Size) &&
+					repo.AssertExpectations(t)
+			},
+		},
+		{
+			name:   "Invalid cursor",
+			filter: &straffic.FilterRequest{Filter: pagination.Filter{Mode: pagination.ModeCursor, Cursor: "bad"}},
+			repoFunc: func() *trafficmocks.IRepository {
+				repositoryMock := &trafficmocks.IRepository{}
+				repositoryMock.On("RetrieveCursor", mock.Anything, mock.Anything).
+					Return(otraffic.CursorPage{}, terrors.New(terrors.ErrBadRequest, "Invalid cursor parameter", map[string]string{}))
+				return repositoryMock
+			},
+			err: true,
+			asserts: func(t *testing.T, res pagination.CursorPaginatedRes, repo *trafficmocks.IRepository) bool {
+				return assert.Nil(t, res.Data) && repo.AssertExpectations(t)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := tc.repoFunc()
+			trafficSvc := traffic.NewDefaultService(log, repo, auditRecorder(), feedPublisher(), straffic.Opts{})
+
+			res, err := trafficSvc.HandleRetrieveCursor(ctxBack, tc.filter)
+			if (err != nil) != tc.err {
+				t.Errorf("DefaultService.HandleRetrieveCursor() error = %v, wantErr %v", err, tc.err)
+			}
+
+			if !tc.asserts(t, res, repo) {
+				t.Errorf("Assert error on test = '%v'", tc.name)
+			}
+		})
+	}
+}
+
+func TestHandleBulk(t *testing.T) {
+	ctxBack := context.Background()
+	ctxBack = context.WithValue(ctxBack, middleware.RequestIDKey, "unit-test-request-id")
+	ctxBack = context.WithValue(ctxBack, &sts.Claim, sts.Claims{
+		UserID:  0,
+		Role:    "unit-test-role",
+		Tenants: []int{2, 1},
+	})
+	log := logger.NewContextLogger("Bulk", "debug", logger.TextFormat)
+
+	cases := []struct { //nolint:wsl
+		name     string
+		request  *straffic.BulkRequest
+		opts     straffic.Opts
+		repoFunc func() *trafficmocks.IRepository
+		err      bool
+		asserts  func(*testing.T, straffic.BulkResponse, *trafficmocks.IRepository) bool
+	}{
+		{
+			name: "Happy path reset counters by ids with the default limit",
+			request: &straffic.BulkRequest{
+				Operation: otraffic.BulkResetCounter,
+				Filter:    &straffic.FilterRequest{IDs: []string{"1", "2"}},
+			},
+			repoFunc: func() *trafficmocks.IRepository {
+				repositoryMock := &trafficmocks.IRepository{}
+				repositoryMock.On("Bulk", mock.Anything, otraffic.BulkResetCounter, mock.MatchedBy(func(m *otraffic.Metadata) bool {
+					return len(m.IDs) == 2 && !m.Scope.All && assert.ObjectsAreEqual([]int{1, 2}, m.Scope.Tenants)
+				}), straffic.DefaultBulkLimit).Return(2, nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, res straffic.BulkResponse, repo *trafficmocks.IRepository) bool {
+				return assert.Equal(t, straffic.BulkResponse{
+					Operation: otraffic.BulkResetCounter,
+					Affected:  2,
+					Limit:     straffic.DefaultBulkLimit,
+				}, res) && repo.AssertExpectations(t)
+			},
+		},
+		{
+			name: "Happy path dry run only counts",
+			request: &straffic.BulkRequest{
+				Operation: otraffic.BulkDelete,
+				DryRun:    true,
+				Filter:    &straffic.FilterRequest{IMEI: "8615"},
+			},
+			opts: straffic.Opts{BulkLimit: 50},
+			repoFunc: func() *trafficmocks.IRepository {
+				repositoryMock := &trafficmocks.IRepository{}
+				repositoryMock.On("CountData", mock.Anything, mock.MatchedBy(func(m *otraffic.Metadata) bool {
+					return m.IMEI == "8615"
+				})).Return(120, nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, res straffic.BulkResponse, repo *trafficmocks.IRepository) bool {
+				return assert.True(t, res.DryRun) &&
+					assert.Equal(t, 120, res.Affected) &&
+					assert.Equal(t, 50, res.Limit) &&
+					repo.AssertNotCalled(t, "Bulk", mock.Anything, mock.Anything, mock.Anything, mock.Anything) &&
+					repo.AssertExpectations(t)
+			},
+		},
+		{
+			name: "Too many affected rows",
+			request: &straffic.BulkRequest{
+				Operation: otraffic.BulkMarkNotified,
+				Filter:    &straffic.FilterRequest{},
+			},
+			opts: straffic.Opts{BulkLimit: 10},
+			repoFunc: func() *trafficmocks.IRepository {
+				repositoryMock := &trafficmocks.IRepository{}
+				repositoryMock.On("Bulk", mock.Anything, otraffic.BulkMarkNotified, mock.Anything, 10).
+					Return(0, terrors.New(terrors.ErrBadRequest, "Too many affected rows, narrow the filters", map[string]string{}))
+				return repositoryMock
+			},
+			err: true,
+			asserts: func(t *testing.T, res straffic.BulkResponse, repo *trafficmocks.IRepository) bool {
+				return assert.Empty(t, res.Operation) && repo.AssertExpectations(t)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := tc.repoFunc()
+			trafficSvc := traffic.NewDefaultService(log, repo, auditRecorder(), feedPublisher(), tc.opts)
+
+			res, err := trafficSvc.HandleBulk(ctxBack, tc.request)
+			if (err != nil) != tc.err {
+				t.Errorf("DefaultService.HandleBulk() error = %v, wantErr %v", err, tc.err)
+			}
+
+			if !tc.asserts(t, res, repo) {
+				t.Errorf("Assert error on test = '%v'", tc.name)
+			}
+		})
+	}
+}
+
+func TestHandleRestore(t *testing.T) {
+	ctxBack := context.Background()
+	ctxBack = context.WithValue(ctxBack, middleware.RequestIDKey, "unit-test-request-id")
+	ctxBack = context.WithValue(ctxBack, &sts.Claim, sts.Claims{
+		UserID:  0,
+		Role:    "unit-test-role",
+		Tenants: []int{1},
+	})
+	log := logger.NewContextLogger("Restore", "debug", logger.TextFormat)
+
+	cases := []struct { //nolint:wsl
+		name      string
+		trafficID string
+		repoFunc  func() *trafficmocks.IRepository
+		err       bool
+		asserts   func(*testing.T, error, *trafficmocks.IRepository, *recordermocks.IRecorder) bool
+	}{
+		{
+			name:      "Happy path",
+			trafficID: "unit-test-traffic-id",
+			repoFunc: func() *trafficmocks.IRepository {
+				repositoryMock := &trafficmocks.IRepository{}
+				repositoryMock.On("FindByID", mock.Anything, mock.Anything).
+					Return(&otraffic.Model{ID: "unit-test-traffic-id", TenantID: 1}, nil)
+				repositoryMock.On("Restore", mock.Anything, "unit-test-traffic-id").Return(nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, err error, repo *trafficmocks.IRepository, recorder *recordermocks.IRecorder) bool {
+				return assert.NoError(t, err) &&
+					repo.AssertExpectations(t) &&
+					recorder.AssertCalled(t, "Record", mock.Anything, mock.MatchedBy(func(event audit.Event) bool {
+						return event.Action == audit.ActionRestore &&
+							event.Resource == audit.ResourceTraffic &&
+							event.TargetID == "unit-test-traffic-id" &&
+							event.Before != nil && event.After != nil
+					}))
+			},
+		},
+		{
+			name: "Empty traffic id",
+			repoFunc: func() *trafficmocks.IRepository {
+				return &trafficmocks.IRepository{}
+			},
+			err: true,
+			asserts: func(t *testing.T, err error, repo *trafficmocks.IRepository, recorder *recordermocks.IRecorder) bool {
+				return assert.True(t, terrors.Is(err, terrors.ErrBadRequest)) &&
+					repo.AssertNotCalled(t, "Restore", mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name:      "Device already has an active traffic",
+			trafficID: "unit-test-traffic-id",
+			repoFunc: func() *trafficmocks.IRepository {
+				repositoryMock := &trafficmocks.IRepository{}
+				repositoryMock.On("FindByID", mock.Anything, mock.Anything).
+					Return(&otraffic.Model{ID: "unit-test-traffic-id", TenantID: 1}, nil)
+				repositoryMock.On("Restore", mock.Anything, mock.Anything).
+					Return(terrors.New(terrors.ErrConflict, "The device already has an active traffic", map[string]string{}))
+				return repositoryMock
+			},
+			err: true,
+			asserts: func(t *testing.T, err error, repo *trafficmocks.IRepository, recorder *recordermocks.IRecorder) bool {
+				return assert.True(t, terrors.Is(err, terrors.ErrConflict)) &&
+					repo.AssertExpectations(t) &&
+					recorder.AssertNotCalled(t, "Record", mock.Anything, mock.Anything)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := tc.repoFunc()
+			recorder := auditRecorder()
+			trafficSvc := traffic.NewDefaultService(log, repo, recorder, feedPublisher(), straffic.Opts{})
+
+			err := trafficSvc.HandleRestore(ctxBack, tc.trafficID)
+			if (err != nil) != tc.err {
+				t.Errorf("DefaultService.HandleRestore() error = %v, wantErr %v", err, tc.err)
+			}
+
+			if !tc.asserts(t, err, repo, recorder) {
+				t.Errorf("Assert error on test = '%v'", tc.name)
+			}
+		})
+	}
+}
+
+func TestPurgeDeleted(t *testing.T) {
+	log := logger.NewContextLogger("PurgeDeleted", "debug", logger.TextFormat)
+
+	cases := []struct { //nolint:wsl
+		name     string
+		opts     straffic.Opts
+		repoFunc func() *trafficmocks.IRepository
+		asserts  func(*testing.T, *trafficmocks.IRepository) bool
+	}{
+		{
+			name: "Happy path with the default retention",
+			repoFunc: func() *trafficmocks.IRepository {
+				repositoryMock := &trafficmocks.IRepository{}
+				repositoryMock.On("PurgeDeleted", mock.Anything, mock.MatchedBy(func(before time.Time) bool {
+					return time.Since(before) >= straffic.DefaultDeletedRetention
+				}), mock.Anything).Return(3, nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, repo *trafficmocks.IRepository) bool {
+				return repo.AssertExpectations(t)
+			},
+		},
+		{
+			name: "Configured retention",
+			opts: straffic.Opts{DeletedRetention: time.Hour},
+			repoFunc: func() *trafficmocks.IRepository {
+				repositoryMock := &trafficmocks.IRepository{}
+				repositoryMock.On("PurgeDeleted", mock.Anything, mock.MatchedBy(func(before time.Time) bool {
+					return time.Since(before) >= time.Hour && time.Since(before) < 2*time.Hour
+				}), mock.Anything).Return(0, nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, repo *trafficmocks.IRepository) bool {
+				return repo.AssertExpectations(t)
+			},
+		},
+		{
+			name: "Error on purge",
+			repoFunc: func() *trafficmocks.IRepository {
+				repositoryMock := &trafficmocks.IRepository{}
+				repositoryMock.On("PurgeDeleted", mock.Anything, mock.Anything, mock.Anything).
+					Return(0, terrors.InternalService("purge_traffic", "Failed purge deleted traffics from the database", map[string]string{}))
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, repo *trafficmocks.IRepository) bool {
+				return repo.AssertExpectations(t)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := tc.repoFunc()
+			trafficSvc := traffic.NewDefaultService(log, repo, auditRecorder(), feedPublisher(), tc.opts)
+
+			trafficSvc.PurgeDeleted(context.Background())
+
+			if !tc.asserts(t, repo) {
+				t.Errorf("Assert error on test = '%v'", tc.name)
+			}
+		})
+	}
+}
+
+func TestPurgeBuckets(t *testing.T) {
+	log := logger.NewContextLogger("PurgeBuckets", "debug", logger.TextFormat)
+
+	cases := []struct { //nolint:wsl
+		name     string
+		opts     straffic.Opts
+		repoFunc func() *trafficmocks.IRepository
+		asserts  func(*testing.T, *trafficmocks.IRepository) bool
+	}{
+		{
+			name: "Happy path purges the buckets older than the default retention",
+			repoFunc: func() *trafficmocks.IRepository {
+				repositoryMock := &trafficmocks.IRepository{}
+				repositoryMock.On("PurgeBuckets", mock.Anything, mock.MatchedBy(func(before time.Time) bool {
+					return time.Since(before) >= straffic.DefaultBucketsRetention
+				})).Return(12, nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, repo *trafficmocks.IRepository) bool {
+				return repo.AssertExpectations(t)
+			},
+		},
+		{
+			name: "A retention shorter than the largest window is ignored",
+			opts: straffic.Opts{BucketsRetention: time.Hour},
+			repoFunc: func() *trafficmocks.IRepository {
+				repositoryMock := &trafficmocks.IRepository{}
+				repositoryMock.On("PurgeBuckets", mock.Anything, mock.MatchedBy(func(before time.Time) bool {
+					return time.Since(before) > otraffic.Window24h && time.Since(before) < straffic.DefaultBucketsRetention
+				})).Return(3, nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, repo *trafficmocks.IRepository) bool {
+				return repo.AssertExpectations(t)
+			},
+		},
+		{
+			name: "Error on purge",
+			repoFunc: func() *trafficmocks.IRepository {
+				repositoryMock := &trafficmocks.IRepository{}
+				repositoryMock.On("PurgeBuckets", mock.Anything, mock.Anything).
+					Return(0, terrors.InternalService("purge_buckets", "Failed purge traffic buckets from the database", map[string]string{}))
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, repo *trafficmocks.IRepository) bool {
+				return repo.AssertExpectations(t)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := tc.repoFunc()
+			trafficSvc := traffic.NewDefaultService(log, repo, auditRecorder(), feedPublisher(), tc.opts)
+
+			trafficSvc.PurgeBuckets(context.Background())
+
+			if !tc.asserts(t, repo) {
+				t.Errorf("Assert error on test = '%v'", tc.name)
+			}
+		})
+	}
+}
+
+func feedPublisher() *publishermocks.IPublisher {
+	publisher := &publishermocks.IPublisher{}
+	publisher.On("Publish", mock.Anything, mock.Anything).Return()
+	return publisher
+}
+
+func auditRecorder() *recordermocks.IRecorder {
+	recorder := &recordermocks.IRecorder{}
+	recorder.On("Record", mock.Anything, mock.Anything).Return()
+	return recorder
+}
+
+// walkModels StreamData mock walking the given rows
+func walkModels(rows ...otraffic.Model) func(context.Context, *otraffic.Metadata, func(otraffic.Model) error) error {
+	return func(_ context.Context, _ *otraffic.Metadata, fn func(otraffic.Model) error) error {
+		for _, row := range rows {
+			if err := fn(row); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
@@ -0,0 +1,252 @@
+package traffic
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmontesinos91/terrors"
+	"github.com/xuri/excelize/v2"
+)
+
+// Export formats
+const (
+	FormatCSV  = "csv"
+	FormatXLSX = "xlsx"
+)
+
+// DefaultExportColumns columns exported when neither the request nor the configuration select any
+var DefaultExportColumns = []string{"id", "request", "imei", "ip", "alarm", "counter", "createdAt", "updatedAt"}
+
+type exportColumn struct {
+	header string
+	value  func(t Traffic) interface{}
+}
+
+var exportColumns = map[string]exportColumn{
+	"id":        {header: "ID", value: func(t Traffic) interface{} { return t.ID }},
+	"request":   {header: "Request", value: func(t Traffic) interface{} { return t.Request }},
+	"imei":      {header: "IMEI", value: func(t Traffic) interface{} { return t.IMEI }},
+	"ip":        {header: "IP", value: func(t Traffic) interface{} { return t.Ip }},
+	"alarm":     {header: "Alarm", value: func(t Traffic) interface{} { return t.IsAlarm }},
+	"counter":   {header: "Counter", value: func(t Traffic) interface{} { return t.Counter }},
+	"tenantId":  {header: "Tenant ID", value: func(t Traffic) interface{} { return t.TenantID }},
+	"createdAt": {header: "Created At", value: func(t Traffic) interface{} { return t.CreatedAt }},
+	"updatedAt": {header: "Updated At", value: func(t Traffic) interface{} { return t.UpdatedAt }},
+}
+
+// ExportFile walks the traffics to export when it is written, each one is encoded as it is read
+type ExportFile struct {
+	Name        string
+	ContentType string
+	format      string
+	columns     []string
+	walk        func(fn func(Traffic) error) error
+}
+
+// NewExportFile builds an export file for the given format, an unknown format falls back to CSV
+func NewExportFile(format string, columns []string, walk func(fn func(Traffic) error) error) *ExportFile {
+	if format != FormatXLSX {
+		format = FormatCSV
+	}
+
+	return &ExportFile{
+		Name:        fmt.Sprintf("traffic_%s.%s", time.Now().UTC().Format("20060102T150405Z"), format),
+		ContentType: ContentType(format),
+		format:      format,
+		columns:     columns,
+		walk:        walk,
+	}
+}
+
+// ValidateExportColumns checks that every column can be exported
+func ValidateExportColumns(columns []string) error {
+	var unknown []string
+	for _, column := range columns {
+		if _, ok := exportColumns[column]; !ok {
+			unknown = append(unknown, column)
+		}
+	}
+
+	if len(unknown) > 0 {
+		return terrors.New(terrors.ErrBadRequest, "Invalid columns parameter", map[string]string{
+			"columns": "unknown columns " + strings.Join(unknown, ","),
+		})
+	}
+
+	return nil
+}
+
+// ResolveExportColumns picks the requested columns, then the configured ones, then the defaults
+func ResolveExportColumns(requested, configured []string) ([]string, error) {
+	columns := requested
+	if len(columns) == 0 {
+		columns = configured
+	}
+	if len(columns) == 0 {
+		columns = DefaultExportColumns
+	}
+	if err := ValidateExportColumns(columns); err != nil {
+		return nil, err
+	}
+
+	return columns, nil
+}
+
+// Write encodes the traffics into the writer as they are walked, the output is incomplete when an
+// error is returned
+func (f *ExportFile) Write(w io.Writer) error {
+	encoder, err := NewEncoder(f.format, f.columns, w)
+	if err != nil {
+		return err
+	}
+
+	if err := f.walk(encoder.Encode); err != nil {
+		return err
+	}
+
+	return encoder.Close()
+}
+
+// Encoder writes traffics one by one in an export format, Close must be called to flush the output
+type Encoder interface {
+	Encode(row Traffic) error
+	Close() error
+}
+
+// NewEncoder builds an encoder for the given format and writes the header row,
+// an unknown format falls back to CSV
+func NewEncoder(format string, columns []string, w io.Writer) (Encoder, error) {
+	if format == FormatXLSX {
+		return newXLSXEncoder(columns, w)
+	}
+	return newCSVEncoder(columns, w)
+}
+
+// ContentType returns the content type of the given export format
+func ContentType(format string) string {
+	if format == FormatXLSX {
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	}
+	return "text/csv"
+}
+
+func headers(columns []string) []string {
+	headers := make([]string, 0, len(columns))
+	for _, column := range columns {
+		headers = append(headers, exportColumns[column].header)
+	}
+	return headers
+}
+
+type csvEncoder struct {
+	writer  *csv.Writer
+	columns []string
+	record  []string
+}
+
+func newCSVEncoder(columns []string, w io.Writer) (*csvEncoder, error) {
+	writer := csv.NewWriter(w)
+	if err := writer.Write(headers(columns)); err != nil {
+		return nil, err
+	}
+
+	return &csvEncoder{
+		writer:  writer,
+		columns: columns,
+		record:  make([]string, len(columns)),
+	}, nil
+}
+
+func (e *csvEncoder) Encode(row Traffic) error {
+	for i, column := range e.columns {
+		e.record[i] = formatCSVValue(exportColumns[column].value(row))
+	}
+	return e.writer.Write(e.record)
+}
+
+func (e *csvEncoder) Close() error {
+	e.writer.Flush()
+	return e.writer.Error()
+}
+
+type xlsxEncoder struct {
+	w       io.Writer
+	file    *excelize.File
+	stream  *excelize.StreamWriter
+	columns []string
+	row     int
+}
+
+func newXLSXEncoder(columns []string, w io.Writer) (*xlsxEncoder, error) {
+	file := excelize.NewFile()
+
+	const sheet = "Sheet1"
+	stream, err := file.NewStreamWriter(sheet)
+	if err != nil {
+		file.Close() //nolint:errcheck
+		return nil, err
+	}
+
+	if err := stream.SetRow("A1", toCells(headers(columns))); err != nil {
+		file.Close() //nolint:errcheck
+		return nil, err
+	}
+
+	return &xlsxEncoder{
+		w:       w,
+		file:    file,
+		stream:  stream,
+		columns: columns,
+		row:     1,
+	}, nil
+}
+
+func (e *xlsxEncoder) Encode(row Traffic) error {
+	values := make([]interface{}, len(e.columns))
+	for i, column := range e.columns {
+		values[i] = exportColumns[column].value(row)
+	}
+
+	e.row++
+	cell, err := excelize.CoordinatesToCellName(1, e.row)
+	if err != nil {
+		return err
+	}
+	return e.stream.SetRow(cell, values)
+}
+
+func (e *xlsxEncoder) Close() error {
+	defer e.file.Close() //nolint:errcheck
+
+	if err := e.stream.Flush(); err != nil {
+		return err
+	}
+	return e.file.Write(e.w)
+}
+
+func formatCSVValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case int:
+		return strconv.Itoa(v)
+	case time.Time:
+		return v.UTC().Format(time.RFC3339)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+func toCells(values []string) []interface{} {
+	cells := make([]interface{}, len(values))
+	for i, value := range values {
+		cells[i] = value
+	}
+	return cells
+}
@@ -1,18 +1,32 @@
 package traffic
 
 import (
+	"encoding/json"
 	"github.com/jmontesinos91/collector/domains/pagination"
 	"net/http"
+	"net/url"
+	"sort"
 	"strconv"
+	"strings"
+	"time"
 
+	"github.com/go-playground/validator/v10"
+	"github.com/jmontesinos91/collector/domains/validation"
 	"github.com/jmontesinos91/collector/internal/repositories/traffic"
 	"github.com/jmontesinos91/terrors"
 )
 
 // ParseFilterRequest builds a single filter object given http params
 func ParseFilterRequest(r *http.Request) (*FilterRequest, error) {
+	return ParseFilterValues(r.URL.Query())
+}
+
+// ParseFilterValues builds a single filter object given the encoded query values,
+// it allows filters stored by background jobs to be parsed with the same rules.
+// Every offending param is reported in the returned error.
+func ParseFilterValues(query url.Values) (*FilterRequest, error) {
 	fr := FilterRequest{}
-	query := r.URL.Query()
+	errs := validation.Errors{}
 
 	if QParam := query.Get("q"); QParam != "" {
 		fr.QParam = QParam
@@ -23,8 +37,8 @@ func ParseFilterRequest(r *http.Request) (*FilterRequest, error) {
 	}
 
 	if counterStr := query.Get("counter"); counterStr != "" {
-		if counter, err := strconv.Atoi(query.Get("counter")); err != nil {
-			return nil, terrors.New(terrors.ErrBadRequest, "Invalid counter parameter", map[string]string{})
+		if counter, err := strconv.Atoi(counterStr); err != nil {
+			errs.Add("counter", "must be an integer")
 		} else {
 			fr.Counter = &counter
 		}
@@ -43,43 +57,92 @@ func ParseFilterRequest(r *http.Request) (*FilterRequest, error) {
 	}
 
 	if alarm := query.Get("alarm"); alarm != "" {
-		isAlarm, err := strconv.ParseBool(query.Get("alarm"))
-		if err != nil {
-			return nil, terrors.New(terrors.ErrBadRequest, "Invalid page parameter", map[string]string{})
+		if isAlarm, err := strconv.ParseBool(alarm); err != nil {
+			errs.Add("alarm", "must be a boolean")
+		} else {
+			fr.IsAlarm = &isAlarm
 		}
-		fr.IsAlarm = &isAlarm
 	}
 
+	fr.CreatedFrom, fr.CreatedTo = parseDateRange(query, "createdFrom", "createdTo", errs)
+	fr.UpdatedFrom, fr.UpdatedTo = parseDateRange(query, "updatedFrom", "updatedTo", errs)
+
 	if SortBy := query.Get("sortBy"); SortBy != "" {
 		fr.Filter.SortBy = SortBy
 	}
 
 	if sortDescStr := query.Get("sortDesc"); sortDescStr != "" {
-		sortDesc, err := strconv.ParseBool(sortDescStr)
-		if err != nil {
-			return nil, terrors.New(terrors.ErrBadRequest, "Invalid sortDesc parameter", map[string]string{})
+		if sortDesc, err := strconv.ParseBool(sortDescStr); err != nil {
+			errs.Add("sortDesc", "must be a boolean")
+		} else {
+			fr.Filter.SortDesc = sortDesc
 		}
-		fr.Filter.SortDesc = sortDesc
 	}
 
-	if offset := query.Get("offset"); offset != "" {
-		fr.Filter.SortBy = offset
+	sorts, err := traffic.Spec.ParseSort(fr.Filter.SortBy, fr.Filter.SortDesc)
+	if err = errs.Merge(err); err != nil {
+		return nil, err
+	}
+	fr.Filter.Sorts = sorts
+
+	if includeDeleted := query.Get("includeDeleted"); includeDeleted != "" {
+		if include, err := strconv.ParseBool(includeDeleted); err != nil {
+			errs.Add("includeDeleted", "must be a boolean")
+		} else {
+			fr.IncludeDeleted = include
+		}
+	}
+
+	conditions, err := traffic.Spec.ParseFilters(query)
+	if err = errs.Merge(err); err != nil {
+		return nil, err
+	}
+	fr.Conditions = conditions
+
+	if offsetStr := query.Get("offset"); offsetStr != "" {
+		if offset, err := strconv.Atoi(offsetStr); err != nil || offset < 0 {
+			errs.Add("offset", "must be a non negative integer")
+		} else {
+			fr.Filter.Offset = offset
+		}
 	}
 
 	if perPageStr := query.Get("size"); perPageStr != "" {
-		perPage, err := strconv.Atoi(perPageStr)
-		if err != nil {
-			return nil, terrors.New(terrors.ErrBadRequest, "Invalid size parameter", map[string]string{})
+		if perPage, err := strconv.Atoi(perPageStr); err != nil {
+			errs.Add("size", "must be an integer")
+		} else {
+			fr.Filter.Size = perPage
 		}
-		fr.Filter.Size = perPage
 	}
 
 	if pageStr := query.Get("page"); pageStr != "" {
-		page, err := strconv.Atoi(pageStr)
-		if err != nil {
-			return nil, terrors.New(terrors.ErrBadRequest, "Invalid page parameter", map[string]string{})
+		if page, err := strconv.Atoi(pageStr); err != nil {
+			errs.Add("page", "must be an integer")
+		} else {
+			fr.Filter.Page = page
+		}
+	}
+
+	if mode := query.Get("mode"); mode != "" {
+		switch mode {
+		case pagination.ModeOffset, pagination.ModeCursor:
+			fr.Filter.Mode = mode
+		default:
+			errs.Add("mode", "must be one of: "+pagination.ModeOffset+" "+pagination.ModeCursor)
+		}
+	}
+
+	if cursor := query.Get("cursor"); cursor != "" {
+		fr.Filter.Mode = pagination.ModeCursor
+		fr.Filter.Cursor = cursor
+	}
+
+	if countStr := query.Get("count"); countStr != "" {
+		if count, err := strconv.ParseBool(countStr); err != nil {
+			errs.Add("count", "must be a boolean")
+		} else {
+			fr.Filter.Count = &count
 		}
-		fr.Filter.Page = page
 	}
 
 	if action := query.Get("action"); action != "" {
@@ -93,9 +156,261 @@ func ParseFilterRequest(r *http.Request) (*FilterRequest, error) {
 		}
 	}
 
+	if format := query.Get("format"); format != "" {
+		switch strings.ToLower(format) {
+		case FormatCSV:
+			fr.Format = FormatCSV
+		case FormatXLSX:
+			fr.Format = FormatXLSX
+		default:
+			errs.Add("format", "must be one of: "+FormatCSV+" "+FormatXLSX)
+		}
+	}
+
+	if columnsStr := query.Get("columns"); columnsStr != "" {
+		for _, column := range strings.Split(columnsStr, ",") {
+			if column = strings.TrimSpace(column); column != "" {
+				fr.Columns = append(fr.Columns, column)
+			}
+		}
+		if err := errs.Merge(ValidateExportColumns(fr.Columns)); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
 	return &fr, nil
 }
 
+// ParseBulkRequest builds a bulk request given the http body, the filter object follows the
+// listing query syntax, e.g. {"operation":"reset-counter","filter":{"imei[prefix]":"8615"}}
+func ParseBulkRequest(r *http.Request, validate *validator.Validate) (*BulkRequest, error) {
+	var body BulkBody
+	if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+		return nil, terrors.New(terrors.ErrBadRequest, "Invalid request body", map[string]string{})
+	}
+
+	errs := validation.Errors{}
+	if err := errs.Merge(validation.Struct(validate, body)); err != nil {
+		return nil, err
+	}
+
+	if len(body.IDs) > 0 && len(body.Filter) > 0 {
+		errs.Add("ids", "must not be set along with filter")
+	}
+	if len(body.IDs) == 0 && len(body.Filter) == 0 {
+		errs.Add("ids", "is required when filter is missing")
+	}
+
+	var filter *FilterRequest
+	if len(body.Filter) > 0 {
+		query := url.Values{}
+		for key, value := range body.Filter {
+			if !bulkFilterParams[key] && !strings.HasSuffix(key, "]") {
+				errs.Add("filter."+key, "is not a filter parameter")
+				continue
+			}
+			query.Set(key, value)
+		}
+
+		var err error
+		filter, err = ParseFilterValues(query)
+		if err = errs.Merge(err); err != nil {
+			return nil, err
+		}
+		if len(errs) == 0 && !hasBulkCondition(filter) {
+			errs.Add("filter", "must set at least one of q, id, imei, ip, alarm, a date range or a field condition")
+		}
+	}
+
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	request := &BulkRequest{
+		Operation: body.Operation,
+		DryRun:    body.DryRun,
+	}
+
+	if len(body.IDs) > 0 {
+		request.Filter = &FilterRequest{IDs: body.IDs}
+		return request, nil
+	}
+
+	// Sorting does not apply to bulk operations, which only touch active traffics
+	filter.Filter = pagination.Filter{}
+	request.Filter = filter
+
+	return request, nil
+}
+
+// hasBulkCondition reports whether the filter narrows the rows, counter and request alone are
+// too broad to select the rows of a bulk operation
+func hasBulkCondition(filter *FilterRequest) bool {
+	return filter.QParam != "" || filter.ID != "" || filter.IMEI != "" || filter.Ip != "" || filter.IsAlarm != nil ||
+		filter.CreatedFrom != nil || filter.CreatedTo != nil || filter.UpdatedFrom != nil || filter.UpdatedTo != nil ||
+		len(filter.Conditions) > 0
+}
+
+// ParseStatsRequest builds a stats request given http params
+func ParseStatsRequest(r *http.Request) (*StatsRequest, error) {
+	query := r.URL.Query()
+	errs := validation.Errors{}
+
+	filter, err := ParseFilterValues(query)
+	if err = errs.Merge(err); err != nil {
+		return nil, err
+	}
+
+	sr := StatsRequest{
+		Filter: filter,
+		Bucket: traffic.BucketHour,
+	}
+	sr.From, sr.To = parseDateRange(query, "from", "to", errs)
+
+	if groupBy := query.Get("groupBy"); groupBy != "" {
+		switch groupBy {
+		case traffic.GroupByIMEI, traffic.GroupByIP, traffic.GroupByAlarm:
+			sr.GroupBy = groupBy
+		default:
+			errs.Add("groupBy", "must be one of: "+traffic.GroupByIMEI+" "+traffic.GroupByIP+" "+traffic.GroupByAlarm)
+		}
+	}
+
+	if bucket := query.Get("bucket"); bucket != "" {
+		switch bucket {
+		case traffic.BucketMinute, traffic.BucketHour, traffic.BucketDay:
+			sr.Bucket = bucket
+		default:
+			errs.Add("bucket", "must be one of: "+traffic.BucketMinute+" "+traffic.BucketHour+" "+traffic.BucketDay)
+		}
+	}
+
+	if topStr := query.Get("top"); topStr != "" {
+		if top, err := strconv.Atoi(topStr); err != nil || top < 0 {
+			errs.Add("top", "must be a non negative integer")
+		} else {
+			sr.Top = top
+		}
+	}
+
+	if err := errs.Err(); err != nil {
+		return nil, err
+	}
+
+	return &sr, nil
+}
+
+// ToStats groups the aggregated rows into series, series are ordered by key or,
+// when only the top ones are requested, by their total frames
+func ToStats(request *StatsRequest, rows []traffic.StatsRow) Stats {
+	stats := Stats{
+		GroupBy: request.GroupBy,
+		Bucket:  request.Bucket,
+		Series:  []StatsSeries{},
+	}
+
+	index := map[string]int{}
+	for _, row := range rows {
+		i, ok := index[row.Key]
+		if !ok {
+			i = len(stats.Series)
+			index[row.Key] = i
+			stats.Series = append(stats.Series, StatsSeries{Key: row.Key, Points: []StatsPoint{}})
+		}
+
+		values := StatsValues{
+			Records: row.Records,
+			Frames:  row.Frames,
+			Alarms:  row.Alarms,
+			Devices: row.Devices,
+		}
+		series := &stats.Series[i]
+		series.Points = append(series.Points, StatsPoint{Bucket: row.Bucket.UTC(), StatsValues: values})
+		series.Total.Records = max(series.Total.Records, values.Records)
+		series.Total.Frames += values.Frames
+		series.Total.Alarms += values.Alarms
+		series.Total.Devices = max(series.Total.Devices, values.Devices)
+	}
+
+	if request.Top > 0 {
+		sort.SliceStable(stats.Series, func(i, j int) bool {
+			if stats.Series[i].Total.Frames != stats.Series[j].Total.Frames {
+				return stats.Series[i].Total.Frames > stats.Series[j].Total.Frames
+			}
+			return stats.Series[i].Key < stats.Series[j].Key
+		})
+		if len(stats.Series) > request.Top {
+			stats.Series = stats.Series[:request.Top]
+		}
+	} else {
+		sort.SliceStable(stats.Series, func(i, j int) bool {
+			return stats.Series[i].Key < stats.Series[j].Key
+		})
+	}
+
+	return stats
+}
+
+// parseDateRange parses an optional pair of range bounds, the lower bound must not be after the upper one
+func parseDateRange(query url.Values, fromParam, toParam string, errs validation.Errors) (*time.Time, *time.Time) {
+	var from, to *time.Time
+
+	if value := query.Get(fromParam); value != "" {
+		if t, err := parseTimeParam(value); err != nil {
+			errs.Add(fromParam, "must be an RFC3339 timestamp or a relative duration such as -24h, +24h or -7d")
+		} else {
+			from = &t
+		}
+	}
+
+	if value := query.Get(toParam); value != "" {
+		if t, err := parseTimeParam(value); err != nil {
+			errs.Add(toParam, "must be an RFC3339 timestamp or a relative duration such as -24h, +24h or -7d")
+		} else {
+			to = &t
+		}
+	}
+
+	if from != nil && to != nil && from.After(*to) {
+		errs.Add(fromParam, "must not be after "+toParam)
+		return nil, nil
+	}
+
+	return from, to
+}
+
+// now current time, replaceable in tests
+var now = time.Now
+
+// parseTimeParam parses an RFC3339 timestamp or a duration relative to now such as -24h, +24h or
+// -7d, days are not supported by time.ParseDuration so they are handled here. A + left unescaped in
+// the query string is decoded as a space, spaces are read back as + so +24h and offsets such as
+// +02:00 are understood, and an unsigned duration counts forward
+func parseTimeParam(value string) (time.Time, error) {
+	value = strings.ReplaceAll(value, " ", "+")
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.UTC(), nil
+	}
+
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return time.Time{}, err
+		}
+		return now().UTC().AddDate(0, 0, n), nil
+	}
+
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return now().UTC().Add(d), nil
+}
+
 // ToResponse builds a response object given the argument values
 func ToResponse(data interface{}, status string, message string) Response {
 	return Response{
@@ -115,6 +430,17 @@ func ToPaginatedResponse(data interface{}, currentPage, pages, total int) pagina
 	}
 }
 
+// ToCursorPaginatedResponse builds a cursor paginated response object given the argument values
+func ToCursorPaginatedResponse(data interface{}, next, prev string, size int, total *int) pagination.CursorPaginatedRes {
+	return pagination.CursorPaginatedRes{
+		Data:  data,
+		Next:  next,
+		Prev:  prev,
+		Size:  size,
+		Total: total,
+	}
+}
+
 // ToTrafficSlice converts a traffic model slice into a serializable slice
 func ToTrafficSlice(trafficModels []traffic.Model) []Traffic {
 	var traffics []Traffic
@@ -128,14 +454,23 @@ func ToTrafficSlice(trafficModels []traffic.Model) []Traffic {
 // ToTraffic converts a model to a Traffic struct to be serialized
 func ToTraffic(model traffic.Model) Traffic {
 	return Traffic{
-		ID:        model.ID,
-		Request:   model.Request,
-		IMEI:      model.IMEI,
-		Ip:        model.Ip,
-		IsAlarm:   model.IsAlarm,
-		Counter:   model.Counter,
-		CreatedAt: model.CreatedAt,
-		UpdatedAt: model.UpdatedAt,
+		ID:       model.ID,
+		Request:  model.Request,
+		IMEI:     model.IMEI,
+		Ip:       model.Ip,
+		IsAlarm:  model.IsAlarm,
+		Counter:  model.Counter,
+		TenantID: model.TenantID,
+		Windows: Windows{
+			Frames5m:  model.Frames5m,
+			Frames1h:  model.Frames1h,
+			Frames24h: model.Frames24h,
+		},
+		CreatedAt:        model.CreatedAt,
+		UpdatedAt:        model.UpdatedAt,
+		DeletedAt:        model.DeletedAt,
+		ExcessiveSince:   model.ExcessiveSince,
+		QuarantinedUntil: model.QuarantinedUntil,
 	}
 }
 
@@ -145,10 +480,20 @@ func ToMetadata(filterRequest *FilterRequest) *traffic.Metadata {
 	return &traffic.Metadata{
 		Qparam:  filterRequest.QParam,
 		ID:      filterRequest.ID,
+		IDs:     filterRequest.IDs,
 		Request: filterRequest.Request,
 		IMEI:    filterRequest.IMEI,
 		Ip:      filterRequest.Ip,
 		IsAlarm: filterRequest.IsAlarm,
 		Counter: filterRequest.Counter,
+
+		CreatedFrom: filterRequest.CreatedFrom,
+		CreatedTo:   filterRequest.CreatedTo,
+		UpdatedFrom: filterRequest.UpdatedFrom,
+		UpdatedTo:   filterRequest.UpdatedTo,
+		Conditions:  filterRequest.Conditions,
+
+		IncludeDeleted: filterRequest.IncludeDeleted,
+		Filter:         filterRequest.Filter,
 	}
 }
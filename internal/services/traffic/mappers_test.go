@@ -2,8 +2,11 @@ package traffic
 
 import (
 	"github.com/jmontesinos91/collector/domains/pagination"
+	"github.com/jmontesinos91/collector/domains/validation"
 	"net/http"
+	"net/http/httptest"
 	"net/url"
+	"strings"
 	"testing"
 	"time"
 
@@ -84,12 +87,82 @@ func TestParseFilterRequest(t *testing.T) {
 				Filter: pagination.Filter{
 					SortBy:   "ip",
 					SortDesc: true,
+					Sorts:    mustParseSort("ip", true),
 					Size:     20,
 					Page:     2,
 				},
 			},
 			expectError: false,
 		},
+		{
+			name: "Multi-field sort, typed filters and offset",
+			queryParams: map[string]string{
+				"sortBy":       "updated_at:desc,imei:asc",
+				"counter[gte]": "3",
+				"offset":       "40",
+			},
+			expected: &FilterRequest{
+				Conditions: mustParseFilters(url.Values{"counter[gte]": {"3"}}),
+				Filter: pagination.Filter{
+					SortBy: "updated_at:desc,imei:asc",
+					Sorts:  mustParseSort("updated_at:desc,imei:asc", false),
+					Offset: 40,
+				},
+			},
+			expectError: false,
+		},
+		{
+			name: "Rich operators",
+			queryParams: map[string]string{
+				"imei[in]":        "861585041440544,861585041440545",
+				"ip[cidr]":        "192.168.0.0/16",
+				"request[prefix]": "GET",
+			},
+			expected: &FilterRequest{
+				Conditions: mustParseFilters(url.Values{
+					"imei[in]":        {"861585041440544,861585041440545"},
+					"ip[cidr]":        {"192.168.0.0/16"},
+					"request[prefix]": {"GET"},
+				}),
+			},
+			expectError: false,
+		},
+		{
+			name: "Invalid ip cidr",
+			queryParams: map[string]string{
+				"ip[cidr]": "192.168.0.1",
+			},
+			expected:    nil,
+			expectError: true,
+			errorMsg:    "Invalid ip[cidr] parameter",
+		},
+		{
+			name: "Unknown sort field",
+			queryParams: map[string]string{
+				"sortBy": "id; DROP TABLE traffic",
+			},
+			expected:    nil,
+			expectError: true,
+			errorMsg:    "Invalid sortBy parameter",
+		},
+		{
+			name: "Unknown filter field",
+			queryParams: map[string]string{
+				"password[eq]": "x",
+			},
+			expected:    nil,
+			expectError: true,
+			errorMsg:    "unknown field password",
+		},
+		{
+			name: "Invalid offset parameter",
+			queryParams: map[string]string{
+				"offset": "-1",
+			},
+			expected:    nil,
+			expectError: true,
+			errorMsg:    "Invalid offset parameter",
+		},
 		{
 			name: "Invalid size parameter",
 			queryParams: map[string]string{
@@ -117,8 +190,179 @@ func TestParseFilterRequest(t *testing.T) {
 			expectError: true,
 			errorMsg:    "Invalid counter parameter",
 		},
+		{
+			name: "Sort by a rolling window",
+			queryParams: map[string]string{
+				"sortBy": "frames_5m:desc",
+			},
+			expected: &FilterRequest{
+				Filter: pagination.Filter{
+					SortBy: "frames_5m:desc",
+					Sorts:  mustParseSort("frames_5m:desc", false),
+				},
+			},
+			expectError: false,
+		},
+		{
+			name: "Rolling windows cannot be filtered",
+			queryParams: map[string]string{
+				"frames_5m[gt]": "10",
+			},
+			expected:    nil,
+			expectError: true,
+			errorMsg:    "unknown field frames_5m",
+		},
+		{
+			name: "Invalid alarm parameter",
+			queryParams: map[string]string{
+				"alarm": "maybe",
+			},
+			expected:    nil,
+			expectError: true,
+			errorMsg:    "Invalid alarm parameter, must be a boolean",
+		},
+		{
+			name: "Every invalid parameter is reported",
+			queryParams: map[string]string{
+				"alarm": "maybe",
+				"page":  "first",
+			},
+			expected:    nil,
+			expectError: true,
+			errorMsg:    "Invalid request parameters",
+		},
+		{
+			name: "Date ranges in RFC3339 and relative forms",
+			queryParams: map[string]string{
+				"createdFrom": "2026-10-01T00:00:00Z",
+				"createdTo":   "2026-10-02T12:00:00-06:00",
+				"updatedFrom": "-24h",
+				"updatedTo":   "+1d",
+			},
+			expected: &FilterRequest{
+				CreatedFrom: timePtr(time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)),
+				CreatedTo:   timePtr(time.Date(2026, 10, 2, 18, 0, 0, 0, time.UTC)),
+				UpdatedFrom: timePtr(time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)),
+				UpdatedTo:   timePtr(time.Date(2026, 10, 20, 12, 0, 0, 0, time.UTC)),
+			},
+			expectError: false,
+		},
+		{
+			name: "Invalid createdFrom parameter",
+			queryParams: map[string]string{
+				"createdFrom": "yesterday",
+			},
+			expected:    nil,
+			expectError: true,
+			errorMsg:    "Invalid createdFrom parameter",
+		},
+		{
+			name: "Invalid updatedTo parameter",
+			queryParams: map[string]string{
+				"updatedTo": "-3w",
+			},
+			expected:    nil,
+			expectError: true,
+			errorMsg:    "Invalid updatedTo parameter",
+		},
+		{
+			name: "Range lower bound after upper bound",
+			queryParams: map[string]string{
+				"createdFrom": "-1h",
+				"createdTo":   "-2h",
+			},
+			expected:    nil,
+			expectError: true,
+			errorMsg:    "Invalid createdFrom parameter, must not be after createdTo",
+		},
+		{
+			name: "Cursor pagination",
+			queryParams: map[string]string{
+				"cursor": "token",
+				"count":  "true",
+				"size":   "50",
+			},
+			expected: &FilterRequest{
+				Filter: pagination.Filter{
+					Size:   50,
+					Mode:   pagination.ModeCursor,
+					Cursor: "token",
+					Count:  &isAlarmTrue,
+				},
+			},
+			expectError: false,
+		},
+		{
+			name: "Invalid mode parameter",
+			queryParams: map[string]string{
+				"mode": "page",
+			},
+			expected:    nil,
+			expectError: true,
+			errorMsg:    "Invalid mode parameter",
+		},
+		{
+			name: "Include deleted traffics",
+			queryParams: map[string]string{
+				"includeDeleted": "true",
+			},
+			expected: &FilterRequest{
+				IncludeDeleted: true,
+			},
+		},
+		{
+			name: "Invalid includeDeleted parameter",
+			queryParams: map[string]string{
+				"includeDeleted": "sometimes",
+			},
+			expected:    nil,
+			expectError: true,
+			errorMsg:    "Invalid includeDeleted parameter",
+		},
+		{
+			name: "Invalid count parameter",
+			queryParams: map[string]string{
+				"count": "maybe",
+			},
+			expected:    nil,
+			expectError: true,
+			errorMsg:    "Invalid count parameter",
+		},
+		{
+			name: "Export format and columns",
+			queryParams: map[string]string{
+				"format":  "XLSX",
+				"columns": "imei, counter",
+			},
+			expected: &FilterRequest{
+				Format:  FormatXLSX,
+				Columns: []string{"imei", "counter"},
+			},
+			expectError: false,
+		},
+		{
+			name: "Invalid format parameter",
+			queryParams: map[string]string{
+				"format": "pdf",
+			},
+			expected:    nil,
+			expectError: true,
+			errorMsg:    "Invalid format parameter",
+		},
+		{
+			name: "Invalid columns parameter",
+			queryParams: map[string]string{
+				"columns": "imei,password",
+			},
+			expected:    nil,
+			expectError: true,
+			errorMsg:    "Invalid columns parameter",
+		},
 	}
 
+	now = func() time.Time { return time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC) }
+	defer func() { now = time.Now }()
+
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 
@@ -143,6 +387,76 @@ func TestParseFilterRequest(t *testing.T) {
 	}
 }
 
+func TestParseFilterValuesUnescapedOffsets(t *testing.T) {
+	now = func() time.Time { return time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC) }
+	defer func() { now = time.Now }()
+
+	tests := []struct {
+		name     string
+		rawQuery string
+		asserts  func(*testing.T, *FilterRequest, error) bool
+	}{
+		{
+			name:     "Unescaped plus sign of a relative duration",
+			rawQuery: "updatedFrom=-24h&updatedTo=+24h",
+			asserts: func(t *testing.T, fr *FilterRequest, err error) bool {
+				return assert.NoError(t, err) &&
+					assert.Equal(t, timePtr(time.Date(2026, 10, 20, 12, 0, 0, 0, time.UTC)), fr.UpdatedTo)
+			},
+		},
+		{
+			name:     "Unescaped plus sign of a timestamp offset",
+			rawQuery: "createdFrom=2026-10-02T12:00:00+02:00",
+			asserts: func(t *testing.T, fr *FilterRequest, err error) bool {
+				return assert.NoError(t, err) &&
+					assert.Equal(t, timePtr(time.Date(2026, 10, 2, 10, 0, 0, 0, time.UTC)), fr.CreatedFrom)
+			},
+		},
+		{
+			name:     "Unsigned relative duration counts forward",
+			rawQuery: "createdTo=1d",
+			asserts: func(t *testing.T, fr *FilterRequest, err error) bool {
+				return assert.NoError(t, err) &&
+					assert.Equal(t, timePtr(time.Date(2026, 10, 20, 12, 0, 0, 0, time.UTC)), fr.CreatedTo)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := url.ParseQuery(tt.rawQuery)
+			if !assert.NoError(t, err) {
+				return
+			}
+
+			fr, err := ParseFilterValues(query)
+			if !tt.asserts(t, fr, err) {
+				t.Errorf("Assert error on test = '%v'", tt.name)
+			}
+		})
+	}
+}
+
+func mustParseSort(sortBy string, sortDesc bool) []pagination.Sort {
+	sorts, err := otraffic.Spec.ParseSort(sortBy, sortDesc)
+	if err != nil {
+		panic(err)
+	}
+	return sorts
+}
+
+func mustParseFilters(query url.Values) []pagination.Condition {
+	conditions, err := otraffic.Spec.ParseFilters(query)
+	if err != nil {
+		panic(err)
+	}
+	return conditions
+}
+
+func timePtr(t time.Time) *time.Time {
+	return &t
+}
+
 func TestToTrafficSlice(t *testing.T) {
 	// Test Data
 	trafficModels := []otraffic.Model{
@@ -184,14 +498,20 @@ func TestToTrafficSlice(t *testing.T) {
 
 func TestToTraffic(t *testing.T) {
 	model := otraffic.Model{
-		ID:        "1",
-		Request:   "request1",
-		IMEI:      "imei1",
-		Ip:        "192.168.0.1",
-		IsAlarm:   true,
-		Counter:   2,
-		CreatedAt: time.Now(),
-		UpdatedAt: time.Now(),
+		ID:               "1",
+		Request:          "request1",
+		IMEI:             "imei1",
+		Ip:               "192.168.0.1",
+		IsAlarm:          true,
+		Counter:          2,
+		CreatedAt:        time.Now(),
+		UpdatedAt:        time.Now(),
+		DeletedAt:        timePtr(time.Now()),
+		Frames5m:         3,
+		Frames1h:         40,
+		Frames24h:        500,
+		ExcessiveSince:   timePtr(time.Now()),
+		QuarantinedUntil: timePtr(time.Now().Add(time.Hour)),
 	}
 
 	result := ToTraffic(model)
@@ -201,20 +521,28 @@ func TestToTraffic(t *testing.T) {
 	assert.Equal(t, model.Ip, result.Ip, "Expected IP to match")
 	assert.Equal(t, model.IsAlarm, result.IsAlarm, "Expected Alarm to match")
 	assert.Equal(t, model.Counter, result.Counter, "Expected Counter to match")
+	assert.Equal(t, Windows{Frames5m: 3, Frames1h: 40, Frames24h: 500}, result.Windows, "Expected Windows to match")
 	assert.Equal(t, model.CreatedAt, result.CreatedAt, "Expected CreatedAt to match")
 	assert.Equal(t, model.UpdatedAt, result.UpdatedAt, "Expected UpdatedAt to match")
+	assert.Equal(t, model.DeletedAt, result.DeletedAt, "Expected DeletedAt to match")
+	assert.Equal(t, model.ExcessiveSince, result.ExcessiveSince, "Expected ExcessiveSince to match")
+	assert.Equal(t, model.QuarantinedUntil, result.QuarantinedUntil, "Expected QuarantinedUntil to match")
 }
 
 func TestToMetadata(t *testing.T) {
 	isAlarmTrue := true
 	filterRequest := &FilterRequest{
-		QParam:  "query",
-		ID:      "1",
-		Request: "request1",
-		IMEI:    "imei1",
-		Ip:      "192.168.0.1",
-		IsAlarm: &isAlarmTrue,
-		Action:  "list",
+		QParam:      "query",
+		ID:          "1",
+		Request:     "request1",
+		IMEI:        "imei1",
+		Ip:          "192.168.0.1",
+		IsAlarm:     &isAlarmTrue,
+		CreatedFrom: timePtr(time.Now()),
+		UpdatedTo:   timePtr(time.Now()),
+		Action:      "list",
+
+		IncludeDeleted: true,
 	}
 
 	result := ToMetadata(filterRequest)
@@ -225,4 +553,206 @@ func TestToMetadata(t *testing.T) {
 	assert.Equal(t, filterRequest.IMEI, result.IMEI, "Expected IMEI to match")
 	assert.Equal(t, filterRequest.Ip, result.Ip, "Expected IP to match")
 	assert.Equal(t, filterRequest.IsAlarm, result.IsAlarm, "Expected Alarm to match")
+	assert.Equal(t, filterRequest.CreatedFrom, result.CreatedFrom, "Expected CreatedFrom to match")
+	assert.Equal(t, filterRequest.UpdatedTo, result.UpdatedTo, "Expected UpdatedTo to match")
+	assert.Equal(t, filterRequest.IncludeDeleted, result.IncludeDeleted, "Expected IncludeDeleted to match")
+	assert.Equal(t, filterRequest.Filter, result.Filter, "Expected Filter to match")
+}
+
+func TestParseStatsRequest(t *testing.T) {
+	tests := []struct {
+		name        string
+		queryParams map[string]string
+		expected    *StatsRequest
+		errorMsg    string
+	}{
+		{
+			name:        "Defaults",
+			queryParams: map[string]string{},
+			expected: &StatsRequest{
+				Filter: &FilterRequest{},
+				Bucket: otraffic.BucketHour,
+			},
+		},
+		{
+			name: "Happy path valid parameters",
+			queryParams: map[string]string{
+				"imei":    "8615",
+				"groupBy": "alarm",
+				"bucket":  "day",
+				"from":    "2026-10-01T00:00:00Z",
+				"to":      "2026-10-08T00:00:00Z",
+				"top":     "5",
+			},
+			expected: &StatsRequest{
+				Filter:  &FilterRequest{IMEI: "8615"},
+				GroupBy: otraffic.GroupByAlarm,
+				Bucket:  otraffic.BucketDay,
+				From:    timePtr(time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)),
+				To:      timePtr(time.Date(2026, 10, 8, 0, 0, 0, 0, time.UTC)),
+				Top:     5,
+			},
+		},
+		{
+			name:        "Invalid groupBy parameter",
+			queryParams: map[string]string{"groupBy": "request"},
+			errorMsg:    "Invalid groupBy parameter",
+		},
+		{
+			name:        "Invalid bucket parameter",
+			queryParams: map[string]string{"bucket": "week"},
+			errorMsg:    "Invalid bucket parameter",
+		},
+		{
+			name:        "Invalid frames range",
+			queryParams: map[string]string{"from": "2026-10-08T00:00:00Z", "to": "2026-10-01T00:00:00Z"},
+			errorMsg:    "Invalid from parameter, must not be after to",
+		},
+		{
+			name:        "Invalid top parameter",
+			queryParams: map[string]string{"top": "-1"},
+			errorMsg:    "Invalid top parameter",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := url.Values{}
+			for key, value := range tt.queryParams {
+				query.Set(key, value)
+			}
+			req := &http.Request{
+				URL: &url.URL{RawQuery: query.Encode()},
+			}
+
+			sr, err := ParseStatsRequest(req)
+			if tt.errorMsg != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+				assert.Nil(t, sr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, sr)
+			}
+		})
+	}
+}
+
+func TestParseBulkRequest(t *testing.T) {
+	alarm := true
+	tests := []struct {
+		name     string
+		body     string
+		expected *BulkRequest
+		errorMsg string
+	}{
+		{
+			name: "Happy path by ids",
+			body: `{"operation":"delete","ids":["5b1f0c7e-3f0a-4d59-9b8e-2b8f8d0c6a11"]}`,
+			expected: &BulkRequest{
+				Operation: otraffic.BulkDelete,
+				Filter:    &FilterRequest{IDs: []string{"5b1f0c7e-3f0a-4d59-9b8e-2b8f8d0c6a11"}},
+			},
+		},
+		{
+			name: "Happy path by filter",
+			body: `{"operation":"reset-counter","dryRun":true,"filter":{"imei[prefix]":"8615","alarm":"true"}}`,
+			expected: &BulkRequest{
+				Operation: otraffic.BulkResetCounter,
+				DryRun:    true,
+				Filter: &FilterRequest{
+					IsAlarm:    &alarm,
+					Conditions: mustParseFilters(url.Values{"imei[prefix]": {"8615"}}),
+				},
+			},
+		},
+		{
+			name:     "Invalid body",
+			body:     `{"operation":`,
+			errorMsg: "Invalid request body",
+		},
+		{
+			name:     "Invalid operation parameter",
+			body:     `{"operation":"truncate","ids":["5b1f0c7e-3f0a-4d59-9b8e-2b8f8d0c6a11"]}`,
+			errorMsg: "Invalid operation parameter",
+		},
+		{
+			name:     "Ids and filter are mutually exclusive",
+			body:     `{"operation":"delete","ids":["5b1f0c7e-3f0a-4d59-9b8e-2b8f8d0c6a11"],"filter":{"imei":"8615"}}`,
+			errorMsg: "Invalid ids parameter, must not be set along with filter",
+		},
+		{
+			name:     "Ids or filter are required",
+			body:     `{"operation":"mark-notified"}`,
+			errorMsg: "Invalid ids parameter, is required when filter is missing",
+		},
+		{
+			name:     "Invalid ids parameter",
+			body:     `{"operation":"delete","ids":["1"]}`,
+			errorMsg: "Invalid ids[0] parameter, must be a UUID",
+		},
+		{
+			name:     "Misspelled filter key is rejected instead of matching every row",
+			body:     `{"operation":"delete","filter":{"imeii":"861585041440544"}}`,
+			errorMsg: "Invalid filter.imeii parameter, is not a filter parameter",
+		},
+		{
+			name:     "Pagination keys are rejected",
+			body:     `{"operation":"delete","filter":{"imei":"861585041440544","page":"3"}}`,
+			errorMsg: "Invalid filter.page parameter, is not a filter parameter",
+		},
+		{
+			name:     "Filter without a narrowing condition",
+			body:     `{"operation":"reset-counter","filter":{"counter":"0"}}`,
+			errorMsg: "Invalid filter parameter, must set at least one of",
+		},
+		{
+			name:     "Invalid filter parameter",
+			body:     `{"operation":"delete","filter":{"imei[cidr]":"10.0.0.0/8"}}`,
+			errorMsg: "unsupported operator",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/v1/traffic/bulk", strings.NewReader(tt.body))
+
+			br, err := ParseBulkRequest(req, validation.New())
+			if tt.errorMsg != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+				assert.Nil(t, br)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, br)
+			}
+		})
+	}
+}
+
+func TestToStats(t *testing.T) {
+	first := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+	second := first.Add(time.Hour)
+	rows := []otraffic.StatsRow{
+		{Bucket: first, Key: "b", Records: 1, Frames: 2, Devices: 1},
+		{Bucket: first, Key: "a", Records: 1, Frames: 1, Alarms: 1, Devices: 1},
+		{Bucket: second, Key: "b", Records: 1, Frames: 8, Devices: 1},
+	}
+
+	stats := ToStats(&StatsRequest{GroupBy: otraffic.GroupByIMEI, Bucket: otraffic.BucketHour}, rows)
+	assert.Len(t, stats.Series, 2)
+	assert.Equal(t, "a", stats.Series[0].Key, "Expected series ordered by key")
+	assert.Equal(t, 10, stats.Series[1].Total.Frames)
+	assert.Equal(t, 1, stats.Series[1].Total.Records, "Expected the busiest bucket as the same row sends frames in many")
+	assert.Equal(t, []StatsPoint{
+		{Bucket: first, StatsValues: StatsValues{Records: 1, Frames: 2, Devices: 1}},
+		{Bucket: second, StatsValues: StatsValues{Records: 1, Frames: 8, Devices: 1}},
+	}, stats.Series[1].Points)
+
+	top := ToStats(&StatsRequest{GroupBy: otraffic.GroupByIMEI, Top: 1}, rows)
+	assert.Len(t, top.Series, 1)
+	assert.Equal(t, "b", top.Series[0].Key, "Expected the busiest series first")
+
+	empty := ToStats(&StatsRequest{}, nil)
+	assert.NotNil(t, empty.Series, "Expected an empty series list instead of null")
 }
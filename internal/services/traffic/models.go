@@ -2,6 +2,7 @@ package traffic
 
 import (
 	"github.com/jmontesinos91/collector/domains/pagination"
+	"github.com/jmontesinos91/collector/domains/tenancy"
 	"time"
 )
 
@@ -23,25 +24,152 @@ type Alarm struct {
 
 // FilterRequest holds the http request params
 type FilterRequest struct {
-	QParam  string            `json:"q,omitempty"`
-	ID      string            `json:"id,omitempty"`
-	Request string            `json:"request,omitempty"`
-	IMEI    string            `json:"imei,omitempty"`
-	Ip      string            `json:"ip,omitempty"`
-	IsAlarm *bool             `json:"alarm,omitempty"`
-	Counter *int              `json:"counter,omitempty"`
-	Action  string            `json:"action,omitempty"`
-	Filter  pagination.Filter `json:"filter,omitempty"`
+	QParam         string                 `json:"q,omitempty"`
+	ID             string                 `json:"id,omitempty"`
+	IDs            []string               `json:"ids,omitempty"`
+	Request        string                 `json:"request,omitempty"`
+	IMEI           string                 `json:"imei,omitempty"`
+	Ip             string                 `json:"ip,omitempty"`
+	IsAlarm        *bool                  `json:"alarm,omitempty"`
+	Counter        *int                   `json:"counter,omitempty"`
+	CreatedFrom    *time.Time             `json:"createdFrom,omitempty"`
+	CreatedTo      *time.Time             `json:"createdTo,omitempty"`
+	UpdatedFrom    *time.Time             `json:"updatedFrom,omitempty"`
+	UpdatedTo      *time.Time             `json:"updatedTo,omitempty"`
+	Conditions     []pagination.Condition `json:"conditions,omitempty"`
+	IncludeDeleted bool                   `json:"includeDeleted,omitempty"`
+	Action         string                 `json:"action,omitempty"`
+	Format         string                 `json:"format,omitempty"`
+	Columns        []string               `json:"columns,omitempty"`
+	Filter         pagination.Filter      `json:"filter,omitempty"`
+}
+
+// Opts traffic service options
+type Opts struct {
+	ExportColumns    []string
+	BulkLimit        int
+	DeletedRetention time.Duration
+	// BucketsRetention never shorter than the largest rolling window
+	BucketsRetention time.Duration
+	Tenancy          tenancy.Policy
 }
 
 // Traffic item
 type Traffic struct {
-	ID        string    `json:"id"`
-	Request   string    `json:"request"`
-	IMEI      string    `json:"imei"`
-	Ip        string    `json:"ip"`
-	IsAlarm   bool      `json:"alarm"`
-	Counter   int       `json:"counter"`
-	CreatedAt time.Time `json:"createdAt"`
-	UpdatedAt time.Time `json:"updatedAt"`
+	ID        string     `json:"id"`
+	Request   string     `json:"request"`
+	IMEI      string     `json:"imei"`
+	Ip        string     `json:"ip"`
+	IsAlarm   bool       `json:"alarm"`
+	Counter   int        `json:"counter"`
+	TenantID  int        `json:"tenantId,omitempty"`
+	Windows   Windows    `json:"windows"`
+	CreatedAt time.Time  `json:"createdAt"`
+	UpdatedAt time.Time  `json:"updatedAt"`
+	DeletedAt *time.Time `json:"deletedAt,omitempty"`
+	// ExcessiveSince is set while the device is over its frames threshold
+	ExcessiveSince   *time.Time `json:"excessiveSince,omitempty"`
+	QuarantinedUntil *time.Time `json:"quarantinedUntil,omitempty"`
+}
+
+// Windows frames received over the rolling windows, unlike the counter they are never reset
+type Windows struct {
+	Frames5m  int `json:"5m"`
+	Frames1h  int `json:"1h"`
+	Frames24h int `json:"24h"`
+}
+
+// DefaultDeletedRetention time a soft deleted traffic is kept before it is purged
+const DefaultDeletedRetention = 30 * 24 * time.Hour
+
+// DefaultBucketsRetention time the frames buckets are kept, they back the rolling windows and the stats
+const DefaultBucketsRetention = 7 * 24 * time.Hour
+
+// purgeBatchSize soft deleted traffics hard deleted per statement
+const purgeBatchSize = 1000
+
+// DefaultBulkLimit maximum number of rows a bulk operation can affect when no limit is configured
+const DefaultBulkLimit = 10000
+
+// bulkFilterParams plain filter keys a bulk filter accepts, besides the field[op] conditions.
+// Any other key is rejected so a typo never widens the filter to every row
+var bulkFilterParams = map[string]bool{
+	"q":           true,
+	"id":          true,
+	"counter":     true,
+	"request":     true,
+	"imei":        true,
+	"ip":          true,
+	"alarm":       true,
+	"createdFrom": true,
+	"createdTo":   true,
+	"updatedFrom": true,
+	"updatedTo":   true,
+}
+
+// BulkBody holds the bulk http request body, either ids or filter select the rows
+type BulkBody struct {
+	Operation string            `json:"operation" validate:"oneof=delete reset-counter mark-notified"`
+	IDs       []string          `json:"ids" validate:"dive,uuid"`
+	Filter    map[string]string `json:"filter"`
+	DryRun    bool              `json:"dryRun"`
+}
+
+// BulkRequest holds the bulk http request, the rows are selected either by ids or by listing filters
+type BulkRequest struct {
+	Operation string
+	DryRun    bool
+	Filter    *FilterRequest
+}
+
+// BulkResponse result of a bulk operation, on dry runs affected holds the matching rows
+type BulkResponse struct {
+	Operation string `json:"operation"`
+	DryRun    bool   `json:"dryRun"`
+	Affected  int    `json:"affected"`
+	Limit     int    `json:"limit"`
+}
+
+// MaximumStatsPoints maximum number of bucket and key pairs a stats request can aggregate
+const MaximumStatsPoints = 10000
+
+// StatsRequest holds the stats http request params, filters follow the listing syntax and
+// from and to bound the time the frames were received
+type StatsRequest struct {
+	Filter  *FilterRequest
+	GroupBy string
+	Bucket  string
+	From    *time.Time
+	To      *time.Time
+	Top     int
+}
+
+// Stats time-bucketed aggregates of the frames received, one series per group key
+type Stats struct {
+	GroupBy string        `json:"groupBy"`
+	Bucket  string        `json:"bucket"`
+	Series  []StatsSeries `json:"series"`
+}
+
+// StatsSeries aggregates of a group key, points are ordered by bucket. Total sums the frames and
+// alarms of the points, records and devices hold the busiest bucket as distinct counts do not add up
+type StatsSeries struct {
+	Key    string       `json:"key"`
+	Total  StatsValues  `json:"total"`
+	Points []StatsPoint `json:"points"`
+}
+
+// StatsPoint aggregates of a single bucket
+type StatsPoint struct {
+	Bucket time.Time `json:"bucket"`
+	StatsValues
+}
+
+// StatsValues aggregated values of a bucket: the traffic rows and distinct devices that sent frames,
+// the frames received and the ones of them that were alarms
+type StatsValues struct {
+	Records int `json:"records"`
+	Frames  int `json:"frames"`
+	Alarms  int `json:"alarms"`
+	Devices int `json:"devices"`
 }
@@ -7,6 +7,12 @@ import (
 
 type IService interface {
 	HandleRetrieve(ctx context.Context, filter *FilterRequest) (pagination.PaginatedRes, error)
+	HandleRetrieveCursor(ctx context.Context, filter *FilterRequest) (pagination.CursorPaginatedRes, error)
 	HandleDelete(ctx context.Context, trafficID string) error
+	HandleRestore(ctx context.Context, trafficID string) error
 	HandleResetCounter(ctx context.Context, trafficID string) error
+	HandleExport(ctx context.Context, filter *FilterRequest) (*ExportFile, error)
+	HandleStream(ctx context.Context, filter *FilterRequest, fn func(Traffic) error) error
+	HandleStats(ctx context.Context, request *StatsRequest) (Stats, error)
+	HandleBulk(ctx context.Context, request *BulkRequest) (BulkResponse, error)
 }
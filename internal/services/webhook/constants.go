@@ -0,0 +1,46 @@
+package webhook
+
+import "time"
+
+// Event types tenants can subscribe to
+const (
+	EventAlarmAccepted          = "alarm.accepted"
+	EventDevicePosition         = "device.position"
+	EventDeviceExcessiveTraffic = "device.excessive_traffic"
+)
+
+// EventTypes every event type a subscription may receive
+var EventTypes = []string{EventAlarmAccepted, EventDevicePosition, EventDeviceExcessiveTraffic}
+
+// Headers sent with every delivery. The signature header has the form t=<unix seconds>,v1=<hex HMAC-SHA256>
+// where the HMAC is computed with the subscription secret over "<unix seconds>.<body>"
+const (
+	HeaderSignature = "X-Webhook-Signature"
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderDelivery  = "X-Webhook-Delivery"
+)
+
+const (
+	// DefaultTimeout time a delivery attempt waits for the endpoint
+	DefaultTimeout = 10 * time.Second
+	// DefaultMaxAttempts attempts before a delivery is marked as failed
+	DefaultMaxAttempts = 8
+	// DefaultBackoffBase wait before the first retry, it doubles on every attempt
+	DefaultBackoffBase = 30 * time.Second
+	// DefaultBackoffMax longest wait between two attempts
+	DefaultBackoffMax = time.Hour
+	// DefaultDisableAfter consecutive failed attempts before a subscription is disabled
+	DefaultDisableAfter = 20
+	// DefaultBatchSize deliveries claimed at once by the worker
+	DefaultBatchSize = 50
+	// DefaultConcurrency deliveries of a batch sent in parallel
+	DefaultConcurrency = 8
+	// DefaultLease time a claimed delivery is hidden from other workers
+	DefaultLease = time.Minute
+
+	secretPrefix   = "whsec_"
+	secretBytes    = 24
+	userAgent      = "collector-webhooks"
+	maxErrorLength = 512
+	maxBodyDrain   = 64 << 10
+)
@@ -0,0 +1,512 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/google/uuid"
+	"github.com/jmontesinos91/collector/domains/pagination"
+	"github.com/jmontesinos91/collector/domains/validation"
+	owebhook "github.com/jmontesinos91/collector/internal/repositories/webhook"
+	"github.com/jmontesinos91/collector/internal/services/audit"
+	"github.com/jmontesinos91/ologs/logger"
+	tracekey "github.com/jmontesinos91/ologs/logger/v2"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/jmontesinos91/terrors"
+	"github.com/sirupsen/logrus"
+)
+
+// DefaultService struct
+type DefaultService struct {
+	log         *logger.ContextLogger
+	webhookRepo owebhook.IRepository
+	auditor     audit.IRecorder
+	opts        Opts
+}
+
+// NewDefaultService creates a new instance of DefaultService
+func NewDefaultService(l *logger.ContextLogger, wr owebhook.IRepository, ar audit.IRecorder, opts Opts) *DefaultService {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultTimeout
+	}
+	if opts.HTTPClient == nil {
+		opts.HTTPClient = opts.Egress.Client()
+	}
+	if opts.MaxAttempts <= 0 {
+		opts.MaxAttempts = DefaultMaxAttempts
+	}
+	if opts.BackoffBase <= 0 {
+		opts.BackoffBase = DefaultBackoffBase
+	}
+	if opts.BackoffMax <= 0 {
+		opts.BackoffMax = DefaultBackoffMax
+	}
+	if opts.DisableAfter <= 0 {
+		opts.DisableAfter = DefaultDisableAfter
+	}
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBatchSize
+	}
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = DefaultConcurrency
+	}
+	if opts.Lease <= 0 {
+		opts.Lease = DefaultLease
+	}
+
+	return &DefaultService{
+		log:         l,
+		webhookRepo: wr,
+		auditor:     ar,
+		opts:        opts,
+	}
+}
+
+// HandleCreate Registers a new webhook endpoint for one of the tenants of the requesting user,
+// the generated secret is only returned here
+func (s *DefaultService) HandleCreate(ctx context.Context, request *CreateRequest) (Subscription, error) {
+	requestID := ctx.Value(middleware.RequestIDKey).(string)
+	claims := ctx.Value(&sts.Claim).(sts.Claims)
+
+	tenantID := request.TenantID
+	if tenantID == 0 && len(claims.Tenants) == 1 {
+		tenantID = claims.Tenants[0]
+	}
+	if !slices.Contains(claims.Tenants, tenantID) {
+		return Subscription{}, terrors.New(terrors.ErrForbidden, "Tenant is not allowed", map[string]string{})
+	}
+	if err := s.checkURL(ctx, request.URL); err != nil {
+		return Subscription{}, err
+	}
+
+	secret, err := generateSecret()
+	if err != nil {
+		return Subscription{}, terrors.InternalService("webhook_secret", "Failed to generate the webhook secret", map[string]string{})
+	}
+
+	now := time.Now().UTC()
+	model := &owebhook.SubscriptionModel{
+		ID:          uuid.NewString(),
+		TenantID:    tenantID,
+		URL:         request.URL,
+		Secret:      secret,
+		EventTypes:  slices.Compact(slices.Sorted(slices.Values(request.EventTypes))),
+		Description: request.Description,
+		Active:      true,
+		CreatedBy:   claims.UserID,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+	}
+
+	err = s.webhookRepo.CreateSubscription(ctx, model)
+	if err != nil {
+		s.log.WithContext(logrus.ErrorLevel,
+			"HandleCreate",
+			"Failed to create webhook subscription",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+				tracekey.UserID:     claims.UserID,
+				tracekey.Role:       claims.Role,
+			},
+			err)
+		return Subscription{}, err
+	}
+
+	subscription := ToSubscription(*model)
+	s.auditor.Record(ctx, audit.Event{
+		Action:   audit.ActionCreate,
+		Resource: audit.ResourceWebhook,
+		TargetID: subscription.ID,
+		After:    subscription,
+	})
+
+	subscription.Secret = secret
+	return subscription, nil
+}
+
+// HandleFind Retrieves the webhook subscriptions of the tenants of the requesting user
+func (s *DefaultService) HandleFind(ctx context.Context) ([]Subscription, error) {
+	requestID := ctx.Value(middleware.RequestIDKey).(string)
+	claims := ctx.Value(&sts.Claim).(sts.Claims)
+
+	if len(claims.Tenants) == 0 {
+		return []Subscription{}, nil
+	}
+
+	models, err := s.webhookRepo.FindSubscriptions(ctx, claims.Tenants)
+	if err != nil {
+		s.log.WithContext(logrus.ErrorLevel,
+			"HandleFind",
+			"Failed to retrieve webhook subscriptions",
+			logger.Context{
+				tracekey.TrackingID: requestID,
+				tracekey.UserID:     claims.UserID,
+				tracekey.Role:       claims.Role,
+			},
+			err)
+		return nil, err
+	}
+
+	return ToSubscriptionSlice(models), nil
+}
+
+// HandleRetrieve Retrieves a webhook subscription of the tenants of the requesting user
+func (s *DefaultService) HandleRetrieve(ctx context.Context, subscriptionID string) (Subscription, error) {
+	model, err := s.find(ctx, "HandleRetrieve", subscriptionID)
+	if err != nil {
+		return Subscription{}, err
+	}
+
+	return ToSubscription(*model), nil
+}
+
+// HandleUpdate Changes a webhook subscription, activating it again resets its failures
+func (s *DefaultService) HandleUpdate(ctx context.Context, subscriptionID string, request *UpdateRequest) (Subscription, error) {
+	model, err := s.find(ctx, "HandleUpdate", subscriptionID)
+	if err != nil {
+		return Subscription{}, err
+	}
+	before := ToSubscription(*model)
+
+	if request.URL != nil {
+		if err := s.checkURL(ctx, *request.URL); err != nil {
+			return Subscription{}, err
+		}
+		model.URL = *request.URL
+	}
+	if request.EventTypes != nil {
+		model.EventTypes = slices.Compact(slices.Sorted(slices.Values(request.EventTypes)))
+	}
+	if request.Description != nil {
+		model.Description = *request.Description
+	}
+	if request.Active != nil {
+		if *request.Active && !model.Active {
+			model.ConsecutiveFailures = 0
+			model.DisabledAt = nil
+			model.DisabledReason = ""
+		}
+		if !*request.Active && model.Active {
+			now := time.Now().UTC()
+			model.DisabledAt = &now
+			model.DisabledReason = "Disabled by user"
+		}
+		model.Active = *request.Active
+	}
+	model.UpdatedAt = time.Now().UTC()
+
+	err = s.webhookRepo.UpdateSubscription(ctx, model)
+	if err != nil {
+		s.logError(ctx, "HandleUpdate", "Failed to update webhook subscription", subscriptionID, err)
+		return Subscription{}, err
+	}
+
+	subscription := ToSubscription(*model)
+	s.auditor.Record(ctx, audit.Event{
+		Action:   audit.ActionUpdate,
+		Resource: audit.ResourceWebhook,
+		TargetID: subscription.ID,
+		Before:   before,
+		After:    subscription,
+	})
+
+	return subscription, nil
+}
+
+// HandleDelete Removes a webhook subscription along with its delivery log
+func (s *DefaultService) HandleDelete(ctx context.Context, subscriptionID string) error {
+	model, err := s.find(ctx, "HandleDelete", subscriptionID)
+	if err != nil {
+		return err
+	}
+
+	err = s.webhookRepo.DeleteSubscription(ctx, subscriptionID)
+	if err != nil {
+		s.logError(ctx, "HandleDelete", "Failed to delete webhook subscription", subscriptionID, err)
+		return err
+	}
+
+	s.auditor.Record(ctx, audit.Event{
+		Action:   audit.ActionDelete,
+		Resource: audit.ResourceWebhook,
+		TargetID: subscriptionID,
+		Before:   ToSubscription(*model),
+	})
+
+	return nil
+}
+
+// HandleDeliveries Retrieves a page of the delivery log of a webhook subscription
+func (s *DefaultService) HandleDeliveries(ctx context.Context, subscriptionID string, filter *DeliveryFilterRequest) (pagination.PaginatedRes, error) {
+	if _, err := s.find(ctx, "HandleDeliveries", subscriptionID); err != nil {
+		return pagination.PaginatedRes{}, err
+	}
+
+	_ = filter.Filter.SanitizePageFilter()
+
+	models, pages, total, err := s.webhookRepo.RetrieveDeliveries(ctx, ToMetadata(subscriptionID, filter))
+	if err != nil {
+		s.logError(ctx, "HandleDeliveries", "Failed to retrieve webhook deliveries", subscriptionID, err)
+		return pagination.PaginatedRes{}, err
+	}
+
+	return ToPaginatedResponse(ToDeliverySlice(models), filter.Filter.Page, pages, total), nil
+}
+
+// Dispatch queues the event for every active subscription of its tenant to the event type. The event
+// already happened when it is dispatched, so failures are logged instead of returned
+func (s *DefaultService) Dispatch(ctx context.Context, event Event) {
+	if event.TenantID == 0 {
+		return
+	}
+
+	requestID, _ := ctx.Value(middleware.RequestIDKey).(string)
+	logCtx := logger.Context{
+		tracekey.TrackingID: requestID,
+		"TenantID":          event.TenantID,
+		"EventType":         event.Type,
+	}
+
+	ctx = context.WithoutCancel(ctx)
+	subscriptions, err := s.webhookRepo.FindActiveByEvent(ctx, event.TenantID, event.Type)
+	if err != nil {
+		s.log.WithContext(logrus.ErrorLevel, "Dispatch", "Failed to find webhook subscriptions", logCtx, err)
+		return
+	}
+	if len(subscriptions) == 0 {
+		return
+	}
+
+	if event.ID == "" {
+		event.ID = uuid.NewString()
+	}
+	if event.OccurredAt.IsZero() {
+		event.OccurredAt = time.Now().UTC()
+	}
+
+	body, err := json.Marshal(Payload{
+		ID:         event.ID,
+		Type:       event.Type,
+		TenantID:   event.TenantID,
+		OccurredAt: event.OccurredAt,
+		Data:       event.Data,
+	})
+	if err != nil {
+		s.log.WithContext(logrus.ErrorLevel, "Dispatch", "Failed to encode webhook payload", logCtx, err)
+		return
+	}
+
+	now := time.Now().UTC()
+	deliveries := make([]owebhook.DeliveryModel, 0, len(subscriptions))
+	for _, subscription := range subscriptions {
+		deliveries = append(deliveries, owebhook.DeliveryModel{
+			ID:             uuid.NewString(),
+			SubscriptionID: subscription.ID,
+			EventID:        event.ID,
+			EventType:      event.Type,
+			Payload:        body,
+			Status:         owebhook.StatusPending,
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		})
+	}
+
+	if err := s.webhookRepo.CreateDeliveries(ctx, deliveries); err != nil {
+		s.log.WithContext(logrus.ErrorLevel, "Dispatch", "Failed to queue webhook deliveries", logCtx, err)
+	}
+}
+
+// ProcessDue sends the deliveries whose next attempt is due until none is left, it is meant to be
+// run periodically by the scheduler
+func (s *DefaultService) ProcessDue(ctx context.Context) {
+	for ctx.Err() == nil {
+		deliveries, err := s.webhookRepo.ClaimDue(ctx, time.Now().UTC(), s.opts.Lease, s.opts.BatchSize)
+		if err != nil {
+			s.log.Error(logrus.ErrorLevel, "ProcessDue", "Failed to claim webhook deliveries", err)
+			return
+		}
+		if len(deliveries) == 0 {
+			return
+		}
+
+		s.processBatch(ctx, deliveries)
+
+		if len(deliveries) < s.opts.BatchSize {
+			return
+		}
+	}
+}
+
+func (s *DefaultService) processBatch(ctx context.Context, deliveries []owebhook.DeliveryModel) {
+	ids := make([]string, 0, len(deliveries))
+	for _, delivery := range deliveries {
+		ids = append(ids, delivery.SubscriptionID)
+	}
+
+	models, err := s.webhookRepo.FindSubscriptionsByIDs(ctx, slices.Compact(slices.Sorted(slices.Values(ids))))
+	if err != nil {
+		// The claimed deliveries are attempted again once their lease expires
+		s.log.Error(logrus.ErrorLevel, "ProcessDue", "Failed to find webhook subscriptions", err)
+		return
+	}
+	subscriptions := make(map[string]owebhook.SubscriptionModel, len(models))
+	for _, model := range models {
+		subscriptions[model.ID] = model
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, s.opts.Concurrency)
+	for _, delivery := range deliveries {
+		subscription, ok := subscriptions[delivery.SubscriptionID]
+		if !ok || !subscription.Active {
+			s.finish(ctx, delivery, owebhook.Attempt{
+				Attempts: delivery.Attempts,
+				Error:    "Subscription is disabled",
+			}, true)
+			continue
+		}
+
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			s.deliver(ctx, subscription, delivery)
+		}()
+	}
+	wg.Wait()
+}
+
+func (s *DefaultService) deliver(ctx context.Context, subscription owebhook.SubscriptionModel, delivery owebhook.DeliveryModel) {
+	attempt := owebhook.Attempt{Attempts: delivery.Attempts + 1}
+	attempt.StatusCode, attempt.Error = s.send(ctx, subscription, delivery)
+
+	if attempt.Error == "" {
+		if err := s.webhookRepo.MarkDelivered(ctx, delivery.ID, attempt); err != nil {
+			s.log.Error(logrus.ErrorLevel, "ProcessDue", "Failed to update webhook delivery", err)
+		}
+		if subscription.ConsecutiveFailures > 0 {
+			if err := s.webhookRepo.RecordSuccess(ctx, subscription.ID); err != nil {
+				s.log.Error(logrus.ErrorLevel, "ProcessDue", "Failed to reset webhook failures", err)
+			}
+		}
+		return
+	}
+
+	logCtx := logger.Context{
+		"SubscriptionID": subscription.ID,
+		"DeliveryID":     delivery.ID,
+		"Attempts":       attempt.Attempts,
+		"StatusCode":     attempt.StatusCode,
+	}
+	s.log.WithContext(logrus.WarnLevel, "ProcessDue", "Webhook delivery attempt failed", logCtx, nil)
+
+	disabled, err := s.webhookRepo.RecordFailure(ctx, subscription.ID, s.opts.DisableAfter,
+		fmt.Sprintf("Disabled after %d consecutive failed deliveries, last error: %s", s.opts.DisableAfter, attempt.Error))
+	if err != nil {
+		s.log.Error(logrus.ErrorLevel, "ProcessDue", "Failed to record webhook failure", err)
+	}
+	if disabled {
+		s.log.WithContext(logrus.WarnLevel, "ProcessDue", "Webhook subscription disabled after repeated failures", logCtx, nil)
+	}
+
+	s.finish(ctx, delivery, attempt, disabled || attempt.Attempts >= s.opts.MaxAttempts)
+}
+
+// send posts the payload and returns the response status with the failure message, if any
+func (s *DefaultService) send(ctx context.Context, subscription owebhook.SubscriptionModel, delivery owebhook.DeliveryModel) (int, string) {
+	ctx, cancel := context.WithTimeout(ctx, s.opts.Timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, subscription.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return 0, truncate("Invalid request: " + err.Error())
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set(HeaderEvent, delivery.EventType)
+	req.Header.Set(HeaderDelivery, delivery.ID)
+	req.Header.Set(HeaderSignature, Sign(subscription.Secret, time.Now(), delivery.Payload))
+
+	res, err := s.opts.HTTPClient.Do(req)
+	if err != nil {
+		return 0, truncate(err.Error())
+	}
+	defer res.Body.Close() //nolint:errcheck
+
+	// Drain the body so the connection can be reused
+	_, _ = io.Copy(io.Discard, io.LimitReader(res.Body, maxBodyDrain))
+
+	if res.StatusCode < http.StatusOK || res.StatusCode >= http.StatusMultipleChoices {
+		return res.StatusCode, fmt.Sprintf("Unexpected response status %d", res.StatusCode)
+	}
+
+	return res.StatusCode, ""
+}
+
+// finish records a failed attempt, the delivery is scheduled again with backoff unless it is final
+func (s *DefaultService) finish(ctx context.Context, delivery owebhook.DeliveryModel, attempt owebhook.Attempt, final bool) {
+	var err error
+	if final {
+		err = s.webhookRepo.MarkFailed(ctx, delivery.ID, attempt)
+	} else {
+		attempt.NextAttemptAt = time.Now().UTC().Add(Backoff(attempt.Attempts, s.opts.BackoffBase, s.opts.BackoffMax))
+		err = s.webhookRepo.MarkRetry(ctx, delivery.ID, attempt)
+	}
+	if err != nil {
+		s.log.Error(logrus.ErrorLevel, "ProcessDue", "Failed to update webhook delivery", err)
+	}
+}
+
+// checkURL rejects the endpoints the egress policy does not allow, so the tenants cannot make the
+// server probe the internal network
+func (s *DefaultService) checkURL(ctx context.Context, rawURL string) error {
+	if err := s.opts.Egress.CheckURL(ctx, rawURL); err != nil {
+		errs := validation.Errors{}
+		errs.Add("url", err.Error())
+		return errs.Err()
+	}
+
+	return nil
+}
+
+func (s *DefaultService) find(ctx context.Context, method string, subscriptionID string) (*owebhook.SubscriptionModel, error) {
+	claims := ctx.Value(&sts.Claim).(sts.Claims)
+
+	if len(claims.Tenants) == 0 {
+		return nil, terrors.New(terrors.ErrNotFound, "Webhook subscription not found", map[string]string{})
+	}
+
+	model, err := s.webhookRepo.FindSubscription(ctx, subscriptionID, claims.Tenants)
+	if err != nil {
+		s.logError(ctx, method, "Failed to retrieve webhook subscription", subscriptionID, err)
+		return nil, err
+	}
+
+	return model, nil
+}
+
+func (s *DefaultService) logError(ctx context.Context, method, message, subscriptionID string, err error) {
+	requestID := ctx.Value(middleware.RequestIDKey).(string)
+	claims := ctx.Value(&sts.Claim).(sts.Claims)
+
+	s.log.WithContext(logrus.ErrorLevel,
+		method,
+		message,
+		logger.Context{
+			tracekey.TrackingID: requestID,
+			tracekey.UserID:     claims.UserID,
+			tracekey.Role:       claims.Role,
+			"SubscriptionID":    subscriptionID,
+		},
+		err)
+}
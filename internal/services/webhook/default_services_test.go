@@ -0,0 +1,534 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jmontesinos91/collector/domains/egress"
+	owebhook "github.com/jmontesinos91/collector/internal/repositories/webhook"
+	"github.com/jmontesinos91/collector/internal/repositories/webhook/webhookmocks"
+	"github.com/jmontesinos91/collector/internal/services/audit"
+	"github.com/jmontesinos91/collector/internal/services/audit/recordermocks"
+	"github.com/jmontesinos91/collector/internal/services/webhook"
+	"github.com/jmontesinos91/ologs/logger"
+	"github.com/jmontesinos91/osecurity/sts"
+	"github.com/jmontesinos91/terrors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const (
+	subscriptionID = "8d3e6a52-3c1f-4f0e-9d0e-5f2b8d1c7a10"
+	deliveryID     = "0f6c1d8e-77a4-4b3a-9e61-2c5d9f3b8e42"
+	secret         = "whsec_unit-test-secret"
+)
+
+var payload = []byte(`{"id":"unit-test-event","type":"alarm.accepted","tenantId":4,"data":{}}`)
+
+func auditRecorder() *recordermocks.IRecorder {
+	recorder := &recordermocks.IRecorder{}
+	recorder.On("Record", mock.Anything, mock.Anything).Return()
+	return recorder
+}
+
+// resolver resolves the endpoints of the tests without reaching the network
+type resolver map[string][]netip.Addr
+
+func (r resolver) LookupNetIP(_ context.Context, _, host string) ([]netip.Addr, error) {
+	return r[host], nil
+}
+
+var egressPolicy = egress.Policy{Resolver: resolver{
+	"example.com":  {netip.MustParseAddr("93.184.216.34")},
+	"internal.lan": {netip.MustParseAddr("10.0.0.12")},
+}}
+
+func testContext(tenants ...int) context.Context {
+	ctxBack := context.Background()
+	ctxBack = context.WithValue(ctxBack, middleware.RequestIDKey, "unit-test-request-id")
+	return context.WithValue(ctxBack, &sts.Claim, sts.Claims{
+		UserID:  7,
+		Role:    "unit-test-role",
+		Tenants: tenants,
+	})
+}
+
+func TestHandleCreate(t *testing.T) {
+	log := logger.NewContextLogger("HandleCreate", "debug", logger.TextFormat)
+
+	cases := []struct { //nolint:wsl
+		name        string
+		ctx         context.Context
+		request     *webhook.CreateRequest
+		webhookFunc func() *webhookmocks.IRepository
+		asserts     func(*testing.T, webhook.Subscription, error, *webhookmocks.IRepository, *recordermocks.IRecorder) bool
+	}{
+		{
+			name: "Happy path the secret is only returned on creation",
+			ctx:  testContext(4, 5),
+			request: &webhook.CreateRequest{
+				TenantID:   5,
+				URL:        "https://example.com/hooks",
+				EventTypes: []string{webhook.EventDevicePosition, webhook.EventAlarmAccepted, webhook.EventAlarmAccepted},
+			},
+			webhookFunc: func() *webhookmocks.IRepository {
+				repositoryMock := &webhookmocks.IRepository{}
+				repositoryMock.On("CreateSubscription", mock.Anything, mock.MatchedBy(func(m *owebhook.SubscriptionModel) bool {
+					return m.TenantID == 5 &&
+						m.CreatedBy == 7 &&
+						m.Active &&
+						len(m.Secret) > 32 &&
+						assert.ObjectsAreEqual([]string{webhook.EventAlarmAccepted, webhook.EventDevicePosition}, m.EventTypes)
+				})).Return(nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, res webhook.Subscription, err error, repo *webhookmocks.IRepository, recorder *recordermocks.IRecorder) bool {
+				return assert.NoError(t, err) &&
+					assert.NotEmpty(t, res.Secret) &&
+					recorder.AssertCalled(t, "Record", mock.Anything, mock.MatchedBy(func(e audit.Event) bool {
+						after, ok := e.After.(webhook.Subscription)
+						return e.Resource == audit.ResourceWebhook && ok && after.Secret == ""
+					})) &&
+					repo.AssertExpectations(t)
+			},
+		},
+		{
+			name: "The only tenant of the user is used by default",
+			ctx:  testContext(4),
+			request: &webhook.CreateRequest{
+				URL:        "https://example.com/hooks",
+				EventTypes: []string{webhook.EventAlarmAccepted},
+			},
+			webhookFunc: func() *webhookmocks.IRepository {
+				repositoryMock := &webhookmocks.IRepository{}
+				repositoryMock.On("CreateSubscription", mock.Anything, mock.MatchedBy(func(m *owebhook.SubscriptionModel) bool {
+					return m.TenantID == 4
+				})).Return(nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, res webhook.Subscription, err error, repo *webhookmocks.IRepository, _ *recordermocks.IRecorder) bool {
+				return assert.NoError(t, err) &&
+					assert.Equal(t, 4, res.TenantID) &&
+					repo.AssertExpectations(t)
+			},
+		},
+		{
+			name: "Endpoint resolving to the internal network is rejected",
+			ctx:  testContext(4),
+			request: &webhook.CreateRequest{
+				URL:        "https://internal.lan/hooks",
+				EventTypes: []string{webhook.EventAlarmAccepted},
+			},
+			webhookFunc: func() *webhookmocks.IRepository {
+				return &webhookmocks.IRepository{}
+			},
+			asserts: func(t *testing.T, _ webhook.Subscription, err error, repo *webhookmocks.IRepository, _ *recordermocks.IRecorder) bool {
+				return assert.True(t, terrors.Is(err, terrors.ErrBadRequest)) &&
+					assert.ErrorContains(t, err, "Invalid url parameter, destination address is not allowed") &&
+					repo.AssertNotCalled(t, "CreateSubscription", mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name: "Cloud metadata endpoint is rejected",
+			ctx:  testContext(4),
+			request: &webhook.CreateRequest{
+				URL:        "https://169.254.169.254/latest/meta-data",
+				EventTypes: []string{webhook.EventAlarmAccepted},
+			},
+			webhookFunc: func() *webhookmocks.IRepository {
+				return &webhookmocks.IRepository{}
+			},
+			asserts: func(t *testing.T, _ webhook.Subscription, err error, repo *webhookmocks.IRepository, _ *recordermocks.IRecorder) bool {
+				return assert.True(t, terrors.Is(err, terrors.ErrBadRequest)) &&
+					repo.AssertNotCalled(t, "CreateSubscription", mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name: "Plain http endpoint is rejected",
+			ctx:  testContext(4),
+			request: &webhook.CreateRequest{
+				URL:        "http://example.com/hooks",
+				EventTypes: []string{webhook.EventAlarmAccepted},
+			},
+			webhookFunc: func() *webhookmocks.IRepository {
+				return &webhookmocks.IRepository{}
+			},
+			asserts: func(t *testing.T, _ webhook.Subscription, err error, repo *webhookmocks.IRepository, _ *recordermocks.IRecorder) bool {
+				return assert.ErrorContains(t, err, "Invalid url parameter, an https url is required") &&
+					repo.AssertNotCalled(t, "CreateSubscription", mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name: "Tenant of another user is forbidden",
+			ctx:  testContext(4),
+			request: &webhook.CreateRequest{
+				TenantID:   9,
+				URL:        "https://example.com/hooks",
+				EventTypes: []string{webhook.EventAlarmAccepted},
+			},
+			webhookFunc: func() *webhookmocks.IRepository {
+				return &webhookmocks.IRepository{}
+			},
+			asserts: func(t *testing.T, _ webhook.Subscription, err error, repo *webhookmocks.IRepository, recorder *recordermocks.IRecorder) bool {
+				return assert.True(t, terrors.Is(err, terrors.ErrForbidden)) &&
+					repo.AssertNotCalled(t, "CreateSubscription", mock.Anything, mock.Anything) &&
+					recorder.AssertNotCalled(t, "Record", mock.Anything, mock.Anything)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := tc.webhookFunc()
+			recorder := auditRecorder()
+			service := webhook.NewDefaultService(log, repo, recorder, webhook.Opts{Egress: egressPolicy})
+
+			res, err := service.HandleCreate(tc.ctx, tc.request)
+			if !tc.asserts(t, res, err, repo, recorder) {
+				t.Errorf("Assert error on test = '%v'", tc.name)
+			}
+		})
+	}
+}
+
+func TestHandleUpdate(t *testing.T) {
+	log := logger.NewContextLogger("HandleUpdate", "debug", logger.TextFormat)
+	active := true
+	disabledAt := time.Now().UTC()
+	internalURL := "https://internal.lan/hooks"
+
+	cases := []struct { //nolint:wsl
+		name        string
+		ctx         context.Context
+		request     *webhook.UpdateRequest
+		webhookFunc func() *webhookmocks.IRepository
+		asserts     func(*testing.T, webhook.Subscription, error, *webhookmocks.IRepository) bool
+	}{
+		{
+			name:    "Activating a disabled subscription resets its failures",
+			ctx:     testContext(4),
+			request: &webhook.UpdateRequest{Active: &active},
+			webhookFunc: func() *webhookmocks.IRepository {
+				repositoryMock := &webhookmocks.IRepository{}
+				repositoryMock.On("FindSubscription", mock.Anything, subscriptionID, []int{4}).
+					Return(&owebhook.SubscriptionModel{
+						ID:                  subscriptionID,
+						TenantID:            4,
+						ConsecutiveFailures: 20,
+						DisabledAt:          &disabledAt,
+						DisabledReason:      "Disabled after 20 consecutive failed deliveries",
+					}, nil)
+				repositoryMock.On("UpdateSubscription", mock.Anything, mock.MatchedBy(func(m *owebhook.SubscriptionModel) bool {
+					return m.Active && m.ConsecutiveFailures == 0 && m.DisabledAt == nil && m.DisabledReason == ""
+				})).Return(nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, res webhook.Subscription, err error, repo *webhookmocks.IRepository) bool {
+				return assert.NoError(t, err) &&
+					assert.True(t, res.Active) &&
+					repo.AssertExpectations(t)
+			},
+		},
+		{
+			name:    "Moving the endpoint to the internal network is rejected",
+			ctx:     testContext(4),
+			request: &webhook.UpdateRequest{URL: &internalURL},
+			webhookFunc: func() *webhookmocks.IRepository {
+				repositoryMock := &webhookmocks.IRepository{}
+				repositoryMock.On("FindSubscription", mock.Anything, subscriptionID, []int{4}).
+					Return(&owebhook.SubscriptionModel{ID: subscriptionID, TenantID: 4, URL: "https://example.com/hooks", Active: true}, nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, _ webhook.Subscription, err error, repo *webhookmocks.IRepository) bool {
+				return assert.True(t, terrors.Is(err, terrors.ErrBadRequest)) &&
+					repo.AssertNotCalled(t, "UpdateSubscription", mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name:    "Subscription of another tenant is not found",
+			ctx:     testContext(4),
+			request: &webhook.UpdateRequest{Active: &active},
+			webhookFunc: func() *webhookmocks.IRepository {
+				repositoryMock := &webhookmocks.IRepository{}
+				repositoryMock.On("FindSubscription", mock.Anything, subscriptionID, []int{4}).
+					Return(nil, terrors.New(terrors.ErrNotFound, "Webhook subscription not found", map[string]string{}))
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, _ webhook.Subscription, err error, repo *webhookmocks.IRepository) bool {
+				return assert.True(t, terrors.Is(err, terrors.ErrNotFound)) &&
+					repo.AssertNotCalled(t, "UpdateSubscription", mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name:    "User without tenants does not reach the database",
+			ctx:     testContext(),
+			request: &webhook.UpdateRequest{Active: &active},
+			webhookFunc: func() *webhookmocks.IRepository {
+				return &webhookmocks.IRepository{}
+			},
+			asserts: func(t *testing.T, _ webhook.Subscription, err error, repo *webhookmocks.IRepository) bool {
+				return assert.True(t, terrors.Is(err, terrors.ErrNotFound)) &&
+					repo.AssertNotCalled(t, "FindSubscription", mock.Anything, mock.Anything, mock.Anything)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := tc.webhookFunc()
+			service := webhook.NewDefaultService(log, repo, auditRecorder(), webhook.Opts{Egress: egressPolicy})
+
+			res, err := service.HandleUpdate(tc.ctx, subscriptionID, tc.request)
+			if !tc.asserts(t, res, err, repo) {
+				t.Errorf("Assert error on test = '%v'", tc.name)
+			}
+		})
+	}
+}
+
+func TestDispatch(t *testing.T) {
+	log := logger.NewContextLogger("Dispatch", "debug", logger.TextFormat)
+
+	cases := []struct { //nolint:wsl
+		name        string
+		event       webhook.Event
+		webhookFunc func() *webhookmocks.IRepository
+		asserts     func(*testing.T, *webhookmocks.IRepository) bool
+	}{
+		{
+			name: "Happy path one delivery per subscription with the same payload",
+			event: webhook.Event{
+				ID:       "unit-test-event",
+				Type:     webhook.EventAlarmAccepted,
+				TenantID: 4,
+				Data:     map[string]string{"imei": "861585041440544"},
+			},
+			webhookFunc: func() *webhookmocks.IRepository {
+				repositoryMock := &webhookmocks.IRepository{}
+				repositoryMock.On("FindActiveByEvent", mock.Anything, 4, webhook.EventAlarmAccepted).
+					Return([]owebhook.SubscriptionModel{{ID: "first"}, {ID: "second"}}, nil)
+				repositoryMock.On("CreateDeliveries", mock.Anything, mock.MatchedBy(func(models []owebhook.DeliveryModel) bool {
+					var body webhook.Payload
+					if len(models) != 2 || json.Unmarshal(models[0].Payload, &body) != nil {
+						return false
+					}
+					return models[0].SubscriptionID == "first" &&
+						models[1].SubscriptionID == "second" &&
+						models[0].Status == owebhook.StatusPending &&
+						string(models[0].Payload) == string(models[1].Payload) &&
+						body.ID == "unit-test-event" &&
+						body.TenantID == 4 &&
+						!body.OccurredAt.IsZero()
+				})).Return(nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, repo *webhookmocks.IRepository) bool {
+				return repo.AssertExpectations(t)
+			},
+		},
+		{
+			name:  "Without subscriptions nothing is queued",
+			event: webhook.Event{Type: webhook.EventDevicePosition, TenantID: 4},
+			webhookFunc: func() *webhookmocks.IRepository {
+				repositoryMock := &webhookmocks.IRepository{}
+				repositoryMock.On("FindActiveByEvent", mock.Anything, 4, webhook.EventDevicePosition).
+					Return([]owebhook.SubscriptionModel{}, nil)
+				return repositoryMock
+			},
+			asserts: func(t *testing.T, repo *webhookmocks.IRepository) bool {
+				return repo.AssertExpectations(t) &&
+					repo.AssertNotCalled(t, "CreateDeliveries", mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name:  "Devices without tenant are skipped",
+			event: webhook.Event{Type: webhook.EventAlarmAccepted},
+			webhookFunc: func() *webhookmocks.IRepository {
+				return &webhookmocks.IRepository{}
+			},
+			asserts: func(t *testing.T, repo *webhookmocks.IRepository) bool {
+				return repo.AssertNotCalled(t, "FindActiveByEvent", mock.Anything, mock.Anything, mock.Anything)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			repo := tc.webhookFunc()
+			service := webhook.NewDefaultService(log, repo, auditRecorder(), webhook.Opts{})
+
+			service.Dispatch(testContext(), tc.event)
+			if !tc.asserts(t, repo) {
+				t.Errorf("Assert error on test = '%v'", tc.name)
+			}
+		})
+	}
+}
+
+func TestProcessDue(t *testing.T) {
+	log := logger.NewContextLogger("ProcessDue", "debug", logger.TextFormat)
+	opts := webhook.Opts{
+		// The test endpoints listen on the loopback
+		Egress:       egress.Policy{AllowPrivate: true},
+		MaxAttempts:  3,
+		BackoffBase:  time.Minute,
+		DisableAfter: 5,
+		BatchSize:    10,
+	}
+
+	type request struct { //nolint:wsl
+		header http.Header
+		body   []byte
+	}
+
+	cases := []struct { //nolint:wsl
+		name         string
+		status       int
+		delivery     owebhook.DeliveryModel
+		subscription owebhook.SubscriptionModel
+		webhookFunc  func(*webhookmocks.IRepository)
+		asserts      func(*testing.T, []request, *webhookmocks.IRepository) bool
+	}{
+		{
+			name:         "Happy path the signed payload is delivered",
+			status:       http.StatusNoContent,
+			delivery:     owebhook.DeliveryModel{ID: deliveryID, EventType: webhook.EventAlarmAccepted},
+			subscription: owebhook.SubscriptionModel{Active: true, ConsecutiveFailures: 2},
+			webhookFunc: func(repositoryMock *webhookmocks.IRepository) {
+				repositoryMock.On("MarkDelivered", mock.Anything, deliveryID, owebhook.Attempt{
+					Attempts:   1,
+					StatusCode: http.StatusNoContent,
+				}).Return(nil)
+				repositoryMock.On("RecordSuccess", mock.Anything, subscriptionID).Return(nil)
+			},
+			asserts: func(t *testing.T, requests []request, repo *webhookmocks.IRepository) bool {
+				if !assert.Len(t, requests, 1) {
+					return false
+				}
+				signature := requests[0].header.Get(webhook.HeaderSignature)
+				var unix int64
+				_, err := fmt.Sscanf(signature, "t=%d,", &unix)
+				return assert.NoError(t, err) &&
+					assert.Equal(t, webhook.Sign(secret, time.Unix(unix, 0), payload), signature) &&
+					assert.Equal(t, string(payload), string(requests[0].body)) &&
+					assert.Equal(t, webhook.EventAlarmAccepted, requests[0].header.Get(webhook.HeaderEvent)) &&
+					assert.Equal(t, deliveryID, requests[0].header.Get(webhook.HeaderDelivery)) &&
+					repo.AssertExpectations(t)
+			},
+		},
+		{
+			name:         "Error response schedules a retry with backoff",
+			status:       http.StatusInternalServerError,
+			delivery:     owebhook.DeliveryModel{ID: deliveryID, Attempts: 1},
+			subscription: owebhook.SubscriptionModel{Active: true},
+			webhookFunc: func(repositoryMock *webhookmocks.IRepository) {
+				repositoryMock.On("RecordFailure", mock.Anything, subscriptionID, 5, mock.Anything).Return(false, nil)
+				repositoryMock.On("MarkRetry", mock.Anything, deliveryID, mock.MatchedBy(func(a owebhook.Attempt) bool {
+					wait := time.Until(a.NextAttemptAt)
+					return a.Attempts == 2 &&
+						a.StatusCode == http.StatusInternalServerError &&
+						a.Error != "" &&
+						wait > time.Minute && wait <= 2*time.Minute
+				})).Return(nil)
+			},
+			asserts: func(t *testing.T, requests []request, repo *webhookmocks.IRepository) bool {
+				return assert.Len(t, requests, 1) &&
+					repo.AssertExpectations(t) &&
+					repo.AssertNotCalled(t, "MarkFailed", mock.Anything, mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name:         "Last attempt marks the delivery as failed",
+			status:       http.StatusBadGateway,
+			delivery:     owebhook.DeliveryModel{ID: deliveryID, Attempts: 2},
+			subscription: owebhook.SubscriptionModel{Active: true},
+			webhookFunc: func(repositoryMock *webhookmocks.IRepository) {
+				repositoryMock.On("RecordFailure", mock.Anything, subscriptionID, 5, mock.Anything).Return(false, nil)
+				repositoryMock.On("MarkFailed", mock.Anything, deliveryID, mock.MatchedBy(func(a owebhook.Attempt) bool {
+					return a.Attempts == 3 && a.StatusCode == http.StatusBadGateway
+				})).Return(nil)
+			},
+			asserts: func(t *testing.T, requests []request, repo *webhookmocks.IRepository) bool {
+				return assert.Len(t, requests, 1) &&
+					repo.AssertExpectations(t) &&
+					repo.AssertNotCalled(t, "MarkRetry", mock.Anything, mock.Anything, mock.Anything)
+			},
+		},
+		{
+			name:         "Failure that disables the subscription ends the delivery",
+			status:       http.StatusServiceUnavailable,
+			delivery:     owebhook.DeliveryModel{ID: deliveryID},
+			subscription: owebhook.SubscriptionModel{Active: true, ConsecutiveFailures: 4},
+			webhookFunc: func(repositoryMock *webhookmocks.IRepository) {
+				repositoryMock.On("RecordFailure", mock.Anything, subscriptionID, 5, mock.Anything).Return(true, nil)
+				repositoryMock.On("MarkFailed", mock.Anything, deliveryID, mock.Anything).Return(nil)
+			},
+			asserts: func(t *testing.T, requests []request, repo *webhookmocks.IRepository) bool {
+				return assert.Len(t, requests, 1) &&
+					repo.AssertExpectations(t)
+			},
+		},
+		{
+			name:         "Deliveries of a disabled subscription are not sent",
+			status:       http.StatusOK,
+			delivery:     owebhook.DeliveryModel{ID: deliveryID, Attempts: 1},
+			subscription: owebhook.SubscriptionModel{Active: false},
+			webhookFunc: func(repositoryMock *webhookmocks.IRepository) {
+				repositoryMock.On("MarkFailed", mock.Anything, deliveryID, owebhook.Attempt{
+					Attempts: 1,
+					Error:    "Subscription is disabled",
+				}).Return(nil)
+			},
+			asserts: func(t *testing.T, requests []request, repo *webhookmocks.IRepository) bool {
+				return assert.Empty(t, requests) &&
+					repo.AssertExpectations(t)
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			requests := make(chan request, 1)
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				body, _ := io.ReadAll(r.Body)
+				requests <- request{header: r.Header, body: body}
+				w.WriteHeader(tc.status)
+			}))
+			defer server.Close()
+
+			tc.delivery.SubscriptionID = subscriptionID
+			tc.delivery.Payload = payload
+			tc.subscription.ID = subscriptionID
+			tc.subscription.URL = server.URL
+			tc.subscription.Secret = secret
+
+			repo := &webhookmocks.IRepository{}
+			repo.On("ClaimDue", mock.Anything, mock.Anything, webhook.DefaultLease, opts.BatchSize).
+				Return([]owebhook.DeliveryModel{tc.delivery}, nil)
+			repo.On("FindSubscriptionsByIDs", mock.Anything, []string{subscriptionID}).
+				Return([]owebhook.SubscriptionModel{tc.subscription}, nil)
+			tc.webhookFunc(repo)
+
+			service := webhook.NewDefaultService(log, repo, auditRecorder(), opts)
+			service.ProcessDue(context.Background())
+
+			close(requests)
+			var received []request
+			for r := range requests {
+				received = append(received, r)
+			}
+
+			if !tc.asserts(t, received, repo) {
+				t.Errorf("Assert error on test = '%v'", tc.name)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/jmontesinos91/collector/domains/pagination"
+	owebhook "github.com/jmontesinos91/collector/internal/repositories/webhook"
+	"github.com/jmontesinos91/terrors"
+)
+
+// ParseCreateRequest builds a new subscription request given the http body
+func ParseCreateRequest(r *http.Request) (*CreateRequest, error) {
+	var request CreateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return nil, terrors.New(terrors.ErrBadRequest, "Invalid request body", map[string]string{})
+	}
+
+	if err := validateURL(request.URL); err != nil {
+		return nil, err
+	}
+	if err := validateEventTypes(request.EventTypes); err != nil {
+		return nil, err
+	}
+
+	return &request, nil
+}
+
+// ParseUpdateRequest builds a subscription update request given the http body
+func ParseUpdateRequest(r *http.Request) (*UpdateRequest, error) {
+	var request UpdateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		return nil, terrors.New(terrors.ErrBadRequest, "Invalid request body", map[string]string{})
+	}
+
+	if request.URL != nil {
+		if err := validateURL(*request.URL); err != nil {
+			return nil, err
+		}
+	}
+	if request.EventTypes != nil {
+		if err := validateEventTypes(request.EventTypes); err != nil {
+			return nil, err
+		}
+	}
+
+	return &request, nil
+}
+
+// ParseDeliveryFilterRequest builds the delivery log filter given http params
+func ParseDeliveryFilterRequest(r *http.Request) (*DeliveryFilterRequest, error) {
+	query := r.URL.Query()
+
+	fr := DeliveryFilterRequest{
+		Status:    query.Get("status"),
+		EventType: query.Get("eventType"),
+	}
+
+	switch fr.Status {
+	case "", owebhook.StatusPending, owebhook.StatusDelivered, owebhook.StatusFailed:
+	default:
+		return nil, terrors.New(terrors.ErrBadRequest, "Invalid status parameter", map[string]string{})
+	}
+
+	if fr.EventType != "" && !slices.Contains(EventTypes, fr.EventType) {
+		return nil, terrors.New(terrors.ErrBadRequest, "Invalid eventType parameter", map[string]string{})
+	}
+
+	if sizeStr := query.Get("size"); sizeStr != "" {
+		size, err := strconv.Atoi(sizeStr)
+		if err != nil {
+			return nil, terrors.New(terrors.ErrBadRequest, "Invalid size parameter", map[string]string{})
+		}
+		fr.Filter.Size = size
+	}
+
+	if pageStr := query.Get("page"); pageStr != "" {
+		page, err := strconv.Atoi(pageStr)
+		if err != nil {
+			return nil, terrors.New(terrors.ErrBadRequest, "Invalid page parameter", map[string]string{})
+		}
+		fr.Filter.Page = page
+	}
+
+	if countStr := query.Get("count"); countStr != "" {
+		count, err := strconv.ParseBool(countStr)
+		if err != nil {
+			return nil, terrors.New(terrors.ErrBadRequest, "Invalid count parameter", map[string]string{})
+		}
+		fr.Filter.Count = &count
+	}
+
+	return &fr, nil
+}
+
+func validateURL(raw string) error {
+	parsed, err := url.Parse(raw)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return terrors.New(terrors.ErrBadRequest, "Invalid url parameter, an absolute http or https url is required", map[string]string{})
+	}
+
+	return nil
+}
+
+func validateEventTypes(eventTypes []string) error {
+	if len(eventTypes) == 0 {
+		return terrors.New(terrors.ErrBadRequest, "Invalid eventTypes parameter, at least one event type is required", map[string]string{})
+	}
+	for _, eventType := range eventTypes {
+		if !slices.Contains(EventTypes, eventType) {
+			return terrors.New(terrors.ErrBadRequest, "Invalid eventTypes parameter", map[string]string{"eventType": eventType})
+		}
+	}
+
+	return nil
+}
+
+// Sign returns the signature header value of a delivery body, receivers recompute the HMAC with
+// their secret and compare it, the timestamp lets them reject replayed deliveries
+func Sign(secret string, timestamp time.Time, body []byte) string {
+	unix := strconv.FormatInt(timestamp.Unix(), 10)
+
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(unix))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return fmt.Sprintf("t=%s,v1=%s", unix, hex.EncodeToString(mac.Sum(nil)))
+}
+
+// Backoff returns the wait before the next attempt of a delivery that failed the given number of times
+func Backoff(attempts int, base, limit time.Duration) time.Duration {
+	wait := base
+	for i := 1; i < attempts && wait < limit; i++ {
+		wait *= 2
+	}
+
+	return min(wait, limit)
+}
+
+func generateSecret() (string, error) {
+	buf := make([]byte, secretBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+
+	return secretPrefix + hex.EncodeToString(buf), nil
+}
+
+func truncate(message string) string {
+	if len(message) <= maxErrorLength {
+		return message
+	}
+
+	return message[:maxErrorLength]
+}
+
+// ToMetadata maps the properties of the service filter into repo filter
+func ToMetadata(subscriptionID string, filterRequest *DeliveryFilterRequest) *owebhook.DeliveryMetadata {
+	return &owebhook.DeliveryMetadata{
+		SubscriptionID: subscriptionID,
+		Status:         filterRequest.Status,
+		EventType:      filterRequest.EventType,
+		Filter:         filterRequest.Filter,
+	}
+}
+
+// ToSubscriptionSlice converts a subscription model slice into a serializable slice
+func ToSubscriptionSlice(models []owebhook.SubscriptionModel) []Subscription {
+	subscriptions := make([]Subscription, 0, len(models))
+	for _, model := range models {
+		subscriptions = append(subscriptions, ToSubscription(model))
+	}
+
+	return subscriptions
+}
+
+// ToSubscription converts a model to a Subscription struct to be serialized, without its secret
+func ToSubscription(model owebhook.SubscriptionModel) Subscription {
+	return Subscription{
+		ID:                  model.ID,
+		TenantID:            model.TenantID,
+		URL:                 model.URL,
+		EventTypes:          model.EventTypes,
+		Description:         model.Description,
+		Active:              model.Active,
+		ConsecutiveFailures: model.ConsecutiveFailures,
+		DisabledAt:          model.DisabledAt,
+		DisabledReason:      model.DisabledReason,
+		CreatedBy:           model.CreatedBy,
+		CreatedAt:           model.CreatedAt,
+		UpdatedAt:           model.UpdatedAt,
+	}
+}
+
+// ToDeliverySlice converts a delivery model slice into a serializable slice
+func ToDeliverySlice(models []owebhook.DeliveryModel) []Delivery {
+	deliveries := make([]Delivery, 0, len(models))
+	for _, model := range models {
+		deliveries = append(deliveries, ToDelivery(model))
+	}
+
+	return deliveries
+}
+
+// ToDelivery converts a model to a Delivery struct to be serialized, the next attempt
+// is only reported while the delivery is pending
+func ToDelivery(model owebhook.DeliveryModel) Delivery {
+	delivery := Delivery{
+		ID:             model.ID,
+		SubscriptionID: model.SubscriptionID,
+		EventID:        model.EventID,
+		EventType:      model.EventType,
+		Payload:        model.Payload,
+		Status:         model.Status,
+		Attempts:       model.Attempts,
+		LastStatusCode: model.LastStatusCode,
+		LastError:      model.LastError,
+		CreatedAt:      model.CreatedAt,
+		DeliveredAt:    model.DeliveredAt,
+	}
+	if model.Status == owebhook.StatusPending {
+		nextAttemptAt := model.NextAttemptAt
+		delivery.NextAttemptAt = &nextAttemptAt
+	}
+
+	return delivery
+}
+
+// ToPaginatedResponse creates a paginated response
+func ToPaginatedResponse(data interface{}, currentPage, pages, total int) pagination.PaginatedRes {
+	return pagination.PaginatedRes{
+		Data:        data,
+		CurrentPage: currentPage,
+		Pages:       pages,
+		Total:       total,
+	}
+}
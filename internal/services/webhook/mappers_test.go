@@ -0,0 +1,202 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmontesinos91/collector/domains/pagination"
+	owebhook "github.com/jmontesinos91/collector/internal/repositories/webhook"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCreateRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		expected *CreateRequest
+		errorMsg string
+	}{
+		{
+			name: "Happy path valid body",
+			body: `{"tenantId":4,"url":"https://example.com/hooks","eventTypes":["alarm.accepted"],"description":"Control room"}`,
+			expected: &CreateRequest{
+				TenantID:    4,
+				URL:         "https://example.com/hooks",
+				EventTypes:  []string{EventAlarmAccepted},
+				Description: "Control room",
+			},
+		},
+		{
+			name:     "Invalid body",
+			body:     `{"url":`,
+			errorMsg: "Invalid request body",
+		},
+		{
+			name:     "Relative url",
+			body:     `{"url":"/hooks","eventTypes":["alarm.accepted"]}`,
+			errorMsg: "Invalid url parameter",
+		},
+		{
+			name:     "Unsupported url scheme",
+			body:     `{"url":"ftp://example.com/hooks","eventTypes":["alarm.accepted"]}`,
+			errorMsg: "Invalid url parameter",
+		},
+		{
+			name:     "Missing event types",
+			body:     `{"url":"https://example.com/hooks"}`,
+			errorMsg: "at least one event type is required",
+		},
+		{
+			name:     "Unknown event type",
+			body:     `{"url":"https://example.com/hooks","eventTypes":["alarm.deleted"]}`,
+			errorMsg: "Invalid eventTypes parameter",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodPost, "/v1/webhooks", strings.NewReader(tt.body))
+
+			request, err := ParseCreateRequest(req)
+			if tt.errorMsg != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+				assert.Nil(t, request)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, request)
+			}
+		})
+	}
+}
+
+func TestParseUpdateRequest(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodPut, "/v1/webhooks/1", strings.NewReader(`{"active":true}`))
+	request, err := ParseUpdateRequest(req)
+	assert.NoError(t, err)
+	assert.True(t, *request.Active)
+	assert.Nil(t, request.URL)
+	assert.Nil(t, request.EventTypes)
+
+	req, _ = http.NewRequest(http.MethodPut, "/v1/webhooks/1", strings.NewReader(`{"eventTypes":[]}`))
+	_, err = ParseUpdateRequest(req)
+	assert.ErrorContains(t, err, "at least one event type is required")
+}
+
+func TestParseDeliveryFilterRequest(t *testing.T) {
+	count := false
+	tests := []struct {
+		name        string
+		queryParams map[string]string
+		expected    *DeliveryFilterRequest
+		errorMsg    string
+	}{
+		{
+			name: "Happy path valid parameters",
+			queryParams: map[string]string{
+				"status":    owebhook.StatusFailed,
+				"eventType": EventDevicePosition,
+				"page":      "2",
+				"size":      "20",
+				"count":     "false",
+			},
+			expected: &DeliveryFilterRequest{
+				Status:    owebhook.StatusFailed,
+				EventType: EventDevicePosition,
+				Filter:    pagination.Filter{Page: 2, Size: 20, Count: &count},
+			},
+		},
+		{
+			name:        "Invalid status parameter",
+			queryParams: map[string]string{"status": "lost"},
+			errorMsg:    "Invalid status parameter",
+		},
+		{
+			name:        "Invalid eventType parameter",
+			queryParams: map[string]string{"eventType": "device.deleted"},
+			errorMsg:    "Invalid eventType parameter",
+		},
+		{
+			name:        "Invalid page parameter",
+			queryParams: map[string]string{"page": "first"},
+			errorMsg:    "Invalid page parameter",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := url.Values{}
+			for key, value := range tt.queryParams {
+				query.Set(key, value)
+			}
+			req := &http.Request{
+				URL: &url.URL{RawQuery: query.Encode()},
+			}
+
+			fr, err := ParseDeliveryFilterRequest(req)
+			if tt.errorMsg != "" {
+				assert.Error(t, err)
+				assert.Contains(t, err.Error(), tt.errorMsg)
+				assert.Nil(t, fr)
+			} else {
+				assert.NoError(t, err)
+				assert.Equal(t, tt.expected, fr)
+			}
+		})
+	}
+}
+
+func TestSign(t *testing.T) {
+	body := []byte(`{"id":"1"}`)
+	timestamp := time.Unix(1792406400, 0)
+
+	mac := hmac.New(sha256.New, []byte("whsec_unit-test"))
+	mac.Write([]byte("1792406400." + string(body)))
+
+	assert.Equal(t, "t=1792406400,v1="+hex.EncodeToString(mac.Sum(nil)), Sign("whsec_unit-test", timestamp, body))
+	assert.NotEqual(t, Sign("whsec_unit-test", timestamp, body), Sign("whsec_other", timestamp, body))
+}
+
+func TestBackoff(t *testing.T) {
+	tests := []struct {
+		attempts int
+		expected time.Duration
+	}{
+		{attempts: 1, expected: 30 * time.Second},
+		{attempts: 2, expected: time.Minute},
+		{attempts: 4, expected: 4 * time.Minute},
+		{attempts: 8, expected: 10 * time.Minute},
+		{attempts: 60, expected: 10 * time.Minute},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, Backoff(tt.attempts, 30*time.Second, 10*time.Minute), "attempts %d", tt.attempts)
+	}
+}
+
+func TestGenerateSecret(t *testing.T) {
+	first, err := generateSecret()
+	assert.NoError(t, err)
+	second, _ := generateSecret()
+
+	assert.True(t, strings.HasPrefix(first, secretPrefix))
+	assert.Len(t, first, len(secretPrefix)+2*secretBytes)
+	assert.NotEqual(t, first, second)
+}
+
+func TestToDelivery(t *testing.T) {
+	next := time.Now().UTC()
+	model := owebhook.DeliveryModel{ID: "1", Status: owebhook.StatusPending, NextAttemptAt: next}
+
+	assert.Equal(t, &next, ToDelivery(model).NextAttemptAt)
+
+	model.Status = owebhook.StatusDelivered
+	assert.Nil(t, ToDelivery(model).NextAttemptAt)
+	assert.Empty(t, ToDeliverySlice(nil))
+}
@@ -0,0 +1,101 @@
+package webhook
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/jmontesinos91/collector/domains/egress"
+	"github.com/jmontesinos91/collector/domains/pagination"
+)
+
+// Event an alarm or device event to be delivered to the subscriptions of its tenant
+type Event struct {
+	ID         string
+	Type       string
+	TenantID   int
+	OccurredAt time.Time
+	Data       interface{}
+}
+
+// Payload body posted to the subscribed endpoints
+type Payload struct {
+	ID         string      `json:"id"`
+	Type       string      `json:"type"`
+	TenantID   int         `json:"tenantId"`
+	OccurredAt time.Time   `json:"occurredAt"`
+	Data       interface{} `json:"data"`
+}
+
+// CreateRequest holds the body of a new subscription
+type CreateRequest struct {
+	TenantID    int      `json:"tenantId"`
+	URL         string   `json:"url"`
+	EventTypes  []string `json:"eventTypes"`
+	Description string   `json:"description"`
+}
+
+// UpdateRequest holds the fields of a subscription to be changed, activating a disabled
+// subscription resets its failures
+type UpdateRequest struct {
+	URL         *string  `json:"url"`
+	EventTypes  []string `json:"eventTypes"`
+	Description *string  `json:"description"`
+	Active      *bool    `json:"active"`
+}
+
+// DeliveryFilterRequest holds the http request params of the delivery log
+type DeliveryFilterRequest struct {
+	Status    string            `json:"status,omitempty"`
+	EventType string            `json:"eventType,omitempty"`
+	Filter    pagination.Filter `json:"filter,omitempty"`
+}
+
+// Subscription webhook subscription, the secret is only returned when the subscription is created
+type Subscription struct {
+	ID                  string     `json:"id"`
+	TenantID            int        `json:"tenantId"`
+	URL                 string     `json:"url"`
+	Secret              string     `json:"secret,omitempty"`
+	EventTypes          []string   `json:"eventTypes"`
+	Description         string     `json:"description,omitempty"`
+	Active              bool       `json:"active"`
+	ConsecutiveFailures int        `json:"consecutiveFailures"`
+	DisabledAt          *time.Time `json:"disabledAt,omitempty"`
+	DisabledReason      string     `json:"disabledReason,omitempty"`
+	CreatedBy           int        `json:"createdBy"`
+	CreatedAt           time.Time  `json:"createdAt"`
+	UpdatedAt           time.Time  `json:"updatedAt"`
+}
+
+// Delivery delivery log item
+type Delivery struct {
+	ID             string          `json:"id"`
+	SubscriptionID string          `json:"subscriptionId"`
+	EventID        string          `json:"eventId"`
+	EventType      string          `json:"eventType"`
+	Payload        json.RawMessage `json:"payload"`
+	Status         string          `json:"status"`
+	Attempts       int             `json:"attempts"`
+	NextAttemptAt  *time.Time      `json:"nextAttemptAt,omitempty"`
+	LastStatusCode int             `json:"lastStatusCode,omitempty"`
+	LastError      string          `json:"lastError,omitempty"`
+	CreatedAt      time.Time       `json:"createdAt"`
+	DeliveredAt    *time.Time      `json:"deliveredAt,omitempty"`
+}
+
+// Opts webhook service options
+type Opts struct {
+	// HTTPClient defaults to a client restricted by the egress policy
+	HTTPClient *http.Client
+	// Egress destinations the endpoints may point to, checked when they are registered
+	Egress       egress.Policy
+	Timeout      time.Duration
+	MaxAttempts  int
+	BackoffBase  time.Duration
+	BackoffMax   time.Duration
+	DisableAfter int
+	BatchSize    int
+	Concurrency  int
+	Lease        time.Duration
+}
@@ -0,0 +1,24 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/jmontesinos91/collector/domains/pagination"
+)
+
+// IDispatcher Queues events for the webhook subscriptions of their tenant, services depend on it
+// to push their own events
+type IDispatcher interface {
+	Dispatch(ctx context.Context, event Event)
+}
+
+// IService Manage the webhook subscriptions of the tenants of the requesting user
+type IService interface {
+	IDispatcher
+	HandleCreate(ctx context.Context, request *CreateRequest) (Subscription, error)
+	HandleFind(ctx context.Context) ([]Subscription, error)
+	HandleRetrieve(ctx context.Context, subscriptionID string) (Subscription, error)
+	HandleUpdate(ctx context.Context, subscriptionID string, request *UpdateRequest) (Subscription, error)
+	HandleDelete(ctx context.Context, subscriptionID string) error
+	HandleDeliveries(ctx context.Context, subscriptionID string, filter *DeliveryFilterRequest) (pagination.PaginatedRes, error)
+}
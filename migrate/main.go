@@ -5,12 +5,18 @@ import (
 	"fmt"
 	"log"
 	"os"
+	"strconv"
 	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/jmontesinos91/collector/config"
 	"github.com/jmontesinos91/collector/internal/adapters/db"
+	opartition "github.com/jmontesinos91/collector/internal/repositories/partition"
+	"github.com/jmontesinos91/collector/internal/services/partition"
 	"github.com/jmontesinos91/collector/migrate/migrations"
 	"github.com/jmontesinos91/ologs/logger"
+	"github.com/sirupsen/logrus"
 	"github.com/uptrace/bun"
 	"github.com/uptrace/bun/migrate"
 	"github.com/urfave/cli/v2"
@@ -30,11 +36,36 @@ func main() {
 		log.Fatalf("Failed to ensure default schema: %v", err)
 	}
 
+	partitionPolicies := make([]partition.Policy, 0, len(configs.Partitions.Tables))
+	for _, table := range configs.Partitions.Tables {
+		tenantRetention := make(map[int]int, len(table.Tenants))
+		for tenant, months := range table.Tenants {
+			tenantID, err := strconv.Atoi(tenant)
+			if err != nil {
+				contextLogger.Log(logrus.WarnLevel, "main", "Tenant retention ignored, invalid tenant id: "+tenant)
+				continue
+			}
+			tenantRetention[tenantID] = months
+		}
+		partitionPolicies = append(partitionPolicies, partition.Policy{
+			Table:                 table.Name,
+			RetentionMonths:       table.RetentionInMonths,
+			Action:                table.Action,
+			TenantRetentionMonths: tenantRetention,
+		})
+	}
+	partitionSvc := partition.NewDefaultService(contextLogger, opartition.NewDatabaseRepository(contextLogger, database),
+		partition.Opts{
+			Policies:      partitionPolicies,
+			PremakeMonths: configs.Partitions.PremakeMonths,
+		})
+
 	app := &cli.App{
 		Name: "bun",
 
 		Commands: []*cli.Command{
 			newDBCommand(migrate.NewMigrator(database, migrations.Migrations)),
+			newPartitionsCommand(partitionSvc),
 		},
 	}
 	if err := app.Run(os.Args); err != nil {
@@ -176,6 +207,92 @@ func newDBCommand(migrator *migrate.Migrator) *cli.Command {
 	}
 }
 
+func newPartitionsCommand(service partition.IService) *cli.Command {
+	return &cli.Command{
+		Name:  "partitions",
+		Usage: "monthly partitions and retention",
+		Subcommands: []*cli.Command{
+			{
+				Name:  "report",
+				Usage: "print the partitions of each table with their sizes",
+				Action: func(c *cli.Context) error {
+					reports, err := service.Report(c.Context)
+					if err != nil {
+						return err
+					}
+
+					w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+					for _, report := range reports {
+						fmt.Fprintf(w, "%s\tretention: %d months (%s)\trows: %d\tsize: %s\n", report.Table,
+							report.RetentionMonths, report.Action, report.Rows, formatBytes(report.SizeBytes))
+						for _, p := range report.Partitions {
+							fmt.Fprintf(w, "  %s\t%s\trows: %d\tsize: %s\n", p.Name, formatRange(p), p.Rows,
+								formatBytes(p.SizeBytes))
+						}
+					}
+					return w.Flush()
+				},
+			},
+			{
+				Name:  "maintain",
+				Usage: "create the coming partitions and expire the ones past the retention",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{
+						Name:  "dry-run",
+						Usage: "print the changes without applying them",
+					},
+				},
+				Action: func(c *cli.Context) error {
+					results, err := service.Maintain(c.Context, c.Bool("dry-run"))
+					for _, result := range results {
+						prefix := ""
+						if result.DryRun {
+							prefix = "(dry run) "
+						}
+						if result.Action != "" {
+							fmt.Printf("%s%s: created %v, %s %v\n", prefix, result.Table, result.Created, result.Action,
+								result.Expired)
+						}
+						for _, purge := range result.Purged {
+							fmt.Printf("%s%s: tenant %d rows before %s purged: %d\n", prefix, result.Table,
+								purge.TenantID, purge.Before.Format("2006-01-02"), purge.Rows)
+						}
+					}
+					return err
+				},
+			},
+		},
+	}
+}
+
+func formatRange(p partition.Partition) string {
+	if p.Default {
+		return "default"
+	}
+
+	bound := func(t *time.Time, unbounded string) string {
+		if t == nil {
+			return unbounded
+		}
+		return t.Format("2006-01-02")
+	}
+	return fmt.Sprintf("[%s, %s)", bound(p.From, "MINVALUE"), bound(p.To, "MAXVALUE"))
+}
+
+func formatBytes(size int64) string {
+	const unit = 1024
+	if size < unit {
+		return fmt.Sprintf("%d B", size)
+	}
+
+	div, exp := int64(unit), 0
+	for n := size / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(size)/float64(div), "KMGTPE"[exp])
+}
+
 func ensureDefaultSchema(db *bun.DB) error {
 	ctx := context.Background()
 
@@ -0,0 +1,7 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+DROP INDEX IF EXISTS export_jobs_status_idx;
+DROP INDEX IF EXISTS export_jobs_user_idx;
+DROP TABLE IF EXISTS export_jobs;
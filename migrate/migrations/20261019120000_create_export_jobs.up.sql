@@ -0,0 +1,24 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+create table if not exists export_jobs
+(
+    id           uuid primary key,
+    user_id      int8                     not null,
+    status       varchar(32)              not null,
+    format       varchar(16)              not null,
+    query        text                     not null default '',
+    rows_written int8                     not null default 0,
+    total_rows   int8                     not null default 0,
+    file_path    varchar(512)             not null default '',
+    file_size    int8                     not null default 0,
+    error        text                     not null default '',
+    created_at   timestamp with time zone not null default current_timestamp,
+    updated_at   timestamp with time zone not null default current_timestamp,
+    completed_at timestamp with time zone null,
+    expires_at   timestamp with time zone null
+);
+
+CREATE INDEX IF NOT EXISTS export_jobs_user_idx ON export_jobs (user_id, created_at);
+CREATE INDEX IF NOT EXISTS export_jobs_status_idx ON export_jobs (status, created_at);
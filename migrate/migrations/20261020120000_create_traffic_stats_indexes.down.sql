@@ -0,0 +1,7 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+DROP INDEX IF EXISTS traffic_ip_updated_at_idx;
+DROP INDEX IF EXISTS traffic_imei_updated_at_idx;
+DROP INDEX IF EXISTS traffic_updated_at_idx;
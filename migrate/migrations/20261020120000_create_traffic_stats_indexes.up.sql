@@ -0,0 +1,7 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS traffic_updated_at_idx ON public.traffic (updated_at);
+CREATE INDEX IF NOT EXISTS traffic_imei_updated_at_idx ON public.traffic (imei, updated_at);
+CREATE INDEX IF NOT EXISTS traffic_ip_updated_at_idx ON public.traffic (ip, updated_at);
@@ -0,0 +1,7 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+DROP INDEX IF EXISTS traffic_counter_id_idx;
+DROP INDEX IF EXISTS traffic_created_at_id_idx;
+DROP INDEX IF EXISTS traffic_updated_at_id_idx;
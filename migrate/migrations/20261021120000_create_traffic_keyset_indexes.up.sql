@@ -0,0 +1,7 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS traffic_updated_at_id_idx ON public.traffic (updated_at, id);
+CREATE INDEX IF NOT EXISTS traffic_created_at_id_idx ON public.traffic (created_at, id);
+CREATE INDEX IF NOT EXISTS traffic_counter_id_idx ON public.traffic (counter, id);
@@ -0,0 +1,12 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+DROP INDEX IF EXISTS traffic_ip_inet_idx;
+DROP INDEX IF EXISTS traffic_request_pattern_idx;
+DROP INDEX IF EXISTS traffic_ip_pattern_idx;
+DROP INDEX IF EXISTS traffic_imei_pattern_idx;
+
+--bun:split
+
+DROP FUNCTION IF EXISTS traffic_ip_inet(text);
@@ -0,0 +1,23 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+CREATE OR REPLACE FUNCTION traffic_ip_inet(value text) RETURNS inet
+    LANGUAGE plpgsql
+    IMMUTABLE
+    PARALLEL SAFE
+AS
+$$
+BEGIN
+    RETURN value::inet;
+EXCEPTION
+    WHEN others THEN RETURN NULL;
+END;
+$$;
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS traffic_imei_pattern_idx ON public.traffic (imei varchar_pattern_ops);
+CREATE INDEX IF NOT EXISTS traffic_ip_pattern_idx ON public.traffic (ip varchar_pattern_ops);
+CREATE INDEX IF NOT EXISTS traffic_request_pattern_idx ON public.traffic (request varchar_pattern_ops);
+CREATE INDEX IF NOT EXISTS traffic_ip_inet_idx ON public.traffic USING gist (traffic_ip_inet(ip) inet_ops);
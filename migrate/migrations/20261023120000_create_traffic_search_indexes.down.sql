@@ -0,0 +1,7 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+DROP INDEX IF EXISTS traffic_ip_trgm_idx;
+DROP INDEX IF EXISTS traffic_imei_trgm_idx;
+DROP INDEX IF EXISTS traffic_request_trgm_idx;
@@ -0,0 +1,11 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS traffic_request_trgm_idx ON public.traffic USING gin (request gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS traffic_imei_trgm_idx ON public.traffic USING gin (imei gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS traffic_ip_trgm_idx ON public.traffic USING gin (ip gin_trgm_ops);
@@ -0,0 +1,6 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+DROP INDEX IF EXISTS traffic_deleted_at_idx;
+ALTER TABLE public.traffic DROP COLUMN IF EXISTS deleted_at;
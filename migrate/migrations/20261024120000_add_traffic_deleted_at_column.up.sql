@@ -0,0 +1,6 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+ALTER TABLE public.traffic ADD COLUMN IF NOT EXISTS deleted_at timestamp with time zone NULL;
+CREATE INDEX IF NOT EXISTS traffic_deleted_at_idx ON public.traffic (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,6 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+DROP TABLE IF EXISTS audit_log;
+DROP FUNCTION IF EXISTS audit_log_append_only();
@@ -0,0 +1,48 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+create table if not exists audit_log
+(
+    id         uuid primary key,
+    actor_id   int8                     not null,
+    role       varchar(128)             not null default '',
+    action     varchar(64)              not null,
+    resource   varchar(64)              not null,
+    target_id  varchar(256)             not null default '',
+    before     jsonb                    null,
+    after      jsonb                    null,
+    request_id varchar(128)             not null default '',
+    created_at timestamp with time zone not null default current_timestamp
+);
+
+CREATE INDEX IF NOT EXISTS audit_log_created_at_idx ON audit_log (created_at, id);
+CREATE INDEX IF NOT EXISTS audit_log_actor_idx ON audit_log (actor_id, created_at);
+CREATE INDEX IF NOT EXISTS audit_log_target_idx ON audit_log (resource, target_id, created_at);
+CREATE INDEX IF NOT EXISTS audit_log_action_idx ON audit_log (action, created_at);
+CREATE INDEX IF NOT EXISTS audit_log_request_idx ON audit_log (request_id);
+
+--bun:split
+
+CREATE OR REPLACE FUNCTION audit_log_append_only() RETURNS trigger
+    LANGUAGE plpgsql
+AS
+$$
+BEGIN
+    RAISE EXCEPTION 'audit_log is append-only';
+END;
+$$;
+
+--bun:split
+
+CREATE TRIGGER audit_log_no_update_delete
+    BEFORE UPDATE OR DELETE
+    ON audit_log
+    FOR EACH ROW
+EXECUTE FUNCTION audit_log_append_only();
+
+CREATE TRIGGER audit_log_no_truncate
+    BEFORE TRUNCATE
+    ON audit_log
+    FOR EACH STATEMENT
+EXECUTE FUNCTION audit_log_append_only();
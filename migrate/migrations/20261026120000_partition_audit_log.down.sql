@@ -0,0 +1,38 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+ALTER TABLE public.audit_log RENAME TO audit_log_partitioned;
+
+CREATE TABLE public.audit_log
+(
+    LIKE public.audit_log_partitioned INCLUDING DEFAULTS
+);
+
+INSERT INTO public.audit_log SELECT * FROM public.audit_log_partitioned;
+
+DROP TABLE public.audit_log_partitioned;
+
+ALTER TABLE public.audit_log ADD PRIMARY KEY (id);
+
+CREATE INDEX IF NOT EXISTS audit_log_created_at_idx ON public.audit_log (created_at, id);
+CREATE INDEX IF NOT EXISTS audit_log_actor_idx ON public.audit_log (actor_id, created_at);
+CREATE INDEX IF NOT EXISTS audit_log_target_idx ON public.audit_log (resource, target_id, created_at);
+CREATE INDEX IF NOT EXISTS audit_log_action_idx ON public.audit_log (action, created_at);
+CREATE INDEX IF NOT EXISTS audit_log_request_idx ON public.audit_log (request_id);
+
+CREATE TRIGGER audit_log_no_update_delete
+    BEFORE UPDATE OR DELETE
+    ON public.audit_log
+    FOR EACH ROW
+EXECUTE FUNCTION audit_log_append_only();
+
+CREATE TRIGGER audit_log_no_truncate
+    BEFORE TRUNCATE
+    ON public.audit_log
+    FOR EACH STATEMENT
+EXECUTE FUNCTION audit_log_append_only();
+
+--bun:split
+
+DROP FUNCTION IF EXISTS ensure_monthly_partition(text, timestamptz);
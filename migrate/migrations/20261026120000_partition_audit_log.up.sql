@@ -0,0 +1,84 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+CREATE SCHEMA IF NOT EXISTS archive;
+
+--bun:split
+
+CREATE OR REPLACE FUNCTION ensure_monthly_partition(parent text, month timestamptz) RETURNS text
+    LANGUAGE plpgsql
+AS
+$$
+DECLARE
+    lower_bound    timestamptz := date_trunc('month', month AT TIME ZONE 'UTC') AT TIME ZONE 'UTC';
+    upper_bound    timestamptz := (date_trunc('month', month AT TIME ZONE 'UTC') + interval '1 month') AT TIME ZONE 'UTC';
+    partition_name text        := parent || '_p' || to_char(lower_bound AT TIME ZONE 'UTC', 'YYYYMM');
+BEGIN
+    IF to_regclass(partition_name) IS NOT NULL THEN
+        RETURN NULL;
+    END IF;
+
+    EXECUTE format('CREATE TABLE %I PARTITION OF %I FOR VALUES FROM (%L) TO (%L)',
+                   partition_name, parent, lower_bound, upper_bound);
+
+    RETURN partition_name;
+END;
+$$;
+
+--bun:split
+
+ALTER TABLE public.audit_log RENAME TO audit_log_unpartitioned;
+
+CREATE TABLE public.audit_log
+(
+    LIKE public.audit_log_unpartitioned INCLUDING DEFAULTS
+) PARTITION BY RANGE (created_at);
+
+CREATE TABLE public.audit_log_default PARTITION OF public.audit_log DEFAULT;
+
+--bun:split
+
+DO
+$$
+DECLARE
+    month timestamptz;
+BEGIN
+    FOR month IN SELECT generate_series(
+                                date_trunc('month', COALESCE((SELECT min(created_at) FROM public.audit_log_unpartitioned),
+                                                             current_timestamp)),
+                                current_timestamp + interval '3 months',
+                                interval '1 month')
+        LOOP
+            PERFORM ensure_monthly_partition('audit_log', month);
+        END LOOP;
+END;
+$$;
+
+--bun:split
+
+INSERT INTO public.audit_log SELECT * FROM public.audit_log_unpartitioned;
+
+DROP TABLE public.audit_log_unpartitioned;
+
+ALTER TABLE public.audit_log ADD PRIMARY KEY (id, created_at);
+
+CREATE INDEX IF NOT EXISTS audit_log_created_at_idx ON public.audit_log (created_at, id);
+CREATE INDEX IF NOT EXISTS audit_log_actor_idx ON public.audit_log (actor_id, created_at);
+CREATE INDEX IF NOT EXISTS audit_log_target_idx ON public.audit_log (resource, target_id, created_at);
+CREATE INDEX IF NOT EXISTS audit_log_action_idx ON public.audit_log (action, created_at);
+CREATE INDEX IF NOT EXISTS audit_log_request_idx ON public.audit_log (request_id);
+
+--bun:split
+
+CREATE TRIGGER audit_log_no_update_delete
+    BEFORE UPDATE OR DELETE
+    ON public.audit_log
+    FOR EACH ROW
+EXECUTE FUNCTION audit_log_append_only();
+
+CREATE TRIGGER audit_log_no_truncate
+    BEFORE TRUNCATE
+    ON public.audit_log
+    FOR EACH STATEMENT
+EXECUTE FUNCTION audit_log_append_only();
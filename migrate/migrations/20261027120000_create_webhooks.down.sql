@@ -0,0 +1,13 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+DROP INDEX IF EXISTS webhook_deliveries_due_idx;
+DROP INDEX IF EXISTS webhook_deliveries_subscription_idx;
+DROP TABLE IF EXISTS webhook_deliveries;
+
+--bun:split
+
+DROP INDEX IF EXISTS webhook_subscriptions_event_types_idx;
+DROP INDEX IF EXISTS webhook_subscriptions_tenant_idx;
+DROP TABLE IF EXISTS webhook_subscriptions;
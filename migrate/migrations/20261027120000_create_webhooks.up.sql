@@ -0,0 +1,45 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+create table if not exists webhook_subscriptions
+(
+    id                   uuid primary key,
+    tenant_id            int8                     not null,
+    url                  varchar(2048)            not null,
+    secret               varchar(128)             not null,
+    event_types          text[]                   not null,
+    description          varchar(255)             not null default '',
+    active               bool                     not null default true,
+    consecutive_failures int4                     not null default 0,
+    disabled_at          timestamp with time zone null,
+    disabled_reason      text                     not null default '',
+    created_by           int8                     not null,
+    created_at           timestamp with time zone not null default current_timestamp,
+    updated_at           timestamp with time zone not null default current_timestamp
+);
+
+CREATE INDEX IF NOT EXISTS webhook_subscriptions_tenant_idx ON webhook_subscriptions (tenant_id, created_at);
+CREATE INDEX IF NOT EXISTS webhook_subscriptions_event_types_idx ON webhook_subscriptions USING gin (event_types) WHERE active;
+
+--bun:split
+
+create table if not exists webhook_deliveries
+(
+    id               uuid primary key,
+    subscription_id  uuid                     not null references webhook_subscriptions (id) on delete cascade,
+    event_id         varchar(64)              not null,
+    event_type       varchar(64)              not null,
+    payload          jsonb                    not null,
+    status           varchar(32)              not null,
+    attempts         int4                     not null default 0,
+    next_attempt_at  timestamp with time zone not null default current_timestamp,
+    last_status_code int4                     not null default 0,
+    last_error       text                     not null default '',
+    created_at       timestamp with time zone not null default current_timestamp,
+    updated_at       timestamp with time zone not null default current_timestamp,
+    delivered_at     timestamp with time zone null
+);
+
+CREATE INDEX IF NOT EXISTS webhook_deliveries_subscription_idx ON webhook_deliveries (subscription_id, created_at);
+CREATE INDEX IF NOT EXISTS webhook_deliveries_due_idx ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
@@ -0,0 +1,16 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+ALTER TABLE export_jobs
+    DROP COLUMN IF EXISTS tenants,
+    DROP COLUMN IF EXISTS all_tenants;
+
+--bun:split
+
+DROP INDEX IF EXISTS traffic_tenant_updated_at_idx;
+
+--bun:split
+
+ALTER TABLE traffic
+    DROP COLUMN IF EXISTS tenant_id;
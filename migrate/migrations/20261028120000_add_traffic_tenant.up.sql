@@ -0,0 +1,19 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+-- Rows written before this migration have no tenant, they pick it up on the next frame of their device
+-- or from the traffic-tenant-backfill job, which resolves the tenants through the old routers and units
+ALTER TABLE traffic
+    ADD COLUMN IF NOT EXISTS tenant_id int8 null;
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS traffic_tenant_updated_at_idx ON traffic (tenant_id, updated_at);
+
+--bun:split
+
+-- Export jobs keep the tenants of the requesting user, the jobs queued before have none and export no rows
+ALTER TABLE export_jobs
+    ADD COLUMN IF NOT EXISTS all_tenants bool   not null default false,
+    ADD COLUMN IF NOT EXISTS tenants     int8[] not null default '{}';
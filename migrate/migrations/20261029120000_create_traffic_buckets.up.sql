@@ -0,0 +1,16 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+-- Frames received by each traffic row per minute, they back the rolling window counters and
+-- are purged once they fall out of the largest window
+create table if not exists traffic_buckets
+(
+    imei     varchar(256)             not null,
+    is_alarm bool                     not null,
+    bucket   timestamp with time zone not null,
+    frames   int8                     not null default 0,
+    primary key (imei, is_alarm, bucket)
+);
+
+CREATE INDEX IF NOT EXISTS traffic_buckets_bucket_idx ON traffic_buckets (bucket);
@@ -0,0 +1,11 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+DROP INDEX IF EXISTS traffic_excessive_since_idx;
+
+--bun:split
+
+ALTER TABLE traffic
+    DROP COLUMN IF EXISTS quarantined_until,
+    DROP COLUMN IF EXISTS excessive_since;
@@ -0,0 +1,13 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+-- Devices whose frames crossed their threshold, both traffic rows of a device are flagged and the
+-- flag is cleared once its traffic is back under the threshold
+ALTER TABLE traffic
+    ADD COLUMN IF NOT EXISTS excessive_since   timestamp with time zone null,
+    ADD COLUMN IF NOT EXISTS quarantined_until timestamp with time zone null;
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS traffic_excessive_since_idx ON traffic (imei) WHERE excessive_since IS NOT NULL;
@@ -0,0 +1,7 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+DROP INDEX IF EXISTS device_commands_queued_idx;
+DROP INDEX IF EXISTS device_commands_imei_idx;
+DROP TABLE IF EXISTS device_commands;
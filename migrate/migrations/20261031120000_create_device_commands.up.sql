@@ -0,0 +1,23 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+-- Downlink commands queued per device, they are delivered in the response to the next frame
+-- of the device and acknowledged by it on a later frame
+create table if not exists device_commands
+(
+    id              uuid primary key,
+    imei            varchar(256)             not null,
+    tenant_id       int8                     not null,
+    type            varchar(64)              not null,
+    params          jsonb                    not null default '{}',
+    status          varchar(32)              not null,
+    created_by      int8                     not null,
+    created_at      timestamp with time zone not null default current_timestamp,
+    updated_at      timestamp with time zone not null default current_timestamp,
+    sent_at         timestamp with time zone null,
+    acknowledged_at timestamp with time zone null
+);
+
+CREATE INDEX IF NOT EXISTS device_commands_imei_idx ON device_commands (imei, created_at);
+CREATE INDEX IF NOT EXISTS device_commands_queued_idx ON device_commands (imei, created_at) WHERE status = 'queued';
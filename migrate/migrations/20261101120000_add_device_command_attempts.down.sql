@@ -0,0 +1,13 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+DROP INDEX IF EXISTS device_commands_sent_idx;
+DROP INDEX IF EXISTS device_commands_pending_idx;
+CREATE INDEX IF NOT EXISTS device_commands_queued_idx ON device_commands (imei, created_at) WHERE status = 'queued';
+
+--bun:split
+
+ALTER TABLE device_commands
+    DROP COLUMN IF EXISTS expired_at,
+    DROP COLUMN IF EXISTS attempts;
@@ -0,0 +1,19 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+-- Sent commands the device does not acknowledge in time are delivered again until the attempts
+-- run out, then they are expired
+ALTER TABLE device_commands
+    ADD COLUMN IF NOT EXISTS attempts   int4                     not null default 0,
+    ADD COLUMN IF NOT EXISTS expired_at timestamp with time zone null;
+
+--bun:split
+
+UPDATE device_commands SET attempts = 1 WHERE sent_at IS NOT NULL;
+
+--bun:split
+
+DROP INDEX IF EXISTS device_commands_queued_idx;
+CREATE INDEX IF NOT EXISTS device_commands_pending_idx ON device_commands (imei, created_at) WHERE status IN ('queued', 'sent');
+CREATE INDEX IF NOT EXISTS device_commands_sent_idx ON device_commands (sent_at) WHERE status = 'sent';
@@ -0,0 +1,11 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+DROP INDEX IF EXISTS export_jobs_lease_idx;
+
+--bun:split
+
+ALTER TABLE export_jobs
+    DROP COLUMN IF EXISTS lease_expires_at,
+    DROP COLUMN IF EXISTS attempts;
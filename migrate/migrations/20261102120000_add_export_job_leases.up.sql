@@ -0,0 +1,18 @@
+SET statement_timeout = 0;
+
+--bun:split
+
+-- A running job holds a lease its worker keeps renewing, a job whose lease lapsed was left behind by a
+-- stopped worker and is claimed again until the attempts run out. The writes of a worker are guarded by
+-- the attempt it claimed so a worker that lost its lease can not overwrite the attempt that replaced it
+ALTER TABLE export_jobs
+    ADD COLUMN IF NOT EXISTS attempts         int4                     not null default 0,
+    ADD COLUMN IF NOT EXISTS lease_expires_at timestamp with time zone null;
+
+--bun:split
+
+UPDATE export_jobs SET attempts = 1 WHERE status <> 'pending';
+
+--bun:split
+
+CREATE INDEX IF NOT EXISTS export_jobs_lease_idx ON export_jobs (lease_expires_at) WHERE status = 'running';